@@ -0,0 +1,31 @@
+package validor
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+var (
+	// azureCredential authenticates requests to Azure with the environment,
+	// workload identity, managed identity or the az CLI's login, whichever is
+	// available first.
+	azureCredential = func() (azcore.TokenCredential, error) {
+		return azidentity.NewDefaultAzureCredential(nil)
+	}
+	// azureClientOptions configures the Azure Resource Manager clients.
+	azureClientOptions *arm.ClientOptions
+)
+
+// azureAccessToken returns a token for scope from azureCredential.
+func azureAccessToken(ctx context.Context, scope string) (string, error) {
+	credential, err := azureCredential()
+	if err != nil {
+		return "", err
+	}
+	token, err := credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}})
+	return token.Token, err
+}
@@ -0,0 +1,78 @@
+package validor
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// WithBenchmark applies and destroys every example iterations times and
+// reports the min, mean and p95 duration of each stage, to track how long a
+// module takes to provision. An example stops at its first failing iteration.
+func WithBenchmark(iterations int) Option {
+	return func(c *Config) { c.BenchmarkIterations = iterations }
+}
+
+// StageTiming summarizes the durations of a stage over benchmark iterations.
+type StageTiming struct {
+	Stage      Stage         `json:"stage"`
+	Iterations int           `json:"iterations"`
+	Min        time.Duration `json:"min"`
+	Mean       time.Duration `json:"mean"`
+	P95        time.Duration `json:"p95"`
+}
+
+// BenchmarkStats summarizes the stage durations of benchmark iterations,
+// built-in stages first in pipeline order followed by custom stages by name.
+// The p95 is the nearest-rank percentile.
+func BenchmarkStats(iterations []map[Stage]time.Duration) []StageTiming {
+	samples := make(map[Stage][]time.Duration)
+	for _, durations := range iterations {
+		for stage, d := range durations {
+			samples[stage] = append(samples[stage], d)
+		}
+	}
+
+	var stats []StageTiming
+	for _, stage := range orderStages(slices.Collect(maps.Keys(samples))) {
+		durations := samples[stage]
+		slices.Sort(durations)
+		var total time.Duration
+		for _, d := range durations {
+			total += d
+		}
+		rank := int(math.Ceil(0.95*float64(len(durations)))) - 1
+		stats = append(stats, StageTiming{
+			Stage:      stage,
+			Iterations: len(durations),
+			Min:        durations[0],
+			Mean:       total / time.Duration(len(durations)),
+			P95:        durations[rank],
+		})
+	}
+	return stats
+}
+
+// printBenchmarks logs the stage timings of the examples that were
+// benchmarked.
+func printBenchmarks(tb testLogger, modules []*Module) {
+	var benchmarked []*Module
+	for _, module := range modules {
+		if len(module.BenchmarkRuns) > 0 {
+			benchmarked = append(benchmarked, module)
+		}
+	}
+	if len(benchmarked) == 0 {
+		return
+	}
+
+	tb.Log("Benchmark (min / mean / p95 per stage):")
+	for _, module := range benchmarked {
+		tb.Logf("  %s (%d iterations)", module.Name, len(module.BenchmarkRuns))
+		for _, timing := range BenchmarkStats(module.BenchmarkRuns) {
+			tb.Logf("    %-10s %s / %s / %s", timing.Stage, timing.Min.Round(time.Second), timing.Mean.Round(time.Second), timing.P95.Round(time.Second))
+		}
+	}
+	tb.Log("")
+}
@@ -0,0 +1,93 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBenchmarkStats(t *testing.T) {
+	var iterations []map[Stage]time.Duration
+	for i := 1; i <= 20; i++ {
+		iterations = append(iterations, map[Stage]time.Duration{
+			StageApply:   time.Duration(i) * time.Minute,
+			StageDestroy: time.Minute,
+			"smoke":      time.Second,
+		})
+	}
+	iterations = append(iterations, map[Stage]time.Duration{StageApply: 30 * time.Minute})
+
+	want := []StageTiming{
+		{Stage: StageApply, Iterations: 21, Min: time.Minute, Mean: 240 * time.Minute / 21, P95: 20 * time.Minute},
+		{Stage: StageDestroy, Iterations: 20, Min: time.Minute, Mean: time.Minute, P95: time.Minute},
+		{Stage: "smoke", Iterations: 20, Min: time.Second, Mean: time.Second, P95: time.Second},
+	}
+	if got := BenchmarkStats(iterations); !reflect.DeepEqual(got, want) {
+		t.Errorf("BenchmarkStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRunModuleTests_Benchmark(t *testing.T) {
+	tests := []struct {
+		name        string
+		failAt      int
+		wantApplies int
+		wantRuns    int
+	}{
+		{name: "every iteration passes", wantApplies: 3, wantRuns: 3},
+		{name: "stops at a failing iteration", failAt: 2, wantApplies: 2, wantRuns: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := newPlannedModule(t, "default")
+			applies, destroys := 0, 0
+			module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+				applies++
+				if applies == tt.failAt {
+					return errors.New("quota exceeded")
+				}
+				return nil
+			}
+			module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+				destroys++
+				return nil
+			}
+
+			recorder := &recordingTB{TB: t}
+			runner := &DefaultTestRunner{Config: NewConfig(WithExample(""), WithBenchmark(3))}
+			report := runner.RunTestsWithReport(context.Background(), recorder, Runners([]*Module{module}), false, nil)
+
+			if applies != tt.wantApplies || destroys != tt.wantApplies {
+				t.Errorf("applies = %d, destroys = %d, want %d of each", applies, destroys, tt.wantApplies)
+			}
+			if len(module.BenchmarkRuns) != tt.wantRuns {
+				t.Errorf("benchmark runs = %d, want %d", len(module.BenchmarkRuns), tt.wantRuns)
+			}
+			if got := report.Modules[0].Benchmark; len(got) == 0 || got[0].Iterations != tt.wantRuns {
+				t.Errorf("report benchmark = %+v, want %d iterations", got, tt.wantRuns)
+			}
+			tb := &recordingTB{}
+			printBenchmarks(tb, []*Module{module})
+			if output := strings.Join(tb.logs, "\n"); !strings.Contains(output, "Benchmark (min / mean / p95 per stage):") || !strings.Contains(output, "apply") {
+				t.Errorf("output missing the benchmark summary:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestRunModuleTests_BenchmarkRequiresDestroy(t *testing.T) {
+	module := newPlannedModule(t, "default")
+	mock := &fatalTB{TB: t}
+	config := NewConfig(WithExample(""), WithBenchmark(3), WithSkipDestroy(true))
+	func() {
+		defer func() { recover() }()
+		runModuleTests(mock, Runners([]*Module{module}), false, config, nil, "registry")
+	}()
+	if !strings.Contains(mock.message, "Benchmarks destroy every iteration") {
+		t.Errorf("a benchmark with skip destroy should be rejected, got %q", mock.message)
+	}
+}
@@ -0,0 +1,137 @@
+package validor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Plan actions that WithForbiddenActions accepts. A replacement plans both a
+// delete and a create, so forbidding delete also forbids replace.
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionReplace = "replace"
+)
+
+// WithMaxPlannedResources fails an example before apply when its plan changes
+// more than n resources.
+func WithMaxPlannedResources(example string, n int) Option {
+	return func(c *Config) {
+		if c.MaxPlannedResources == nil {
+			c.MaxPlannedResources = make(map[string]int)
+		}
+		c.MaxPlannedResources[example] = n
+	}
+}
+
+// WithForbiddenActions fails an example before apply when its plan contains
+// any of the actions: create, update, delete or replace.
+func WithForbiddenActions(example string, actions ...string) Option {
+	return func(c *Config) {
+		if c.ForbiddenActions == nil {
+			c.ForbiddenActions = make(map[string][]string)
+		}
+		c.ForbiddenActions[example] = append(c.ForbiddenActions[example], actions...)
+	}
+}
+
+// exampleIntFlag parses repeated EXAMPLE=N flag values into m.
+func exampleIntFlag(m *map[string]int) func(string) error {
+	return func(value string) error {
+		example, number, ok := strings.Cut(value, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(number))
+		if !ok || strings.TrimSpace(example) == "" || err != nil {
+			return fmt.Errorf("invalid value %q, expected EXAMPLE=N", value)
+		}
+		if *m == nil {
+			*m = make(map[string]int)
+		}
+		(*m)[strings.TrimSpace(example)] = n
+		return nil
+	}
+}
+
+// planBudget returns the plan check for an example's budget, if it has one.
+func (c *Config) planBudget(example string) (planCheck, bool) {
+	limit, limited := c.MaxPlannedResources[example]
+	forbidden := c.ForbiddenActions[example]
+	if !limited && len(forbidden) == 0 {
+		return planCheck{}, false
+	}
+	if !limited {
+		limit = -1
+	}
+	return planBudgetCheck(limit, forbidden), true
+}
+
+// planBudgetCheck fails a plan that changes more than limit resources, unless
+// limit is negative, or that contains a forbidden action.
+func planBudgetCheck(limit int, forbidden []string) planCheck {
+	return planCheck{
+		operation: "plan budget",
+		run: func(ctx context.Context, t testing.TB, m *Module, planJSON []byte) error {
+			for _, action := range forbidden {
+				if !slices.Contains([]string{ActionCreate, ActionUpdate, ActionDelete, ActionReplace}, action) {
+					return fmt.Errorf("unknown plan action %q", action)
+				}
+			}
+
+			changes, err := planResourceChanges(planJSON)
+			if err != nil {
+				return err
+			}
+
+			planned := 0
+			violations := make(map[string][]string)
+			for _, rc := range changes {
+				actions := planActions(rc.Change.Actions)
+				if len(actions) == 0 {
+					continue
+				}
+				planned++
+				for _, action := range actions {
+					if slices.Contains(forbidden, action) {
+						violations[action] = append(violations[action], rc.Address)
+					}
+				}
+			}
+
+			var problems []string
+			for _, action := range forbidden {
+				if addresses := violations[action]; len(addresses) > 0 {
+					problems = append(problems, fmt.Sprintf("plan contains forbidden action %s: %s", action, strings.Join(addresses, ", ")))
+					delete(violations, action)
+				}
+			}
+			if limit >= 0 && planned > limit {
+				problems = append(problems, fmt.Sprintf("plan changes %d resources, more than the budget of %d", planned, limit))
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%s", strings.Join(problems, "; "))
+			}
+			t.Logf("Plan for module %s changes %d resources", m.Name, planned)
+			return nil
+		},
+	}
+}
+
+// planActions maps the actions of a resource change to the budget's actions,
+// leaving out no-ops and reads.
+func planActions(actions []string) []string {
+	switch {
+	case slices.Contains(actions, ActionDelete) && slices.Contains(actions, ActionCreate):
+		return []string{ActionReplace, ActionDelete, ActionCreate}
+	case slices.Contains(actions, ActionCreate):
+		return []string{ActionCreate}
+	case slices.Contains(actions, ActionUpdate):
+		return []string{ActionUpdate}
+	case slices.Contains(actions, ActionDelete):
+		return []string{ActionDelete}
+	}
+	return nil
+}
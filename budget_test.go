@@ -0,0 +1,99 @@
+package validor
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanBudgetCheck(t *testing.T) {
+	plan := `{"resource_changes":[
+		{"address":"azurerm_resource_group.rg","change":{"actions":["no-op"]}},
+		{"address":"azurerm_storage_account.sa","change":{"actions":["create"]}},
+		{"address":"azurerm_key_vault.kv","change":{"actions":["update"]}},
+		{"address":"azurerm_subnet.sn","change":{"actions":["delete","create"]}},
+		{"address":"data.azurerm_client_config.current","change":{"actions":["read"]}}
+	]}`
+
+	tests := []struct {
+		name      string
+		limit     int
+		forbidden []string
+		wantErr   string
+	}{
+		{name: "within budget", limit: 3},
+		{name: "no limit", limit: -1},
+		{name: "over budget", limit: 2, wantErr: "plan changes 3 resources, more than the budget of 2"},
+		{name: "forbidden delete catches replacement", limit: -1, forbidden: []string{ActionDelete}, wantErr: "forbidden action delete: azurerm_subnet.sn"},
+		{name: "forbidden replace", limit: -1, forbidden: []string{ActionReplace}, wantErr: "forbidden action replace: azurerm_subnet.sn"},
+		{name: "forbidden update", limit: -1, forbidden: []string{ActionUpdate}, wantErr: "forbidden action update: azurerm_key_vault.kv"},
+		{name: "unknown action", limit: -1, forbidden: []string{"destroy"}, wantErr: `unknown plan action "destroy"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := planBudgetCheck(tt.limit, tt.forbidden)
+			err := check.run(context.Background(), t, NewModule("default", "/tmp/default"), []byte(plan))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("check error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("check error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanBudget_BlocksApply(t *testing.T) {
+	module := newPlannedModule(t, "default")
+	module.planHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+		return []byte(`{"resource_changes":[{"address":"azurerm_subnet.sn","change":{"actions":["delete"]}}]}`), nil
+	}
+	applied := false
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+		applied = true
+		return nil
+	}
+
+	config := NewConfig(WithForbiddenActions("default", ActionDelete), WithMaxPlannedResources("other", 1))
+	check, ok := config.planBudget("default")
+	if !ok {
+		t.Fatal("expected a plan budget for default")
+	}
+	if _, ok := config.planBudget("complete"); ok {
+		t.Error("complete has no plan budget")
+	}
+	module.planChecks = []planCheck{check}
+
+	if err := module.Apply(context.Background(), t); err == nil {
+		t.Fatal("Apply() should fail on a forbidden action")
+	}
+	if applied {
+		t.Error("the example should not be applied")
+	}
+	if module.failed[StagePlan] == nil {
+		t.Error("the plan stage should be marked as failed")
+	}
+}
+
+func TestExampleIntFlag(t *testing.T) {
+	var limits map[string]int
+	set := exampleIntFlag(&limits)
+	for _, value := range []string{"default=10", " complete = 25 "} {
+		if err := set(value); err != nil {
+			t.Fatalf("set(%q) error = %v", value, err)
+		}
+	}
+	if want := map[string]int{"default": 10, "complete": 25}; !reflect.DeepEqual(limits, want) {
+		t.Errorf("limits = %v, want %v", limits, want)
+	}
+	for _, value := range []string{"default", "=3", "default=many"} {
+		if err := set(value); err == nil {
+			t.Errorf("set(%q) should fail", value)
+		}
+	}
+}
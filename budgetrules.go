@@ -0,0 +1,181 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WithBudgetRules fails examples before apply whose plan breaks the rules in
+// the YAML file at path, such as creating more than a few clusters or using a
+// banned SKU.
+func WithBudgetRules(path string) Option {
+	return func(c *Config) { c.BudgetRules = path }
+}
+
+// BudgetRules limit what the examples of a module may create, keyed by
+// resource type. A type may be a glob, such as azurerm_* or *.
+type BudgetRules struct {
+	Resources map[string]ResourceRule `yaml:"resources"`
+}
+
+// ResourceRule limits the resources of one type. MaxCount caps how many a
+// plan may create. Banned maps an attribute to globs of values it may not
+// have, and Max an attribute to the highest number it may have. Attributes
+// are dot-separated paths into the planned values, such as
+// default_node_pool.vm_size; nested blocks are searched element by element.
+type ResourceRule struct {
+	MaxCount *int                `yaml:"max_count"`
+	Banned   map[string][]string `yaml:"banned"`
+	Max      map[string]float64  `yaml:"max"`
+}
+
+// ParseBudgetRules decodes budget rules, rejecting unknown keys and invalid
+// globs so a typo does not silently disable a rule.
+func ParseBudgetRules(data []byte) (*BudgetRules, error) {
+	rules := &BudgetRules{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	for resourceType, rule := range rules.Resources {
+		patterns := []string{resourceType}
+		for _, values := range rule.Banned {
+			patterns = append(patterns, values...)
+		}
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%s: invalid pattern %q: %w", resourceType, pattern, err)
+			}
+		}
+		if rule.MaxCount != nil && *rule.MaxCount < 0 {
+			return nil, fmt.Errorf("%s: max_count must not be negative", resourceType)
+		}
+	}
+	return rules, nil
+}
+
+// LoadBudgetRules reads the budget rules file at path.
+func LoadBudgetRules(path string) (*BudgetRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read budget rules: %w", err)
+	}
+	rules, err := ParseBudgetRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid budget rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Violations returns every rule the planned resource changes break, sorted by
+// resource type. Counts only include resources the plan creates, and values
+// that are known after apply are not checked.
+func (r *BudgetRules) Violations(planJSON []byte) ([]string, error) {
+	resources, err := plannedResources(planJSON)
+	if err != nil {
+		return nil, err
+	}
+	addresses := slices.Sorted(maps.Keys(resources))
+	types := slices.Sorted(maps.Keys(r.Resources))
+
+	var violations []string
+	for _, resourceType := range types {
+		rule := r.Resources[resourceType]
+		var created []string
+		for _, address := range addresses {
+			resource := resources[address]
+			if matched, _ := path.Match(resourceType, resource.Type); !matched {
+				continue
+			}
+			if slices.Contains(planActions(resource.Change.Actions), ActionCreate) {
+				created = append(created, address)
+			}
+			violations = append(violations, rule.attributeViolations(address, resource.Change.After)...)
+		}
+		if rule.MaxCount != nil && len(created) > *rule.MaxCount {
+			violations = append(violations, fmt.Sprintf("plan creates %d %s resources, more than the %d allowed: %s",
+				len(created), resourceType, *rule.MaxCount, strings.Join(created, ", ")))
+		}
+	}
+	return violations, nil
+}
+
+func (rule ResourceRule) attributeViolations(address string, after map[string]any) []string {
+	var violations []string
+	for _, attribute := range slices.Sorted(maps.Keys(rule.Banned)) {
+		for _, value := range attributeValues(after, strings.Split(attribute, ".")) {
+			s, ok := value.(string)
+			if !ok {
+				continue
+			}
+			for _, pattern := range rule.Banned[attribute] {
+				if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(s)); matched {
+					violations = append(violations, fmt.Sprintf("%s sets %s to banned value %q", address, attribute, s))
+					break
+				}
+			}
+		}
+	}
+	for _, attribute := range slices.Sorted(maps.Keys(rule.Max)) {
+		for _, value := range attributeValues(after, strings.Split(attribute, ".")) {
+			if n, ok := value.(float64); ok && n > rule.Max[attribute] {
+				violations = append(violations, fmt.Sprintf("%s sets %s to %v, more than the %v allowed", address, attribute, n, rule.Max[attribute]))
+			}
+		}
+	}
+	return violations
+}
+
+// attributeValues returns the values at path in a planned value, following
+// every element of the lists nested blocks are planned as.
+func attributeValues(value any, path []string) []any {
+	switch v := value.(type) {
+	case []any:
+		var values []any
+		for _, element := range v {
+			values = append(values, attributeValues(element, path)...)
+		}
+		return values
+	case map[string]any:
+		if len(path) == 0 {
+			return nil
+		}
+		return attributeValues(v[path[0]], path[1:])
+	case nil:
+		return nil
+	}
+	if len(path) > 0 {
+		return nil
+	}
+	return []any{value}
+}
+
+// budgetRulesCheck fails a plan that breaks the budget rules.
+func budgetRulesCheck(rules *BudgetRules) planCheck {
+	return planCheck{
+		operation: "budget rules",
+		run: func(ctx context.Context, t testing.TB, m *Module, planJSON []byte) error {
+			violations, err := rules.Violations(planJSON)
+			if err != nil {
+				return err
+			}
+			if len(violations) > 0 {
+				return fmt.Errorf("%d budget rule(s) broken: %s", len(violations), strings.Join(violations, "; "))
+			}
+			return nil
+		},
+	}
+}
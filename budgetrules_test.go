@@ -0,0 +1,135 @@
+package validor
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const budgetRulesYAML = `
+resources:
+  azurerm_kubernetes_cluster:
+    max_count: 1
+    banned:
+      default_node_pool.vm_size: ["Standard_M*", "Standard_ND*"]
+    max:
+      default_node_pool.node_count: 5
+  "*":
+    banned:
+      sku_name: [premium]
+`
+
+func TestParseBudgetRules(t *testing.T) {
+	rules, err := ParseBudgetRules([]byte(budgetRulesYAML))
+	if err != nil {
+		t.Fatalf("ParseBudgetRules() error = %v", err)
+	}
+	if got := *rules.Resources["azurerm_kubernetes_cluster"].MaxCount; got != 1 {
+		t.Errorf("max_count = %d, want 1", got)
+	}
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "unknown key", yaml: "resources:\n  azurerm_kubernetes_cluster:\n    max_cuont: 1\n", wantErr: "max_cuont"},
+		{name: "invalid type pattern", yaml: "resources:\n  \"azurerm_[\":\n    max_count: 1\n", wantErr: "invalid pattern"},
+		{name: "invalid value pattern", yaml: "resources:\n  azurerm_linux_virtual_machine:\n    banned:\n      size: [\"Standard_[\"]\n", wantErr: "invalid pattern"},
+		{name: "negative count", yaml: "resources:\n  azurerm_linux_virtual_machine:\n    max_count: -1\n", wantErr: "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseBudgetRules([]byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseBudgetRules() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBudgetRules_Violations(t *testing.T) {
+	rules, err := ParseBudgetRules([]byte(budgetRulesYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		plan string
+		want []string
+	}{
+		{
+			name: "within the rules",
+			plan: `{"resource_changes":[
+				{"address":"azurerm_kubernetes_cluster.aks","type":"azurerm_kubernetes_cluster","change":{"actions":["create"],"after":{"default_node_pool":[{"vm_size":"Standard_D2s_v5","node_count":3}]}}},
+				{"address":"azurerm_key_vault.kv","type":"azurerm_key_vault","change":{"actions":["create"],"after":{"sku_name":"standard"}}}
+			]}`,
+		},
+		{
+			name: "broken rules",
+			plan: `{"resource_changes":[
+				{"address":"azurerm_kubernetes_cluster.aks[\"a\"]","type":"azurerm_kubernetes_cluster","change":{"actions":["create"],"after":{"default_node_pool":[{"vm_size":"standard_m128s","node_count":100}]}}},
+				{"address":"azurerm_kubernetes_cluster.aks[\"b\"]","type":"azurerm_kubernetes_cluster","change":{"actions":["create"],"after":{"default_node_pool":[{"vm_size":null,"node_count":2}]},"after_unknown":{"default_node_pool":[{"vm_size":true}]}}},
+				{"address":"azurerm_key_vault.kv","type":"azurerm_key_vault","change":{"actions":["update"],"after":{"sku_name":"Premium"}}}
+			]}`,
+			want: []string{
+				`azurerm_key_vault.kv sets sku_name to banned value "Premium"`,
+				`azurerm_kubernetes_cluster.aks["a"] sets default_node_pool.vm_size to banned value "standard_m128s"`,
+				`azurerm_kubernetes_cluster.aks["a"] sets default_node_pool.node_count to 100, more than the 5 allowed`,
+				`plan creates 2 azurerm_kubernetes_cluster resources, more than the 1 allowed: azurerm_kubernetes_cluster.aks["a"], azurerm_kubernetes_cluster.aks["b"]`,
+			},
+		},
+		{
+			name: "existing resources are not counted",
+			plan: `{"resource_changes":[
+				{"address":"azurerm_kubernetes_cluster.a","type":"azurerm_kubernetes_cluster","change":{"actions":["update"],"after":{}}},
+				{"address":"azurerm_kubernetes_cluster.b","type":"azurerm_kubernetes_cluster","change":{"actions":["create"],"after":{}}}
+			]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rules.Violations([]byte(tt.plan))
+			if err != nil {
+				t.Fatalf("Violations() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Violations() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudgetRules_BlockApply(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"budget.yaml": budgetRulesYAML})
+	rules, err := LoadBudgetRules(filepath.Join(dir, "budget.yaml"))
+	if err != nil {
+		t.Fatalf("LoadBudgetRules() error = %v", err)
+	}
+	if _, err := LoadBudgetRules(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing rules file")
+	}
+
+	module := newPlannedModule(t, "default")
+	module.planHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+		return []byte(`{"resource_changes":[{"address":"azurerm_key_vault.kv","type":"azurerm_key_vault","change":{"actions":["create"],"after":{"sku_name":"premium"}}}]}`), nil
+	}
+	applied := false
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+		applied = true
+		return nil
+	}
+	module.planChecks = []planCheck{budgetRulesCheck(rules)}
+
+	err = module.Apply(context.Background(), &recordingTB{TB: t})
+	if err == nil || !strings.Contains(err.Error(), "1 budget rule(s) broken") {
+		t.Fatalf("Apply() error = %v, want a broken budget rule", err)
+	}
+	if applied {
+		t.Error("the example should not be applied")
+	}
+}
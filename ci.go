@@ -0,0 +1,158 @@
+package validor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const (
+	CITeamCity  = "teamcity"
+	CIBuildkite = "buildkite"
+)
+
+// WithCIAdapter reports module progress in the format of a CI system's UI:
+// TeamCity service messages or Buildkite log groups and annotations.
+func WithCIAdapter(name string) Option {
+	return func(c *Config) { c.CIAdapter = name }
+}
+
+// NewCIAdapter returns the observer behind WithCIAdapter.
+func NewCIAdapter(name string) (Observer, error) {
+	switch name {
+	case CITeamCity:
+		return &teamCityAdapter{out: ciOutput, started: make(map[string]bool)}, nil
+	case CIBuildkite:
+		return &buildkiteAdapter{out: ciOutput, annotate: buildkiteAnnotate}, nil
+	default:
+		return nil, fmt.Errorf("unknown CI adapter %q (supported: %s, %s)", name, CITeamCity, CIBuildkite)
+	}
+}
+
+// CI systems read these from the job's stdout as they are printed, which the
+// test log of a parallel subtest is not until it finishes.
+var ciOutput io.Writer = os.Stdout
+
+// teamCityAdapter emits service messages that turn every example into a test
+// of the build. The flow id keeps the messages of parallel examples apart.
+type teamCityAdapter struct {
+	NopObserver
+	mu      sync.Mutex
+	out     io.Writer
+	started map[string]bool
+}
+
+func (a *teamCityAdapter) message(name string, attributes ...string) {
+	parts := []string{"##teamcity[" + name}
+	for i := 0; i+1 < len(attributes); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s='%s'", attributes[i], teamCityEscape(attributes[i+1])))
+	}
+	fmt.Fprintln(a.out, strings.Join(parts, " ")+"]")
+}
+
+func (a *teamCityAdapter) OnRunStart(ctx context.Context, event RunStartEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.message("testSuiteStarted", "name", "validor")
+}
+
+func (a *teamCityAdapter) OnModuleStageStart(ctx context.Context, event StageEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.start(event.Module)
+}
+
+func (a *teamCityAdapter) start(module string) {
+	if a.started[module] {
+		return
+	}
+	a.started[module] = true
+	a.message("testStarted", "name", module, "flowId", module)
+}
+
+func (a *teamCityAdapter) OnModuleComplete(ctx context.Context, module ModuleReport) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.start(module.Name)
+	switch {
+	case module.Skipped:
+		a.message("testIgnored", "name", module.Name, "flowId", module.Name, "message", module.SkipReason)
+	case !module.Passed:
+		a.message("testFailed", "name", module.Name, "flowId", module.Name, "message", firstError(module), "details", strings.Join(module.Errors, "\n"))
+	}
+	a.message("testFinished", "name", module.Name, "flowId", module.Name, "duration", fmt.Sprint(module.Duration.Milliseconds()))
+}
+
+func (a *teamCityAdapter) OnRunComplete(ctx context.Context, report *RunReport) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.message("testSuiteFinished", "name", "validor")
+}
+
+func teamCityEscape(value string) string {
+	return strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]").Replace(value)
+}
+
+// buildkiteAdapter opens a log group per example and annotates the build with
+// every failed example and the run's outcome.
+type buildkiteAdapter struct {
+	NopObserver
+	mu       sync.Mutex
+	out      io.Writer
+	annotate func(ctx context.Context, name, style, body string) error
+}
+
+func (a *buildkiteAdapter) OnModuleStageStart(ctx context.Context, event StageEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fmt.Fprintf(a.out, "--- :terraform: %s %s\n", event.Module, event.Stage)
+}
+
+func (a *buildkiteAdapter) OnModuleComplete(ctx context.Context, module ModuleReport) {
+	if module.Skipped || module.Passed {
+		return
+	}
+	a.mu.Lock()
+	// Expands the group that was open when the example failed.
+	fmt.Fprintf(a.out, "^^^ +++\n+++ :x: %s failed\n", module.Name)
+	a.mu.Unlock()
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "**%s** failed\n\n```\n%s\n```\n", module.Name, strings.Join(module.Errors, "\n"))
+	a.warn(a.annotate(ctx, "validor-"+module.Name, "error", body.String()))
+}
+
+func (a *buildkiteAdapter) OnRunComplete(ctx context.Context, report *RunReport) {
+	failed := report.Failed()
+	style, body := "success", fmt.Sprintf("All %d examples passed", len(report.Modules))
+	if len(failed) > 0 {
+		names := make([]string, len(failed))
+		for i, module := range failed {
+			names[i] = module.Name
+		}
+		style, body = "error", fmt.Sprintf("%d of %d examples failed: %s", len(failed), len(report.Modules), strings.Join(names, ", "))
+	}
+	a.warn(a.annotate(ctx, "validor", style, body))
+}
+
+func (a *buildkiteAdapter) warn(err error) {
+	if err == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fmt.Fprintf(a.out, "Warning: failed to annotate the build: %v\n", err)
+}
+
+var buildkiteAnnotate = func(ctx context.Context, name, style, body string) error {
+	cmd := exec.CommandContext(ctx, "buildkite-agent", "annotate", "--context", name, "--style", style)
+	cmd.Stdin = strings.NewReader(body)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewCIAdapter_Unknown(t *testing.T) {
+	if _, err := NewCIAdapter("jenkins"); err == nil {
+		t.Error("expected an error for an unknown adapter")
+	}
+}
+
+func TestTeamCityAdapter(t *testing.T) {
+	var output bytes.Buffer
+	adapter := &teamCityAdapter{out: &output, started: make(map[string]bool)}
+	ctx := context.Background()
+
+	adapter.OnRunStart(ctx, RunStartEvent{Modules: []string{"complete", "default"}})
+	adapter.OnModuleStageStart(ctx, StageEvent{Module: "complete", Stage: StageInit})
+	adapter.OnModuleStageStart(ctx, StageEvent{Module: "complete", Stage: StageApply})
+	adapter.OnModuleComplete(ctx, ModuleReport{Name: "complete", Errors: []string{"apply failed: [quota] isn't\nenough"}})
+	adapter.OnModuleComplete(ctx, ModuleReport{Name: "private", Passed: true, Skipped: true, SkipReason: "in the exception list"})
+	adapter.OnRunComplete(ctx, &RunReport{})
+
+	want := []string{
+		"##teamcity[testSuiteStarted name='validor']",
+		"##teamcity[testStarted name='complete' flowId='complete']",
+		"##teamcity[testFailed name='complete' flowId='complete' message='apply failed: |[quota|] isn|'t|nenough' details='apply failed: |[quota|] isn|'t|nenough']",
+		"##teamcity[testFinished name='complete' flowId='complete' duration='0']",
+		"##teamcity[testStarted name='private' flowId='private']",
+		"##teamcity[testIgnored name='private' flowId='private' message='in the exception list']",
+		"##teamcity[testFinished name='private' flowId='private' duration='0']",
+		"##teamcity[testSuiteFinished name='validor']",
+	}
+	if got := strings.Split(strings.TrimSpace(output.String()), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("messages =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildkiteAdapter(t *testing.T) {
+	type annotation struct{ context, style, body string }
+	var output bytes.Buffer
+	var annotations []annotation
+	adapter := &buildkiteAdapter{out: &output, annotate: func(ctx context.Context, name, style, body string) error {
+		annotations = append(annotations, annotation{name, style, body})
+		if name == "validor" {
+			return errors.New("agent not found")
+		}
+		return nil
+	}}
+	ctx := context.Background()
+
+	adapter.OnModuleStageStart(ctx, StageEvent{Module: "complete", Stage: StageApply})
+	adapter.OnModuleComplete(ctx, ModuleReport{Name: "default", Passed: true})
+	failed := ModuleReport{Name: "complete", Errors: []string{"apply failed"}}
+	adapter.OnModuleComplete(ctx, failed)
+	adapter.OnRunComplete(ctx, &RunReport{Modules: []ModuleReport{failed, {Name: "default", Passed: true}}})
+
+	for _, want := range []string{
+		"--- :terraform: complete apply\n",
+		"^^^ +++\n+++ :x: complete failed\n",
+		"Warning: failed to annotate the build: agent not found\n",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, output.String())
+		}
+	}
+
+	wantAnnotations := []annotation{
+		{"validor-complete", "error", "**complete** failed\n\n```\napply failed\n```\n"},
+		{"validor", "error", "1 of 2 examples failed: complete"},
+	}
+	if !reflect.DeepEqual(annotations, wantAnnotations) {
+		t.Errorf("annotations = %+v, want %+v", annotations, wantAnnotations)
+	}
+}
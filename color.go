@@ -0,0 +1,83 @@
+package validor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// ColorFunc styles a message for the test log.
+type ColorFunc func(a ...any) string
+
+// WithColor forces colored output on or off. Without it output is colored
+// unless NO_COLOR is set or the output is not a terminal.
+func WithColor(enabled bool) Option {
+	return func(c *Config) { c.Color = &enabled }
+}
+
+func WithErrorColor(fn ColorFunc) Option {
+	return func(c *Config) { c.ErrorColor = fn }
+}
+
+func WithSuccessColor(fn ColorFunc) Option {
+	return func(c *Config) { c.SuccessColor = fn }
+}
+
+var (
+	colorMu      sync.RWMutex
+	errorColor   ColorFunc = color.New(color.FgHiRed, color.Bold).SprintFunc()
+	successColor ColorFunc = color.New(color.FgHiGreen).SprintFunc()
+)
+
+func errorText(a ...any) string {
+	colorMu.RLock()
+	defer colorMu.RUnlock()
+	return errorColor(a...)
+}
+
+func successText(a ...any) string {
+	colorMu.RLock()
+	defer colorMu.RUnlock()
+	return successColor(a...)
+}
+
+// useColors sets the colors for the output of the run. Disabling color, or
+// NO_COLOR without an explicit WithColor, also drops custom color functions.
+func useColors(config *Config) {
+	errorFn, successFn := config.ErrorColor, config.SuccessColor
+	if errorFn == nil {
+		errorFn = colorFunc(config.Color, color.FgHiRed, color.Bold)
+	}
+	if successFn == nil {
+		successFn = colorFunc(config.Color, color.FgHiGreen)
+	}
+	if enabled := config.Color; (enabled != nil && !*enabled) || (enabled == nil && os.Getenv("NO_COLOR") != "") {
+		errorFn, successFn = fmt.Sprint, fmt.Sprint
+	}
+
+	colorMu.Lock()
+	defer colorMu.Unlock()
+	errorColor, successColor = errorFn, successFn
+}
+
+func colorFunc(enabled *bool, attributes ...color.Attribute) ColorFunc {
+	c := color.New(attributes...)
+	if enabled != nil && *enabled {
+		c.EnableColor()
+	}
+	return c.SprintFunc()
+}
+
+func colorFlag(target **bool) func(string) error {
+	return func(value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*target = &enabled
+		return nil
+	}
+}
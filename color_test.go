@@ -0,0 +1,58 @@
+package validor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUseColors(t *testing.T) {
+	origError, origSuccess := errorColor, successColor
+	t.Cleanup(func() { errorColor, successColor = origError, origSuccess })
+
+	on, off := true, false
+	brackets := func(a ...any) string { return "[" + a[0].(string) + "]" }
+
+	tests := []struct {
+		name        string
+		config      *Config
+		noColor     string
+		colored     bool
+		wantError   string
+		wantSuccess string
+	}{
+		{name: "forced on", config: &Config{Color: &on}, colored: true},
+		{name: "forced on despite NO_COLOR", config: &Config{Color: &on}, noColor: "1", colored: true},
+		{name: "forced off", config: &Config{Color: &off, ErrorColor: brackets}, wantError: "failed", wantSuccess: "passed"},
+		{name: "NO_COLOR", config: &Config{ErrorColor: brackets}, noColor: "1", wantError: "failed", wantSuccess: "passed"},
+		{name: "custom functions", config: &Config{Color: &on, ErrorColor: brackets, SuccessColor: brackets}, wantError: "[failed]", wantSuccess: "[passed]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			useColors(tt.config)
+
+			gotError, gotSuccess := errorText("failed"), successText("passed")
+			if tt.colored {
+				if !strings.Contains(gotError, "\x1b[") || !strings.Contains(gotSuccess, "\x1b[") {
+					t.Errorf("expected colored output, got %q and %q", gotError, gotSuccess)
+				}
+				return
+			}
+			if gotError != tt.wantError || gotSuccess != tt.wantSuccess {
+				t.Errorf("got %q and %q, want %q and %q", gotError, gotSuccess, tt.wantError, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestColorFlag(t *testing.T) {
+	config := NewConfig()
+	set := colorFlag(&config.Color)
+	if err := set("false"); err != nil || config.Color == nil || *config.Color {
+		t.Errorf("-color=false gave %v, %v", config.Color, err)
+	}
+	if err := set("maybe"); err == nil {
+		t.Error("expected an error for an invalid value")
+	}
+}
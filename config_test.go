@@ -1,8 +1,10 @@
 package validor
 
 import (
+	"flag"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestConfig_ParseExceptionList(t *testing.T) {
@@ -64,7 +66,7 @@ func TestNewConfig(t *testing.T) {
 				Example:       "",
 				Local:         false,
 				ExceptionList: nil,
-				Namespace:     "",
+				Namespace:     "cloudnationhq",
 				ExamplesPath:  "",
 			},
 		},
@@ -140,10 +142,60 @@ func TestNewConfig(t *testing.T) {
 			if tt.want.ExceptionList != nil && !reflect.DeepEqual(got.ExceptionList, tt.want.ExceptionList) {
 				t.Errorf("ExceptionList = %v, want %v", got.ExceptionList, tt.want.ExceptionList)
 			}
+			if tt.want.Namespace != "" && got.Namespace != tt.want.Namespace {
+				t.Errorf("Namespace = %v, want %v", got.Namespace, tt.want.Namespace)
+			}
 		})
 	}
 }
 
+func TestRegisterFlags(t *testing.T) {
+	originalConfig := globalConfig
+	defer func() { globalConfig = originalConfig }()
+	globalConfig = NewConfig(WithExample("preset"))
+
+	fs := flag.NewFlagSet("validor", flag.ContinueOnError)
+	RegisterFlags(fs)
+	RegisterFlags(fs)
+
+	if got := fs.Lookup("example").DefValue; got != "preset" {
+		t.Errorf("example default = %q, want value set before registration", got)
+	}
+	if got := fs.Lookup("namespace").DefValue; got != "cloudnationhq" {
+		t.Errorf("namespace default = %q, want cloudnationhq", got)
+	}
+
+	args := []string{"-example=default,complete", "-skip-destroy", "-matrix=location=westeurope,eastus", "-drift-wait=2m"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	config := GetConfig()
+	if config.Example != "default,complete" || !config.SkipDestroy {
+		t.Errorf("flags not bound to shared config: %+v", config)
+	}
+	if !reflect.DeepEqual(config.Matrix["location"], []string{"westeurope", "eastus"}) {
+		t.Errorf("Matrix = %v", config.Matrix)
+	}
+	if config.DriftWait != 2*time.Minute {
+		t.Errorf("DriftWait = %v", config.DriftWait)
+	}
+}
+
+func TestGetConfig_Lazy(t *testing.T) {
+	originalConfig := globalConfig
+	defer func() { globalConfig = originalConfig }()
+	globalConfig = nil
+
+	config := GetConfig()
+	if config == nil || config != GetConfig() {
+		t.Fatal("GetConfig should create the shared config once")
+	}
+	if config.Namespace != "cloudnationhq" || config.ScanSeverity != SeverityHigh || config.PolicyQuery != defaultPolicyQuery {
+		t.Errorf("unexpected defaults: %+v", config)
+	}
+}
+
 func TestWithOptions(t *testing.T) {
 	t.Run("WithSkipDestroy", func(t *testing.T) {
 		c := &Config{}
@@ -216,3 +268,46 @@ func TestGetExamplesPath(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_VersionPins(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantDefault string
+		wantPins    map[string]string
+	}{
+		{
+			name:     "no pins",
+			wantPins: map[string]string{},
+		},
+		{
+			name:        "default pin",
+			opts:        []Option{WithPinnedVersion("1.8.0")},
+			wantDefault: "1.8.0",
+			wantPins:    map[string]string{},
+		},
+		{
+			name:        "flag style module pins",
+			opts:        []Option{WithPinnedVersion("1.8.0, vnet=2.0.0 ,storage=3.1.0")},
+			wantDefault: "1.8.0",
+			wantPins:    map[string]string{"vnet": "2.0.0", "storage": "3.1.0"},
+		},
+		{
+			name:     "option module pins override flag",
+			opts:     []Option{WithPinnedVersion("vnet=2.0.0"), WithModulePinnedVersion("vnet", "2.1.0")},
+			wantPins: map[string]string{"vnet": "2.1.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotDefault, gotPins := NewConfig(tt.opts...).versionPins()
+			if gotDefault != tt.wantDefault {
+				t.Errorf("versionPins() default = %q, want %q", gotDefault, tt.wantDefault)
+			}
+			if !reflect.DeepEqual(gotPins, tt.wantPins) {
+				t.Errorf("versionPins() pins = %v, want %v", gotPins, tt.wantPins)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// WithContainerImage runs terraform inside a container of image, such as
+// hashicorp/terraform:1.9, instead of a terraform binary on the host, so test
+// environments do not need terraform installed and its version is pinned by
+// the image. The image's entrypoint is called with the terraform arguments.
+func WithContainerImage(image string) Option {
+	return func(c *Config) { c.ContainerImage = image }
+}
+
+// WithContainerRuntime sets the command that runs containers for
+// WithContainerImage: docker, the default, or podman.
+func WithContainerRuntime(runtime string) Option {
+	return func(c *Config) { c.ContainerRuntime = runtime }
+}
+
+// containerEnvPrefixes are the prefixes of the environment variables passed
+// into the container: terraform's own, TF_VAR_ variables and the credentials
+// of the azurerm, aws and google providers.
+var containerEnvPrefixes = []string{"TF", "ARM", "AZURE", "AWS", "GOOGLE", "CLOUDSDK", "CHECKPOINT"}
+
+func (c *Config) containerRuntime() string {
+	if c.ContainerRuntime != "" {
+		return c.ContainerRuntime
+	}
+	return "docker"
+}
+
+// containerCheck verifies that the container runtime is available, in place
+// of the terraform binary.
+func containerCheck(runtime string) PreflightCheck {
+	return PreflightCheck{
+		Name: "container runtime",
+		Run: func(ctx context.Context) error {
+			if _, err := lookPath(runtime); err != nil {
+				return fmt.Errorf("%s not found on PATH", runtime)
+			}
+			return nil
+		},
+	}
+}
+
+// containerRun runs terraform in a container, with the directories in mounts
+// mounted at the same path so the absolute paths of examples, local module
+// sources, plugin caches and CLI configurations resolve inside it.
+type containerRun struct {
+	runtime string
+	image   string
+	mounts  []string
+}
+
+// containerMounts resolves dirs to absolute paths and drops those inside
+// another one. Directories inside a git repository are replaced by the
+// repository, so local sources that point outside the module resolve too.
+func containerMounts(dirs []string) []string {
+	var mounts []string
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		mounts = append(mounts, repositoryRoot(abs))
+	}
+	slices.Sort(mounts)
+	mounts = slices.Compact(mounts)
+	return slices.DeleteFunc(mounts, func(mount string) bool {
+		return slices.ContainsFunc(mounts, func(other string) bool {
+			return other != mount && strings.HasPrefix(mount, strings.TrimSuffix(other, string(filepath.Separator))+string(filepath.Separator))
+		})
+	})
+}
+
+// repositoryRoot returns the nearest directory above or at dir that contains
+// .git, or dir when there is none.
+func repositoryRoot(dir string) string {
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+// writeScript writes a script to dir that runs terraform in the container
+// through wrapper, from the directory it is called in. Environment variables
+// with one of containerEnvPrefixes are passed in, and files are written as the
+// calling user.
+func (c *containerRun) writeScript(dir string, wrapper []string) (string, error) {
+	if goos == "windows" {
+		return "", errors.New("running terraform in a container needs a POSIX shell")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	command := append(append([]string{}, wrapper...), c.runtime, "run", "--rm", "-i")
+	if filepath.Base(c.runtime) == "podman" {
+		command = append(command, "--userns=keep-id")
+	}
+	for _, mount := range c.mounts {
+		command = append(command, "-v", mount+":"+mount)
+	}
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = shellQuote(arg)
+	}
+	if filepath.Base(c.runtime) != "podman" {
+		quoted = append(quoted, `--user "$(id -u):$(id -g)"`)
+	}
+	quoted = append(quoted, `-w "$PWD"`, `"$@"`)
+
+	// The terraform arguments are moved behind the environment options and the
+	// image, as a POSIX shell has no arrays.
+	script := "#!/bin/sh\n" +
+		"n=$#\n" +
+		"for name in $(env | sed -n 's/^\\([A-Za-z_][A-Za-z0-9_]*\\)=.*/\\1/p' | grep -E '^(" + strings.Join(containerEnvPrefixes, "|") + ")_'); do\n" +
+		"\tset -- \"$@\" -e \"$name\"\n" +
+		"done\n" +
+		"set -- \"$@\" " + shellQuote(c.image) + "\n" +
+		"while [ \"$n\" -gt 0 ]; do\n" +
+		"\tset -- \"$@\" \"$1\"\n" +
+		"\tshift\n" +
+		"\tn=$((n - 1))\n" +
+		"done\n" +
+		"exec " + strings.Join(quoted, " ") + "\n"
+
+	path := filepath.Join(dir, "terraform")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
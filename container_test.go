@@ -0,0 +1,109 @@
+package validor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestContainerMounts(t *testing.T) {
+	repo := t.TempDir()
+	writeFiles(t, repo, map[string]string{
+		".git/HEAD":                       "ref: refs/heads/main\n",
+		"modules/vnet/examples/a/main.tf": "",
+	})
+	other := t.TempDir()
+
+	got := containerMounts([]string{filepath.Join(repo, "modules", "vnet"), "", other, filepath.Join(other, "plugin-cache"), filepath.Join(repo, "modules")})
+	want := []string{repo, other}
+	slices.Sort(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("containerMounts() = %v, want %v", got, want)
+	}
+}
+
+func TestContainerRun_WriteScript(t *testing.T) {
+	if goos == "windows" {
+		t.Skip("container scripts need a POSIX shell")
+	}
+	dir := t.TempDir()
+	docker := filepath.Join(dir, "docker")
+	if err := os.WriteFile(docker, []byte("#!/bin/sh\nfor arg in \"$@\"; do echo \"$arg\"; done\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	podman := filepath.Join(dir, "podman")
+	if err := os.Symlink(docker, podman); err != nil {
+		t.Fatal(err)
+	}
+	id, err := exec.Command("sh", "-c", "echo $(id -u):$(id -g)").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	example, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		runtime string
+		options []string
+	}{
+		{name: "docker", runtime: docker, options: []string{"-v", "/repo:/repo", "--user", strings.TrimSpace(string(id))}},
+		{name: "podman", runtime: podman, options: []string{"--userns=keep-id", "-v", "/repo:/repo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := &containerRun{runtime: tt.runtime, image: "hashicorp/terraform:1.9", mounts: []string{"/repo"}}
+			script, err := container.writeScript(t.TempDir(), []string{"env"})
+			if err != nil {
+				t.Fatalf("writeScript() error = %v", err)
+			}
+
+			cmd := exec.Command(script, "plan", "-var", "name=a b")
+			cmd.Dir = example
+			cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=/home/ci", "TF_VAR_location=westeurope", "ARM_CLIENT_ID=abc"}
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("script error = %v", err)
+			}
+			got := strings.Split(strings.TrimSpace(string(out)), "\n")
+
+			want := slices.Concat([]string{"run", "--rm", "-i"}, tt.options, []string{"-w", example})
+			if len(got) < len(want) || !reflect.DeepEqual(got[:len(want)], want) {
+				t.Fatalf("arguments = %q, want them to start with %q", got, want)
+			}
+			env := got[len(want) : len(got)-4]
+			slices.Sort(env)
+			if want := []string{"-e", "-e", "ARM_CLIENT_ID", "TF_VAR_location"}; !reflect.DeepEqual(env, want) {
+				t.Errorf("environment options = %q, want %q", env, want)
+			}
+			if tail, want := got[len(got)-4:], []string{"hashicorp/terraform:1.9", "plan", "-var", "name=a b"}; !reflect.DeepEqual(tail, want) {
+				t.Errorf("arguments end with %q, want %q", tail, want)
+			}
+		})
+	}
+}
+
+func TestConfig_ExecWrappers(t *testing.T) {
+	if wrappers := (&Config{}).execWrappers(t.TempDir()); wrappers != nil {
+		t.Errorf("execWrappers() = %+v, want nil without a wrapper or image", wrappers)
+	}
+
+	config := NewConfig(WithContainerImage("hashicorp/terraform:1.9"), WithContainerRuntime("podman"), WithExamplesPath(filepath.Join(t.TempDir(), "examples")))
+	wrappers := config.execWrappers(t.TempDir())
+	if wrappers == nil || wrappers.container == nil || wrappers.container.runtime != "podman" {
+		t.Fatalf("execWrappers() = %+v, want a podman container", wrappers)
+	}
+	if want := containerMounts([]string{filepath.Dir(config.ExamplesPath), os.TempDir()}); !reflect.DeepEqual(wrappers.container.mounts, want) {
+		t.Errorf("mounts = %v, want %v", wrappers.container.mounts, want)
+	}
+	if checks := preflightChecks(config, nil, "", nil); len(checks) != 1 || checks[0].Name != "container runtime" {
+		t.Errorf("preflight checks = %+v, want the container runtime instead of terraform", checks)
+	}
+}
@@ -2,128 +2,561 @@ package validor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/sync/errgroup"
 )
 
 type DefaultSourceConverter struct {
 	registryClient RegistryClient
+	dryRunOutput   io.Writer
+	pinnedVersion  string
+	modulePins     map[string]string
+	style          ConstraintStyle
+	mappings       []ModuleMapping
+	workers        int
 }
 
-func NewSourceConverter(client RegistryClient) SourceConverter {
-	return &DefaultSourceConverter{
+// defaultConversionWorkers is how many files ConvertToLocal and
+// RevertToRegistry handle at once unless WithConversionWorkers says otherwise.
+const defaultConversionWorkers = 8
+
+// ModuleMapping tells the converter how to handle another registry module an
+// example calls besides the module under test. With Root set its sources are
+// pointed at that local path, with Version they keep the registry source and
+// are pinned to it. Namespace defaults to the one of the module under test.
+type ModuleMapping struct {
+	ModuleInfo
+	Version string
+}
+
+// ConstraintStyle selects the version constraint RevertToRegistry writes back
+// to a registry source.
+type ConstraintStyle string
+
+const (
+	// ConstraintStylePreserve keeps the operator of the original constraint
+	// and only bumps its version. It is the default.
+	ConstraintStylePreserve    ConstraintStyle = "preserve"
+	ConstraintStylePessimistic ConstraintStyle = "pessimistic"
+	ConstraintStyleMinimum     ConstraintStyle = "minimum"
+	ConstraintStyleExact       ConstraintStyle = "exact"
+)
+
+var constraintStyles = []ConstraintStyle{ConstraintStylePreserve, ConstraintStylePessimistic, ConstraintStyleMinimum, ConstraintStyleExact}
+
+// constraintPattern matches a single constraint such as "~> 1.2", ">= 1.0.0"
+// or "1.2.3".
+var constraintPattern = regexp.MustCompile(`^\s*(~>|>=|<=|!=|>|<|=)?\s*v?\d+(?:\.\d+)*(?:-[0-9A-Za-z.-]+)?\s*$`)
+
+type ConverterOption func(*DefaultSourceConverter)
+
+// WithDryRunOutput makes ConvertToLocal write a unified diff of each rewrite
+// to w instead of modifying any files.
+func WithDryRunOutput(w io.Writer) ConverterOption {
+	return func(c *DefaultSourceConverter) { c.dryRunOutput = w }
+}
+
+// WithVersionPins makes RevertToRegistry write exact versions instead of
+// resolving the latest release. modulePins is keyed by module name and takes
+// precedence over defaultVersion.
+func WithVersionPins(defaultVersion string, modulePins map[string]string) ConverterOption {
+	return func(c *DefaultSourceConverter) {
+		c.pinnedVersion = defaultVersion
+		c.modulePins = modulePins
+	}
+}
+
+// WithConstraintStyle makes RevertToRegistry write every constraint in style
+// instead of preserving the operator of the original one.
+func WithConstraintStyle(style ConstraintStyle) ConverterOption {
+	return func(c *DefaultSourceConverter) { c.style = style }
+}
+
+// WithModuleMappings makes ConvertToLocal also convert or pin the sources of
+// the given modules. The files are restored to their original sources on
+// revert.
+func WithModuleMappings(mappings ...ModuleMapping) ConverterOption {
+	return func(c *DefaultSourceConverter) { c.mappings = append(c.mappings, mappings...) }
+}
+
+// moduleMappingFlag parses NAMESPACE/NAME/PROVIDER=PATH or =VERSION; values
+// that start with "." or "/" are paths.
+func moduleMappingFlag(c *Config) func(string) error {
+	return func(value string) error {
+		source, target, ok := strings.Cut(value, "=")
+		parts := strings.Split(strings.TrimSpace(source), "/")
+		target = strings.TrimSpace(target)
+		if !ok || len(parts) != 3 || slices.Contains(parts, "") || target == "" {
+			return fmt.Errorf("invalid value %q, expected NAMESPACE/NAME/PROVIDER=PATH or NAMESPACE/NAME/PROVIDER=VERSION", value)
+		}
+		mapping := ModuleMapping{ModuleInfo: ModuleInfo{Namespace: parts[0], Name: parts[1], Provider: parts[2]}}
+		if strings.HasPrefix(target, ".") || filepath.IsAbs(target) {
+			mapping.Root = target
+		} else {
+			mapping.Version = target
+		}
+		c.ModuleMappings = append(c.ModuleMappings, mapping)
+		return nil
+	}
+}
+
+// WithConversionWorkers sets how many files are converted or reverted at once.
+func WithConversionWorkers(n int) ConverterOption {
+	return func(c *DefaultSourceConverter) { c.workers = n }
+}
+
+// constraintStyleFlag parses -constraint-style.
+func constraintStyleFlag(c *Config) func(string) error {
+	return func(value string) error {
+		style := ConstraintStyle(value)
+		if !slices.Contains(constraintStyles, style) {
+			return fmt.Errorf("invalid value %q, expected preserve, pessimistic, minimum or exact", value)
+		}
+		c.ConstraintStyle = style
+		return nil
+	}
+}
+
+func NewSourceConverter(client RegistryClient, opts ...ConverterOption) SourceConverter {
+	converter := &DefaultSourceConverter{
 		registryClient: client,
 	}
+	for _, opt := range opts {
+		opt(converter)
+	}
+	return converter
 }
 
 func (c *DefaultSourceConverter) ConvertToLocal(ctx context.Context, modulePath string, moduleInfo ModuleInfo) ([]FileRestore, error) {
+	files, err := findTerraformFiles(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find terraform files: %w", err)
+	}
+
+	root := moduleInfo.Root
+	if root == "" {
+		root = filepath.Join(modulePath, "..", "..")
+	}
+
+	restores := make([]*FileRestore, len(files))
+	diffs := make([]string, len(files))
+	errs := make([]error, len(files))
+	var group errgroup.Group
+	group.SetLimit(c.workerLimit())
+	for i, file := range files {
+		group.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return nil
+			}
+			restores[i], diffs[i], errs[i] = c.convertFile(file, root, moduleInfo)
+			return nil
+		})
+	}
+	group.Wait()
+
 	var filesToRestore []FileRestore
+	for _, restore := range restores {
+		if restore != nil {
+			filesToRestore = append(filesToRestore, *restore)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return filesToRestore, err
+	}
+	if c.dryRunOutput != nil {
+		for _, diff := range diffs {
+			if _, err := io.WriteString(c.dryRunOutput, diff); err != nil {
+				return filesToRestore, err
+			}
+		}
+	}
+	return filesToRestore, errors.Join(errs...)
+}
 
-	files, err := filepath.Glob(filepath.Join(modulePath, "*.tf"))
+// convertFile points the registry sources of moduleInfo in file at the local
+// module below root. It returns the original content to restore, or the diff
+// of the change in a dry run; both are empty when nothing changed.
+func (c *DefaultSourceConverter) convertFile(file, root string, moduleInfo ModuleInfo) (*FileRestore, string, error) {
+	content, err := os.ReadFile(file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find terraform files: %w", err)
+		return nil, "", nil
+	}
+
+	originalContent := string(content)
+	parsedFile, diags := hclwrite.ParseConfig(content, file, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, "", fmt.Errorf("failed to parse %s: %s", file, diags.Error())
+	}
+
+	localSource, err := relativeSource(filepath.Dir(file), root)
+	if err != nil {
+		return nil, "", err
 	}
 
 	moduleSource := fmt.Sprintf("%s/%s/%s", moduleInfo.Namespace, moduleInfo.Name, moduleInfo.Provider)
-	submodulePattern := fmt.Sprintf(`^%s/%s/%s//modules/(.*)$`,
-		regexp.QuoteMeta(moduleInfo.Namespace),
-		regexp.QuoteMeta(moduleInfo.Name),
-		regexp.QuoteMeta(moduleInfo.Provider))
-	submoduleRegex := regexp.MustCompile(submodulePattern)
-
-	for _, file := range files {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
+	var changed, mapped bool
+	if isTerragruntFile(file) {
+		changed = updateTerragruntSources(parsedFile.Body(), moduleSource, localSource)
+	} else {
+		changed = c.updateModuleBlocks(parsedFile.Body(), moduleSource, submodulePattern(moduleInfo), localSource)
+		mapped, err = c.applyMappings(parsedFile.Body(), filepath.Dir(file), moduleInfo.Namespace)
+		if err != nil {
+			return nil, "", err
 		}
+	}
+	if !changed && !mapped {
+		return nil, "", nil
+	}
 
-		content, err := os.ReadFile(file)
-		if err != nil {
-			continue
+	if c.dryRunOutput != nil {
+		var diff strings.Builder
+		if err := writeUnifiedDiff(&diff, file, originalContent, string(parsedFile.Bytes())); err != nil {
+			return nil, "", err
 		}
+		return nil, diff.String(), nil
+	}
+
+	if err := os.WriteFile(file, parsedFile.Bytes(), 0644); err != nil {
+		return nil, "", fmt.Errorf("failed to write file %s: %w", file, err)
+	}
+
+	return &FileRestore{
+		Path:            file,
+		OriginalContent: originalContent,
+		ModuleName:      moduleInfo.Name,
+		Provider:        moduleInfo.Provider,
+		Namespace:       moduleInfo.Namespace,
+	}, "", nil
+}
+
+func (c *DefaultSourceConverter) workerLimit() int {
+	if c.workers > 0 {
+		return c.workers
+	}
+	return defaultConversionWorkers
+}
+
+// submodulePattern matches the registry sources of the submodules of info and
+// captures their path below modules/.
+func submodulePattern(info ModuleInfo) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`^%s/%s/%s//modules/(.*)$`,
+		regexp.QuoteMeta(info.Namespace),
+		regexp.QuoteMeta(info.Name),
+		regexp.QuoteMeta(info.Provider)))
+}
 
-		originalContent := string(content)
-		parsedFile, diags := hclwrite.ParseConfig(content, file, hcl.InitialPos)
-		if diags.HasErrors() {
-			return filesToRestore, fmt.Errorf("failed to parse %s: %s", file, diags.Error())
+// applyMappings converts or pins the sources of the mapped modules in body,
+// a file in dir, and reports whether anything changed.
+func (c *DefaultSourceConverter) applyMappings(body *hclwrite.Body, dir, namespace string) (bool, error) {
+	changed := false
+	for _, mapping := range c.mappings {
+		info := mapping.ModuleInfo
+		if info.Namespace == "" {
+			info.Namespace = namespace
 		}
+		source := fmt.Sprintf("%s/%s/%s", info.Namespace, info.Name, info.Provider)
 
-		if !c.updateModuleBlocks(parsedFile.Body(), moduleSource, submoduleRegex) {
+		if info.Root != "" {
+			localSource, err := relativeSource(dir, info.Root)
+			if err != nil {
+				return changed, err
+			}
+			if c.updateModuleBlocks(body, source, submodulePattern(info), localSource) {
+				changed = true
+			}
+			continue
+		}
+		if mapping.Version == "" {
 			continue
 		}
+		for _, block := range registryModuleBlocks(body, source) {
+			if attr := block.Body().GetAttribute("version"); attr != nil {
+				if constraint, ok := attributeStringValue(attr); ok && constraint == mapping.Version {
+					continue
+				}
+			}
+			block.Body().SetAttributeValue("version", cty.StringVal(mapping.Version))
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+func writeUnifiedDiff(w io.Writer, file, original, updated string) error {
+	name := strings.TrimPrefix(filepath.ToSlash(file), "/")
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(original),
+		B:        difflib.SplitLines(updated),
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to diff %s: %w", file, err)
+	}
+	_, err = io.WriteString(w, diff)
+	return err
+}
 
-		if err := os.WriteFile(file, parsedFile.Bytes(), 0644); err != nil {
-			return filesToRestore, fmt.Errorf("failed to write file %s: %w", file, err)
+// findTerraformFiles returns the .tf and terragrunt.hcl files in dir and its
+// subdirectories, such as helper modules or terragrunt units of an example,
+// skipping hidden directories like .terraform.
+func findTerraformFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
 		}
+		if filepath.Ext(path) == ".tf" || isTerragruntFile(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
 
-		filesToRestore = append(filesToRestore, FileRestore{
-			Path:            file,
-			OriginalContent: originalContent,
-			ModuleName:      moduleInfo.Name,
-			Provider:        moduleInfo.Provider,
-			Namespace:       moduleInfo.Namespace,
+func relativeSource(fromDir, root string) (string, error) {
+	absFrom, err := filepath.Abs(fromDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", fromDir, err)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+	rel, err := filepath.Rel(absFrom, absRoot)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute path from %s to %s: %w", fromDir, root, err)
+	}
+	return filepath.ToSlash(rel) + "/", nil
+}
+
+func (c *DefaultSourceConverter) RevertToRegistry(ctx context.Context, filesToRestore []FileRestore) error {
+	versions := &versionCache{}
+	errs := make([]error, len(filesToRestore))
+	var group errgroup.Group
+	group.SetLimit(c.workerLimit())
+	for i, restore := range filesToRestore {
+		group.Go(func() error {
+			if ctx.Err() == nil {
+				errs[i] = c.revertFile(ctx, restore, versions)
+			}
+			return nil
 		})
 	}
+	group.Wait()
 
-	return filesToRestore, nil
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
 }
 
-func (c *DefaultSourceConverter) RevertToRegistry(ctx context.Context, filesToRestore []FileRestore) error {
-	for _, restore := range filesToRestore {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
+// revertFile writes the original content of a converted file back, with the
+// version constraint of its registry sources updated. When no version can be
+// resolved the original content is restored unchanged.
+func (c *DefaultSourceConverter) revertFile(ctx context.Context, restore FileRestore, versions *versionCache) error {
+	if isTerragruntFile(restore.Path) {
+		return c.revertTerragruntFile(ctx, restore, versions)
+	}
+	constraint, err := c.versionConstraint(ctx, restore, versions)
+	if err != nil {
+		if writeErr := os.WriteFile(restore.Path, []byte(restore.OriginalContent), 0644); writeErr != nil {
+			return fmt.Errorf("failed to restore file %s: %w", restore.Path, writeErr)
+		}
+		return nil
+	}
+
+	moduleSource := fmt.Sprintf("%s/%s/%s", restore.Namespace, restore.ModuleName, restore.Provider)
+	updatedContent := c.updateVersionInContent(restore.OriginalContent, moduleSource, constraint)
+
+	if err := os.WriteFile(restore.Path, []byte(updatedContent), 0644); err != nil {
+		return fmt.Errorf("failed to write updated file %s: %w", restore.Path, err)
+	}
+	return nil
+}
+
+func (c *DefaultSourceConverter) versionConstraint(ctx context.Context, restore FileRestore, versions *versionCache) (string, error) {
+	if pin, ok := c.modulePins[restore.ModuleName]; ok && pin != "" {
+		return pin, nil
+	}
+	if c.pinnedVersion != "" {
+		return c.pinnedVersion, nil
+	}
+
+	moduleSource := fmt.Sprintf("%s/%s/%s", restore.Namespace, restore.ModuleName, restore.Provider)
+	original := moduleVersionConstraint(restore.OriginalContent, moduleSource)
+	latestVersion, err := versions.get(moduleSource+"@"+original, func() (string, error) {
+		return c.resolveVersion(ctx, restore, original)
+	})
+	if err != nil {
+		return "", err
+	}
+	return formatConstraint(original, latestVersion, c.style), nil
+}
+
+// versionCache shares a registry lookup between the files that need the same
+// version, so reverting many examples makes one call per module and
+// constraint.
+type versionCache struct {
+	mu      sync.Mutex
+	entries map[string]*versionLookup
+}
+
+type versionLookup struct {
+	once    sync.Once
+	version string
+	err     error
+}
+
+func (v *versionCache) get(key string, lookup func() (string, error)) (string, error) {
+	v.mu.Lock()
+	if v.entries == nil {
+		v.entries = make(map[string]*versionLookup)
+	}
+	entry, ok := v.entries[key]
+	if !ok {
+		entry = &versionLookup{}
+		v.entries[key] = entry
+	}
+	v.mu.Unlock()
+
+	entry.once.Do(func() { entry.version, entry.err = lookup() })
+	return entry.version, entry.err
+}
+
+// formatConstraint writes latest as a constraint in style. Preserving keeps
+// the operators of original and bumps the version of its lower bound, so
+// ">= 1.0, < 2" becomes ">= 1.9.3, < 2" and an exact pin stays exact. Upper
+// bounds and exclusions are kept as they are, and a missing constraint
+// becomes "~> latest".
+func formatConstraint(original, latest string, style ConstraintStyle) string {
+	switch style {
+	case ConstraintStylePessimistic:
+		return "~> " + latest
+	case ConstraintStyleMinimum:
+		return ">= " + latest
+	case ConstraintStyleExact:
+		return latest
+	}
+
+	if strings.TrimSpace(original) == "" {
+		return "~> " + latest
+	}
+	parts := strings.Split(original, ",")
+	for i, part := range parts {
+		match := constraintPattern.FindStringSubmatch(part)
+		if match == nil {
+			return original
+		}
+		switch operator := match[1]; operator {
+		case "<", "<=", "!=", ">":
+			parts[i] = strings.TrimSpace(part)
+		case "":
+			parts[i] = latest
 		default:
+			parts[i] = operator + " " + latest
 		}
+	}
+	return strings.Join(parts, ", ")
+}
 
-		latestVersion, err := c.registryClient.GetLatestVersion(ctx, restore.Namespace, restore.ModuleName, restore.Provider)
-		if err != nil {
-			if writeErr := os.WriteFile(restore.Path, []byte(restore.OriginalContent), 0644); writeErr != nil {
-				return fmt.Errorf("failed to restore file %s: %w", restore.Path, writeErr)
+func (c *DefaultSourceConverter) resolveVersion(ctx context.Context, restore FileRestore, constraint string) (string, error) {
+	if constraint != "" {
+		return c.registryClient.GetLatestMatching(ctx, restore.Namespace, restore.ModuleName, restore.Provider, constraint)
+	}
+	return c.registryClient.GetLatestVersion(ctx, restore.Namespace, restore.ModuleName, restore.Provider)
+}
+
+func moduleVersionConstraint(content, moduleSource string) string {
+	parsedFile, diags := hclwrite.ParseConfig([]byte(content), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return ""
+	}
+	for _, block := range registryModuleBlocks(parsedFile.Body(), moduleSource) {
+		if versionAttr := block.Body().GetAttribute("version"); versionAttr != nil {
+			if constraint, ok := attributeStringValue(versionAttr); ok {
+				return constraint
 			}
-			continue
 		}
+	}
+	return ""
+}
 
-		updatedContent := c.updateVersionInContent(restore.OriginalContent, latestVersion)
-
-		if err := os.WriteFile(restore.Path, []byte(updatedContent), 0644); err != nil {
-			return fmt.Errorf("failed to write updated file %s: %w", restore.Path, err)
+// registryModuleBlocks returns the module blocks sourced from moduleSource or
+// one of its submodules, including blocks nested in other blocks.
+func registryModuleBlocks(body *hclwrite.Body, moduleSource string) []*hclwrite.Block {
+	var blocks []*hclwrite.Block
+	for _, block := range body.Blocks() {
+		if block.Type() == "module" {
+			if attr := block.Body().GetAttribute("source"); attr != nil {
+				source, ok := attributeStringValue(attr)
+				source = registryAddress(source)
+				if ok && (source == moduleSource || strings.HasPrefix(source, moduleSource+"//")) {
+					blocks = append(blocks, block)
+				}
+			}
 		}
+		blocks = append(blocks, registryModuleBlocks(block.Body(), moduleSource)...)
 	}
-	return nil
+	return blocks
 }
 
-func (c *DefaultSourceConverter) updateVersionInContent(content, latestVersion string) string {
-	versionRegex := regexp.MustCompile(`(version\s*=\s*")[^"]*(")`)
-	if versionRegex.MatchString(content) {
-		return versionRegex.ReplaceAllString(content, fmt.Sprintf("${1}~> %s${2}", latestVersion))
+func (c *DefaultSourceConverter) updateVersionInContent(content, moduleSource, constraint string) string {
+	parsedFile, diags := hclwrite.ParseConfig([]byte(content), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return content
 	}
-	return content
+
+	changed := false
+	for _, block := range registryModuleBlocks(parsedFile.Body(), moduleSource) {
+		if block.Body().GetAttribute("version") == nil {
+			continue
+		}
+		block.Body().SetAttributeValue("version", cty.StringVal(constraint))
+		changed = true
+	}
+	if !changed {
+		return content
+	}
+	return string(parsedFile.Bytes())
 }
 
-func (c *DefaultSourceConverter) updateModuleBlocks(body *hclwrite.Body, moduleSource string, submoduleRegex *regexp.Regexp) bool {
+func (c *DefaultSourceConverter) updateModuleBlocks(body *hclwrite.Body, moduleSource string, submoduleRegex *regexp.Regexp, localSource string) bool {
 	changed := false
 	for _, block := range body.Blocks() {
-		if block.Type() == "module" && c.updateModuleBlock(block, moduleSource, submoduleRegex) {
+		if block.Type() == "module" && c.updateModuleBlock(block, moduleSource, submoduleRegex, localSource) {
 			changed = true
 		}
-		if c.updateModuleBlocks(block.Body(), moduleSource, submoduleRegex) {
+		if c.updateModuleBlocks(block.Body(), moduleSource, submoduleRegex, localSource) {
 			changed = true
 		}
 	}
 	return changed
 }
 
-func (c *DefaultSourceConverter) updateModuleBlock(block *hclwrite.Block, moduleSource string, submoduleRegex *regexp.Regexp) bool {
+func (c *DefaultSourceConverter) updateModuleBlock(block *hclwrite.Block, moduleSource string, submoduleRegex *regexp.Regexp, localSource string) bool {
 	attr := block.Body().GetAttribute("source")
 	if attr == nil {
 		return false
@@ -133,15 +566,16 @@ func (c *DefaultSourceConverter) updateModuleBlock(block *hclwrite.Block, module
 	if !ok {
 		return false
 	}
+	sourceValue = registryAddress(sourceValue)
 
 	switch {
 	case sourceValue == moduleSource:
-		block.Body().SetAttributeValue("source", cty.StringVal("../../"))
+		block.Body().SetAttributeValue("source", cty.StringVal(localSource))
 		block.Body().RemoveAttribute("version")
 		return true
 	case submoduleRegex != nil:
 		if matches := submoduleRegex.FindStringSubmatch(sourceValue); len(matches) == 2 {
-			localPath := fmt.Sprintf("../../modules/%s", strings.TrimPrefix(matches[1], "/"))
+			localPath := fmt.Sprintf("%smodules/%s", localSource, strings.TrimPrefix(matches[1], "/"))
 			block.Body().SetAttributeValue("source", cty.StringVal(localPath))
 			block.Body().RemoveAttribute("version")
 			return true
@@ -151,6 +585,22 @@ func (c *DefaultSourceConverter) updateModuleBlock(block *hclwrite.Block, module
 	return false
 }
 
+// registryAddress strips the hostname from a registry source, so that
+// "registry.terraform.io/ns/name/provider" and "app.terraform.io/ns/name/provider"
+// compare equal to "ns/name/provider". Other sources are returned as they are.
+func registryAddress(source string) string {
+	address, subdir, hasSubdir := strings.Cut(source, "//")
+	parts := strings.Split(address, "/")
+	if len(parts) != 4 || !strings.ContainsAny(parts[0], ".:") || strings.Contains(parts[0], "::") {
+		return source
+	}
+	address = strings.Join(parts[1:], "/")
+	if hasSubdir {
+		return address + "//" + subdir
+	}
+	return address
+}
+
 func attributeStringValue(attr *hclwrite.Attribute) (string, bool) {
 	tokens := attr.Expr().BuildTokens(nil)
 	if len(tokens) == 0 {
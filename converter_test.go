@@ -2,9 +2,13 @@ package validor
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/hashicorp/hcl/v2/hclwrite"
@@ -12,8 +16,18 @@ import (
 )
 
 type mockRegistryClient struct {
-	latestVersion string
-	err           error
+	latestVersion   string
+	matchingVersion string
+	constraint      string
+	versions        []string
+	err             error
+}
+
+func (m *mockRegistryClient) ListVersions(ctx context.Context, namespace, name, provider string) ([]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.versions, nil
 }
 
 func (m *mockRegistryClient) GetLatestVersion(ctx context.Context, namespace, name, provider string) (string, error) {
@@ -23,6 +37,17 @@ func (m *mockRegistryClient) GetLatestVersion(ctx context.Context, namespace, na
 	return m.latestVersion, nil
 }
 
+func (m *mockRegistryClient) GetLatestMatching(ctx context.Context, namespace, name, provider, constraint string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	m.constraint = constraint
+	if m.matchingVersion != "" {
+		return m.matchingVersion, nil
+	}
+	return m.latestVersion, nil
+}
+
 func TestNewSourceConverter(t *testing.T) {
 	client := NewRegistryClient()
 	converter := NewSourceConverter(client)
@@ -88,6 +113,216 @@ module "submodule" {
 	}
 }
 
+func TestDefaultSourceConverter_ConvertToLocal_NestedExamples(t *testing.T) {
+	root := t.TempDir()
+	content := "module \"test\" {\n  source  = \"cloudnationhq/mymodule/azure\"\n  version = \"~> 1.0\"\n}\n\nmodule \"sub\" {\n  source  = \"cloudnationhq/mymodule/azure//modules/network\"\n  version = \"~> 1.0\"\n}\n"
+
+	tests := []struct {
+		name          string
+		example       string
+		dir           string
+		wantSource    string
+		wantSubmodule string
+	}{
+		{name: "two levels", example: "examples/default", dir: "examples/default", wantSource: "../../", wantSubmodule: "../../modules/network"},
+		{name: "three levels", example: "examples/group/default", dir: "examples/group/default", wantSource: "../../../", wantSubmodule: "../../../modules/network"},
+		{name: "nested inside example", example: "examples/complete", dir: "examples/complete/child", wantSource: "../../../", wantSubmodule: "../../../modules/network"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(root, tt.dir)
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			tfFile := filepath.Join(dir, "main.tf")
+			if err := os.WriteFile(tfFile, []byte(content), 0o644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			converter := NewSourceConverter(&mockRegistryClient{latestVersion: "1.0.0"})
+			moduleInfo := ModuleInfo{Name: "mymodule", Provider: "azure", Namespace: "cloudnationhq", Root: root}
+			filesToRestore, err := converter.ConvertToLocal(testContext(t), filepath.Join(root, tt.example), moduleInfo)
+			if err != nil {
+				t.Fatalf("ConvertToLocal() error = %v", err)
+			}
+			if len(filesToRestore) != 1 {
+				t.Fatalf("ConvertToLocal() returned %d files, want 1", len(filesToRestore))
+			}
+
+			converted, err := os.ReadFile(tfFile)
+			if err != nil {
+				t.Fatalf("Failed to read modified file: %v", err)
+			}
+			if !strings.Contains(string(converted), `source = "`+tt.wantSource+`"`) {
+				t.Errorf("expected source %q, got: %s", tt.wantSource, converted)
+			}
+			if !strings.Contains(string(converted), `source = "`+tt.wantSubmodule+`"`) {
+				t.Errorf("expected submodule source %q, got: %s", tt.wantSubmodule, converted)
+			}
+		})
+	}
+}
+
+func TestDefaultSourceConverter_ConvertToLocal_HelperModules(t *testing.T) {
+	root := t.TempDir()
+	example := filepath.Join(root, "examples", "default")
+	content := "module \"sub\" {\n  source  = \"cloudnationhq/mymodule/azure//modules/network\"\n  version = \"~> 1.0\"\n}\n"
+	files := map[string]string{
+		filepath.Join(example, "main.tf"):                                 content,
+		filepath.Join(example, "modules", "helper", "main.tf"):            content,
+		filepath.Join(example, ".terraform", "modules", "sub", "main.tf"): content,
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	converter := NewSourceConverter(&mockRegistryClient{latestVersion: "1.0.0"})
+	moduleInfo := ModuleInfo{Name: "mymodule", Provider: "azure", Namespace: "cloudnationhq", Root: root}
+	filesToRestore, err := converter.ConvertToLocal(testContext(t), example, moduleInfo)
+	if err != nil {
+		t.Fatalf("ConvertToLocal() error = %v", err)
+	}
+	if len(filesToRestore) != 2 {
+		t.Fatalf("ConvertToLocal() returned %d files, want 2", len(filesToRestore))
+	}
+
+	helper, _ := os.ReadFile(filepath.Join(example, "modules", "helper", "main.tf"))
+	if !strings.Contains(string(helper), `source = "../../../../modules/network"`) {
+		t.Errorf("helper module source should be localized, got: %s", helper)
+	}
+	if cached, _ := os.ReadFile(filepath.Join(example, ".terraform", "modules", "sub", "main.tf")); string(cached) != content {
+		t.Errorf("files in hidden directories should be left alone, got: %s", cached)
+	}
+
+	if err := converter.RevertToRegistry(testContext(t), filesToRestore); err != nil {
+		t.Fatalf("RevertToRegistry() error = %v", err)
+	}
+	if reverted, _ := os.ReadFile(filepath.Join(example, "modules", "helper", "main.tf")); !strings.Contains(string(reverted), `source  = "cloudnationhq/mymodule/azure//modules/network"`) {
+		t.Errorf("helper module source should be restored, got: %s", reverted)
+	}
+}
+
+func TestDefaultSourceConverter_ConvertToLocal_ModuleMappings(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "examples", "default")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	original := "module \"storage\" {\n  source  = \"cloudnationhq/sa/azure\"\n  version = \"~> 1.0\"\n}\n\nmodule \"vnet\" {\n  source  = \"cloudnationhq/vnet/azure\"\n  version = \"~> 2.0\"\n}\n\nmodule \"kv\" {\n  source  = \"cloudnationhq/kv/azure\"\n  version = \"~> 3.0\"\n}\n"
+	tfFile := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(tfFile, []byte(original), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	converter := NewSourceConverter(&mockRegistryClient{latestVersion: "1.4.0"}, WithModuleMappings(
+		ModuleMapping{ModuleInfo: ModuleInfo{Name: "vnet", Provider: "azure", Root: filepath.Join(root, "..", "vnet")}},
+		ModuleMapping{ModuleInfo: ModuleInfo{Name: "kv", Provider: "azure"}, Version: "3.2.1"},
+	))
+	moduleInfo := ModuleInfo{Name: "sa", Provider: "azure", Namespace: "cloudnationhq", Root: root}
+	filesToRestore, err := converter.ConvertToLocal(testContext(t), dir, moduleInfo)
+	if err != nil {
+		t.Fatalf("ConvertToLocal() error = %v", err)
+	}
+
+	converted, err := os.ReadFile(tfFile)
+	if err != nil {
+		t.Fatalf("Failed to read modified file: %v", err)
+	}
+	for _, want := range []string{`source = "../../"`, `source = "../../../vnet/"`, `source  = "cloudnationhq/kv/azure"`, `version = "3.2.1"`} {
+		if !strings.Contains(string(converted), want) {
+			t.Errorf("converted file should contain %s, got: %s", want, converted)
+		}
+	}
+
+	if err := converter.RevertToRegistry(testContext(t), filesToRestore); err != nil {
+		t.Fatalf("RevertToRegistry() error = %v", err)
+	}
+	reverted, err := os.ReadFile(tfFile)
+	if err != nil {
+		t.Fatalf("Failed to read reverted file: %v", err)
+	}
+	for _, want := range []string{`version = "~> 1.4.0"`, `source  = "cloudnationhq/vnet/azure"`, `version = "~> 2.0"`, `version = "~> 3.0"`} {
+		if !strings.Contains(string(reverted), want) {
+			t.Errorf("reverted file should contain %s, got: %s", want, reverted)
+		}
+	}
+}
+
+func TestModuleMappingFlag(t *testing.T) {
+	config := NewConfig()
+	for _, value := range []string{"cloudnationhq/vnet/azure=../vnet", "cloudnationhq/kv/azure=3.2.1"} {
+		if err := moduleMappingFlag(config)(value); err != nil {
+			t.Fatalf("moduleMappingFlag(%q) error = %v", value, err)
+		}
+	}
+	want := []ModuleMapping{
+		{ModuleInfo: ModuleInfo{Namespace: "cloudnationhq", Name: "vnet", Provider: "azure", Root: "../vnet"}},
+		{ModuleInfo: ModuleInfo{Namespace: "cloudnationhq", Name: "kv", Provider: "azure"}, Version: "3.2.1"},
+	}
+	if !reflect.DeepEqual(config.ModuleMappings, want) {
+		t.Errorf("ModuleMappings = %+v, want %+v", config.ModuleMappings, want)
+	}
+	for _, value := range []string{"vnet=../vnet", "cloudnationhq/vnet/azure", "cloudnationhq//azure=1.0.0", "cloudnationhq/vnet/azure="} {
+		if err := moduleMappingFlag(config)(value); err == nil {
+			t.Errorf("moduleMappingFlag(%q) should fail", value)
+		}
+	}
+}
+
+func TestDefaultSourceConverter_ConvertToLocal_DryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	original := "module \"test\" {\n  source  = \"cloudnationhq/mymodule/azure\"\n  version = \"~> 1.0\"\n}\n"
+	tfFile := filepath.Join(tmpDir, "main.tf")
+	if err := os.WriteFile(tfFile, []byte(original), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	untouched := filepath.Join(tmpDir, "other.tf")
+	if err := os.WriteFile(untouched, []byte("locals {}\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var diff strings.Builder
+	converter := NewSourceConverter(nil, WithDryRunOutput(&diff))
+	moduleInfo := ModuleInfo{Name: "mymodule", Provider: "azure", Namespace: "cloudnationhq"}
+
+	filesToRestore, err := converter.ConvertToLocal(testContext(t), tmpDir, moduleInfo)
+	if err != nil {
+		t.Fatalf("ConvertToLocal() error = %v", err)
+	}
+	if len(filesToRestore) != 0 {
+		t.Errorf("dry run should not return files to restore, got %d", len(filesToRestore))
+	}
+
+	content, err := os.ReadFile(tfFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != original {
+		t.Errorf("dry run should not modify files, got: %s", content)
+	}
+
+	for _, want := range []string{
+		"--- a/" + strings.TrimPrefix(filepath.ToSlash(tfFile), "/"),
+		"+++ b/" + strings.TrimPrefix(filepath.ToSlash(tfFile), "/"),
+		`-  source  = "cloudnationhq/mymodule/azure"`,
+		`-  version = "~> 1.0"`,
+		`+  source = "../../"`,
+	} {
+		if !strings.Contains(diff.String(), want) {
+			t.Errorf("diff missing %q:\n%s", want, diff.String())
+		}
+	}
+	if strings.Contains(diff.String(), "other.tf") {
+		t.Errorf("diff should only include changed files:\n%s", diff.String())
+	}
+}
+
 func TestDefaultSourceConverter_RevertToRegistry(t *testing.T) {
 	tmpDir := t.TempDir()
 	tfFile := filepath.Join(tmpDir, "main.tf")
@@ -139,6 +374,119 @@ module "test" {
 	}
 }
 
+func TestDefaultSourceConverter_RevertToRegistry_Constraint(t *testing.T) {
+	tfFile := filepath.Join(t.TempDir(), "main.tf")
+	if err := os.WriteFile(tfFile, []byte("module \"test\" {\n  source = \"../../\"\n}\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	client := &mockRegistryClient{latestVersion: "2.0.0", matchingVersion: "1.4.7"}
+	converter := NewSourceConverter(client)
+
+	err := converter.RevertToRegistry(testContext(t), []FileRestore{{
+		Path:            tfFile,
+		OriginalContent: "module \"test\" {\n  source  = \"cloudnationhq/mymodule/azure//modules/sub\"\n  version = \">= 1.4, < 2\"\n}\n",
+		ModuleName:      "mymodule",
+		Provider:        "azure",
+		Namespace:       "cloudnationhq",
+	}})
+	if err != nil {
+		t.Fatalf("RevertToRegistry() error = %v", err)
+	}
+
+	if client.constraint != ">= 1.4, < 2" {
+		t.Errorf("GetLatestMatching() constraint = %q, want %q", client.constraint, ">= 1.4, < 2")
+	}
+	content, err := os.ReadFile(tfFile)
+	if err != nil {
+		t.Fatalf("Failed to read restored file: %v", err)
+	}
+	if !strings.Contains(string(content), `version = ">= 1.4.7, < 2"`) {
+		t.Errorf("Version should be resolved within the original constraint, got: %s", content)
+	}
+}
+
+func TestModuleVersionConstraint(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "matching module",
+			content: "module \"a\" {\n  source  = \"ns/mod/azure\"\n  version = \"~> 1.4\"\n}\n",
+			want:    "~> 1.4",
+		},
+		{
+			name:    "other module ignored",
+			content: "module \"a\" {\n  source  = \"ns/other/azure\"\n  version = \"~> 1.4\"\n}\n",
+			want:    "",
+		},
+		{
+			name:    "no version",
+			content: "module \"a\" {\n  source = \"ns/mod/azure\"\n}\n",
+			want:    "",
+		},
+		{
+			name:    "invalid hcl",
+			content: "module \"a\" {",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := moduleVersionConstraint(tt.content, "ns/mod/azure"); got != tt.want {
+				t.Errorf("moduleVersionConstraint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultSourceConverter_RevertToRegistry_PinnedVersion(t *testing.T) {
+	original := "module \"test\" {\n  source  = \"cloudnationhq/%s/azure\"\n  version = \"~> 1.0\"\n}\n"
+
+	tests := []struct {
+		name       string
+		module     string
+		defaultPin string
+		modulePins map[string]string
+		want       string
+	}{
+		{name: "default pin", module: "vnet", defaultPin: "1.8.0", want: `version = "1.8.0"`},
+		{name: "module pin wins", module: "vnet", defaultPin: "1.8.0", modulePins: map[string]string{"vnet": "2.0.1"}, want: `version = "2.0.1"`},
+		{name: "other module uses default", module: "storage", defaultPin: "1.8.0", modulePins: map[string]string{"vnet": "2.0.1"}, want: `version = "1.8.0"`},
+		{name: "unpinned module floats", module: "storage", modulePins: map[string]string{"vnet": "2.0.1"}, want: `version = "~> 1.9.0"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tfFile := filepath.Join(t.TempDir(), "main.tf")
+			client := &mockRegistryClient{latestVersion: "1.9.0"}
+			converter := NewSourceConverter(client, WithVersionPins(tt.defaultPin, tt.modulePins))
+
+			err := converter.RevertToRegistry(testContext(t), []FileRestore{{
+				Path:            tfFile,
+				OriginalContent: fmt.Sprintf(original, tt.module),
+				ModuleName:      tt.module,
+				Provider:        "azure",
+				Namespace:       "cloudnationhq",
+			}})
+			if err != nil {
+				t.Fatalf("RevertToRegistry() error = %v", err)
+			}
+
+			content, err := os.ReadFile(tfFile)
+			if err != nil {
+				t.Fatalf("Failed to read restored file: %v", err)
+			}
+			if !strings.Contains(string(content), tt.want) {
+				t.Errorf("expected %q, got: %s", tt.want, content)
+			}
+		})
+	}
+}
+
 func TestDefaultSourceConverter_updateVersionInContent(t *testing.T) {
 	client := &mockRegistryClient{}
 	converter := NewSourceConverter(client).(*DefaultSourceConverter)
@@ -169,16 +517,48 @@ func TestDefaultSourceConverter_updateVersionInContent(t *testing.T) {
 		{
 			name: "version with different format",
 			content: `module "test" {
+  source="cloudnationhq/mymodule/azure"
   version="1.0.0"
 }`,
 			latestVersion: "3.0.0",
-			expectedMatch: `version="~> 3.0.0"`,
+			expectedMatch: `"~> 3.0.0"`,
+		},
+		{
+			name: "submodule version",
+			content: `module "test" {
+  source  = "cloudnationhq/mymodule/azure//modules/network"
+  version = "~> 1.0"
+}`,
+			latestVersion: "2.0.0",
+			expectedMatch: `version = "~> 2.0.0"`,
+		},
+		{
+			name: "unrelated module untouched",
+			content: `module "other" {
+  source  = "hashicorp/consul/aws"
+  version = "0.1.0"
+}`,
+			latestVersion: "2.0.0",
+			expectedMatch: "",
+		},
+		{
+			name: "provider version untouched",
+			content: `terraform {
+  required_providers {
+    azurerm = {
+      source  = "hashicorp/azurerm"
+      version = "~> 4.0"
+    }
+  }
+}`,
+			latestVersion: "2.0.0",
+			expectedMatch: "",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := converter.updateVersionInContent(tt.content, tt.latestVersion)
+			result := converter.updateVersionInContent(tt.content, "cloudnationhq/mymodule/azure", "~> "+tt.latestVersion)
 
 			if tt.expectedMatch != "" {
 				matched, _ := regexp.MatchString(regexp.QuoteMeta(tt.expectedMatch), result)
@@ -194,6 +574,40 @@ func TestDefaultSourceConverter_updateVersionInContent(t *testing.T) {
 	}
 }
 
+func TestDefaultSourceConverter_updateVersionInContent_MixedFile(t *testing.T) {
+	converter := NewSourceConverter(&mockRegistryClient{}).(*DefaultSourceConverter)
+	content := `terraform {
+  required_providers {
+    azurerm = {
+      source  = "hashicorp/azurerm"
+      version = "~> 4.0"
+    }
+  }
+}
+
+module "test" {
+  source  = "cloudnationhq/mymodule/azure"
+  version = "~> 1.0"
+}
+
+module "other" {
+  source  = "cloudnationhq/other/azure"
+  version = "~> 1.0"
+}
+`
+	result := converter.updateVersionInContent(content, "cloudnationhq/mymodule/azure", "~> 2.1.0")
+
+	for _, want := range []string{
+		`version = "~> 4.0"`,
+		`version = "~> 2.1.0"`,
+		"source  = \"cloudnationhq/other/azure\"\n  version = \"~> 1.0\"",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in result:\n%s", want, result)
+		}
+	}
+}
+
 func TestDefaultSourceConverter_ConvertToLocal_CancelledMidFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	tfContent := `
@@ -261,6 +675,24 @@ func TestDefaultSourceConverter_updateModuleBlock(t *testing.T) {
 			expectedSource: "../../modules/network",
 			shouldChange:   true,
 		},
+		{
+			name:           "public registry hostname",
+			sourceValue:    "registry.terraform.io/cloudnationhq/mymodule/azure",
+			expectedSource: "../../",
+			shouldChange:   true,
+		},
+		{
+			name:           "private registry hostname",
+			sourceValue:    "app.terraform.io/cloudnationhq/mymodule/azure",
+			expectedSource: "../../",
+			shouldChange:   true,
+		},
+		{
+			name:           "hostname submodule source",
+			sourceValue:    "registry.terraform.io/cloudnationhq/mymodule/azure//modules/network",
+			expectedSource: "../../modules/network",
+			shouldChange:   true,
+		},
 		{
 			name:           "different module source",
 			sourceValue:    "hashicorp/consul/aws",
@@ -282,7 +714,7 @@ func TestDefaultSourceConverter_updateModuleBlock(t *testing.T) {
 			block := rootBody.AppendNewBlock("module", []string{"test"})
 			block.Body().SetAttributeValue("source", cty.StringVal(tt.sourceValue))
 
-			changed := converter.updateModuleBlock(block, moduleSource, submoduleRegex)
+			changed := converter.updateModuleBlock(block, moduleSource, submoduleRegex, "../../")
 
 			if changed != tt.shouldChange {
 				t.Errorf("updateModuleBlock() changed = %v, want %v", changed, tt.shouldChange)
@@ -299,6 +731,54 @@ func TestDefaultSourceConverter_updateModuleBlock(t *testing.T) {
 	}
 }
 
+func TestRegistryAddress(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{source: "cloudnationhq/mymodule/azure", want: "cloudnationhq/mymodule/azure"},
+		{source: "registry.terraform.io/cloudnationhq/mymodule/azure", want: "cloudnationhq/mymodule/azure"},
+		{source: "app.terraform.io/org/mymodule/azure//modules/network", want: "org/mymodule/azure//modules/network"},
+		{source: "localhost:8080/org/mymodule/azure", want: "org/mymodule/azure"},
+		{source: "github.com/org/repo", want: "github.com/org/repo"},
+		{source: "git::https://example.com/org/mymodule/azure.git", want: "git::https://example.com/org/mymodule/azure.git"},
+		{source: "../../", want: "../../"},
+	}
+
+	for _, tt := range tests {
+		if got := registryAddress(tt.source); got != tt.want {
+			t.Errorf("registryAddress(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultSourceConverter_RevertToRegistry_Hostname(t *testing.T) {
+	original := "module \"test\" {\n  source  = \"registry.terraform.io/cloudnationhq/mymodule/azure\"\n  version = \"~> 1.0\"\n}\n"
+	tfFile := filepath.Join(t.TempDir(), "main.tf")
+	converter := NewSourceConverter(&mockRegistryClient{latestVersion: "1.9.0"})
+
+	err := converter.RevertToRegistry(testContext(t), []FileRestore{{
+		Path:            tfFile,
+		OriginalContent: original,
+		ModuleName:      "mymodule",
+		Provider:        "azure",
+		Namespace:       "cloudnationhq",
+	}})
+	if err != nil {
+		t.Fatalf("RevertToRegistry() error = %v", err)
+	}
+
+	content, err := os.ReadFile(tfFile)
+	if err != nil {
+		t.Fatalf("Failed to read restored file: %v", err)
+	}
+	for _, want := range []string{`source  = "registry.terraform.io/cloudnationhq/mymodule/azure"`, `version = "~> 1.9.0"`} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("restored content should contain %s, got: %s", want, content)
+		}
+	}
+}
+
 func TestAttributeStringValue(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -343,3 +823,143 @@ func testContext(t *testing.T) context.Context {
 	t.Helper()
 	return context.Background()
 }
+
+func TestFormatConstraint(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		style    ConstraintStyle
+		want     string
+	}{
+		{name: "no constraint", want: "~> 1.9.0"},
+		{name: "pessimistic", original: "~> 1.0", want: "~> 1.9.0"},
+		{name: "minimum", original: ">= 1.0", want: ">= 1.9.0"},
+		{name: "exact pin", original: "1.2.3", want: "1.9.0"},
+		{name: "exact operator", original: "= 1.2.3", want: "= 1.9.0"},
+		{name: "range keeps upper bound", original: ">= 1.4, < 2", want: ">= 1.9.0, < 2"},
+		{name: "exclusion kept", original: "~> 1.0, != 1.5.0", want: "~> 1.9.0, != 1.5.0"},
+		{name: "upper bound only", original: "< 2.0.0", want: "< 2.0.0"},
+		{name: "unparsable", original: "latest", want: "latest"},
+		{name: "forced pessimistic", original: ">= 1.0", style: ConstraintStylePessimistic, want: "~> 1.9.0"},
+		{name: "forced minimum", original: "1.2.3", style: ConstraintStyleMinimum, want: ">= 1.9.0"},
+		{name: "forced exact", original: "~> 1.0", style: ConstraintStyleExact, want: "1.9.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatConstraint(tt.original, "1.9.0", tt.style); got != tt.want {
+				t.Errorf("formatConstraint(%q) = %q, want %q", tt.original, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultSourceConverter_RevertToRegistry_ConstraintStyle(t *testing.T) {
+	original := "module \"test\" {\n  source  = \"cloudnationhq/mymodule/azure\"\n  version = \"1.2.0\"\n}\n"
+
+	tests := []struct {
+		name  string
+		style ConstraintStyle
+		want  string
+	}{
+		{name: "exact pin preserved", want: `version = "1.2.0"`},
+		{name: "forced style", style: ConstraintStylePessimistic, want: `version = "~> 1.2.0"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tfFile := filepath.Join(t.TempDir(), "main.tf")
+			client := &mockRegistryClient{latestVersion: "1.9.0", matchingVersion: "1.2.0"}
+			converter := NewSourceConverter(client, WithConstraintStyle(tt.style))
+
+			err := converter.RevertToRegistry(testContext(t), []FileRestore{{
+				Path:            tfFile,
+				OriginalContent: original,
+				ModuleName:      "mymodule",
+				Provider:        "azure",
+				Namespace:       "cloudnationhq",
+			}})
+			if err != nil {
+				t.Fatalf("RevertToRegistry() error = %v", err)
+			}
+
+			content, err := os.ReadFile(tfFile)
+			if err != nil {
+				t.Fatalf("Failed to read restored file: %v", err)
+			}
+			if !strings.Contains(string(content), tt.want) {
+				t.Errorf("restored content = %s, want %s", content, tt.want)
+			}
+		})
+	}
+}
+
+func TestConstraintStyleFlag(t *testing.T) {
+	config := NewConfig()
+	if err := constraintStyleFlag(config)("exact"); err != nil || config.ConstraintStyle != ConstraintStyleExact {
+		t.Errorf("constraintStyleFlag(exact) = %v, style %q", err, config.ConstraintStyle)
+	}
+	if err := constraintStyleFlag(config)("loose"); err == nil {
+		t.Error("constraintStyleFlag(loose) should fail")
+	}
+}
+
+type countingRegistryClient struct {
+	mockRegistryClient
+	calls atomic.Int32
+}
+
+func (c *countingRegistryClient) GetLatestMatching(ctx context.Context, namespace, name, provider, constraint string) (string, error) {
+	c.calls.Add(1)
+	return "1.9.0", nil
+}
+
+func TestDefaultSourceConverter_RevertToRegistry_SharesLookups(t *testing.T) {
+	dir := t.TempDir()
+	var restores []FileRestore
+	for i := range 40 {
+		restores = append(restores, FileRestore{
+			Path:            filepath.Join(dir, fmt.Sprintf("example%d.tf", i)),
+			OriginalContent: "module \"test\" {\n  source  = \"cloudnationhq/mymodule/azure\"\n  version = \"~> 1.0\"\n}\n",
+			ModuleName:      "mymodule",
+			Provider:        "azure",
+			Namespace:       "cloudnationhq",
+		})
+	}
+
+	client := &countingRegistryClient{}
+	if err := NewSourceConverter(client, WithConversionWorkers(4)).RevertToRegistry(testContext(t), restores); err != nil {
+		t.Fatalf("RevertToRegistry() error = %v", err)
+	}
+	if calls := client.calls.Load(); calls != 1 {
+		t.Errorf("registry lookups = %d, want 1", calls)
+	}
+	for _, restore := range restores {
+		if content, _ := os.ReadFile(restore.Path); !strings.Contains(string(content), `version = "~> 1.9.0"`) {
+			t.Errorf("%s was not reverted, got: %s", restore.Path, content)
+		}
+	}
+}
+
+func TestDefaultSourceConverter_ConvertToLocal_PerFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	good := "module \"test\" {\n  source  = \"cloudnationhq/mymodule/azure\"\n  version = \"~> 1.0\"\n}\n"
+	for name, content := range map[string]string{"a.tf": good, "broken.tf": "module \"test\" {", "c.tf": good} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	moduleInfo := ModuleInfo{Name: "mymodule", Provider: "azure", Namespace: "cloudnationhq"}
+	filesToRestore, err := NewSourceConverter(nil).ConvertToLocal(testContext(t), dir, moduleInfo)
+	if err == nil || !strings.Contains(err.Error(), "broken.tf") {
+		t.Fatalf("ConvertToLocal() error = %v, want one naming broken.tf", err)
+	}
+	var paths []string
+	for _, restore := range filesToRestore {
+		paths = append(paths, filepath.Base(restore.Path))
+	}
+	if want := []string{"a.tf", "c.tf"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("files to restore = %v, want %v", paths, want)
+	}
+}
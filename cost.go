@@ -0,0 +1,90 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func WithCostEstimation(enabled bool) Option {
+	return func(c *Config) { c.CostEstimation = enabled }
+}
+
+func WithCostThreshold(amount float64) Option {
+	return func(c *Config) {
+		c.CostEstimation = true
+		c.CostThreshold = amount
+	}
+}
+
+var runInfracost = func(ctx context.Context, planJSONPath string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "infracost", "breakdown", "--path", planJSONPath, "--format", "json", "--no-color")
+	return cmd.Output()
+}
+
+type infracostBreakdown struct {
+	Currency         string `json:"currency"`
+	TotalMonthlyCost string `json:"totalMonthlyCost"`
+}
+
+func costCheck(threshold float64) planCheck {
+	return planCheck{
+		operation: "cost estimation",
+		run: func(ctx context.Context, t testing.TB, m *Module, _ []byte) error {
+			output, err := runInfracost(ctx, m.PlanJSONPath())
+			if err != nil {
+				return fmt.Errorf("infracost failed: %w", err)
+			}
+
+			var breakdown infracostBreakdown
+			if err := json.Unmarshal(output, &breakdown); err != nil {
+				return fmt.Errorf("failed to parse infracost output: %w", err)
+			}
+
+			cost := 0.0
+			if breakdown.TotalMonthlyCost != "" {
+				cost, err = strconv.ParseFloat(breakdown.TotalMonthlyCost, 64)
+				if err != nil {
+					return fmt.Errorf("invalid monthly cost %q: %w", breakdown.TotalMonthlyCost, err)
+				}
+			}
+			m.MonthlyCost = &cost
+			m.Currency = breakdown.Currency
+
+			t.Logf("Estimated monthly cost for module %s: %.2f %s", m.Name, cost, m.Currency)
+			if threshold > 0 && cost > threshold {
+				return fmt.Errorf("estimated monthly cost %.2f %s exceeds threshold %.2f", cost, m.Currency, threshold)
+			}
+			return nil
+		},
+	}
+}
+
+func printModuleCosts(tb testLogger, modules []*Module) {
+	var costed []*Module
+	for _, module := range modules {
+		if module.MonthlyCost != nil {
+			costed = append(costed, module)
+		}
+	}
+	if len(costed) == 0 {
+		return
+	}
+
+	sort.SliceStable(costed, func(i, j int) bool {
+		return *costed[i].MonthlyCost > *costed[j].MonthlyCost
+	})
+
+	var total float64
+	tb.Log("Estimated monthly cost:")
+	for _, module := range costed {
+		total += *module.MonthlyCost
+		tb.Logf("  %-30s %10.2f %s", module.Name, *module.MonthlyCost, module.Currency)
+	}
+	tb.Logf("  %-30s %10.2f %s", "total", total, costed[0].Currency)
+	tb.Log("")
+}
@@ -0,0 +1,99 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newPlannedModule(t *testing.T, name string) *Module {
+	t.Helper()
+	module := NewModule(name, t.TempDir())
+	module.planHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+		return []byte(`{"format_version":"1.2"}`), nil
+	}
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+		return nil
+	}
+	return module
+}
+
+func TestCostCheck(t *testing.T) {
+	origInfracost := runInfracost
+	defer func() { runInfracost = origInfracost }()
+
+	runInfracost = func(ctx context.Context, planJSONPath string) ([]byte, error) {
+		return []byte(`{"currency":"USD","totalMonthlyCost":"125.50"}`), nil
+	}
+
+	t.Run("under threshold", func(t *testing.T) {
+		module := newPlannedModule(t, "cheap")
+		module.planChecks = []planCheck{costCheck(200)}
+
+		if err := module.Apply(context.Background(), t); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if module.MonthlyCost == nil || *module.MonthlyCost != 125.50 {
+			t.Errorf("MonthlyCost = %v, want 125.50", module.MonthlyCost)
+		}
+		if module.Currency != "USD" {
+			t.Errorf("Currency = %v, want USD", module.Currency)
+		}
+	})
+
+	t.Run("over threshold", func(t *testing.T) {
+		module := newPlannedModule(t, "expensive")
+		applied := false
+		module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+			applied = true
+			return nil
+		}
+		module.planChecks = []planCheck{costCheck(100)}
+
+		err := module.Apply(context.Background(), t)
+		if err == nil {
+			t.Fatal("expected Apply() to fail when cost exceeds threshold")
+		}
+		if applied {
+			t.Error("apply should not run when cost exceeds threshold")
+		}
+		if !module.ApplyFailed || len(module.Errors) != 1 || !strings.Contains(module.Errors[0], "cost estimation failed") {
+			t.Errorf("unexpected module state: failed=%v errors=%v", module.ApplyFailed, module.Errors)
+		}
+	})
+
+	t.Run("infracost error", func(t *testing.T) {
+		runInfracost = func(ctx context.Context, planJSONPath string) ([]byte, error) {
+			return nil, errors.New("infracost: not found")
+		}
+		module := newPlannedModule(t, "broken")
+		module.planChecks = []planCheck{costCheck(0)}
+
+		if err := module.Apply(context.Background(), t); err == nil {
+			t.Fatal("expected Apply() to fail when infracost fails")
+		}
+	})
+}
+
+func TestPrintModuleCosts(t *testing.T) {
+	cheap, expensive := 10.0, 90.0
+	a := NewModule("a", "/path/a")
+	a.MonthlyCost, a.Currency = &cheap, "USD"
+	b := NewModule("b", "/path/b")
+	b.MonthlyCost, b.Currency = &expensive, "USD"
+
+	mock := &mockTB{}
+	PrintModuleSummary(mock, []*Module{a, b, NewModule("c", "/path/c")})
+
+	joined := strings.Join(mock.logs, "\n")
+	if !strings.Contains(joined, "Estimated monthly cost:") {
+		t.Fatalf("expected cost section in summary, got %q", joined)
+	}
+	if strings.Index(joined, "  b ") > strings.Index(joined, "  a ") {
+		t.Errorf("expected most expensive module first, got %q", joined)
+	}
+	if !strings.Contains(joined, "100.00 USD") {
+		t.Errorf("expected total cost in summary, got %q", joined)
+	}
+}
@@ -0,0 +1,249 @@
+package validor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var moduleMetaArguments = []string{"source", "version", "providers", "count", "for_each", "depends_on"}
+
+func WithCoverageThreshold(percent float64) Option {
+	return func(c *Config) { c.CoverageThreshold = percent }
+}
+
+type CoverageReport struct {
+	Variables      []string
+	UnsetVariables []string
+	Outputs        []string
+	UnusedOutputs  []string
+}
+
+func (r *CoverageReport) VariableCoverage() float64 {
+	if len(r.Variables) == 0 {
+		return 100
+	}
+	return float64(len(r.Variables)-len(r.UnsetVariables)) / float64(len(r.Variables)) * 100
+}
+
+func parseTerraformFiles(dir string) ([]*hclsyntax.Body, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find terraform files: %w", err)
+	}
+
+	var bodies []*hclsyntax.Body
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		parsed, diags := hclsyntax.ParseConfig(content, file, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse %s: %s", file, diags.Error())
+		}
+		bodies = append(bodies, parsed.Body.(*hclsyntax.Body))
+	}
+	return bodies, nil
+}
+
+func blockLabels(bodies []*hclsyntax.Body, blockType string) []string {
+	var labels []string
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type == blockType && len(block.Labels) > 0 {
+				labels = append(labels, block.Labels[0])
+			}
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func isModuleUnderTest(source, exampleDir, moduleRoot string, moduleInfo ModuleInfo) bool {
+	if moduleInfo.Name != "" && registryAddress(source) == fmt.Sprintf("%s/%s/%s", moduleInfo.Namespace, moduleInfo.Name, moduleInfo.Provider) {
+		return true
+	}
+	if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+		return false
+	}
+	resolved, err := filepath.Abs(filepath.Join(exampleDir, source))
+	if err != nil {
+		return false
+	}
+	root, err := filepath.Abs(moduleRoot)
+	if err != nil {
+		return false
+	}
+	return resolved == root
+}
+
+func literalString(expr hclsyntax.Expression) (string, bool) {
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// modulesUnderTest returns the module blocks of an example that call the
+// module being tested.
+func modulesUnderTest(bodies []*hclsyntax.Body, exampleDir, moduleRoot string, moduleInfo ModuleInfo) []*hclsyntax.Block {
+	var calls []*hclsyntax.Block
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "module" || len(block.Labels) == 0 {
+				continue
+			}
+			sourceAttr, ok := block.Body.Attributes["source"]
+			if !ok {
+				continue
+			}
+			source, ok := literalString(sourceAttr.Expr)
+			if ok && isModuleUnderTest(source, exampleDir, moduleRoot, moduleInfo) {
+				calls = append(calls, block)
+			}
+		}
+	}
+	return calls
+}
+
+// outputReference is a module.<name>.<output> expression in an example. Output
+// is empty when the whole module object is referenced.
+type outputReference struct {
+	Module string
+	Output string
+	Range  hcl.Range
+}
+
+func outputReferences(bodies []*hclsyntax.Body, moduleNames []string) []outputReference {
+	var refs []outputReference
+	for _, body := range bodies {
+		hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			expr, ok := node.(*hclsyntax.ScopeTraversalExpr)
+			if !ok || expr.Traversal.RootName() != "module" || len(expr.Traversal) < 2 {
+				return nil
+			}
+			step, ok := expr.Traversal[1].(hcl.TraverseAttr)
+			if !ok || !slices.Contains(moduleNames, step.Name) {
+				return nil
+			}
+			ref := outputReference{Module: step.Name, Range: expr.SrcRange}
+			if len(expr.Traversal) > 2 {
+				output, ok := expr.Traversal[2].(hcl.TraverseAttr)
+				if !ok {
+					// An index into a module with count or for_each.
+					if len(expr.Traversal) < 4 {
+						return nil
+					}
+					if output, ok = expr.Traversal[3].(hcl.TraverseAttr); !ok {
+						return nil
+					}
+				}
+				ref.Output = output.Name
+			}
+			refs = append(refs, ref)
+			return nil
+		})
+	}
+	return refs
+}
+
+func AnalyzeCoverage(moduleRoot, examplesPath string, moduleInfo ModuleInfo) (*CoverageReport, error) {
+	rootBodies, err := parseTerraformFiles(moduleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CoverageReport{
+		Variables: blockLabels(rootBodies, "variable"),
+		Outputs:   blockLabels(rootBodies, "output"),
+	}
+
+	setVariables := make(map[string]bool)
+	usedOutputs := make(map[string]bool)
+	allOutputsUsed := false
+
+	entries, err := os.ReadDir(examplesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read examples directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		exampleDir := filepath.Join(examplesPath, entry.Name())
+		bodies, err := parseTerraformFiles(exampleDir)
+		if err != nil {
+			return nil, err
+		}
+
+		calls := modulesUnderTest(bodies, exampleDir, moduleRoot, moduleInfo)
+		var moduleNames []string
+		for _, call := range calls {
+			moduleNames = append(moduleNames, call.Labels[0])
+			for name := range call.Body.Attributes {
+				if !slices.Contains(moduleMetaArguments, name) {
+					setVariables[name] = true
+				}
+			}
+		}
+
+		for _, ref := range outputReferences(bodies, moduleNames) {
+			if ref.Output == "" {
+				allOutputsUsed = true
+			} else {
+				usedOutputs[ref.Output] = true
+			}
+		}
+	}
+
+	for _, variable := range report.Variables {
+		if !setVariables[variable] {
+			report.UnsetVariables = append(report.UnsetVariables, variable)
+		}
+	}
+	if !allOutputsUsed {
+		for _, output := range report.Outputs {
+			if !usedOutputs[output] {
+				report.UnusedOutputs = append(report.UnusedOutputs, output)
+			}
+		}
+	}
+	return report, nil
+}
+
+func TestVariableCoverage(t testing.TB, opts ...Option) {
+	config := setupConfigWithOptions(opts...)
+	examplesPath := getExamplesPath(config)
+	moduleRoot := filepath.Dir(examplesPath)
+
+	moduleInfo := config.moduleInfo()
+
+	report, err := AnalyzeCoverage(moduleRoot, examplesPath, moduleInfo)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Failed to analyze coverage: %v", err)))
+	}
+
+	t.Logf("Variable coverage: %.1f%% (%d of %d variables set by at least one example)",
+		report.VariableCoverage(), len(report.Variables)-len(report.UnsetVariables), len(report.Variables))
+	if len(report.UnsetVariables) > 0 {
+		t.Logf("Variables never set by any example: %s", strings.Join(report.UnsetVariables, ", "))
+	}
+	if len(report.UnusedOutputs) > 0 {
+		t.Logf("Outputs never consumed by any example: %s", strings.Join(report.UnusedOutputs, ", "))
+	}
+
+	if config.CoverageThreshold > 0 && report.VariableCoverage() < config.CoverageThreshold {
+		t.Error(errorText(fmt.Sprintf("Variable coverage %.1f%% is below threshold %.1f%%", report.VariableCoverage(), config.CoverageThreshold)))
+	}
+}
@@ -0,0 +1,103 @@
+package validor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func setupCoverageRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	writeTestFile(t, filepath.Join(root, "variables.tf"), `
+variable "vnet" {}
+variable "naming" {}
+variable "tags" {}
+variable "location" {}
+`)
+	writeTestFile(t, filepath.Join(root, "outputs.tf"), `
+output "vnet" { value = 1 }
+output "subnets" { value = 2 }
+`)
+	writeTestFile(t, filepath.Join(root, "examples", "default", "main.tf"), `
+module "network" {
+  source  = "cloudnationhq/vnet/azure"
+  version = "~> 9.0"
+
+  vnet   = {}
+  naming = {}
+}
+
+module "rg" {
+  source = "cloudnationhq/rg/azure"
+  location = "westeurope"
+}
+
+output "id" {
+  value = module.network.vnet.id
+}
+`)
+	writeTestFile(t, filepath.Join(root, "examples", "local", "main.tf"), `
+module "network" {
+  source = "../../"
+  tags   = {}
+}
+`)
+	return root
+}
+
+func TestAnalyzeCoverage(t *testing.T) {
+	root := setupCoverageRepo(t)
+	moduleInfo := ModuleInfo{Namespace: "cloudnationhq", Name: "vnet", Provider: "azure"}
+
+	report, err := AnalyzeCoverage(root, filepath.Join(root, "examples"), moduleInfo)
+	if err != nil {
+		t.Fatalf("AnalyzeCoverage() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(report.UnsetVariables, []string{"location"}) {
+		t.Errorf("UnsetVariables = %v, want [location]", report.UnsetVariables)
+	}
+	if !reflect.DeepEqual(report.UnusedOutputs, []string{"subnets"}) {
+		t.Errorf("UnusedOutputs = %v, want [subnets]", report.UnusedOutputs)
+	}
+	if got := report.VariableCoverage(); got != 75 {
+		t.Errorf("VariableCoverage() = %v, want 75", got)
+	}
+}
+
+func TestAnalyzeCoverage_WholeModuleReference(t *testing.T) {
+	root := setupCoverageRepo(t)
+	writeTestFile(t, filepath.Join(root, "examples", "local", "outputs.tf"), `
+output "all" {
+  value = module.network
+}
+`)
+
+	report, err := AnalyzeCoverage(root, filepath.Join(root, "examples"), ModuleInfo{})
+	if err != nil {
+		t.Fatalf("AnalyzeCoverage() error = %v", err)
+	}
+	if len(report.UnusedOutputs) != 0 {
+		t.Errorf("referencing the whole module should consume all outputs, got %v", report.UnusedOutputs)
+	}
+}
+
+func TestCoverageReport_NoVariables(t *testing.T) {
+	report := &CoverageReport{}
+	if got := report.VariableCoverage(); got != 100 {
+		t.Errorf("VariableCoverage() with no variables = %v, want 100", got)
+	}
+}
@@ -0,0 +1,220 @@
+package validor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CredentialSet is a set of environment variables, such as the ARM_ variables
+// of a subscription or the AWS_ variables of an account, that an example runs
+// with.
+type CredentialSet struct {
+	Name string
+	Env  map[string]string
+}
+
+// WithCredentialPool gives every example a credential set from sets for the
+// time it runs, up to and including its destroy, so examples running in
+// parallel use different subscriptions or accounts and do not collide on
+// their quotas. Examples wait while every set is in use.
+func WithCredentialPool(sets ...CredentialSet) Option {
+	return func(c *Config) { c.CredentialSets = append(c.CredentialSets, sets...) }
+}
+
+// WithCredentialPoolFile adds the credential sets in path to the pool, see
+// LoadCredentialSets.
+func WithCredentialPoolFile(path string) Option {
+	return func(c *Config) { c.CredentialPool = path }
+}
+
+// LoadCredentialSets reads credential sets from path. A YAML file maps the
+// name of each set to its variables; a directory holds a KEY=VALUE file per
+// set, named after the file without its extension. Values are expanded from
+// the environment, so sets can refer to CI secrets as ${NAME}.
+func LoadCredentialSets(path string) ([]CredentialSet, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential pool: %w", err)
+	}
+
+	var sets []CredentialSet
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credential pool: %w", err)
+		}
+		var named map[string]map[string]string
+		if err := yaml.Unmarshal(data, &named); err != nil {
+			return nil, fmt.Errorf("failed to parse credential pool %s: %w", path, err)
+		}
+		for name, env := range named {
+			sets = append(sets, CredentialSet{Name: name, Env: env})
+		}
+	} else {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credential pool: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(path, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read credential pool: %w", err)
+			}
+			env, err := parseEnvFile(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse credential set %s: %w", entry.Name(), err)
+			}
+			sets = append(sets, CredentialSet{Name: strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())), Env: env})
+		}
+	}
+
+	for _, set := range sets {
+		for key, value := range set.Env {
+			set.Env[key] = os.ExpandEnv(value)
+		}
+	}
+	slices.SortFunc(sets, func(a, b CredentialSet) int { return strings.Compare(a.Name, b.Name) })
+	return sets, nil
+}
+
+// parseEnvFile parses KEY=VALUE lines, skipping blank lines and comments. An
+// export prefix and quotes around the value are removed.
+func parseEnvFile(data []byte) (map[string]string, error) {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[key] = value
+	}
+	return env, scanner.Err()
+}
+
+// CredentialPool hands out credential sets to one example at a time.
+type CredentialPool struct {
+	sets chan CredentialSet
+	size int
+}
+
+func NewCredentialPool(sets ...CredentialSet) (*CredentialPool, error) {
+	if len(sets) == 0 {
+		return nil, errors.New("the credential pool has no credential sets")
+	}
+	pool := &CredentialPool{sets: make(chan CredentialSet, len(sets)), size: len(sets)}
+	seen := make(map[string]bool)
+	for _, set := range sets {
+		if set.Name == "" || seen[set.Name] {
+			return nil, fmt.Errorf("credential set names must be unique and not empty, got %q", set.Name)
+		}
+		if len(set.Env) == 0 {
+			return nil, fmt.Errorf("credential set %s has no variables", set.Name)
+		}
+		seen[set.Name] = true
+		pool.sets <- set
+	}
+	return pool, nil
+}
+
+// Size returns the number of credential sets in the pool.
+func (p *CredentialPool) Size() int {
+	return p.size
+}
+
+// Checkout takes a credential set from the pool, waiting until one is returned
+// when all of them are in use.
+func (p *CredentialPool) Checkout(ctx context.Context) (CredentialSet, error) {
+	select {
+	case set := <-p.sets:
+		return set, nil
+	default:
+	}
+	select {
+	case set := <-p.sets:
+		return set, nil
+	case <-ctx.Done():
+		return CredentialSet{}, ctx.Err()
+	}
+}
+
+// Return puts a credential set taken with Checkout back into the pool.
+func (p *CredentialPool) Return(set CredentialSet) {
+	p.sets <- set
+}
+
+func (c *Config) credentialPool() (*CredentialPool, error) {
+	sets := slices.Clone(c.CredentialSets)
+	if c.CredentialPool != "" {
+		loaded, err := LoadCredentialSets(c.CredentialPool)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, loaded...)
+	}
+	if len(sets) == 0 {
+		return nil, nil
+	}
+	return NewCredentialPool(sets...)
+}
+
+// useCredentials checks out a credential set for the module and sets its
+// variables on the module. The returned function restores the variables the
+// module had and returns the set to the pool.
+func (m *Module) useCredentials(ctx context.Context, t testing.TB, pool *CredentialPool) (func(), error) {
+	var set CredentialSet
+	select {
+	case set = <-pool.sets:
+	default:
+		t.Logf("Waiting for one of the %d credential sets to be returned", pool.Size())
+		var err error
+		if set, err = pool.Checkout(ctx); err != nil {
+			return nil, fmt.Errorf("failed to check out a credential set: %w", err)
+		}
+	}
+	t.Logf("Using credential set %s for module %s", set.Name, m.Name)
+
+	if m.Options.EnvVars == nil {
+		m.Options.EnvVars = make(map[string]string)
+	}
+	previous := maps.Clone(m.Options.EnvVars)
+	for key, value := range set.Env {
+		m.Options.EnvVars[key] = value
+		if m.masker != nil {
+			m.masker.Add(value)
+		}
+	}
+	return func() {
+		for key := range set.Env {
+			if value, ok := previous[key]; ok {
+				m.Options.EnvVars[key] = value
+			} else {
+				delete(m.Options.EnvVars, key)
+			}
+		}
+		pool.Return(set)
+	}, nil
+}
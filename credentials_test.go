@@ -0,0 +1,143 @@
+package validor
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadCredentialSets(t *testing.T) {
+	t.Setenv("SUB_B_SECRET", "s3cret")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"pool.yaml":      "sub-b:\n  ARM_SUBSCRIPTION_ID: bbbb\n  ARM_CLIENT_SECRET: ${SUB_B_SECRET}\nsub-a:\n  ARM_SUBSCRIPTION_ID: aaaa\n",
+		"pool/sub-a.env": "# subscription a\nexport ARM_SUBSCRIPTION_ID=\"aaaa\"\n\nARM_CLIENT_SECRET='${SUB_B_SECRET}'\n",
+		"pool/sub-b.env": "ARM_SUBSCRIPTION_ID=bbbb\n",
+		"pool/.gitkeep":  "",
+	})
+
+	tests := []struct {
+		name string
+		path string
+		want []CredentialSet
+	}{
+		{
+			name: "yaml file",
+			path: "pool.yaml",
+			want: []CredentialSet{
+				{Name: "sub-a", Env: map[string]string{"ARM_SUBSCRIPTION_ID": "aaaa"}},
+				{Name: "sub-b", Env: map[string]string{"ARM_SUBSCRIPTION_ID": "bbbb", "ARM_CLIENT_SECRET": "s3cret"}},
+			},
+		},
+		{
+			name: "directory of env files",
+			path: "pool",
+			want: []CredentialSet{
+				{Name: "sub-a", Env: map[string]string{"ARM_SUBSCRIPTION_ID": "aaaa", "ARM_CLIENT_SECRET": "s3cret"}},
+				{Name: "sub-b", Env: map[string]string{"ARM_SUBSCRIPTION_ID": "bbbb"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadCredentialSets(filepath.Join(dir, tt.path))
+			if err != nil {
+				t.Fatalf("LoadCredentialSets() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadCredentialSets() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	writeFiles(t, dir, map[string]string{"invalid/sub-a.env": "ARM_SUBSCRIPTION_ID\n"})
+	if _, err := LoadCredentialSets(filepath.Join(dir, "invalid")); err == nil || !strings.Contains(err.Error(), "line 1: expected KEY=VALUE") {
+		t.Errorf("LoadCredentialSets() error = %v, want a parse error", err)
+	}
+}
+
+func TestNewCredentialPool(t *testing.T) {
+	env := map[string]string{"ARM_SUBSCRIPTION_ID": "aaaa"}
+	tests := []struct {
+		name    string
+		sets    []CredentialSet
+		wantErr string
+	}{
+		{name: "empty", wantErr: "no credential sets"},
+		{name: "duplicate name", sets: []CredentialSet{{Name: "a", Env: env}, {Name: "a", Env: env}}, wantErr: "must be unique"},
+		{name: "no variables", sets: []CredentialSet{{Name: "a"}}, wantErr: "has no variables"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCredentialPool(tt.sets...); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewCredentialPool() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestModule_UseCredentials(t *testing.T) {
+	pool, err := NewCredentialPool(CredentialSet{Name: "sub-a", Env: map[string]string{"ARM_SUBSCRIPTION_ID": "aaaa", "ARM_CLIENT_SECRET": "s3cret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	module := NewModule("default", t.TempDir())
+	module.Options.EnvVars = map[string]string{"ARM_SUBSCRIPTION_ID": "default", "TF_LOG": "info"}
+
+	release, err := module.useCredentials(context.Background(), t, pool)
+	if err != nil {
+		t.Fatalf("useCredentials() error = %v", err)
+	}
+	if want := map[string]string{"ARM_SUBSCRIPTION_ID": "aaaa", "ARM_CLIENT_SECRET": "s3cret", "TF_LOG": "info"}; !reflect.DeepEqual(module.Options.EnvVars, want) {
+		t.Errorf("EnvVars = %v, want %v", module.Options.EnvVars, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tb := &recordingTB{TB: t}
+	if _, err := NewModule("complete", t.TempDir()).useCredentials(ctx, tb, pool); err == nil {
+		t.Error("expected an error while every credential set is in use")
+	}
+	if len(tb.logs) != 1 || !strings.Contains(tb.logs[0], "Waiting for one of the 1 credential sets") {
+		t.Errorf("logs = %q, want a waiting message", tb.logs)
+	}
+
+	release()
+	if want := map[string]string{"ARM_SUBSCRIPTION_ID": "default", "TF_LOG": "info"}; !reflect.DeepEqual(module.Options.EnvVars, want) {
+		t.Errorf("EnvVars after release = %v, want %v", module.Options.EnvVars, want)
+	}
+	if _, err := pool.Checkout(ctx); err != nil {
+		t.Errorf("the credential set was not returned: %v", err)
+	}
+}
+
+func TestRunModuleTests_CredentialPool(t *testing.T) {
+	var used []string
+	var modules []*Module
+	for _, name := range []string{"first", "second", "third"} {
+		module := NewModule(name, t.TempDir())
+		module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+			used = append(used, m.Name+"="+m.Options.EnvVars["ARM_SUBSCRIPTION_ID"])
+			return nil
+		}
+		module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+		modules = append(modules, module)
+	}
+
+	config := &Config{CredentialSets: []CredentialSet{
+		{Name: "sub-a", Env: map[string]string{"ARM_SUBSCRIPTION_ID": "aaaa"}},
+		{Name: "sub-b", Env: map[string]string{"ARM_SUBSCRIPTION_ID": "bbbb"}},
+	}}
+	runModuleTests(&recordingTB{TB: t}, Runners(modules), true, config, nil, "registry")
+
+	if want := []string{"first=aaaa", "second=bbbb", "third=aaaa"}; !reflect.DeepEqual(used, want) {
+		t.Errorf("credential sets = %v, want %v", used, want)
+	}
+	for _, module := range modules {
+		if _, ok := module.Options.EnvVars["ARM_SUBSCRIPTION_ID"]; ok {
+			t.Errorf("credentials of %s were not removed after its run", module.Name)
+		}
+	}
+}
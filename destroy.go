@@ -0,0 +1,106 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// WithDestroyRetries retries a failed destroy up to retries times, waiting
+// backoff before the first retry and twice as long before each next one.
+// Destroys often fail on dependency ordering or soft-delete protection that
+// clears up after a while. Retries skip the refresh, since the failed attempt
+// already refreshed the state and the resources it destroyed are gone from it.
+func WithDestroyRetries(retries int, backoff time.Duration) Option {
+	return func(c *Config) {
+		c.DestroyRetries = retries
+		c.DestroyBackoff = backoff
+	}
+}
+
+// WithDestroyTimeout limits how long destroying an example may take, including
+// its retries, independent of the example's timeout.
+func WithDestroyTimeout(timeout time.Duration) Option {
+	return func(c *Config) { c.DestroyTimeout = timeout }
+}
+
+func (m *Module) useDestroyPolicy(config *Config) {
+	m.destroyRetries = config.DestroyRetries
+	m.destroyBackoff = config.DestroyBackoff
+	m.destroyTimeout = config.DestroyTimeout
+}
+
+// destroyInfrastructure runs terraform destroy with the module's destroy
+// retries and timeout.
+func (m *Module) destroyInfrastructure(ctx context.Context, t testing.TB) error {
+	return m.retryDestroy(ctx, t, func(ctx context.Context, retry bool) error {
+		if retry {
+			return m.terraformDestroy(ctx, t, "-refresh=false")
+		}
+		return m.terraformDestroy(ctx, t)
+	})
+}
+
+// retryDestroy calls destroy until it succeeds, the module's destroy retries
+// are used up or its destroy timeout passes. retry is set on every call after
+// the first.
+func (m *Module) retryDestroy(ctx context.Context, t testing.TB, destroy func(ctx context.Context, retry bool) error) error {
+	if m.destroyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.destroyTimeout)
+		defer cancel()
+	}
+
+	backoff := m.destroyBackoff
+	for attempt := 0; ; attempt++ {
+		err := destroy(ctx, attempt > 0)
+		if err != nil && m.destroyTimeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("destroy exceeded timeout of %s: %w", m.destroyTimeout, err)
+		}
+		if err == nil || attempt >= m.destroyRetries || ctx.Err() != nil {
+			return err
+		}
+		t.Logf("Destroy of module %s failed, retrying in %s (%d of %d): %v", m.Name, backoff, attempt+1, m.destroyRetries, err)
+		if sleepContext(ctx, backoff) != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// verifyDestroyed checks that nothing managed is left in the state after a
+// destroy that reported success, recording what is left in Leftovers.
+func (m *Module) verifyDestroyed(ctx context.Context, t testing.TB) error {
+	if m.stateHook == nil && m.terragrunt == TerragruntStack {
+		return nil
+	}
+	state, err := m.State(ctx, t)
+	if err != nil {
+		t.Logf("Warning: failed to verify that %s was destroyed: %v", m.Name, err)
+		return nil
+	}
+	m.Leftovers = nil
+	for _, address := range state.Addresses {
+		if managedAddress(address) {
+			m.Leftovers = append(m.Leftovers, address)
+		}
+	}
+	if len(m.Leftovers) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d resource(s) remain in state after destroy: %s", len(m.Leftovers), strings.Join(m.Leftovers, ", "))
+}
+
+// managedAddress reports whether a state address is a managed resource rather
+// than a data source.
+func managedAddress(address string) bool {
+	parts := strings.Split(address, ".")
+	i := 0
+	for i+2 < len(parts) && parts[i] == "module" {
+		i += 2
+	}
+	return parts[i] != "data"
+}
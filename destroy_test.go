@@ -0,0 +1,144 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModule_RetryDestroy(t *testing.T) {
+	tests := []struct {
+		name       string
+		retries    int
+		failures   int
+		wantErr    bool
+		wantCalls  int
+		wantDelays []time.Duration
+	}{
+		{name: "no retries", failures: 1, wantErr: true, wantCalls: 1},
+		{name: "succeeds on a retry", retries: 3, failures: 2, wantCalls: 3, wantDelays: []time.Duration{time.Second, 2 * time.Second}},
+		{name: "retries used up", retries: 2, failures: 5, wantErr: true, wantCalls: 3, wantDelays: []time.Duration{time.Second, 2 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := stubSleep(t)
+			module := NewModule("default", t.TempDir())
+			module.useDestroyPolicy(&Config{DestroyRetries: tt.retries, DestroyBackoff: time.Second})
+
+			var retries []bool
+			err := module.retryDestroy(context.Background(), t, func(ctx context.Context, retry bool) error {
+				retries = append(retries, retry)
+				if len(retries) <= tt.failures {
+					return errors.New("soft-deleted key vault still exists")
+				}
+				return nil
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("retryDestroy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(retries) != tt.wantCalls || retries[0] || len(retries) > 1 && !retries[1] {
+				t.Errorf("destroy calls = %v, want %d with only the first not a retry", retries, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(*delays, tt.wantDelays) {
+				t.Errorf("delays = %v, want %v", *delays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+func TestModule_RetryDestroyTimeout(t *testing.T) {
+	module := NewModule("default", t.TempDir())
+	module.destroyTimeout = 50 * time.Millisecond
+	module.destroyRetries = 5
+
+	calls := 0
+	err := module.retryDestroy(context.Background(), t, func(ctx context.Context, retry bool) error {
+		calls++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err == nil || !strings.Contains(err.Error(), "destroy exceeded timeout of 50ms") || calls != 1 {
+		t.Errorf("retryDestroy() error = %v after %d calls, want a timeout after one", err, calls)
+	}
+}
+
+func TestModule_DestroyRetriesWithoutRefresh(t *testing.T) {
+	stubSleep(t)
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	module := NewModule("default", t.TempDir())
+	module.Options.TerraformBinary = fakeTerraform(t, "echo \"$@\" >> "+calls+"\n[ -f "+calls+".failed ] && exit 0\ntouch "+calls+".failed\necho 'Error: deleting subnet: in use'\nexit 1\n")
+	module.destroyRetries = 1
+
+	if err := module.Destroy(context.Background(), t); err != nil {
+		t.Fatalf("Destroy() error = %v", err)
+	}
+	content, _ := os.ReadFile(calls)
+	var lines []string
+	for line := range strings.SplitSeq(strings.TrimSpace(string(content)), "\n") {
+		if strings.HasPrefix(line, "destroy") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 2 || strings.Contains(lines[0], "-refresh=false") || !strings.Contains(lines[1], "-refresh=false") {
+		t.Errorf("destroy ran with %q, want only the retry to skip the refresh", lines)
+	}
+}
+
+func TestModule_DestroyVerifiesEmptyState(t *testing.T) {
+	tests := []struct {
+		name          string
+		state         string
+		wantLeftovers []string
+	}{
+		{name: "empty state", state: `{}`},
+		{name: "only data sources", state: `{"values":{"root_module":{"resources":[{"address":"data.azurerm_client_config.current"}]}}}`},
+		{
+			name:          "partial destroy",
+			state:         `{"values":{"root_module":{"resources":[{"address":"azurerm_resource_group.rg"},{"address":"data.azurerm_client_config.current"}],"child_modules":[{"resources":[{"address":"module.kv.azurerm_key_vault.this"},{"address":"module.kv.data.azurerm_subscription.current"}]}]}}}`,
+			wantLeftovers: []string{"azurerm_resource_group.rg", "module.kv.azurerm_key_vault.this"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := NewModule("default", t.TempDir())
+			module.stateHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) { return []byte(tt.state), nil }
+			module.destroyHook = func(ctx context.Context, t testing.TB, m *Module) error { return nil }
+			cleaned := false
+			module.cleanupHook = func(ctx context.Context, t testing.TB, m *Module) error {
+				cleaned = true
+				return nil
+			}
+
+			err := module.Destroy(context.Background(), &recordingTB{TB: t})
+			if (err != nil) != (tt.wantLeftovers != nil) {
+				t.Fatalf("Destroy() error = %v", err)
+			}
+			if !reflect.DeepEqual(module.Leftovers, tt.wantLeftovers) {
+				t.Errorf("Leftovers = %v, want %v", module.Leftovers, tt.wantLeftovers)
+			}
+			if cleaned == (tt.wantLeftovers != nil) {
+				t.Errorf("cleaned = %v, the state should only be kept when resources remain", cleaned)
+			}
+			if tt.wantLeftovers == nil {
+				return
+			}
+			if len(module.Errors) != 1 || !strings.Contains(module.Errors[0], "2 resource(s) remain in state after destroy") {
+				t.Errorf("errors = %v", module.Errors)
+			}
+			tb := &recordingTB{}
+			PrintModuleSummary(tb, []*Module{module})
+			if output := strings.Join(tb.logs, "\n"); !strings.Contains(output, "Left in state after destroy: azurerm_resource_group.rg, module.kv.azurerm_key_vault.this") {
+				t.Errorf("summary should list the leftovers:\n%s", output)
+			}
+		})
+	}
+}
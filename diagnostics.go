@@ -0,0 +1,166 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// WithDiagnostics validates every example after init and records the
+// warnings terraform reports, listing deprecations in the summary.
+func WithDiagnostics(enabled bool) Option {
+	return func(c *Config) { c.Diagnostics = enabled }
+}
+
+// WithFailOnWarnings fails examples for which terraform reports warnings,
+// such as the use of deprecated arguments. It implies WithDiagnostics.
+func WithFailOnWarnings(enabled bool) Option {
+	return func(c *Config) { c.FailOnWarnings = enabled }
+}
+
+// Diagnostic is a warning or error terraform reported for an example.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+func (d Diagnostic) String() string {
+	s := d.Summary
+	if d.Detail != "" {
+		s += ": " + strings.Join(strings.Fields(d.Detail), " ")
+	}
+	if d.Location != "" {
+		s += " at " + d.Location
+	}
+	return s
+}
+
+// Deprecation reports whether the diagnostic warns about something that is
+// deprecated and will be removed, such as a provider argument.
+func (d Diagnostic) Deprecation() bool {
+	return d.Severity == "warning" && strings.Contains(strings.ToLower(d.Summary+" "+d.Detail), "deprecat")
+}
+
+// ParseDiagnostics parses the output of terraform validate -json into its
+// diagnostics.
+func ParseDiagnostics(validateJSON []byte) ([]Diagnostic, error) {
+	var result struct {
+		Diagnostics []struct {
+			Severity string `json:"severity"`
+			Summary  string `json:"summary"`
+			Detail   string `json:"detail"`
+			Range    *struct {
+				Filename string `json:"filename"`
+				Start    struct {
+					Line int `json:"line"`
+				} `json:"start"`
+			} `json:"range"`
+		} `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(validateJSON, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse validate json: %w", err)
+	}
+
+	var diagnostics []Diagnostic
+	for _, d := range result.Diagnostics {
+		diagnostic := Diagnostic{Severity: d.Severity, Summary: d.Summary, Detail: d.Detail}
+		if d.Range != nil {
+			diagnostic.Location = fmt.Sprintf("%s:%d", d.Range.Filename, d.Range.Start.Line)
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics, nil
+}
+
+func (m *Module) validate(ctx context.Context, t testing.TB) ([]byte, error) {
+	if m.validateHook != nil {
+		return m.validateHook(ctx, t, m)
+	}
+	if m.terragrunt == TerragruntStack {
+		return nil, fmt.Errorf("validating is %w", errTerragruntStack)
+	}
+	// validate exits non-zero when the configuration is invalid, but still
+	// describes why on stdout.
+	args := append([]string{"validate", "-json", "-no-color"}, m.Options.ExtraArgs.Validate...)
+	out, err := terraform.RunTerraformCommandAndGetStdoutE(t, m.Options, args...)
+	if err != nil && !json.Valid([]byte(out)) {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// checkDiagnostics validates the initialized example and records what
+// terraform reports in Diagnostics. Errors fail the example, and so do
+// warnings when failOnWarnings is set; otherwise they are logged.
+func (m *Module) checkDiagnostics(ctx context.Context, t testing.TB) error {
+	t.Helper()
+
+	out, err := m.validate(ctx, t)
+	if err == nil {
+		m.Diagnostics, err = ParseDiagnostics(out)
+	}
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform validate", Err: err})
+	}
+
+	var errs, warnings []string
+	for _, diagnostic := range m.Diagnostics {
+		if diagnostic.Severity == "error" {
+			errs = append(errs, diagnostic.String())
+			continue
+		}
+		warnings = append(warnings, diagnostic.String())
+		if !m.failOnWarnings {
+			t.Logf("Warning: %s", diagnostic)
+		}
+	}
+
+	if len(errs) > 0 {
+		err := fmt.Errorf("%d error(s): %s", len(errs), strings.Join(errs, "; "))
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform validate", Err: err})
+	}
+	if len(warnings) > 0 && m.failOnWarnings {
+		err := fmt.Errorf("%d warning(s): %s", len(warnings), strings.Join(warnings, "; "))
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform validate", Err: err})
+	}
+	return nil
+}
+
+// Deprecations returns the deprecation warnings terraform reported for the
+// example.
+func (m *Module) Deprecations() []Diagnostic {
+	var deprecations []Diagnostic
+	for _, diagnostic := range m.Diagnostics {
+		if diagnostic.Deprecation() {
+			deprecations = append(deprecations, diagnostic)
+		}
+	}
+	return deprecations
+}
+
+func printDeprecations(tb testLogger, modules []*Module) {
+	printed := false
+	for _, module := range modules {
+		deprecations := module.Deprecations()
+		if len(deprecations) == 0 {
+			continue
+		}
+		if !printed {
+			tb.Log("Deprecation warnings:")
+			printed = true
+		}
+		tb.Logf("  %s:", module.Name)
+		for _, deprecation := range deprecations {
+			tb.Logf("    - %s", deprecation)
+		}
+	}
+	if printed {
+		tb.Log("")
+	}
+}
@@ -0,0 +1,107 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validateJSON = `{"valid":true,"error_count":0,"warning_count":2,"diagnostics":[
+	{"severity":"warning","summary":"Argument is deprecated","detail":"Use network_rules\ninstead.","range":{"filename":"main.tf","start":{"line":12}}},
+	{"severity":"warning","summary":"Redundant empty provider block"}
+]}`
+
+func TestParseDiagnostics(t *testing.T) {
+	got, err := ParseDiagnostics([]byte(validateJSON))
+	if err != nil {
+		t.Fatalf("ParseDiagnostics() error = %v", err)
+	}
+	want := []Diagnostic{
+		{Severity: "warning", Summary: "Argument is deprecated", Detail: "Use network_rules\ninstead.", Location: "main.tf:12"},
+		{Severity: "warning", Summary: "Redundant empty provider block"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDiagnostics() = %+v, want %+v", got, want)
+	}
+	if got[0].String() != "Argument is deprecated: Use network_rules instead. at main.tf:12" {
+		t.Errorf("String() = %q", got[0].String())
+	}
+	if !got[0].Deprecation() || got[1].Deprecation() {
+		t.Errorf("Deprecation() = %v, %v, want true, false", got[0].Deprecation(), got[1].Deprecation())
+	}
+
+	if _, err := ParseDiagnostics([]byte("{")); err == nil {
+		t.Error("expected an error for invalid validate json")
+	}
+}
+
+func TestModule_CheckDiagnostics(t *testing.T) {
+	tests := []struct {
+		name           string
+		output         string
+		validateErr    error
+		failOnWarnings bool
+		wantErr        string
+		wantApplied    bool
+	}{
+		{name: "warnings are logged", output: validateJSON, wantApplied: true},
+		{name: "warnings fail when configured", output: validateJSON, failOnWarnings: true, wantErr: "2 warning(s): Argument is deprecated"},
+		{name: "errors fail", output: `{"valid":false,"diagnostics":[{"severity":"error","summary":"Unsupported argument"}]}`, wantErr: "1 error(s): Unsupported argument"},
+		{name: "validate failures fail", validateErr: errors.New("terraform not found"), wantErr: "terraform not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied := false
+			module := NewModule("default", t.TempDir())
+			module.diagnostics = true
+			module.failOnWarnings = tt.failOnWarnings
+			module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+				applied = true
+				return nil
+			}
+			module.validateHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+				return []byte(tt.output), tt.validateErr
+			}
+
+			tb := &recordingTB{TB: t}
+			err := module.Apply(context.Background(), tb)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "terraform validate")) {
+				t.Fatalf("Apply() error = %v, want %q", err, tt.wantErr)
+			}
+			if applied != tt.wantApplied {
+				t.Errorf("applied = %v, want %v", applied, tt.wantApplied)
+			}
+			if tt.wantApplied && !strings.Contains(strings.Join(tb.logs, "\n"), "Warning: Redundant empty provider block") {
+				t.Errorf("warnings should be logged, got %v", tb.logs)
+			}
+		})
+	}
+}
+
+func TestPrintDeprecations(t *testing.T) {
+	deprecated := NewModule("default", "")
+	deprecated.Diagnostics, _ = ParseDiagnostics([]byte(validateJSON))
+	clean := NewModule("clean", "")
+
+	tb := &recordingTB{}
+	printDeprecations(tb, []*Module{clean, deprecated})
+	want := []string{"Deprecation warnings:", "  default:", "    - Argument is deprecated: Use network_rules instead. at main.tf:12", ""}
+	if !reflect.DeepEqual(tb.logs, want) {
+		t.Errorf("printDeprecations() logged %q, want %q", tb.logs, want)
+	}
+	if got := newModuleReport(deprecated).Deprecations; len(got) != 1 || got[0].Location != "main.tf:12" {
+		t.Errorf("report deprecations = %+v", got)
+	}
+
+	tb = &recordingTB{}
+	printDeprecations(tb, []*Module{clean})
+	if len(tb.logs) != 0 {
+		t.Errorf("printDeprecations() without deprecations logged %q", tb.logs)
+	}
+}
@@ -0,0 +1,172 @@
+package validor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var (
+	docsAnchorRegex = regexp.MustCompile(`<a name="(?:input|output)_([^"]+)"`)
+	docsLinkRegex   = regexp.MustCompile(`\[([^\]]+)\]`)
+)
+
+type DocsDrift struct {
+	UndocumentedInputs  []string
+	StaleInputs         []string
+	UndocumentedOutputs []string
+	StaleOutputs        []string
+}
+
+func (d *DocsDrift) HasDrift() bool {
+	return len(d.UndocumentedInputs)+len(d.StaleInputs)+len(d.UndocumentedOutputs)+len(d.StaleOutputs) > 0
+}
+
+func (d *DocsDrift) Error() string {
+	var parts []string
+	add := func(label string, names []string) {
+		if len(names) > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %s", label, strings.Join(names, ", ")))
+		}
+	}
+	add("undocumented inputs", d.UndocumentedInputs)
+	add("documented inputs that do not exist", d.StaleInputs)
+	add("undocumented outputs", d.UndocumentedOutputs)
+	add("documented outputs that do not exist", d.StaleOutputs)
+	return "README is out of date: " + strings.Join(parts, "; ")
+}
+
+func docsTableName(cell string) string {
+	cell = strings.TrimSpace(cell)
+	if matches := docsAnchorRegex.FindStringSubmatch(cell); len(matches) == 2 {
+		return strings.ReplaceAll(matches[1], `\_`, "_")
+	}
+	if matches := docsLinkRegex.FindStringSubmatch(cell); len(matches) == 2 {
+		cell = matches[1]
+	}
+	cell = strings.Trim(cell, "`* ")
+	return strings.ReplaceAll(cell, `\_`, "_")
+}
+
+func parseDocsTables(readmePath string) (inputs, outputs []string, err error) {
+	file, err := os.Open(readmePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", readmePath, err)
+	}
+	defer file.Close()
+
+	var section *[]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "#") {
+			heading := strings.ToLower(strings.TrimSpace(strings.TrimLeft(line, "#")))
+			switch heading {
+			case "inputs":
+				section = &inputs
+			case "outputs":
+				section = &outputs
+			default:
+				section = nil
+			}
+			continue
+		}
+
+		if section == nil || !strings.HasPrefix(line, "|") {
+			continue
+		}
+
+		cells := strings.Split(strings.Trim(line, "|"), "|")
+		first := strings.TrimSpace(cells[0])
+		if first == "" || strings.EqualFold(first, "name") || strings.Trim(first, "-: ") == "" {
+			continue
+		}
+		if name := docsTableName(first); name != "" {
+			*section = append(*section, name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", readmePath, err)
+	}
+
+	sort.Strings(inputs)
+	sort.Strings(outputs)
+	return inputs, outputs, nil
+}
+
+func difference(a, b []string) []string {
+	var diff []string
+	for _, item := range a {
+		if !slices.Contains(b, item) {
+			diff = append(diff, item)
+		}
+	}
+	return diff
+}
+
+func CheckDocs(moduleDir string) (*DocsDrift, error) {
+	bodies, err := parseTerraformFiles(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+	variables := blockLabels(bodies, "variable")
+	outputs := blockLabels(bodies, "output")
+
+	documentedInputs, documentedOutputs, err := parseDocsTables(filepath.Join(moduleDir, "README.md"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &DocsDrift{
+		UndocumentedInputs:  difference(variables, documentedInputs),
+		StaleInputs:         difference(documentedInputs, variables),
+		UndocumentedOutputs: difference(outputs, documentedOutputs),
+		StaleOutputs:        difference(documentedOutputs, outputs),
+	}, nil
+}
+
+func discoverDocumentedModules(root string) []string {
+	dirs := []string{root}
+	submodules, _ := filepath.Glob(filepath.Join(root, "modules", "*", "README.md"))
+	for _, readme := range submodules {
+		dirs = append(dirs, filepath.Dir(readme))
+	}
+	return dirs
+}
+
+func TestDocsConsistency(t testing.TB, opts ...Option) {
+	config := setupConfigWithOptions(opts...)
+	root := filepath.Dir(getExamplesPath(config))
+	results := NewTestResults()
+
+	for _, dir := range discoverDocumentedModules(root) {
+		name, err := filepath.Rel(root, dir)
+		if err != nil || name == "." {
+			name = "root"
+		}
+		module := NewModule(name, dir)
+
+		runSubtest(t, name, false, func(t testing.TB) {
+			drift, err := CheckDocs(dir)
+			if err == nil && drift.HasDrift() {
+				err = drift
+			}
+			if err != nil {
+				wrappedErr := &ModuleError{ModuleName: name, Operation: "docs validation", Err: err}
+				module.Errors = append(module.Errors, wrappedErr.Error())
+				t.Error(errorText(wrappedErr.Error()))
+			}
+			results.AddModule(module)
+		})
+	}
+
+	modules, _ := results.GetResults()
+	PrintModuleSummary(t, modules)
+}
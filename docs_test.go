@@ -0,0 +1,88 @@
+package validor
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const terraformDocsReadme = `# Virtual Network
+
+## Inputs
+
+| Name | Description | Type | Default | Required |
+|------|-------------|------|---------|:--------:|
+| <a name="input_vnet"></a> [vnet](#input\_vnet) | vnet config | ` + "`any`" + ` | n/a | yes |
+| <a name="input_resource_group_name"></a> [resource\_group\_name](#input\_resource\_group\_name) | rg | ` + "`string`" + ` | null | no |
+| <a name="input_legacy"></a> [legacy](#input\_legacy) | removed | ` + "`bool`" + ` | false | no |
+
+## Outputs
+
+| Name | Description |
+|------|-------------|
+| ` + "`vnet`" + ` | vnet object |
+`
+
+func TestParseDocsTables(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "README.md"), terraformDocsReadme)
+
+	inputs, outputs, err := parseDocsTables(filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Fatalf("parseDocsTables() error = %v", err)
+	}
+
+	if want := []string{"legacy", "resource_group_name", "vnet"}; !reflect.DeepEqual(inputs, want) {
+		t.Errorf("inputs = %v, want %v", inputs, want)
+	}
+	if want := []string{"vnet"}; !reflect.DeepEqual(outputs, want) {
+		t.Errorf("outputs = %v, want %v", outputs, want)
+	}
+}
+
+func TestCheckDocs(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "README.md"), terraformDocsReadme)
+	writeTestFile(t, filepath.Join(dir, "variables.tf"), `
+variable "vnet" {}
+variable "resource_group_name" {}
+variable "tags" {}
+`)
+	writeTestFile(t, filepath.Join(dir, "outputs.tf"), `
+output "vnet" { value = 1 }
+output "subnets" { value = 2 }
+`)
+
+	drift, err := CheckDocs(dir)
+	if err != nil {
+		t.Fatalf("CheckDocs() error = %v", err)
+	}
+	if !drift.HasDrift() {
+		t.Fatal("expected drift to be detected")
+	}
+	if !reflect.DeepEqual(drift.UndocumentedInputs, []string{"tags"}) {
+		t.Errorf("UndocumentedInputs = %v", drift.UndocumentedInputs)
+	}
+	if !reflect.DeepEqual(drift.StaleInputs, []string{"legacy"}) {
+		t.Errorf("StaleInputs = %v", drift.StaleInputs)
+	}
+	if !reflect.DeepEqual(drift.UndocumentedOutputs, []string{"subnets"}) {
+		t.Errorf("UndocumentedOutputs = %v", drift.UndocumentedOutputs)
+	}
+	if len(drift.StaleOutputs) != 0 {
+		t.Errorf("StaleOutputs = %v", drift.StaleOutputs)
+	}
+}
+
+func TestDiscoverDocumentedModules(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "README.md"), "# root")
+	writeTestFile(t, filepath.Join(root, "modules", "subnet", "README.md"), "# subnet")
+	writeTestFile(t, filepath.Join(root, "modules", "undocumented", "main.tf"), "")
+
+	got := discoverDocumentedModules(root)
+	want := []string{root, filepath.Join(root, "modules", "subnet")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("discoverDocumentedModules() = %v, want %v", got, want)
+	}
+}
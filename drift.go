@@ -0,0 +1,91 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+func WithDriftDetection(wait time.Duration) Option {
+	return func(c *Config) {
+		c.DriftCheck = true
+		c.DriftWait = wait
+	}
+}
+
+// driftedResources returns the addresses the provider reported as changed
+// outside of Terraform.
+func driftedResources(planJSON []byte) ([]string, error) {
+	var plan struct {
+		ResourceDrift []resourceChange `json:"resource_drift"`
+	}
+	if err := json.Unmarshal(planJSON, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan json: %w", err)
+	}
+
+	var addresses []string
+	for _, rc := range plan.ResourceDrift {
+		for _, action := range rc.Change.Actions {
+			if action != "no-op" {
+				addresses = append(addresses, rc.Address)
+				break
+			}
+		}
+	}
+	return addresses, nil
+}
+
+func (m *Module) refreshPlan(ctx context.Context, t testing.TB) ([]byte, error) {
+	if err := m.remotePlanError(); err != nil {
+		return nil, err
+	}
+	if m.refreshHook != nil {
+		return m.refreshHook(ctx, t, m)
+	}
+
+	opts := *m.Options
+	opts.PlanFilePath = filepath.Join(m.Options.TerraformDir, ".terraform", "validor-refresh.tfplan")
+	opts.ExtraArgs.Plan = []string{"-refresh-only"}
+	if _, err := terraform.PlanE(t, &opts); err != nil {
+		return nil, err
+	}
+	out, err := terraform.ShowE(t, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// DetectDrift waits for cloud-side defaults to settle, then fails the module
+// if a refresh-only plan reports changes made outside of Terraform.
+func (m *Module) DetectDrift(ctx context.Context, t testing.TB, wait time.Duration) error {
+	t.Helper()
+	defer m.startStage(StageDrift)()
+
+	if wait > 0 {
+		t.Logf("Waiting %s before checking module %s for drift", wait, m.Name)
+		if err := sleepContext(ctx, wait); err != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "drift detection", Err: err})
+		}
+	}
+
+	planJSON, err := m.refreshPlan(ctx, t)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "drift detection", Err: err})
+	}
+	drifted, err := driftedResources(planJSON)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "drift detection", Err: err})
+	}
+	if len(drifted) > 0 {
+		err := fmt.Errorf("%d resource(s) drifted after apply: %s", len(drifted), strings.Join(drifted, ", "))
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "drift detection", Err: err})
+	}
+	return nil
+}
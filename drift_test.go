@@ -0,0 +1,99 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDriftedResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "no drift", plan: `{"format_version":"1.2"}`},
+		{
+			name: "unchanged resources",
+			plan: `{"resource_drift":[{"address":"azurerm_resource_group.rg","change":{"actions":["no-op"]}}]}`,
+		},
+		{
+			name: "drifted resources",
+			plan: `{"resource_drift":[
+				{"address":"azurerm_storage_account.sa","change":{"actions":["update"]}},
+				{"address":"azurerm_subnet.sn","change":{"actions":["delete"]}}
+			]}`,
+			want: []string{"azurerm_storage_account.sa", "azurerm_subnet.sn"},
+		},
+		{name: "invalid json", plan: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := driftedResources([]byte(tt.plan))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("driftedResources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("driftedResources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModule_DetectDrift(t *testing.T) {
+	tests := []struct {
+		name       string
+		plan       string
+		refreshErr error
+		wait       time.Duration
+		wantDelays []time.Duration
+		wantErr    string
+	}{
+		{
+			name:       "no drift after wait",
+			plan:       `{"resource_drift":[]}`,
+			wait:       30 * time.Second,
+			wantDelays: []time.Duration{30 * time.Second},
+		},
+		{
+			name:    "drift reported",
+			plan:    `{"resource_drift":[{"address":"azurerm_storage_account.sa","change":{"actions":["update"]}}]}`,
+			wantErr: "azurerm_storage_account.sa",
+		},
+		{
+			name:       "refresh failure",
+			refreshErr: errors.New("provider unavailable"),
+			wantErr:    "provider unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := stubSleep(t)
+
+			module := newPlannedModule(t, "default")
+			module.refreshHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+				return []byte(tt.plan), tt.refreshErr
+			}
+
+			err := module.DetectDrift(context.Background(), t, tt.wait)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("DetectDrift() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr) || !module.ApplyFailed) {
+				t.Fatalf("DetectDrift() error = %v, want failed module with %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(*delays, tt.wantDelays) {
+				t.Errorf("delays = %v, want %v", *delays, tt.wantDelays)
+			}
+			if _, ok := module.Durations[StageDrift]; !ok {
+				t.Error("drift stage duration should be recorded")
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package validor
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// WithExecWrapper runs every terraform invocation through wrapper, such as
+// aws-vault exec profile -- or op run --, so credential brokers can provide
+// credentials without changes to the examples. The terraform command and its
+// arguments follow the wrapper's arguments.
+func WithExecWrapper(wrapper []string) Option {
+	return func(c *Config) { c.ExecWrapper = wrapper }
+}
+
+// execWrappers writes a script per terraform binary that runs it through the
+// wrapper, as terratest runs a binary rather than a command line.
+type execWrappers struct {
+	dir     string
+	wrapper []string
+	// container runs terraform in a container instead of the binary.
+	container *containerRun
+	// originals maps each script to the binary it wraps.
+	originals map[string]string
+}
+
+func newExecWrappers(dir string, wrapper []string) *execWrappers {
+	return &execWrappers{dir: dir, wrapper: wrapper, originals: make(map[string]string)}
+}
+
+// execWrappers returns the wrappers that terraform runs through with the
+// exec wrapper and container image of c, writing their scripts to dir, or
+// nil when terraform runs directly. Containers mount the module, the
+// temporary directory and mounts.
+func (c *Config) execWrappers(dir string, mounts ...string) *execWrappers {
+	if len(c.ExecWrapper) == 0 && c.ContainerImage == "" {
+		return nil
+	}
+	wrappers := newExecWrappers(dir, c.ExecWrapper)
+	if c.ContainerImage != "" {
+		mounts = append(mounts, filepath.Dir(getExamplesPath(c)), os.TempDir())
+		wrappers.container = &containerRun{runtime: c.containerRuntime(), image: c.ContainerImage, mounts: containerMounts(mounts)}
+	}
+	return wrappers
+}
+
+// wrap points options at the script for its binary and returns that binary.
+// Options that already run through a script keep it.
+func (w *execWrappers) wrap(options *terraform.Options) (string, error) {
+	binary := cmp.Or(options.TerraformBinary, terraform.DefaultExecutable)
+	if original, ok := w.originals[binary]; ok {
+		return original, nil
+	}
+	for script, original := range w.originals {
+		if original == binary {
+			options.TerraformBinary = script
+			return binary, nil
+		}
+	}
+
+	dir := filepath.Join(w.dir, fmt.Sprintf("wrapper-%d", len(w.originals)+1))
+	var script string
+	var err error
+	if w.container != nil {
+		script, err = w.container.writeScript(dir, w.wrapper)
+	} else {
+		script, err = writeExecWrapper(dir, w.wrapper, binary)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write the exec wrapper for %s: %w", binary, err)
+	}
+	w.originals[script] = binary
+	options.TerraformBinary = script
+	return binary, nil
+}
+
+// writeExecWrapper writes a script to dir, named after binary, that runs
+// binary through wrapper with the arguments it is given.
+func writeExecWrapper(dir string, wrapper []string, binary string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(filepath.Base(binary), ".exe")
+	command := append(append([]string{}, wrapper...), binary)
+
+	var path, content string
+	if goos == "windows" {
+		quoted := make([]string, len(command))
+		for i, arg := range command {
+			quoted[i] = `"` + arg + `"`
+		}
+		path = filepath.Join(dir, name+".cmd")
+		content = "@echo off\r\n" + strings.Join(quoted, " ") + " %*\r\n"
+	} else {
+		quoted := make([]string, len(command))
+		for i, arg := range command {
+			quoted[i] = shellQuote(arg)
+		}
+		path = filepath.Join(dir, name)
+		content = "#!/bin/sh\nexec " + strings.Join(quoted, " ") + " \"$@\"\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// useExecWrapper runs the module's terraform through wrappers, remembering
+// the binary it wraps for the destroy manifest.
+func (m *Module) useExecWrapper(wrappers *execWrappers) error {
+	binary, err := wrappers.wrap(m.Options)
+	if err != nil {
+		return err
+	}
+	m.wrappedBinary = binary
+	return nil
+}
@@ -0,0 +1,72 @@
+package validor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+func TestModule_UseExecWrapper(t *testing.T) {
+	if goos == "windows" {
+		t.Skip("uses a shell script as wrapper")
+	}
+	dir := t.TempDir()
+	wrapper := filepath.Join(dir, "broker")
+	if err := os.WriteFile(wrapper, []byte("#!/bin/sh\n[ \"$1\" = \"--profile=sandbox\" ] || exit 3\nshift\nTF_BROKER=sandbox exec \"$@\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	binary := fakeTerraform(t, "echo \"$TF_BROKER terraform $*\"\n")
+
+	module := NewModule("default", t.TempDir())
+	module.Options.TerraformBinary = binary
+	if err := module.useExecWrapper(newExecWrappers(t.TempDir(), []string{wrapper, "--profile=sandbox"})); err != nil {
+		t.Fatalf("useExecWrapper() error = %v", err)
+	}
+
+	out, err := module.runTerraform(context.Background(), t, "version", "-json")
+	if err != nil {
+		t.Fatalf("runTerraform() error = %v", err)
+	}
+	if !strings.Contains(out, "sandbox terraform version -json") {
+		t.Errorf("output = %q, want terraform to run through the wrapper", out)
+	}
+	if got := manifestExample(module, "1", time.Now()).TerraformBinary; got != binary {
+		t.Errorf("manifest binary = %q, want the wrapped binary %q", got, binary)
+	}
+}
+
+func TestExecWrappers_Wrap(t *testing.T) {
+	wrappers := newExecWrappers(t.TempDir(), []string{"op", "run", "--env-file", "prod env", "--"})
+	first := &terraform.Options{TerraformBinary: "/opt/terraform"}
+	second := &terraform.Options{TerraformBinary: "/opt/terraform"}
+	other := &terraform.Options{TerraformBinary: "terragrunt"}
+
+	for _, options := range []*terraform.Options{first, second, first, other} {
+		if _, err := wrappers.wrap(options); err != nil {
+			t.Fatalf("wrap() error = %v", err)
+		}
+	}
+	if first.TerraformBinary != second.TerraformBinary || first.TerraformBinary == other.TerraformBinary {
+		t.Errorf("scripts = %s, %s, %s, want one per binary", first.TerraformBinary, second.TerraformBinary, other.TerraformBinary)
+	}
+	if original, _ := wrappers.wrap(first); original != "/opt/terraform" {
+		t.Errorf("wrap() of a wrapped binary = %q, want /opt/terraform", original)
+	}
+
+	content, err := os.ReadFile(first.TerraformBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "exec op run --env-file 'prod env' -- /opt/terraform \"$@\"\n"
+	if goos == "windows" {
+		want = `"op" "run" "--env-file" "prod env" "--" "/opt/terraform" %*`
+	}
+	if !strings.Contains(string(content), want) {
+		t.Errorf("script = %q, want %q", content, want)
+	}
+}
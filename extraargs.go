@@ -0,0 +1,87 @@
+package validor
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// WithExtraArgs passes args to every run of a terraform command, such as
+// -parallelism=5 to apply or -lock-timeout=5m to destroy. The command is one
+// of init, plan, apply, destroy, validate, output or show.
+func WithExtraArgs(command string, args ...string) Option {
+	return func(c *Config) {
+		if c.ExtraArgs == nil {
+			c.ExtraArgs = make(map[string][]string)
+		}
+		c.ExtraArgs[command] = append(c.ExtraArgs[command], args...)
+	}
+}
+
+func extraArgsFlag(m *map[string][]string) func(string) error {
+	return func(value string) error {
+		command, args, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(command) == "" || strings.TrimSpace(args) == "" {
+			return fmt.Errorf("invalid value %q, expected COMMAND=ARGS", value)
+		}
+		if *m == nil {
+			*m = make(map[string][]string)
+		}
+		command = strings.TrimSpace(command)
+		(*m)[command] = append((*m)[command], strings.Fields(args)...)
+		return nil
+	}
+}
+
+// extraArgsOf returns where terratest keeps the extra arguments of command,
+// or nil when they cannot be passed to it.
+func extraArgsOf(extra *terraform.ExtraArgs, command string) *[]string {
+	switch command {
+	case "init":
+		return &extra.Init
+	case "plan":
+		return &extra.Plan
+	case "apply":
+		return &extra.Apply
+	case "destroy":
+		return &extra.Destroy
+	case "validate":
+		return &extra.Validate
+	case "output":
+		return &extra.Output
+	case "show":
+		return &extra.Show
+	}
+	return nil
+}
+
+func validateExtraArgs(extra map[string][]string) error {
+	var unknown []string
+	for command := range extra {
+		if extraArgsOf(&terraform.ExtraArgs{}, command) == nil {
+			unknown = append(unknown, command)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("extra arguments cannot be passed to %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// useExtraArgs adds the extra arguments to the module's terraform commands.
+// They are added once, so reruns of the module do not repeat them.
+func (m *Module) useExtraArgs(extra map[string][]string) {
+	if m.extraArgs || len(extra) == 0 {
+		return
+	}
+	m.extraArgs = true
+	for command, args := range extra {
+		if field := extraArgsOf(&m.Options.ExtraArgs, command); field != nil {
+			*field = append(slices.Clone(*field), args...)
+		}
+	}
+}
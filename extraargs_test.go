@@ -0,0 +1,86 @@
+package validor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithExtraArgs(t *testing.T) {
+	config := &Config{}
+	WithExtraArgs("apply", "-parallelism=5")(config)
+	WithExtraArgs("apply", "-compact-warnings")(config)
+	WithExtraArgs("destroy", "-lock-timeout=5m")(config)
+
+	want := map[string][]string{
+		"apply":   {"-parallelism=5", "-compact-warnings"},
+		"destroy": {"-lock-timeout=5m"},
+	}
+	if !reflect.DeepEqual(config.ExtraArgs, want) {
+		t.Errorf("ExtraArgs = %v, want %v", config.ExtraArgs, want)
+	}
+}
+
+func TestExtraArgsFlag(t *testing.T) {
+	var extra map[string][]string
+	set := extraArgsFlag(&extra)
+	for _, value := range []string{"apply=-parallelism=5 -compact-warnings", " plan = -lock-timeout=5m"} {
+		if err := set(value); err != nil {
+			t.Fatalf("extraArgsFlag(%q) error = %v", value, err)
+		}
+	}
+	want := map[string][]string{
+		"apply": {"-parallelism=5", "-compact-warnings"},
+		"plan":  {"-lock-timeout=5m"},
+	}
+	if !reflect.DeepEqual(extra, want) {
+		t.Errorf("extra = %v, want %v", extra, want)
+	}
+
+	for _, value := range []string{"apply", "=-parallelism=5", "apply= "} {
+		if err := set(value); err == nil {
+			t.Errorf("extraArgsFlag(%q) should fail", value)
+		}
+	}
+}
+
+func TestValidateExtraArgs(t *testing.T) {
+	if err := validateExtraArgs(map[string][]string{"init": {"-upgrade"}, "show": {"-no-color"}}); err != nil {
+		t.Errorf("validateExtraArgs() error = %v", err)
+	}
+	err := validateExtraArgs(map[string][]string{"console": {"-x"}, "apply": {"-y"}, "import": {"-z"}})
+	if err == nil || !strings.Contains(err.Error(), "console, import") {
+		t.Errorf("validateExtraArgs() error = %v, want the unknown commands", err)
+	}
+}
+
+func TestModule_UseExtraArgs(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	module := NewModule("default", t.TempDir())
+	module.Options.TerraformBinary = fakeTerraform(t, `echo "$@" >> `+calls+"\n")
+	module.SetTargets(nil, []string{"azurerm_resource_group.rg"})
+
+	extra := map[string][]string{"apply": {"-parallelism=5"}, "destroy": {"-lock-timeout=5m"}}
+	module.useExtraArgs(extra)
+	module.useExtraArgs(extra)
+
+	if err := module.terraformApply(context.Background(), t); err != nil {
+		t.Fatalf("terraformApply() error = %v", err)
+	}
+	if err := module.terraformDestroy(context.Background(), t); err != nil {
+		t.Fatalf("terraformDestroy() error = %v", err)
+	}
+
+	content, _ := os.ReadFile(calls)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	want := []string{
+		"apply -input=false -auto-approve -replace=azurerm_resource_group.rg -parallelism=5 -no-color -lock=false",
+		"destroy -auto-approve -input=false -lock-timeout=5m -no-color -lock=false",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("terraform ran with %q, want %q", lines, want)
+	}
+}
@@ -0,0 +1,61 @@
+package validor
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+func WithInclude(patterns ...string) Option {
+	return func(c *Config) { c.Include = append(c.Include, patterns...) }
+}
+
+func WithExclude(patterns ...string) Option {
+	return func(c *Config) { c.Exclude = append(c.Exclude, patterns...) }
+}
+
+func patternListFlag(patterns *[]string) func(string) error {
+	return func(value string) error {
+		for pattern := range strings.SplitSeq(value, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			*patterns = append(*patterns, pattern)
+		}
+		return nil
+	}
+}
+
+// selectsModule reports whether name passes the include and exclude globs. An
+// empty include list selects every module.
+func (c *Config) selectsModule(name string) (bool, error) {
+	included := len(c.Include) == 0
+	for _, pattern := range c.Include {
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+		if matched {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false, nil
+	}
+
+	for _, pattern := range c.Exclude {
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,98 @@
+package validor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestConfig_SelectsModule(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		module  string
+		want    bool
+		wantErr bool
+	}{
+		{name: "no filters", module: "default", want: true},
+		{name: "included", include: []string{"vm-*"}, module: "vm-linux", want: true},
+		{name: "not included", include: []string{"vm-*"}, module: "storage", want: false},
+		{name: "any include matches", include: []string{"vm-*", "storage"}, module: "storage", want: true},
+		{name: "excluded", exclude: []string{"legacy/*"}, module: "legacy/default", want: false},
+		{name: "exclude does not cross separators", exclude: []string{"legacy*"}, module: "legacy/default", want: true},
+		{name: "exclude wins over include", include: []string{"vm-*"}, exclude: []string{"vm-windows"}, module: "vm-windows", want: false},
+		{name: "bad pattern", include: []string{"vm-["}, module: "vm-linux", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig(WithInclude(tt.include...), WithExclude(tt.exclude...))
+			got, err := config.selectsModule(tt.module)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectsModule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("selectsModule(%q) = %v, want %v", tt.module, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternListFlag(t *testing.T) {
+	var patterns []string
+	parse := patternListFlag(&patterns)
+
+	if err := parse("vm-*, legacy/*,"); err != nil {
+		t.Fatalf("parse error = %v", err)
+	}
+	if err := parse("storage"); err != nil {
+		t.Fatalf("parse error = %v", err)
+	}
+	if want := []string{"vm-*", "legacy/*", "storage"}; !reflect.DeepEqual(patterns, want) {
+		t.Errorf("patterns = %v, want %v", patterns, want)
+	}
+	if err := parse("vm-["); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestModuleManager_DiscoverModulesFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"vm-linux", "vm-windows", "storage"} {
+		if err := os.Mkdir(filepath.Join(tmpDir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		config *Config
+		prefix string
+		want   []string
+	}{
+		{name: "include", config: NewConfig(WithInclude("vm-*")), want: []string{"vm-linux", "vm-windows"}},
+		{name: "exclude", config: NewConfig(WithExclude("vm-*")), want: []string{"storage"}},
+		{name: "prefixed", config: NewConfig(WithExclude("legacy/vm-*")), prefix: "legacy/", want: []string{"legacy/storage"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewModuleManager(tmpDir)
+			manager.SetConfig(tt.config)
+			manager.namePrefix = tt.prefix
+
+			modules, err := manager.DiscoverModules()
+			if err != nil {
+				t.Fatalf("DiscoverModules() error = %v", err)
+			}
+			got := extractModuleNames(modules)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("modules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
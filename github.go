@@ -0,0 +1,247 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const githubCommentMarker = "<!-- validor-report -->"
+
+// githubCommentLimit is the most characters GitHub accepts in a comment.
+const githubCommentLimit = 65536
+
+func WithGitHubComment(enabled bool) Option {
+	return func(c *Config) { c.GitHubComment = enabled }
+}
+
+type githubContext struct {
+	apiURL     string
+	token      string
+	repository string
+	prNumber   int
+}
+
+func githubContextFromEnv() (*githubContext, error) {
+	gh := &githubContext{
+		apiURL:     strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/"),
+		token:      os.Getenv("GITHUB_TOKEN"),
+		repository: os.Getenv("GITHUB_REPOSITORY"),
+	}
+	if gh.apiURL == "" {
+		gh.apiURL = "https://api.github.com"
+	}
+	if gh.token == "" || gh.repository == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN and GITHUB_REPOSITORY must be set")
+	}
+
+	eventPath := os.Getenv("GITHUB_EVENT_PATH")
+	if eventPath == "" {
+		return nil, fmt.Errorf("GITHUB_EVENT_PATH is not set")
+	}
+	content, err := os.ReadFile(eventPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read github event: %w", err)
+	}
+
+	var event struct {
+		Number      int `json:"number"`
+		PullRequest struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(content, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse github event: %w", err)
+	}
+
+	gh.prNumber = event.PullRequest.Number
+	if gh.prNumber == 0 {
+		gh.prNumber = event.Number
+	}
+	if gh.prNumber == 0 {
+		return nil, fmt.Errorf("github event is not a pull request")
+	}
+	return gh, nil
+}
+
+func githubCommentBody(modules []*Module) string {
+	var b strings.Builder
+	b.WriteString(githubCommentMarker + "\n")
+
+	failed := 0
+	for _, module := range modules {
+		if len(module.Errors) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(&b, "### :x: validor: %d of %d modules failed\n\n", failed, len(modules))
+	} else {
+		fmt.Fprintf(&b, "### :white_check_mark: validor: all %d modules passed\n\n", len(modules))
+	}
+
+	b.WriteString("| Module | Status | Duration |\n|---|---|---|\n")
+	for _, module := range modules {
+		status := BoolToStr(len(module.Errors) > 0, ":x: failed", ":white_check_mark: passed")
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", module.Name, status, module.TotalDuration().Round(time.Second))
+	}
+
+	var footer string
+	if url := ciJobURL(); url != "" {
+		footer = fmt.Sprintf("\n[CI job](%s)\n", url)
+	}
+	const truncated = "\n_The errors are truncated to fit in a comment, see the report for the rest._\n"
+
+	// The table comes first; error details are added while they fit.
+	for _, module := range modules {
+		if len(module.Errors) == 0 {
+			continue
+		}
+		errs := strings.Join(module.Errors, "\n")
+		fence := codeFence(errs)
+		details := fmt.Sprintf("\n<details><summary><code>%s</code> errors</summary>\n\n%s\n%s\n%s\n</details>\n", module.Name, fence, errs, fence)
+		if b.Len()+len(details)+len(truncated)+len(footer) > githubCommentLimit {
+			b.WriteString(truncated)
+			break
+		}
+		b.WriteString(details)
+	}
+	b.WriteString(footer)
+
+	body := b.String()
+	if len(body) > githubCommentLimit {
+		body = strings.ToValidUTF8(body[:githubCommentLimit-len(truncated)], "") + truncated
+	}
+	return body
+}
+
+// codeFence returns a backtick fence longer than any run of backticks in
+// text, so the text cannot close it.
+func codeFence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func (gh *githubContext) do(ctx context.Context, method, path string, body any, out any) error {
+	_, err := gh.request(ctx, method, gh.apiURL+path, body, out)
+	return err
+}
+
+// request sends a request to url and returns the response headers.
+func (gh *githubContext) request(ctx context.Context, method, url string, body any, out any) (http.Header, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode github request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create github request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+gh.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := reportHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("github request %s %s failed: HTTP %d", method, url, resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("failed to parse github response: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
+
+func (gh *githubContext) upsertComment(ctx context.Context, body string) error {
+	payload := map[string]string{"body": body}
+	next := fmt.Sprintf("%s/repos/%s/issues/%d/comments?per_page=100", gh.apiURL, gh.repository, gh.prNumber)
+	for next != "" {
+		var comments []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+		}
+		header, err := gh.request(ctx, http.MethodGet, next, nil, &comments)
+		if err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			if strings.Contains(comment.Body, githubCommentMarker) {
+				return gh.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/comments/%d", gh.repository, comment.ID), payload, nil)
+			}
+		}
+		next = nextPageURL(header.Get("Link"))
+	}
+	return gh.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", gh.repository, gh.prNumber), payload, nil)
+}
+
+// nextPageURL returns the rel="next" URL of a GitHub Link header, or an
+// empty string on the last page.
+func nextPageURL(link string) string {
+	for part := range strings.SplitSeq(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for param := range strings.SplitSeq(params, ";") {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") == `rel="next"` {
+				return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			}
+		}
+	}
+	return ""
+}
+
+// writeGitHubAnnotations writes an error workflow command per module error,
+// escaping the title as a property and the error as the message.
+func writeGitHubAnnotations(w io.Writer, modules []*Module) {
+	escapeData := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	escapeProperty := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+	for _, module := range modules {
+		for _, errMsg := range module.Errors {
+			fmt.Fprintf(w, "::error title=%s::%s\n", escapeProperty.Replace("validor "+module.Name), escapeData.Replace(errMsg))
+		}
+	}
+}
+
+func reportToGitHub(ctx context.Context, config *Config, modules []*Module) error {
+	if !config.GitHubComment {
+		return nil
+	}
+
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		writeGitHubAnnotations(os.Stdout, modules)
+	}
+
+	gh, err := githubContextFromEnv()
+	if err != nil {
+		return fmt.Errorf("skipping github pull request comment: %w", err)
+	}
+	return gh.upsertComment(ctx, githubCommentBody(modules))
+}
@@ -0,0 +1,218 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setGitHubEnv(t *testing.T, event string) {
+	t.Helper()
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(eventPath, []byte(event), 0o644); err != nil {
+		t.Fatalf("failed to write event file: %v", err)
+	}
+	t.Setenv("GITHUB_TOKEN", "token")
+	t.Setenv("GITHUB_REPOSITORY", "org/terraform-azure-vnet")
+	t.Setenv("GITHUB_API_URL", "https://api.github.test")
+	t.Setenv("GITHUB_EVENT_PATH", eventPath)
+}
+
+func TestGitHubContextFromEnv(t *testing.T) {
+	t.Run("pull request event", func(t *testing.T) {
+		setGitHubEnv(t, `{"pull_request":{"number":7}}`)
+		gh, err := githubContextFromEnv()
+		if err != nil {
+			t.Fatalf("githubContextFromEnv() error = %v", err)
+		}
+		if gh.prNumber != 7 || gh.repository != "org/terraform-azure-vnet" {
+			t.Errorf("unexpected context: %+v", gh)
+		}
+	})
+
+	t.Run("push event", func(t *testing.T) {
+		setGitHubEnv(t, `{"ref":"refs/heads/main"}`)
+		if _, err := githubContextFromEnv(); err == nil {
+			t.Error("expected error for non pull request event")
+		}
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		setGitHubEnv(t, `{"number":3}`)
+		t.Setenv("GITHUB_TOKEN", "")
+		if _, err := githubContextFromEnv(); err == nil {
+			t.Error("expected error when GITHUB_TOKEN is missing")
+		}
+	})
+}
+
+func TestGitHubCommentBody(t *testing.T) {
+	broken := NewModule("broken", "/path/broken")
+	broken.Errors = append(broken.Errors, "terraform apply failed")
+
+	body := githubCommentBody([]*Module{NewModule("ok", "/path/ok"), broken})
+
+	for _, want := range []string{githubCommentMarker, "1 of 2 modules failed", "| `ok` | :white_check_mark: passed", "| `broken` | :x: failed", "terraform apply failed"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected comment body to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestGitHubCommentBody_FencesErrors(t *testing.T) {
+	broken := NewModule("broken", "/path/broken")
+	broken.Errors = append(broken.Errors, "unexpected ```hcl block, and a ````` run")
+
+	body := githubCommentBody([]*Module{broken})
+	if !strings.Contains(body, "\n``````\nunexpected ```hcl block, and a ````` run\n``````\n") {
+		t.Errorf("expected the errors in a fence they cannot close, got:\n%s", body)
+	}
+}
+
+func TestGitHubCommentBody_Truncates(t *testing.T) {
+	var modules []*Module
+	for i := range 40 {
+		module := NewModule(fmt.Sprintf("example%d", i), "/path")
+		module.Errors = append(module.Errors, strings.Repeat("terraform apply failed ", 200))
+		modules = append(modules, module)
+	}
+
+	body := githubCommentBody(modules)
+	if len(body) > githubCommentLimit {
+		t.Fatalf("body has %d characters, want at most %d", len(body), githubCommentLimit)
+	}
+	if !strings.Contains(body, "truncated to fit in a comment, see the report") || !strings.Contains(body, "| `example39` | :x: failed") {
+		t.Errorf("expected every module in the table and a truncation note, got:\n%s", body[len(body)-300:])
+	}
+	if strings.Count(body, "<details>") != strings.Count(body, "</details>") {
+		t.Error("a truncated body should not leave error details open")
+	}
+}
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	broken := NewModule("broken", "/path/broken")
+	broken.Errors = append(broken.Errors, "line1\nline2")
+	matrix := NewModule("default[location=westeurope,sku=Basic]", "/path/default")
+	matrix.Errors = append(matrix.Errors, "apply: failed, 100%")
+
+	var buf bytes.Buffer
+	writeGitHubAnnotations(&buf, []*Module{broken, matrix})
+
+	want := "::error title=validor broken::line1%0Aline2\n" +
+		"::error title=validor default[location=westeurope%2Csku=Basic]::apply: failed, 100%25\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeGitHubAnnotations() = %q, want %q", got, want)
+	}
+}
+
+func TestReportToGitHub_UpdatesExistingComment(t *testing.T) {
+	setGitHubEnv(t, `{"pull_request":{"number":7}}`)
+	t.Setenv("GITHUB_ACTIONS", "")
+
+	origClient := reportHTTPClient
+	defer func() { reportHTTPClient = origClient }()
+
+	var requests []string
+	reportHTTPClient = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			body := "{}"
+			if req.Method == http.MethodGet {
+				comments, _ := json.Marshal([]map[string]any{
+					{"id": 1, "body": "unrelated"},
+					{"id": 99, "body": githubCommentMarker + " old report"},
+				})
+				body = string(comments)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	config := NewConfig(WithGitHubComment(true))
+	if err := reportToGitHub(context.Background(), config, []*Module{NewModule("ok", "/path/ok")}); err != nil {
+		t.Fatalf("reportToGitHub() error = %v", err)
+	}
+
+	want := []string{
+		"GET /repos/org/terraform-azure-vnet/issues/7/comments",
+		"PATCH /repos/org/terraform-azure-vnet/issues/comments/99",
+	}
+	if strings.Join(requests, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+}
+
+func TestReportToGitHub_FollowsCommentPages(t *testing.T) {
+	setGitHubEnv(t, `{"pull_request":{"number":7}}`)
+	t.Setenv("GITHUB_ACTIONS", "")
+
+	origClient := reportHTTPClient
+	defer func() { reportHTTPClient = origClient }()
+
+	var requests []string
+	reportHTTPClient = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req.Method+" "+req.URL.RequestURI())
+			header := make(http.Header)
+			body := "{}"
+			if req.Method == http.MethodGet {
+				comments := []map[string]any{{"id": 1, "body": "unrelated"}}
+				if req.URL.Query().Get("page") == "2" {
+					comments = []map[string]any{{"id": 250, "body": githubCommentMarker + " old report"}}
+				} else {
+					header.Set("Link", `<https://api.github.test/repositories/1/issues/7/comments?per_page=100&page=2>; rel="next", <https://api.github.test/repositories/1/issues/7/comments?per_page=100&page=2>; rel="last"`)
+				}
+				encoded, _ := json.Marshal(comments)
+				body = string(encoded)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     header,
+			}, nil
+		}),
+	}
+
+	config := NewConfig(WithGitHubComment(true))
+	if err := reportToGitHub(context.Background(), config, []*Module{NewModule("ok", "/path/ok")}); err != nil {
+		t.Fatalf("reportToGitHub() error = %v", err)
+	}
+
+	want := []string{
+		"GET /repos/org/terraform-azure-vnet/issues/7/comments?per_page=100",
+		"GET /repositories/1/issues/7/comments?per_page=100&page=2",
+		"PATCH /repos/org/terraform-azure-vnet/issues/comments/250",
+	}
+	if strings.Join(requests, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{link: "", want: ""},
+		{link: `<https://api.github.test/x?page=2>; rel="next", <https://api.github.test/x?page=5>; rel="last"`, want: "https://api.github.test/x?page=2"},
+		{link: `<https://api.github.test/x?page=1>; rel="prev", <https://api.github.test/x?page=1>; rel="first"`, want: ""},
+		{link: `<https://api.github.test/x?page=3>;rel="next"`, want: "https://api.github.test/x?page=3"},
+	}
+
+	for _, tt := range tests {
+		if got := nextPageURL(tt.link); got != tt.want {
+			t.Errorf("nextPageURL(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+)
+
+// errHung is the cause a command is stopped with when it stopped producing
+// output.
+var errHung = errors.New("terraform stopped producing output")
+
+// WithHangTimeout warns when a terraform apply or destroy has not written any
+// output for timeout, which usually means a provider is stuck on an API call,
+// and logs a snapshot of its processes and of the test's goroutines.
+func WithHangTimeout(timeout time.Duration) Option {
+	return func(c *Config) { c.HangTimeout = timeout }
+}
+
+// WithHangRetry stops a command that hung, per WithHangTimeout, and retries it
+// up to the module's MaxRetries instead of waiting for the test to time out.
+func WithHangRetry(enabled bool) Option {
+	return func(c *Config) { c.HangRetry = enabled }
+}
+
+// watchForHang checks how long ago out was written to until the returned stop
+// function is called. When that exceeds the module's hang timeout it logs a
+// warning and a snapshot once per silence, and with hang retry enabled stops
+// the command through cancel.
+func (m *Module) watchForHang(t testing.TB, cmd *exec.Cmd, out *streamWriter, cancel context.CancelCauseFunc) (stop func()) {
+	if m.hangTimeout <= 0 {
+		return func() {}
+	}
+
+	out.idle()
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(max(m.hangTimeout/4, time.Millisecond))
+		defer ticker.Stop()
+		warned := false
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			idle := out.idle()
+			if idle < m.hangTimeout {
+				warned = false
+				continue
+			}
+			if warned {
+				continue
+			}
+			warned = true
+			t.Logf("Warning: terraform for module %s has produced no output for %s", m.Name, idle.Round(time.Second))
+			m.logHangSnapshot(t, cmd)
+			if m.hangRetry {
+				cancel(fmt.Errorf("%w for %s", errHung, idle.Round(time.Second)))
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// logHangSnapshot records the processes terraform started and the goroutines
+// of the test, to the module's log file when there is one.
+func (m *Module) logHangSnapshot(t testing.TB, cmd *exec.Cmd) {
+	var snapshot strings.Builder
+	if cmd.Process != nil {
+		fmt.Fprintf(&snapshot, "Processes of %s:\n%s\n", m.Name, processSnapshot(cmd.Process))
+	}
+	snapshot.WriteString("Goroutines:\n")
+	_ = pprof.Lookup("goroutine").WriteTo(&snapshot, 1)
+
+	if m.logFile != nil {
+		if _, err := fmt.Fprintf(m.logFile, "\n%s\n", snapshot.String()); err == nil {
+			t.Logf("Snapshot of the hung processes written to %s", m.logFile.Name())
+			return
+		}
+	}
+	t.Log(snapshot.String())
+}
@@ -0,0 +1,56 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModule_RunTerraformDetectsHangs(t *testing.T) {
+	stubSleep(t)
+
+	tests := []struct {
+		name      string
+		retry     bool
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "warns and waits", wantCalls: 1},
+		{name: "stops and retries", retry: true, wantErr: true, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := filepath.Join(t.TempDir(), "calls")
+			module := NewModule("default", t.TempDir())
+			module.Options.TerraformBinary = fakeTerraform(t, "echo x >> "+calls+"\necho 'Creating...'\nsleep 1\necho 'Creation complete'\n")
+			module.Options.MaxRetries = 1
+			module.hangTimeout = 200 * time.Millisecond
+			module.hangRetry = tt.retry
+			module.cancelGrace = 100 * time.Millisecond
+
+			recorder := &recordingTB{TB: t}
+			err := module.terraformApply(context.Background(), recorder)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("terraformApply() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, errHung) {
+				t.Errorf("terraformApply() error = %v, want it to wrap errHung", err)
+			}
+			if content, _ := os.ReadFile(calls); strings.Count(string(content), "x") != tt.wantCalls {
+				t.Errorf("terraform ran %d times, want %d", strings.Count(string(content), "x"), tt.wantCalls)
+			}
+			logs := strings.Join(recorder.logs, "\n")
+			if !strings.Contains(logs, "has produced no output for") || !strings.Contains(logs, "Goroutines:") {
+				t.Errorf("logs should warn about the hang with a snapshot, got:\n%s", logs)
+			}
+			if !strings.Contains(logs, "PID") {
+				t.Errorf("the snapshot should list the processes, got:\n%s", logs)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package validor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// historyWindow is the number of most recent runs of an example that its
+	// flakiness is computed over.
+	historyWindow = 20
+	// minFlakyRuns is the number of runs an example needs before it can be
+	// quarantined.
+	minFlakyRuns = 4
+)
+
+// WithHistory appends the outcome of every run to a JSON lines file, which is
+// used to report the examples whose outcome alternates between runs.
+func WithHistory(path string) Option {
+	return func(c *Config) { c.HistoryFile = path }
+}
+
+// WithQuarantine skips examples whose flakiness in the history is at least
+// rate, between 0 and 1. Zero disables quarantining.
+func WithQuarantine(rate float64) Option {
+	return func(c *Config) { c.QuarantineRate = rate }
+}
+
+type HistoryRun struct {
+	Time      time.Time                `json:"time"`
+	Results   map[string]bool          `json:"results"`
+	Durations map[string]time.Duration `json:"durations,omitempty"`
+}
+
+// FlakyExample describes how often an example's outcome changed between
+// consecutive runs. Rate is the share of those runs in which it changed.
+type FlakyExample struct {
+	Name     string  `json:"name"`
+	Rate     float64 `json:"rate"`
+	Runs     int     `json:"runs"`
+	Failures int     `json:"failures"`
+}
+
+func LoadHistory(path string) ([]HistoryRun, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var runs []HistoryRun
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var run HistoryRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("failed to parse history: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
+
+func appendHistory(path string, run HistoryRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// FlakyExamples returns the examples whose outcome changed at least once in
+// their last runs, most flaky first.
+func FlakyExamples(runs []HistoryRun) []FlakyExample {
+	outcomes := make(map[string][]bool)
+	for _, run := range runs {
+		for name, passed := range run.Results {
+			outcomes[name] = append(outcomes[name], passed)
+		}
+	}
+
+	var flaky []FlakyExample
+	for name, results := range outcomes {
+		if len(results) > historyWindow {
+			results = results[len(results)-historyWindow:]
+		}
+		example := FlakyExample{Name: name, Runs: len(results)}
+		changes := 0
+		for i, passed := range results {
+			if !passed {
+				example.Failures++
+			}
+			if i > 0 && passed != results[i-1] {
+				changes++
+			}
+		}
+		if changes == 0 {
+			continue
+		}
+		example.Rate = float64(changes) / float64(len(results)-1)
+		flaky = append(flaky, example)
+	}
+
+	sort.Slice(flaky, func(i, j int) bool {
+		if flaky[i].Rate != flaky[j].Rate {
+			return flaky[i].Rate > flaky[j].Rate
+		}
+		return flaky[i].Name < flaky[j].Name
+	})
+	return flaky
+}
+
+// quarantined returns the examples to skip, with the reason, based on the
+// history recorded before this run.
+func quarantined(config *Config) (map[string]string, error) {
+	if config.HistoryFile == "" || config.QuarantineRate <= 0 {
+		return nil, nil
+	}
+	runs, err := LoadHistory(config.HistoryFile)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := make(map[string]string)
+	for _, example := range FlakyExamples(runs) {
+		if example.Runs >= minFlakyRuns && example.Rate >= config.QuarantineRate {
+			skip[example.Name] = fmt.Sprintf("quarantined as flaky (outcome changed in %.0f%% of the last %d runs)", example.Rate*100, example.Runs)
+		}
+	}
+	return skip, nil
+}
+
+// recordHistory appends the run's outcomes to the history and stores the
+// resulting flaky examples on results.
+func recordHistory(config *Config, results *TestResults, finished time.Time) error {
+	if config.HistoryFile == "" {
+		return nil
+	}
+
+	modules, _ := results.GetResults()
+	run := HistoryRun{
+		Time:      finished,
+		Results:   make(map[string]bool, len(modules)),
+		Durations: make(map[string]time.Duration, len(modules)),
+	}
+	for _, module := range modules {
+		run.Results[module.Name] = len(module.Errors) == 0
+		run.Durations[module.Name] = module.TotalDuration()
+	}
+	if len(run.Results) > 0 {
+		if err := appendHistory(config.HistoryFile, run); err != nil {
+			return err
+		}
+	}
+
+	runs, err := LoadHistory(config.HistoryFile)
+	if err != nil {
+		return err
+	}
+	results.SetFlaky(FlakyExamples(runs))
+	return nil
+}
+
+func printFlakyExamples(tb testLogger, flaky []FlakyExample) {
+	if len(flaky) == 0 {
+		return
+	}
+	const top = 5
+	tb.Log("Top flaky examples:")
+	for i, example := range flaky {
+		if i == top {
+			break
+		}
+		tb.Logf("  %-30s %3.0f%% changed, %d of %d runs failed", example.Name, example.Rate*100, example.Failures, example.Runs)
+	}
+	tb.Log("")
+}
@@ -0,0 +1,122 @@
+package validor
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func historyRuns(outcomes map[string]string) []HistoryRun {
+	var runs []HistoryRun
+	for name, sequence := range outcomes {
+		for i, outcome := range sequence {
+			for len(runs) <= i {
+				runs = append(runs, HistoryRun{Results: make(map[string]bool)})
+			}
+			if outcome != '-' {
+				runs[i].Results[name] = outcome == 'P'
+			}
+		}
+	}
+	return runs
+}
+
+func TestFlakyExamples(t *testing.T) {
+	runs := historyRuns(map[string]string{
+		"stable":      "PPPPP",
+		"broken":      "FFFFF",
+		"alternating": "PFPFP",
+		"once":        "PPPPF",
+		"sparse":      "P-F--",
+	})
+
+	want := []FlakyExample{
+		{Name: "alternating", Rate: 1, Runs: 5, Failures: 2},
+		{Name: "sparse", Rate: 1, Runs: 2, Failures: 1},
+		{Name: "once", Rate: 0.25, Runs: 5, Failures: 1},
+	}
+	if got := FlakyExamples(runs); !reflect.DeepEqual(got, want) {
+		t.Errorf("FlakyExamples() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFlakyExamples_Window(t *testing.T) {
+	runs := historyRuns(map[string]string{"recovered": "PF" + strings.Repeat("P", historyWindow)})
+	if flaky := FlakyExamples(runs); len(flaky) != 0 {
+		t.Errorf("changes outside the window should be ignored, got %+v", flaky)
+	}
+}
+
+func TestHistory_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "runs.jsonl")
+
+	runs, err := LoadHistory(path)
+	if err != nil || len(runs) != 0 {
+		t.Fatalf("LoadHistory() on a missing file = %v, %v", runs, err)
+	}
+
+	for _, passed := range []bool{true, false} {
+		if err := appendHistory(path, HistoryRun{Time: time.Unix(0, 0).UTC(), Results: map[string]bool{"default": passed}}); err != nil {
+			t.Fatalf("appendHistory() error = %v", err)
+		}
+	}
+	runs, err = LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory() error = %v", err)
+	}
+	if len(runs) != 2 || !runs[0].Results["default"] || runs[1].Results["default"] {
+		t.Errorf("unexpected history %+v", runs)
+	}
+}
+
+func TestQuarantined(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.jsonl")
+	for _, run := range historyRuns(map[string]string{"flaky": "PFPFP", "new": "-PF-F", "stable": "PPPPP"}) {
+		if err := appendHistory(path, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	skip, err := quarantined(&Config{HistoryFile: path, QuarantineRate: 0.5})
+	if err != nil {
+		t.Fatalf("quarantined() error = %v", err)
+	}
+	if len(skip) != 1 || !strings.Contains(skip["flaky"], "quarantined as flaky") {
+		t.Errorf("quarantined() = %v, want only flaky", skip)
+	}
+
+	if skip, _ := quarantined(&Config{HistoryFile: path}); len(skip) != 0 {
+		t.Errorf("quarantine should be disabled without a rate, got %v", skip)
+	}
+}
+
+func TestRunModuleTests_History(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.jsonl")
+	for _, run := range historyRuns(map[string]string{"flaky": "PFPF", "ok": "PPPP"}) {
+		if err := appendHistory(path, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	flaky := &fakeRunner{name: "flaky"}
+	runners := []ModuleRunner{flaky, &fakeRunner{name: "ok"}, &fakeRunner{name: "new"}}
+	runner := &DefaultTestRunner{Config: NewConfig(WithExample(""), WithHistory(path), WithQuarantine(0.9))}
+	report := runner.RunTestsWithReport(context.Background(), t, runners, false, nil)
+
+	if flaky.applied {
+		t.Error("quarantined example should not run")
+	}
+	runs, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]bool{"ok": true, "new": true}; len(runs) != 5 || !reflect.DeepEqual(runs[4].Results, want) {
+		t.Errorf("last history run = %+v, want %v", runs[len(runs)-1], want)
+	}
+	if len(report.Flaky) != 1 || report.Flaky[0].Name != "flaky" {
+		t.Errorf("report flaky examples = %+v", report.Flaky)
+	}
+}
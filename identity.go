@@ -0,0 +1,147 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Identity is the cloud identity a run's credentials resolve to, recorded in
+// the run report so results can be traced back to the subscription, account
+// or project they ran against.
+type Identity struct {
+	Cloud     string `json:"cloud"`
+	Account   string `json:"account"`
+	Tenant    string `json:"tenant,omitempty"`
+	Principal string `json:"principal,omitempty"`
+}
+
+func (i Identity) String() string {
+	s := i.Cloud + " " + i.Account
+	if i.Tenant != "" {
+		s += " in tenant " + i.Tenant
+	}
+	if i.Principal != "" {
+		s += " as " + i.Principal
+	}
+	return s
+}
+
+// CredentialValidator verifies that the credentials of a cloud can be used,
+// not just that they are configured, and resolves the identity they belong
+// to.
+type CredentialValidator func(ctx context.Context) (Identity, error)
+
+var (
+	credentialValidatorsMu sync.RWMutex
+	credentialValidators   = map[string]CredentialValidator{
+		"azure": validateAzureCredentials,
+		"aws":   validateAWSCredentials,
+		"gcp":   validateGCPCredentials,
+	}
+)
+
+// RegisterCredentialValidator makes a validator available under cloud for
+// WithCredentialCheck and the -check-credentials flag, replacing any
+// validator of that name.
+func RegisterCredentialValidator(cloud string, validator CredentialValidator) {
+	credentialValidatorsMu.Lock()
+	defer credentialValidatorsMu.Unlock()
+	credentialValidators[cloud] = validator
+}
+
+func credentialValidator(cloud string) (CredentialValidator, error) {
+	credentialValidatorsMu.RLock()
+	defer credentialValidatorsMu.RUnlock()
+	validator, ok := credentialValidators[cloud]
+	if !ok {
+		clouds := slices.Sorted(maps.Keys(credentialValidators))
+		return nil, fmt.Errorf("unknown cloud %q (want %s)", cloud, strings.Join(clouds, ", "))
+	}
+	return validator, nil
+}
+
+// runPreflightJSON runs a CLI command and decodes its JSON output into v.
+func runPreflightJSON(ctx context.Context, v any, command ...string) error {
+	output, err := runPreflightCommand(ctx, command[0], command[1:]...)
+	if err != nil {
+		return fmt.Errorf("%s failed, log in first: %w", strings.Join(command, " "), err)
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("failed to parse the output of %s: %w", strings.Join(command, " "), err)
+	}
+	return nil
+}
+
+// validateAzureCredentials gets a token for the subscription terraform uses,
+// ARM_SUBSCRIPTION_ID or the CLI's default, which fails when the identity has
+// no access to it.
+func validateAzureCredentials(ctx context.Context) (Identity, error) {
+	show := []string{"az", "account", "show", "--output", "json"}
+	token := []string{"az", "account", "get-access-token", "--output", "json"}
+	if subscription := os.Getenv("ARM_SUBSCRIPTION_ID"); subscription != "" {
+		show = append(show, "--subscription", subscription)
+		token = append(token, "--subscription", subscription)
+	}
+
+	var account struct {
+		ID       string `json:"id"`
+		TenantID string `json:"tenantId"`
+		User     struct {
+			Name string `json:"name"`
+		} `json:"user"`
+	}
+	if err := runPreflightJSON(ctx, &account, show...); err != nil {
+		return Identity{}, err
+	}
+	if _, err := runPreflightCommand(ctx, token[0], token[1:]...); err != nil {
+		return Identity{}, fmt.Errorf("no access to subscription %s: %w", account.ID, err)
+	}
+	return Identity{Cloud: "azure", Account: account.ID, Tenant: account.TenantID, Principal: account.User.Name}, nil
+}
+
+func validateAWSCredentials(ctx context.Context) (Identity, error) {
+	var caller struct {
+		Account string `json:"Account"`
+		Arn     string `json:"Arn"`
+	}
+	if err := runPreflightJSON(ctx, &caller, "aws", "sts", "get-caller-identity", "--output", "json"); err != nil {
+		return Identity{}, err
+	}
+	return Identity{Cloud: "aws", Account: caller.Account, Principal: caller.Arn}, nil
+}
+
+// validateGCPCredentials describes the project terraform uses, from
+// GOOGLE_PROJECT or the gcloud configuration, which fails when the identity
+// has no access to it.
+func validateGCPCredentials(ctx context.Context) (Identity, error) {
+	var config struct {
+		Core struct {
+			Account string `json:"account"`
+			Project string `json:"project"`
+		} `json:"core"`
+	}
+	if err := runPreflightJSON(ctx, &config, "gcloud", "config", "list", "--format=json"); err != nil {
+		return Identity{}, err
+	}
+	project := config.Core.Project
+	for _, name := range []string{"GOOGLE_PROJECT", "GOOGLE_CLOUD_PROJECT", "CLOUDSDK_CORE_PROJECT"} {
+		if value := os.Getenv(name); value != "" {
+			project = value
+			break
+		}
+	}
+	if project == "" {
+		return Identity{}, errors.New("no project configured; set GOOGLE_PROJECT or run gcloud config set project")
+	}
+	if _, err := runPreflightCommand(ctx, "gcloud", "projects", "describe", project, "--format=value(projectId)"); err != nil {
+		return Identity{}, fmt.Errorf("no access to project %s: %w", project, err)
+	}
+	return Identity{Cloud: "gcp", Account: project, Principal: config.Core.Account}, nil
+}
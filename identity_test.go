@@ -0,0 +1,131 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCredentialValidators(t *testing.T) {
+	tests := []struct {
+		name    string
+		cloud   string
+		env     map[string]string
+		outputs map[string]string
+		errs    map[string]error
+		want    Identity
+		wantErr string
+	}{
+		{
+			name:  "azure subscription from the environment",
+			cloud: "azure",
+			env:   map[string]string{"ARM_SUBSCRIPTION_ID": "0000-2222"},
+			outputs: map[string]string{
+				"az account show --output json --subscription 0000-2222": `{"id": "0000-2222", "tenantId": "tttt", "user": {"name": "ci@example.com", "type": "servicePrincipal"}}`,
+			},
+			want: Identity{Cloud: "azure", Account: "0000-2222", Tenant: "tttt", Principal: "ci@example.com"},
+		},
+		{
+			name:    "azure without access",
+			cloud:   "azure",
+			env:     map[string]string{"ARM_SUBSCRIPTION_ID": ""},
+			outputs: map[string]string{"az account show --output json": `{"id": "0000-1111"}`},
+			errs:    map[string]error{"az account get-access-token --output json": errors.New("AADSTS700082: the refresh token has expired")},
+			wantErr: "no access to subscription 0000-1111",
+		},
+		{
+			name:    "aws",
+			cloud:   "aws",
+			outputs: map[string]string{"aws sts get-caller-identity --output json": `{"UserId": "AIDA", "Account": "123456789012", "Arn": "arn:aws:iam::123456789012:user/ci"}`},
+			want:    Identity{Cloud: "aws", Account: "123456789012", Principal: "arn:aws:iam::123456789012:user/ci"},
+		},
+		{
+			name:  "gcp project from the environment",
+			cloud: "gcp",
+			env:   map[string]string{"GOOGLE_PROJECT": "sandbox-2"},
+			outputs: map[string]string{
+				"gcloud config list --format=json": `{"core": {"account": "ci@sandbox.iam.gserviceaccount.com", "project": "sandbox-1"}}`,
+			},
+			want: Identity{Cloud: "gcp", Account: "sandbox-2", Principal: "ci@sandbox.iam.gserviceaccount.com"},
+		},
+		{
+			name:    "gcp without a project",
+			cloud:   "gcp",
+			env:     map[string]string{"GOOGLE_PROJECT": "", "GOOGLE_CLOUD_PROJECT": "", "CLOUDSDK_CORE_PROJECT": ""},
+			outputs: map[string]string{"gcloud config list --format=json": `{"core": {"account": "ci@example.com"}}`},
+			wantErr: "no project configured",
+		},
+		{
+			name:    "unparsable output",
+			cloud:   "aws",
+			outputs: map[string]string{"aws sts get-caller-identity --output json": "Account: 123"},
+			wantErr: "failed to parse the output of aws sts get-caller-identity",
+		},
+	}
+
+	origRun := runPreflightCommand
+	t.Cleanup(func() { runPreflightCommand = origRun })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			runPreflightCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				command := name + " " + strings.Join(args, " ")
+				if err := tt.errs[command]; err != nil {
+					return nil, err
+				}
+				return []byte(tt.outputs[command]), nil
+			}
+
+			validator, err := credentialValidator(tt.cloud)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := validator(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validator() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validator() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("validator() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterCredentialValidator(t *testing.T) {
+	RegisterCredentialValidator("stackit", func(ctx context.Context) (Identity, error) {
+		return Identity{Cloud: "stackit", Account: "project-1"}, nil
+	})
+	t.Cleanup(func() {
+		credentialValidatorsMu.Lock()
+		delete(credentialValidators, "stackit")
+		credentialValidatorsMu.Unlock()
+	})
+
+	var identities []Identity
+	checks := preflightChecks(&Config{Credentials: []string{"stackit"}}, nil, "", func(identity Identity) {
+		identities = append(identities, identity)
+	})
+	if err := RunPreflight(context.Background(), checks); err != nil {
+		t.Fatalf("RunPreflight() error = %v", err)
+	}
+	if len(identities) != 1 || identities[0].String() != "stackit project-1" {
+		t.Errorf("identities = %v, want the stackit project", identities)
+	}
+
+	results := NewTestResults()
+	results.AddIdentity(identities[0])
+	if report := NewRunReport(results, time.Time{}, time.Time{}); len(report.Identities) != 1 {
+		t.Errorf("Identities = %v, want the identity in the run report", report.Identities)
+	}
+}
@@ -0,0 +1,116 @@
+package validor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// ImportScenario describes an example whose resources already exist outside
+// of Terraform. Setup creates them and returns the import IDs keyed by
+// resource address; Imports lists IDs that are known up front. Examples that
+// use import blocks can leave both empty.
+type ImportScenario struct {
+	Setup   func(ctx context.Context, t testing.TB) (map[string]string, error)
+	Imports map[string]string
+}
+
+func WithImportScenario(example string, scenario ImportScenario) Option {
+	return func(c *Config) {
+		if c.ImportScenarios == nil {
+			c.ImportScenarios = make(map[string]ImportScenario)
+		}
+		c.ImportScenarios[example] = scenario
+	}
+}
+
+// pendingChanges returns the addresses the plan would still change; a plan
+// that only imports or reads resources has converged.
+func pendingChanges(planJSON []byte) ([]string, error) {
+	changes, err := planResourceChanges(planJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	for _, rc := range changes {
+		for _, action := range rc.Change.Actions {
+			if action != "no-op" && action != "read" {
+				addresses = append(addresses, rc.Address)
+				break
+			}
+		}
+	}
+	return addresses, nil
+}
+
+func (m *Module) importResource(t testing.TB, address, id string) error {
+	_, err := terraform.RunTerraformCommandE(t, m.Options, "import", "-input=false", "-no-color", address, id)
+	return err
+}
+
+// Import runs the scenario setup, imports the declared resources and verifies
+// that the example then plans no changes before applying it.
+func (m *Module) Import(ctx context.Context, t testing.TB, scenario ImportScenario) error {
+	t.Helper()
+	defer m.startStage(StageImport)()
+
+	imports := maps.Clone(scenario.Imports)
+	if scenario.Setup != nil {
+		created, err := scenario.Setup(ctx, t)
+		if err != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "import setup", Err: err})
+		}
+		if imports == nil {
+			imports = make(map[string]string)
+		}
+		maps.Copy(imports, created)
+	}
+
+	if m.applyHook == nil {
+		if err := m.init(t); err != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform init", Err: err})
+		}
+	}
+
+	importResource := m.importResource
+	if m.importHook != nil {
+		importResource = func(t testing.TB, address, id string) error { return m.importHook(ctx, t, m, address, id) }
+	}
+	for _, address := range slices.Sorted(maps.Keys(imports)) {
+		t.Logf("Importing %s into module %s", address, m.Name)
+		if err := importResource(t, address, imports[address]); err != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform import", Err: fmt.Errorf("%s: %w", address, err)})
+		}
+	}
+
+	m.planJSON = nil
+	planJSON, err := m.Plan(ctx, t)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform plan", Err: err})
+	}
+	pending, err := pendingChanges(planJSON)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform plan", Err: err})
+	}
+	if len(pending) > 0 {
+		err := fmt.Errorf("plan is not empty after import, %d resource(s) would change: %s", len(pending), strings.Join(pending, ", "))
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "import convergence", Err: err})
+	}
+
+	// Applying records resources imported through import blocks in state.
+	if m.applyHook != nil {
+		err = m.applyHook(ctx, t, m)
+	} else {
+		err = m.terraformApply(ctx, t)
+	}
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform apply", Err: err})
+	}
+	return nil
+}
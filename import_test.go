@@ -0,0 +1,150 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPendingChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "converged with import blocks",
+			plan: `{"resource_changes":[{"address":"azurerm_resource_group.rg","change":{"actions":["no-op"],"importing":{"id":"/subscriptions/x"}}}]}`,
+		},
+		{
+			name: "data sources are ignored",
+			plan: `{"resource_changes":[{"address":"data.azurerm_client_config.current","change":{"actions":["read"]}}]}`,
+		},
+		{
+			name: "drift after import",
+			plan: `{"resource_changes":[
+				{"address":"azurerm_resource_group.rg","change":{"actions":["update"]}},
+				{"address":"azurerm_storage_account.sa","change":{"actions":["create"]}}
+			]}`,
+			want: []string{"azurerm_resource_group.rg", "azurerm_storage_account.sa"},
+		},
+		{name: "invalid json", plan: `[`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pendingChanges([]byte(tt.plan))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pendingChanges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pendingChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModule_Import(t *testing.T) {
+	convergedPlan := `{"resource_changes":[{"address":"azurerm_resource_group.rg","change":{"actions":["no-op"]}}]}`
+
+	tests := []struct {
+		name        string
+		scenario    ImportScenario
+		plan        string
+		importErr   error
+		wantImports []string
+		wantApply   bool
+		wantErr     string
+	}{
+		{
+			name: "setup and declared imports",
+			scenario: ImportScenario{
+				Imports: map[string]string{"azurerm_resource_group.rg": "/rg"},
+				Setup: func(ctx context.Context, t testing.TB) (map[string]string, error) {
+					return map[string]string{"azurerm_storage_account.sa": "/sa"}, nil
+				},
+			},
+			plan:        convergedPlan,
+			wantImports: []string{"azurerm_resource_group.rg=/rg", "azurerm_storage_account.sa=/sa"},
+			wantApply:   true,
+		},
+		{
+			name:      "import blocks only",
+			plan:      convergedPlan,
+			wantApply: true,
+		},
+		{
+			name: "setup failure",
+			scenario: ImportScenario{
+				Setup: func(ctx context.Context, t testing.TB) (map[string]string, error) {
+					return nil, errors.New("quota exceeded")
+				},
+			},
+			wantErr: "import setup",
+		},
+		{
+			name:      "import failure",
+			scenario:  ImportScenario{Imports: map[string]string{"azurerm_resource_group.rg": "/rg"}},
+			importErr: errors.New("resource not found"),
+			wantErr:   "terraform import",
+		},
+		{
+			name:        "plan not converged",
+			scenario:    ImportScenario{Imports: map[string]string{"azurerm_resource_group.rg": "/rg"}},
+			plan:        `{"resource_changes":[{"address":"azurerm_resource_group.rg","change":{"actions":["update"]}}]}`,
+			wantImports: []string{"azurerm_resource_group.rg=/rg"},
+			wantErr:     "azurerm_resource_group.rg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var imports []string
+			applied := false
+
+			module := NewModule("default", t.TempDir())
+			module.planJSON = []byte(`{"stale":true}`)
+			module.importHook = func(ctx context.Context, t testing.TB, m *Module, address, id string) error {
+				if tt.importErr != nil {
+					return tt.importErr
+				}
+				imports = append(imports, address+"="+id)
+				return nil
+			}
+			module.planHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+				return []byte(tt.plan), nil
+			}
+			module.applyHook = func(ctx context.Context, t testing.TB, m *Module) error {
+				applied = true
+				return nil
+			}
+
+			err := module.Import(context.Background(), t, tt.scenario)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Import() error = %v, want error containing %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(imports, tt.wantImports) {
+				t.Errorf("imports = %v, want %v", imports, tt.wantImports)
+			}
+			if applied != tt.wantApply {
+				t.Errorf("applied = %v, want %v", applied, tt.wantApply)
+			}
+			if _, ok := module.Durations[StageImport]; !ok {
+				t.Error("import stage duration should be recorded")
+			}
+		})
+	}
+}
+
+func TestWithImportScenario(t *testing.T) {
+	config := NewConfig(WithImportScenario("existing-rg", ImportScenario{Imports: map[string]string{"a.b": "id"}}))
+	if got := config.ImportScenarios["existing-rg"].Imports["a.b"]; got != "id" {
+		t.Errorf("ImportScenarios[existing-rg] = %v", config.ImportScenarios["existing-rg"])
+	}
+}
@@ -0,0 +1,80 @@
+package validor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// WithAlwaysInit runs terraform init before every stage and retry. By default
+// init is skipped when the lock file, the terraform blocks with their
+// required_providers and backend, the module sources and the init options of
+// an example are unchanged since its last init.
+func WithAlwaysInit(enabled bool) Option {
+	return func(c *Config) { c.AlwaysInit = enabled }
+}
+
+// initInputs hashes everything that makes terraform init produce a different
+// working directory. It returns an empty string when the directory has not
+// been initialized.
+func (m *Module) initInputs() (string, error) {
+	dir := m.Options.TerraformDir
+	if _, err := os.Stat(filepath.Join(dir, ".terraform")); err != nil {
+		return "", nil
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%v|%v|%v|%v|%q|%q|%q\n", m.Options.BackendConfig, m.Options.Upgrade, m.Options.Reconfigure,
+		m.Options.MigrateState, m.Options.PluginDir, m.Options.ExtraArgs.Init, m.Options.TerraformBinary)
+
+	lock, err := os.ReadFile(filepath.Join(dir, ".terraform.lock.hcl"))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read lock file: %w", err)
+	}
+	h.Write(lock)
+
+	files, err := findTerraformFiles(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to find terraform files in %s: %w", dir, err)
+	}
+	slices.Sort(files)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		fmt.Fprintf(h, "\n%s\n", file)
+		hashInitBlocks(h, content, file)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashInitBlocks writes the terraform blocks and module sources of a file to
+// h, or the whole file when it cannot be parsed.
+func hashInitBlocks(h hash.Hash, content []byte, filename string) {
+	parsedFile, diags := hclwrite.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		h.Write(content)
+		return
+	}
+	for _, block := range parsedFile.Body().Blocks() {
+		switch block.Type() {
+		case "terraform":
+			h.Write(block.BuildTokens(nil).Bytes())
+		case "module":
+			fmt.Fprintf(h, "module %q\n", block.Labels())
+			for _, name := range []string{"source", "version"} {
+				if attr := block.Body().GetAttribute(name); attr != nil {
+					h.Write(attr.BuildTokens(nil).Bytes())
+				}
+			}
+		}
+	}
+}
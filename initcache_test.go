@@ -0,0 +1,91 @@
+package validor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModule_InitSkipsUnchangedDirectories(t *testing.T) {
+	tests := []struct {
+		name       string
+		alwaysInit bool
+		change     func(t *testing.T, m *Module)
+		wantInits  int
+	}{
+		{name: "nothing changed", wantInits: 1},
+		{name: "always init", alwaysInit: true, wantInits: 2},
+		{
+			name: "required providers changed",
+			change: func(t *testing.T, m *Module) {
+				writeExample(t, m, "terraform {\n  required_providers {\n    azurerm = { source = \"hashicorp/azurerm\", version = \"~> 4.1\" }\n  }\n}\n")
+			},
+			wantInits: 2,
+		},
+		{
+			name: "module source changed",
+			change: func(t *testing.T, m *Module) {
+				writeExample(t, m, "terraform {\n  required_providers {\n    azurerm = { source = \"hashicorp/azurerm\", version = \"~> 4.0\" }\n  }\n}\n\nmodule \"test\" {\n  source = \"../../\"\n}\n")
+			},
+			wantInits: 2,
+		},
+		{
+			name: "lock file changed",
+			change: func(t *testing.T, m *Module) {
+				if err := os.WriteFile(filepath.Join(m.Path, ".terraform.lock.hcl"), []byte("# upgraded\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantInits: 2,
+		},
+		{
+			name: "working directory cleaned up",
+			change: func(t *testing.T, m *Module) {
+				if err := os.RemoveAll(filepath.Join(m.Path, ".terraform")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantInits: 2,
+		},
+		{
+			name: "other configuration changed",
+			change: func(t *testing.T, m *Module) {
+				writeExample(t, m, "terraform {\n  required_providers {\n    azurerm = { source = \"hashicorp/azurerm\", version = \"~> 4.0\" }\n  }\n}\n\nmodule \"test\" {\n  source  = \"cloudnationhq/mymodule/azure\"\n  version = \"~> 1.0\"\n  name    = \"renamed\"\n}\n")
+			},
+			wantInits: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := filepath.Join(t.TempDir(), "calls")
+			module := NewModule("default", t.TempDir())
+			module.Options.TerraformBinary = fakeTerraform(t, "echo x >> "+calls+"\nmkdir -p .terraform\n[ -f .terraform.lock.hcl ] || echo '# lock' > .terraform.lock.hcl\n")
+			module.alwaysInit = tt.alwaysInit
+			writeExample(t, module, "terraform {\n  required_providers {\n    azurerm = { source = \"hashicorp/azurerm\", version = \"~> 4.0\" }\n  }\n}\n\nmodule \"test\" {\n  source  = \"cloudnationhq/mymodule/azure\"\n  version = \"~> 1.0\"\n}\n")
+
+			if err := module.init(t); err != nil {
+				t.Fatalf("init() error = %v", err)
+			}
+			if tt.change != nil {
+				tt.change(t, module)
+			}
+			if err := module.init(t); err != nil {
+				t.Fatalf("init() error = %v", err)
+			}
+
+			content, _ := os.ReadFile(calls)
+			if got := strings.Count(string(content), "x"); got != tt.wantInits {
+				t.Errorf("terraform init ran %d times, want %d", got, tt.wantInits)
+			}
+		})
+	}
+}
+
+func writeExample(t *testing.T, m *Module, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(m.Path, "main.tf"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write example: %v", err)
+	}
+}
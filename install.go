@@ -0,0 +1,289 @@
+package validor
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/go-version"
+)
+
+// WithTerraformInstall downloads terraform into the install directory when no
+// binary on PATH satisfies the TerraformVersion constraint.
+func WithTerraformInstall(enabled bool) Option {
+	return func(c *Config) { c.InstallTerraform = enabled }
+}
+
+func WithTerraformInstallDir(dir string) Option {
+	return func(c *Config) { c.TerraformInstallDir = dir }
+}
+
+// WithTerraformSigningKey verifies downloaded releases against the armored
+// public key in path instead of HashiCorp's release key.
+func WithTerraformSigningKey(path string) Option {
+	return func(c *Config) { c.TerraformSigningKey = path }
+}
+
+var (
+	terraformReleasesURL = "https://releases.hashicorp.com/terraform"
+	releasesClient       = &http.Client{Timeout: 5 * time.Minute}
+	releaseSigningKey    = hashicorpReleaseKey
+)
+
+// terraformBinary returns the path of an installed terraform binary, or an
+// empty string when the one on PATH is used.
+func terraformBinary(ctx context.Context, config *Config) (string, error) {
+	if !config.InstallTerraform || config.ContainerImage != "" {
+		return "", nil
+	}
+	if err := TerraformCheck("terraform", config.TerraformVersion).Run(ctx); err == nil {
+		return "", nil
+	} else if config.Offline {
+		return "", fmt.Errorf("%w; terraform cannot be downloaded offline", err)
+	}
+
+	dir := config.TerraformInstallDir
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine user cache directory: %w", err)
+		}
+		dir = filepath.Join(cacheDir, "validor", "terraform")
+	}
+	return InstallTerraform(ctx, config.TerraformVersion, dir, config.TerraformSigningKey)
+}
+
+// InstallTerraform downloads the newest release matching constraint into dir,
+// verifies the signature of its checksums against HashiCorp's release key, or
+// the armored key in signingKey when set, and its checksum, and returns the
+// binary's path. The newest installed version that satisfies constraint is
+// reused without looking for newer releases.
+func InstallTerraform(ctx context.Context, constraint, dir, signingKey string) (string, error) {
+	if binary := installedTerraform(dir, constraint); binary != "" {
+		return binary, nil
+	}
+	v, err := resolveTerraformVersion(ctx, constraint)
+	if err != nil {
+		return "", err
+	}
+
+	binary := filepath.Join(dir, v.String(), terraformExecutable())
+	if _, err := os.Stat(binary); err == nil {
+		return binary, nil
+	}
+
+	archive := fmt.Sprintf("terraform_%s_%s_%s.zip", v, runtime.GOOS, runtime.GOARCH)
+	sums, err := fetchRelease(ctx, fmt.Sprintf("%s/%s/terraform_%s_SHA256SUMS", terraformReleasesURL, v, v))
+	if err != nil {
+		return "", err
+	}
+	key := []byte(releaseSigningKey)
+	if signingKey != "" {
+		if key, err = os.ReadFile(signingKey); err != nil {
+			return "", fmt.Errorf("failed to read signing key: %w", err)
+		}
+	}
+	signature, err := fetchRelease(ctx, fmt.Sprintf("%s/%s/terraform_%s_SHA256SUMS.sig", terraformReleasesURL, v, v))
+	if err != nil {
+		return "", err
+	}
+	if err := verifySignature(key, sums, signature); err != nil {
+		return "", err
+	}
+	want, err := releaseChecksum(sums, archive)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := fetchRelease(ctx, fmt.Sprintf("%s/%s/%s", terraformReleasesURL, v, archive))
+	if err != nil {
+		return "", err
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != want {
+		return "", fmt.Errorf("checksum mismatch for %s", archive)
+	}
+
+	if err := extractTerraform(data, binary); err != nil {
+		return "", fmt.Errorf("failed to install terraform %s: %w", v, err)
+	}
+	return binary, nil
+}
+
+func terraformExecutable() string {
+	if runtime.GOOS == "windows" {
+		return "terraform.exe"
+	}
+	return "terraform"
+}
+
+// installedTerraform returns the binary of the newest version in dir that
+// satisfies constraint, or an empty string when there is none.
+func installedTerraform(dir, constraint string) string {
+	var constraints version.Constraints
+	if constraint != "" {
+		var err error
+		if constraints, err = version.NewConstraint(constraint); err != nil {
+			return ""
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	var newest *version.Version
+	var binary string
+	for _, entry := range entries {
+		v, err := version.NewVersion(entry.Name())
+		if err != nil || !entry.IsDir() || !constraints.Check(v) || newest != nil && !v.GreaterThan(newest) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name(), terraformExecutable())
+		if _, err := os.Stat(path); err == nil {
+			newest, binary = v, path
+		}
+	}
+	return binary
+}
+
+func resolveTerraformVersion(ctx context.Context, constraint string) (*version.Version, error) {
+	if exact, err := version.NewVersion(constraint); err == nil {
+		return exact, nil
+	}
+
+	var constraints version.Constraints
+	if constraint != "" {
+		var err error
+		if constraints, err = version.NewConstraint(constraint); err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+		}
+	}
+
+	data, err := fetchRelease(ctx, terraformReleasesURL+"/index.json")
+	if err != nil {
+		return nil, err
+	}
+	var index struct {
+		Versions map[string]json.RawMessage `json:"versions"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform release index: %w", err)
+	}
+
+	var latest *version.Version
+	for raw := range index.Versions {
+		v, err := version.NewVersion(raw)
+		if err != nil || v.Prerelease() != "" || !constraints.Check(v) {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no terraform release matches %q", constraint)
+	}
+	return latest, nil
+}
+
+func fetchRelease(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := releasesClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func releaseChecksum(sums []byte, file string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		if sum, name, ok := strings.Cut(scanner.Text(), "  "); ok && name == file {
+			return sum, nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s", file)
+}
+
+// verifySignature checks the signature against key as it was when the
+// signature was made, so releases signed before the key expired stay valid.
+func verifySignature(key, signed, signature []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+	if err != nil {
+		return fmt.Errorf("failed to read signing key: %w", err)
+	}
+	p, err := packet.Read(bytes.NewReader(signature))
+	if err != nil {
+		return fmt.Errorf("invalid signature on terraform checksums: %w", err)
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		return fmt.Errorf("invalid signature on terraform checksums: unexpected %T packet", p)
+	}
+	config := &packet.Config{Time: func() time.Time { return sig.CreationTime }}
+	if _, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(signed), bytes.NewReader(signature), config); err != nil {
+		return fmt.Errorf("invalid signature on terraform checksums: %w", err)
+	}
+	return nil
+}
+
+func extractTerraform(archive []byte, binary string) error {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+	for _, file := range reader.File {
+		if file.Name != filepath.Base(binary) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(binary), 0755); err != nil {
+			return err
+		}
+		src, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		// Write next to the target and rename, so concurrent runs never see a
+		// partially written binary.
+		tmp, err := os.CreateTemp(filepath.Dir(binary), ".terraform-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := io.Copy(tmp, src); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(tmp.Name(), 0755); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), binary)
+	}
+	return fmt.Errorf("archive does not contain %s", filepath.Base(binary))
+}
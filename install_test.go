@@ -0,0 +1,353 @@
+package validor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+func terraformArchive(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create(terraformExecutable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func fakeReleases(t *testing.T, files map[string][]byte) *int {
+	t.Helper()
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	origURL := terraformReleasesURL
+	terraformReleasesURL = server.URL + "/terraform"
+	t.Cleanup(func() { terraformReleasesURL = origURL })
+	return &requests
+}
+
+func releaseFiles(v string, archive []byte, checksum string) map[string][]byte {
+	name := fmt.Sprintf("terraform_%s_%s_%s.zip", v, runtime.GOOS, runtime.GOARCH)
+	if checksum == "" {
+		sum := sha256.Sum256(archive)
+		checksum = hex.EncodeToString(sum[:])
+	}
+	return map[string][]byte{
+		"/terraform/index.json": []byte(`{"versions":{"1.5.7":{},"1.9.5":{},"1.10.0-beta1":{},"1.9.8":{}}}`),
+		fmt.Sprintf("/terraform/%s/terraform_%s_SHA256SUMS", v, v): fmt.Appendf(nil, "0000  terraform_%s_other_arch.zip\n%s  %s\n", v, checksum, name),
+		fmt.Sprintf("/terraform/%s/%s", v, name):                    archive,
+	}
+}
+
+// releaseSigner replaces HashiCorp's release key with a generated one and
+// returns it along with its armored public key.
+func releaseSigner(t *testing.T) (*openpgp.Entity, []byte) {
+	t.Helper()
+	signer, err := openpgp.NewEntity("releases", "", "releases@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key bytes.Buffer
+	w, err := armor.Encode(&key, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	origKey := releaseSigningKey
+	releaseSigningKey = key.String()
+	t.Cleanup(func() { releaseSigningKey = origKey })
+	return signer, key.Bytes()
+}
+
+func signRelease(t *testing.T, signer *openpgp.Entity, files map[string][]byte, v string, signed []byte) {
+	t.Helper()
+	sumsPath := fmt.Sprintf("/terraform/%s/terraform_%s_SHA256SUMS", v, v)
+	if signed == nil {
+		signed = files[sumsPath]
+	}
+	var signature bytes.Buffer
+	if err := openpgp.DetachSign(&signature, signer, bytes.NewReader(signed), nil); err != nil {
+		t.Fatal(err)
+	}
+	files[sumsPath+".sig"] = signature.Bytes()
+}
+
+func TestResolveTerraformVersion(t *testing.T) {
+	fakeReleases(t, releaseFiles("1.9.8", nil, "x"))
+
+	tests := []struct {
+		constraint string
+		want       string
+		wantErr    bool
+	}{
+		{constraint: "1.6.0", want: "1.6.0"},
+		{constraint: "", want: "1.9.8"},
+		{constraint: "~> 1.9.0", want: "1.9.8"},
+		{constraint: "< 1.9", want: "1.5.7"},
+		{constraint: ">= 2.0", wantErr: true},
+		{constraint: "newest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			got, err := resolveTerraformVersion(context.Background(), tt.constraint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveTerraformVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("resolveTerraformVersion() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallTerraform(t *testing.T) {
+	signer, _ := releaseSigner(t)
+	archive := terraformArchive(t, "#!/bin/sh\necho terraform\n")
+	files := releaseFiles("1.9.8", archive, "")
+	signRelease(t, signer, files, "1.9.8", nil)
+	requests := fakeReleases(t, files)
+	dir := t.TempDir()
+
+	binary, err := InstallTerraform(context.Background(), "~> 1.9.0", dir, "")
+	if err != nil {
+		t.Fatalf("InstallTerraform() error = %v", err)
+	}
+	if want := filepath.Join(dir, "1.9.8", terraformExecutable()); binary != want {
+		t.Errorf("binary = %s, want %s", binary, want)
+	}
+	content, err := os.ReadFile(binary)
+	if err != nil || !strings.Contains(string(content), "echo terraform") {
+		t.Errorf("unexpected binary content %q, err %v", content, err)
+	}
+
+	before := *requests
+	if _, err := InstallTerraform(context.Background(), "1.9.8", dir, ""); err != nil {
+		t.Fatalf("second InstallTerraform() error = %v", err)
+	}
+	if *requests != before {
+		t.Errorf("installed version should be reused, got %d more requests", *requests-before)
+	}
+}
+
+func TestInstallTerraform_ChecksumMismatch(t *testing.T) {
+	signer, _ := releaseSigner(t)
+	archive := terraformArchive(t, "tampered")
+	files := releaseFiles("1.9.8", archive, strings.Repeat("ab", 32))
+	signRelease(t, signer, files, "1.9.8", nil)
+	fakeReleases(t, files)
+	dir := t.TempDir()
+
+	if _, err := InstallTerraform(context.Background(), "1.9.8", dir, ""); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("error = %v, want checksum mismatch", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1.9.8", terraformExecutable())); !os.IsNotExist(err) {
+		t.Error("no binary should be installed on a checksum mismatch")
+	}
+}
+
+func TestInstallTerraform_Signature(t *testing.T) {
+	signer, key := releaseSigner(t)
+	keyPath := filepath.Join(t.TempDir(), "key.asc")
+	if err := os.WriteFile(keyPath, key, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := releaseSigner(t)
+
+	tests := []struct {
+		name       string
+		signer     *openpgp.Entity
+		signed     []byte
+		signingKey string
+		unsigned   bool
+		wantErr    bool
+	}{
+		{name: "signed with the release key", signer: other},
+		{name: "signed with the configured key", signer: signer, signingKey: keyPath},
+		{name: "signed with another key", signer: signer, wantErr: true},
+		{name: "signature over other content", signer: other, signed: []byte("something else"), wantErr: true},
+		{name: "unsigned", unsigned: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := releaseFiles("1.9.8", terraformArchive(t, "signed"), "")
+			if !tt.unsigned {
+				signRelease(t, tt.signer, files, "1.9.8", tt.signed)
+			}
+			fakeReleases(t, files)
+
+			_, err := InstallTerraform(context.Background(), "1.9.8", t.TempDir(), tt.signingKey)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InstallTerraform() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// hashicorpSums and hashicorpSignature are the SHA256SUMS of a provider
+// release and its signature by HashiCorp's release key, made in April 2021.
+const hashicorpSums = `fea4227271ebf7d9e2b61b89ce2328c7262acd9fd190e1fd6d15a591abfa848e  terraform-provider-null_3.1.0_darwin_amd64.zip
+9ebf4d9704faba06b3ec7242c773c0fbfe12d62db7d00356d4f55385fc69bfb2  terraform-provider-null_3.1.0_darwin_arm64.zip
+a6576c81adc70326e4e1c999c04ad9ca37113a6e925aefab4765e5a5198efa7e  terraform-provider-null_3.1.0_freebsd_386.zip
+5f9200bf708913621d0f6514179d89700e9aa3097c77dac730e8ba6e5901d521  terraform-provider-null_3.1.0_freebsd_amd64.zip
+fc39cc1fe71234a0b0369d5c5c7f876c71b956d23d7d6f518289737a001ba69b  terraform-provider-null_3.1.0_freebsd_arm.zip
+c797744d08a5307d50210e0454f91ca4d1c7621c68740441cf4579390452321d  terraform-provider-null_3.1.0_linux_386.zip
+53e30545ff8926a8e30ad30648991ca8b93b6fa496272cd23b26763c8ee84515  terraform-provider-null_3.1.0_linux_amd64.zip
+cecb6a304046df34c11229f20a80b24b1603960b794d68361a67c5efe58e62b8  terraform-provider-null_3.1.0_linux_arm64.zip
+e1371aa1e502000d9974cfaff5be4cfa02f47b17400005a16f14d2ef30dc2a70  terraform-provider-null_3.1.0_linux_arm.zip
+a8a42d13346347aff6c63a37cda9b2c6aa5cc384a55b2fe6d6adfa390e609c53  terraform-provider-null_3.1.0_windows_386.zip
+02a1675fd8de126a00460942aaae242e65ca3380b5bb192e8773ef3da9073fd2  terraform-provider-null_3.1.0_windows_amd64.zip
+`
+
+const hashicorpSignature = `wsFcBAABCAAQBQJgga+GCRCwtEEJdoW2dgAA` +
+	`o0YQAAW911BGDr2WHLo5NwcZenwHyxL5DX9g+4BknKbc/WxRC1hD8Afi3eygZk1yR6eT4Gp2H` +
+	`yNOwCjGL1PTONBumMfj9udIeuX8onrJMMvjFHh+bORGxBi4FKr4V3b2ZV1IYOjWMEyyTGRDvw` +
+	`SCdxBkp3apH3s2xZLmRoAj84JZ4KaxGF7hlT0j4IkNyQKd2T5cCByN9DV80+x+HtzaOieFwJL` +
+	`97iyGj6aznXfKfslK6S4oIrVTwyLTrQbxSxA0LsdUjRPHnJamL3sFOG77qUEUoXG3r61yi5vW` +
+	`V4P5gCH/+C+VkfGHqaB1s0jHYLxoTEXtwthe66MydDBPe2Hd0J12u9ppOIeK3leeb4uiixWIi` +
+	`rNdpWyjr/LU1KKWPxsDqMGYJ9TexyWkXjEpYmIEiY1Rxar8jrLh+FqVAhxRJajjgSRu5pZj50` +
+	`CNeKmmbyolLhPCmICjYYU/xKPGXSyDFqonVVyMWCSpO+8F38OmwDQHIk5AWyc8hPOAZ+g5N95` +
+	`cfUAzEqlvmNvVHQIU40Y6/Ip2HZzzFCLKQkMP1aDakYHq5w4ZO/ucjhKuoh1HDQMuMnZSu4eo` +
+	`2nMTBzYZnUxwtROrJZF1t103avbmP2QE/GaPvLIQn7o5WMV3ZcPCJ+szzzby7H2e33WIynrY/` +
+	`95ensBxh7mGFbcQ1C59b5o7viwIaaY2`
+
+func TestVerifySignature_ReleaseKey(t *testing.T) {
+	signature, err := base64.StdEncoding.DecodeString(hashicorpSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifySignature([]byte(hashicorpReleaseKey), []byte(hashicorpSums), signature); err != nil {
+		t.Errorf("verifySignature() error = %v, want a signature by HashiCorp's release key to verify", err)
+	}
+	if err := verifySignature([]byte(hashicorpReleaseKey), []byte(hashicorpSums+"tampered"), signature); err == nil {
+		t.Error("verifySignature() should reject tampered checksums")
+	}
+}
+
+func TestVerifySignature_KeyExpiry(t *testing.T) {
+	created := time.Now().Add(-2 * time.Hour)
+	config := &packet.Config{Time: func() time.Time { return created }}
+	signer, err := openpgp.NewEntity("releases", "", "releases@example.com", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The signatures are made with the key as generated; the published key
+	// expires an hour after it was created.
+	signatures := make(map[time.Time][]byte)
+	for _, at := range []time.Time{created.Add(30 * time.Minute), created.Add(90 * time.Minute)} {
+		var signature bytes.Buffer
+		if err := openpgp.DetachSign(&signature, signer, strings.NewReader("sums"), &packet.Config{Time: func() time.Time { return at }}); err != nil {
+			t.Fatal(err)
+		}
+		signatures[at] = signature.Bytes()
+	}
+	lifetime := uint32(3600)
+	for _, identity := range signer.Identities {
+		identity.SelfSignature.KeyLifetimeSecs = &lifetime
+		if err := identity.SelfSignature.SignUserId(identity.UserId.Id, signer.PrimaryKey, signer.PrivateKey, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var key bytes.Buffer
+	w, err := armor.Encode(&key, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	tests := []struct {
+		name     string
+		signedAt time.Time
+		wantErr  bool
+	}{
+		{name: "signed before the key expired", signedAt: created.Add(30 * time.Minute)},
+		{name: "signed after the key expired", signedAt: created.Add(90 * time.Minute), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature(key.Bytes(), []byte("sums"), signatures[tt.signedAt])
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifySignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstallTerraform_ReusesInstalledVersion(t *testing.T) {
+	requests := fakeReleases(t, releaseFiles("1.9.8", nil, "x"))
+	dir := t.TempDir()
+	for _, v := range []string{"1.5.7", "1.9.2", "1.9.5", "1.10.0"} {
+		writeFiles(t, filepath.Join(dir, v), map[string]string{terraformExecutable(): "terraform"})
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "1.9.7"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	binary, err := InstallTerraform(context.Background(), "~> 1.9.0", dir, "")
+	if err != nil {
+		t.Fatalf("InstallTerraform() error = %v", err)
+	}
+	if want := filepath.Join(dir, "1.9.5", terraformExecutable()); binary != want {
+		t.Errorf("binary = %s, want %s", binary, want)
+	}
+	if *requests != 0 {
+		t.Errorf("an installed version satisfies the constraint, got %d requests", *requests)
+	}
+}
+
+func TestTerraformBinary_PrefersPath(t *testing.T) {
+	origLookPath := lookPath
+	t.Cleanup(func() { lookPath = origLookPath })
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+	binary, err := terraformBinary(context.Background(), &Config{InstallTerraform: true})
+	if err != nil || binary != "" {
+		t.Errorf("terraformBinary() = %q, %v; want the binary on PATH", binary, err)
+	}
+	binary, err = terraformBinary(context.Background(), &Config{})
+	if err != nil || binary != "" {
+		t.Errorf("terraformBinary() = %q, %v; want no install when disabled", binary, err)
+	}
+}
@@ -5,10 +5,14 @@ import (
 	"testing"
 )
 
+// ModuleRunner is a unit of work driven by RunTests. Use Module.Runner to
+// adapt a Module; decorators can implement Unwrap() ModuleRunner so the module
+// stages still apply to the Module they wrap.
 type ModuleRunner interface {
-	Apply(ctx context.Context, t *testing.T) error
-	Destroy(ctx context.Context, t *testing.T) error
-	Cleanup(ctx context.Context, t *testing.T) error
+	Name() string
+	Apply(ctx context.Context, t testing.TB) error
+	Destroy(ctx context.Context, t testing.TB) error
+	Cleanup(ctx context.Context, t testing.TB) error
 }
 
 type ModuleDiscoverer interface {
@@ -23,9 +27,11 @@ type SourceConverter interface {
 
 type RegistryClient interface {
 	GetLatestVersion(ctx context.Context, namespace, name, provider string) (string, error)
+	GetLatestMatching(ctx context.Context, namespace, name, provider, constraint string) (string, error)
+	ListVersions(ctx context.Context, namespace, name, provider string) ([]string, error)
 }
 
 type TestRunner interface {
-	RunTests(ctx context.Context, t *testing.T, modules []ModuleRunner, parallel bool, config *Config)
-	RunLocalTests(ctx context.Context, t *testing.T, examplesPath string) error
+	RunTests(ctx context.Context, t testing.TB, modules []ModuleRunner, parallel bool, config *Config)
+	RunLocalTests(ctx context.Context, t testing.TB, examplesPath string) error
 }
@@ -0,0 +1,178 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const lockFileName = ".terraform.lock.hcl"
+
+var defaultLockPlatforms = []string{"linux_amd64", "darwin_arm64", "windows_amd64"}
+
+var lockPlatformPattern = regexp.MustCompile(`^[a-z0-9]+_[a-z0-9]+$`)
+
+// WithLockPlatformCheck checks that the lock file each example commits has
+// provider hashes for every platform of WithLockPlatforms, so it does not
+// break consumers on other platforms. Examples without a lock file are not
+// checked.
+func WithLockPlatformCheck(enabled bool) Option {
+	return func(c *Config) { c.CheckLockPlatforms = enabled }
+}
+
+// WithLockPlatforms sets the platforms lock files must cover, which default
+// to linux_amd64, darwin_arm64 and windows_amd64.
+func WithLockPlatforms(platforms ...string) Option {
+	return func(c *Config) { c.LockPlatforms = platforms }
+}
+
+// WithFixLockFiles adds the missing hashes to lock files with terraform
+// providers lock instead of failing the example. It implies
+// WithLockPlatformCheck.
+func WithFixLockFiles(enabled bool) Option {
+	return func(c *Config) { c.FixLockFiles = enabled }
+}
+
+func (c *Config) lockPlatforms() []string {
+	if !c.CheckLockPlatforms && !c.FixLockFiles {
+		return nil
+	}
+	if len(c.LockPlatforms) > 0 {
+		return c.LockPlatforms
+	}
+	return defaultLockPlatforms
+}
+
+func validateLockPlatforms(platforms []string) error {
+	for _, platform := range platforms {
+		if !lockPlatformPattern.MatchString(platform) {
+			return fmt.Errorf("invalid platform %q, expected OS_ARCH such as linux_amd64", platform)
+		}
+	}
+	return nil
+}
+
+// LockFileHashes returns the hashes a lock file records per provider.
+func LockFileHashes(content []byte, filename string) (map[string][]string, error) {
+	file, diags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %s", filename, diags.Error())
+	}
+
+	hashes := make(map[string][]string)
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "provider" || len(block.Labels) == 0 {
+			continue
+		}
+		provider := block.Labels[0]
+		hashes[provider] = []string{}
+		attr, ok := block.Body.Attributes["hashes"]
+		if !ok {
+			continue
+		}
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || !value.CanIterateElements() {
+			continue
+		}
+		for it := value.ElementIterator(); it.Next(); {
+			_, hash := it.Element()
+			if hash.Type() == cty.String && hash.IsKnown() && !hash.IsNull() {
+				hashes[provider] = append(hashes[provider], hash.AsString())
+			}
+		}
+	}
+	return hashes, nil
+}
+
+// useLockPlatforms remembers the lock file the example commits, before init
+// adds the hashes of the current platform to it.
+func (m *Module) useLockPlatforms(platforms []string, fix bool) {
+	m.lockPlatforms = platforms
+	m.fixLock = fix
+	m.committedLock = nil
+	if len(platforms) > 0 {
+		m.committedLock, _ = os.ReadFile(filepath.Join(m.Path, lockFileName))
+	}
+}
+
+func (m *Module) providersLock(ctx context.Context, t testing.TB) error {
+	if m.lockHook != nil {
+		return m.lockHook(ctx, t, m)
+	}
+	args := []string{"providers", "lock"}
+	for _, platform := range m.lockPlatforms {
+		args = append(args, "-platform="+platform)
+	}
+	_, err := terraform.RunTerraformCommandE(t, m.Options, args...)
+	return err
+}
+
+// checkLockFile runs terraform providers lock for the configured platforms
+// and fails the example when that adds hashes to its committed lock file.
+// With fixLock set the added hashes are kept instead.
+func (m *Module) checkLockFile(ctx context.Context, t testing.TB) error {
+	t.Helper()
+	if m.committedLock == nil {
+		return nil
+	}
+	if m.terragrunt == TerragruntStack {
+		t.Logf("Skipping the lock file check of %s: it is %v", m.Name, errTerragruntStack)
+		return nil
+	}
+
+	path := filepath.Join(m.Path, lockFileName)
+	fail := func(err error) error {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "lock file check", Err: err})
+	}
+	if err := m.providersLock(ctx, t); err != nil {
+		return fail(err)
+	}
+	locked, err := os.ReadFile(path)
+	if err != nil {
+		return fail(err)
+	}
+	if bytes.Equal(locked, m.committedLock) {
+		return nil
+	}
+
+	before, err := LockFileHashes(m.committedLock, path)
+	if err != nil {
+		return fail(err)
+	}
+	after, err := LockFileHashes(locked, path)
+	if err != nil {
+		return fail(err)
+	}
+	var incomplete []string
+	for provider, hashes := range after {
+		if slices.ContainsFunc(hashes, func(hash string) bool { return !slices.Contains(before[provider], hash) }) {
+			incomplete = append(incomplete, provider)
+		}
+	}
+	slices.Sort(incomplete)
+	if len(incomplete) == 0 {
+		return nil
+	}
+
+	if m.fixLock {
+		m.committedLock = locked
+		t.Logf("Added the hashes for %s to the lock file of %s: %s", strings.Join(m.lockPlatforms, ", "), m.Name, strings.Join(incomplete, ", "))
+		return nil
+	}
+	if err := os.WriteFile(path, m.committedLock, 0644); err != nil {
+		return fail(err)
+	}
+	return fail(fmt.Errorf("the lock file does not cover %s for %s; run terraform providers lock for these platforms or use -fix-lock-files",
+		strings.Join(m.lockPlatforms, ", "), strings.Join(incomplete, ", ")))
+}
@@ -0,0 +1,133 @@
+package validor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const linuxLock = `provider "registry.terraform.io/hashicorp/azurerm" {
+  version     = "4.10.0"
+  constraints = "~> 4.0"
+  hashes = [
+    "h1:linux",
+    "zh:checksum",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.3"
+  hashes = [
+    "h1:random",
+  ]
+}
+`
+
+var allPlatformsLock = strings.Replace(linuxLock, `"h1:linux",`, `"h1:darwin",
+    "h1:linux",
+    "h1:windows",`, 1)
+
+func TestLockFileHashes(t *testing.T) {
+	got, err := LockFileHashes([]byte(linuxLock), lockFileName)
+	if err != nil {
+		t.Fatalf("LockFileHashes() error = %v", err)
+	}
+	want := map[string][]string{
+		"registry.terraform.io/hashicorp/azurerm": {"h1:linux", "zh:checksum"},
+		"registry.terraform.io/hashicorp/random":  {"h1:random"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LockFileHashes() = %v, want %v", got, want)
+	}
+
+	if _, err := LockFileHashes([]byte("provider {"), lockFileName); err == nil {
+		t.Error("expected an error for an invalid lock file")
+	}
+}
+
+func TestConfig_LockPlatforms(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		want   []string
+	}{
+		{name: "disabled", config: &Config{LockPlatforms: []string{"linux_arm64"}}},
+		{name: "defaults", config: &Config{CheckLockPlatforms: true}, want: defaultLockPlatforms},
+		{name: "configured", config: &Config{CheckLockPlatforms: true, LockPlatforms: []string{"linux_arm64"}}, want: []string{"linux_arm64"}},
+		{name: "implied by fix", config: &Config{FixLockFiles: true}, want: defaultLockPlatforms},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.lockPlatforms(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lockPlatforms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := validateLockPlatforms([]string{"linux_amd64", "darwin-arm64"}); err == nil || !strings.Contains(err.Error(), "darwin-arm64") {
+		t.Errorf("validateLockPlatforms() error = %v, want one for darwin-arm64", err)
+	}
+}
+
+func TestModule_CheckLockFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		committed string
+		locked    string
+		fix       bool
+		wantErr   string
+		wantLock  string
+		wantCalls int
+	}{
+		{name: "no committed lock file", locked: allPlatformsLock},
+		{name: "all platforms covered", committed: allPlatformsLock, locked: allPlatformsLock, wantLock: allPlatformsLock, wantCalls: 1},
+		{
+			name:      "missing platforms",
+			committed: linuxLock,
+			locked:    allPlatformsLock,
+			wantErr:   "does not cover linux_amd64, darwin_arm64, windows_amd64 for registry.terraform.io/hashicorp/azurerm;",
+			wantLock:  linuxLock,
+			wantCalls: 1,
+		},
+		{name: "fixed", committed: linuxLock, locked: allPlatformsLock, fix: true, wantLock: allPlatformsLock, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, lockFileName)
+			if tt.committed != "" {
+				writeFiles(t, dir, map[string]string{lockFileName: tt.committed})
+			}
+			module := NewModule("default", dir)
+			module.useLockPlatforms(defaultLockPlatforms, tt.fix)
+
+			calls := 0
+			module.lockHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+				calls++
+				return os.WriteFile(path, []byte(tt.locked), 0644)
+			}
+			module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+
+			err := module.Apply(context.Background(), &recordingTB{TB: t})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Apply() error = %v, want %q", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("providers lock ran %d times, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantLock != "" {
+				content, _ := os.ReadFile(path)
+				if string(content) != tt.wantLock {
+					t.Errorf("lock file =\n%s\nwant\n%s", content, tt.wantLock)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package validor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+func WithLogDir(path string) Option {
+	return func(c *Config) { c.LogDir = path }
+}
+
+type fileLogger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func (l *fileLogger) Logf(_ terratesting.TestingT, format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.file, "%s %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+func logFileName(moduleName string) string {
+	// Replace the characters Windows does not allow in file names, which matrix
+	// values and monorepo prefixes may contain.
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", "\"", "_", "<", "_", ">", "_", "|", "_").Replace(moduleName)
+	return name + ".log"
+}
+
+func (m *Module) OpenLogFile(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory %s: %w", dir, err)
+	}
+
+	path := filepath.Join(dir, logFileName(m.Name))
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create log file %s: %w", path, err)
+	}
+
+	m.LogPath = path
+	m.logFile = file
+	m.Options.Logger = logger.New(&fileLogger{file: file})
+	return nil
+}
+
+func (m *Module) CloseLogFile() error {
+	if m.logFile == nil {
+		return nil
+	}
+	err := m.logFile.Close()
+	m.logFile = nil
+	m.Options.Logger = nil
+	return err
+}
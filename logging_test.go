@@ -0,0 +1,96 @@
+package validor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogFileName(t *testing.T) {
+	tests := []struct {
+		name       string
+		moduleName string
+		want       string
+	}{
+		{
+			name:       "simple name",
+			moduleName: "default",
+			want:       "default.log",
+		},
+		{
+			name:       "nested name",
+			moduleName: "network/peering",
+			want:       "network_peering.log",
+		},
+		{
+			name:       "characters windows does not allow",
+			moduleName: `default[version=~> 1.0,path=C:\tmp|"a"?*<b>]`,
+			want:       "default[version=~_ 1.0,path=C__tmp__a____b_].log",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logFileName(tt.moduleName); got != tt.want {
+				t.Errorf("logFileName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModule_OpenLogFile(t *testing.T) {
+	logDir := filepath.Join(t.TempDir(), "logs")
+	module := NewModule("example1", t.TempDir())
+
+	if err := module.OpenLogFile(logDir); err != nil {
+		t.Fatalf("OpenLogFile() error = %v", err)
+	}
+
+	if module.Options.Logger == nil {
+		t.Fatal("OpenLogFile() should set a terraform logger")
+	}
+
+	module.Options.Logger.Logf(t, "terraform %s output", "apply")
+
+	if err := module.CloseLogFile(); err != nil {
+		t.Fatalf("CloseLogFile() error = %v", err)
+	}
+	if module.Options.Logger != nil {
+		t.Error("CloseLogFile() should reset the terraform logger")
+	}
+
+	want := filepath.Join(logDir, "example1.log")
+	if module.LogPath != want {
+		t.Errorf("Module.LogPath = %v, want %v", module.LogPath, want)
+	}
+
+	content, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(content), "terraform apply output") {
+		t.Errorf("expected log file to contain terraform output, got %q", string(content))
+	}
+}
+
+func TestRunModuleTests_WritesLogFiles(t *testing.T) {
+	logDir := t.TempDir()
+	modules := createMockModules([]string{"mod1", "mod2"}, t.TempDir())
+
+	config := &Config{SkipDestroy: true, LogDir: logDir}
+	runModuleTests(t, Runners(modules), false, config, nil, "registry")
+
+	for _, name := range []string{"mod1", "mod2"} {
+		if _, err := os.Stat(filepath.Join(logDir, name+".log")); err != nil {
+			t.Errorf("expected log file for module %s: %v", name, err)
+		}
+	}
+}
+
+func TestModule_CloseLogFile_WithoutOpen(t *testing.T) {
+	module := NewModule("example1", t.TempDir())
+	if err := module.CloseLogFile(); err != nil {
+		t.Errorf("CloseLogFile() without open file should not error, got %v", err)
+	}
+}
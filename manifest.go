@@ -0,0 +1,252 @@
+package validor
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+var defaultDestroyManifest = "validor-destroy-manifest.json"
+
+// WithDestroyManifest sets the file that skip-destroy runs record their
+// examples in and that TestDestroyAll reads them from.
+func WithDestroyManifest(path string) Option {
+	return func(c *Config) { c.DestroyManifest = path }
+}
+
+// DestroyManifest lists the examples a skip-destroy run left applied.
+type DestroyManifest struct {
+	Examples []ManifestExample `json:"examples"`
+}
+
+type ManifestExample struct {
+	Name            string         `json:"name"`
+	Path            string         `json:"path"`
+	Workspace       string         `json:"workspace"`
+	RunID           string         `json:"run_id"`
+	Created         time.Time      `json:"created"`
+	Vars            map[string]any `json:"vars,omitempty"`
+	TerraformBinary string         `json:"terraform_binary,omitempty"`
+}
+
+func (c *Config) destroyManifestPath() string {
+	if c.DestroyManifest != "" {
+		return c.DestroyManifest
+	}
+	return defaultDestroyManifest
+}
+
+func LoadDestroyManifest(path string) (*DestroyManifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &DestroyManifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destroy manifest: %w", err)
+	}
+	var manifest DestroyManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse destroy manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Save writes the manifest to path, or removes the file once no examples are
+// left to destroy.
+func (m *DestroyManifest) Save(path string) error {
+	if len(m.Examples) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove destroy manifest: %w", err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode destroy manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create destroy manifest directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write destroy manifest: %w", err)
+	}
+	return nil
+}
+
+// add records an example, replacing an earlier entry for the same working
+// directory and workspace, whose state the new run has taken over.
+func (m *DestroyManifest) add(example ManifestExample) {
+	m.Examples = slices.DeleteFunc(m.Examples, func(e ManifestExample) bool {
+		return e.Path == example.Path && e.Workspace == example.Workspace
+	})
+	m.Examples = append(m.Examples, example)
+}
+
+func manifestExample(module *Module, runID string, created time.Time) ManifestExample {
+	workspace := module.Options.EnvVars["TF_WORKSPACE"]
+	if workspace == "" {
+		workspace = os.Getenv("TF_WORKSPACE")
+	}
+	if workspace == "" {
+		workspace = "default"
+	}
+	return ManifestExample{
+		Name:            module.Name,
+		Path:            module.terraformDir(),
+		Workspace:       workspace,
+		RunID:           runID,
+		Created:         created,
+		Vars:            module.Options.Vars,
+		TerraformBinary: cmp.Or(module.wrappedBinary, module.Options.TerraformBinary),
+	}
+}
+
+func (e ManifestExample) module() *Module {
+	module := NewModule(e.Name, e.Path)
+	module.Options.Vars = e.Vars
+	if e.TerraformBinary != "" {
+		module.Options.TerraformBinary = e.TerraformBinary
+	}
+	if e.Workspace != "default" {
+		module.Options.EnvVars = map[string]string{"TF_WORKSPACE": e.Workspace}
+	}
+	return module
+}
+
+// runID identifies a run in the manifest, preferring the CI run it is part of.
+func runID(started time.Time) string {
+	if id := os.Getenv("GITHUB_RUN_ID"); id != "" {
+		return id
+	}
+	return started.UTC().Format("20060102T150405Z")
+}
+
+// recordDestroyManifest adds the examples a skip-destroy run applied to the
+// manifest, in the order of modules so they are destroyed deterministically.
+// Runners that are not backed by a Module have no directory to record and are
+// left out.
+func recordDestroyManifest(config *Config, results *TestResults, modules []*Module, started time.Time) error {
+	if !config.SkipDestroy {
+		return nil
+	}
+	path := config.destroyManifestPath()
+	manifest, err := LoadDestroyManifest(path)
+	if err != nil {
+		return err
+	}
+
+	id := runID(started)
+	ran, _ := results.GetResults()
+	for _, module := range modules {
+		if slices.Contains(ran, module) {
+			manifest.add(manifestExample(module, id, started))
+		}
+	}
+	return manifest.Save(path)
+}
+
+// destroyManifestExample destroys an example and removes its state once that
+// succeeded, so a failed destroy can be retried.
+var destroyManifestExample = func(ctx context.Context, t testing.TB, module *Module) error {
+	if err := module.init(t); err != nil {
+		return err
+	}
+	if err := module.runPreDestroyHooks(ctx, t); err != nil {
+		t.Logf("Warning: pre-destroy hooks of %s failed: %v", module.Name, err)
+	}
+	purge := module.prepareAzurePurge(ctx, t)
+	if err := module.destroyInfrastructure(ctx, t); err != nil {
+		return err
+	}
+	purge()
+	return module.Cleanup(ctx, t)
+}
+
+// TestDestroyAll destroys the examples recorded in the destroy manifest, in
+// the reverse of the order they were applied in, limited to -example when set.
+// Examples that fail to destroy stay in the manifest.
+func TestDestroyAll(t testing.TB, opts ...Option) {
+	destroyAll(context.Background(), t, setupConfigWithOptions(opts...))
+}
+
+func destroyAll(ctx context.Context, t testing.TB, config *Config) {
+	path := config.destroyManifestPath()
+	manifest, err := LoadDestroyManifest(path)
+	if err != nil {
+		t.Fatal(errorText(err.Error()))
+		return
+	}
+	if len(manifest.Examples) == 0 {
+		t.Logf("No examples to destroy in %s", path)
+		return
+	}
+
+	var paths []string
+	for _, example := range manifest.Examples {
+		paths = append(paths, example.Path)
+	}
+	wrappers := config.execWrappers(t.TempDir(), paths...)
+	secrets, err := resolveSecrets(ctx, config.Secrets)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Failed to resolve secrets:\n%v", err)))
+		return
+	}
+	masker, err := config.secretMasker(secrets)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Invalid secret masking configuration: %v", err)))
+		return
+	}
+
+	selected := parseExampleList(config.Example)
+	var remaining []ManifestExample
+	for _, example := range slices.Backward(manifest.Examples) {
+		if len(selected) > 0 && !slices.Contains(selected, example.Name) {
+			remaining = append(remaining, example)
+			continue
+		}
+		runSubtest(t, example.Name, false, func(t testing.TB) {
+			t = masker.TB(t)
+			t.Logf("Destroying example %s from run %s in %s", example.Name, example.RunID, example.Path)
+			module := example.module()
+			module.useTerragrunt(cmp.Or(config.TerragruntBinary, example.TerraformBinary), "")
+			if wrappers != nil {
+				if err := module.useExecWrapper(wrappers); err != nil {
+					t.Error(errorText(fmt.Sprintf("Failed to destroy example %s: %v", example.Name, err)))
+					remaining = append(remaining, example)
+					return
+				}
+			}
+			useSecrets(module.Options, secrets)
+			module.masker = masker
+			module.addSecretVariables(config.SecretVariables)
+			defer module.maskLogs()()
+			module.useExtraArgs(config.ExtraArgs)
+			module.cleanup = config.CleanupPatterns
+			module.keepLock = config.PreserveLockFile
+			module.useDestroyPolicy(config)
+			module.azurePurge = config.AzurePurge
+			module.preDestroy = config.PreDestroyHooks[example.Name]
+			if module.preDestroy == nil {
+				// Matrix variants are recorded by their own name.
+				module.preDestroy = config.PreDestroyHooks[filepath.Base(example.Path)]
+			}
+			if err := destroyManifestExample(ctx, t, module); err != nil {
+				t.Error(errorText(fmt.Sprintf("Failed to destroy example %s: %v", example.Name, err)))
+				remaining = append(remaining, example)
+			}
+		})
+	}
+
+	slices.Reverse(remaining)
+	manifest.Examples = remaining
+	if err := manifest.Save(path); err != nil {
+		t.Error(errorText(err.Error()))
+	}
+}
@@ -0,0 +1,151 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestMain keeps the tests that skip destroy from writing a manifest into the
+// package directory.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "validor-manifest")
+	if err != nil {
+		panic(err)
+	}
+	defaultDestroyManifest = filepath.Join(dir, "manifest.json")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func manifestNames(t *testing.T, path string) []string {
+	t.Helper()
+	manifest, err := LoadDestroyManifest(path)
+	if err != nil {
+		t.Fatalf("LoadDestroyManifest() error = %v", err)
+	}
+	var names []string
+	for _, example := range manifest.Examples {
+		names = append(names, example.Name)
+	}
+	return names
+}
+
+func TestRunModuleTests_DestroyManifest(t *testing.T) {
+	t.Setenv("GITHUB_RUN_ID", "42")
+	path := filepath.Join(t.TempDir(), "manifest.json")
+
+	first, second := newPlannedModule(t, "first"), newPlannedModule(t, "second")
+	first.Options.Vars = map[string]any{"location": "westeurope"}
+	earlier := &DestroyManifest{Examples: []ManifestExample{
+		{Name: "older", Path: "/tmp/older", Workspace: "default", RunID: "41"},
+		{Name: "second", Path: second.terraformDir(), Workspace: "default", RunID: "41"},
+	}}
+	if err := earlier.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("run", func(t *testing.T) {
+		config := NewConfig(WithExample(""), WithSkipDestroy(true), WithDestroyManifest(path))
+		runModuleTests(t, Runners([]*Module{first, second}), false, config, nil, "registry")
+	})
+
+	manifest, err := LoadDestroyManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := manifestNames(t, path), []string{"older", "first", "second"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("manifest examples = %v, want %v", got, want)
+	}
+	recorded := manifest.Examples[1]
+	if recorded.Path != first.terraformDir() || recorded.Workspace != "default" || recorded.RunID != "42" || recorded.Vars["location"] != "westeurope" {
+		t.Errorf("unexpected manifest entry %+v", recorded)
+	}
+}
+
+func TestTestDestroyAll(t *testing.T) {
+	tests := []struct {
+		name          string
+		example       string
+		failing       string
+		wantDestroyed []string
+		wantRemaining []string
+	}{
+		{
+			name:          "reverse order",
+			wantDestroyed: []string{"c", "b", "a"},
+		},
+		{
+			name:          "failed destroy stays in the manifest",
+			failing:       "b",
+			wantDestroyed: []string{"c", "b", "a"},
+			wantRemaining: []string{"b"},
+		},
+		{
+			name:          "selected examples",
+			example:       "a,c",
+			wantDestroyed: []string{"c", "a"},
+			wantRemaining: []string{"b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "manifest.json")
+			manifest := &DestroyManifest{}
+			for _, name := range []string{"a", "b", "c"} {
+				manifest.add(ManifestExample{Name: name, Path: "/tmp/" + name, Workspace: "default", Created: time.Now()})
+			}
+			if err := manifest.Save(path); err != nil {
+				t.Fatal(err)
+			}
+
+			origDestroy := destroyManifestExample
+			t.Cleanup(func() { destroyManifestExample = origDestroy })
+			var destroyed []string
+			destroyManifestExample = func(ctx context.Context, t testing.TB, module *Module) error {
+				destroyed = append(destroyed, module.Name)
+				if module.Name == tt.failing {
+					return errors.New("destroy failed")
+				}
+				return nil
+			}
+
+			recorder := &recordingTB{TB: t}
+			destroyAll(context.Background(), recorder, &Config{Example: tt.example, DestroyManifest: path})
+
+			if recorder.failed != (tt.failing != "") {
+				t.Errorf("failed = %v, want %v", recorder.failed, tt.failing != "")
+			}
+			if !reflect.DeepEqual(destroyed, tt.wantDestroyed) {
+				t.Errorf("destroyed = %v, want %v", destroyed, tt.wantDestroyed)
+			}
+			if got := manifestNames(t, path); !reflect.DeepEqual(got, tt.wantRemaining) {
+				t.Errorf("remaining = %v, want %v", got, tt.wantRemaining)
+			}
+			if tt.wantRemaining == nil {
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Error("manifest should be removed once everything is destroyed")
+				}
+			}
+		})
+	}
+}
+
+func TestManifestExample_Workspace(t *testing.T) {
+	module := NewModule("default", "/tmp/default")
+	module.Options.EnvVars = map[string]string{"TF_WORKSPACE": "feature"}
+
+	example := manifestExample(module, "1", time.Now())
+	if example.Workspace != "feature" {
+		t.Fatalf("Workspace = %q, want feature", example.Workspace)
+	}
+	if got := example.module().Options.EnvVars["TF_WORKSPACE"]; got != "feature" {
+		t.Errorf("restored TF_WORKSPACE = %q, want feature", got)
+	}
+}
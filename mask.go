@@ -0,0 +1,302 @@
+package validor
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// WithSecretMasking masks the values of sensitive outputs and of the variables
+// examples declare sensitive in console logs, log files and reports. Modules
+// then log through a testing.TB that wraps the one the run was given.
+func WithSecretMasking(enabled bool) Option {
+	return func(c *Config) { c.MaskSecrets = enabled }
+}
+
+// WithSecretPatterns masks text matching these regular expressions, such as
+// connection strings, as well. It implies WithSecretMasking.
+func WithSecretPatterns(patterns ...string) Option {
+	return func(c *Config) { c.SecretPatterns = append(c.SecretPatterns, patterns...) }
+}
+
+// WithSecretVariables masks the values of these variables as well, in
+// addition to the ones examples declare sensitive. It implies
+// WithSecretMasking.
+func WithSecretVariables(names ...string) Option {
+	return func(c *Config) { c.SecretVariables = append(c.SecretVariables, names...) }
+}
+
+const (
+	maskedSecret = "***"
+	// minSecretLength keeps short values like "true" or "1" from being masked
+	// wherever they appear.
+	minSecretLength = 4
+)
+
+// Masker replaces known secrets in text before it is logged or reported.
+// Secrets are the values it is given and whatever matches its patterns. A
+// nil Masker leaves text as it is.
+type Masker struct {
+	mu       sync.RWMutex
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+func NewMasker(patterns ...string) (*Masker, error) {
+	masker := &Masker{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern %q: %w", pattern, err)
+		}
+		masker.patterns = append(masker.patterns, re)
+	}
+	return masker, nil
+}
+
+// secretMasker returns the masker for the secret patterns of c and the values
+// of the resolved secrets, or nil when nothing needs masking.
+func (c *Config) secretMasker(secrets map[string]string) (*Masker, error) {
+	if !c.MaskSecrets && len(c.SecretPatterns) == 0 && len(c.SecretVariables) == 0 && len(secrets) == 0 {
+		return nil, nil
+	}
+	masker, err := NewMasker(c.SecretPatterns...)
+	if err != nil {
+		return nil, err
+	}
+	masker.Add(slices.Collect(maps.Values(secrets))...)
+	return masker, nil
+}
+
+// Add masks these values from now on. Values shorter than four characters
+// are ignored.
+func (mk *Masker) Add(values ...string) {
+	if mk == nil {
+		return
+	}
+	mk.mu.Lock()
+	defer mk.mu.Unlock()
+	for _, value := range values {
+		if len(value) >= minSecretLength && !slices.Contains(mk.values, value) {
+			mk.values = append(mk.values, value)
+		}
+	}
+	// Longer values go first so a secret containing another is masked whole.
+	slices.SortStableFunc(mk.values, func(a, b string) int { return len(b) - len(a) })
+}
+
+// clone returns a Masker that masks what mk masks, or nothing when mk is
+// nil, so more values can be added without masking them in mk.
+func (mk *Masker) clone() *Masker {
+	if mk == nil {
+		return &Masker{}
+	}
+	mk.mu.RLock()
+	defer mk.mu.RUnlock()
+	return &Masker{values: slices.Clone(mk.values), patterns: slices.Clone(mk.patterns)}
+}
+
+// addValue masks the strings in a decoded JSON value, or the value itself
+// when it is not a string.
+func (mk *Masker) addValue(value any) {
+	switch v := value.(type) {
+	case nil:
+	case string:
+		mk.Add(v)
+	case map[string]any:
+		for _, nested := range v {
+			mk.addValue(nested)
+		}
+	case []any:
+		for _, nested := range v {
+			mk.addValue(nested)
+		}
+	default:
+		if encoded, err := json.Marshal(v); err == nil {
+			mk.Add(string(encoded))
+		}
+	}
+}
+
+func (mk *Masker) Mask(s string) string {
+	if mk == nil {
+		return s
+	}
+	mk.mu.RLock()
+	defer mk.mu.RUnlock()
+	for _, value := range mk.values {
+		s = strings.ReplaceAll(s, value, maskedSecret)
+	}
+	for _, re := range mk.patterns {
+		s = re.ReplaceAllString(s, maskedSecret)
+	}
+	return s
+}
+
+// maskValue returns a copy of a decoded JSON value with its strings masked.
+func (mk *Masker) maskValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return mk.Mask(v)
+	case map[string]any:
+		masked := make(map[string]any, len(v))
+		for key, nested := range v {
+			masked[key] = mk.maskValue(nested)
+		}
+		return masked
+	case []any:
+		masked := make([]any, len(v))
+		for i, nested := range v {
+			masked[i] = mk.maskValue(nested)
+		}
+		return masked
+	}
+	return value
+}
+
+// TB masks what is logged through tb. A nil Masker returns tb itself, so
+// subtests keep working when nothing needs masking.
+func (mk *Masker) TB(tb testing.TB) testing.TB {
+	if mk == nil {
+		return tb
+	}
+	return &maskedTB{TB: tb, masker: mk}
+}
+
+type maskedTB struct {
+	testing.TB
+	masker *Masker
+}
+
+func (t *maskedTB) Log(args ...any) {
+	t.Helper()
+	t.TB.Log(t.masker.Mask(fmt.Sprint(args...)))
+}
+
+func (t *maskedTB) Logf(format string, args ...any) {
+	t.Helper()
+	t.TB.Log(t.masker.Mask(fmt.Sprintf(format, args...)))
+}
+
+func (t *maskedTB) Error(args ...any) {
+	t.Helper()
+	t.TB.Error(t.masker.Mask(fmt.Sprint(args...)))
+}
+
+func (t *maskedTB) Errorf(format string, args ...any) {
+	t.Helper()
+	t.TB.Error(t.masker.Mask(fmt.Sprintf(format, args...)))
+}
+
+func (t *maskedTB) Fatal(args ...any) {
+	t.Helper()
+	t.TB.Fatal(t.masker.Mask(fmt.Sprint(args...)))
+}
+
+func (t *maskedTB) Fatalf(format string, args ...any) {
+	t.Helper()
+	t.TB.Fatal(t.masker.Mask(fmt.Sprintf(format, args...)))
+}
+
+func (t *maskedTB) Skip(args ...any) {
+	t.Helper()
+	t.TB.Skip(t.masker.Mask(fmt.Sprint(args...)))
+}
+
+func (t *maskedTB) Skipf(format string, args ...any) {
+	t.Helper()
+	t.TB.Skip(t.masker.Mask(fmt.Sprintf(format, args...)))
+}
+
+// maskingLogger masks terratest's output before passing it on to next, which
+// is terratest's default logger when nil.
+type maskingLogger struct {
+	masker *Masker
+	next   *logger.Logger
+}
+
+func (l *maskingLogger) Logf(t terratesting.TestingT, format string, args ...any) {
+	l.next.Logf(t, "%s", l.masker.Mask(fmt.Sprintf(format, args...)))
+}
+
+// maskLogs routes terratest's output for the module through its masker and
+// returns a func that restores the logger it used before.
+func (m *Module) maskLogs() func() {
+	previous := m.Options.Logger
+	if m.masker != nil {
+		m.Options.Logger = logger.New(&maskingLogger{masker: m.masker, next: previous})
+	}
+	return func() { m.Options.Logger = previous }
+}
+
+// maskSensitiveOutputs masks the values of the sensitive outputs in the
+// output of terraform output -json.
+func (m *Module) maskSensitiveOutputs(outputJSON []byte) {
+	if m.masker == nil {
+		return
+	}
+	var outputs map[string]struct {
+		Sensitive bool `json:"sensitive"`
+		Value     any  `json:"value"`
+	}
+	if err := json.Unmarshal(outputJSON, &outputs); err != nil {
+		return
+	}
+	for _, output := range outputs {
+		if output.Sensitive {
+			m.masker.addValue(output.Value)
+		}
+	}
+}
+
+// addSecretVariables masks the values the module passes to the variables in
+// names and to those its example declares sensitive.
+func (m *Module) addSecretVariables(names []string) {
+	if m.masker == nil {
+		return
+	}
+	if bodies, err := parseTerraformFiles(m.Options.TerraformDir); err == nil {
+		names = append(slices.Clone(names), sensitiveVariables(bodies)...)
+	}
+	for _, name := range names {
+		if value, ok := m.Options.Vars[name]; ok {
+			m.masker.addValue(value)
+		}
+		if value, ok := m.Options.EnvVars["TF_VAR_"+name]; ok {
+			m.masker.Add(value)
+		}
+		if value, ok := os.LookupEnv("TF_VAR_" + name); ok {
+			m.masker.Add(value)
+		}
+	}
+}
+
+func sensitiveVariables(bodies []*hclsyntax.Body) []string {
+	var names []string
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "variable" || len(block.Labels) == 0 {
+				continue
+			}
+			attr, ok := block.Body.Attributes["sensitive"]
+			if !ok {
+				continue
+			}
+			if value, diags := attr.Expr.Value(nil); !diags.HasErrors() && value.RawEquals(cty.True) {
+				names = append(names, block.Labels[0])
+			}
+		}
+	}
+	return names
+}
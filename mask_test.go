@@ -0,0 +1,148 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMasker(t *testing.T) {
+	masker, err := NewMasker(`AccountKey=[^;]+`)
+	if err != nil {
+		t.Fatalf("NewMasker() error = %v", err)
+	}
+	masker.Add("hunter2", "hunter2-admin", "abc", "")
+	masker.addValue(map[string]any{"password": "s3cr3t!", "port": 5432.0, "hosts": []any{"db.internal"}})
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "password hunter2-admin", want: "password ***"},
+		{in: "logged in with hunter2", want: "logged in with ***"},
+		{in: "abc is too short to mask", want: "abc is too short to mask"},
+		{in: "DefaultEndpointsProtocol=https;AccountKey=Zm9v==;EndpointSuffix=core", want: "DefaultEndpointsProtocol=https;***;EndpointSuffix=core"},
+		{in: "connect s3cr3t! db.internal:5432", want: "connect *** ***:***"},
+	}
+	for _, tt := range tests {
+		if got := masker.Mask(tt.in); got != tt.want {
+			t.Errorf("Mask(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	var none *Masker
+	none.Add("hunter2")
+	if got := none.Mask("hunter2"); got != "hunter2" {
+		t.Errorf("a nil Masker should not mask, got %q", got)
+	}
+	if _, err := NewMasker("("); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestMasker_TB(t *testing.T) {
+	masker, _ := NewMasker()
+	masker.Add("hunter2")
+
+	rec := &recordingTB{TB: t}
+	tb := masker.TB(rec)
+	tb.Logf("password %s", "hunter2")
+	tb.Error("failed with hunter2")
+	want := []string{"password ***", "failed with ***"}
+	if !reflect.DeepEqual(rec.logs, want) || !rec.failed {
+		t.Errorf("logs = %q, failed = %v, want %q", rec.logs, rec.failed, want)
+	}
+
+	var none *Masker
+	if none.TB(rec) != rec {
+		t.Error("a nil Masker should return the TB itself")
+	}
+}
+
+func TestModule_AddSecretVariables(t *testing.T) {
+	dir := t.TempDir()
+	variables := `variable "password" {
+  sensitive = true
+}
+
+variable "location" {}
+
+variable "token" {
+  sensitive = false
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(variables), 0644); err != nil {
+		t.Fatalf("Failed to write variables: %v", err)
+	}
+	t.Setenv("TF_VAR_token", "env-token")
+
+	module := NewModule("default", dir)
+	module.Options.Vars = map[string]any{"password": "hunter2", "location": "westeurope"}
+	module.Options.EnvVars = map[string]string{"TF_VAR_api_key": "key-1234"}
+	module.masker, _ = NewMasker()
+	module.addSecretVariables([]string{"api_key", "token"})
+
+	got := module.masker.Mask("hunter2 westeurope key-1234 env-token")
+	if want := "*** westeurope *** ***"; got != want {
+		t.Errorf("Mask() = %q, want %q", got, want)
+	}
+}
+
+func TestModule_MasksSecrets(t *testing.T) {
+	module := NewModule("default", t.TempDir())
+	module.masker, _ = NewMasker()
+	module.revealOutputs = true
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+	module.outputsHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+		return []byte(`{"id":{"value":"rg-test"},"key":{"sensitive":true,"value":"primary-key"}}`), nil
+	}
+	if err := module.OpenLogFile(t.TempDir()); err != nil {
+		t.Fatalf("OpenLogFile() error = %v", err)
+	}
+	restore := module.maskLogs()
+
+	tb := &recordingTB{TB: t}
+	module.Apply(context.Background(), tb)
+	module.Options.Logger.Logf(t, "using key %s", "primary-key")
+	module.recordError(tb, StageDestroy, &ModuleError{ModuleName: module.Name, Operation: "terraform destroy", Err: errors.New("key primary-key rejected")})
+	restore()
+	module.CloseLogFile()
+
+	if content, _ := os.ReadFile(module.LogPath); strings.Contains(string(content), "primary-key") || !strings.Contains(string(content), "using key ***") {
+		t.Errorf("the log file should be masked, got %q", content)
+	}
+	if module.Outputs["key"] != "primary-key" {
+		t.Errorf("revealed outputs should stay on the module, got %v", module.Outputs)
+	}
+	report := newModuleReport(module)
+	if report.Outputs["key"] != maskedSecret || report.Outputs["id"] != "rg-test" {
+		t.Errorf("report outputs = %v, want the sensitive one masked", report.Outputs)
+	}
+	if want := []string{"terraform destroy failed for module default: key *** rejected"}; !reflect.DeepEqual(report.Errors, want) {
+		t.Errorf("report errors = %q, want %q", report.Errors, want)
+	}
+}
+
+func TestStreamWriter_Mask(t *testing.T) {
+	var dst strings.Builder
+	masker, _ := NewMasker()
+	masker.Add("hunter2")
+	w := &streamWriter{dst: &dst, mask: masker.Mask}
+
+	w.Write([]byte("password hun"))
+	w.Write([]byte("ter2\nlast hunter2"))
+	if dst.String() != "password ***\n" {
+		t.Errorf("only complete lines should be written, got %q", dst.String())
+	}
+	w.flush()
+	if dst.String() != "password ***\nlast ***" {
+		t.Errorf("flush() should write the masked last line, got %q", dst.String())
+	}
+	if w.tail() != "password ***\nlast ***" {
+		t.Errorf("tail() = %q", w.tail())
+	}
+}
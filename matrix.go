@@ -0,0 +1,175 @@
+package validor
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func WithMatrix(matrix map[string][]string) Option {
+	return func(c *Config) { c.Matrix = matrix }
+}
+
+func matrixFlag(m *map[string][]string) func(string) error {
+	return func(value string) error {
+		key, values, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(key) == "" || strings.TrimSpace(values) == "" {
+			return fmt.Errorf("invalid matrix %q, expected KEY=VALUE1,VALUE2", value)
+		}
+		if *m == nil {
+			*m = make(map[string][]string)
+		}
+		key = strings.TrimSpace(key)
+		for v := range strings.SplitSeq(values, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				(*m)[key] = append((*m)[key], v)
+			}
+		}
+		return nil
+	}
+}
+
+// matrixCombinations returns the cartesian product of the matrix values,
+// ordered by key name and then by the order the values were given in.
+func matrixCombinations(matrix map[string][]string) []map[string]string {
+	combinations := []map[string]string{{}}
+	for _, key := range slices.Sorted(maps.Keys(matrix)) {
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, value := range matrix[key] {
+				expanded := maps.Clone(combination)
+				expanded[key] = value
+				next = append(next, expanded)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+func matrixSuffix(combination map[string]string) string {
+	var parts []string
+	for _, key := range slices.Sorted(maps.Keys(combination)) {
+		parts = append(parts, key+"="+combination[key])
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// expandMatrix replaces every module runner with one variant per combination
+// of the matrix keys its example declares as variables, as terraform rejects
+// values for undeclared ones. Variants of the same example share a working
+// directory, so they are given a common lock and run one at a time. Other
+// runners are kept as is. A key no example declares is an error.
+func expandMatrix(runners []ModuleRunner, matrix map[string][]string, exceptions []string) ([]ModuleRunner, error) {
+	if len(matrix) == 0 {
+		return runners, nil
+	}
+
+	unused := make(map[string]bool)
+	for key, values := range matrix {
+		if len(values) > 0 {
+			unused[key] = true
+		}
+	}
+	var expanded []ModuleRunner
+	for _, runner := range runners {
+		adapter, ok := runner.(moduleRunner)
+		if !ok || slices.Contains(exceptions, runner.Name()) {
+			expanded = append(expanded, runner)
+			continue
+		}
+
+		module := adapter.module
+		declared, err := matrixVariables(module.Path, matrix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the variables of %s: %w", module.Name, err)
+		}
+		for key := range declared {
+			delete(unused, key)
+		}
+		combinations := matrixCombinations(declared)
+		if len(declared) == 0 || len(combinations) == 0 {
+			expanded = append(expanded, runner)
+			continue
+		}
+
+		lock := module.variantLock()
+		for _, combination := range combinations {
+			variant := module.variant(module.Name+matrixSuffix(combination), lock)
+			if variant.Options.Vars == nil {
+				variant.Options.Vars = make(map[string]any)
+			}
+			for key, value := range combination {
+				variant.Options.Vars[key] = value
+			}
+			expanded = append(expanded, variant.Runner())
+		}
+	}
+	if len(unused) > 0 {
+		return nil, fmt.Errorf("no example declares a variable for matrix key(s) %s", strings.Join(slices.Sorted(maps.Keys(unused)), ", "))
+	}
+	return expanded, nil
+}
+
+// matrixVariables returns the part of matrix whose keys the example in dir
+// declares as variables.
+func matrixVariables(dir string, matrix map[string][]string) (map[string][]string, error) {
+	bodies, err := parseTerraformFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	declared := make(map[string][]string)
+	for _, name := range blockLabels(bodies, "variable") {
+		if values, ok := matrix[name]; ok && len(values) > 0 {
+			declared[name] = values
+		}
+	}
+	return declared, nil
+}
+
+// variantLock returns the lock the variants of the module share, which is the
+// module's own when it is a variant already.
+func (m *Module) variantLock() sync.Locker {
+	if m.runLock != nil {
+		return m.runLock
+	}
+	return &sync.Mutex{}
+}
+
+// restoreLater runs restore when the test finishes or, for a variant, before
+// the next variant of its example takes over the working directory.
+func (m *Module) restoreLater(t testing.TB, restore func()) {
+	if m.runLock == nil {
+		t.Cleanup(restore)
+		return
+	}
+	m.restores = append(m.restores, restore)
+}
+
+// restore runs the restores deferred by restoreLater, latest first.
+func (m *Module) restore() {
+	for i := len(m.restores) - 1; i >= 0; i-- {
+		m.restores[i]()
+	}
+	m.restores = nil
+}
+
+// variant returns a copy of the module that runs as name in the same working
+// directory, holding lock while it runs.
+func (m *Module) variant(name string, lock sync.Locker) *Module {
+	variant := NewModule(name, m.Path)
+	variant.example = m.exampleName()
+	variant.info = m.info
+	variant.runLock = lock
+	variant.Tags = m.Tags
+	variant.Metadata = m.Metadata
+	variant.Remote = m.Remote
+	variant.UpgradeFrom = m.UpgradeFrom
+	options := *m.Options
+	variant.Options = &options
+	variant.Options.Vars = maps.Clone(m.Options.Vars)
+	return variant
+}
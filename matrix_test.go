@@ -0,0 +1,160 @@
+package validor
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// matrixExample returns a module for an example that declares variables.
+func matrixExample(t *testing.T, name string, variables ...string) *Module {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), name)
+	var tf strings.Builder
+	for _, variable := range variables {
+		fmt.Fprintf(&tf, "variable %q {}\n", variable)
+	}
+	writeFiles(t, dir, map[string]string{"variables.tf": tf.String()})
+	return NewModule(name, dir)
+}
+
+func TestMatrixFlag(t *testing.T) {
+	var matrix map[string][]string
+	parse := matrixFlag(&matrix)
+
+	for _, value := range []string{"location=westeurope, eastus", "sku=Basic", "sku=Standard"} {
+		if err := parse(value); err != nil {
+			t.Fatalf("parse(%q) error = %v", value, err)
+		}
+	}
+	want := map[string][]string{
+		"location": {"westeurope", "eastus"},
+		"sku":      {"Basic", "Standard"},
+	}
+	if !reflect.DeepEqual(matrix, want) {
+		t.Errorf("matrix = %v, want %v", matrix, want)
+	}
+
+	for _, value := range []string{"location", "=eastus", "location="} {
+		if err := parse(value); err == nil {
+			t.Errorf("parse(%q) should fail", value)
+		}
+	}
+}
+
+func TestExpandMatrix(t *testing.T) {
+	tests := []struct {
+		name       string
+		matrix     map[string][]string
+		exceptions []string
+		wantNames  []string
+		wantErr    string
+	}{
+		{
+			name:      "no matrix",
+			wantNames: []string{"default", "complete"},
+		},
+		{
+			name:      "single key",
+			matrix:    map[string][]string{"location": {"westeurope", "eastus"}},
+			wantNames: []string{"default[location=westeurope]", "default[location=eastus]", "complete[location=westeurope]", "complete[location=eastus]"},
+		},
+		{
+			name:       "cartesian product with exception",
+			matrix:     map[string][]string{"sku": {"Basic", "Standard"}, "location": {"westeurope", "eastus"}},
+			exceptions: []string{"complete"},
+			wantNames: []string{
+				"default[location=westeurope,sku=Basic]",
+				"default[location=westeurope,sku=Standard]",
+				"default[location=eastus,sku=Basic]",
+				"default[location=eastus,sku=Standard]",
+				"complete",
+			},
+		},
+		{
+			name:      "keys the example does not declare",
+			matrix:    map[string][]string{"sku": {"Basic", "Standard"}, "location": {"westeurope"}},
+			wantNames: []string{"default[location=westeurope,sku=Basic]", "default[location=westeurope,sku=Standard]", "complete[location=westeurope]"},
+		},
+		{
+			name:    "key no example declares",
+			matrix:  map[string][]string{"location": {"westeurope"}, "zone": {"1"}, "region": {"eu"}},
+			wantErr: "matrix key(s) region, zone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := matrixExample(t, "default", "prefix", "location", "sku")
+			base.Options.Vars = map[string]any{"prefix": "test"}
+			modules := []*Module{base, matrixExample(t, "complete", "location")}
+
+			expanded, err := expandMatrix(Runners(modules), tt.matrix, tt.exceptions)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandMatrix() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandMatrix() error = %v", err)
+			}
+
+			var names []string
+			for _, runner := range expanded {
+				names = append(names, runner.Name())
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestExpandMatrix_Variants(t *testing.T) {
+	base := matrixExample(t, "default", "prefix", "location")
+	base.Options.Vars = map[string]any{"prefix": "test"}
+
+	runners, err := expandMatrix(Runners([]*Module{base}), map[string][]string{"location": {"westeurope", "eastus"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded := runnerModules(runners)
+	if len(expanded) != 2 {
+		t.Fatalf("expected 2 variants, got %d", len(expanded))
+	}
+
+	first, second := expanded[0], expanded[1]
+	if first.exampleName() != "default" || second.exampleName() != "default" {
+		t.Errorf("exampleName = %q, %q, want default", first.exampleName(), second.exampleName())
+	}
+	if first.Options.Vars["location"] != "westeurope" || second.Options.Vars["location"] != "eastus" {
+		t.Errorf("unexpected matrix vars: %v, %v", first.Options.Vars, second.Options.Vars)
+	}
+	if first.Options.Vars["prefix"] != "test" {
+		t.Errorf("base vars not copied: %v", first.Options.Vars)
+	}
+	if _, ok := base.Options.Vars["location"]; ok {
+		t.Error("base module vars should not be modified")
+	}
+	if first.runLock == nil || first.runLock != second.runLock {
+		t.Error("variants of one example should share a run lock")
+	}
+	if first.Options.TerraformDir != base.Path {
+		t.Errorf("TerraformDir = %q", first.Options.TerraformDir)
+	}
+}
+
+func TestExpandMatrix_KeepsCustomRunners(t *testing.T) {
+	custom := &fakeRunner{name: "custom"}
+	network := matrixExample(t, "network", "location")
+	expanded, err := expandMatrix([]ModuleRunner{custom, network.Runner()}, map[string][]string{"location": {"westeurope", "eastus"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expanded) != 3 || expanded[0] != custom {
+		t.Errorf("expected custom runner to be kept as is, got %v", expanded)
+	}
+}
@@ -0,0 +1,362 @@
+package validor
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const metadataFileName = ".validor.yaml"
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// ExampleMetadata is read from a .validor.yaml file in the example directory.
+type ExampleMetadata struct {
+	Skip      string          `yaml:"skip"`
+	Tags      []string        `yaml:"tags"`
+	Timeout   time.Duration   `yaml:"timeout"`
+	VarFiles  []string        `yaml:"var_files"`
+	DependsOn []string        `yaml:"depends_on"`
+	Expect    ExpectedOutcome `yaml:"expect"`
+	// Weight orders the examples of a run: lower weights run first.
+	Weight int `yaml:"weight"`
+}
+
+type ExpectedOutcome struct {
+	Outcome Outcome `yaml:"outcome"`
+	Error   string  `yaml:"error"`
+	// Stage is where the failure must happen. An example expected to fail at
+	// plan is only planned, so nothing is created when the plan does pass.
+	Stage Stage `yaml:"stage"`
+}
+
+// WithExpectFailure marks an example as one that must fail with an error
+// matching pattern, such as a variable validation or precondition message.
+// An empty pattern accepts any error. Metadata of the example is overridden.
+func WithExpectFailure(example, pattern string) Option {
+	return func(c *Config) { c.expectFailure(example, ExpectedOutcome{Outcome: OutcomeFailure, Error: pattern}) }
+}
+
+// WithExpectPlanFailure is WithExpectFailure for an example that must already
+// fail at plan; it is never applied.
+func WithExpectPlanFailure(example, pattern string) Option {
+	return func(c *Config) {
+		c.expectFailure(example, ExpectedOutcome{Outcome: OutcomeFailure, Error: pattern, Stage: StagePlan})
+	}
+}
+
+func (c *Config) expectFailure(example string, expect ExpectedOutcome) {
+	if c.ExpectedFailures == nil {
+		c.ExpectedFailures = make(map[string]ExpectedOutcome)
+	}
+	c.ExpectedFailures[example] = expect
+}
+
+// expectFailureFlag parses repeated EXAMPLE[=PATTERN] flag values.
+func expectFailureFlag(c *Config) func(string) error {
+	return func(value string) error {
+		example, pattern, _ := strings.Cut(value, "=")
+		if example = strings.TrimSpace(example); example == "" {
+			return fmt.Errorf("invalid value %q, expected EXAMPLE=PATTERN", value)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid expected error pattern: %w", err)
+		}
+		c.expectFailure(example, ExpectedOutcome{Outcome: OutcomeFailure, Error: pattern})
+		return nil
+	}
+}
+
+func (e ExpectedOutcome) validate() error {
+	switch e.Outcome {
+	case "", OutcomeSuccess, OutcomeFailure:
+	default:
+		return fmt.Errorf("unknown expected outcome %q", e.Outcome)
+	}
+	if e.Error != "" {
+		if e.Outcome != OutcomeFailure {
+			return fmt.Errorf("expected error %q requires outcome %q", e.Error, OutcomeFailure)
+		}
+		if _, err := regexp.Compile(e.Error); err != nil {
+			return fmt.Errorf("invalid expected error pattern: %w", err)
+		}
+	}
+	switch e.Stage {
+	case "":
+	case StagePlan, StageApply:
+		if e.Outcome != OutcomeFailure {
+			return fmt.Errorf("expected stage %q requires outcome %q", e.Stage, OutcomeFailure)
+		}
+	default:
+		return fmt.Errorf("expected failure stage must be %q or %q, got %q", StagePlan, StageApply, e.Stage)
+	}
+	return nil
+}
+
+// ParseExampleMetadata decodes metadata, rejecting unknown keys so typos do not
+// silently change how an example runs.
+func ParseExampleMetadata(data []byte) (*ExampleMetadata, error) {
+	metadata := &ExampleMetadata{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(metadata); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if err := metadata.Expect.validate(); err != nil {
+		return nil, err
+	}
+	if metadata.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative")
+	}
+	return metadata, nil
+}
+
+// LoadMetadata reads the example's metadata file, if any, and configures the
+// module from it. Dependencies are qualified the same way as module names.
+func (mm *ModuleManager) LoadMetadata(module *Module) error {
+	data, err := os.ReadFile(filepath.Join(module.Path, metadataFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read metadata for %s: %w", module.Name, err)
+	}
+
+	metadata, err := ParseExampleMetadata(data)
+	if err != nil {
+		return fmt.Errorf("invalid %s in %s: %w", metadataFileName, module.Name, err)
+	}
+	for i, dependency := range metadata.DependsOn {
+		metadata.DependsOn[i] = mm.namePrefix + dependency
+	}
+
+	module.Metadata = metadata
+	module.Tags = metadata.Tags
+	module.Options.VarFiles = append(module.Options.VarFiles, metadata.VarFiles...)
+	return nil
+}
+
+func loadModulesMetadata(t testing.TB, modules []*Module, basePath string) {
+	t.Helper()
+	manager := NewModuleManager(basePath)
+	for _, module := range modules {
+		if err := manager.LoadMetadata(module); err != nil {
+			t.Fatal(errorText(err.Error()))
+		}
+	}
+}
+
+func (m *Module) skipReason() string {
+	if m.Metadata == nil {
+		return ""
+	}
+	return m.Metadata.Skip
+}
+
+func (m *Module) timeout() time.Duration {
+	if m.Metadata == nil {
+		return 0
+	}
+	return m.Metadata.Timeout
+}
+
+// expect overrides the outcome the example's metadata expects.
+func (m *Module) expect(expect ExpectedOutcome) {
+	if m.Metadata == nil {
+		m.Metadata = &ExampleMetadata{}
+	}
+	m.Metadata.Expect = expect
+}
+
+func (m *Module) expectsFailureAt(stage Stage) bool {
+	return m.Metadata != nil && m.Metadata.Expect.Outcome == OutcomeFailure && m.Metadata.Expect.Stage == stage
+}
+
+// failedStage returns the first stage the module failed in.
+func (m *Module) failedStage() Stage {
+	for _, stage := range stageOrder {
+		if m.failed[stage] != nil {
+			return stage
+		}
+	}
+	return ""
+}
+
+// checkExpectedOutcome turns a failure into a pass for examples that are
+// expected to fail, and a pass into a failure when the error did not happen.
+func (m *Module) checkExpectedOutcome(t testing.TB, err error) error {
+	t.Helper()
+	if m.Metadata == nil || m.Metadata.Expect.Outcome != OutcomeFailure {
+		return err
+	}
+	expect := m.Metadata.Expect
+
+	if err == nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "expected failure", Err: fmt.Errorf("example succeeded but was expected to fail")})
+	}
+	if expect.Stage != "" && m.failed[expect.Stage] == nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "expected failure", Err: fmt.Errorf("example failed at %s but was expected to fail at %s", m.failedStage(), expect.Stage)})
+	}
+	if expect.Error != "" {
+		pattern, compileErr := regexp.Compile(expect.Error)
+		if compileErr != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "expected failure", Err: fmt.Errorf("invalid expected error pattern: %w", compileErr)})
+		}
+		if !pattern.MatchString(err.Error()) {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "expected failure", Err: fmt.Errorf("error does not match %q", expect.Error)})
+		}
+	}
+
+	t.Logf("Module %s failed as expected", m.Name)
+	m.Errors = nil
+	m.Failures = nil
+	m.failed = nil
+	return nil
+}
+
+type dependencyState struct {
+	done      chan struct{}
+	succeeded bool
+}
+
+func runnerExample(runner ModuleRunner) string {
+	if module := moduleOf(runner); module != nil {
+		return module.exampleName()
+	}
+	return runner.Name()
+}
+
+func runnerDependsOn(runner ModuleRunner) []string {
+	if module := moduleOf(runner); module != nil && module.Metadata != nil {
+		return module.Metadata.DependsOn
+	}
+	return nil
+}
+
+// dependencyIndices returns, for every runner, the indices of the runners it
+// depends on. Dependencies on examples that are not part of the run are
+// ignored.
+func dependencyIndices(runners []ModuleRunner) [][]int {
+	byExample := make(map[string][]int)
+	for i, runner := range runners {
+		byExample[runnerExample(runner)] = append(byExample[runnerExample(runner)], i)
+	}
+
+	dependencies := make([][]int, len(runners))
+	for i, runner := range runners {
+		for _, dependency := range runnerDependsOn(runner) {
+			for _, j := range byExample[dependency] {
+				if j != i {
+					dependencies[i] = append(dependencies[i], j)
+				}
+			}
+		}
+	}
+	return dependencies
+}
+
+// dependencyWaves groups the indices of runners ordered by
+// orderByDependencies into waves, each holding the runners whose dependencies
+// are all in earlier waves.
+func dependencyWaves(dependencies [][]int) [][]int {
+	level := make([]int, len(dependencies))
+	var waves [][]int
+	for i, deps := range dependencies {
+		for _, j := range deps {
+			level[i] = max(level[i], level[j]+1)
+		}
+		if level[i] == len(waves) {
+			waves = append(waves, nil)
+		}
+		waves[level[i]] = append(waves[level[i]], i)
+	}
+	return waves
+}
+
+// WithExampleWeight overrides the weight of an example's metadata, so cheap
+// or fast examples can run before expensive ones.
+func WithExampleWeight(example string, weight int) Option {
+	return func(c *Config) {
+		if c.Weights == nil {
+			c.Weights = make(map[string]int)
+		}
+		c.Weights[example] = weight
+	}
+}
+
+func runnerWeight(runner ModuleRunner, weights map[string]int) int {
+	if weight, ok := weights[runnerExample(runner)]; ok {
+		return weight
+	}
+	if module := moduleOf(runner); module != nil && module.Metadata != nil {
+		return module.Metadata.Weight
+	}
+	return 0
+}
+
+// orderByWeight sorts runners by weight, keeping the original order of runners
+// of equal weight. Dependencies still run first; see orderByDependencies.
+func orderByWeight(runners []ModuleRunner, weights map[string]int) []ModuleRunner {
+	ordered := slices.Clone(runners)
+	slices.SortStableFunc(ordered, func(a, b ModuleRunner) int {
+		return cmp.Compare(runnerWeight(a, weights), runnerWeight(b, weights))
+	})
+	return ordered
+}
+
+// orderByDependencies sorts runners so that every runner comes after the
+// examples it depends on, keeping the original order otherwise.
+func orderByDependencies(runners []ModuleRunner) ([]ModuleRunner, error) {
+	dependencies := dependencyIndices(runners)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(runners))
+	ordered := make([]ModuleRunner, 0, len(runners))
+
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v", append(path, runners[i].Name()))
+		}
+		state[i] = visiting
+		for _, j := range dependencies[i] {
+			if err := visit(j, append(path, runners[i].Name())); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		ordered = append(ordered, runners[i])
+		return nil
+	}
+
+	for i := range runners {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
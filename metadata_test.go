@@ -0,0 +1,399 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseExampleMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *ExampleMetadata
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			data: "",
+			want: &ExampleMetadata{},
+		},
+		{
+			name: "all fields",
+			data: `
+skip: waiting on provider fix
+tags: [network, slow]
+timeout: 45m
+var_files: [ci.tfvars]
+depends_on: [shared]
+weight: 10
+expect:
+  outcome: failure
+  error: must be one of
+`,
+			want: &ExampleMetadata{
+				Skip:      "waiting on provider fix",
+				Tags:      []string{"network", "slow"},
+				Timeout:   45 * time.Minute,
+				VarFiles:  []string{"ci.tfvars"},
+				DependsOn: []string{"shared"},
+				Expect:    ExpectedOutcome{Outcome: OutcomeFailure, Error: "must be one of"},
+				Weight:    10,
+			},
+		},
+		{name: "unknown key", data: "skipp: typo", wantErr: true},
+		{name: "unknown outcome", data: "expect:\n  outcome: maybe", wantErr: true},
+		{name: "error without failure outcome", data: "expect:\n  error: boom", wantErr: true},
+		{name: "invalid error pattern", data: "expect:\n  outcome: failure\n  error: '('", wantErr: true},
+		{name: "invalid timeout", data: "timeout: soon", wantErr: true},
+		{
+			name: "expected plan failure",
+			data: "expect:\n  outcome: failure\n  stage: plan",
+			want: &ExampleMetadata{Expect: ExpectedOutcome{Outcome: OutcomeFailure, Stage: StagePlan}},
+		},
+		{name: "stage without failure outcome", data: "expect:\n  stage: plan", wantErr: true},
+		{name: "unsupported stage", data: "expect:\n  outcome: failure\n  stage: destroy", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExampleMetadata([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExampleMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExampleMetadata() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleManager_LoadMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"default", "complete", "broken"} {
+		if err := os.Mkdir(filepath.Join(tmpDir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	metadata := "tags: [network]\nvar_files: [ci.tfvars]\ndepends_on: [default]\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "complete", metadataFileName), []byte(metadata), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewModuleManager(tmpDir)
+	manager.namePrefix = "vnet/"
+	modules, err := manager.DiscoverModules()
+	if err != nil {
+		t.Fatalf("DiscoverModules() error = %v", err)
+	}
+
+	byName := make(map[string]*Module)
+	for _, module := range modules {
+		byName[module.Name] = module
+	}
+	if byName["vnet/default"].Metadata != nil {
+		t.Error("example without metadata file should have no metadata")
+	}
+	complete := byName["vnet/complete"]
+	if complete.Metadata == nil {
+		t.Fatal("expected metadata for complete example")
+	}
+	if !reflect.DeepEqual(complete.Tags, []string{"network"}) {
+		t.Errorf("Tags = %v", complete.Tags)
+	}
+	if !reflect.DeepEqual(complete.Options.VarFiles, []string{"ci.tfvars"}) {
+		t.Errorf("VarFiles = %v", complete.Options.VarFiles)
+	}
+	if !reflect.DeepEqual(complete.Metadata.DependsOn, []string{"vnet/default"}) {
+		t.Errorf("DependsOn = %v, want qualified names", complete.Metadata.DependsOn)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "broken", metadataFileName), []byte("timeout: soon"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.DiscoverModules(); err == nil {
+		t.Error("expected error for invalid metadata")
+	}
+}
+
+func TestOrderByDependencies(t *testing.T) {
+	module := func(name string, deps ...string) *Module {
+		m := NewModule(name, "/tmp/"+name)
+		if len(deps) > 0 {
+			m.Metadata = &ExampleMetadata{DependsOn: deps}
+		}
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		modules []*Module
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "no dependencies keeps order",
+			modules: []*Module{module("b"), module("a")},
+			want:    []string{"b", "a"},
+		},
+		{
+			name:    "dependency moves first",
+			modules: []*Module{module("app", "network"), module("storage"), module("network")},
+			want:    []string{"network", "app", "storage"},
+		},
+		{
+			name:    "unknown dependency is ignored",
+			modules: []*Module{module("app", "excluded")},
+			want:    []string{"app"},
+		},
+		{
+			name:    "cycle",
+			modules: []*Module{module("a", "b"), module("b", "a")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := orderByDependencies(Runners(tt.modules))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("orderByDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(extractModuleNames(runnerModules(ordered)), tt.want) {
+				t.Errorf("order = %v, want %v", extractModuleNames(runnerModules(ordered)), tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderByWeight(t *testing.T) {
+	module := func(name string, weight int, deps ...string) *Module {
+		m := NewModule(name, "/tmp/"+name)
+		m.Metadata = &ExampleMetadata{Weight: weight, DependsOn: deps}
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		modules []*Module
+		weights map[string]int
+		want    []string
+	}{
+		{
+			name:    "lower weight first, ties keep order",
+			modules: []*Module{module("complete", 10), module("default", 0), module("private", 10), module("minimal", -5)},
+			want:    []string{"minimal", "default", "complete", "private"},
+		},
+		{
+			name:    "option overrides metadata",
+			modules: []*Module{module("complete", 10), module("default", 0)},
+			weights: map[string]int{"complete": -1},
+			want:    []string{"complete", "default"},
+		},
+		{
+			name:    "dependencies still run first",
+			modules: []*Module{module("network", 10), module("app", 0, "network"), module("storage", 5)},
+			want:    []string{"network", "app", "storage"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := orderByDependencies(orderByWeight(Runners(tt.modules), tt.weights))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := extractModuleNames(runnerModules(ordered)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencyIndices(t *testing.T) {
+	network := matrixExample(t, "network", "location")
+	app := NewModule("app", "/tmp/app")
+	app.Metadata = &ExampleMetadata{DependsOn: []string{"network", "custom"}}
+
+	runners, err := expandMatrix(Runners([]*Module{network}), map[string][]string{"location": {"westeurope", "eastus"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runners = append(runners, app.Runner(), &fakeRunner{name: "custom"})
+
+	dependencies := dependencyIndices(runners)
+	if want := []int{0, 1, 3}; !reflect.DeepEqual(dependencies[2], want) {
+		t.Errorf("app dependencies = %v, want both matrix variants and the custom runner %v", dependencies[2], want)
+	}
+	if len(dependencies[0]) != 0 || len(dependencies[3]) != 0 {
+		t.Errorf("unexpected dependencies: %v", dependencies)
+	}
+}
+
+func TestDependencyWaves(t *testing.T) {
+	tests := []struct {
+		name         string
+		dependencies [][]int
+		want         [][]int
+	}{
+		{name: "no runners"},
+		{name: "no dependencies", dependencies: [][]int{nil, nil, nil}, want: [][]int{{0, 1, 2}}},
+		{
+			name:         "chain and independent runner",
+			dependencies: [][]int{nil, {0}, nil, {1}},
+			want:         [][]int{{0, 2}, {1}, {3}},
+		},
+		{
+			name:         "longest path decides the wave",
+			dependencies: [][]int{nil, {0}, {0, 1}},
+			want:         [][]int{{0}, {1}, {2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dependencyWaves(tt.dependencies); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dependencyWaves() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunModuleTests_DependentsRunInLaterWave(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	network := NewModule("network", t.TempDir())
+	app := NewModule("app", t.TempDir())
+	app.Metadata = &ExampleMetadata{DependsOn: []string{"network"}}
+	for _, module := range []*Module{network, app} {
+		module.applyHook = func(ctx context.Context, t testing.TB, m *Module) error {
+			record(m.Name)
+			return nil
+		}
+		module.destroyHook = func(ctx context.Context, t testing.TB, m *Module) error { return nil }
+	}
+
+	t.Run("run", func(t *testing.T) {
+		runModuleTests(t, Runners([]*Module{app, network}), true, &Config{}, nil, "registry")
+	})
+	if !reflect.DeepEqual(order, []string{"network", "app"}) {
+		t.Errorf("apply order = %v, want the dependency first", order)
+	}
+}
+
+func TestModule_CheckExpectedOutcome(t *testing.T) {
+	tests := []struct {
+		name       string
+		expect     ExpectedOutcome
+		err        error
+		wantErr    bool
+		wantErrors int
+	}{
+		{name: "no expectation passes error through", err: errors.New("boom"), wantErr: true},
+		{name: "no expectation passes success through"},
+		{name: "expected failure happened", expect: ExpectedOutcome{Outcome: OutcomeFailure}, err: errors.New("boom")},
+		{name: "expected failure matches", expect: ExpectedOutcome{Outcome: OutcomeFailure, Error: "must be one of"}, err: errors.New("value must be one of: a, b")},
+		{name: "expected failure with other error", expect: ExpectedOutcome{Outcome: OutcomeFailure, Error: "must be one of"}, err: errors.New("quota exceeded"), wantErr: true, wantErrors: 2},
+		{name: "expected failure did not happen", expect: ExpectedOutcome{Outcome: OutcomeFailure}, wantErr: true, wantErrors: 1},
+		{name: "expected failure in stage", expect: ExpectedOutcome{Outcome: OutcomeFailure, Stage: StageApply}, err: errors.New("boom")},
+		{name: "expected failure in other stage", expect: ExpectedOutcome{Outcome: OutcomeFailure, Stage: StagePlan}, err: errors.New("boom"), wantErr: true, wantErrors: 2},
+		{name: "invalid pattern from option", expect: ExpectedOutcome{Outcome: OutcomeFailure, Error: "("}, err: errors.New("boom"), wantErr: true, wantErrors: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := NewModule("default", "/tmp/default")
+			module.Metadata = &ExampleMetadata{Expect: tt.expect}
+			if tt.err != nil {
+				module.failed = map[Stage]error{StageApply: tt.err}
+				module.Errors = append(module.Errors, tt.err.Error())
+			}
+
+			err := module.checkExpectedOutcome(t, tt.err)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkExpectedOutcome() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.expect.Outcome == OutcomeFailure && len(module.Errors) != tt.wantErrors {
+				t.Errorf("Errors = %v, want %d entries", module.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestModule_ExpectPlanFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		planErr error
+		wantErr string
+	}{
+		{name: "plan fails as expected", planErr: errors.New(`Invalid value for variable: sku must be one of "Standard", "Premium"`)},
+		{name: "plan passes", wantErr: "example succeeded but was expected to fail"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := newPlannedModule(t, "invalid-sku")
+			module.planHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+				if tt.planErr != nil {
+					return nil, tt.planErr
+				}
+				return []byte(`{"format_version":"1.2"}`), nil
+			}
+			applied := false
+			module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+				applied = true
+				return nil
+			}
+
+			config := NewConfig(WithExpectPlanFailure("invalid-sku", "must be one of"))
+			module.expect(config.ExpectedFailures["invalid-sku"])
+
+			err := module.checkExpectedOutcome(t, module.Apply(context.Background(), t))
+			if applied {
+				t.Error("an example expected to fail at plan should not be applied")
+			}
+			if tt.wantErr == "" {
+				if err != nil || len(module.Errors) != 0 {
+					t.Fatalf("checkExpectedOutcome() error = %v, Errors = %v", err, module.Errors)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("checkExpectedOutcome() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpectFailureFlag(t *testing.T) {
+	config := NewConfig()
+	set := expectFailureFlag(config)
+	for _, value := range []string{"invalid-sku=must be one of", "precondition"} {
+		if err := set(value); err != nil {
+			t.Fatalf("set(%q) error = %v", value, err)
+		}
+	}
+	want := map[string]ExpectedOutcome{
+		"invalid-sku":  {Outcome: OutcomeFailure, Error: "must be one of"},
+		"precondition": {Outcome: OutcomeFailure},
+	}
+	if !reflect.DeepEqual(config.ExpectedFailures, want) {
+		t.Errorf("ExpectedFailures = %+v, want %+v", config.ExpectedFailures, want)
+	}
+	for _, value := range []string{"=pattern", "example=("} {
+		if err := set(value); err == nil {
+			t.Errorf("set(%q) should fail", value)
+		}
+	}
+}
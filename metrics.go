@@ -0,0 +1,119 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func WithMetricsFile(path string) Option {
+	return func(c *Config) { c.MetricsFile = path }
+}
+
+func WithPushgateway(gatewayURL, job string) Option {
+	return func(c *Config) {
+		c.PushgatewayURL = gatewayURL
+		c.MetricsJob = job
+	}
+}
+
+var reportHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+func emitMetrics(ctx context.Context, config *Config, modules []*Module) error {
+	if config.MetricsFile != "" {
+		var buf bytes.Buffer
+		writeMetrics(&buf, modules)
+		buf.WriteString("# EOF\n")
+		if err := os.WriteFile(config.MetricsFile, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write metrics file %s: %w", config.MetricsFile, err)
+		}
+	}
+
+	if config.PushgatewayURL != "" {
+		if err := pushMetrics(ctx, config.PushgatewayURL, config.MetricsJob, modules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pushMetrics(ctx context.Context, gatewayURL, job string, modules []*Module) error {
+	if job == "" {
+		job = "validor"
+	}
+
+	var buf bytes.Buffer
+	writeMetrics(&buf, modules)
+
+	endpoint := fmt.Sprintf("%s/metrics/job/%s", strings.TrimSuffix(gatewayURL, "/"), url.PathEscape(job))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &buf)
+	if err != nil {
+		return fmt.Errorf("failed to create pushgateway request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := reportHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to push metrics: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func writeMetrics(w io.Writer, modules []*Module) {
+	sorted := make([]*Module, len(modules))
+	copy(sorted, modules)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	failed := 0
+	for _, module := range sorted {
+		if len(module.Errors) > 0 {
+			failed++
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP validor_modules Number of modules tested in the run.")
+	fmt.Fprintln(w, "# TYPE validor_modules gauge")
+	fmt.Fprintf(w, "validor_modules %d\n", len(sorted))
+
+	fmt.Fprintln(w, "# HELP validor_modules_failed Number of modules that failed in the run.")
+	fmt.Fprintln(w, "# TYPE validor_modules_failed gauge")
+	fmt.Fprintf(w, "validor_modules_failed %d\n", failed)
+
+	fmt.Fprintln(w, "# HELP validor_module_failed Whether the module failed (1) or passed (0).")
+	fmt.Fprintln(w, "# TYPE validor_module_failed gauge")
+	for _, module := range sorted {
+		fmt.Fprintf(w, "validor_module_failed{module=\"%s\"} %s\n", escapeLabel(module.Name), BoolToStr(len(module.Errors) > 0, "1", "0"))
+	}
+
+	fmt.Fprintln(w, "# HELP validor_module_retries Number of retries performed for the module.")
+	fmt.Fprintln(w, "# TYPE validor_module_retries gauge")
+	for _, module := range sorted {
+		fmt.Fprintf(w, "validor_module_retries{module=\"%s\"} %d\n", escapeLabel(module.Name), module.Retries)
+	}
+
+	fmt.Fprintln(w, "# HELP validor_module_stage_duration_seconds Time spent in each module stage.")
+	fmt.Fprintln(w, "# TYPE validor_module_stage_duration_seconds gauge")
+	for _, module := range sorted {
+		for _, stage := range module.stages() {
+			if d, ok := module.Durations[stage]; ok {
+				fmt.Fprintf(w, "validor_module_stage_duration_seconds{module=\"%s\",stage=\"%s\"} %g\n", escapeLabel(module.Name), stage, d.Seconds())
+			}
+		}
+	}
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
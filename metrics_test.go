@@ -0,0 +1,114 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	ok := NewModule("ok", "/path/ok")
+	ok.Durations[StageApply] = 90 * time.Second
+	broken := NewModule("broken", "/path/broken")
+	broken.Errors = append(broken.Errors, "apply failed")
+	broken.Retries = 2
+
+	var buf bytes.Buffer
+	writeMetrics(&buf, []*Module{ok, broken})
+	output := buf.String()
+
+	for _, want := range []string{
+		"validor_modules 2",
+		"validor_modules_failed 1",
+		`validor_module_failed{module="broken"} 1`,
+		`validor_module_failed{module="ok"} 0`,
+		`validor_module_retries{module="broken"} 2`,
+		`validor_module_stage_duration_seconds{module="ok",stage="apply"} 90`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel(`a"b\c`); got != `a\"b\\c` {
+		t.Errorf("escapeLabel() = %v, want %v", got, `a\"b\\c`)
+	}
+}
+
+func TestEmitMetrics_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.txt")
+	config := NewConfig(WithMetricsFile(path))
+
+	if err := emitMetrics(context.Background(), config, []*Module{NewModule("ok", "/path/ok")}); err != nil {
+		t.Fatalf("emitMetrics() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read metrics file: %v", err)
+	}
+	if !strings.HasSuffix(string(content), "# EOF\n") {
+		t.Errorf("expected OpenMetrics file to end with # EOF, got %q", string(content))
+	}
+}
+
+func TestEmitMetrics_Pushgateway(t *testing.T) {
+	origClient := reportHTTPClient
+	defer func() { reportHTTPClient = origClient }()
+
+	var gotMethod, gotURL, gotBody string
+	reportHTTPClient = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			gotMethod, gotURL, gotBody = req.Method, req.URL.String(), string(body)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	config := NewConfig(WithPushgateway("http://gateway:9091/", "module-tests"))
+	if err := emitMetrics(context.Background(), config, []*Module{NewModule("ok", "/path/ok")}); err != nil {
+		t.Fatalf("emitMetrics() error = %v", err)
+	}
+
+	if gotMethod != http.MethodPut {
+		t.Errorf("method = %v, want PUT", gotMethod)
+	}
+	if gotURL != "http://gateway:9091/metrics/job/module-tests" {
+		t.Errorf("url = %v", gotURL)
+	}
+	if !strings.Contains(gotBody, "validor_modules 1") {
+		t.Errorf("expected pushed body to contain metrics, got %q", gotBody)
+	}
+}
+
+func TestEmitMetrics_PushgatewayError(t *testing.T) {
+	origClient := reportHTTPClient
+	defer func() { reportHTTPClient = origClient }()
+
+	reportHTTPClient = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	config := NewConfig(WithPushgateway("http://gateway:9091", ""))
+	if err := emitMetrics(context.Background(), config, nil); err == nil {
+		t.Fatal("expected error for non-2xx pushgateway response")
+	}
+}
@@ -1,26 +1,109 @@
 package validor
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
 )
 
 type Module struct {
-	Name        string
-	Path        string
-	Options     *terraform.Options
-	Errors      []string
+	Name    string
+	Path    string
+	Options *terraform.Options
+	Errors  []string
+	// Failures are the errors in Errors with the stage they occurred in and
+	// their classification.
+	Failures    []*ModuleError
 	ApplyFailed bool
-
-	applyHook   func(ctx context.Context, t *testing.T, m *Module) error
-	destroyHook func(ctx context.Context, t *testing.T, m *Module) error
-	cleanupHook func(ctx context.Context, t *testing.T, m *Module) error
+	LogPath     string
+	Durations   map[Stage]time.Duration
+	Retries     int
+	Flaky       bool
+	MonthlyCost *float64
+	Currency    string
+	Findings    []Finding
+	Tags        []string
+	Metadata    *ExampleMetadata
+	Remote      *RemoteBackend
+	RemoteRuns  []string
+	// UpgradeFrom is the release the example is applied from before it is
+	// upgraded to the local source, when testing upgrades from past releases.
+	UpgradeFrom string
+	// Outputs are the values of the example's outputs after apply, with
+	// sensitive values redacted unless WithSensitiveOutputs is set.
+	Outputs map[string]any
+	// Leftovers are the resources still in state after a destroy that
+	// reported success.
+	Leftovers []string
+	// Diagnostics are the warnings and errors terraform validate reported
+	// after init.
+	Diagnostics []Diagnostic
+	// BenchmarkRuns holds the stage durations of every benchmark iteration.
+	BenchmarkRuns []map[Stage]time.Duration
+
+	example        string
+	info           *ModuleInfo
+	initLock       sync.Locker
+	runLock        sync.Locker
+	restores       []func()
+	stage          Stage
+	failed         map[Stage]error
+	observer       Observer
+	observerCtx    context.Context
+	openStage      Stage
+	openSince      time.Time
+	logFile        *os.File
+	stream         bool
+	cancelGrace    time.Duration
+	hangTimeout    time.Duration
+	hangRetry      bool
+	alwaysInit     bool
+	diagnostics    bool
+	failOnWarnings bool
+	cleanup        []string
+	keepLock       bool
+	lockPlatforms  []string
+	fixLock        bool
+	committedLock  []byte
+	extraArgs      bool
+	skipCleanup    bool
+	destroyRetries int
+	destroyBackoff time.Duration
+	destroyTimeout time.Duration
+	preDestroy     []PreDestroyHook
+	azurePurge     bool
+	revealOutputs  bool
+	terragrunt     TerragruntLayout
+	wrappedBinary  string
+	masker         *Masker
+	preserveDir    string
+	initHash       string
+	onStage        func(m *Module, stage Stage)
+	planJSON       []byte
+	planChecks     []planCheck
+	staged         bool
+	planHook       func(ctx context.Context, t testing.TB, m *Module) ([]byte, error)
+	applyHook      func(ctx context.Context, t testing.TB, m *Module) error
+	destroyHook    func(ctx context.Context, t testing.TB, m *Module) error
+	cleanupHook    func(ctx context.Context, t testing.TB, m *Module) error
+	stateHook      func(ctx context.Context, t testing.TB, m *Module) ([]byte, error)
+	importHook     func(ctx context.Context, t testing.TB, m *Module, address, id string) error
+	refreshHook    func(ctx context.Context, t testing.TB, m *Module) ([]byte, error)
+	consoleHook    func(ctx context.Context, t testing.TB, m *Module, expressions []string) ([]string, error)
+	outputsHook    func(ctx context.Context, t testing.TB, m *Module) ([]byte, error)
+	validateHook   func(ctx context.Context, t testing.TB, m *Module) ([]byte, error)
+	lockHook       func(ctx context.Context, t testing.TB, m *Module) error
 }
 
 type testLogger interface {
@@ -33,6 +116,8 @@ type testLogger interface {
 type ModuleManager struct {
 	BaseExamplesPath string
 	Config           *Config
+
+	namePrefix string
 }
 
 func NewModuleManager(baseExamplesPath string) *ModuleManager {
@@ -56,6 +141,8 @@ func NewModule(name, path string) *Module {
 		},
 		Errors:      []string{},
 		ApplyFailed: false,
+		Durations:   make(map[Stage]time.Duration),
+		terragrunt:  DetectTerragrunt(path),
 	}
 }
 
@@ -74,51 +161,191 @@ func (mm *ModuleManager) DiscoverModules() ([]*Module, error) {
 				fmt.Printf("Skipping module %s as it is in the exception list\n", moduleName)
 				continue
 			}
-			modulePath := filepath.Join(mm.BaseExamplesPath, moduleName)
-			modules = append(modules, NewModule(moduleName, modulePath))
+			fullName := mm.namePrefix + moduleName
+			if mm.Config != nil {
+				selected, err := mm.Config.selectsModule(fullName)
+				if err != nil {
+					return nil, err
+				}
+				if !selected {
+					continue
+				}
+			}
+			module := NewModule(fullName, filepath.Join(mm.BaseExamplesPath, moduleName))
+			if err := mm.LoadMetadata(module); err != nil {
+				return nil, err
+			}
+			modules = append(modules, module)
 		}
 	}
 
 	return modules, nil
 }
 
-func (m *Module) Apply(ctx context.Context, t *testing.T) error {
+// Apply inits, validates, plans and applies the module. When the runner's
+// pipeline runs init, validate and plan as stages of their own, it only
+// applies.
+func (m *Module) Apply(ctx context.Context, t testing.TB) error {
 	t.Helper()
 
+	stages := []func(ctx context.Context, t testing.TB) error{m.initStage, m.validateStage, m.planStage, m.applyStage}
+	if m.staged {
+		stages = stages[len(stages)-1:]
+	}
+	for _, stage := range stages {
+		if err := stage(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Module) initStage(ctx context.Context, t testing.TB) error {
+	t.Helper()
 	if m.applyHook != nil {
-		return m.applyHook(ctx, t, m)
+		return nil
 	}
 
-	t.Logf("Applying Terraform module: %s", m.Name)
 	terraform.WithDefaultRetryableErrors(t, m.Options)
+	done := m.startStage(StageInit)
+	err := m.init(t)
+	done()
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform init", Err: err})
+	}
+	return nil
+}
+
+// validateStage checks the diagnostics of terraform validate and the lock
+// file, when they are enabled. Examples that are expected to fail at plan
+// skip it.
+func (m *Module) validateStage(ctx context.Context, t testing.TB) error {
+	t.Helper()
+	diagnostics := m.diagnostics && (m.applyHook == nil || m.validateHook != nil)
+	lockFile := m.committedLock != nil && (m.applyHook == nil || m.lockHook != nil)
+	if m.expectsFailureAt(StagePlan) || !diagnostics && !lockFile {
+		return nil
+	}
 
-	_, err := terraform.InitAndApplyE(t, m.Options)
+	defer m.startStage(StageValidate)()
+	if diagnostics {
+		if err := m.checkDiagnostics(ctx, t); err != nil {
+			return err
+		}
+	}
+	if lockFile {
+		return m.checkLockFile(ctx, t)
+	}
+	return nil
+}
+
+// planStage runs the plan checks, or only plans examples that are expected
+// to fail at plan.
+func (m *Module) planStage(ctx context.Context, t testing.TB) error {
+	t.Helper()
+	if m.expectsFailureAt(StagePlan) {
+		return m.planOnly(ctx, t)
+	}
+	return m.runPlanChecks(ctx, t)
+}
+
+func (m *Module) applyStage(ctx context.Context, t testing.TB) error {
+	t.Helper()
+	if m.expectsFailureAt(StagePlan) {
+		return nil
+	}
+
+	if m.applyHook != nil {
+		done := m.startStage(StageApply)
+		err := m.applyHook(ctx, t, m)
+		done()
+		if err == nil && m.outputsHook != nil {
+			m.captureOutputs(ctx, t)
+		}
+		return err
+	}
+
+	t.Logf("Applying Terraform module: %s", m.Name)
+	done := m.startStage(StageApply)
+	err := m.terraformApply(ctx, t)
+	done()
 	if err != nil {
-		m.ApplyFailed = true
-		wrappedErr := &ModuleError{ModuleName: m.Name, Operation: "terraform apply", Err: err}
-		m.Errors = append(m.Errors, wrappedErr.Error())
-		t.Log(redError(wrappedErr.Error()))
-		return wrappedErr
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform apply", Err: err})
 	}
+	m.captureOutputs(ctx, t)
 	return nil
 }
 
-func (m *Module) Destroy(ctx context.Context, t *testing.T) error {
+// planOnly plans an example that is expected to fail at plan, instead of
+// applying it.
+func (m *Module) planOnly(ctx context.Context, t testing.TB) error {
+	t.Helper()
+	defer m.startStage(StagePlan)()
+	if _, err := m.Plan(ctx, t); err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform plan", Err: err})
+	}
+	return nil
+}
+
+// exampleName is the example a module was created from, which differs from
+// Name for matrix variants.
+func (m *Module) exampleName() string {
+	if m.example != "" {
+		return m.example
+	}
+	return m.Name
+}
+
+func (m *Module) init(t testing.TB) error {
+	if !m.alwaysInit && m.initHash != "" {
+		if current, err := m.initInputs(); err == nil && current == m.initHash {
+			t.Logf("Skipping terraform init for module %s: nothing changed since the last init", m.Name)
+			return nil
+		}
+	}
+
+	if m.initLock != nil {
+		m.initLock.Lock()
+		defer m.initLock.Unlock()
+	}
+	m.initHash = ""
+	var err error
+	if m.terragrunt == TerragruntStack {
+		err = m.initTerragruntStack(t)
+	} else {
+		_, err = terraform.InitE(t, m.Options)
+	}
+	if err == nil && !m.alwaysInit {
+		m.initHash, _ = m.initInputs()
+	}
+	return err
+}
+
+func (m *Module) Destroy(ctx context.Context, t testing.TB) error {
 	t.Helper()
 
 	if m.destroyHook != nil {
-		destroyErr := m.destroyHook(ctx, t, m)
+		done := m.startStage(StageDestroy)
+		m.preDestroyHooks(ctx, t)
+		purge := m.prepareAzurePurge(ctx, t)
+		destroyErr := m.retryDestroy(ctx, t, func(ctx context.Context, retry bool) error { return m.destroyHook(ctx, t, m) })
+		if destroyErr == nil && m.stateHook != nil {
+			destroyErr = m.verifyDestroyed(ctx, t)
+		}
+		if destroyErr == nil {
+			purge()
+		}
+		done()
 		if destroyErr != nil && !m.ApplyFailed {
-			wrappedErr := &ModuleError{ModuleName: m.Name, Operation: "terraform destroy", Err: destroyErr}
-			m.Errors = append(m.Errors, wrappedErr.Error())
-			t.Log(redError(wrappedErr.Error()))
+			m.recordError(t, StageDestroy, &ModuleError{ModuleName: m.Name, Operation: "terraform destroy", Err: destroyErr})
 		}
 
-		if m.cleanupHook != nil && !m.ApplyFailed {
-			if err := m.cleanupHook(ctx, t, m); err != nil {
-				wrappedErr := &ModuleError{ModuleName: m.Name, Operation: "cleanup", Err: err}
-				m.Errors = append(m.Errors, wrappedErr.Error())
-				t.Log(redError(wrappedErr.Error()))
+		if m.cleanupHook != nil && !m.ApplyFailed && !m.skipCleanup && len(m.Leftovers) == 0 {
+			done = m.startStage(StageCleanup)
+			err := m.cleanupHook(ctx, t, m)
+			done()
+			if err != nil {
+				m.recordError(t, StageCleanup, &ModuleError{ModuleName: m.Name, Operation: "cleanup", Err: err})
 			}
 		}
 		return destroyErr
@@ -126,32 +353,55 @@ func (m *Module) Destroy(ctx context.Context, t *testing.T) error {
 
 	t.Logf("Destroying Terraform module: %s", m.Name)
 
-	_, destroyErr := terraform.DestroyE(t, m.Options)
+	done := m.startStage(StageDestroy)
+	m.preDestroyHooks(ctx, t)
+	purge := m.prepareAzurePurge(ctx, t)
+	destroyErr := m.destroyInfrastructure(ctx, t)
+	if destroyErr == nil {
+		destroyErr = m.verifyDestroyed(ctx, t)
+	}
+	if destroyErr == nil {
+		purge()
+	}
+	done()
 
 	if destroyErr != nil && !m.ApplyFailed {
-		wrappedErr := &ModuleError{ModuleName: m.Name, Operation: "terraform destroy", Err: destroyErr}
-		m.Errors = append(m.Errors, wrappedErr.Error())
-		t.Log(redError(wrappedErr.Error()))
+		m.recordError(t, StageDestroy, &ModuleError{ModuleName: m.Name, Operation: "terraform destroy", Err: destroyErr})
 	}
 
-	if err := m.Cleanup(ctx, t); err != nil && !m.ApplyFailed {
-		wrappedErr := &ModuleError{ModuleName: m.Name, Operation: "cleanup", Err: err}
-		m.Errors = append(m.Errors, wrappedErr.Error())
-		t.Log(redError(wrappedErr.Error()))
+	if m.skipCleanup {
+		t.Logf("Skipping cleanup in: %s", m.Options.TerraformDir)
+	} else if len(m.Leftovers) > 0 {
+		t.Logf("Keeping the state in %s to destroy the remaining resources", m.Options.TerraformDir)
+	} else if err := m.Cleanup(ctx, t); err != nil && !m.ApplyFailed {
+		m.recordError(t, StageCleanup, &ModuleError{ModuleName: m.Name, Operation: "cleanup", Err: err})
 	}
 
 	return destroyErr
 }
 
-func (m *Module) Cleanup(ctx context.Context, t *testing.T) error {
+func (m *Module) Cleanup(ctx context.Context, t testing.TB) error {
 	t.Helper()
+	defer m.startStage(StageCleanup)()
 
+	m.preserveArtifacts(t)
 	if m.cleanupHook != nil {
 		return m.cleanupHook(ctx, t, m)
 	}
 
 	t.Logf("Cleaning up in: %s", m.Options.TerraformDir)
-	filesToCleanup := []string{"*.terraform*", "*tfstate*", "*.lock.hcl"}
+	filesToCleanup := append([]string{"*.terraform*", "*tfstate*", "*.lock.hcl"}, m.cleanup...)
+
+	// Names are matched rather than globbing the full path, so characters in
+	// the directory that are special to Glob, like brackets or Windows
+	// separators, are taken literally.
+	entries, err := os.ReadDir(m.Options.TerraformDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", m.Options.TerraformDir, err)
+	}
 
 	for _, pattern := range filesToCleanup {
 		select {
@@ -160,19 +410,135 @@ func (m *Module) Cleanup(ctx context.Context, t *testing.T) error {
 		default:
 		}
 
-		matches, err := filepath.Glob(filepath.Join(m.Options.TerraformDir, pattern))
-		if err != nil {
-			return fmt.Errorf("error matching pattern %s: %w", pattern, err)
-		}
-		for _, filePath := range matches {
-			if err := os.RemoveAll(filePath); err != nil {
+		for _, entry := range entries {
+			matched, err := filepath.Match(pattern, entry.Name())
+			if err != nil {
+				return fmt.Errorf("error matching pattern %s: %w", pattern, err)
+			}
+			if !matched || m.keepLock && entry.Name() == ".terraform.lock.hcl" {
+				continue
+			}
+			filePath := filepath.Join(m.Options.TerraformDir, entry.Name())
+			if err := removeAll(ctx, filePath); err != nil {
 				return fmt.Errorf("failed to remove %s: %w", filePath, err)
 			}
 		}
 	}
+	if m.terragrunt != TerragruntNone {
+		return m.cleanupTerragruntCaches(ctx)
+	}
 	return nil
 }
 
+func (m *Module) setStage(stage Stage) {
+	m.stage = stage
+	m.observeStage(stage)
+	if m.onStage != nil {
+		m.onStage(m, stage)
+	}
+}
+
+func (m *Module) startStage(stage Stage) func() {
+	m.setStage(stage)
+	start := time.Now()
+	return func() {
+		if m.Durations == nil {
+			m.Durations = make(map[Stage]time.Duration)
+		}
+		m.Durations[stage] += time.Since(start)
+	}
+}
+
+// resetAttempt clears what an attempt recorded so a rerun starts fresh.
+func (m *Module) resetAttempt() {
+	m.endObservedStage()
+	m.Errors = []string{}
+	m.Failures = nil
+	m.ApplyFailed = false
+	m.failed = nil
+	m.Durations = make(map[Stage]time.Duration)
+	m.Findings = nil
+	m.MonthlyCost = nil
+	m.planJSON = nil
+	m.planChecks = nil
+	m.Leftovers = nil
+	m.Outputs = nil
+	m.Diagnostics = nil
+}
+
+func (m *Module) TotalDuration() time.Duration {
+	var total time.Duration
+	for _, d := range m.Durations {
+		total += d
+	}
+	return total
+}
+
+// stages returns the stages the module ran or failed in, in the order of
+// orderStages.
+func (m *Module) stages() []Stage {
+	var stages []Stage
+	for stage := range m.Durations {
+		stages = append(stages, stage)
+	}
+	for stage := range m.failed {
+		if !slices.Contains(stages, stage) {
+			stages = append(stages, stage)
+		}
+	}
+	return orderStages(stages)
+}
+
+// orderStages sorts stages in place, built-in stages first in pipeline order
+// followed by custom stages by name, and returns them.
+func orderStages(stages []Stage) []Stage {
+	slices.SortFunc(stages, func(a, b Stage) int {
+		i, j := slices.Index(stageOrder, a), slices.Index(stageOrder, b)
+		switch {
+		case i >= 0 && j >= 0:
+			return cmp.Compare(i, j)
+		case i >= 0:
+			return -1
+		case j >= 0:
+			return 1
+		}
+		return cmp.Compare(a, b)
+	})
+	return stages
+}
+
+func formatStageDurations(m *Module) string {
+	var parts []string
+	for _, stage := range m.stages() {
+		if d, ok := m.Durations[stage]; ok {
+			parts = append(parts, fmt.Sprintf("%s %s", stage, d.Round(time.Second)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printModuleDurations(tb testLogger, modules []*Module) {
+	var timed []*Module
+	for _, module := range modules {
+		if len(module.Durations) > 0 {
+			timed = append(timed, module)
+		}
+	}
+	if len(timed) == 0 {
+		return
+	}
+
+	sort.SliceStable(timed, func(i, j int) bool {
+		return timed[i].TotalDuration() > timed[j].TotalDuration()
+	})
+
+	tb.Log("Module durations (slowest first):")
+	for _, module := range timed {
+		tb.Logf("  %-30s %8s  (%s)", module.Name, module.TotalDuration().Round(time.Second), formatStageDurations(module))
+	}
+	tb.Log("")
+}
+
 func PrintModuleSummary(tb testLogger, modules []*Module) {
 	tb.Helper()
 
@@ -183,19 +549,65 @@ func PrintModuleSummary(tb testLogger, modules []*Module) {
 		}
 	}
 
+	printModuleDurations(tb, modules)
+	printModuleCosts(tb, modules)
+	printDeprecations(tb, modules)
+
+	for _, module := range modules {
+		if module.Flaky {
+			tb.Logf("Module %s passed after %d rerun(s) (flaky)", module.Name, module.Retries)
+		}
+	}
+
 	if len(failedModules) > 0 {
 		for _, module := range failedModules {
-			tb.Log(redError("Module " + module.Name + " failed with errors:"))
+			tb.Log(errorText("Module " + module.Name + " failed with errors:"))
 			for i, errMsg := range module.Errors {
 				errText := fmt.Sprintf("  %d. %s", i+1, errMsg)
-				tb.Log(redError(errText))
+				tb.Log(errorText(errText))
+			}
+			for _, url := range module.RemoteRuns {
+				tb.Logf("  Remote run: %s", url)
+			}
+			if len(module.Leftovers) > 0 {
+				tb.Logf("  Left in state after destroy: %s", strings.Join(module.Leftovers, ", "))
 			}
 			tb.Log("")
 		}
 
 		totalText := fmt.Sprintf("TOTAL: %d of %d modules failed", len(failedModules), len(modules))
-		tb.Log(redError(totalText))
+		tb.Log(errorText(totalText))
 	} else {
-		tb.Logf("\n==== SUCCESS: All %d modules applied and destroyed successfully ====", len(modules))
+		tb.Log(successText(fmt.Sprintf("\n==== SUCCESS: All %d modules applied and destroyed successfully ====", len(modules))))
+	}
+}
+
+// rerunCommand reproduces a failed example on its own and keeps its
+// infrastructure for inspection. Matrix variants rerun the whole example.
+func rerunCommand(module *Module, local bool) string {
+	args := []string{"go", "test", "-v", "-run", "'^TestApplyNoError$'", "-example=" + shellQuote(module.exampleName())}
+	if local {
+		args = append(args, "-local")
+	}
+	return strings.Join(append(args, "-skip-destroy"), " ")
+}
+
+func printRerunCommands(tb testLogger, modules []*Module, local bool) {
+	var commands []string
+	for _, module := range modules {
+		if len(module.Errors) == 0 {
+			continue
+		}
+		if command := rerunCommand(module, local); !slices.Contains(commands, command) {
+			commands = append(commands, command)
+		}
+	}
+	if len(commands) == 0 {
+		return
+	}
+	tb.Log("Reproduce a failed example with:")
+	for _, command := range commands {
+		tb.Logf("  %s", command)
 	}
+	tb.Log("")
 }
@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
 )
@@ -299,13 +303,70 @@ func TestModule_Cleanup(t *testing.T) {
 	}
 }
 
+func TestModule_CleanupPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		keepLock bool
+		removed  []string
+		kept     []string
+	}{
+		{
+			name:    "defaults",
+			removed: []string{".terraform", "terraform.tfstate", ".terraform.lock.hcl"},
+			kept:    []string{"crash.log", "main.tf", "override.tf"},
+		},
+		{
+			name:     "extra patterns",
+			patterns: []string{"crash.log", "*.tfplan", "*override.tf"},
+			removed:  []string{".terraform", "crash.log", "validor.tfplan", "override.tf"},
+			kept:     []string{"main.tf"},
+		},
+		{
+			name:     "preserve lock file",
+			keepLock: true,
+			removed:  []string{".terraform", "terraform.tfstate"},
+			kept:     []string{".terraform.lock.hcl", "main.tf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for _, file := range append(slices.Clone(tt.removed), tt.kept...) {
+				if err := os.WriteFile(filepath.Join(tmpDir, file), []byte("test"), 0o644); err != nil {
+					t.Fatalf("Failed to create test file: %v", err)
+				}
+			}
+
+			module := NewModule("test", tmpDir)
+			module.cleanup = tt.patterns
+			module.keepLock = tt.keepLock
+			if err := module.Cleanup(testContext(t), t); err != nil {
+				t.Fatalf("Cleanup() error = %v", err)
+			}
+
+			for _, file := range tt.removed {
+				if _, err := os.Stat(filepath.Join(tmpDir, file)); !os.IsNotExist(err) {
+					t.Errorf("File %s should have been removed", file)
+				}
+			}
+			for _, file := range tt.kept {
+				if _, err := os.Stat(filepath.Join(tmpDir, file)); err != nil {
+					t.Errorf("File %s should have been kept: %v", file, err)
+				}
+			}
+		})
+	}
+}
+
 func TestModule_DestroyErrors(t *testing.T) {
 	module := NewModule("test", t.TempDir())
 
-	module.destroyHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		return fmt.Errorf("destroy failed")
 	}
-	module.cleanupHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	module.cleanupHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		return fmt.Errorf("cleanup failed")
 	}
 
@@ -321,3 +382,93 @@ func TestModule_DestroyErrors(t *testing.T) {
 		t.Fatalf("expected error messages to be populated, got %#v", module.Errors)
 	}
 }
+
+func TestModule_RecordsStageDurations(t *testing.T) {
+	module := NewModule("test", t.TempDir())
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+	module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+		return nil
+	}
+	module.cleanupHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+		return nil
+	}
+
+	if err := module.Apply(context.Background(), t); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if err := module.Destroy(context.Background(), t); err != nil {
+		t.Fatalf("Destroy() error = %v", err)
+	}
+
+	for _, stage := range []Stage{StageApply, StageDestroy, StageCleanup} {
+		if _, ok := module.Durations[stage]; !ok {
+			t.Errorf("expected duration recorded for stage %s", stage)
+		}
+	}
+	if module.Durations[StageApply] < 5*time.Millisecond {
+		t.Errorf("apply duration = %v, want at least 5ms", module.Durations[StageApply])
+	}
+	if module.TotalDuration() < module.Durations[StageApply] {
+		t.Errorf("TotalDuration() = %v, should include apply duration", module.TotalDuration())
+	}
+}
+
+func TestPrintModuleSummary_SortsDurationsSlowestFirst(t *testing.T) {
+	fast := NewModule("fast", "/path/fast")
+	fast.Durations[StageApply] = time.Minute
+	slow := NewModule("slow", "/path/slow")
+	slow.Durations[StageInit] = time.Minute
+	slow.Durations[StageApply] = 10 * time.Minute
+
+	mock := &mockTB{}
+	PrintModuleSummary(mock, []*Module{fast, slow})
+
+	joined := strings.Join(mock.logs, "\n")
+	slowIdx := strings.Index(joined, "slow")
+	fastIdx := strings.Index(joined, "fast")
+	if slowIdx == -1 || fastIdx == -1 || slowIdx > fastIdx {
+		t.Fatalf("expected slow module listed before fast module, got %q", joined)
+	}
+	if !strings.Contains(joined, "init 1m0s, apply 10m0s") {
+		t.Errorf("expected per-stage durations in summary, got %q", joined)
+	}
+}
+
+func TestPrintRerunCommands(t *testing.T) {
+	failed := func(name, example string) *Module {
+		module := NewModule(name, "/path/"+name)
+		module.example = example
+		module.Errors = []string{"apply failed"}
+		return module
+	}
+	modules := []*Module{
+		NewModule("passing", "/path/passing"),
+		failed("default", ""),
+		failed("matrix-westeurope", "matrix"),
+		failed("matrix-northeurope", "matrix"),
+		failed("it's", ""),
+	}
+
+	recorder := &recordingTB{TB: t}
+	printRerunCommands(recorder, modules, true)
+
+	want := []string{
+		"Reproduce a failed example with:",
+		"  go test -v -run '^TestApplyNoError$' -example=default -local -skip-destroy",
+		"  go test -v -run '^TestApplyNoError$' -example=matrix -local -skip-destroy",
+		`  go test -v -run '^TestApplyNoError$' -example='it'\''s' -local -skip-destroy`,
+		"",
+	}
+	if !reflect.DeepEqual(recorder.logs, want) {
+		t.Errorf("logs = %q, want %q", recorder.logs, want)
+	}
+
+	recorder = &recordingTB{TB: t}
+	printRerunCommands(recorder, modules[:1], false)
+	if len(recorder.logs) != 0 {
+		t.Errorf("no commands expected without failures, got %q", recorder.logs)
+	}
+}
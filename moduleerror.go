@@ -0,0 +1,119 @@
+package validor
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+)
+
+// ErrorCode classifies why a module failed, so reports and notifications can
+// tell a missing permission or an exhausted quota from a broken example.
+type ErrorCode string
+
+const (
+	// ErrorAuth is a failure to authenticate or a missing permission.
+	ErrorAuth ErrorCode = "AUTH"
+	// ErrorQuota is an exhausted quota, limit or capacity of the account.
+	ErrorQuota ErrorCode = "QUOTA"
+	// ErrorTimeout is an operation that did not finish in time.
+	ErrorTimeout ErrorCode = "TIMEOUT"
+	// ErrorSyntax is an invalid configuration that terraform rejected.
+	ErrorSyntax ErrorCode = "SYNTAX"
+	// ErrorTransient is a network, throttling or eventual consistency error
+	// that is likely to pass on a rerun.
+	ErrorTransient ErrorCode = "TRANSIENT"
+	// ErrorUnknown is any other failure.
+	ErrorUnknown ErrorCode = "UNKNOWN"
+)
+
+// errorCodeRules classify terraform output, in order, by the messages of
+// terraform and the azurerm, aws and google providers.
+var errorCodeRules = []struct {
+	code    ErrorCode
+	pattern *regexp.Regexp
+}{
+	{ErrorTransient, regexp.MustCompile(`(?i)TLS handshake timeout|i/o timeout|connection reset by peer|connection refused|no such host|transport is closing|unexpected EOF|TooManyRequests|Throttl|RequestLimitExceeded|Rate exceeded|rateLimitExceeded|ServiceUnavailable|Service Unavailable|InternalServerError|Internal Server Error|status code:? (429|500|502|503|504)|StatusCode=(429|500|502|503|504)|RetryableError|AnotherOperationInProgress|Provider produced inconsistent result after apply`)},
+	{ErrorTimeout, regexp.MustCompile(`(?i)context deadline exceeded|timeout while waiting for state|timed out|exceeded timeout|deadline exceeded|polling after .* context`)},
+	{ErrorQuota, regexp.MustCompile(`(?i)QuotaExceeded|quota exceeded|exceed\w* .*quota|Quota .* exceeded|LimitExceeded|SkuNotAvailable|InsufficientInstanceCapacity|RESOURCE_EXHAUSTED|VcpuLimitExceeded|MaxNumberOf\w+Exceeded`)},
+	{ErrorAuth, regexp.MustCompile(`AuthorizationFailed|AuthenticationFailed|AuthorizationPermissionMismatch|does not have authorization|InvalidAuthenticationToken|InvalidClientTokenId|ExpiredToken|UnrecognizedClientException|UnauthorizedOperation|AccessDenied|Access Denied|PERMISSION_DENIED|status code:? (401|403)|StatusCode=(401|403)|\b401 Unauthorized|\b403 Forbidden|Error building ARM Config|could not find default credentials|no valid credential sources|invalid_grant|AADSTS\d+|unable to build authorizer`)},
+	{ErrorSyntax, regexp.MustCompile(`Unsupported argument|Missing required argument|Unsupported block type|Unsupported attribute|Invalid reference|Reference to undeclared|Argument or block definition required|Invalid expression|Incorrect attribute value type|Invalid value for (input )?variable|Invalid function argument|Call to unknown function|Missing required provider|Duplicate \w+ (definition|configuration|block)|Unclosed configuration block|Invalid block definition|Variables not allowed|Unsupported operator|Invalid count argument|Invalid for_each argument|Module not installed`)},
+}
+
+// classifyError returns the code of the first rule that matches the message
+// of err, which includes terraform's error output. Output matching one of
+// retryable, the retryable errors of the module's options, is transient.
+func classifyError(err error, retryable map[string]string) ErrorCode {
+	if err == nil {
+		return ErrorUnknown
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+	message := err.Error()
+	for _, rule := range errorCodeRules {
+		if rule.pattern.MatchString(message) {
+			return rule.code
+		}
+	}
+	if retryableOutput(retryable, message) {
+		return ErrorTransient
+	}
+	return ErrorUnknown
+}
+
+type moduleErrorJSON struct {
+	Module    string    `json:"module"`
+	Stage     Stage     `json:"stage,omitempty"`
+	Operation string    `json:"operation"`
+	Code      ErrorCode `json:"code"`
+	Message   string    `json:"message"`
+}
+
+// MarshalJSON encodes the error with its message as text. Errors that were not
+// recorded for a module are classified when they are encoded.
+func (e *ModuleError) MarshalJSON() ([]byte, error) {
+	var message string
+	if e.Err != nil {
+		message = e.Err.Error()
+	}
+	return json.Marshal(moduleErrorJSON{
+		Module:    e.ModuleName,
+		Stage:     e.Stage,
+		Operation: e.Operation,
+		Code:      cmp.Or(e.Code, classifyError(e.Err, nil)),
+		Message:   message,
+	})
+}
+
+// UnmarshalJSON decodes an error encoded with MarshalJSON. Its Err only holds
+// the message.
+func (e *ModuleError) UnmarshalJSON(data []byte) error {
+	var decoded moduleErrorJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*e = ModuleError{ModuleName: decoded.Module, Stage: decoded.Stage, Operation: decoded.Operation, Code: decoded.Code, Err: errors.New(decoded.Message)}
+	return nil
+}
+
+// recordFailure classifies err and keeps it in Failures, with its message
+// masked.
+func (m *Module) recordFailure(stage Stage, err *ModuleError) {
+	if err.Stage == "" {
+		err.Stage = stage
+	}
+	if err.Code == "" {
+		var retryable map[string]string
+		if m.Options != nil {
+			retryable = m.Options.RetryableTerraformErrors
+		}
+		err.Code = classifyError(err.Err, retryable)
+	}
+	failure := *err
+	if m.masker != nil && err.Err != nil {
+		failure.Err = errors.New(m.masker.Mask(err.Err.Error()))
+	}
+	m.Failures = append(m.Failures, &failure)
+}
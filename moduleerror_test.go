@@ -0,0 +1,106 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable map[string]string
+		want      ErrorCode
+	}{
+		{name: "nil", err: nil, want: ErrorUnknown},
+		{name: "azure authorization", err: errors.New(`Error: creating Resource Group: unexpected status 403 with error: AuthorizationFailed: The client 'x' does not have authorization to perform action`), want: ErrorAuth},
+		{name: "aws invalid token", err: errors.New(`Error: creating EC2 Instance: operation error EC2: RunInstances, api error InvalidClientTokenId: The security token included in the request is invalid`), want: ErrorAuth},
+		{name: "azure provider configuration", err: errors.New(`Error: building AzureRM Client: Error building ARM Config: obtain subscription from Azure CLI`), want: ErrorAuth},
+		{name: "azure regional quota", err: errors.New(`Code="OperationNotAllowed" Message="Operation could not be completed as it results in exceeding approved Total Regional Cores quota"`), want: ErrorQuota},
+		{name: "aws vcpu limit", err: errors.New(`api error VcpuLimitExceeded: You have requested more vCPU capacity than your current vCPU limit`), want: ErrorQuota},
+		{name: "gcp exhausted", err: errors.New(`googleapi: Error 429: RESOURCE_EXHAUSTED`), want: ErrorQuota},
+		{name: "gcp quota", err: errors.New(`Error: Quota 'CPUS' exceeded. Limit: 24.0 in region europe-west1.`), want: ErrorQuota},
+		{name: "terraform timeout", err: errors.New(`Error: waiting for creation of Server: timeout while waiting for state to become 'Ready'`), want: ErrorTimeout},
+		{name: "deadline", err: fmt.Errorf("terraform apply: %w", context.DeadlineExceeded), want: ErrorTimeout},
+		{name: "module timeout", err: errors.New("exceeded timeout of 30m0s"), want: ErrorTimeout},
+		{name: "unsupported argument", err: errors.New(`Error: Unsupported argument on main.tf line 12: An argument named "sku" is not expected here.`), want: ErrorSyntax},
+		{name: "undeclared reference", err: errors.New(`Error: Reference to undeclared input variable`), want: ErrorSyntax},
+		{name: "throttling", err: errors.New(`api error Throttling: Rate exceeded`), want: ErrorTransient},
+		{name: "network", err: errors.New(`Error: Failed to query available provider packages: dial tcp: lookup registry.terraform.io: i/o timeout`), want: ErrorTransient},
+		{name: "eventual consistency", err: errors.New(`Error: Provider produced inconsistent result after apply`), want: ErrorTransient},
+		{name: "retryable pattern", err: errors.New(`Error: 409 Conflict: resource is busy`), retryable: map[string]string{".*resource is busy.*": "busy"}, want: ErrorTransient},
+		{name: "unknown", err: errors.New("output contract violated"), want: ErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err, tt.retryable); got != tt.want {
+				t.Errorf("classifyError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleError_JSON(t *testing.T) {
+	err := &ModuleError{ModuleName: "default", Operation: "terraform apply", Stage: StageApply, Code: ErrorQuota, Err: errors.New("QuotaExceeded")}
+	data, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatalf("Marshal() error = %v", marshalErr)
+	}
+	want := `{"module":"default","stage":"apply","operation":"terraform apply","code":"QUOTA","message":"QuotaExceeded"}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded ModuleError
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if decoded.Error() != err.Error() || decoded.Stage != err.Stage || decoded.Code != err.Code {
+		t.Errorf("Unmarshal() = %+v, want %+v", decoded, *err)
+	}
+
+	unrecorded, _ := json.Marshal(&ModuleError{ModuleName: "default", Operation: "terraform init", Err: errors.New("Error: Unsupported block type")})
+	if !strings.Contains(string(unrecorded), `"code":"SYNTAX"`) {
+		t.Errorf("errors that were not recorded should be classified when encoded, got %s", unrecorded)
+	}
+}
+
+func TestModule_RecordErrorClassifies(t *testing.T) {
+	module := NewModule("default", t.TempDir())
+	module.masker, _ = NewMasker()
+	module.masker.Add("s3cr3t-key")
+	module.stage = StagePlan
+
+	module.failApply(&recordingTB{TB: t}, &ModuleError{ModuleName: module.Name, Operation: "terraform plan", Err: errors.New("AuthenticationFailed for key s3cr3t-key")})
+
+	if len(module.Failures) != 1 {
+		t.Fatalf("Failures = %v, want one failure", module.Failures)
+	}
+	failure := module.Failures[0]
+	if failure.Stage != StagePlan || failure.Code != ErrorAuth {
+		t.Errorf("failure stage, code = %s, %s, want plan, AUTH", failure.Stage, failure.Code)
+	}
+	if strings.Contains(failure.Error(), "s3cr3t-key") {
+		t.Errorf("failures should be masked, got %v", failure)
+	}
+
+	report := newModuleReport(module)
+	data, _ := json.Marshal(report)
+	if !strings.Contains(string(data), `"failures":[{"module":"default","stage":"plan","operation":"terraform plan","code":"AUTH"`) {
+		t.Errorf("module report should include the classified failures, got %s", data)
+	}
+	summary := newNotificationSummary([]*Module{module})
+	if text := summary.text(); !strings.Contains(text, "[AUTH]") {
+		t.Errorf("notification text should include the error code, got %q", text)
+	}
+
+	module.resetAttempt()
+	if module.Failures != nil {
+		t.Errorf("resetAttempt() should clear Failures, got %v", module.Failures)
+	}
+}
@@ -0,0 +1,170 @@
+package validor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// moduleCandidate is a registry module the examples call, which might be the
+// module under test.
+type moduleCandidate struct {
+	info     ModuleInfo
+	examples int
+	// fits is false once a call passes an argument the module at the root
+	// does not declare as a variable.
+	fits bool
+}
+
+// InferModuleInfo determines the namespace, name and provider of the module
+// at moduleRoot from the registry sources its examples call it with, so it
+// does not depend on the name of the repository or its checkout. Examples
+// often call other registry modules too; the module under test is the one
+// whose calls only set variables declared at moduleRoot, preferring one whose
+// provider matches a provider moduleRoot requires and then the one the most
+// examples call.
+func InferModuleInfo(moduleRoot, examplesPath string) (ModuleInfo, error) {
+	rootBodies, err := parseTerraformFiles(moduleRoot)
+	if err != nil {
+		return ModuleInfo{}, err
+	}
+	variables := blockLabels(rootBodies, "variable")
+	providers := requiredProviders(rootBodies)
+
+	entries, err := os.ReadDir(examplesPath)
+	if err != nil {
+		return ModuleInfo{}, fmt.Errorf("failed to read examples directory: %w", err)
+	}
+
+	var candidates []*moduleCandidate
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		bodies, err := parseTerraformFiles(filepath.Join(examplesPath, entry.Name()))
+		if err != nil {
+			return ModuleInfo{}, err
+		}
+
+		seen := make(map[*moduleCandidate]bool)
+		for _, call := range registryModuleCalls(bodies) {
+			info, ok := parseRegistrySource(call.source)
+			if !ok {
+				continue
+			}
+			i := slices.IndexFunc(candidates, func(c *moduleCandidate) bool { return c.info == info })
+			if i < 0 {
+				i = len(candidates)
+				candidates = append(candidates, &moduleCandidate{info: info, fits: true})
+			}
+			candidate := candidates[i]
+			if !seen[candidate] {
+				seen[candidate] = true
+				candidate.examples++
+			}
+			for name := range call.block.Body.Attributes {
+				if !slices.Contains(moduleMetaArguments, name) && !slices.Contains(variables, name) {
+					candidate.fits = false
+				}
+			}
+		}
+	}
+
+	candidates = slices.DeleteFunc(candidates, func(c *moduleCandidate) bool { return !c.fits })
+	if len(candidates) == 0 {
+		return ModuleInfo{}, errors.New("no example calls the module from a registry")
+	}
+	if matching := slices.DeleteFunc(slices.Clone(candidates), func(c *moduleCandidate) bool {
+		return !providerMatches(c.info.Provider, providers)
+	}); len(matching) > 0 {
+		candidates = matching
+	}
+
+	best := slices.MaxFunc(candidates, func(a, b *moduleCandidate) int { return a.examples - b.examples })
+	var tied []string
+	for _, candidate := range candidates {
+		if candidate.examples == best.examples {
+			tied = append(tied, registrySourceOf(candidate.info))
+		}
+	}
+	if len(tied) > 1 {
+		return ModuleInfo{}, fmt.Errorf("examples call several modules that could be under test: %s", strings.Join(tied, ", "))
+	}
+	return best.info, nil
+}
+
+type registryModuleCall struct {
+	block  *hclsyntax.Block
+	source string
+}
+
+func registryModuleCalls(bodies []*hclsyntax.Body) []registryModuleCall {
+	var calls []registryModuleCall
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "module" || len(block.Labels) == 0 {
+				continue
+			}
+			sourceAttr, ok := block.Body.Attributes["source"]
+			if !ok {
+				continue
+			}
+			if source, ok := literalString(sourceAttr.Expr); ok {
+				calls = append(calls, registryModuleCall{block: block, source: source})
+			}
+		}
+	}
+	return calls
+}
+
+// parseRegistrySource parses a registry source of a whole module, such as
+// "cloudnationhq/vnet/azure". Submodules are skipped, as their calls set the
+// variables of the submodule rather than those of the module at the root.
+func parseRegistrySource(source string) (ModuleInfo, bool) {
+	address := registryAddress(source)
+	if strings.Contains(address, "//") {
+		return ModuleInfo{}, false
+	}
+	parts := strings.Split(address, "/")
+	if len(parts) != 3 || slices.Contains(parts, "") || strings.ContainsAny(address, ".:") {
+		return ModuleInfo{}, false
+	}
+	return ModuleInfo{Namespace: parts[0], Name: parts[1], Provider: parts[2]}, true
+}
+
+func registrySourceOf(info ModuleInfo) string {
+	return fmt.Sprintf("%s/%s/%s", info.Namespace, info.Name, info.Provider)
+}
+
+// requiredProviders returns the local names of the providers in the
+// required_providers blocks of a module.
+func requiredProviders(bodies []*hclsyntax.Body) []string {
+	var providers []string
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "terraform" {
+				continue
+			}
+			for _, required := range block.Body.Blocks {
+				if required.Type != "required_providers" {
+					continue
+				}
+				for name := range required.Body.Attributes {
+					providers = append(providers, name)
+				}
+			}
+		}
+	}
+	return providers
+}
+
+// providerMatches reports whether a registry provider name, like azure,
+// belongs to one of the required providers, like azurerm.
+func providerMatches(provider string, providers []string) bool {
+	return slices.ContainsFunc(providers, func(p string) bool { return strings.HasPrefix(p, provider) })
+}
@@ -0,0 +1,144 @@
+package validor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInferModuleInfo(t *testing.T) {
+	const root = `
+terraform {
+  required_providers {
+    azurerm = {
+      source = "hashicorp/azurerm"
+    }
+  }
+}
+
+variable "vnet" {}
+variable "naming" {}
+`
+	const vnet = `
+module "naming" {
+  source = "cloudnationhq/naming/azure"
+  suffix = ["demo"]
+}
+
+module "network" {
+  source  = "cloudnationhq/vnet/azure"
+  version = "~> 9.0"
+  vnet    = {}
+}
+`
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    ModuleInfo
+		wantErr string
+	}{
+		{
+			name:  "module under test sets root variables",
+			files: map[string]string{"main.tf": root, "examples/default/main.tf": vnet, "examples/complete/main.tf": vnet},
+			want:  ModuleInfo{Namespace: "cloudnationhq", Name: "vnet", Provider: "azure"},
+		},
+		{
+			name: "provider breaks a tie",
+			files: map[string]string{
+				"main.tf": root,
+				"examples/default/main.tf": `
+module "rg" {
+  source = "acme/rg/aws"
+}
+
+module "network" {
+  source = "registry.terraform.io/acme/vnet/azure"
+}
+`,
+			},
+			want: ModuleInfo{Namespace: "acme", Name: "vnet", Provider: "azure"},
+		},
+		{
+			name: "submodules and other sources are ignored",
+			files: map[string]string{
+				"main.tf": root,
+				"examples/default/main.tf": `
+module "subnet" {
+  source = "cloudnationhq/vnet/azure//modules/subnet"
+  cidr   = "10.0.0.0/24"
+}
+
+module "local" {
+  source = "../../"
+}
+
+module "git" {
+  source = "github.com/acme/terraform-azure-vnet"
+}
+`,
+			},
+			wantErr: "no example calls the module from a registry",
+		},
+		{
+			name: "ambiguous",
+			files: map[string]string{
+				"main.tf": root,
+				"examples/default/main.tf": `
+module "a" {
+  source = "acme/a/azure"
+}
+
+module "b" {
+  source = "acme/b/azure"
+}
+`,
+			},
+			wantErr: "acme/a/azure, acme/b/azure",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			got, err := InferModuleInfo(dir, filepath.Join(dir, "examples"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("InferModuleInfo() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InferModuleInfo() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("InferModuleInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_ModuleInfoFromExamples(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"variables.tf": `variable "vnet" {}`,
+		"examples/default/main.tf": `
+module "network" {
+  source = "acme/vnet/azure"
+  vnet   = {}
+}
+`,
+	})
+
+	config := &Config{Namespace: "cloudnationhq", ExamplesPath: filepath.Join(dir, "examples")}
+	if got, want := config.moduleInfo(), (ModuleInfo{Namespace: "acme", Name: "vnet", Provider: "azure"}); got != want {
+		t.Errorf("moduleInfo() = %+v, want %+v", got, want)
+	}
+
+	config.ModuleInfo = ModuleInfo{Name: "vnet", Provider: "azure"}
+	if got, want := config.moduleInfo(), (ModuleInfo{Namespace: "cloudnationhq", Name: "vnet", Provider: "azure"}); got != want {
+		t.Errorf("moduleInfo() with module info = %+v, want %+v", got, want)
+	}
+}
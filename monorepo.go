@@ -0,0 +1,105 @@
+package validor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func WithModule(info ModuleInfo) Option {
+	return func(c *Config) { c.Modules = append(c.Modules, info) }
+}
+
+func WithMonorepo(enabled bool) Option {
+	return func(c *Config) { c.Monorepo = enabled }
+}
+
+// DiscoverModuleTargets walks repoRoot for examples directories and returns one
+// target per module that owns them. The root module keeps the name from base,
+// nested modules are named after their directory.
+func DiscoverModuleTargets(repoRoot string, base ModuleInfo) ([]ModuleInfo, error) {
+	var targets []ModuleInfo
+	err := filepath.WalkDir(repoRoot, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != repoRoot && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.Name() != "examples" {
+			return nil
+		}
+
+		moduleRoot := filepath.Dir(path)
+		if files, _ := filepath.Glob(filepath.Join(moduleRoot, "*.tf")); len(files) == 0 {
+			return filepath.SkipDir
+		}
+
+		target := base
+		target.Root = moduleRoot
+		target.ExamplesPath = path
+		if filepath.Clean(moduleRoot) != filepath.Clean(repoRoot) || target.Name == "" {
+			absRoot, err := filepath.Abs(moduleRoot)
+			if err != nil {
+				return err
+			}
+			target.Name = filepath.Base(absRoot)
+		}
+		targets = append(targets, target)
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover modules in %s: %w", repoRoot, err)
+	}
+	return targets, nil
+}
+
+func moduleTargets(config *Config) ([]ModuleInfo, error) {
+	if len(config.Modules) > 0 {
+		targets := make([]ModuleInfo, 0, len(config.Modules))
+		for _, target := range config.Modules {
+			if target.Namespace == "" {
+				target.Namespace = config.Namespace
+			}
+			if target.ExamplesPath == "" && target.Root != "" {
+				target.ExamplesPath = filepath.Join(target.Root, "examples")
+			}
+			if target.Root == "" && target.ExamplesPath != "" {
+				target.Root = filepath.Dir(target.ExamplesPath)
+			}
+			if target.Name == "" || target.ExamplesPath == "" {
+				return nil, fmt.Errorf("module target requires a name and an examples path or root: %+v", target)
+			}
+			targets = append(targets, target)
+		}
+		return targets, nil
+	}
+
+	if !config.Monorepo {
+		return nil, nil
+	}
+	base := config.moduleInfo()
+	return DiscoverModuleTargets(filepath.Dir(getExamplesPath(config)), base)
+}
+
+func discoverTargetModules(targets []ModuleInfo, config *Config) ([]*Module, error) {
+	var modules []*Module
+	for _, target := range targets {
+		manager := NewModuleManager(target.ExamplesPath)
+		manager.SetConfig(config)
+		manager.namePrefix = target.Name + "/"
+		discovered, err := manager.DiscoverModules()
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", target.Name, err)
+		}
+		for _, module := range discovered {
+			module.info = &target
+			modules = append(modules, module)
+		}
+	}
+	return modules, nil
+}
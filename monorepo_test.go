@@ -0,0 +1,157 @@
+package validor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func createMonorepo(t *testing.T, dirs ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range dirs {
+		path := filepath.Join(root, dir)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+		if filepath.Base(filepath.Dir(path)) != "examples" {
+			if err := os.WriteFile(filepath.Join(path, "main.tf"), []byte(""), 0644); err != nil {
+				t.Fatalf("Failed to create terraform file: %v", err)
+			}
+		}
+	}
+	return root
+}
+
+func TestDiscoverModuleTargets(t *testing.T) {
+	root := createMonorepo(t,
+		".",
+		"examples/default",
+		"modules/vnet",
+		"modules/vnet/examples/basic",
+		"modules/storage",
+		"modules/storage/examples/complete",
+		"modules/docs-only",
+		".git/examples/ignored",
+	)
+	if err := os.MkdirAll(filepath.Join(root, "scripts", "examples", "x"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	base := ModuleInfo{Name: "network", Provider: "azure", Namespace: "ns"}
+	targets, err := DiscoverModuleTargets(root, base)
+	if err != nil {
+		t.Fatalf("DiscoverModuleTargets() error = %v", err)
+	}
+
+	want := map[string]string{
+		"network": filepath.Join(root, "examples"),
+		"storage": filepath.Join(root, "modules", "storage", "examples"),
+		"vnet":    filepath.Join(root, "modules", "vnet", "examples"),
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("DiscoverModuleTargets() returned %d targets, want %d: %+v", len(targets), len(want), targets)
+	}
+	for _, target := range targets {
+		if want[target.Name] != target.ExamplesPath {
+			t.Errorf("target %s examples path = %q, want %q", target.Name, target.ExamplesPath, want[target.Name])
+		}
+		if target.Root != filepath.Dir(target.ExamplesPath) {
+			t.Errorf("target %s root = %q", target.Name, target.Root)
+		}
+		if target.Provider != "azure" || target.Namespace != "ns" {
+			t.Errorf("target %s should inherit provider and namespace, got %+v", target.Name, target)
+		}
+	}
+}
+
+func TestModuleTargets(t *testing.T) {
+	t.Run("explicit modules", func(t *testing.T) {
+		config := NewConfig(
+			WithModule(ModuleInfo{Name: "vnet", Provider: "azure", Root: "../modules/vnet"}),
+			WithModule(ModuleInfo{Name: "storage", Provider: "azure", ExamplesPath: "../modules/storage/examples"}),
+		)
+		config.Namespace = "ns"
+
+		targets, err := moduleTargets(config)
+		if err != nil {
+			t.Fatalf("moduleTargets() error = %v", err)
+		}
+		if len(targets) != 2 {
+			t.Fatalf("moduleTargets() returned %d targets, want 2", len(targets))
+		}
+		if targets[0].ExamplesPath != filepath.Join("..", "modules", "vnet", "examples") {
+			t.Errorf("examples path should default to root/examples, got %q", targets[0].ExamplesPath)
+		}
+		if targets[1].Root != filepath.Join("..", "modules", "storage") {
+			t.Errorf("root should default to the examples parent, got %q", targets[1].Root)
+		}
+		for _, target := range targets {
+			if target.Namespace != "ns" {
+				t.Errorf("namespace should default to config namespace, got %q", target.Namespace)
+			}
+		}
+	})
+
+	t.Run("incomplete module", func(t *testing.T) {
+		config := NewConfig(WithModule(ModuleInfo{Name: "vnet"}))
+		if _, err := moduleTargets(config); err == nil {
+			t.Fatal("expected error for module without examples path")
+		}
+	})
+
+	t.Run("single module repo", func(t *testing.T) {
+		targets, err := moduleTargets(NewConfig())
+		if err != nil || targets != nil {
+			t.Fatalf("moduleTargets() = %v, %v; want no targets", targets, err)
+		}
+	})
+
+	t.Run("monorepo discovery", func(t *testing.T) {
+		root := createMonorepo(t, "modules/vnet", "modules/vnet/examples/basic")
+		config := NewConfig(WithMonorepo(true), WithExamplesPath(filepath.Join(root, "examples")))
+
+		targets, err := moduleTargets(config)
+		if err != nil {
+			t.Fatalf("moduleTargets() error = %v", err)
+		}
+		if len(targets) != 1 || targets[0].Name != "vnet" {
+			t.Fatalf("moduleTargets() = %+v, want the vnet module", targets)
+		}
+	})
+}
+
+func TestDiscoverTargetModules(t *testing.T) {
+	root := createMonorepo(t,
+		"modules/vnet",
+		"modules/vnet/examples/default",
+		"modules/storage",
+		"modules/storage/examples/default",
+		"modules/storage/examples/private",
+	)
+	targets, err := DiscoverModuleTargets(root, ModuleInfo{Provider: "azure", Namespace: "ns"})
+	if err != nil {
+		t.Fatalf("DiscoverModuleTargets() error = %v", err)
+	}
+
+	config := NewConfig(WithException("private"))
+	config.ParseExceptionList()
+	modules, err := discoverTargetModules(targets, config)
+	if err != nil {
+		t.Fatalf("discoverTargetModules() error = %v", err)
+	}
+
+	got := make(map[string]*Module)
+	for _, module := range modules {
+		got[module.Name] = module
+	}
+	if len(got) != 2 || got["storage/default"] == nil || got["vnet/default"] == nil {
+		t.Fatalf("unexpected modules: %v", extractModuleNames(modules))
+	}
+	if info := got["vnet/default"].info; info == nil || info.Name != "vnet" {
+		t.Errorf("module should carry its target info, got %+v", info)
+	}
+	if got["storage/default"].Path != filepath.Join(root, "modules", "storage", "examples", "default") {
+		t.Errorf("unexpected module path %q", got["storage/default"].Path)
+	}
+}
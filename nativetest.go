@@ -0,0 +1,273 @@
+package validor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+const nativeTestsName = "terraform-test"
+
+// WithNativeTests runs terraform test for every module that has
+// tests/*.tftest.hcl files, alongside its examples. Its results are listed
+// in the summary and reports as a runner named terraform-test.
+func WithNativeTests(enabled bool) Option {
+	return func(c *Config) { c.NativeTests = enabled }
+}
+
+// HasNativeTests reports whether the module at root has terraform test
+// files in its tests directory.
+func HasNativeTests(root string) bool {
+	matches, _ := filepath.Glob(filepath.Join(root, "tests", "*.tftest.hcl"))
+	return len(matches) > 0
+}
+
+// NativeTestResult is the outcome of a single run block of a terraform test
+// file.
+type NativeTestResult struct {
+	File        string
+	Run         string
+	Status      string
+	Diagnostics []Diagnostic
+}
+
+func (r NativeTestResult) String() string {
+	s := r.File
+	if r.Run != "" {
+		s += "/" + r.Run
+	}
+	var details []string
+	for _, diagnostic := range r.Diagnostics {
+		if diagnostic.Severity == "error" {
+			details = append(details, diagnostic.String())
+		}
+	}
+	if len(details) > 0 {
+		s += ": " + strings.Join(details, "; ")
+	}
+	return s
+}
+
+// Failed reports whether the run failed an assertion or errored.
+func (r NativeTestResult) Failed() bool {
+	return r.Status == "fail" || r.Status == "error"
+}
+
+// ParseNativeTests parses the output of terraform test -json into the
+// results of its run blocks. Errors terraform reports for a test file as a
+// whole are recorded as a run without a name.
+func ParseNativeTests(testJSON []byte) ([]NativeTestResult, error) {
+	var results []NativeTestResult
+	index := make(map[string]int)
+	result := func(file, run string) *NativeTestResult {
+		key := file + "\x00" + run
+		i, ok := index[key]
+		if !ok {
+			i = len(results)
+			index[key] = i
+			results = append(results, NativeTestResult{File: file, Run: run})
+		}
+		return &results[i]
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(testJSON))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var message struct {
+			Type     string `json:"type"`
+			TestFile string `json:"@testfile"`
+			TestRun  string `json:"@testrun"`
+			Run      *struct {
+				Path     string `json:"path"`
+				Run      string `json:"run"`
+				Progress string `json:"progress"`
+				Status   string `json:"status"`
+			} `json:"test_run"`
+			Diagnostic *struct {
+				Severity string `json:"severity"`
+				Summary  string `json:"summary"`
+				Detail   string `json:"detail"`
+				Range    *struct {
+					Filename string `json:"filename"`
+					Start    struct {
+						Line int `json:"line"`
+					} `json:"start"`
+				} `json:"range"`
+			} `json:"diagnostic"`
+		}
+		if err := json.Unmarshal(line, &message); err != nil {
+			return nil, fmt.Errorf("failed to parse test json: %w", err)
+		}
+
+		switch {
+		case message.Type == "test_run" && message.Run != nil:
+			if message.Run.Progress == "complete" {
+				result(message.Run.Path, message.Run.Run).Status = message.Run.Status
+			}
+		case message.Type == "diagnostic" && message.Diagnostic != nil && message.TestFile != "":
+			d := message.Diagnostic
+			diagnostic := Diagnostic{Severity: d.Severity, Summary: d.Summary, Detail: d.Detail}
+			if d.Range != nil {
+				diagnostic.Location = fmt.Sprintf("%s:%d", d.Range.Filename, d.Range.Start.Line)
+			}
+			r := result(message.TestFile, message.TestRun)
+			r.Diagnostics = append(r.Diagnostics, diagnostic)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read test json: %w", err)
+	}
+
+	// Errors without a completed run, such as a test file terraform cannot
+	// load, mean the run or file errored.
+	for i := range results {
+		if results[i].Status == "" && hasErrors(results[i].Diagnostics) {
+			results[i].Status = "error"
+		}
+	}
+	return results, nil
+}
+
+func hasErrors(diagnostics []Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+// NativeTestRunner runs terraform test in the root of a module. Apply
+// initializes the module and runs its test files; terraform test destroys
+// what it creates itself, so Destroy and Cleanup do nothing.
+type NativeTestRunner struct {
+	name    string
+	Options *terraform.Options
+	Results []NativeTestResult
+}
+
+// NewNativeTestRunner returns a runner named name for the terraform tests of
+// the module at root.
+func NewNativeTestRunner(name, root string) *NativeTestRunner {
+	return &NativeTestRunner{
+		name: name,
+		Options: &terraform.Options{
+			TerraformDir:    root,
+			NoColor:         true,
+			TerraformBinary: "terraform",
+		},
+	}
+}
+
+// use applies the terraform binary, CLI configuration and offline mode of a
+// run, which modules get when they are applied.
+func (r *NativeTestRunner) use(binary, cliConfigPath string, offline bool) {
+	if binary != "" {
+		r.Options.TerraformBinary = binary
+	}
+	if cliConfigPath != "" {
+		if r.Options.EnvVars == nil {
+			r.Options.EnvVars = make(map[string]string)
+		}
+		r.Options.EnvVars["TF_CLI_CONFIG_FILE"] = cliConfigPath
+	}
+	if offline {
+		useOffline(r.Options)
+	}
+}
+
+func (r *NativeTestRunner) Name() string {
+	return r.name
+}
+
+func (r *NativeTestRunner) Apply(ctx context.Context, t testing.TB) error {
+	if _, err := terraform.InitE(t, r.Options); err != nil {
+		return fmt.Errorf("failed to init: %w", err)
+	}
+
+	// terraform test exits non-zero when a test fails, but still describes
+	// every run on stdout.
+	out, err := terraform.RunTerraformCommandAndGetStdoutE(t, r.Options, "test", "-json", "-no-color")
+	results, parseErr := ParseNativeTests([]byte(out))
+	if parseErr != nil {
+		if err != nil {
+			return err
+		}
+		return parseErr
+	}
+	r.Results = results
+
+	var failed []string
+	for _, result := range results {
+		if result.Run != "" {
+			t.Logf("%s %s/%s", result.Status, result.File, result.Run)
+		}
+		if result.Failed() {
+			failed = append(failed, result.String())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d terraform test(s) failed: %s", len(failed), len(results), strings.Join(failed, "; "))
+	}
+	return err
+}
+
+func (r *NativeTestRunner) Destroy(ctx context.Context, t testing.TB) error {
+	return nil
+}
+
+func (r *NativeTestRunner) Cleanup(ctx context.Context, t testing.TB) error {
+	return nil
+}
+
+// nativeTestRunners returns a runner for every module under test that has
+// terraform test files. Modules of a monorepo get runners named after them.
+func nativeTestRunners(config *Config) ([]ModuleRunner, error) {
+	targets, err := moduleTargets(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var runners []ModuleRunner
+	if len(targets) == 0 {
+		if root := filepath.Dir(getExamplesPath(config)); HasNativeTests(root) {
+			runners = append(runners, NewNativeTestRunner(nativeTestsName, root))
+		}
+		return runners, nil
+	}
+	for _, target := range targets {
+		if HasNativeTests(target.Root) {
+			runners = append(runners, NewNativeTestRunner(target.Name+"/"+nativeTestsName, target.Root))
+		}
+	}
+	return runners, nil
+}
+
+// TestNative runs terraform test for every module that has terraform test
+// files, without applying its examples.
+func TestNative(t testing.TB, opts ...Option) {
+	config := setupConfigWithOptions(opts...)
+	runners, err := nativeTestRunners(config)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Failed to discover modules: %v", err)))
+		return
+	}
+	if len(runners) == 0 {
+		t.Skip("No terraform test files found")
+		return
+	}
+	native := *config
+	native.NativeTests = false
+	runModuleTests(t, runners, false, &native, nil, "local")
+}
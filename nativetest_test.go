@@ -0,0 +1,173 @@
+package validor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const nativeTestJSON = `{"@level":"info","@message":"Found 2 files and 3 run blocks","type":"test_abstract"}
+{"@level":"info","@message":"tests/main.tftest.hcl... in progress","@testfile":"tests/main.tftest.hcl","type":"test_file","test_file":{"path":"tests/main.tftest.hcl","progress":"starting"}}
+{"@level":"info","@message":"  \"defaults\"... pass","@testfile":"tests/main.tftest.hcl","@testrun":"defaults","type":"test_run","test_run":{"path":"tests/main.tftest.hcl","run":"defaults","progress":"complete","status":"pass"}}
+{"@level":"error","@message":"Error: Test assertion failed","@testfile":"tests/main.tftest.hcl","@testrun":"name","type":"diagnostic","diagnostic":{"severity":"error","summary":"Test assertion failed","detail":"name did not match","range":{"filename":"tests/main.tftest.hcl","start":{"line":12}}}}
+{"@level":"info","@message":"  \"name\"... fail","@testfile":"tests/main.tftest.hcl","@testrun":"name","type":"test_run","test_run":{"path":"tests/main.tftest.hcl","run":"name","progress":"complete","status":"fail"}}
+{"@level":"error","@message":"Error: Invalid block","@testfile":"tests/broken.tftest.hcl","type":"diagnostic","diagnostic":{"severity":"error","summary":"Invalid block"}}
+{"@level":"info","@message":"Failure! 1 passed, 1 failed.","type":"test_summary","test_summary":{"status":"fail","passed":1,"failed":1,"errored":0,"skipped":0}}
+`
+
+func TestHasNativeTests(t *testing.T) {
+	root := t.TempDir()
+	if HasNativeTests(root) {
+		t.Error("expected no terraform tests in an empty module")
+	}
+	writeFiles(t, root, map[string]string{"main.tftest.hcl": "", "tests/README.md": ""})
+	if HasNativeTests(root) {
+		t.Error("expected only test files in the tests directory to count")
+	}
+	writeFiles(t, root, map[string]string{"tests/main.tftest.hcl": ""})
+	if !HasNativeTests(root) {
+		t.Error("expected terraform tests to be detected")
+	}
+}
+
+func TestParseNativeTests(t *testing.T) {
+	got, err := ParseNativeTests([]byte(nativeTestJSON))
+	if err != nil {
+		t.Fatalf("ParseNativeTests() error = %v", err)
+	}
+	want := []NativeTestResult{
+		{File: "tests/main.tftest.hcl", Run: "defaults", Status: "pass"},
+		{File: "tests/main.tftest.hcl", Run: "name", Status: "fail", Diagnostics: []Diagnostic{
+			{Severity: "error", Summary: "Test assertion failed", Detail: "name did not match", Location: "tests/main.tftest.hcl:12"},
+		}},
+		{File: "tests/broken.tftest.hcl", Status: "error", Diagnostics: []Diagnostic{{Severity: "error", Summary: "Invalid block"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseNativeTests() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseNativeTests([]byte("not json")); err == nil {
+		t.Error("expected an error for invalid test json")
+	}
+}
+
+func TestNativeTestRunner_Apply(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "test.json")
+	if err := os.WriteFile(output, []byte(nativeTestJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	passing := `{"type":"test_run","test_run":{"path":"tests/main.tftest.hcl","run":"defaults","progress":"complete","status":"pass"}}`
+
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+		wantLog string
+	}{
+		{
+			name:    "passing",
+			script:  "[ \"$1\" = test ] && echo '" + passing + "'\nexit 0",
+			wantLog: "pass tests/main.tftest.hcl/defaults",
+		},
+		{
+			name:    "failing",
+			script:  "[ \"$1\" = test ] && cat " + output + " && exit 1\nexit 0",
+			wantErr: "2 of 3 terraform test(s) failed: tests/main.tftest.hcl/name: Test assertion failed: name did not match at tests/main.tftest.hcl:12; tests/broken.tftest.hcl: Invalid block",
+			wantLog: "fail tests/main.tftest.hcl/name",
+		},
+		{
+			name:    "init fails",
+			script:  "echo 'no providers' >&2\nexit 1",
+			wantErr: "failed to init",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewNativeTestRunner("terraform-test", t.TempDir())
+			runner.Options.TerraformBinary = fakeTerraform(t, tt.script)
+			tb := &recordingTB{TB: t}
+
+			err := runner.Apply(context.Background(), tb)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Apply() error = %v, want %q", err, tt.wantErr)
+			}
+			if tt.wantLog != "" && !strings.Contains(strings.Join(tb.logs, "\n"), tt.wantLog) {
+				t.Errorf("logs = %v, want %q", tb.logs, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestNativeTestRunners(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"tests/main.tftest.hcl":                    "",
+		"examples/default/main.tf":                 "",
+		"modules/network/tests/a.tftest.hcl":       "",
+		"modules/network/examples/default/main.tf": "",
+		"modules/dns/examples/default/main.tf":     "",
+	})
+
+	tests := []struct {
+		name   string
+		config *Config
+		want   map[string]string
+	}{
+		{
+			name:   "module root",
+			config: &Config{ExamplesPath: filepath.Join(root, "examples")},
+			want:   map[string]string{"terraform-test": root},
+		},
+		{
+			name: "module targets",
+			config: &Config{Modules: []ModuleInfo{
+				{Name: "network", Root: filepath.Join(root, "modules", "network")},
+				{Name: "dns", Root: filepath.Join(root, "modules", "dns")},
+			}},
+			want: map[string]string{"network/terraform-test": filepath.Join(root, "modules", "network")},
+		},
+		{
+			name:   "no tests",
+			config: &Config{ExamplesPath: filepath.Join(root, "modules", "dns", "examples")},
+			want:   map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runners, err := nativeTestRunners(tt.config)
+			if err != nil {
+				t.Fatalf("nativeTestRunners() error = %v", err)
+			}
+			got := make(map[string]string)
+			for _, runner := range runners {
+				got[runner.Name()] = runner.(*NativeTestRunner).Options.TerraformDir
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("nativeTestRunners() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNativeTestRunner_Use(t *testing.T) {
+	runner := NewNativeTestRunner("terraform-test", t.TempDir())
+	runner.use("/opt/terraform", "/tmp/validor.tfrc", true)
+
+	if runner.Options.TerraformBinary != "/opt/terraform" {
+		t.Errorf("TerraformBinary = %q, want /opt/terraform", runner.Options.TerraformBinary)
+	}
+	if got := runner.Options.EnvVars["TF_CLI_CONFIG_FILE"]; got != "/tmp/validor.tfrc" {
+		t.Errorf("TF_CLI_CONFIG_FILE = %q, want /tmp/validor.tfrc", got)
+	}
+	if got := runner.Options.EnvVars["CHECKPOINT_DISABLE"]; got != "1" {
+		t.Errorf("CHECKPOINT_DISABLE = %q, want 1", got)
+	}
+}
@@ -0,0 +1,158 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+type NotificationFormat string
+
+const (
+	NotificationJSON  NotificationFormat = "json"
+	NotificationSlack NotificationFormat = "slack"
+	NotificationTeams NotificationFormat = "teams"
+)
+
+func WithNotification(url string, format NotificationFormat) Option {
+	return func(c *Config) {
+		c.NotificationURL = url
+		c.NotificationFormat = format
+	}
+}
+
+type NotificationModule struct {
+	Name     string         `json:"name"`
+	Duration string         `json:"duration,omitempty"`
+	Errors   []string       `json:"errors"`
+	Failures []*ModuleError `json:"failures,omitempty"`
+}
+
+type NotificationSummary struct {
+	Total         int                  `json:"total"`
+	Passed        int                  `json:"passed"`
+	Failed        int                  `json:"failed"`
+	Duration      string               `json:"duration,omitempty"`
+	FailedModules []NotificationModule `json:"failed_modules"`
+	JobURL        string               `json:"job_url,omitempty"`
+}
+
+func newNotificationSummary(modules []*Module) NotificationSummary {
+	summary := NotificationSummary{
+		Total:         len(modules),
+		FailedModules: []NotificationModule{},
+		JobURL:        ciJobURL(),
+	}
+
+	var total time.Duration
+	for _, module := range modules {
+		total += module.TotalDuration()
+		if len(module.Errors) == 0 {
+			summary.Passed++
+			continue
+		}
+		summary.Failed++
+		summary.FailedModules = append(summary.FailedModules, NotificationModule{
+			Name:     module.Name,
+			Duration: module.TotalDuration().Round(time.Second).String(),
+			Errors:   module.Errors,
+			Failures: module.Failures,
+		})
+	}
+	summary.Duration = total.Round(time.Second).String()
+	return summary
+}
+
+func (s NotificationSummary) text() string {
+	var b strings.Builder
+	if s.Failed > 0 {
+		fmt.Fprintf(&b, "validor: %d of %d modules failed", s.Failed, s.Total)
+	} else {
+		fmt.Fprintf(&b, "validor: all %d modules applied and destroyed successfully", s.Total)
+	}
+	for _, module := range s.FailedModules {
+		fmt.Fprintf(&b, "\n• %s (%s)", module.Name, module.Duration)
+		if len(module.Failures) > 0 {
+			fmt.Fprintf(&b, " [%s]", module.Failures[0].Code)
+		}
+		if len(module.Errors) > 0 {
+			fmt.Fprintf(&b, ": %s", firstLine(module.Errors[0]))
+		}
+	}
+	if s.JobURL != "" {
+		fmt.Fprintf(&b, "\n%s", s.JobURL)
+	}
+	return b.String()
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func notificationPayload(format NotificationFormat, summary NotificationSummary) ([]byte, error) {
+	switch format {
+	case NotificationSlack:
+		return json.Marshal(map[string]string{"text": summary.text()})
+	case NotificationTeams:
+		return json.Marshal(map[string]string{
+			"@type":    "MessageCard",
+			"@context": "http://schema.org/extensions",
+			"summary":  firstLine(summary.text()),
+			"text":     strings.ReplaceAll(summary.text(), "\n", "<br>"),
+		})
+	case NotificationJSON, "":
+		return json.Marshal(summary)
+	default:
+		return nil, fmt.Errorf("unsupported notification format %q", format)
+	}
+}
+
+func sendNotification(ctx context.Context, config *Config, modules []*Module) error {
+	if config.NotificationURL == "" {
+		return nil
+	}
+
+	payload, err := notificationPayload(config.NotificationFormat, newNotificationSummary(modules))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.NotificationURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := reportHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to send notification: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func ciJobURL() string {
+	switch {
+	case os.Getenv("GITHUB_RUN_ID") != "":
+		return fmt.Sprintf("%s/%s/actions/runs/%s", os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"))
+	case os.Getenv("CI_JOB_URL") != "":
+		return os.Getenv("CI_JOB_URL")
+	case os.Getenv("BUILDKITE_BUILD_URL") != "":
+		return os.Getenv("BUILDKITE_BUILD_URL")
+	case os.Getenv("BUILD_BUILDID") != "":
+		return fmt.Sprintf("%s%s/_build/results?buildId=%s", os.Getenv("SYSTEM_COLLECTIONURI"), os.Getenv("SYSTEM_TEAMPROJECT"), os.Getenv("BUILD_BUILDID"))
+	case os.Getenv("BUILD_URL") != "":
+		return os.Getenv("BUILD_URL")
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewNotificationSummary(t *testing.T) {
+	t.Setenv("GITHUB_RUN_ID", "42")
+	t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	t.Setenv("GITHUB_REPOSITORY", "org/terraform-azure-vnet")
+
+	broken := NewModule("broken", "/path/broken")
+	broken.Errors = append(broken.Errors, "terraform apply failed\nmore detail")
+	modules := []*Module{NewModule("ok", "/path/ok"), broken}
+
+	summary := newNotificationSummary(modules)
+
+	if summary.Total != 2 || summary.Passed != 1 || summary.Failed != 1 {
+		t.Errorf("unexpected counts: %+v", summary)
+	}
+	if len(summary.FailedModules) != 1 || summary.FailedModules[0].Name != "broken" {
+		t.Errorf("unexpected failed modules: %+v", summary.FailedModules)
+	}
+	if summary.JobURL != "https://github.com/org/terraform-azure-vnet/actions/runs/42" {
+		t.Errorf("JobURL = %v", summary.JobURL)
+	}
+
+	text := summary.text()
+	if !strings.Contains(text, "1 of 2 modules failed") || !strings.Contains(text, "broken") {
+		t.Errorf("unexpected text: %q", text)
+	}
+	if strings.Contains(text, "more detail") {
+		t.Errorf("text should only include the first line of each error, got %q", text)
+	}
+}
+
+func TestNotificationPayload(t *testing.T) {
+	summary := NotificationSummary{Total: 1, Passed: 1, FailedModules: []NotificationModule{}}
+
+	tests := []struct {
+		name    string
+		format  NotificationFormat
+		wantKey string
+		wantErr bool
+	}{
+		{name: "json", format: NotificationJSON, wantKey: "total"},
+		{name: "default", format: "", wantKey: "total"},
+		{name: "slack", format: NotificationSlack, wantKey: "text"},
+		{name: "teams", format: NotificationTeams, wantKey: "@type"},
+		{name: "unsupported", format: "pager", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := notificationPayload(tt.format, summary)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("notificationPayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var decoded map[string]any
+			if err := json.Unmarshal(payload, &decoded); err != nil {
+				t.Fatalf("payload is not valid json: %v", err)
+			}
+			if _, ok := decoded[tt.wantKey]; !ok {
+				t.Errorf("expected payload key %q, got %s", tt.wantKey, payload)
+			}
+		})
+	}
+}
+
+func TestSendNotification(t *testing.T) {
+	origClient := reportHTTPClient
+	defer func() { reportHTTPClient = origClient }()
+
+	var gotBody string
+	reportHTTPClient = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			gotBody = string(body)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("ok")),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	config := NewConfig(WithNotification("https://hooks.slack.com/services/x", NotificationSlack))
+	if err := sendNotification(context.Background(), config, []*Module{NewModule("ok", "/path/ok")}); err != nil {
+		t.Fatalf("sendNotification() error = %v", err)
+	}
+	if !strings.Contains(gotBody, "all 1 modules applied") {
+		t.Errorf("unexpected body: %s", gotBody)
+	}
+}
+
+func TestSendNotification_Disabled(t *testing.T) {
+	if err := sendNotification(context.Background(), NewConfig(), nil); err != nil {
+		t.Errorf("sendNotification() without url should be a no-op, got %v", err)
+	}
+}
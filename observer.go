@@ -0,0 +1,113 @@
+package validor
+
+import (
+	"context"
+	"time"
+)
+
+// Observer receives events as a run progresses. Modules run in parallel, so
+// implementations must be safe for concurrent use. Embed NopObserver to only
+// implement the callbacks of interest.
+type Observer interface {
+	OnRunStart(ctx context.Context, event RunStartEvent)
+	OnModuleStageStart(ctx context.Context, event StageEvent)
+	OnModuleStageEnd(ctx context.Context, event StageEvent)
+	OnModuleComplete(ctx context.Context, module ModuleReport)
+	OnRunComplete(ctx context.Context, report *RunReport)
+}
+
+type RunStartEvent struct {
+	Modules  []string
+	Parallel bool
+	Started  time.Time
+}
+
+// StageEvent describes a module entering or leaving a stage. Duration, Passed
+// and Err are only set when the stage ends.
+type StageEvent struct {
+	Module   string
+	Stage    Stage
+	Time     time.Time
+	Duration time.Duration
+	Passed   bool
+	Err      error
+}
+
+type NopObserver struct{}
+
+func (NopObserver) OnRunStart(context.Context, RunStartEvent)      {}
+func (NopObserver) OnModuleStageStart(context.Context, StageEvent) {}
+func (NopObserver) OnModuleStageEnd(context.Context, StageEvent)   {}
+func (NopObserver) OnModuleComplete(context.Context, ModuleReport) {}
+func (NopObserver) OnRunComplete(context.Context, *RunReport)      {}
+
+func WithObserver(observer Observer) Option {
+	return func(c *Config) { c.Observers = append(c.Observers, observer) }
+}
+
+type observers []Observer
+
+func (o observers) OnRunStart(ctx context.Context, event RunStartEvent) {
+	for _, observer := range o {
+		observer.OnRunStart(ctx, event)
+	}
+}
+
+func (o observers) OnModuleStageStart(ctx context.Context, event StageEvent) {
+	for _, observer := range o {
+		observer.OnModuleStageStart(ctx, event)
+	}
+}
+
+func (o observers) OnModuleStageEnd(ctx context.Context, event StageEvent) {
+	for _, observer := range o {
+		observer.OnModuleStageEnd(ctx, event)
+	}
+}
+
+func (o observers) OnModuleComplete(ctx context.Context, module ModuleReport) {
+	for _, observer := range o {
+		observer.OnModuleComplete(ctx, module)
+	}
+}
+
+func (o observers) OnRunComplete(ctx context.Context, report *RunReport) {
+	for _, observer := range o {
+		observer.OnRunComplete(ctx, report)
+	}
+}
+
+func (m *Module) observe(ctx context.Context, observer Observer) {
+	m.observer = observer
+	m.observerCtx = ctx
+}
+
+// observeStage ends the stage the module is in, if any, and starts stage.
+// A stage ends when the next one starts or the module completes, so errors
+// recorded after the stage's work returned still count against it.
+func (m *Module) observeStage(stage Stage) {
+	if m.observer == nil {
+		return
+	}
+	m.endObservedStage()
+	m.openStage = stage
+	m.openSince = time.Now()
+	m.observer.OnModuleStageStart(m.observerCtx, StageEvent{Module: m.Name, Stage: stage, Time: m.openSince})
+}
+
+func (m *Module) endObservedStage() {
+	if m.observer == nil || m.openStage == "" {
+		return
+	}
+	now := time.Now()
+	err := m.failed[m.openStage]
+	m.observer.OnModuleStageEnd(m.observerCtx, StageEvent{
+		Module:   m.Name,
+		Stage:    m.openStage,
+		Time:     now,
+		Duration: now.Sub(m.openSince),
+		Passed:   err == nil,
+		Err:      err,
+	})
+	m.openStage = ""
+}
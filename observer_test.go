@@ -0,0 +1,112 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type recordingObserver struct {
+	NopObserver
+	mu      sync.Mutex
+	events  []string
+	modules []ModuleReport
+	report  *RunReport
+}
+
+func (o *recordingObserver) record(event string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *recordingObserver) OnRunStart(ctx context.Context, event RunStartEvent) {
+	o.record(fmt.Sprintf("run start %v", event.Modules))
+}
+
+func (o *recordingObserver) OnModuleStageStart(ctx context.Context, event StageEvent) {
+	o.record(fmt.Sprintf("%s %s start", event.Module, event.Stage))
+}
+
+func (o *recordingObserver) OnModuleStageEnd(ctx context.Context, event StageEvent) {
+	o.record(fmt.Sprintf("%s %s end passed=%v", event.Module, event.Stage, event.Passed))
+}
+
+func (o *recordingObserver) OnModuleComplete(ctx context.Context, module ModuleReport) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.modules = append(o.modules, module)
+}
+
+func (o *recordingObserver) OnRunComplete(ctx context.Context, report *RunReport) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.report = report
+}
+
+func TestObserver_ReceivesRunEvents(t *testing.T) {
+	observer := &recordingObserver{}
+	config := NewConfig(WithExample(""), WithObserver(observer))
+	config.ExceptionList = []string{"private"}
+
+	// Cleanups run last in first out, so this runs after the runner's own
+	// cleanup has reported the completed run.
+	t.Cleanup(func() {
+		if observer.report == nil {
+			t.Fatal("OnRunComplete was not called")
+		}
+		if len(observer.report.Modules) != 3 || observer.report.Passed() {
+			t.Errorf("unexpected run report %+v", observer.report)
+		}
+	})
+
+	runner := &DefaultTestRunner{Config: config}
+	recorder := &recordingTB{TB: t}
+	runner.RunTests(context.Background(), recorder, []ModuleRunner{
+		&fakeRunner{name: "network"},
+		&fakeRunner{name: "broken", destroyErr: errors.New("boom")},
+		&fakeRunner{name: "private"},
+	}, false, nil)
+
+	want := []string{
+		"run start [network broken private]",
+		"network apply start",
+		"network apply end passed=true",
+		"network destroy start",
+		"network destroy end passed=true",
+		"broken apply start",
+		"broken apply end passed=true",
+		"broken destroy start",
+		"broken destroy end passed=false",
+	}
+	if !reflect.DeepEqual(observer.events, want) {
+		t.Errorf("events = %v, want %v", observer.events, want)
+	}
+
+	var names []string
+	for _, module := range observer.modules {
+		names = append(names, module.Name)
+	}
+	if want := []string{"network", "broken", "private"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("completed modules = %v, want %v", names, want)
+	}
+	if observer.modules[1].Passed || !observer.modules[2].Skipped {
+		t.Errorf("unexpected module reports %+v", observer.modules)
+	}
+}
+
+func TestWithObserver(t *testing.T) {
+	first, second := &recordingObserver{}, &recordingObserver{}
+	config := NewConfig(WithObserver(first), WithObserver(second))
+	if len(config.Observers) != 2 {
+		t.Fatalf("expected 2 observers, got %d", len(config.Observers))
+	}
+
+	observers(config.Observers).OnRunStart(context.Background(), RunStartEvent{Modules: []string{"default"}})
+	if len(first.events) != 1 || len(second.events) != 1 {
+		t.Errorf("expected every observer to be notified, got %v and %v", first.events, second.events)
+	}
+}
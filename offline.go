@@ -0,0 +1,57 @@
+package validor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// WithOffline runs without internet access: the registry is not queried, so
+// examples get their original sources back verbatim after local tests unless
+// a version is pinned, terraform does not check for updates, and terraform
+// is not downloaded. Combine it with WithProviderMirror to install providers
+// without the registry.
+func WithOffline(offline bool) Option {
+	return func(c *Config) { c.Offline = offline }
+}
+
+// WithProviderMirror installs every provider from dir, a directory written by
+// terraform providers mirror, instead of from their registries.
+func WithProviderMirror(dir string) Option {
+	return func(c *Config) { c.ProviderMirror = dir }
+}
+
+var errOffline = errors.New("the registry is not available offline")
+
+// offlineRegistry is the RegistryClient of an offline run, which fails every
+// lookup instead of making a request.
+type offlineRegistry struct{}
+
+func (offlineRegistry) GetLatestVersion(ctx context.Context, namespace, name, provider string) (string, error) {
+	return "", errOffline
+}
+
+func (offlineRegistry) GetLatestMatching(ctx context.Context, namespace, name, provider, constraint string) (string, error) {
+	return "", errOffline
+}
+
+func (offlineRegistry) ListVersions(ctx context.Context, namespace, name, provider string) ([]string, error) {
+	return nil, errOffline
+}
+
+func (c *Config) registryClient() RegistryClient {
+	if c.Offline {
+		return offlineRegistry{}
+	}
+	return NewRegistryClient(c.RegistryOptions...)
+}
+
+// useOffline keeps terraform from checking for newer versions of itself and
+// its providers.
+func useOffline(options *terraform.Options) {
+	if options.EnvVars == nil {
+		options.EnvVars = make(map[string]string)
+	}
+	options.EnvVars["CHECKPOINT_DISABLE"] = "1"
+}
@@ -0,0 +1,72 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_RegistryClient(t *testing.T) {
+	if _, ok := (&Config{}).registryClient().(offlineRegistry); ok {
+		t.Error("expected the registry to be used when online")
+	}
+
+	client := (&Config{Offline: true}).registryClient()
+	if _, err := client.GetLatestVersion(context.Background(), "cloudnationhq", "vnet", "azure"); !errors.Is(err, errOffline) {
+		t.Errorf("GetLatestVersion() error = %v, want %v", err, errOffline)
+	}
+	if _, err := client.GetLatestMatching(context.Background(), "cloudnationhq", "vnet", "azure", "~> 1.0"); !errors.Is(err, errOffline) {
+		t.Errorf("GetLatestMatching() error = %v, want %v", err, errOffline)
+	}
+	if _, err := client.ListVersions(context.Background(), "cloudnationhq", "vnet", "azure"); !errors.Is(err, errOffline) {
+		t.Errorf("ListVersions() error = %v, want %v", err, errOffline)
+	}
+}
+
+func TestOffline_RevertRestoresVerbatim(t *testing.T) {
+	root := t.TempDir()
+	original := "module \"vnet\" {\n  source  = \"cloudnationhq/vnet/azure\"\n  version = \"~> 1.0\"\n}\n"
+	writeFiles(t, root, map[string]string{"examples/default/main.tf": original})
+	example := filepath.Join(root, "examples", "default")
+	info := ModuleInfo{Namespace: "cloudnationhq", Name: "vnet", Provider: "azure", Root: root}
+
+	tests := []struct {
+		name string
+		pin  string
+		want string
+	}{
+		{name: "verbatim", want: original},
+		{name: "pinned", pin: "2.0.0", want: strings.Replace(original, "~> 1.0", "2.0.0", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Offline: true}
+			converter := NewSourceConverter(config.registryClient(), WithVersionPins(tt.pin, nil))
+			restores, err := converter.ConvertToLocal(context.Background(), example, info)
+			if err != nil || len(restores) != 1 {
+				t.Fatalf("ConvertToLocal() = %v, %v", restores, err)
+			}
+			if err := converter.RevertToRegistry(context.Background(), restores); err != nil {
+				t.Fatalf("RevertToRegistry() error = %v", err)
+			}
+			content, err := os.ReadFile(filepath.Join(example, "main.tf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("reverted content =\n%s\nwant\n%s", content, tt.want)
+			}
+		})
+	}
+}
+
+func TestOffline_TerraformBinary(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, err := terraformBinary(context.Background(), &Config{InstallTerraform: true, Offline: true, TerraformVersion: "~> 1.9"})
+	if err == nil || !strings.Contains(err.Error(), "cannot be downloaded offline") {
+		t.Errorf("terraformBinary() error = %v, want an offline error", err)
+	}
+}
@@ -0,0 +1,206 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// WithOutputContract checks after apply that the examples only reference
+// outputs the module declares and that every declared output has a value.
+func WithOutputContract(enabled bool) Option {
+	return func(c *Config) { c.OutputContract = enabled }
+}
+
+// WithSensitiveOutputs keeps the values of sensitive outputs in the outputs
+// captured after apply, instead of redacting them. With secret masking they
+// are still masked in reports.
+func WithSensitiveOutputs(enabled bool) Option {
+	return func(c *Config) { c.SensitiveOutputs = enabled }
+}
+
+// WithNullableOutputs allows these outputs to be null, such as ones that are
+// only set when an optional feature is enabled.
+func WithNullableOutputs(outputs ...string) Option {
+	return func(c *Config) { c.NullableOutputs = append(c.NullableOutputs, outputs...) }
+}
+
+// UndeclaredOutputs returns the references of the example in exampleDir to
+// outputs the module at moduleRoot does not declare, as file:line: reference.
+func UndeclaredOutputs(moduleRoot, exampleDir string, moduleInfo ModuleInfo) ([]string, error) {
+	contract, err := loadOutputContract(moduleRoot, exampleDir, moduleInfo)
+	if err != nil {
+		return nil, err
+	}
+	return contract.undeclared, nil
+}
+
+type outputContract struct {
+	declared   []string
+	undeclared []string
+	// calls are the labels of the module blocks whose outputs can be evaluated;
+	// instances of count and for_each are left out.
+	calls []string
+}
+
+func loadOutputContract(moduleRoot, exampleDir string, moduleInfo ModuleInfo) (*outputContract, error) {
+	rootBodies, err := parseTerraformFiles(moduleRoot)
+	if err != nil {
+		return nil, err
+	}
+	bodies, err := parseTerraformFiles(exampleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	contract := &outputContract{declared: blockLabels(rootBodies, "output")}
+	var names []string
+	for _, call := range modulesUnderTest(bodies, exampleDir, moduleRoot, moduleInfo) {
+		names = append(names, call.Labels[0])
+		_, counted := call.Body.Attributes["count"]
+		_, forEach := call.Body.Attributes["for_each"]
+		if !counted && !forEach {
+			contract.calls = append(contract.calls, call.Labels[0])
+		}
+	}
+	for _, ref := range outputReferences(bodies, names) {
+		if ref.Output != "" && !slices.Contains(contract.declared, ref.Output) {
+			contract.undeclared = append(contract.undeclared, fmt.Sprintf("%s:%d: module.%s.%s", ref.Range.Filename, ref.Range.Start.Line, ref.Module, ref.Output))
+		}
+	}
+	return contract, nil
+}
+
+// CheckOutputs verifies the example against the outputs the module at
+// moduleRoot declares: it may only reference declared outputs, and after apply
+// every declared output that is not in nullable must have a value. Outputs are
+// evaluated with terraform console; sensitive ones count as set.
+func (m *Module) CheckOutputs(ctx context.Context, t testing.TB, moduleRoot string, moduleInfo ModuleInfo, nullable []string) error {
+	t.Helper()
+	defer m.startStage(StageOutputs)()
+
+	contract, err := loadOutputContract(moduleRoot, m.Options.TerraformDir, moduleInfo)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "output contract", Err: err})
+	}
+	if len(contract.undeclared) > 0 {
+		err := fmt.Errorf("example references outputs the module does not declare: %s", strings.Join(contract.undeclared, ", "))
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "output contract", Err: err})
+	}
+
+	var outputs, expressions []string
+	for _, call := range contract.calls {
+		for _, output := range contract.declared {
+			if !slices.Contains(nullable, output) {
+				outputs = append(outputs, fmt.Sprintf("module.%s.%s", call, output))
+				expressions = append(expressions, fmt.Sprintf("module.%s.%s == null", call, output))
+			}
+		}
+	}
+	if len(expressions) == 0 {
+		return nil
+	}
+
+	results, err := m.console(ctx, t, expressions)
+	if err == nil && len(results) != len(expressions) {
+		err = fmt.Errorf("terraform console returned %d results for %d outputs", len(results), len(expressions))
+	}
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "output contract", Err: err})
+	}
+
+	var null []string
+	for i, result := range results {
+		if result == "true" {
+			null = append(null, outputs[i])
+		}
+	}
+	if len(null) > 0 {
+		err := fmt.Errorf("%d output(s) are null: %s", len(null), strings.Join(null, ", "))
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "output contract", Err: err})
+	}
+	t.Logf("All %d outputs of module %s are set", len(outputs), m.Name)
+	return nil
+}
+
+// console evaluates expressions against the example's state, returning one
+// result line per expression.
+func (m *Module) console(ctx context.Context, t testing.TB, expressions []string) ([]string, error) {
+	if m.consoleHook != nil {
+		return m.consoleHook(ctx, t, m, expressions)
+	}
+	options := *m.Options
+	options.Stdin = strings.NewReader(strings.Join(expressions, "\n") + "\n")
+	out, err := terraform.RunTerraformCommandAndGetStdoutE(t, &options, "console")
+	if err != nil {
+		return nil, err
+	}
+	var results []string
+	for line := range strings.SplitSeq(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			results = append(results, line)
+		}
+	}
+	return results, nil
+}
+
+// RedactedOutput replaces the value of a sensitive output.
+const RedactedOutput = "(sensitive)"
+
+// ParseOutputs parses the output of terraform output -json into the values
+// of the outputs by name, replacing the values of sensitive outputs with
+// RedactedOutput unless reveal is set.
+func ParseOutputs(outputJSON []byte, reveal bool) (map[string]any, error) {
+	var outputs map[string]struct {
+		Sensitive bool `json:"sensitive"`
+		Value     any  `json:"value"`
+	}
+	if err := json.Unmarshal(outputJSON, &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse output json: %w", err)
+	}
+	values := make(map[string]any, len(outputs))
+	for name, output := range outputs {
+		values[name] = output.Value
+		if output.Sensitive && !reveal {
+			values[name] = RedactedOutput
+		}
+	}
+	return values, nil
+}
+
+// captureOutputs records the outputs of the applied example in Outputs. A
+// failure to read them is only logged.
+func (m *Module) captureOutputs(ctx context.Context, t testing.TB) {
+	var out []byte
+	if m.outputsHook == nil && m.terragrunt == TerragruntStack {
+		return
+	}
+	if m.outputsHook != nil {
+		var err error
+		if out, err = m.outputsHook(ctx, t, m); err != nil {
+			t.Logf("Warning: failed to read the outputs of %s: %v", m.Name, err)
+			return
+		}
+	} else {
+		args := append([]string{"output", "-json", "-no-color"}, m.Options.ExtraArgs.Output...)
+		stdout, err := terraform.RunTerraformCommandAndGetStdoutE(t, m.Options, args...)
+		if err != nil {
+			t.Logf("Warning: failed to read the outputs of %s: %v", m.Name, err)
+			return
+		}
+		out = []byte(stdout)
+	}
+
+	outputs, err := ParseOutputs(out, m.revealOutputs)
+	if err != nil {
+		t.Logf("Warning: failed to read the outputs of %s: %v", m.Name, err)
+		return
+	}
+	m.maskSensitiveOutputs(out)
+	m.Outputs = outputs
+}
@@ -0,0 +1,240 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeOutputContractModule(t *testing.T, example string) (root, exampleDir string) {
+	t.Helper()
+	root = t.TempDir()
+	exampleDir = filepath.Join(root, "examples", "default")
+	if err := os.MkdirAll(exampleDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	outputs := `
+output "id" {
+  value = "id"
+}
+
+output "name" {
+  value = "name"
+}
+
+output "endpoint" {
+  value = null
+}
+`
+	if err := os.WriteFile(filepath.Join(root, "outputs.tf"), []byte(outputs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(exampleDir, "main.tf"), []byte(example), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root, exampleDir
+}
+
+func TestModule_CheckOutputs(t *testing.T) {
+	tests := []struct {
+		name            string
+		example         string
+		nullable        []string
+		results         map[string]string
+		wantExpressions []string
+		wantErr         string
+	}{
+		{
+			name: "all outputs set",
+			example: `
+module "main" {
+  source = "../../"
+}
+
+output "id" {
+  value = module.main.id
+}
+`,
+			results: map[string]string{"module.main.id == null": "false", "module.main.name == null": "false", "module.main.endpoint == null": "(sensitive value)"},
+			wantExpressions: []string{
+				"module.main.endpoint == null",
+				"module.main.id == null",
+				"module.main.name == null",
+			},
+		},
+		{
+			name: "null output",
+			example: `
+module "main" {
+  source = "../../"
+}
+`,
+			results: map[string]string{"module.main.id == null": "false", "module.main.name == null": "false", "module.main.endpoint == null": "true"},
+			wantErr: "1 output(s) are null: module.main.endpoint",
+		},
+		{
+			name: "nullable output",
+			example: `
+module "main" {
+  source = "../../"
+}
+`,
+			nullable:        []string{"endpoint"},
+			results:         map[string]string{"module.main.id == null": "false", "module.main.name == null": "false"},
+			wantExpressions: []string{"module.main.id == null", "module.main.name == null"},
+		},
+		{
+			name: "undeclared output",
+			example: `
+module "main" {
+  source = "../../"
+}
+
+output "url" {
+  value = module.main.url
+}
+`,
+			wantErr: "main.tf:7: module.main.url",
+		},
+		{
+			name: "instances are not evaluated",
+			example: `
+module "main" {
+  source   = "../../"
+  for_each = toset(["a", "b"])
+}
+
+output "ids" {
+  value = module.main["a"].id
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, exampleDir := writeOutputContractModule(t, tt.example)
+			module := NewModule("default", exampleDir)
+
+			var expressions []string
+			module.consoleHook = func(ctx context.Context, tb testing.TB, m *Module, exprs []string) ([]string, error) {
+				expressions = exprs
+				var results []string
+				for _, expr := range exprs {
+					results = append(results, tt.results[expr])
+				}
+				return results, nil
+			}
+
+			err := module.CheckOutputs(context.Background(), t, root, ModuleInfo{}, tt.nullable)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CheckOutputs() error = %v, want %q", err, tt.wantErr)
+				}
+				if module.failed[StageOutputs] == nil {
+					t.Error("the outputs stage should be marked as failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckOutputs() error = %v", err)
+			}
+			if !reflect.DeepEqual(expressions, tt.wantExpressions) {
+				t.Errorf("expressions = %v, want %v", expressions, tt.wantExpressions)
+			}
+		})
+	}
+}
+
+func TestUndeclaredOutputs(t *testing.T) {
+	root, exampleDir := writeOutputContractModule(t, `
+module "main" {
+  source = "../../"
+}
+
+module "other" {
+  source = "./other"
+}
+
+locals {
+  id      = module.main.id
+  missing = module.main.missing
+  foreign = module.other.missing
+}
+`)
+	got, err := UndeclaredOutputs(root, exampleDir, ModuleInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(exampleDir, "main.tf") + ":12: module.main.missing"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UndeclaredOutputs() = %v, want %v", got, want)
+	}
+}
+
+func TestParseOutputs(t *testing.T) {
+	outputJSON := []byte(`{
+		"resource_group": {"sensitive": false, "type": "string", "value": "rg-test"},
+		"subnets": {"sensitive": false, "type": ["list", "string"], "value": ["a", "b"]},
+		"admin_password": {"sensitive": true, "type": "string", "value": "hunter2"}
+	}`)
+
+	tests := []struct {
+		name   string
+		reveal bool
+		want   map[string]any
+	}{
+		{name: "redacted", want: map[string]any{"resource_group": "rg-test", "subnets": []any{"a", "b"}, "admin_password": RedactedOutput}},
+		{name: "revealed", reveal: true, want: map[string]any{"resource_group": "rg-test", "subnets": []any{"a", "b"}, "admin_password": "hunter2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOutputs(outputJSON, tt.reveal)
+			if err != nil {
+				t.Fatalf("ParseOutputs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOutputs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseOutputs([]byte(`[`), false); err == nil {
+		t.Error("expected an error for invalid output json")
+	}
+}
+
+func TestModule_ApplyCapturesOutputs(t *testing.T) {
+	tests := []struct {
+		name      string
+		applyErr  error
+		outputErr error
+		want      map[string]any
+	}{
+		{name: "captured after apply", want: map[string]any{"id": "rg-test", "key": RedactedOutput}},
+		{name: "not captured when apply fails", applyErr: errors.New("quota exceeded")},
+		{name: "unreadable outputs are skipped", outputErr: errors.New("no state")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := NewModule("default", t.TempDir())
+			module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return tt.applyErr }
+			module.outputsHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+				return []byte(`{"id":{"value":"rg-test"},"key":{"sensitive":true,"value":"secret"}}`), tt.outputErr
+			}
+
+			module.Apply(context.Background(), &recordingTB{TB: t})
+			if !reflect.DeepEqual(module.Outputs, tt.want) {
+				t.Errorf("Outputs = %v, want %v", module.Outputs, tt.want)
+			}
+			if got := newModuleReport(module).Outputs; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("report outputs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
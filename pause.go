@@ -0,0 +1,130 @@
+package validor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultPauseTimeout = 30 * time.Minute
+
+// WithPauseOnFailure pauses before destroying a failed example so its live
+// infrastructure can be inspected. It only pauses when stdin is a terminal.
+func WithPauseOnFailure(enabled bool) Option {
+	return func(c *Config) { c.PauseOnFailure = enabled }
+}
+
+func WithPauseTimeout(timeout time.Duration) Option {
+	return func(c *Config) { c.PauseTimeout = timeout }
+}
+
+var (
+	pauseInput      io.Reader = os.Stdin
+	pauseOutput     io.Writer = os.Stderr
+	stdinIsTerminal           = func() bool { return isTerminal(os.Stdin) }
+
+	// Lines are read by a single goroutine, so a pause that timed out does not
+	// leave a reader behind that swallows the next one's enter.
+	pauseLinesOnce sync.Once
+	pauseLines     chan struct{}
+
+	// Parallel examples that fail together pause one at a time.
+	pauseMu sync.Mutex
+)
+
+func readPauseLines() chan struct{} {
+	pauseLinesOnce.Do(func() {
+		lines, input := make(chan struct{}), pauseInput
+		pauseLines = lines
+		go func() {
+			scanner := bufio.NewScanner(input)
+			for scanner.Scan() {
+				lines <- struct{}{}
+			}
+			close(lines)
+		}()
+	})
+	return pauseLines
+}
+
+// pauseOnFailure prints how to inspect the failed example and waits for enter
+// or the timeout. It ignores the example's own timeout, which may be what made
+// it fail. Output goes straight to the terminal, as the test log of a parallel
+// subtest is only printed once it finishes.
+func (m *Module) pauseOnFailure(timeout time.Duration) {
+	if !stdinIsTerminal() {
+		return
+	}
+	if timeout <= 0 {
+		timeout = defaultPauseTimeout
+	}
+
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+
+	fmt.Fprintf(pauseOutput, "\nExample %s failed; its infrastructure is still up.\n", m.Name)
+	fmt.Fprintf(pauseOutput, "  Path:    %s\n", m.terraformDir())
+	fmt.Fprintf(pauseOutput, "  Inspect: %s\n", m.inspectCommand())
+	fmt.Fprintf(pauseOutput, "Press enter to destroy it (continues automatically in %s)... ", timeout)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-readPauseLines():
+	case <-timer.C:
+		fmt.Fprintln(pauseOutput, "timed out")
+	}
+}
+
+func (m *Module) terraformDir() string {
+	dir, err := filepath.Abs(m.Options.TerraformDir)
+	if err != nil {
+		return m.Options.TerraformDir
+	}
+	return dir
+}
+
+// inspectCommand is a POSIX shell command, or a PowerShell one on Windows.
+func (m *Module) inspectCommand() string {
+	names := make([]string, 0, len(m.Options.EnvVars))
+	for name := range m.Options.EnvVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	binary := m.Options.TerraformBinary
+	if binary == "" {
+		binary = "terraform"
+	}
+
+	if goos == "windows" {
+		parts := []string{"Set-Location " + powerShellQuote(m.terraformDir())}
+		for _, name := range names {
+			parts = append(parts, "$env:"+name+" = "+powerShellQuote(m.Options.EnvVars[name]))
+		}
+		return strings.Join(append(parts, "& "+powerShellQuote(binary)+" state list"), "; ")
+	}
+
+	parts := []string{"cd", shellQuote(m.terraformDir()), "&&"}
+	for _, name := range names {
+		parts = append(parts, name+"="+shellQuote(m.Options.EnvVars[name]))
+	}
+	return strings.Join(append(parts, shellQuote(binary), "state", "list"), " ")
+}
+
+func shellQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func powerShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
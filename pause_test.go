@@ -0,0 +1,98 @@
+package validor
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func stubPause(t *testing.T, input io.Reader, terminal bool) *bytes.Buffer {
+	t.Helper()
+	origInput, origOutput, origTerminal := pauseInput, pauseOutput, stdinIsTerminal
+	origGOOS := goos
+	t.Cleanup(func() {
+		goos = origGOOS
+		pauseInput, pauseOutput, stdinIsTerminal = origInput, origOutput, origTerminal
+		pauseLinesOnce, pauseLines = sync.Once{}, nil
+	})
+
+	var output bytes.Buffer
+	goos = "linux"
+	pauseInput, pauseOutput = input, &output
+	stdinIsTerminal = func() bool { return terminal }
+	pauseLinesOnce, pauseLines = sync.Once{}, nil
+	return &output
+}
+
+func TestModule_PauseOnFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "default")
+	module := NewModule("default", dir)
+	module.Options.EnvVars = map[string]string{"TF_PLUGIN_CACHE_DIR": "/tmp/plugin cache"}
+
+	output := stubPause(t, strings.NewReader("\n"), true)
+	module.pauseOnFailure(time.Minute)
+
+	for _, want := range []string{
+		"Example default failed",
+		"Path:    " + dir,
+		"Inspect: cd " + shellQuote(dir) + " && TF_PLUGIN_CACHE_DIR='/tmp/plugin cache' terraform state list",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, output.String())
+		}
+	}
+}
+
+func TestModule_InspectCommand_Windows(t *testing.T) {
+	origGOOS := goos
+	t.Cleanup(func() { goos = origGOOS })
+	goos = "windows"
+
+	dir := filepath.Join(t.TempDir(), "it's")
+	module := NewModule("default", dir)
+	module.Options.EnvVars = map[string]string{"TF_CLI_CONFIG_FILE": "/tmp/cli.tfrc"}
+
+	want := "Set-Location '" + strings.ReplaceAll(dir, "'", "''") + "'; $env:TF_CLI_CONFIG_FILE = '/tmp/cli.tfrc'; & 'terraform' state list"
+	if got := module.inspectCommand(); got != want {
+		t.Errorf("inspectCommand() = %s, want %s", got, want)
+	}
+}
+
+func TestModule_PauseOnFailure_Timeout(t *testing.T) {
+	reader, writer := io.Pipe()
+	t.Cleanup(func() { writer.Close() })
+	output := stubPause(t, reader, true)
+
+	NewModule("default", "/tmp/default").pauseOnFailure(10 * time.Millisecond)
+	if !strings.Contains(output.String(), "timed out") {
+		t.Errorf("expected the pause to time out, got %q", output.String())
+	}
+}
+
+func TestModule_PauseOnFailure_NoTerminal(t *testing.T) {
+	output := stubPause(t, strings.NewReader(""), false)
+
+	NewModule("default", "/tmp/default").pauseOnFailure(time.Minute)
+	if output.Len() != 0 {
+		t.Errorf("should not pause without a terminal, got %q", output.String())
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"terraform":    "terraform",
+		"/tmp/a b":     "'/tmp/a b'",
+		"it's":         `'it'\''s'`,
+		"":             "''",
+		"$HOME/config": "'$HOME/config'",
+	}
+	for value, want := range tests {
+		if got := shellQuote(value); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package validor
+
+import (
+	"sort"
+	"sync"
+	"testing"
+)
+
+// WithPhasedDestroy applies all examples first, respecting parallelism, and
+// only destroys them, one at a time in reverse order, once every example has
+// been applied and validated.
+func WithPhasedDestroy(enabled bool) Option {
+	return func(c *Config) { c.PhasedDestroy = enabled }
+}
+
+type phasedTeardown struct {
+	index int
+	name  string
+	run   func(t testing.TB)
+}
+
+// destroyPhase collects the teardowns of the examples applied in the apply
+// phase, which may finish in any order when run in parallel.
+type destroyPhase struct {
+	mu        sync.Mutex
+	teardowns []phasedTeardown
+}
+
+func (p *destroyPhase) add(index int, name string, run func(t testing.TB)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.teardowns = append(p.teardowns, phasedTeardown{index: index, name: name, run: run})
+}
+
+// run destroys the examples in the reverse of the order they were started in,
+// so examples are destroyed before the ones they depend on.
+func (p *destroyPhase) run(t testing.TB) {
+	p.mu.Lock()
+	teardowns := p.teardowns
+	p.mu.Unlock()
+
+	sort.Slice(teardowns, func(i, j int) bool { return teardowns[i].index > teardowns[j].index })
+	for _, teardown := range teardowns {
+		runSubtest(t, teardown.name, false, teardown.run)
+	}
+}
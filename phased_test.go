@@ -0,0 +1,113 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type phasedRunner struct {
+	fakeRunner
+	mu       *sync.Mutex
+	events   *[]string
+	failures int
+	applies  int
+}
+
+func (p *phasedRunner) record(event string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*p.events = append(*p.events, event+" "+p.name)
+}
+
+func (p *phasedRunner) Apply(ctx context.Context, t testing.TB) error {
+	p.record("apply")
+	p.applies++
+	if p.applies <= p.failures {
+		return errors.New("transient")
+	}
+	return nil
+}
+
+func (p *phasedRunner) Destroy(ctx context.Context, t testing.TB) error {
+	p.record("destroy")
+	return nil
+}
+
+func TestRunModuleTests_PhasedDestroy(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		failures map[string]int
+		want     []string
+	}{
+		{
+			name:     "sequential",
+			want:     []string{"apply a", "apply b", "apply c", "destroy c", "destroy b", "destroy a"},
+			failures: map[string]int{},
+		},
+		{
+			name:     "failed attempt is destroyed before its rerun",
+			options:  []Option{WithRerunFailed(1)},
+			failures: map[string]int{"b": 1},
+			want:     []string{"apply a", "apply b", "destroy b", "apply c", "apply b", "destroy c", "destroy b", "destroy a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var events []string
+			var runners []ModuleRunner
+			for _, name := range []string{"a", "b", "c"} {
+				runners = append(runners, &phasedRunner{fakeRunner: fakeRunner{name: name}, mu: &mu, events: &events, failures: tt.failures[name]})
+			}
+
+			config := NewConfig(append(tt.options, WithExample(""), WithPhasedDestroy(true))...)
+			runModuleTests(t, runners, false, config, nil, "registry")
+
+			if !reflect.DeepEqual(events, tt.want) {
+				t.Errorf("events = %v, want %v", events, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunModuleTests_PhasedDestroyParallel(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	var runners []ModuleRunner
+	for _, name := range []string{"a", "b", "c"} {
+		runners = append(runners, &phasedRunner{fakeRunner: fakeRunner{name: name}, mu: &mu, events: &events})
+	}
+
+	runModuleTests(t, runners, true, NewConfig(WithExample(""), WithPhasedDestroy(true)), nil, "registry")
+
+	if len(events) != 6 {
+		t.Fatalf("events = %v, want every example applied and destroyed", events)
+	}
+	for _, event := range events[:3] {
+		if !strings.HasPrefix(event, "apply") {
+			t.Fatalf("destroy started before every example was applied: %v", events)
+		}
+	}
+	if want := []string{"destroy c", "destroy b", "destroy a"}; !reflect.DeepEqual(events[3:], want) {
+		t.Errorf("destroys = %v, want %v", events[3:], want)
+	}
+}
+
+func TestRunModuleTests_PhasedDestroyMatrix(t *testing.T) {
+	mock := &fatalTB{TB: t}
+	config := NewConfig(WithExample(""), WithPhasedDestroy(true), WithMatrix(map[string][]string{"location": {"westeurope", "northeurope"}}))
+	func() {
+		defer func() { recover() }()
+		runModuleTests(mock, Runners([]*Module{matrixExample(t, "default", "location")}), false, config, nil, "registry")
+	}()
+
+	if !strings.Contains(mock.message, "cannot be combined with a matrix") {
+		t.Errorf("expected the matrix to be rejected, got %q", mock.message)
+	}
+}
@@ -0,0 +1,125 @@
+package validor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// PipelineStage is a custom step in the per-module pipeline. It runs against
+// the module after the stages before it passed; returning an error fails the
+// module and skips the remaining stages up to destroy.
+type PipelineStage struct {
+	Name Stage
+	Run  func(ctx context.Context, t testing.TB, m *Module) error
+}
+
+// StagePosition anchors a custom stage to another stage. Anchors that are not
+// part of a module's pipeline, such as a stage that is not configured,
+// resolve to where that stage would run.
+type StagePosition struct {
+	anchor Stage
+	after  bool
+}
+
+func BeforeStage(stage Stage) StagePosition {
+	return StagePosition{anchor: stage}
+}
+
+func AfterStage(stage Stage) StagePosition {
+	return StagePosition{anchor: stage, after: true}
+}
+
+type StagePlugin struct {
+	Position StagePosition
+	Stage    PipelineStage
+}
+
+func WithStage(position StagePosition, stage PipelineStage) Option {
+	return func(c *Config) {
+		c.Stages = append(c.Stages, StagePlugin{Position: position, Stage: stage})
+	}
+}
+
+// WithoutStage disables built-in or custom stages by name.
+func WithoutStage(stages ...Stage) Option {
+	return func(c *Config) { c.DisabledStages = append(c.DisabledStages, stages...) }
+}
+
+type pipelineStep struct {
+	name    Stage
+	enabled bool
+	run     func(ctx context.Context, t testing.TB) error
+}
+
+// validateStagePlugins checks that custom stages have unique names and known
+// anchors, and that the disabled stages exist.
+func validateStagePlugins(plugins []StagePlugin, disabled []Stage) error {
+	known := slices.Clone(stageOrder)
+	for _, plugin := range plugins {
+		if plugin.Stage.Name == "" || plugin.Stage.Run == nil {
+			return fmt.Errorf("custom stage needs a name and a run function")
+		}
+		if slices.Contains(known, plugin.Stage.Name) {
+			return fmt.Errorf("stage %s is already defined", plugin.Stage.Name)
+		}
+		if !slices.Contains(known, plugin.Position.anchor) {
+			return fmt.Errorf("stage %s is anchored to unknown stage %q", plugin.Stage.Name, plugin.Position.anchor)
+		}
+		known = append(known, plugin.Stage.Name)
+	}
+	for _, stage := range disabled {
+		if !slices.Contains(known, stage) {
+			return fmt.Errorf("cannot disable unknown stage %q", stage)
+		}
+	}
+	return nil
+}
+
+// buildPipeline inserts the plugins into the built-in steps and drops the
+// disabled ones. Plugins must have been validated.
+func buildPipeline(module *Module, steps []pipelineStep, plugins []StagePlugin, disabled []Stage) []pipelineStep {
+	for _, plugin := range plugins {
+		steps = slices.Insert(steps, insertIndex(steps, plugin.Position), plugin.Stage.step(module))
+	}
+
+	var pipeline []pipelineStep
+	for _, step := range steps {
+		if step.enabled && !slices.Contains(disabled, step.name) {
+			pipeline = append(pipeline, step)
+		}
+	}
+	return pipeline
+}
+
+func insertIndex(steps []pipelineStep, position StagePosition) int {
+	if i := slices.IndexFunc(steps, func(step pipelineStep) bool { return step.name == position.anchor }); i >= 0 {
+		if position.after {
+			return i + 1
+		}
+		return i
+	}
+
+	rank := slices.Index(stageOrder, position.anchor)
+	for i, step := range steps {
+		if slices.Index(stageOrder, step.name) > rank {
+			return i
+		}
+	}
+	return len(steps)
+}
+
+func (s PipelineStage) step(module *Module) pipelineStep {
+	return pipelineStep{
+		name:    s.Name,
+		enabled: true,
+		run: func(ctx context.Context, t testing.TB) error {
+			defer module.startStage(s.Name)()
+			if err := s.Run(ctx, t, module); err != nil {
+				return module.failApply(t, &ModuleError{ModuleName: module.Name, Operation: string(s.Name), Err: err})
+			}
+			return nil
+		},
+	}
+}
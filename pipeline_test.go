@@ -0,0 +1,198 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateStagePlugins(t *testing.T) {
+	run := func(ctx context.Context, t testing.TB, m *Module) error { return nil }
+
+	tests := []struct {
+		name     string
+		plugins  []StagePlugin
+		disabled []Stage
+		wantErr  bool
+	}{
+		{name: "none"},
+		{
+			name: "anchored to built-in and custom stages",
+			plugins: []StagePlugin{
+				{Position: AfterStage(StageApply), Stage: PipelineStage{Name: "lint", Run: run}},
+				{Position: BeforeStage("lint"), Stage: PipelineStage{Name: "format", Run: run}},
+			},
+		},
+		{name: "unknown anchor", plugins: []StagePlugin{{Position: AfterStage("lint"), Stage: PipelineStage{Name: "format", Run: run}}}, wantErr: true},
+		{name: "built-in name", plugins: []StagePlugin{{Position: AfterStage(StageApply), Stage: PipelineStage{Name: StageDrift, Run: run}}}, wantErr: true},
+		{name: "missing run", plugins: []StagePlugin{{Position: AfterStage(StageApply), Stage: PipelineStage{Name: "lint"}}}, wantErr: true},
+		{name: "disabled built-in and custom stages", plugins: []StagePlugin{{Position: AfterStage(StageApply), Stage: PipelineStage{Name: "lint", Run: run}}}, disabled: []Stage{StageValidate, "lint"}},
+		{name: "disabled unknown stage", disabled: []Stage{"valdiate"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateStagePlugins(tt.plugins, tt.disabled); (err != nil) != tt.wantErr {
+				t.Errorf("validateStagePlugins() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildPipeline(t *testing.T) {
+	steps := []pipelineStep{
+		{name: StageScan},
+		{name: StageInit, enabled: true},
+		{name: StageValidate, enabled: true},
+		{name: StagePlan, enabled: true},
+		{name: StageApply, enabled: true},
+		{name: StageDrift, enabled: true},
+		{name: StageUpgrade, enabled: true},
+		{name: StageDestroy, enabled: true},
+	}
+	stage := func(name Stage) PipelineStage {
+		return PipelineStage{Name: name, Run: func(ctx context.Context, t testing.TB, m *Module) error { return nil }}
+	}
+
+	tests := []struct {
+		name     string
+		plugins  []StagePlugin
+		disabled []Stage
+		want     []Stage
+	}{
+		{
+			name: "built-ins only",
+			want: []Stage{StageInit, StageValidate, StagePlan, StageApply, StageDrift, StageUpgrade, StageDestroy},
+		},
+		{
+			name:     "disabled built-in",
+			disabled: []Stage{StageDrift},
+			want:     []Stage{StageInit, StageValidate, StagePlan, StageApply, StageUpgrade, StageDestroy},
+		},
+		{
+			name: "before and after anchors",
+			plugins: []StagePlugin{
+				{Position: AfterStage(StageApply), Stage: stage("verify-dns")},
+				{Position: BeforeStage(StageApply), Stage: stage("lint")},
+				{Position: AfterStage(StageDestroy), Stage: stage("leftovers")},
+			},
+			want: []Stage{StageInit, StageValidate, StagePlan, "lint", StageApply, "verify-dns", StageDrift, StageUpgrade, StageDestroy, "leftovers"},
+		},
+		{
+			name:    "between plan and apply",
+			plugins: []StagePlugin{{Position: AfterStage(StagePlan), Stage: stage("cost")}, {Position: BeforeStage(StagePlan), Stage: stage("lint")}},
+			want:    []Stage{StageInit, StageValidate, "lint", StagePlan, "cost", StageApply, StageDrift, StageUpgrade, StageDestroy},
+		},
+		{
+			name:    "anchor not in the pipeline",
+			plugins: []StagePlugin{{Position: AfterStage(StageVerify), Stage: stage("smoke")}},
+			want:    []Stage{StageInit, StageValidate, StagePlan, StageApply, StageDrift, "smoke", StageUpgrade, StageDestroy},
+		},
+		{
+			name:     "disabled custom stage",
+			plugins:  []StagePlugin{{Position: AfterStage(StageApply), Stage: stage("lint")}},
+			disabled: []Stage{"lint"},
+			want:     []Stage{StageInit, StageValidate, StagePlan, StageApply, StageDrift, StageUpgrade, StageDestroy},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := buildPipeline(NewModule("default", "/tmp/default"), append([]pipelineStep(nil), steps...), tt.plugins, tt.disabled)
+
+			var names []Stage
+			for _, step := range pipeline {
+				names = append(names, step.name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("pipeline = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunModuleTests_CustomStages(t *testing.T) {
+	var calls []string
+	policies := t.TempDir()
+	writeFiles(t, policies, map[string]string{"main.rego": "package main\n"})
+	newModule := func() *Module {
+		module := NewModule("default", t.TempDir())
+		module.planHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+			calls = append(calls, "plan")
+			return []byte(`{}`), nil
+		}
+		module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+			calls = append(calls, "apply")
+			return nil
+		}
+		module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+			calls = append(calls, "destroy")
+			return nil
+		}
+		return module
+	}
+	stage := func(name Stage, err error) PipelineStage {
+		return PipelineStage{Name: name, Run: func(ctx context.Context, t testing.TB, m *Module) error {
+			calls = append(calls, string(name))
+			return err
+		}}
+	}
+
+	tests := []struct {
+		name       string
+		options    []Option
+		wantCalls  []string
+		wantFailed bool
+	}{
+		{
+			name: "custom stages around apply and destroy",
+			options: []Option{
+				WithStage(AfterStage(StageApply), stage("smoke", nil)),
+				WithStage(AfterStage(StageDestroy), stage("leftovers", nil)),
+			},
+			wantCalls: []string{"apply", "smoke", "destroy", "leftovers"},
+		},
+		{
+			name:       "failing stage skips the rest but still destroys",
+			options:    []Option{WithStage(BeforeStage(StageApply), stage("lint", errors.New("bad format")))},
+			wantCalls:  []string{"lint", "destroy"},
+			wantFailed: true,
+		},
+		{
+			name: "custom stages around plan",
+			options: []Option{
+				WithPolicyDir(policies),
+				WithStage(AfterStage(StagePlan), stage("cost", nil)),
+				WithStage(BeforeStage(StagePlan), stage("lint", nil)),
+			},
+			wantCalls: []string{"lint", "plan", "cost", "apply", "destroy"},
+		},
+		{
+			name:      "disabled plan",
+			options:   []Option{WithPolicyDir(policies), WithoutStage(StagePlan)},
+			wantCalls: []string{"apply", "destroy"},
+		},
+		{
+			name:      "disabled destroy",
+			options:   []Option{WithoutStage(StageDestroy)},
+			wantCalls: []string{"apply"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			module := newModule()
+			recorder := &recordingTB{TB: t}
+			runModuleTests(recorder, Runners([]*Module{module}), false, NewConfig(append(tt.options, WithExample(""))...), nil, "local")
+
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if failed := len(module.Errors) > 0; failed != tt.wantFailed {
+				t.Errorf("module failed = %v, want %v (errors: %v)", failed, tt.wantFailed, module.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+type planCheck struct {
+	operation string
+	run       func(ctx context.Context, t testing.TB, m *Module, planJSON []byte) error
+}
+
+func (m *Module) planFilePath() string {
+	return filepath.Join(m.Options.TerraformDir, ".terraform", "validor.tfplan")
+}
+
+func (m *Module) PlanJSONPath() string {
+	return filepath.Join(m.Options.TerraformDir, ".terraform", "validor-plan.json")
+}
+
+func (m *Module) Plan(ctx context.Context, t testing.TB) ([]byte, error) {
+	t.Helper()
+
+	if m.planJSON != nil {
+		return m.planJSON, nil
+	}
+	if err := m.remotePlanError(); err != nil {
+		return nil, err
+	}
+
+	var planJSON []byte
+	if m.planHook == nil && m.terragrunt == TerragruntStack {
+		return nil, fmt.Errorf("plan JSON is %w", errTerragruntStack)
+	}
+	if m.planHook != nil {
+		out, err := m.planHook(ctx, t, m)
+		if err != nil {
+			return nil, err
+		}
+		planJSON = out
+	} else {
+		opts := *m.Options
+		opts.PlanFilePath = m.planFilePath()
+		if _, err := terraform.PlanE(t, &opts); err != nil {
+			return nil, err
+		}
+		out, err := terraform.ShowE(t, &opts)
+		if err != nil {
+			return nil, err
+		}
+		planJSON = []byte(out)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.PlanJSONPath()), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create plan directory: %w", err)
+	}
+	if err := os.WriteFile(m.PlanJSONPath(), planJSON, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write plan json: %w", err)
+	}
+
+	m.planJSON = planJSON
+	return planJSON, nil
+}
+
+type resourceChange struct {
+	Address string `json:"address"`
+	Change  struct {
+		Actions []string `json:"actions"`
+	} `json:"change"`
+}
+
+func planResourceChanges(planJSON []byte) ([]resourceChange, error) {
+	var plan struct {
+		ResourceChanges []resourceChange `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(planJSON, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan json: %w", err)
+	}
+	return plan.ResourceChanges, nil
+}
+
+func (m *Module) runPlanChecks(ctx context.Context, t testing.TB) error {
+	t.Helper()
+
+	if len(m.planChecks) == 0 {
+		return nil
+	}
+
+	done := m.startStage(StagePlan)
+	defer done()
+
+	planJSON, err := m.Plan(ctx, t)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "terraform plan", Err: err})
+	}
+
+	for _, check := range m.planChecks {
+		if err := check.run(ctx, t, m, planJSON); err != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: check.operation, Err: err})
+		}
+	}
+	return nil
+}
+
+func (m *Module) failApply(t testing.TB, err *ModuleError) error {
+	t.Helper()
+	m.ApplyFailed = true
+	m.recordError(t, m.stage, err)
+	return err
+}
+
+func (m *Module) recordError(t testing.TB, stage Stage, err *ModuleError) {
+	t.Helper()
+	if m.failed == nil {
+		m.failed = make(map[Stage]error)
+	}
+	if m.failed[stage] == nil {
+		m.failed[stage] = err
+	}
+	m.recordFailure(stage, err)
+	message := m.masker.Mask(err.Error())
+	m.Errors = append(m.Errors, message)
+	t.Log(errorText(message))
+}
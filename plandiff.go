@@ -0,0 +1,224 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// PlanDifference is a resource whose planned change differs between the
+// registry and the local source of an example. Registry or Local is nil when
+// only the other plan changes the resource.
+type PlanDifference struct {
+	Address    string
+	Registry   []string
+	Local      []string
+	Attributes []string
+}
+
+func (d PlanDifference) String() string {
+	switch {
+	case d.Registry == nil:
+		return fmt.Sprintf("%s: only the local source plans %s", d.Address, strings.Join(d.Local, ", "))
+	case d.Local == nil:
+		return fmt.Sprintf("%s: only the registry source plans %s", d.Address, strings.Join(d.Registry, ", "))
+	case !slices.Equal(d.Registry, d.Local):
+		return fmt.Sprintf("%s: planned %s with the registry source, %s with the local source", d.Address, strings.Join(d.Registry, ", "), strings.Join(d.Local, ", "))
+	}
+	return fmt.Sprintf("%s: %s planned with different values for %s", d.Address, strings.Join(d.Local, ", "), strings.Join(d.Attributes, ", "))
+}
+
+type plannedResource struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+	Change  struct {
+		Actions      []string       `json:"actions"`
+		After        map[string]any `json:"after"`
+		AfterUnknown map[string]any `json:"after_unknown"`
+	} `json:"change"`
+}
+
+// DiffPlans compares the resource changes of a plan made with the registry
+// source to one made with the local source, ignoring no-ops and reads. The
+// differences are sorted by address.
+func DiffPlans(registryPlan, localPlan []byte) ([]PlanDifference, error) {
+	registry, err := plannedResources(registryPlan)
+	if err != nil {
+		return nil, fmt.Errorf("registry plan: %w", err)
+	}
+	local, err := plannedResources(localPlan)
+	if err != nil {
+		return nil, fmt.Errorf("local plan: %w", err)
+	}
+
+	var differences []PlanDifference
+	for address, before := range registry {
+		after, ok := local[address]
+		if !ok {
+			differences = append(differences, PlanDifference{Address: address, Registry: before.Change.Actions})
+			continue
+		}
+		diff := PlanDifference{Address: address, Registry: before.Change.Actions, Local: after.Change.Actions}
+		if slices.Equal(diff.Registry, diff.Local) {
+			diff.Attributes = changedAttributes(before, after)
+			if len(diff.Attributes) == 0 {
+				continue
+			}
+		}
+		differences = append(differences, diff)
+	}
+	for address, after := range local {
+		if _, ok := registry[address]; !ok {
+			differences = append(differences, PlanDifference{Address: address, Local: after.Change.Actions})
+		}
+	}
+	slices.SortFunc(differences, func(a, b PlanDifference) int { return strings.Compare(a.Address, b.Address) })
+	return differences, nil
+}
+
+func plannedResources(planJSON []byte) (map[string]plannedResource, error) {
+	var plan struct {
+		ResourceChanges []plannedResource `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(planJSON, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan json: %w", err)
+	}
+	resources := make(map[string]plannedResource)
+	for _, rc := range plan.ResourceChanges {
+		if len(planActions(rc.Change.Actions)) > 0 {
+			resources[rc.Address] = rc
+		}
+	}
+	return resources, nil
+}
+
+// changedAttributes returns the top-level attributes whose planned values, or
+// whether they are known after apply, differ.
+func changedAttributes(a, b plannedResource) []string {
+	var names []string
+	for _, values := range []map[string]any{a.Change.After, b.Change.After, a.Change.AfterUnknown, b.Change.AfterUnknown} {
+		for name := range values {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	var changed []string
+	for _, name := range names {
+		if !reflect.DeepEqual(a.Change.After[name], b.Change.After[name]) ||
+			!reflect.DeepEqual(a.Change.AfterUnknown[name], b.Change.AfterUnknown[name]) {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// DiffPlan plans the example with its registry source and again with the
+// local source, and returns how the resource changes differ. Nothing is
+// applied, and the example's files are restored before it returns.
+func (m *Module) DiffPlan(ctx context.Context, t testing.TB, moduleInfo ModuleInfo) ([]PlanDifference, error) {
+	t.Helper()
+	defer m.startStage(StagePlan)()
+
+	m.planJSON = nil
+	if m.planHook == nil {
+		if err := m.init(t); err != nil {
+			return nil, m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "plan diff init", Err: err})
+		}
+	}
+	registryPlan, err := m.Plan(ctx, t)
+	if err != nil {
+		return nil, m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "plan diff registry plan", Err: err})
+	}
+
+	filesToRestore, err := NewSourceConverter(nil).ConvertToLocal(ctx, m.Path, moduleInfo)
+	defer func() {
+		for _, restore := range filesToRestore {
+			if err := os.WriteFile(restore.Path, []byte(restore.OriginalContent), 0644); err != nil {
+				t.Logf("Warning: failed to restore %s: %v", restore.Path, err)
+			}
+		}
+	}()
+	if err == nil && len(filesToRestore) == 0 {
+		err = fmt.Errorf("no registry sources for %s/%s/%s found", moduleInfo.Namespace, moduleInfo.Name, moduleInfo.Provider)
+	}
+	if err != nil {
+		return nil, m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "plan diff", Err: err})
+	}
+
+	m.planJSON = nil
+	if m.planHook == nil {
+		if err := m.init(t); err != nil {
+			return nil, m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "plan diff init", Err: err})
+		}
+	}
+	localPlan, err := m.Plan(ctx, t)
+	if err != nil {
+		return nil, m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "plan diff local plan", Err: err})
+	}
+
+	differences, err := DiffPlans(registryPlan, localPlan)
+	if err != nil {
+		return nil, m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "plan diff", Err: err})
+	}
+	return differences, nil
+}
+
+// TestPlanDiff plans every example with its registry source and with the local
+// source and logs how the planned resource changes differ, showing what a
+// release would change for its users. Nothing is applied.
+func TestPlanDiff(t testing.TB, opts ...Option) {
+	config := setupConfigWithOptions(opts...)
+	var modules []*Module
+	if config.Example != "" {
+		modules = createModulesFromNames(parseExampleList(config.Example), getExamplesPath(config))
+	} else {
+		modules = discoverModules(t, config)
+	}
+	diffPlans(t, config, modules)
+}
+
+func diffPlans(t testing.TB, config *Config, modules []*Module) {
+	ctx := context.Background()
+
+	repoInfo := config.moduleInfo()
+	repoInfo.Root = filepath.Dir(getExamplesPath(config))
+
+	for _, module := range modules {
+		if slices.Contains(config.ExceptionList, module.Name) {
+			continue
+		}
+
+		moduleInfo := repoInfo
+		if module.info != nil {
+			moduleInfo = *module.info
+		}
+		if moduleInfo.Name == "" || moduleInfo.Provider == "" {
+			t.Fatal(errorText("could not determine module name and provider from repository"))
+		}
+
+		runSubtest(t, module.Name, false, func(t testing.TB) {
+			differences, err := module.DiffPlan(ctx, t, moduleInfo)
+			if err != nil {
+				t.Error(errorText(fmt.Sprintf("Failed to diff the plans of %s: %v", module.Name, err)))
+				return
+			}
+			if len(differences) == 0 {
+				t.Log(successText(fmt.Sprintf("✓ %s: the local source plans the same changes as the registry source", module.Name)))
+				return
+			}
+			t.Logf("%s: the local source plans %d different resource change(s):", module.Name, len(differences))
+			for _, difference := range differences {
+				t.Logf("  %s", difference)
+			}
+		})
+	}
+}
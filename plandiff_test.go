@@ -0,0 +1,101 @@
+package validor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffPlans(t *testing.T) {
+	registry := `{"resource_changes":[
+		{"address":"azurerm_resource_group.rg","change":{"actions":["create"],"after":{"name":"rg","location":"westeurope"}}},
+		{"address":"azurerm_storage_account.sa","change":{"actions":["create"],"after":{"name":"sa","tier":"Standard"},"after_unknown":{"id":true}}},
+		{"address":"azurerm_subnet.sn","change":{"actions":["create"],"after":{"name":"sn"}}},
+		{"address":"azurerm_key_vault.kv","change":{"actions":["no-op"],"after":{"name":"kv"}}},
+		{"address":"data.azurerm_client_config.current","change":{"actions":["read"]}}
+	]}`
+	local := `{"resource_changes":[
+		{"address":"azurerm_resource_group.rg","change":{"actions":["create"],"after":{"name":"rg","location":"westeurope"}}},
+		{"address":"azurerm_storage_account.sa","change":{"actions":["create"],"after":{"name":"sa","tier":"Premium"},"after_unknown":{"id":true}}},
+		{"address":"azurerm_key_vault.kv","change":{"actions":["update"],"after":{"name":"kv"}}},
+		{"address":"azurerm_private_endpoint.pe","change":{"actions":["create"],"after":{"name":"pe"}}}
+	]}`
+
+	got, err := DiffPlans([]byte(registry), []byte(local))
+	if err != nil {
+		t.Fatalf("DiffPlans() error = %v", err)
+	}
+	want := []PlanDifference{
+		{Address: "azurerm_key_vault.kv", Local: []string{"update"}},
+		{Address: "azurerm_private_endpoint.pe", Local: []string{"create"}},
+		{Address: "azurerm_storage_account.sa", Registry: []string{"create"}, Local: []string{"create"}, Attributes: []string{"tier"}},
+		{Address: "azurerm_subnet.sn", Registry: []string{"create"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffPlans() = %+v, want %+v", got, want)
+	}
+
+	wantText := []string{
+		"azurerm_key_vault.kv: only the local source plans update",
+		"azurerm_private_endpoint.pe: only the local source plans create",
+		"azurerm_storage_account.sa: create planned with different values for tier",
+		"azurerm_subnet.sn: only the registry source plans create",
+	}
+	for i, difference := range got {
+		if difference.String() != wantText[i] {
+			t.Errorf("String() = %q, want %q", difference.String(), wantText[i])
+		}
+	}
+
+	replaced := PlanDifference{Address: "azurerm_subnet.sn", Registry: []string{"update"}, Local: []string{"delete", "create"}}
+	if want := "azurerm_subnet.sn: planned update with the registry source, delete, create with the local source"; replaced.String() != want {
+		t.Errorf("String() = %q, want %q", replaced.String(), want)
+	}
+
+	if _, err := DiffPlans([]byte(`{`), []byte(local)); err == nil {
+		t.Error("expected an error for an invalid plan")
+	}
+}
+
+func TestModule_DiffPlan(t *testing.T) {
+	original := "module \"test\" {\n  source  = \"cloudnationhq/mymodule/azure\"\n  version = \"~> 1.0\"\n}\n"
+	moduleInfo := ModuleInfo{Name: "mymodule", Provider: "azure", Namespace: "cloudnationhq"}
+	dir := filepath.Join(t.TempDir(), "examples", "default")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create example dir: %v", err)
+	}
+	tfFile := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(tfFile, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create terraform file: %v", err)
+	}
+
+	module := NewModule("default", dir)
+	module.planHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+		content, _ := os.ReadFile(tfFile)
+		if strings.Contains(string(content), `source = "../../"`) {
+			return []byte(`{"resource_changes":[{"address":"azurerm_resource_group.rg","change":{"actions":["create"],"after":{"name":"rg-local"}}}]}`), nil
+		}
+		return []byte(`{"resource_changes":[{"address":"azurerm_resource_group.rg","change":{"actions":["create"],"after":{"name":"rg"}}}]}`), nil
+	}
+
+	differences, err := module.DiffPlan(context.Background(), t, moduleInfo)
+	if err != nil {
+		t.Fatalf("DiffPlan() error = %v", err)
+	}
+	want := []PlanDifference{{Address: "azurerm_resource_group.rg", Registry: []string{"create"}, Local: []string{"create"}, Attributes: []string{"name"}}}
+	if !reflect.DeepEqual(differences, want) {
+		t.Errorf("DiffPlan() = %+v, want %+v", differences, want)
+	}
+	if content, _ := os.ReadFile(tfFile); string(content) != original {
+		t.Errorf("the original sources should be restored, got: %s", content)
+	}
+
+	other := NewModule("other", t.TempDir())
+	other.planHook = module.planHook
+	if _, err := other.DiffPlan(context.Background(), t, moduleInfo); err == nil || !other.ApplyFailed {
+		t.Errorf("DiffPlan() should fail without a registry source, got %v", err)
+	}
+}
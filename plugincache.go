@@ -0,0 +1,80 @@
+package validor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+func WithPluginCache(enabled bool) Option {
+	return func(c *Config) { c.DisablePluginCache = !enabled }
+}
+
+func WithPluginCacheDir(dir string) Option {
+	return func(c *Config) { c.PluginCacheDir = dir }
+}
+
+func WithPluginCachePrewarm(prewarm bool) Option {
+	return func(c *Config) { c.PrewarmPluginCache = prewarm }
+}
+
+// Terraform does not lock the plugin cache, so concurrent inits that install
+// the same provider can corrupt it. Inits sharing the cache are serialized.
+var pluginCacheMu sync.Mutex
+
+func pluginCacheDir(config *Config) (string, error) {
+	if config.DisablePluginCache {
+		return "", nil
+	}
+
+	dir := config.PluginCacheDir
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine user cache directory: %w", err)
+		}
+		dir = filepath.Join(cacheDir, "validor", "plugin-cache")
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve plugin cache directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create plugin cache directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+func (m *Module) UsePluginCache(dir string) {
+	if m.Options.EnvVars == nil {
+		m.Options.EnvVars = make(map[string]string)
+	}
+	m.Options.EnvVars["TF_PLUGIN_CACHE_DIR"] = dir
+	// Examples rarely commit a lock file; without this Terraform 1.4+ ignores the cache.
+	m.Options.EnvVars["TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE"] = "true"
+	m.initLock = &pluginCacheMu
+}
+
+var runProviderInit = func(t testing.TB, options *terraform.Options) error {
+	_, err := terraform.RunTerraformCommandE(t, options, "init", "-backend=false", "-input=false")
+	return err
+}
+
+// prewarmPluginCache installs every module's providers into the cache one at a
+// time, so parallel runs start with the cache already populated.
+func prewarmPluginCache(t testing.TB, modules []*Module, config *Config) {
+	for _, module := range modules {
+		if slices.Contains(config.ExceptionList, module.Name) {
+			continue
+		}
+		if err := runProviderInit(t, module.Options); err != nil {
+			t.Logf("Warning: failed to pre-warm plugin cache for %s: %v", module.Name, err)
+		}
+	}
+}
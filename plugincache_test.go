@@ -0,0 +1,92 @@
+package validor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+func TestPluginCacheDir(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		dir, err := pluginCacheDir(NewConfig(WithPluginCache(false)))
+		if err != nil || dir != "" {
+			t.Fatalf("pluginCacheDir() = %q, %v; want disabled", dir, err)
+		}
+	})
+
+	t.Run("explicit directory", func(t *testing.T) {
+		want := filepath.Join(t.TempDir(), "cache")
+		dir, err := pluginCacheDir(NewConfig(WithPluginCacheDir(want)))
+		if err != nil {
+			t.Fatalf("pluginCacheDir() error = %v", err)
+		}
+		if dir != want {
+			t.Errorf("pluginCacheDir() = %q, want %q", dir, want)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Errorf("plugin cache directory should be created: %v", err)
+		}
+	})
+
+	t.Run("default directory", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("XDG_CACHE_HOME only applies on linux")
+		}
+		home := t.TempDir()
+		t.Setenv("XDG_CACHE_HOME", home)
+
+		dir, err := pluginCacheDir(NewConfig())
+		if err != nil {
+			t.Fatalf("pluginCacheDir() error = %v", err)
+		}
+		if want := filepath.Join(home, "validor", "plugin-cache"); dir != want {
+			t.Errorf("pluginCacheDir() = %q, want %q", dir, want)
+		}
+	})
+}
+
+func TestModule_UsePluginCache(t *testing.T) {
+	module := NewModule("example", "/tmp/example")
+	module.UsePluginCache("/tmp/cache")
+
+	if got := module.Options.EnvVars["TF_PLUGIN_CACHE_DIR"]; got != "/tmp/cache" {
+		t.Errorf("TF_PLUGIN_CACHE_DIR = %q, want /tmp/cache", got)
+	}
+	if got := module.Options.EnvVars["TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE"]; got != "true" {
+		t.Errorf("TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE = %q, want true", got)
+	}
+	if module.initLock != &pluginCacheMu {
+		t.Error("modules sharing the plugin cache should serialize init")
+	}
+}
+
+func TestPrewarmPluginCache(t *testing.T) {
+	var initialized []string
+	original := runProviderInit
+	runProviderInit = func(t testing.TB, options *terraform.Options) error {
+		initialized = append(initialized, options.TerraformDir)
+		if options.TerraformDir == "/examples/broken" {
+			return errors.New("registry unavailable")
+		}
+		return nil
+	}
+	t.Cleanup(func() { runProviderInit = original })
+
+	modules := []*Module{
+		NewModule("default", "/examples/default"),
+		NewModule("skipped", "/examples/skipped"),
+		NewModule("broken", "/examples/broken"),
+	}
+	config := NewConfig(WithException("skipped"))
+
+	prewarmPluginCache(t, modules, config)
+
+	want := []string{"/examples/default", "/examples/broken"}
+	if len(initialized) != len(want) || initialized[0] != want[0] || initialized[1] != want[1] {
+		t.Errorf("initialized = %v, want %v", initialized, want)
+	}
+}
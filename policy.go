@@ -0,0 +1,89 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+const defaultPolicyQuery = "data.main.deny"
+
+func WithPolicyDir(path string) Option {
+	return func(c *Config) { c.PolicyDir = path }
+}
+
+func WithPolicyQuery(query string) Option {
+	return func(c *Config) { c.PolicyQuery = query }
+}
+
+type PolicyViolationError struct {
+	Violations []string
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("%d policy violation(s): %s", len(e.Violations), strings.Join(e.Violations, "; "))
+}
+
+// loadPolicies compiles the Rego policies in policyDir for query once per
+// run, so an invalid policy fails the run before any example is applied.
+func loadPolicies(ctx context.Context, policyDir, query string) (*rego.PreparedEvalQuery, error) {
+	if query == "" {
+		query = defaultPolicyQuery
+	}
+	prepared, err := rego.New(rego.Query(query), rego.Load([]string{policyDir}, nil)).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policies from %s: %w", policyDir, err)
+	}
+	return &prepared, nil
+}
+
+func policyViolations(results rego.ResultSet) []string {
+	var violations []string
+	for _, r := range results {
+		for _, expr := range r.Expressions {
+			items, ok := expr.Value.([]any)
+			if !ok {
+				continue
+			}
+			for _, item := range items {
+				switch v := item.(type) {
+				case string:
+					violations = append(violations, v)
+				case map[string]any:
+					if msg, ok := v["msg"].(string); ok {
+						violations = append(violations, msg)
+					} else {
+						encoded, _ := json.Marshal(v)
+						violations = append(violations, string(encoded))
+					}
+				}
+			}
+		}
+	}
+	return violations
+}
+
+func policyCheck(policies *rego.PreparedEvalQuery) planCheck {
+	return planCheck{
+		operation: "policy evaluation",
+		run: func(ctx context.Context, t testing.TB, m *Module, planJSON []byte) error {
+			var input any
+			if err := json.Unmarshal(planJSON, &input); err != nil {
+				return fmt.Errorf("failed to parse plan JSON: %w", err)
+			}
+
+			results, err := policies.Eval(ctx, rego.EvalInput(input))
+			if err != nil {
+				return fmt.Errorf("policy evaluation failed: %w", err)
+			}
+			if violations := policyViolations(results); len(violations) > 0 {
+				return &PolicyViolationError{Violations: violations}
+			}
+			return nil
+		},
+	}
+}
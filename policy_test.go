@@ -0,0 +1,118 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testPolicy = `package main
+
+deny contains msg if {
+	some change in input.resource_changes
+	change.type == "azurerm_public_ip"
+	msg := sprintf("public ip not allowed: %s", [change.address])
+}
+
+deny contains {"msg": "missing tag owner", "resource": change.address} if {
+	some change in input.resource_changes
+	not change.change.after.tags.owner
+}
+`
+
+func TestPolicyViolations(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.rego": testPolicy})
+	policies, err := loadPolicies(context.Background(), dir, "")
+	if err != nil {
+		t.Fatalf("loadPolicies() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		plan string
+		want []string
+	}{
+		{
+			name: "string and object messages",
+			plan: `{"resource_changes":[{"address":"azurerm_public_ip.pip","type":"azurerm_public_ip","change":{"after":{"tags":{"owner":"team"}}}},{"address":"azurerm_resource_group.rg","type":"azurerm_resource_group","change":{"after":{}}}]}`,
+			want: []string{"public ip not allowed: azurerm_public_ip.pip", "missing tag owner"},
+		},
+		{
+			name: "no violations",
+			plan: `{"resource_changes":[{"address":"azurerm_resource_group.rg","type":"azurerm_resource_group","change":{"after":{"tags":{"owner":"team"}}}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policyCheck(policies).run(context.Background(), t, nil, []byte(tt.plan))
+			var violation *PolicyViolationError
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("run() error = %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &violation) {
+				t.Fatalf("run() error = %v, want a PolicyViolationError", err)
+			}
+			if strings.Join(violation.Violations, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("violations = %v, want %v", violation.Violations, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPolicies_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.rego": "package main\n\ndeny contains msg if {\n"})
+	if _, err := loadPolicies(context.Background(), dir, ""); err == nil || !strings.Contains(err.Error(), dir) {
+		t.Fatalf("loadPolicies() error = %v, want a parse error naming the policy directory", err)
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"terraform.rego": "package terraform\n\ndeny contains \"plans are not allowed\" if input.format_version\n"})
+
+	t.Run("violations block apply", func(t *testing.T) {
+		policies, err := loadPolicies(context.Background(), dir, "data.terraform.deny")
+		if err != nil {
+			t.Fatalf("loadPolicies() error = %v", err)
+		}
+		module := newPlannedModule(t, "public")
+		applied := false
+		module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+			applied = true
+			return nil
+		}
+		module.planChecks = []planCheck{policyCheck(policies)}
+
+		err = module.Apply(context.Background(), t)
+		var violation *PolicyViolationError
+		if !errors.As(err, &violation) || len(violation.Violations) != 1 {
+			t.Fatalf("expected PolicyViolationError, got %v", err)
+		}
+		if applied {
+			t.Error("apply should not run when policies are violated")
+		}
+		if !strings.Contains(module.Errors[0], "plans are not allowed") {
+			t.Errorf("expected violation in module errors, got %v", module.Errors)
+		}
+	})
+
+	t.Run("compliant plan applies", func(t *testing.T) {
+		policies, err := loadPolicies(context.Background(), dir, "")
+		if err != nil {
+			t.Fatalf("loadPolicies() error = %v", err)
+		}
+		module := newPlannedModule(t, "private")
+		module.planChecks = []planCheck{policyCheck(policies)}
+
+		if err := module.Apply(context.Background(), t); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+)
+
+// PreDestroyHook runs before an example is destroyed, for the out-of-band
+// steps some resources need before terraform can delete them. It gets the
+// state of the example.
+type PreDestroyHook func(ctx context.Context, t testing.TB, state *State) error
+
+// WithPreDestroyHooks runs hooks, in order, before the example is destroyed.
+// A failing hook is reported, but the destroy is still attempted.
+func WithPreDestroyHooks(example string, hooks ...PreDestroyHook) Option {
+	return func(c *Config) {
+		if c.PreDestroyHooks == nil {
+			c.PreDestroyHooks = make(map[string][]PreDestroyHook)
+		}
+		c.PreDestroyHooks[example] = append(c.PreDestroyHooks[example], hooks...)
+	}
+}
+
+var runHookCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil && len(output) > 0 {
+		return output, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return output, err
+}
+
+// RunCommand is a pre-destroy hook that runs a command, such as a script that
+// prepares the example's resources for deletion.
+func RunCommand(name string, args ...string) PreDestroyHook {
+	return func(ctx context.Context, t testing.TB, state *State) error {
+		t.Logf("Running %s %s", name, strings.Join(args, " "))
+		if _, err := runHookCommand(ctx, name, args...); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	}
+}
+
+// EmptyStorageContainers is a pre-destroy hook that deletes the blobs in every
+// azurerm_storage_container of the example with the az CLI, for containers
+// whose immutability or legal hold settings block deleting them with content.
+func EmptyStorageContainers() PreDestroyHook {
+	return func(ctx context.Context, t testing.TB, state *State) error {
+		var errs []error
+		for _, values := range resourcesOfType(state, "azurerm_storage_container") {
+			container, _ := values["name"].(string)
+			account, _ := values["storage_account_name"].(string)
+			if id, _ := values["storage_account_id"].(string); account == "" && id != "" {
+				account = path.Base(id)
+			}
+			if container == "" || account == "" {
+				continue
+			}
+			t.Logf("Emptying storage container %s in %s", container, account)
+			if _, err := runHookCommand(ctx, "az", "storage", "blob", "delete-batch", "--account-name", account, "--source", container, "--auth-mode", "login"); err != nil {
+				errs = append(errs, fmt.Errorf("empty storage container %s: %w", container, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// RemoveManagementLocks is a pre-destroy hook that deletes every
+// azurerm_management_lock of the example with the az CLI, since a CanNotDelete
+// lock blocks deleting the resource group it is on before terraform reaches the
+// lock itself.
+func RemoveManagementLocks() PreDestroyHook {
+	return func(ctx context.Context, t testing.TB, state *State) error {
+		var errs []error
+		for _, values := range resourcesOfType(state, "azurerm_management_lock") {
+			id, _ := values["id"].(string)
+			if id == "" {
+				continue
+			}
+			t.Logf("Removing management lock %s", id)
+			if _, err := runHookCommand(ctx, "az", "lock", "delete", "--ids", id); err != nil {
+				errs = append(errs, fmt.Errorf("remove management lock %s: %w", id, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// EmptyS3Buckets is a pre-destroy hook that deletes the objects in every
+// aws_s3_bucket of the example with the aws CLI, for buckets created without
+// force_destroy.
+func EmptyS3Buckets() PreDestroyHook {
+	return func(ctx context.Context, t testing.TB, state *State) error {
+		var errs []error
+		for _, values := range resourcesOfType(state, "aws_s3_bucket") {
+			bucket, _ := values["bucket"].(string)
+			if bucket == "" {
+				continue
+			}
+			t.Logf("Emptying S3 bucket %s", bucket)
+			if _, err := runHookCommand(ctx, "aws", "s3", "rm", "s3://"+bucket, "--recursive"); err != nil {
+				errs = append(errs, fmt.Errorf("empty S3 bucket %s: %w", bucket, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// resourcesOfType returns the values of the managed resources of a type, in
+// any module, in the order of the state.
+func resourcesOfType(state *State, resourceType string) []map[string]any {
+	var resources []map[string]any
+	for _, address := range state.Addresses {
+		parts := strings.Split(address, ".")
+		i := 0
+		for i+2 < len(parts) && parts[i] == "module" {
+			i += 2
+		}
+		if parts[i] == resourceType {
+			resources = append(resources, state.Resources[address])
+		}
+	}
+	return resources
+}
+
+// runPreDestroyHooks runs the module's pre-destroy hooks against its state.
+func (m *Module) runPreDestroyHooks(ctx context.Context, t testing.TB) error {
+	if len(m.preDestroy) == 0 {
+		return nil
+	}
+	state, err := m.State(ctx, t)
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	var errs []error
+	for _, hook := range m.preDestroy {
+		if err := hook(ctx, t, state); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// preDestroyHooks runs the module's pre-destroy hooks, recording a failure
+// without stopping the destroy.
+func (m *Module) preDestroyHooks(ctx context.Context, t testing.TB) {
+	if err := m.runPreDestroyHooks(ctx, t); err != nil && !m.ApplyFailed {
+		m.recordError(t, StageDestroy, &ModuleError{ModuleName: m.Name, Operation: "pre-destroy hook", Err: err})
+	}
+}
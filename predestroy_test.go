@@ -0,0 +1,132 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func stubHookCommand(t *testing.T, fail string) *[]string {
+	t.Helper()
+	var commands []string
+	original := runHookCommand
+	runHookCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		command := strings.Join(append([]string{name}, args...), " ")
+		commands = append(commands, command)
+		if fail != "" && strings.Contains(command, fail) {
+			return nil, errors.New("forbidden")
+		}
+		return nil, nil
+	}
+	t.Cleanup(func() { runHookCommand = original })
+	return &commands
+}
+
+func TestPreDestroyHooks(t *testing.T) {
+	state := &State{
+		Addresses: []string{
+			"azurerm_storage_container.logs",
+			"module.storage.azurerm_storage_container.data[0]",
+			"azurerm_management_lock.rg",
+			"aws_s3_bucket.artifacts",
+			"data.azurerm_storage_container.existing",
+		},
+		Resources: map[string]map[string]any{
+			"azurerm_storage_container.logs":                   {"name": "logs", "storage_account_name": "stlogs"},
+			"module.storage.azurerm_storage_container.data[0]": {"name": "data", "storage_account_id": "/subscriptions/x/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/stdata"},
+			"azurerm_management_lock.rg":                       {"id": "/subscriptions/x/resourceGroups/rg/providers/Microsoft.Authorization/locks/nodelete"},
+			"aws_s3_bucket.artifacts":                          {"bucket": "artifacts"},
+			"data.azurerm_storage_container.existing":          {"name": "existing", "storage_account_name": "stshared"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		hook    PreDestroyHook
+		fail    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "empty storage containers",
+			hook: EmptyStorageContainers(),
+			want: []string{
+				"az storage blob delete-batch --account-name stlogs --source logs --auth-mode login",
+				"az storage blob delete-batch --account-name stdata --source data --auth-mode login",
+			},
+		},
+		{
+			name: "remove management locks",
+			hook: RemoveManagementLocks(),
+			want: []string{"az lock delete --ids /subscriptions/x/resourceGroups/rg/providers/Microsoft.Authorization/locks/nodelete"},
+		},
+		{
+			name: "empty s3 buckets",
+			hook: EmptyS3Buckets(),
+			want: []string{"aws s3 rm s3://artifacts --recursive"},
+		},
+		{
+			name: "run command",
+			hook: RunCommand("./scripts/unprotect.sh", "rg"),
+			want: []string{"./scripts/unprotect.sh rg"},
+		},
+		{
+			name:    "continues after a failure",
+			hook:    EmptyStorageContainers(),
+			fail:    "stlogs",
+			wantErr: true,
+			want: []string{
+				"az storage blob delete-batch --account-name stlogs --source logs --auth-mode login",
+				"az storage blob delete-batch --account-name stdata --source data --auth-mode login",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands := stubHookCommand(t, tt.fail)
+			err := tt.hook(context.Background(), t, state)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("hook error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(*commands, tt.want) {
+				t.Errorf("commands = %q, want %q", *commands, tt.want)
+			}
+		})
+	}
+}
+
+func TestModule_DestroyRunsPreDestroyHooks(t *testing.T) {
+	module := NewModule("default", t.TempDir())
+	var order []string
+	module.stateHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+		if slices.Contains(order, "destroy") {
+			return []byte(`{}`), nil
+		}
+		return []byte(`{"values":{"root_module":{"resources":[{"address":"azurerm_resource_group.rg","values":{"name":"rg"}}]}}}`), nil
+	}
+	module.destroyHook = func(ctx context.Context, t testing.TB, m *Module) error {
+		order = append(order, "destroy")
+		return nil
+	}
+	module.preDestroy = []PreDestroyHook{
+		func(ctx context.Context, t testing.TB, state *State) error {
+			order = append(order, "hook "+state.Addresses[0])
+			return errors.New("lock still held")
+		},
+	}
+
+	recorder := &recordingTB{TB: t}
+	if err := module.Destroy(context.Background(), recorder); err != nil {
+		t.Fatalf("Destroy() error = %v", err)
+	}
+	if want := []string{"hook azurerm_resource_group.rg", "destroy"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if len(module.Errors) != 1 || !strings.Contains(module.Errors[0], "pre-destroy hook") {
+		t.Errorf("errors = %v, want the failed hook recorded", module.Errors)
+	}
+}
@@ -0,0 +1,205 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// PreflightCheck verifies the environment before any example is touched.
+type PreflightCheck struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// WithPreflight enables the built-in preflight checks: the terraform binary is
+// on PATH and every example directory exists.
+func WithPreflight(enabled bool) Option {
+	return func(c *Config) { c.Preflight = enabled }
+}
+
+func WithPreflightCheck(checks ...PreflightCheck) Option {
+	return func(c *Config) { c.PreflightChecks = append(c.PreflightChecks, checks...) }
+}
+
+// WithTerraformVersion requires the terraform binary to satisfy constraint,
+// e.g. ">= 1.6, < 2.0".
+func WithTerraformVersion(constraint string) Option {
+	return func(c *Config) { c.TerraformVersion = constraint }
+}
+
+func WithRequiredEnv(names ...string) Option {
+	return func(c *Config) { c.RequiredEnv = append(c.RequiredEnv, names...) }
+}
+
+// WithCredentialCheck verifies the credentials of the given clouds (azure, aws,
+// gcp) with their CLI before any example runs.
+func WithCredentialCheck(clouds ...string) Option {
+	return func(c *Config) { c.Credentials = append(c.Credentials, clouds...) }
+}
+
+func listFlag(values *[]string) func(string) error {
+	return func(value string) error {
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*values = append(*values, item)
+			}
+		}
+		return nil
+	}
+}
+
+var lookPath = exec.LookPath
+
+var runPreflightCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return output, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return output, err
+}
+
+func TerraformCheck(binary, constraint string) PreflightCheck {
+	return PreflightCheck{
+		Name: "terraform",
+		Run: func(ctx context.Context) error {
+			if _, err := lookPath(binary); err != nil {
+				return fmt.Errorf("%s not found on PATH", binary)
+			}
+			if constraint == "" {
+				return nil
+			}
+			constraints, err := version.NewConstraint(constraint)
+			if err != nil {
+				return fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+			}
+			output, err := runPreflightCommand(ctx, binary, "version", "-json")
+			if err != nil {
+				return fmt.Errorf("failed to get version: %w", err)
+			}
+			var info struct {
+				Version string `json:"terraform_version"`
+			}
+			if err := json.Unmarshal(output, &info); err != nil {
+				return fmt.Errorf("failed to parse version output: %w", err)
+			}
+			current, err := version.NewVersion(info.Version)
+			if err != nil {
+				return fmt.Errorf("invalid version %q: %w", info.Version, err)
+			}
+			if !constraints.Check(current) {
+				return fmt.Errorf("version %s does not satisfy %s", current, constraint)
+			}
+			return nil
+		},
+	}
+}
+
+func EnvCheck(names ...string) PreflightCheck {
+	return PreflightCheck{
+		Name: "environment",
+		Run: func(ctx context.Context) error {
+			var missing []string
+			for _, name := range names {
+				if os.Getenv(name) == "" {
+					missing = append(missing, name)
+				}
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("required variables not set: %s", strings.Join(missing, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// CredentialCheck verifies the credentials of cloud with the validator
+// registered for it.
+func CredentialCheck(cloud string) PreflightCheck {
+	return credentialCheck(cloud, nil)
+}
+
+// credentialCheck is CredentialCheck, passing the resolved identity to record
+// when it is not nil.
+func credentialCheck(cloud string, record func(Identity)) PreflightCheck {
+	return PreflightCheck{
+		Name: cloud + " credentials",
+		Run: func(ctx context.Context) error {
+			validator, err := credentialValidator(cloud)
+			if err != nil {
+				return err
+			}
+			identity, err := validator(ctx)
+			if err != nil {
+				return err
+			}
+			if record != nil {
+				record(identity)
+			}
+			return nil
+		},
+	}
+}
+
+func examplesCheck(modules []*Module) PreflightCheck {
+	return PreflightCheck{
+		Name: "examples",
+		Run: func(ctx context.Context) error {
+			var missing []string
+			for _, module := range modules {
+				if info, err := os.Stat(module.Options.TerraformDir); err != nil || !info.IsDir() {
+					missing = append(missing, module.Options.TerraformDir)
+				}
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("example directories not found: %s", strings.Join(missing, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+func preflightChecks(config *Config, modules []*Module, binary string, record func(Identity)) []PreflightCheck {
+	if binary == "" {
+		binary = "terraform"
+	}
+	var checks []PreflightCheck
+	if config.ContainerImage != "" {
+		checks = append(checks, containerCheck(config.containerRuntime()))
+	} else if config.Preflight || config.TerraformVersion != "" {
+		checks = append(checks, TerraformCheck(binary, config.TerraformVersion))
+	}
+	if config.Preflight {
+		checks = append(checks, examplesCheck(modules))
+	}
+	if len(config.RequiredEnv) > 0 {
+		checks = append(checks, EnvCheck(config.RequiredEnv...))
+	}
+	for _, cloud := range config.Credentials {
+		checks = append(checks, credentialCheck(cloud, record))
+	}
+	for _, hostname := range remoteHostnames(modules) {
+		checks = append(checks, RemoteTokenCheck(hostname))
+	}
+	return append(checks, config.PreflightChecks...)
+}
+
+// RunPreflight runs every check and returns all failures together, so a
+// broken environment is reported once instead of by every example.
+func RunPreflight(ctx context.Context, checks []PreflightCheck) error {
+	var errs []error
+	for _, check := range checks {
+		if err := check.Run(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", check.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,149 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestTerraformCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		found      bool
+		output     string
+		constraint string
+		wantErr    string
+	}{
+		{name: "found without constraint", found: true},
+		{name: "missing binary", wantErr: "not found on PATH"},
+		{name: "satisfies constraint", found: true, output: `{"terraform_version":"1.9.5"}`, constraint: ">= 1.6"},
+		{name: "too old", found: true, output: `{"terraform_version":"1.5.7"}`, constraint: ">= 1.6", wantErr: "does not satisfy"},
+		{name: "invalid constraint", found: true, constraint: "latest", wantErr: "invalid version constraint"},
+	}
+
+	origLookPath, origRun := lookPath, runPreflightCommand
+	t.Cleanup(func() { lookPath, runPreflightCommand = origLookPath, origRun })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookPath = func(file string) (string, error) {
+				if !tt.found {
+					return "", exec.ErrNotFound
+				}
+				return "/usr/bin/" + file, nil
+			}
+			runPreflightCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				return []byte(tt.output), nil
+			}
+
+			err := TerraformCheck("terraform", tt.constraint).Run(context.Background())
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvCheck(t *testing.T) {
+	t.Setenv("VALIDOR_SET", "1")
+	t.Setenv("VALIDOR_EMPTY", "")
+
+	err := EnvCheck("VALIDOR_SET", "VALIDOR_EMPTY", "VALIDOR_UNSET_FOR_TEST").Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "VALIDOR_EMPTY, VALIDOR_UNSET_FOR_TEST") {
+		t.Errorf("error = %v", err)
+	}
+	if err := EnvCheck("VALIDOR_SET").Run(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCredentialCheck(t *testing.T) {
+	origRun := runPreflightCommand
+	t.Cleanup(func() { runPreflightCommand = origRun })
+	t.Setenv("ARM_SUBSCRIPTION_ID", "")
+
+	var commands []string
+	runPreflightCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		if name == "aws" {
+			return nil, errors.New("expired token")
+		}
+		return []byte(`{"id": "0000-1111"}`), nil
+	}
+
+	if err := CredentialCheck("azure").Run(context.Background()); err != nil {
+		t.Errorf("azure: unexpected error: %v", err)
+	}
+	if err := CredentialCheck("aws").Run(context.Background()); err == nil || !strings.Contains(err.Error(), "expired token") {
+		t.Errorf("aws: error = %v", err)
+	}
+	if err := CredentialCheck("oracle").Run(context.Background()); err == nil || !strings.Contains(err.Error(), "want aws, azure, gcp") {
+		t.Errorf("oracle: error = %v, want an unknown cloud", err)
+	}
+	want := []string{"az account show --output json", "az account get-access-token --output json", "aws sts get-caller-identity --output json"}
+	if strings.Join(commands, ";") != strings.Join(want, ";") {
+		t.Errorf("commands = %v, want %v", commands, want)
+	}
+}
+
+func TestRunPreflight(t *testing.T) {
+	checks := []PreflightCheck{
+		{Name: "ok", Run: func(ctx context.Context) error { return nil }},
+		{Name: "first", Run: func(ctx context.Context) error { return errors.New("broken") }},
+		{Name: "second", Run: func(ctx context.Context) error { return errors.New("missing") }},
+	}
+
+	err := RunPreflight(context.Background(), checks)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if want := "first: broken\nsecond: missing"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+	if err := RunPreflight(context.Background(), checks[:1]); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRunModuleTests_PreflightFailureStopsRun(t *testing.T) {
+	module := NewModule("missing", t.TempDir()+"/missing")
+	applied := false
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+		applied = true
+		return nil
+	}
+
+	origLookPath := lookPath
+	t.Cleanup(func() { lookPath = origLookPath })
+	lookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+
+	mock := &fatalTB{TB: t}
+	config := NewConfig(WithExample(""), WithPreflight(true))
+	func() {
+		defer func() { recover() }()
+		runModuleTests(mock, Runners([]*Module{module}), false, config, nil, "registry")
+	}()
+
+	if applied {
+		t.Error("no example should run when preflight fails")
+	}
+	if !strings.Contains(mock.message, "terraform: terraform not found on PATH") || !strings.Contains(mock.message, "example directories not found") {
+		t.Errorf("expected a consolidated error, got %q", mock.message)
+	}
+}
+
+type fatalTB struct {
+	testing.TB
+	message string
+}
+
+func (f *fatalTB) Fatal(args ...any) {
+	f.message = fmt.Sprint(args...)
+	panic("fatal")
+}
@@ -0,0 +1,121 @@
+package validor
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// WithPreserveOnFailure copies the state, plan JSON and log of a failed
+// example to a directory named after it in dir before the example is cleaned
+// up, so they can be inspected or uploaded as CI artifacts. Sensitive values
+// are scrubbed from the copies.
+func WithPreserveOnFailure(dir string) Option {
+	return func(c *Config) { c.PreserveOnFailure = dir }
+}
+
+// preserveArtifacts copies what is needed to debug a failed module to its
+// artifacts directory, replacing what an earlier attempt preserved.
+func (m *Module) preserveArtifacts(t testing.TB) {
+	if m.preserveDir == "" || !m.ApplyFailed && len(m.failed) == 0 {
+		return
+	}
+
+	dest := filepath.Join(m.preserveDir, strings.TrimSuffix(logFileName(m.Name), ".log"))
+	if err := os.RemoveAll(dest); err != nil {
+		t.Logf("Warning: failed to clear %s: %v", dest, err)
+		return
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Logf("Warning: failed to create artifacts directory %s: %v", dest, err)
+		return
+	}
+
+	var errs []string
+	var artifacts []artifact
+	entries, _ := os.ReadDir(m.Options.TerraformDir)
+	for _, entry := range entries {
+		if matched, _ := filepath.Match("*tfstate*", entry.Name()); matched {
+			read, err := readArtifacts(filepath.Join(m.Options.TerraformDir, entry.Name()), entry.Name())
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+			artifacts = append(artifacts, read...)
+		}
+	}
+
+	if m.planJSON != nil {
+		artifacts = append(artifacts, artifact{name: "plan.json", content: m.planJSON})
+	} else if _, err := os.Stat(m.PlanJSONPath()); err == nil {
+		read, err := readArtifacts(m.PlanJSONPath(), "plan.json")
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		artifacts = append(artifacts, read...)
+	}
+
+	if m.logFile != nil {
+		read, err := readArtifacts(m.logFile.Name(), filepath.Base(m.logFile.Name()))
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		artifacts = append(artifacts, read...)
+	}
+
+	scrubArtifacts(artifacts, m.masker)
+	for _, a := range artifacts {
+		path := filepath.Join(dest, filepath.FromSlash(a.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err := os.WriteFile(path, a.content, 0644); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to write %s: %v", path, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		t.Logf("Warning: failed to preserve some artifacts of %s: %s", m.Name, strings.Join(errs, "; "))
+	}
+	t.Logf("Preserved the state, plan and log of failed module %s in %s", m.Name, dest)
+}
+
+// readArtifacts reads a file, or the files in a directory such as
+// terraform.tfstate.d with the state of other workspaces, naming them after
+// name.
+func readArtifacts(src, name string) ([]artifact, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		content, err := os.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", src, err)
+		}
+		return []artifact{{name: name, content: content}}, nil
+	}
+	var artifacts []artifact
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, artifact{name: name + "/" + filepath.ToSlash(rel), content: content})
+		return nil
+	})
+	if err != nil {
+		return artifacts, fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	return artifacts, nil
+}
@@ -0,0 +1,69 @@
+package validor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModule_PreserveOnFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		failed    bool
+		wantFiles []string
+	}{
+		{name: "passing module", failed: false},
+		{
+			name:      "failed module",
+			failed:    true,
+			wantFiles: []string{"terraform.tfstate", "terraform.tfstate.backup", "terraform.tfstate.d/staging/terraform.tfstate", "plan.json", "module_a.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, file := range []string{"terraform.tfstate", "terraform.tfstate.backup", "terraform.tfstate.d/staging/terraform.tfstate", "main.tf"} {
+				path := filepath.Join(dir, file)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			module := NewModule("module/a", dir)
+			module.preserveDir = t.TempDir()
+			module.planJSON = []byte(`{"resource_changes":[]}`)
+			if err := module.OpenLogFile(t.TempDir()); err != nil {
+				t.Fatalf("OpenLogFile() error = %v", err)
+			}
+			defer module.CloseLogFile()
+			if tt.failed {
+				module.failApply(t, &ModuleError{ModuleName: module.Name, Operation: "terraform apply", Err: errors.New("quota exceeded")})
+			}
+
+			if err := module.Cleanup(testContext(t), &recordingTB{TB: t}); err != nil {
+				t.Fatalf("Cleanup() error = %v", err)
+			}
+
+			dest := filepath.Join(module.preserveDir, "module_a")
+			if _, err := os.Stat(dest); tt.failed == os.IsNotExist(err) {
+				t.Fatalf("artifacts directory exists = %v, want %v", err == nil, tt.failed)
+			}
+			for _, file := range tt.wantFiles {
+				if _, err := os.Stat(filepath.Join(dest, file)); err != nil {
+					t.Errorf("%s should have been preserved: %v", file, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dest, "main.tf")); err == nil {
+				t.Error("configuration files should not be preserved")
+			}
+			if _, err := os.Stat(filepath.Join(dir, "terraform.tfstate")); !os.IsNotExist(err) {
+				t.Error("the state should still be cleaned up")
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// defaultCancelGrace is how long a cancelled terraform command gets to stop
+// after being interrupted before it is killed.
+const defaultCancelGrace = 30 * time.Second
+
+// WithCancelGracePeriod sets how long terraform gets to stop gracefully, and
+// release its state lock, after the context of an apply or destroy is
+// cancelled or times out. Terraform is killed once it has passed.
+func WithCancelGracePeriod(d time.Duration) Option {
+	return func(c *Config) { c.CancelGrace = d }
+}
+
+// runProcess runs cmd in its own process group and stops the whole group when
+// ctx is done: it is interrupted first, so terraform stops making changes and
+// releases its state lock, and killed when it is still running after grace.
+func runProcess(ctx context.Context, cmd *exec.Cmd, grace time.Duration) error {
+	if grace <= 0 {
+		grace = defaultCancelGrace
+	}
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	interruptProcessGroup(cmd.Process)
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return fmt.Errorf("interrupted: %w", context.Cause(ctx))
+	case <-timer.C:
+	}
+	killProcessGroup(cmd.Process)
+	<-done
+	return fmt.Errorf("killed after %s: %w", grace, context.Cause(ctx))
+}
+
+// runTerraform runs terraform with args, formatted the way terratest formats
+// them, and returns its output. Unlike terratest it stops terraform when ctx
+// is done. With streaming enabled the output goes to the module's log file as
+// it is produced and is not returned. Errors include the last lines of output,
+// and retryable errors are retried like terratest retries them.
+func (m *Module) runTerraform(ctx context.Context, t testing.TB, args ...string) (string, error) {
+	options := *m.Options
+	opts, args := terraform.GetCommonOptions(&options, terraform.FormatArgs(&options, args...)...)
+
+	for attempt := 0; ; attempt++ {
+		var output strings.Builder
+		out := &streamWriter{dst: &output, onLine: func(line string) { opts.Logger.Logf(t, "%s", line) }}
+		if m.stream {
+			var dst io.Writer = io.Discard
+			if m.logFile != nil {
+				dst = m.logFile
+			}
+			out = &streamWriter{dst: dst, onLine: func(line string) { m.recordRemoteRuns(t, line) }}
+			if m.masker != nil {
+				out.mask = m.masker.Mask
+			}
+		}
+
+		opts.Logger.Logf(t, "Running command %s with args %s", opts.TerraformBinary, args)
+		cmd := exec.Command(opts.TerraformBinary, args...)
+		cmd.Dir = opts.TerraformDir
+		cmd.Env = os.Environ()
+		for key, value := range opts.EnvVars {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+		cmd.Stdin = opts.Stdin
+		cmd.Stdout = out
+		cmd.Stderr = out
+
+		cmdCtx, cancel := context.WithCancelCause(ctx)
+		stopWatching := m.watchForHang(t, cmd, out, cancel)
+		err := runProcess(cmdCtx, cmd, m.cancelGrace)
+		stopWatching()
+		out.flush()
+		hung := errors.Is(context.Cause(cmdCtx), errHung)
+		cancel(nil)
+		if err == nil {
+			return output.String(), nil
+		}
+		tail := out.tail()
+		err = fmt.Errorf("%s %s: %w\nlast lines of output:\n%s", opts.TerraformBinary, strings.Join(args, " "), err, tail)
+		if ctx.Err() != nil || attempt >= opts.MaxRetries || !hung && !retryableOutput(opts.RetryableTerraformErrors, tail) {
+			return output.String(), err
+		}
+		t.Logf("Retrying %s for module %s after a retryable error (%d of %d)", args[0], m.Name, attempt+1, opts.MaxRetries)
+		if err := sleepContext(ctx, opts.TimeBetweenRetries); err != nil {
+			return output.String(), err
+		}
+	}
+}
+
+// terraformApply runs terraform apply like terratest's ApplyE.
+func (m *Module) terraformApply(ctx context.Context, t testing.TB) error {
+	out, err := m.runTerraform(ctx, t, append(m.command("apply", "-input=false", "-auto-approve"), m.Options.ExtraArgs.Apply...)...)
+	m.recordRemoteRuns(t, out)
+	return err
+}
+
+// terraformDestroy runs terraform destroy like terratest's DestroyE, with
+// extra arguments after the configured ones.
+func (m *Module) terraformDestroy(ctx context.Context, t testing.TB, extra ...string) error {
+	args := append(m.command("destroy", "-auto-approve", "-input=false"), m.Options.ExtraArgs.Destroy...)
+	out, err := m.runTerraform(ctx, t, append(args, extra...)...)
+	m.recordRemoteRuns(t, out)
+	return err
+}
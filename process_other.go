@@ -0,0 +1,24 @@
+//go:build !unix
+
+package validor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Without process groups and interrupts, cancelled commands are killed.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func interruptProcessGroup(p *os.Process) {
+	_ = p.Kill()
+}
+
+func killProcessGroup(p *os.Process) {
+	_ = p.Kill()
+}
+
+func processSnapshot(p *os.Process) string {
+	return fmt.Sprintf("process %d (listing its children is not supported on this platform)", p.Pid)
+}
@@ -0,0 +1,95 @@
+//go:build unix
+
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunProcess(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		cancel   bool
+		wantErr  string
+		wantOut  string
+		maxSpent time.Duration
+	}{
+		{name: "completes", script: "echo done", wantOut: "done"},
+		{name: "reports failures", script: "exit 3", wantErr: "exit status 3"},
+		{
+			name:     "interrupts the process group",
+			script:   "trap 'echo interrupted; exit 130' INT\nwhile true; do sleep 0.1; done",
+			cancel:   true,
+			wantErr:  "interrupted: context canceled",
+			wantOut:  "interrupted",
+			maxSpent: 5 * time.Second,
+		},
+		{
+			name:     "kills a process ignoring the interrupt",
+			script:   "trap '' INT\nwhile true; do sleep 0.1; done",
+			cancel:   true,
+			wantErr:  "killed after 200ms: context canceled",
+			maxSpent: 5 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(300*time.Millisecond, cancel)
+			}
+
+			var out strings.Builder
+			cmd := exec.Command("/bin/sh", "-c", tt.script)
+			cmd.Stdout = &out
+			started := time.Now()
+			err := runProcess(ctx, cmd, 200*time.Millisecond)
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runProcess() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("runProcess() error = %v, want %q", err, tt.wantErr)
+			}
+			if tt.cancel && !errors.Is(err, context.Canceled) {
+				t.Errorf("runProcess() error = %v, want it to wrap context.Canceled", err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output = %q, want it to contain %q", out.String(), tt.wantOut)
+			}
+			if tt.maxSpent > 0 && time.Since(started) > tt.maxSpent {
+				t.Errorf("runProcess() took %s after cancelling", time.Since(started))
+			}
+		})
+	}
+}
+
+func TestModule_RunTerraformStopsOnCancel(t *testing.T) {
+	stubSleep(t)
+	calls := filepath.Join(t.TempDir(), "calls")
+	module := NewModule("default", t.TempDir())
+	module.Options.TerraformBinary = fakeTerraform(t, "echo x >> "+calls+"\necho 'Error: connection reset by peer'\nwhile true; do sleep 0.1; done\n")
+	module.Options.MaxRetries = 3
+	module.Options.RetryableTerraformErrors = map[string]string{"connection reset": "network"}
+	module.cancelGrace = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err := module.terraformDestroy(ctx, t)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "connection reset by peer") {
+		t.Errorf("terraformDestroy() error = %v, want the deadline and the output tail", err)
+	}
+	if content, _ := os.ReadFile(calls); strings.Count(string(content), "x") != 1 {
+		t.Errorf("a cancelled command should not be retried, ran %d times", strings.Count(string(content), "x"))
+	}
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package validor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Signalling the negative pid reaches the providers terraform started too.
+func interruptProcessGroup(p *os.Process) {
+	_ = syscall.Kill(-p.Pid, syscall.SIGINT)
+}
+
+func killProcessGroup(p *os.Process) {
+	_ = syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
+
+// processSnapshot lists the processes in the group of p, as ps reports them.
+func processSnapshot(p *os.Process) string {
+	out, err := exec.Command("ps", "-A", "-o", "pid,ppid,pgid,stat,etime,args").Output()
+	if err != nil {
+		return fmt.Sprintf("failed to list processes: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	group := lines[:1]
+	for _, line := range lines[1:] {
+		if fields := strings.Fields(line); len(fields) > 2 && fields[2] == strconv.Itoa(p.Pid) {
+			group = append(group, line)
+		}
+	}
+	return strings.Join(group, "\n")
+}
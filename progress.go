@@ -0,0 +1,142 @@
+package validor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	progressRunning = "running"
+	progressPassed  = "passed"
+	progressFailed  = "failed"
+)
+
+func WithProgress(enabled bool) Option {
+	return func(c *Config) { c.Progress = enabled }
+}
+
+type progressEntry struct {
+	name    string
+	stage   Stage
+	status  string
+	started time.Time
+	ended   time.Time
+}
+
+type ProgressRenderer struct {
+	mu       sync.Mutex
+	out      io.Writer
+	tty      bool
+	interval time.Duration
+	entries  []*progressEntry
+	index    map[string]*progressEntry
+	lines    int
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewProgressRenderer(out io.Writer) *ProgressRenderer {
+	p := &ProgressRenderer{
+		out:      out,
+		tty:      isTerminal(out),
+		interval: 30 * time.Second,
+		index:    make(map[string]*progressEntry),
+	}
+	if p.tty {
+		p.interval = 500 * time.Millisecond
+	}
+	return p
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
+
+func (p *ProgressRenderer) Start() {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.render()
+			}
+		}
+	}()
+}
+
+func (p *ProgressRenderer) Stop() {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+		p.stop = nil
+	}
+	p.render()
+}
+
+func (p *ProgressRenderer) SetStage(m *Module, stage Stage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.index[m.Name]
+	if !ok {
+		entry = &progressEntry{name: m.Name, status: progressRunning, started: time.Now()}
+		p.index[m.Name] = entry
+		p.entries = append(p.entries, entry)
+	}
+	entry.stage = stage
+}
+
+func (p *ProgressRenderer) Finish(m *Module) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.index[m.Name]
+	if !ok {
+		entry = &progressEntry{name: m.Name, started: time.Now()}
+		p.index[m.Name] = entry
+		p.entries = append(p.entries, entry)
+	}
+	entry.status = BoolToStr(len(m.Errors) > 0, progressFailed, progressPassed)
+	entry.ended = time.Now()
+}
+
+func (p *ProgressRenderer) render() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.entries) == 0 {
+		return
+	}
+
+	if p.tty && p.lines > 0 {
+		fmt.Fprintf(p.out, "\033[%dA", p.lines)
+	}
+
+	now := time.Now()
+	for _, entry := range p.entries {
+		end := now
+		if !entry.ended.IsZero() {
+			end = entry.ended
+		}
+		line := fmt.Sprintf("%-30s %-8s %-8s %s", entry.name, entry.stage, entry.status, end.Sub(entry.started).Round(time.Second))
+		if p.tty {
+			fmt.Fprintf(p.out, "\033[2K%s\n", line)
+		} else {
+			fmt.Fprintf(p.out, "[validor] %s\n", line)
+		}
+	}
+	p.lines = len(p.entries)
+}
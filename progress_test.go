@@ -0,0 +1,77 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestProgressRenderer_NonTTY(t *testing.T) {
+	var buf bytes.Buffer
+	renderer := NewProgressRenderer(&buf)
+
+	if renderer.tty {
+		t.Fatal("bytes.Buffer should not be detected as a terminal")
+	}
+
+	ok := NewModule("ok", "/path/ok")
+	broken := NewModule("broken", "/path/broken")
+	broken.Errors = append(broken.Errors, "apply failed")
+
+	renderer.Start()
+	renderer.SetStage(ok, StageApply)
+	renderer.SetStage(broken, StageInit)
+	renderer.Finish(ok)
+	renderer.Finish(broken)
+	renderer.Stop()
+
+	output := buf.String()
+	if strings.Contains(output, "\033[") {
+		t.Errorf("non-tty output should not contain escape sequences, got %q", output)
+	}
+	for _, want := range []string{"[validor] ok", "passed", "[validor] broken", "failed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got %q", want, output)
+		}
+	}
+}
+
+func TestProgressRenderer_TTYRedrawsInPlace(t *testing.T) {
+	var buf bytes.Buffer
+	renderer := NewProgressRenderer(&buf)
+	renderer.tty = true
+
+	module := NewModule("example1", "/path/example1")
+	renderer.SetStage(module, StageInit)
+	renderer.render()
+	renderer.SetStage(module, StageApply)
+	renderer.render()
+
+	if !strings.Contains(buf.String(), "\033[1A") {
+		t.Errorf("expected cursor to move up before redraw, got %q", buf.String())
+	}
+}
+
+func TestModule_StageListener(t *testing.T) {
+	module := NewModule("example1", t.TempDir())
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+	module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+
+	var stages []Stage
+	module.onStage = func(m *Module, stage Stage) {
+		stages = append(stages, stage)
+	}
+
+	if err := module.Apply(context.Background(), t); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if err := module.Destroy(context.Background(), t); err != nil {
+		t.Fatalf("Destroy() error = %v", err)
+	}
+
+	want := []Stage{StageApply, StageDestroy}
+	if len(stages) != len(want) || stages[0] != want[0] || stages[1] != want[1] {
+		t.Errorf("stages = %v, want %v", stages, want)
+	}
+}
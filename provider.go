@@ -0,0 +1,88 @@
+package validor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func WithProviderOverride(source, dir string) Option {
+	return func(c *Config) {
+		if c.ProviderOverrides == nil {
+			c.ProviderOverrides = make(map[string]string)
+		}
+		c.ProviderOverrides[source] = dir
+	}
+}
+
+func parseProviderOverrides(value string, overrides map[string]string) error {
+	for entry := range strings.SplitSeq(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		source, dir, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(source) == "" || strings.TrimSpace(dir) == "" {
+			return fmt.Errorf("invalid provider override %q, expected SOURCE=DIR", entry)
+		}
+		overrides[strings.TrimSpace(source)] = strings.TrimSpace(dir)
+	}
+	return nil
+}
+
+// writeCLIConfig writes a Terraform CLI configuration that installs the given
+// providers from local directories, and everything else from mirror when it
+// is set or else as usual.
+func writeCLIConfig(dir string, overrides map[string]string, mirror string) (string, error) {
+	sources := make([]string, 0, len(overrides))
+	for source := range overrides {
+		sources = append(sources, source)
+	}
+	slices.Sort(sources)
+
+	var b strings.Builder
+	b.WriteString("provider_installation {\n")
+	if len(sources) > 0 {
+		b.WriteString("  dev_overrides {\n")
+	}
+	for _, source := range sources {
+		path, err := filepath.Abs(overrides[source])
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve provider directory %s: %w", overrides[source], err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("provider directory for %s: %w", source, err)
+		}
+		fmt.Fprintf(&b, "    %q = %q\n", source, filepath.ToSlash(path))
+	}
+	if len(sources) > 0 {
+		b.WriteString("  }\n\n")
+	}
+	if mirror != "" {
+		path, err := filepath.Abs(mirror)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve provider mirror %s: %w", mirror, err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("provider mirror: %w", err)
+		}
+		fmt.Fprintf(&b, "  filesystem_mirror {\n    path = %q\n  }\n}\n", filepath.ToSlash(path))
+	} else {
+		b.WriteString("  direct {}\n}\n")
+	}
+
+	path := filepath.Join(dir, "validor.tfrc")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write terraform cli config: %w", err)
+	}
+	return path, nil
+}
+
+func (m *Module) UseCLIConfig(path string) {
+	if m.Options.EnvVars == nil {
+		m.Options.EnvVars = make(map[string]string)
+	}
+	m.Options.EnvVars["TF_CLI_CONFIG_FILE"] = path
+}
@@ -0,0 +1,112 @@
+package validor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProviderOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "single override",
+			value: "hashicorp/azurerm=/tmp/azurerm",
+			want:  map[string]string{"hashicorp/azurerm": "/tmp/azurerm"},
+		},
+		{
+			name:  "multiple overrides",
+			value: "hashicorp/azurerm=./bin, registry.terraform.io/azure/azapi = ../azapi ,",
+			want:  map[string]string{"hashicorp/azurerm": "./bin", "registry.terraform.io/azure/azapi": "../azapi"},
+		},
+		{name: "missing directory", value: "hashicorp/azurerm=", wantErr: true},
+		{name: "missing separator", value: "hashicorp/azurerm", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]string)
+			err := parseProviderOverrides(tt.value, got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProviderOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProviderOverrides() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCLIConfig(t *testing.T) {
+	dir := t.TempDir()
+	azurerm := filepath.Join(dir, "azurerm")
+	azapi := filepath.Join(dir, "azapi")
+	for _, path := range []string{azurerm, azapi} {
+		if err := os.Mkdir(path, 0755); err != nil {
+			t.Fatalf("Failed to create provider dir: %v", err)
+		}
+	}
+
+	path, err := writeCLIConfig(dir, map[string]string{
+		"hashicorp/azurerm": azurerm,
+		"azure/azapi":       azapi,
+	}, "")
+	if err != nil {
+		t.Fatalf("writeCLIConfig() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read cli config: %v", err)
+	}
+	want := "provider_installation {\n  dev_overrides {\n" +
+		"    \"azure/azapi\" = \"" + filepath.ToSlash(azapi) + "\"\n" +
+		"    \"hashicorp/azurerm\" = \"" + filepath.ToSlash(azurerm) + "\"\n" +
+		"  }\n\n  direct {}\n}\n"
+	if string(content) != want {
+		t.Errorf("cli config =\n%s\nwant\n%s", content, want)
+	}
+
+	path, err = writeCLIConfig(dir, map[string]string{"hashicorp/azurerm": azurerm}, filepath.Join(dir, "azapi"))
+	if err != nil {
+		t.Fatalf("writeCLIConfig() with mirror error = %v", err)
+	}
+	content, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read cli config: %v", err)
+	}
+	want = "provider_installation {\n  dev_overrides {\n" +
+		"    \"hashicorp/azurerm\" = \"" + filepath.ToSlash(azurerm) + "\"\n" +
+		"  }\n\n  filesystem_mirror {\n    path = \"" + filepath.ToSlash(azapi) + "\"\n  }\n}\n"
+	if string(content) != want {
+		t.Errorf("cli config with mirror =\n%s\nwant\n%s", content, want)
+	}
+
+	if _, err := writeCLIConfig(dir, nil, filepath.Join(dir, "missing")); err == nil || !strings.Contains(err.Error(), "provider mirror") {
+		t.Errorf("expected error for missing provider mirror, got %v", err)
+	}
+
+	if _, err := writeCLIConfig(dir, map[string]string{"hashicorp/azurerm": filepath.Join(dir, "missing")}, ""); err == nil || !strings.Contains(err.Error(), "hashicorp/azurerm") {
+		t.Errorf("expected error for missing provider directory, got %v", err)
+	}
+}
+
+func TestModule_UseCLIConfig(t *testing.T) {
+	module := NewModule("example", "/tmp/example")
+	module.UseCLIConfig("/tmp/validor.tfrc")
+
+	if got := module.Options.EnvVars["TF_CLI_CONFIG_FILE"]; got != "/tmp/validor.tfrc" {
+		t.Errorf("TF_CLI_CONFIG_FILE = %q, want /tmp/validor.tfrc", got)
+	}
+
+	config := NewConfig(WithProviderOverride("hashicorp/azurerm", "./bin"))
+	if config.ProviderOverrides["hashicorp/azurerm"] != "./bin" {
+		t.Errorf("WithProviderOverride() did not register override: %v", config.ProviderOverrides)
+	}
+}
@@ -0,0 +1,183 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// WithAzurePurge purges the Key Vaults, API Management instances and Cognitive
+// Services accounts an example destroyed, which Azure otherwise keeps
+// soft-deleted so the next run fails on their names. It uses the Azure SDK
+// with azureCredential. Resources with purge protection are left to expire.
+func WithAzurePurge(enabled bool) Option {
+	return func(c *Config) { c.AzurePurge = enabled }
+}
+
+// azurePurgeDelay is how long to wait between polls of a purge when Azure does
+// not say.
+const azurePurgeDelay = 10 * time.Second
+
+// errPurgeConflict is returned for a purge Azure refuses with 409 Conflict,
+// as it does for resources with purge protection enabled.
+var errPurgeConflict = errors.New("purge refused, as it is for resources with purge protection enabled")
+
+// softDeleted is a resource that Azure keeps soft-deleted after destroy.
+type softDeleted struct {
+	resourceType string
+	kind         string
+	name         string
+	subscription string
+	location     string
+	group        string
+}
+
+// softDeletedResources returns what destroying the resources in state leaves
+// soft-deleted.
+func softDeletedResources(state *State) []softDeleted {
+	var resources []softDeleted
+	add := func(resourceType, kind string) {
+		for _, values := range resourcesOfType(state, resourceType) {
+			name, _ := values["name"].(string)
+			location, _ := values["location"].(string)
+			id, _ := values["id"].(string)
+			group, _ := values["resource_group_name"].(string)
+			subscription := azureSubscription(id)
+			if name == "" || location == "" || subscription == "" {
+				continue
+			}
+			resources = append(resources, softDeleted{
+				resourceType: resourceType,
+				kind:         kind,
+				name:         name,
+				subscription: subscription,
+				location:     strings.ToLower(strings.ReplaceAll(location, " ", "")),
+				group:        group,
+			})
+		}
+	}
+	add("azurerm_key_vault", "key vault")
+	add("azurerm_api_management", "API Management instance")
+	add("azurerm_cognitive_account", "cognitive account")
+	return resources
+}
+
+func azureSubscription(id string) string {
+	parts := strings.Split(id, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if strings.EqualFold(parts[i], "subscriptions") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// prepareAzurePurge records what the module's destroy will leave soft-deleted
+// and returns the function that purges it once the destroy succeeded.
+func (m *Module) prepareAzurePurge(ctx context.Context, t testing.TB) func() {
+	if !m.azurePurge {
+		return func() {}
+	}
+	state, err := m.State(ctx, t)
+	if err != nil {
+		t.Logf("Warning: failed to read the state of %s to purge soft-deleted resources: %v", m.Name, err)
+		return func() {}
+	}
+	resources := softDeletedResources(state)
+	return func() {
+		if err := purgeSoftDeleted(ctx, t, resources); err != nil && !m.ApplyFailed {
+			m.recordError(t, StageDestroy, &ModuleError{ModuleName: m.Name, Operation: "purge soft-deleted resources", Err: err})
+		}
+	}
+}
+
+func purgeSoftDeleted(ctx context.Context, t testing.TB, resources []softDeleted) error {
+	if len(resources) == 0 {
+		return nil
+	}
+	credential, err := azureCredential()
+	if err != nil {
+		return fmt.Errorf("failed to get an Azure credential: %w", err)
+	}
+
+	var errs []error
+	for _, resource := range resources {
+		t.Logf("Purging soft-deleted %s %s", resource.kind, resource.name)
+		err := purgeResource(ctx, credential, resource)
+		if errors.Is(err, errPurgeConflict) {
+			t.Logf("Skipping purge of soft-deleted %s %s: %v", resource.kind, resource.name, err)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("purge %s %s: %w", resource.kind, resource.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// purgeResource purges a soft-deleted resource and waits for the purge to
+// complete. A resource that is not soft-deleted is left alone.
+func purgeResource(ctx context.Context, credential azcore.TokenCredential, resource softDeleted) error {
+	var err error
+	switch resource.resourceType {
+	case "azurerm_key_vault":
+		err = purgeARM(ctx, credential, http.MethodPost, "2023-07-01",
+			"/subscriptions/"+resource.subscription+"/providers/Microsoft.KeyVault/locations/"+resource.location+"/deletedVaults/"+resource.name+"/purge")
+	case "azurerm_api_management":
+		err = purgeARM(ctx, credential, http.MethodDelete, "2022-08-01",
+			"/subscriptions/"+resource.subscription+"/providers/Microsoft.ApiManagement/locations/"+resource.location+"/deletedservices/"+resource.name)
+	case "azurerm_cognitive_account":
+		err = purgeARM(ctx, credential, http.MethodDelete, "2025-06-01",
+			"/subscriptions/"+resource.subscription+"/providers/Microsoft.CognitiveServices/locations/"+resource.location+"/resourceGroups/"+resource.group+"/deletedAccounts/"+resource.name)
+	}
+
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) {
+		switch responseErr.StatusCode {
+		case http.StatusNotFound:
+			return nil
+		case http.StatusConflict:
+			return fmt.Errorf("%w: %s", errPurgeConflict, responseErr.ErrorCode)
+		}
+		return fmt.Errorf("%d %s: %s", responseErr.StatusCode, http.StatusText(responseErr.StatusCode), responseErr.ErrorCode)
+	}
+	return err
+}
+
+// purgeARM sends a purge request through the pipeline of an Azure Resource
+// Manager client and polls the long-running operation until it completes.
+func purgeARM(ctx context.Context, credential azcore.TokenCredential, method, apiVersion, path string) error {
+	client, err := arm.NewClient("validor", "v0.0.0", credential, azureClientOptions)
+	if err != nil {
+		return err
+	}
+	req, err := runtime.NewRequest(ctx, method, runtime.JoinPaths(client.Endpoint(), path))
+	if err != nil {
+		return err
+	}
+	query := req.Raw().URL.Query()
+	query.Set("api-version", apiVersion)
+	req.Raw().URL.RawQuery = query.Encode()
+
+	resp, err := client.Pipeline().Do(req)
+	if err != nil {
+		return err
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK, http.StatusAccepted, http.StatusNoContent) {
+		return runtime.NewResponseError(resp)
+	}
+	poller, err := runtime.NewPoller[struct{}](resp, client.Pipeline(), nil)
+	if err != nil {
+		return err
+	}
+	_, err = poller.PollUntilDone(ctx, &runtime.PollUntilDoneOptions{Frequency: azurePurgeDelay})
+	return err
+}
@@ -0,0 +1,155 @@
+package validor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const purgeTestState = `{"values":{"root_module":{"resources":[
+	{"address":"azurerm_key_vault.kv","values":{"name":"kv-test","location":"West Europe","id":"/subscriptions/sub1/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv-test"}},
+	{"address":"azurerm_resource_group.rg","values":{"name":"rg","location":"westeurope","id":"/subscriptions/sub1/resourceGroups/rg"}}
+],"child_modules":[{"resources":[
+	{"address":"module.ai.azurerm_cognitive_account.oai","values":{"name":"oai-test","location":"swedencentral","resource_group_name":"rg","id":"/subscriptions/sub1/resourceGroups/rg/providers/Microsoft.CognitiveServices/accounts/oai-test"}},
+	{"address":"module.ai.azurerm_api_management.apim","values":{"name":"apim-test","location":"westeurope","id":"/subscriptions/sub1/resourceGroups/rg/providers/Microsoft.ApiManagement/service/apim-test"}}
+]}]}}}`
+
+type staticCredential string
+
+func (c staticCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: string(c), ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func stubAzure(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	// The SDK only sends tokens over TLS.
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+	originalCredential, originalOptions := azureCredential, azureClientOptions
+	azureCredential = func() (azcore.TokenCredential, error) { return staticCredential("token"), nil }
+	azureClientOptions = &arm.ClientOptions{ClientOptions: policy.ClientOptions{
+		Cloud: cloud.Configuration{Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+			cloud.ResourceManager: {Endpoint: server.URL, Audience: "https://management.azure.com"},
+		}},
+		Transport: server.Client(),
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+	}}
+	t.Cleanup(func() { azureCredential, azureClientOptions = originalCredential, originalOptions })
+}
+
+func TestSoftDeletedResources(t *testing.T) {
+	state, err := ParseState([]byte(purgeTestState))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []softDeleted{
+		{resourceType: "azurerm_key_vault", kind: "key vault", name: "kv-test", subscription: "sub1", location: "westeurope"},
+		{resourceType: "azurerm_api_management", kind: "API Management instance", name: "apim-test", subscription: "sub1", location: "westeurope"},
+		{resourceType: "azurerm_cognitive_account", kind: "cognitive account", name: "oai-test", subscription: "sub1", location: "swedencentral", group: "rg"},
+	}
+	if got := softDeletedResources(state); !reflect.DeepEqual(got, want) {
+		t.Errorf("softDeletedResources() = %+v, want %+v", got, want)
+	}
+}
+
+func TestModule_DestroyPurgesSoftDeletedResources(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	stubAzure(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case strings.Contains(r.URL.Path, "deletedVaults"):
+			w.Header().Set("Location", "https://"+r.Host+"/operations/1")
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusAccepted)
+		case strings.HasPrefix(r.URL.Path, "/operations/"):
+			w.WriteHeader(http.StatusOK)
+		case strings.Contains(r.URL.Path, "deletedservices"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":{"code":"FlagMustBeSetForRestore","message":"purge protection"}}`))
+		}
+	})
+
+	module := NewModule("default", t.TempDir())
+	module.azurePurge = true
+	destroyed := false
+	module.stateHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+		if destroyed {
+			return []byte(`{}`), nil
+		}
+		return []byte(purgeTestState), nil
+	}
+	module.destroyHook = func(ctx context.Context, t testing.TB, m *Module) error {
+		destroyed = true
+		return nil
+	}
+
+	tb := &recordingTB{TB: t}
+	if err := module.Destroy(context.Background(), tb); err != nil {
+		t.Fatalf("Destroy() error = %v", err)
+	}
+	want := []string{
+		"POST /subscriptions/sub1/providers/Microsoft.KeyVault/locations/westeurope/deletedVaults/kv-test/purge",
+		"GET /operations/1",
+		"DELETE /subscriptions/sub1/providers/Microsoft.ApiManagement/locations/westeurope/deletedservices/apim-test",
+		"DELETE /subscriptions/sub1/providers/Microsoft.CognitiveServices/locations/swedencentral/resourceGroups/rg/deletedAccounts/oai-test",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %q, want %q", requests, want)
+	}
+	if len(module.Errors) != 0 {
+		t.Errorf("errors = %v, want a purge-protected resource to be skipped", module.Errors)
+	}
+	if logs := strings.Join(tb.logs, "\n"); !strings.Contains(logs, "Skipping purge of soft-deleted cognitive account oai-test: purge refused, as it is for resources with purge protection enabled: FlagMustBeSetForRestore") {
+		t.Errorf("logs = %q, want the skipped purge", tb.logs)
+	}
+}
+
+func TestPurgeResource_Error(t *testing.T) {
+	stubAzure(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"code":"AuthorizationFailed","message":"no access"}}`))
+	})
+
+	err := purgeSoftDeleted(context.Background(), &recordingTB{TB: t}, []softDeleted{{resourceType: "azurerm_key_vault", kind: "key vault", name: "kv-test", subscription: "sub1", location: "westeurope"}})
+	if err == nil || err.Error() != "purge key vault kv-test: 403 Forbidden: AuthorizationFailed" {
+		t.Errorf("purgeSoftDeleted() error = %v, want the failed purge", err)
+	}
+}
+
+func TestModule_DestroySkipsPurgeWhenDestroyFails(t *testing.T) {
+	called := false
+	stubAzure(t, func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	module := NewModule("default", t.TempDir())
+	module.azurePurge = true
+	module.stateHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+		return []byte(purgeTestState), nil
+	}
+	module.destroyHook = func(ctx context.Context, t testing.TB, m *Module) error { return context.DeadlineExceeded }
+
+	if err := module.Destroy(context.Background(), &recordingTB{TB: t}); err == nil {
+		t.Fatal("Destroy() should fail")
+	}
+	if called {
+		t.Error("nothing should be purged when the destroy failed")
+	}
+}
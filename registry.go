@@ -2,56 +2,270 @@ package validor
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/hashicorp/go-version"
 )
 
+const maxRetryDelay = time.Minute
+
+const defaultRegistryBaseURL = "https://registry.terraform.io/v1/modules"
+
 type DefaultRegistryClient struct {
-	baseURL string
-	client  *http.Client
+	baseURL            string
+	client             *http.Client
+	includePrereleases bool
+	maxRetries         int
+	retryDelay         time.Duration
+	// err is an invalid option, reported by every request.
+	err error
+}
+
+type RegistryOption func(*DefaultRegistryClient)
+
+func WithPrereleases(include bool) RegistryOption {
+	return func(c *DefaultRegistryClient) { c.includePrereleases = include }
+}
+
+func WithRetry(maxRetries int, baseDelay time.Duration) RegistryOption {
+	return func(c *DefaultRegistryClient) {
+		c.maxRetries = maxRetries
+		c.retryDelay = baseDelay
+	}
 }
 
-func NewRegistryClient() RegistryClient {
-	return &DefaultRegistryClient{
-		baseURL: "https://registry.terraform.io/v1/modules",
-		client:  &http.Client{Timeout: 10 * time.Second},
+// WithHTTPClient sends the registry requests with client, e.g. one with a
+// custom transport, timeout or authentication.
+func WithHTTPClient(client *http.Client) RegistryOption {
+	return func(c *DefaultRegistryClient) { c.client = client }
+}
+
+// WithTransport sends the registry requests through transport.
+func WithTransport(transport http.RoundTripper) RegistryOption {
+	return func(c *DefaultRegistryClient) {
+		client := *c.client
+		client.Transport = transport
+		c.client = &client
 	}
 }
 
+// WithRegistryBaseURL queries the modules API at url instead of the public
+// registry, e.g. https://registry.example.com/v1/modules for a private
+// registry or mirror in an air-gapped network.
+func WithRegistryBaseURL(url string) RegistryOption {
+	return func(c *DefaultRegistryClient) { c.baseURL = strings.TrimRight(url, "/") }
+}
+
+// WithCACertFile trusts the PEM certificates in path, such as a corporate CA
+// bundle, in addition to the system's. Requests keep using the proxy from
+// HTTPS_PROXY and NO_PROXY.
+func WithCACertFile(path string) RegistryOption {
+	return func(c *DefaultRegistryClient) {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			c.err = fmt.Errorf("failed to read CA certificates: %w", err)
+			return
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			c.err = fmt.Errorf("no certificates found in %s", path)
+			return
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		WithTransport(transport)(c)
+	}
+}
+
+func NewRegistryClient(opts ...RegistryOption) RegistryClient {
+	client := &DefaultRegistryClient{
+		baseURL:    defaultRegistryBaseURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		maxRetries: 3,
+		retryDelay: time.Second,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
+}
+
 func (c *DefaultRegistryClient) GetLatestVersion(ctx context.Context, namespace, name, provider string) (string, error) {
+	versions, err := c.fetchVersions(ctx, namespace, name, provider)
+	if err != nil {
+		return "", err
+	}
+
+	latest := c.highest(versions, nil)
+	if latest == nil {
+		return "", fmt.Errorf("no release versions found for module %s/%s/%s", namespace, name, provider)
+	}
+	return latest.Original(), nil
+}
+
+func (c *DefaultRegistryClient) GetLatestMatching(ctx context.Context, namespace, name, provider, constraint string) (string, error) {
+	constraints, err := version.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+
+	versions, err := c.fetchVersions(ctx, namespace, name, provider)
+	if err != nil {
+		return "", err
+	}
+
+	latest := c.highest(versions, constraints)
+	if latest == nil {
+		return "", fmt.Errorf("no versions of module %s/%s/%s match constraint %q", namespace, name, provider, constraint)
+	}
+	return latest.Original(), nil
+}
+
+// ListVersions returns the published versions of a module, oldest first.
+// Pre-releases are only included with WithPrereleases.
+func (c *DefaultRegistryClient) ListVersions(ctx context.Context, namespace, name, provider string) ([]string, error) {
+	versions, err := c.fetchVersions(ctx, namespace, name, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var listed version.Collection
+	for _, v := range versions {
+		if v.Prerelease() == "" || c.includePrereleases {
+			listed = append(listed, v)
+		}
+	}
+	sort.Sort(listed)
+
+	names := make([]string, len(listed))
+	for i, v := range listed {
+		names[i] = v.Original()
+	}
+	return names, nil
+}
+
+func (c *DefaultRegistryClient) highest(versions []*version.Version, constraints version.Constraints) *version.Version {
+	var latest *version.Version
+	for _, v := range versions {
+		if v.Prerelease() != "" && !c.includePrereleases {
+			continue
+		}
+		if constraints != nil && !constraints.Check(v) {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+		}
+	}
+	return latest
+}
+
+func (c *DefaultRegistryClient) fetchVersions(ctx context.Context, namespace, name, provider string) ([]*version.Version, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
 	url := fmt.Sprintf("%s/%s/%s/%s/versions", c.baseURL, namespace, name, provider)
 
+	body, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var registryResp TerraformRegistryResponse
+	if err := json.Unmarshal(body, &registryResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	allVersions := registryResp.AllVersions()
+	if len(allVersions) == 0 {
+		return nil, fmt.Errorf("no versions found for module %s/%s/%s", namespace, name, provider)
+	}
+
+	var versions []*version.Version
+	for _, v := range allVersions {
+		parsed, err := version.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, parsed)
+	}
+	return versions, nil
+}
+
+func (c *DefaultRegistryClient) get(ctx context.Context, url string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, err := c.getOnce(ctx, url)
+		if err == nil || retryAfter < 0 || attempt >= c.maxRetries {
+			return body, err
+		}
+
+		delay := c.retryDelay << attempt
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+		if err := sleepContext(ctx, min(delay, maxRetryDelay)); err != nil {
+			return nil, fmt.Errorf("failed to fetch module versions: %w", err)
+		}
+	}
+}
+
+// getOnce returns a negative retryAfter when the error is not worth retrying,
+// zero to use the default backoff, or the delay requested by the registry.
+func (c *DefaultRegistryClient) getOnce(ctx context.Context, url string) ([]byte, time.Duration, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, -1, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch module versions: %w", err)
+		if ctx.Err() != nil {
+			return nil, -1, fmt.Errorf("failed to fetch module versions: %w", err)
+		}
+		return nil, 0, fmt.Errorf("failed to fetch module versions: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch module versions: HTTP %d", resp.StatusCode)
+		err := fmt.Errorf("failed to fetch module versions: HTTP %d", resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, parseRetryAfter(resp.Header.Get("Retry-After")), err
+		}
+		return nil, -1, err
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, 0, fmt.Errorf("failed to read response body: %w", err)
 	}
+	return body, 0, nil
+}
 
-	var registryResp TerraformRegistryResponse
-	if err := json.Unmarshal(body, &registryResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
 	}
-
-	if len(registryResp.Versions) == 0 {
-		return "", fmt.Errorf("no versions found for module %s/%s/%s", namespace, name, provider)
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
 	}
-
-	return registryResp.Versions[0].Version, nil
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
 }
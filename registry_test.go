@@ -2,13 +2,18 @@ package validor
 
 import (
 	"context"
+	"encoding/pem"
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultRegistryClient_GetLatestVersion(t *testing.T) {
@@ -33,6 +38,8 @@ func TestDefaultRegistryClient_GetLatestVersion(t *testing.T) {
 }
 
 func TestDefaultRegistryClient_GetLatestVersion_Errors(t *testing.T) {
+	stubSleep(t)
+
 	t.Run("non-200 response", func(t *testing.T) {
 		client := NewRegistryClient().(*DefaultRegistryClient)
 		client.client = &http.Client{
@@ -85,6 +92,128 @@ func TestDefaultRegistryClient_GetLatestVersion_Errors(t *testing.T) {
 	})
 }
 
+func newTestRegistryClient(body string, opts ...RegistryOption) *DefaultRegistryClient {
+	client := NewRegistryClient(opts...).(*DefaultRegistryClient)
+	client.client = &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	return client
+}
+
+func TestDefaultRegistryClient_GetLatestVersion_Semver(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		opts []RegistryOption
+		want string
+	}{
+		{
+			name: "unordered versions",
+			body: `{"versions":[{"version":"1.9.0"},{"version":"1.10.0"},{"version":"1.2.3"}]}`,
+			want: "1.10.0",
+		},
+		{
+			name: "registry modules response shape",
+			body: `{"modules":[{"versions":[{"version":"0.9.1"},{"version":"2.1.0"},{"version":"2.0.4"}]}]}`,
+			want: "2.1.0",
+		},
+		{
+			name: "pre-releases excluded by default",
+			body: `{"versions":[{"version":"3.0.0-beta.1"},{"version":"2.4.0"}]}`,
+			want: "2.4.0",
+		},
+		{
+			name: "pre-releases included when requested",
+			body: `{"versions":[{"version":"3.0.0-beta.1"},{"version":"2.4.0"}]}`,
+			opts: []RegistryOption{WithPrereleases(true)},
+			want: "3.0.0-beta.1",
+		},
+		{
+			name: "invalid versions ignored",
+			body: `{"versions":[{"version":"not-a-version"},{"version":"1.0.0"}]}`,
+			want: "1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestRegistryClient(tt.body, tt.opts...)
+			got, err := client.GetLatestVersion(context.Background(), "ns", "name", "provider")
+			if err != nil {
+				t.Fatalf("GetLatestVersion() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetLatestVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRegistryClient_GetLatestVersion_OnlyPrereleases(t *testing.T) {
+	client := newTestRegistryClient(`{"versions":[{"version":"1.0.0-rc.1"}]}`)
+	if _, err := client.GetLatestVersion(context.Background(), "ns", "name", "provider"); err == nil {
+		t.Fatal("expected error when only pre-releases are published")
+	}
+}
+
+func TestDefaultRegistryClient_ListVersions(t *testing.T) {
+	body := `{"versions":[{"version":"1.10.0"},{"version":"2.0.0-rc.1"},{"version":"1.2.3"},{"version":"1.9.0"}]}`
+
+	got, err := newTestRegistryClient(body).ListVersions(context.Background(), "ns", "name", "provider")
+	if err != nil {
+		t.Fatalf("ListVersions() error = %v", err)
+	}
+	if want := []string{"1.2.3", "1.9.0", "1.10.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListVersions() = %v, want %v", got, want)
+	}
+
+	got, err = newTestRegistryClient(body, WithPrereleases(true)).ListVersions(context.Background(), "ns", "name", "provider")
+	if err != nil {
+		t.Fatalf("ListVersions() error = %v", err)
+	}
+	if want := []string{"1.2.3", "1.9.0", "1.10.0", "2.0.0-rc.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListVersions() with pre-releases = %v, want %v", got, want)
+	}
+}
+
+func TestDefaultRegistryClient_GetLatestMatching(t *testing.T) {
+	body := `{"versions":[{"version":"1.3.2"},{"version":"1.4.0"},{"version":"1.4.7"},{"version":"1.5.0"},{"version":"2.0.0"},{"version":"2.3.1"},{"version":"3.0.0-rc.1"}]}`
+
+	tests := []struct {
+		name       string
+		constraint string
+		want       string
+		wantErr    bool
+	}{
+		{name: "pessimistic minor", constraint: "~> 1.4", want: "1.5.0"},
+		{name: "pessimistic patch", constraint: "~> 1.4.0", want: "1.4.7"},
+		{name: "range", constraint: ">= 2, < 3", want: "2.3.1"},
+		{name: "exact", constraint: "1.3.2", want: "1.3.2"},
+		{name: "pre-releases excluded", constraint: ">= 3.0.0-rc.1", wantErr: true},
+		{name: "no match", constraint: "~> 4.0", wantErr: true},
+		{name: "invalid constraint", constraint: "latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestRegistryClient(body)
+			got, err := client.GetLatestMatching(context.Background(), "ns", "name", "provider", tt.constraint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetLatestMatching() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetLatestMatching() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDefaultSourceConverter_RevertToRegistry_Fallback(t *testing.T) {
 	tmpDir := t.TempDir()
 	tfFile := filepath.Join(tmpDir, "main.tf")
@@ -127,3 +256,198 @@ func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 	return f(req)
 }
 
+
+func stubSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	original := sleepContext
+	sleepContext = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleepContext = original })
+	return &delays
+}
+
+func TestDefaultRegistryClient_Retry(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		transport  error
+		opts       []RegistryOption
+		wantErr    bool
+		wantCalls  int
+		wantDelays []time.Duration
+	}{
+		{
+			name:       "recovers from server errors with backoff",
+			statuses:   []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			wantCalls:  3,
+			wantDelays: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:       "honors retry-after on rate limit",
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter: "7",
+			wantCalls:  2,
+			wantDelays: []time.Duration{7 * time.Second},
+		},
+		{
+			name:       "caps retry-after",
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter: "3600",
+			wantCalls:  2,
+			wantDelays: []time.Duration{maxRetryDelay},
+		},
+		{
+			name:       "gives up after max retries",
+			statuses:   []int{http.StatusInternalServerError},
+			opts:       []RegistryOption{WithRetry(2, 100*time.Millisecond)},
+			wantErr:    true,
+			wantCalls:  3,
+			wantDelays: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name:      "client errors are not retried",
+			statuses:  []int{http.StatusNotFound},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:       "transport errors are retried",
+			transport:  errors.New("connection reset"),
+			opts:       []RegistryOption{WithRetry(1, time.Second)},
+			wantErr:    true,
+			wantCalls:  2,
+			wantDelays: []time.Duration{time.Second},
+		},
+		{
+			name:      "retries disabled",
+			statuses:  []int{http.StatusServiceUnavailable},
+			opts:      []RegistryOption{WithRetry(0, time.Second)},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := stubSleep(t)
+
+			calls := 0
+			client := NewRegistryClient(tt.opts...).(*DefaultRegistryClient)
+			client.client = &http.Client{
+				Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					calls++
+					if tt.transport != nil {
+						return nil, tt.transport
+					}
+					status := tt.statuses[min(calls, len(tt.statuses))-1]
+					header := make(http.Header)
+					if tt.retryAfter != "" {
+						header.Set("Retry-After", tt.retryAfter)
+					}
+					return &http.Response{
+						StatusCode: status,
+						Body:       io.NopCloser(strings.NewReader(`{"versions":[{"version":"1.0.0"}]}`)),
+						Header:     header,
+					}, nil
+				}),
+			}
+
+			_, err := client.GetLatestVersion(context.Background(), "ns", "name", "provider")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetLatestVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", calls, tt.wantCalls)
+			}
+			if !slices.Equal(*delays, tt.wantDelays) {
+				t.Errorf("delays = %v, want %v", *delays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("5"); got != 5*time.Second {
+		t.Errorf("parseRetryAfter(seconds) = %v", got)
+	}
+	if got := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); got < 59*time.Minute {
+		t.Errorf("parseRetryAfter(date) = %v", got)
+	}
+	for _, value := range []string{"", "soon", "-1"} {
+		if got := parseRetryAfter(value); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %v, want 0", value, got)
+		}
+	}
+}
+
+func TestDefaultRegistryClient_Transport(t *testing.T) {
+	var requested string
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.String()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"versions":[{"version":"1.2.0"}]}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	tests := []struct {
+		name string
+		opts []RegistryOption
+	}{
+		{name: "transport", opts: []RegistryOption{WithTransport(transport)}},
+		{name: "http client", opts: []RegistryOption{WithHTTPClient(&http.Client{Transport: transport})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append(tt.opts, WithRegistryBaseURL("https://registry.example.com/api/v1/modules/"))
+			client := NewRegistryClient(opts...)
+			if _, err := client.GetLatestVersion(context.Background(), "ns", "name", "azure"); err != nil {
+				t.Fatalf("GetLatestVersion returned error: %v", err)
+			}
+			if want := "https://registry.example.com/api/v1/modules/ns/name/azure/versions"; requested != want {
+				t.Errorf("requested %s, want %s", requested, want)
+			}
+		})
+	}
+}
+
+func TestDefaultRegistryClient_CACertFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions":[{"version":"3.1.0"}]}`))
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, pemData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	untrusted := NewRegistryClient(WithRegistryBaseURL(server.URL), WithRetry(0, 0))
+	if _, err := untrusted.GetLatestVersion(context.Background(), "ns", "name", "azure"); err == nil {
+		t.Fatal("expected a certificate error without the CA file")
+	}
+
+	trusted := NewRegistryClient(WithRegistryBaseURL(server.URL), WithCACertFile(caFile))
+	version, err := trusted.GetLatestVersion(context.Background(), "ns", "name", "azure")
+	if err != nil || version != "3.1.0" {
+		t.Fatalf("GetLatestVersion() = %s, %v", version, err)
+	}
+
+	for name, path := range map[string]string{"missing": filepath.Join(t.TempDir(), "missing.pem"), "not pem": caFile + ".txt"} {
+		if name == "not pem" {
+			if err := os.WriteFile(path, []byte("not a certificate"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		client := NewRegistryClient(WithRegistryBaseURL(server.URL), WithCACertFile(path))
+		if _, err := client.GetLatestVersion(context.Background(), "ns", "name", "azure"); err == nil {
+			t.Errorf("%s: expected an error for an unusable CA file", name)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	BackendCloud  = "cloud"
+	BackendRemote = "remote"
+
+	defaultRemoteHostname = "app.terraform.io"
+)
+
+// RemoteBackend describes an example whose state and runs live in Terraform
+// Cloud or Enterprise, configured with a cloud block or the remote backend.
+// Runs are CLI-driven: terraform starts them remotely, polls their status and
+// streams their logs.
+type RemoteBackend struct {
+	Kind         string
+	Hostname     string
+	Organization string
+	// Workspace is the fixed workspace name, if the configuration sets one.
+	Workspace string
+	// Tags select the workspaces of a cloud block; Prefix those of the remote
+	// backend.
+	Tags   []string
+	Prefix string
+}
+
+// DetectRemoteBackend returns the remote backend configured in the Terraform
+// files of dir, or nil when state is kept by another backend.
+func DetectRemoteBackend(dir string) (*RemoteBackend, error) {
+	bodies, err := parseTerraformFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type != "terraform" {
+				continue
+			}
+			for _, nested := range block.Body.Blocks {
+				switch {
+				case nested.Type == "cloud":
+					return remoteBackend(BackendCloud, nested.Body), nil
+				case nested.Type == "backend" && len(nested.Labels) > 0 && nested.Labels[0] == BackendRemote:
+					return remoteBackend(BackendRemote, nested.Body), nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func remoteBackend(kind string, body *hclsyntax.Body) *RemoteBackend {
+	backend := &RemoteBackend{Kind: kind}
+	backend.Hostname = stringAttribute(body, "hostname")
+	backend.Organization = stringAttribute(body, "organization")
+	for _, block := range body.Blocks {
+		if block.Type != "workspaces" {
+			continue
+		}
+		backend.Workspace = stringAttribute(block.Body, "name")
+		backend.Prefix = stringAttribute(block.Body, "prefix")
+		if attr, ok := block.Body.Attributes["tags"]; ok {
+			backend.Tags = literalStrings(attr.Expr)
+		}
+	}
+
+	// A cloud block can leave these to the environment.
+	if kind == BackendCloud {
+		if backend.Hostname == "" {
+			backend.Hostname = os.Getenv("TF_CLOUD_HOSTNAME")
+		}
+		if backend.Organization == "" {
+			backend.Organization = os.Getenv("TF_CLOUD_ORGANIZATION")
+		}
+	}
+	if backend.Hostname == "" {
+		backend.Hostname = defaultRemoteHostname
+	}
+	return backend
+}
+
+func stringAttribute(body *hclsyntax.Body, name string) string {
+	if attr, ok := body.Attributes[name]; ok {
+		value, _ := literalString(attr.Expr)
+		return value
+	}
+	return ""
+}
+
+func literalStrings(expr hclsyntax.Expression) []string {
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || !(value.Type().IsListType() || value.Type().IsTupleType()) {
+		return nil
+	}
+	var values []string
+	for it := value.ElementIterator(); it.Next(); {
+		_, element := it.Element()
+		if element.Type() == cty.String && element.IsKnown() && !element.IsNull() {
+			values = append(values, element.AsString())
+		}
+	}
+	return values
+}
+
+// savedPlans reports whether the backend can save a plan to a file, which plan
+// checks, drift detection and soaking rely on. The remote backend never could.
+func (b *RemoteBackend) savedPlans() bool {
+	return b.Kind == BackendCloud
+}
+
+// selectsWorkspace reports whether the configuration maps to more than one
+// workspace, so one has to be selected for a non-interactive run.
+func (b *RemoteBackend) selectsWorkspace() bool {
+	return b.Workspace == "" && (len(b.Tags) > 0 || b.Prefix != "")
+}
+
+// useRemoteBackend detects the module's remote backend and, when its
+// configuration does not name a single workspace and TF_WORKSPACE is not set,
+// gives the example a workspace of its own so parallel examples do not share
+// state.
+func (m *Module) useRemoteBackend() error {
+	backend, err := DetectRemoteBackend(m.Options.TerraformDir)
+	if err != nil || backend == nil {
+		return err
+	}
+	m.Remote = backend
+
+	if !backend.selectsWorkspace() || m.Options.EnvVars["TF_WORKSPACE"] != "" || os.Getenv("TF_WORKSPACE") != "" {
+		return nil
+	}
+	if m.Options.EnvVars == nil {
+		m.Options.EnvVars = make(map[string]string)
+	}
+	m.Options.EnvVars["TF_WORKSPACE"] = remoteWorkspaceName(m.Name)
+	return nil
+}
+
+var workspaceNameReplacer = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+func remoteWorkspaceName(moduleName string) string {
+	return "validor-" + strings.Trim(workspaceNameReplacer.ReplaceAllString(moduleName, "-"), "-")
+}
+
+var remoteRunPattern = regexp.MustCompile(`https?://\S+/runs/run-[A-Za-z0-9]+`)
+
+// recordRemoteRuns keeps the links to the remote runs terraform printed, so a
+// failure can be looked up in Terraform Cloud.
+func (m *Module) recordRemoteRuns(t testLogger, output string) {
+	if m.Remote == nil {
+		return
+	}
+	for _, url := range remoteRunPattern.FindAllString(output, -1) {
+		if !slices.Contains(m.RemoteRuns, url) {
+			m.RemoteRuns = append(m.RemoteRuns, url)
+			t.Logf("Remote run for module %s: %s", m.Name, url)
+		}
+	}
+}
+
+func (m *Module) remotePlanError() error {
+	if m.Remote == nil || m.Remote.savedPlans() {
+		return nil
+	}
+	return errors.New("the remote backend cannot save plans, which plan checks, drift detection and soaking need; use a cloud block instead")
+}
+
+// RemoteTokenCheck verifies that terraform has an API token for hostname, in a
+// TF_TOKEN_ variable or the credentials file that terraform login writes.
+func RemoteTokenCheck(hostname string) PreflightCheck {
+	return PreflightCheck{
+		Name: hostname + " token",
+		Run: func(ctx context.Context) error {
+			if os.Getenv(remoteTokenVariable(hostname)) != "" || os.Getenv("TF_CLI_CONFIG_FILE") != "" {
+				return nil
+			}
+			if remoteCredentialsFileHas(hostname) {
+				return nil
+			}
+			return fmt.Errorf("no API token found, run terraform login %s or set %s", hostname, remoteTokenVariable(hostname))
+		},
+	}
+}
+
+// remoteTokenVariable is the variable terraform reads a host's token from:
+// dots become underscores and dashes double underscores.
+func remoteTokenVariable(hostname string) string {
+	return "TF_TOKEN_" + strings.NewReplacer(".", "_", "-", "__").Replace(hostname)
+}
+
+func remoteCredentialsFileHas(hostname string) bool {
+	dir, err := terraformConfigDir()
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "credentials.tfrc.json"))
+	if err != nil {
+		return false
+	}
+	var credentials struct {
+		Credentials map[string]struct {
+			Token string `json:"token"`
+		} `json:"credentials"`
+	}
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return false
+	}
+	return credentials.Credentials[hostname].Token != ""
+}
+
+var terraformConfigDir = func() (string, error) {
+	if goos == "windows" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "terraform.d"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".terraform.d"), nil
+}
+
+// remoteHostnames returns the distinct hosts the modules run remotely on.
+func remoteHostnames(modules []*Module) []string {
+	var hostnames []string
+	for _, module := range modules {
+		if module.Remote != nil && !slices.Contains(hostnames, module.Remote.Hostname) {
+			hostnames = append(hostnames, module.Remote.Hostname)
+		}
+	}
+	return hostnames
+}
@@ -0,0 +1,195 @@
+package validor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTerraform(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "terraform.tf"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestDetectRemoteBackend(t *testing.T) {
+	t.Setenv("TF_CLOUD_ORGANIZATION", "from-env")
+	t.Setenv("TF_CLOUD_HOSTNAME", "")
+
+	tests := []struct {
+		name   string
+		config string
+		want   *RemoteBackend
+	}{
+		{
+			name: "cloud block with tags",
+			config: `terraform {
+  cloud {
+    workspaces {
+      tags = ["validor", "ci"]
+    }
+  }
+}`,
+			want: &RemoteBackend{Kind: BackendCloud, Hostname: "app.terraform.io", Organization: "from-env", Tags: []string{"validor", "ci"}},
+		},
+		{
+			name: "cloud block with a workspace",
+			config: `terraform {
+  cloud {
+    hostname     = "tfe.example.com"
+    organization = "acme"
+    workspaces {
+      name = "network"
+    }
+  }
+}`,
+			want: &RemoteBackend{Kind: BackendCloud, Hostname: "tfe.example.com", Organization: "acme", Workspace: "network"},
+		},
+		{
+			name: "remote backend",
+			config: `terraform {
+  backend "remote" {
+    organization = "acme"
+    workspaces {
+      prefix = "network-"
+    }
+  }
+}`,
+			want: &RemoteBackend{Kind: BackendRemote, Hostname: "app.terraform.io", Organization: "acme", Prefix: "network-"},
+		},
+		{
+			name: "other backend",
+			config: `terraform {
+  backend "azurerm" {}
+}`,
+		},
+		{
+			name:   "no terraform block",
+			config: `resource "null_resource" "this" {}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectRemoteBackend(writeTerraform(t, tt.config))
+			if err != nil {
+				t.Fatalf("DetectRemoteBackend() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectRemoteBackend() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModule_UseRemoteBackend(t *testing.T) {
+	t.Setenv("TF_WORKSPACE", "")
+	tagged := `terraform {
+  cloud {
+    organization = "acme"
+    workspaces { tags = ["validor"] }
+  }
+}`
+
+	tests := []struct {
+		name          string
+		config        string
+		envVars       map[string]string
+		wantWorkspace string
+	}{
+		{name: "tags get a workspace per example", config: tagged, wantWorkspace: "validor-network-default"},
+		{name: "TF_WORKSPACE is kept", config: tagged, envVars: map[string]string{"TF_WORKSPACE": "mine"}, wantWorkspace: "mine"},
+		{name: "named workspace", config: `terraform {
+  cloud {
+    organization = "acme"
+    workspaces { name = "network" }
+  }
+}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := NewModule("network/default", writeTerraform(t, tt.config))
+			module.Options.EnvVars = tt.envVars
+			if err := module.useRemoteBackend(); err != nil {
+				t.Fatalf("useRemoteBackend() error = %v", err)
+			}
+			if module.Remote == nil {
+				t.Fatal("remote backend should be detected")
+			}
+			if got := module.Options.EnvVars["TF_WORKSPACE"]; got != tt.wantWorkspace {
+				t.Errorf("TF_WORKSPACE = %q, want %q", got, tt.wantWorkspace)
+			}
+		})
+	}
+}
+
+func TestModule_RecordRemoteRuns(t *testing.T) {
+	output := `Running apply in HCP Terraform. Output will stream here.
+
+To view this run in a browser, visit:
+https://app.terraform.io/app/acme/validor-default/runs/run-CZcmD7eagjhyX0vN
+
+Apply complete!
+https://app.terraform.io/app/acme/validor-default/runs/run-CZcmD7eagjhyX0vN`
+
+	module := NewModule("default", t.TempDir())
+	module.recordRemoteRuns(t, output)
+	if len(module.RemoteRuns) != 0 {
+		t.Errorf("local modules should not record runs, got %v", module.RemoteRuns)
+	}
+
+	module.Remote = &RemoteBackend{Kind: BackendCloud}
+	module.recordRemoteRuns(t, output)
+	if want := []string{"https://app.terraform.io/app/acme/validor-default/runs/run-CZcmD7eagjhyX0vN"}; !reflect.DeepEqual(module.RemoteRuns, want) {
+		t.Errorf("RemoteRuns = %v, want %v", module.RemoteRuns, want)
+	}
+}
+
+func TestModule_Plan_RemoteBackend(t *testing.T) {
+	module := newPlannedModule(t, "default")
+	module.Remote = &RemoteBackend{Kind: BackendRemote}
+	if _, err := module.Plan(context.Background(), t); err == nil || !strings.Contains(err.Error(), "cannot save plans") {
+		t.Errorf("Plan() error = %v, want the remote backend to be rejected", err)
+	}
+
+	module.Remote.Kind = BackendCloud
+	if _, err := module.Plan(context.Background(), t); err != nil {
+		t.Errorf("Plan() error = %v, cloud blocks support saved plans", err)
+	}
+}
+
+func TestRemoteTokenCheck(t *testing.T) {
+	configDir := t.TempDir()
+	origConfigDir := terraformConfigDir
+	t.Cleanup(func() { terraformConfigDir = origConfigDir })
+	terraformConfigDir = func() (string, error) { return configDir, nil }
+	t.Setenv("TF_CLI_CONFIG_FILE", "")
+	t.Setenv("TF_TOKEN_app_terraform_io", "")
+	t.Setenv("TF_TOKEN_tfe__1_example_com", "")
+
+	check := func(hostname string) error { return RemoteTokenCheck(hostname).Run(context.Background()) }
+
+	if err := check("app.terraform.io"); err == nil || !strings.Contains(err.Error(), "TF_TOKEN_app_terraform_io") {
+		t.Errorf("expected a missing token error, got %v", err)
+	}
+
+	t.Setenv("TF_TOKEN_tfe__1_example_com", "token")
+	if err := check("tfe-1.example.com"); err != nil {
+		t.Errorf("token variable should satisfy the check, got %v", err)
+	}
+
+	credentials := `{"credentials":{"app.terraform.io":{"token":"secret"}}}`
+	if err := os.WriteFile(filepath.Join(configDir, "credentials.tfrc.json"), []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := check("app.terraform.io"); err != nil {
+		t.Errorf("credentials file should satisfy the check, got %v", err)
+	}
+}
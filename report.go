@@ -0,0 +1,138 @@
+package validor
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+type StageResult struct {
+	Stage    Stage         `json:"stage"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration"`
+}
+
+type ModuleReport struct {
+	Name         string         `json:"name"`
+	Path         string         `json:"path,omitempty"`
+	Passed       bool           `json:"passed"`
+	Skipped      bool           `json:"skipped,omitempty"`
+	SkipReason   string         `json:"skip_reason,omitempty"`
+	Duration     time.Duration  `json:"duration"`
+	Stages       []StageResult  `json:"stages,omitempty"`
+	Errors       []string       `json:"errors,omitempty"`
+	Failures     []*ModuleError `json:"failures,omitempty"`
+	LogPath      string         `json:"log_path,omitempty"`
+	Findings     []Finding      `json:"findings,omitempty"`
+	MonthlyCost  *float64       `json:"monthly_cost,omitempty"`
+	Currency     string         `json:"currency,omitempty"`
+	Retries      int            `json:"retries,omitempty"`
+	Flaky        bool           `json:"flaky,omitempty"`
+	RemoteRuns   []string       `json:"remote_runs,omitempty"`
+	UpgradeFrom  string         `json:"upgrade_from,omitempty"`
+	Leftovers    []string       `json:"leftovers,omitempty"`
+	Outputs      map[string]any `json:"outputs,omitempty"`
+	Deprecations []Diagnostic   `json:"deprecations,omitempty"`
+	Benchmark    []StageTiming  `json:"benchmark,omitempty"`
+}
+
+// RunReport describes the outcome of a run for tooling that needs more than
+// the test log. Modules are sorted by name.
+type RunReport struct {
+	Started    time.Time      `json:"started"`
+	Finished   time.Time      `json:"finished"`
+	Modules    []ModuleReport `json:"modules"`
+	Flaky      []FlakyExample `json:"flaky_examples,omitempty"`
+	Identities []Identity     `json:"identities,omitempty"`
+}
+
+func NewRunReport(results *TestResults, started, finished time.Time) *RunReport {
+	report := &RunReport{Started: started, Finished: finished}
+	if results == nil {
+		return report
+	}
+
+	report.Flaky = results.Flaky()
+	report.Identities = results.Identities()
+	modules, _ := results.GetResults()
+	for _, module := range modules {
+		report.Modules = append(report.Modules, newModuleReport(module))
+	}
+	for name, reason := range results.Skipped() {
+		report.Modules = append(report.Modules, skippedModuleReport(name, reason))
+	}
+	sort.SliceStable(report.Modules, func(i, j int) bool { return report.Modules[i].Name < report.Modules[j].Name })
+	return report
+}
+
+func newModuleReport(module *Module) ModuleReport {
+	report := ModuleReport{
+		Name:         module.Name,
+		Path:         module.Path,
+		Passed:       len(module.Errors) == 0,
+		Duration:     module.TotalDuration(),
+		Errors:       module.Errors,
+		Failures:     module.Failures,
+		LogPath:      module.LogPath,
+		Findings:     module.Findings,
+		MonthlyCost:  module.MonthlyCost,
+		Currency:     module.Currency,
+		Retries:      module.Retries,
+		Flaky:        module.Flaky,
+		RemoteRuns:   module.RemoteRuns,
+		UpgradeFrom:  module.UpgradeFrom,
+		Leftovers:    module.Leftovers,
+		Outputs:      module.Outputs,
+		Deprecations: module.Deprecations(),
+	}
+	if module.masker != nil {
+		if module.Outputs != nil {
+			report.Outputs = module.masker.maskValue(module.Outputs).(map[string]any)
+		}
+		for i, deprecation := range report.Deprecations {
+			report.Deprecations[i].Detail = module.masker.Mask(deprecation.Detail)
+		}
+	}
+	if len(module.BenchmarkRuns) > 0 {
+		report.Benchmark = BenchmarkStats(module.BenchmarkRuns)
+	}
+	for _, stage := range module.stages() {
+		report.Stages = append(report.Stages, StageResult{Stage: stage, Passed: module.failed[stage] == nil, Duration: module.Durations[stage]})
+	}
+	return report
+}
+
+func skippedModuleReport(name, reason string) ModuleReport {
+	return ModuleReport{Name: name, Passed: true, Skipped: true, SkipReason: reason}
+}
+
+func (r *RunReport) Duration() time.Duration {
+	return r.Finished.Sub(r.Started)
+}
+
+func (r *RunReport) Passed() bool {
+	return len(r.Failed()) == 0
+}
+
+func (r *RunReport) Failed() []ModuleReport {
+	var failed []ModuleReport
+	for _, module := range r.Modules {
+		if !module.Passed {
+			failed = append(failed, module)
+		}
+	}
+	return failed
+}
+
+// RunTestsWithReport runs the runners like RunTests and returns the report
+// once every module has finished. With *testing.T and *testing.B the modules
+// run inside a "modules" subtest so parallel subtests complete before it
+// returns.
+func (r *DefaultTestRunner) RunTestsWithReport(ctx context.Context, t testing.TB, runners []ModuleRunner, parallel bool, config *Config) *RunReport {
+	started := time.Now()
+	runSubtest(t, "modules", false, func(t testing.TB) {
+		r.RunTests(ctx, t, runners, parallel, config)
+	})
+	return NewRunReport(r.Results, started, time.Now())
+}
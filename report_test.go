@@ -0,0 +1,91 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewRunReport(t *testing.T) {
+	results := NewTestResults()
+
+	passed := NewModule("default", "/tmp/default")
+	passed.Durations = map[Stage]time.Duration{StageInit: time.Second, StageApply: 2 * time.Second}
+	results.AddModule(passed)
+
+	failed := NewModule("complete", "/tmp/complete")
+	failed.Durations = map[Stage]time.Duration{StageInit: time.Second, StageApply: time.Second}
+	failed.recordError(t, StageApply, &ModuleError{ModuleName: "complete", Operation: "apply", Err: errors.New("boom")})
+	results.AddModule(failed)
+
+	results.AddSkipped("private", "in the exception list")
+
+	started := time.Now()
+	report := NewRunReport(results, started, started.Add(time.Minute))
+
+	var names []string
+	for _, module := range report.Modules {
+		names = append(names, module.Name)
+	}
+	if want := []string{"complete", "default", "private"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("modules = %v, want %v", names, want)
+	}
+	if report.Duration() != time.Minute {
+		t.Errorf("Duration() = %v", report.Duration())
+	}
+	if report.Passed() {
+		t.Error("report with a failed module should not pass")
+	}
+	if failures := report.Failed(); len(failures) != 1 || failures[0].Name != "complete" {
+		t.Errorf("Failed() = %v", failures)
+	}
+
+	complete := report.Modules[0]
+	wantStages := []StageResult{
+		{Stage: StageInit, Passed: true, Duration: time.Second},
+		{Stage: StageApply, Passed: false, Duration: time.Second},
+	}
+	if !reflect.DeepEqual(complete.Stages, wantStages) {
+		t.Errorf("Stages = %+v, want %+v", complete.Stages, wantStages)
+	}
+	if len(complete.Errors) != 1 {
+		t.Errorf("Errors = %v", complete.Errors)
+	}
+
+	private := report.Modules[2]
+	if !private.Skipped || !private.Passed || private.SkipReason != "in the exception list" {
+		t.Errorf("skipped module = %+v", private)
+	}
+}
+
+func TestNewRunReport_NilResults(t *testing.T) {
+	report := NewRunReport(nil, time.Now(), time.Now())
+	if len(report.Modules) != 0 || !report.Passed() {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestDefaultTestRunner_RunTestsWithReport(t *testing.T) {
+	runner := &DefaultTestRunner{Config: &Config{}, SourceType: "registry"}
+	recorder := &recordingTB{TB: t}
+
+	report := runner.RunTestsWithReport(context.Background(), recorder, []ModuleRunner{
+		&fakeRunner{name: "custom"},
+		&fakeRunner{name: "broken", applyErr: errors.New("boom")},
+	}, false, nil)
+
+	if len(report.Modules) != 2 {
+		t.Fatalf("expected 2 modules, got %+v", report.Modules)
+	}
+	if report.Modules[0].Name != "broken" || report.Modules[0].Passed {
+		t.Errorf("broken = %+v", report.Modules[0])
+	}
+	if !report.Modules[1].Passed {
+		t.Errorf("custom = %+v", report.Modules[1])
+	}
+	if report.Finished.Before(report.Started) {
+		t.Error("Finished should not be before Started")
+	}
+}
@@ -0,0 +1,293 @@
+package validor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Reporter writes the outcome of a run in a format a CI platform understands.
+// Path is where to write it; reporters that print to the job log ignore it.
+type Reporter interface {
+	Report(ctx context.Context, report *RunReport, path string) error
+}
+
+type ReporterFunc func(ctx context.Context, report *RunReport, path string) error
+
+func (f ReporterFunc) Report(ctx context.Context, report *RunReport, path string) error {
+	return f(ctx, report, path)
+}
+
+var (
+	reportersMu sync.RWMutex
+	reporters   = map[string]Reporter{
+		"junit":        ReporterFunc(writeJUnitReport),
+		"gitlab":       ReporterFunc(writeGitLabReport),
+		"azure-devops": ReporterFunc(writeAzureDevOpsReport),
+	}
+	defaultReportPaths = map[string]string{
+		"junit":  "validor-junit.xml",
+		"gitlab": "gl-code-quality-report.json",
+	}
+)
+
+// RegisterReporter makes a reporter available under name for WithReport and
+// the -report flag, replacing any reporter of that name.
+func RegisterReporter(name string, reporter Reporter) {
+	reportersMu.Lock()
+	defer reportersMu.Unlock()
+	reporters[name] = reporter
+}
+
+// WithReport writes the run's outcome with the named reporter: junit, gitlab
+// (code quality JSON), azure-devops (logging commands) or one registered with
+// RegisterReporter. An empty path uses the reporter's default.
+func WithReport(name, path string) Option {
+	return func(c *Config) {
+		if path != "" {
+			name += "=" + path
+		}
+		c.Reports = append(c.Reports, name)
+	}
+}
+
+// writeReports runs the reporters in config.Reports, each given as a name
+// with an optional =path.
+func writeReports(ctx context.Context, config *Config, report *RunReport) error {
+	var errs []string
+	for _, spec := range config.Reports {
+		name, path, _ := strings.Cut(spec, "=")
+		if path == "" {
+			path = defaultReportPaths[name]
+		}
+
+		reportersMu.RLock()
+		reporter, ok := reporters[name]
+		reportersMu.RUnlock()
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown reporter %q", name))
+			continue
+		}
+		if err := reporter.Report(ctx, report, path); err != nil {
+			errs = append(errs, fmt.Sprintf("%s report: %v", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to write reports: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// reportOutput opens path for writing, or stdout when path is empty.
+func reportOutput(path string) (io.WriteCloser, error) {
+	if path == "" {
+		return nopWriteCloser{reportStdout}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+var reportStdout io.Writer = os.Stdout
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+type junitTestSuite struct {
+	XMLName    xml.Name        `xml:"testsuite"`
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       float64         `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name    string        `xml:"name,attr"`
+	Class   string        `xml:"classname,attr"`
+	Time    float64       `xml:"time,attr"`
+	Failure *junitMessage `xml:"failure,omitempty"`
+	Skipped *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnitReport(ctx context.Context, report *RunReport, path string) error {
+	suite := junitTestSuite{Name: "validor", Time: report.Duration().Seconds()}
+	for _, identity := range report.Identities {
+		suite.Properties = append(suite.Properties, junitProperty{Name: identity.Cloud + ".identity", Value: identity.String()})
+	}
+	for _, module := range report.Modules {
+		testCase := junitTestCase{Name: module.Name, Class: "validor", Time: module.Duration.Seconds()}
+		switch {
+		case module.Skipped:
+			testCase.Skipped = &junitMessage{Message: module.SkipReason}
+			suite.Skipped++
+		case !module.Passed:
+			testCase.Failure = &junitMessage{Message: firstError(module), Text: strings.Join(module.Errors, "\n")}
+			if len(module.Failures) > 0 {
+				testCase.Failure.Type = string(module.Failures[0].Code)
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Tests = len(suite.Cases)
+
+	out, err := reportOutput(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, "\n")
+	return err
+}
+
+// gitLabIssue is an entry of GitLab's code quality report, which merge
+// requests show inline next to the file it points at.
+type gitLabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitLabLocation `json:"location"`
+}
+
+type gitLabLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+func writeGitLabReport(ctx context.Context, report *RunReport, path string) error {
+	issues := []gitLabIssue{}
+	for _, module := range report.Modules {
+		var messages []string
+		severity := "major"
+		switch {
+		case !module.Passed:
+			messages = module.Errors
+		case module.Flaky:
+			messages = []string{fmt.Sprintf("passed after %d rerun(s)", module.Retries)}
+			severity = "minor"
+		}
+		for _, message := range messages {
+			issue := gitLabIssue{
+				Description: fmt.Sprintf("Example %s: %s", module.Name, message),
+				CheckName:   "validor",
+				Fingerprint: reportFingerprint(module.Name, message),
+				Severity:    severity,
+				Location:    gitLabLocation{Path: reportSourcePath(module.Path)},
+			}
+			issue.Location.Lines.Begin = 1
+			issues = append(issues, issue)
+		}
+	}
+
+	out, err := reportOutput(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(issues)
+}
+
+// writeAzureDevOpsReport prints logging commands, which Azure Pipelines turns
+// into errors and warnings on the job. They have to reach the agent on stdout,
+// so path is only for capturing them.
+func writeAzureDevOpsReport(ctx context.Context, report *RunReport, path string) error {
+	out, err := reportOutput(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	for _, module := range report.Modules {
+		source := azureProperty(reportSourcePath(module.Path))
+		switch {
+		case !module.Passed:
+			for _, message := range module.Errors {
+				fmt.Fprintf(out, "##vso[task.logissue type=error;sourcepath=%s]%s\n", source, azureMessage("Example "+module.Name+": "+message))
+			}
+		case module.Flaky:
+			fmt.Fprintf(out, "##vso[task.logissue type=warning;sourcepath=%s]%s\n", source, azureMessage(fmt.Sprintf("Example %s passed after %d rerun(s)", module.Name, module.Retries)))
+		}
+	}
+	if !report.Passed() {
+		fmt.Fprintf(out, "##vso[task.complete result=Failed]%s\n", azureMessage(fmt.Sprintf("%d example(s) failed", len(report.Failed()))))
+	}
+	return nil
+}
+
+func azureMessage(message string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A").Replace(message)
+}
+
+func azureProperty(value string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A", ";", "%3B", "]", "%5D").Replace(value)
+}
+
+// reportSourcePath makes an example path relative to the checkout, which is
+// what CI platforms resolve annotations against.
+func reportSourcePath(path string) string {
+	if path == "" {
+		return ""
+	}
+	for _, root := range []string{os.Getenv("CI_PROJECT_DIR"), os.Getenv("BUILD_SOURCESDIRECTORY")} {
+		if root == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			break
+		}
+		if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+func reportFingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+func firstError(module ModuleReport) string {
+	if len(module.Errors) == 0 {
+		return ""
+	}
+	return module.Errors[0]
+}
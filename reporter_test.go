@@ -0,0 +1,161 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func reporterRunReport() *RunReport {
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &RunReport{
+		Started:  started,
+		Finished: started.Add(time.Minute),
+		Modules: []ModuleReport{
+			{Name: "complete", Path: "examples/complete", Duration: 30 * time.Second, Errors: []string{"apply failed: quota; exceeded\nretry later"}},
+			{Name: "default", Path: "examples/default", Passed: true, Duration: 20 * time.Second, Retries: 1, Flaky: true},
+			{Name: "private", Passed: true, Skipped: true, SkipReason: "in the exception list"},
+		},
+		Identities: []Identity{{Cloud: "azure", Account: "0000-1111", Tenant: "2222"}},
+	}
+}
+
+func TestWriteReports_JUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "junit.xml")
+	config := NewConfig(WithReport("junit", path))
+
+	if err := writeReports(context.Background(), config, reporterRunReport()); err != nil {
+		t.Fatalf("writeReports() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suite junitTestSuite
+	if err := xml.Unmarshal(data, &suite); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, data)
+	}
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 || suite.Time != 60 {
+		t.Errorf("suite = %+v", suite)
+	}
+	if failure := suite.Cases[0].Failure; failure == nil || !strings.HasPrefix(failure.Message, "apply failed") {
+		t.Errorf("complete should fail, got %+v", suite.Cases[0])
+	}
+	if suite.Cases[1].Failure != nil || suite.Cases[1].Skipped != nil {
+		t.Errorf("default should pass, got %+v", suite.Cases[1])
+	}
+	if skipped := suite.Cases[2].Skipped; skipped == nil || skipped.Message != "in the exception list" {
+		t.Errorf("private should be skipped, got %+v", suite.Cases[2])
+	}
+	if want := []junitProperty{{Name: "azure.identity", Value: "azure 0000-1111 in tenant 2222"}}; len(suite.Properties) != 1 || suite.Properties[0] != want[0] {
+		t.Errorf("properties = %+v, want %+v", suite.Properties, want)
+	}
+}
+
+func TestWriteReports_GitLab(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("CI_PROJECT_DIR", root)
+	t.Setenv("BUILD_SOURCESDIRECTORY", "")
+	path := filepath.Join(root, "gl-code-quality-report.json")
+
+	report := reporterRunReport()
+	report.Modules[0].Path = filepath.Join(root, "examples", "complete")
+	if err := writeReports(context.Background(), NewConfig(WithReport("gitlab", path)), report); err != nil {
+		t.Fatalf("writeReports() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []gitLabIssue
+	if err := json.Unmarshal(data, &issues); err != nil {
+		t.Fatalf("invalid code quality JSON: %v\n%s", err, data)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("issues = %+v, want the failure and the flaky example", issues)
+	}
+
+	failure := issues[0]
+	if failure.Severity != "major" || failure.Location.Path != "examples/complete" || failure.Location.Lines.Begin != 1 {
+		t.Errorf("failure issue = %+v", failure)
+	}
+	if !strings.HasPrefix(failure.Description, "Example complete: apply failed") {
+		t.Errorf("Description = %q", failure.Description)
+	}
+	if len(failure.Fingerprint) != 32 || failure.Fingerprint == issues[1].Fingerprint {
+		t.Errorf("fingerprints should be distinct hashes, got %q and %q", failure.Fingerprint, issues[1].Fingerprint)
+	}
+	if issues[1].Severity != "minor" || !strings.Contains(issues[1].Description, "passed after 1 rerun(s)") {
+		t.Errorf("flaky issue = %+v", issues[1])
+	}
+}
+
+func TestWriteReports_AzureDevOps(t *testing.T) {
+	t.Setenv("CI_PROJECT_DIR", "")
+	t.Setenv("BUILD_SOURCESDIRECTORY", "")
+	var output bytes.Buffer
+	orig := reportStdout
+	t.Cleanup(func() { reportStdout = orig })
+	reportStdout = &output
+
+	if err := writeReports(context.Background(), NewConfig(WithReport("azure-devops", "")), reporterRunReport()); err != nil {
+		t.Fatalf("writeReports() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"##vso[task.logissue type=error;sourcepath=examples/complete]Example complete: apply failed: quota; exceeded%0Aretry later",
+		"##vso[task.logissue type=warning;sourcepath=examples/default]Example default passed after 1 rerun(s)",
+		"##vso[task.complete result=Failed]1 example(s) failed",
+		"",
+	}, "\n")
+	if output.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", output.String(), want)
+	}
+}
+
+func TestWriteReports_Registered(t *testing.T) {
+	var got string
+	RegisterReporter("custom", ReporterFunc(func(ctx context.Context, report *RunReport, path string) error {
+		got = path
+		return nil
+	}))
+	t.Cleanup(func() {
+		reportersMu.Lock()
+		delete(reporters, "custom")
+		reportersMu.Unlock()
+	})
+
+	config := NewConfig()
+	if err := listFlag(&config.Reports)("custom=out.txt, missing"); err != nil {
+		t.Fatal(err)
+	}
+	err := writeReports(context.Background(), config, reporterRunReport())
+	if got != "out.txt" {
+		t.Errorf("custom reporter got path %q, want out.txt", got)
+	}
+	if err == nil || !strings.Contains(err.Error(), `unknown reporter "missing"`) {
+		t.Errorf("writeReports() error = %v, want an unknown reporter error", err)
+	}
+}
+
+func TestAzureProperty(t *testing.T) {
+	tests := map[string]string{
+		"examples/default": "examples/default",
+		"a;b]c":            "a%3Bb%5Dc",
+		"100%":             "100%AZP25",
+		"line\r\nbreak":    "line%0D%0Abreak",
+	}
+	for value, want := range tests {
+		if got := azureProperty(value); got != want {
+			t.Errorf("azureProperty(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
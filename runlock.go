@@ -0,0 +1,319 @@
+package validor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// WithRunLock makes runs that share target wait for each other, so pipelines
+// testing modules against the same subscription or account do not run
+// concurrently and exceed its quotas. The target is a file on a shared
+// filesystem or the http(s) URL of a blob, such as an Azure storage SAS URL,
+// that is created with If-None-Match: * and deleted when the run completes. The
+// URL must allow PUT, GET and DELETE, so presigned S3 URLs, which are signed
+// for a single method, do not work.
+func WithRunLock(target string) Option {
+	return func(c *Config) { c.RunLock = target }
+}
+
+// WithRunLockTimeout sets how long a run waits for the run lock before it
+// fails, which defaults to an hour.
+func WithRunLockTimeout(timeout time.Duration) Option {
+	return func(c *Config) { c.RunLockTimeout = timeout }
+}
+
+// WithRunLockTTL sets how old a run lock can get before waiting runs consider
+// its holder gone and remove it, which defaults to six hours. Runs that take
+// longer need a longer TTL.
+func WithRunLockTTL(ttl time.Duration) Option {
+	return func(c *Config) { c.RunLockTTL = ttl }
+}
+
+const (
+	defaultRunLockTimeout = time.Hour
+	defaultRunLockTTL     = 6 * time.Hour
+)
+
+var runLockPollInterval = 15 * time.Second
+
+// lockHolder is the content of a run lock, describing the run that holds it.
+type lockHolder struct {
+	Host  string    `json:"host"`
+	PID   int       `json:"pid"`
+	Job   string    `json:"job,omitempty"`
+	Since time.Time `json:"since"`
+}
+
+func currentLockHolder() lockHolder {
+	host, _ := os.Hostname()
+	return lockHolder{Host: host, PID: os.Getpid(), Job: ciJobURL(), Since: time.Now().UTC()}
+}
+
+// staleHolder reports whether the lock with content was taken longer than ttl
+// ago, so the run holding it most likely crashed.
+func staleHolder(content []byte, ttl time.Duration) bool {
+	var holder lockHolder
+	if err := json.Unmarshal(content, &holder); err != nil || holder.Since.IsZero() {
+		return false
+	}
+	return time.Since(holder.Since) > ttl
+}
+
+// redactLockTarget returns target without the query of a blob URL, which can
+// hold a SAS token or the signature of a presigned URL.
+func redactLockTarget(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return target
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// describeHolder describes the run in the content of a lock, or returns
+// "another run" when the content cannot be read.
+func describeHolder(content []byte) string {
+	var holder lockHolder
+	if err := json.Unmarshal(content, &holder); err != nil || holder.Host == "" {
+		return "another run"
+	}
+	description := fmt.Sprintf("%s (pid %d) since %s", holder.Host, holder.PID, holder.Since.UTC().Format(time.RFC3339))
+	if holder.Job != "" {
+		description = holder.Job + " on " + description
+	}
+	return description
+}
+
+// crossRunLock is a lock shared by runs in different processes. tryLock
+// returns the content of the lock when another run holds it, and breakStale
+// removes the lock only while it still has that content.
+type crossRunLock interface {
+	tryLock(ctx context.Context, content []byte) (held []byte, ok bool, err error)
+	breakStale(ctx context.Context, held []byte) error
+	unlock(ctx context.Context) error
+}
+
+func newCrossRunLock(target string) crossRunLock {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return &blobRunLock{url: target}
+	}
+	return fileRunLock{path: target}
+}
+
+type fileRunLock struct {
+	path string
+}
+
+func (l fileRunLock) tryLock(ctx context.Context, content []byte) ([]byte, bool, error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return nil, false, err
+	}
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		held, _ := os.ReadFile(l.path)
+		return held, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		os.Remove(l.path)
+		return nil, false, err
+	}
+	return nil, true, file.Close()
+}
+
+// breakStale removes the lock file if it still holds held. Another run that
+// breaks the same lock in between is not detected, which is unlikely given
+// how long a lock has to be held before it is stale.
+func (l fileRunLock) breakStale(ctx context.Context, held []byte) error {
+	current, err := os.ReadFile(l.path)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && !bytes.Equal(current, held) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return l.unlock(ctx)
+}
+
+func (l fileRunLock) unlock(ctx context.Context) error {
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+type blobRunLock struct {
+	url     string
+	content []byte
+	etag    string
+}
+
+// tryLock creates the blob and remembers the content and ETag it was created
+// with, so unlock only deletes the lock this run took.
+func (l *blobRunLock) tryLock(ctx context.Context, content []byte) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, l.url, bytes.NewReader(content))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-None-Match", "*")
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+
+	resp, err := reportHTTPClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		l.content, l.etag = content, resp.Header.Get("ETag")
+		return nil, true, nil
+	case resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusPreconditionFailed:
+		held, _ := l.read(ctx)
+		return held, false, nil
+	}
+	return nil, false, fmt.Errorf("HTTP %d", resp.StatusCode)
+}
+
+func (l *blobRunLock) read(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := reportHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+}
+
+// breakStale deletes the blob if it still holds held, with If-Match on the
+// ETag it was read with so a lock another run took in between is kept.
+func (l *blobRunLock) breakStale(ctx context.Context, held []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := reportHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	current, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound || !bytes.Equal(current, held) {
+		return nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return l.delete(ctx, resp.Header.Get("ETag"))
+}
+
+// unlock deletes the blob with If-Match on the ETag it was created with, so a
+// lock another run took after this one was broken as stale is kept. Without
+// an ETag, it deletes the blob only while it still has the content this run
+// wrote.
+func (l *blobRunLock) unlock(ctx context.Context) error {
+	if l.etag == "" {
+		return l.breakStale(ctx, l.content)
+	}
+	return l.delete(ctx, l.etag)
+}
+
+func (l *blobRunLock) delete(ctx context.Context, etag string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, l.url, nil)
+	if err != nil {
+		return err
+	}
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	resp, err := reportHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusPreconditionFailed {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// acquireRunLock waits until the run lock at target is free and takes it,
+// logging which run holds it while waiting. A lock taken longer than ttl ago
+// is removed as its run most likely crashed. The returned function releases
+// it.
+func acquireRunLock(ctx context.Context, t testing.TB, target string, timeout, ttl time.Duration) (func() error, error) {
+	if timeout <= 0 {
+		timeout = defaultRunLockTimeout
+	}
+	if ttl <= 0 {
+		ttl = defaultRunLockTTL
+	}
+	lock := newCrossRunLock(target)
+	name := redactLockTarget(target)
+	content, err := json.Marshal(currentLockHolder())
+	if err != nil {
+		return nil, err
+	}
+
+	var holder string
+	for waited := time.Duration(0); ; {
+		held, ok, err := lock.tryLock(ctx, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to take the run lock %s: %w", name, err)
+		}
+		if ok {
+			if waited > 0 {
+				t.Logf("Took the run lock %s after waiting %s", name, waited)
+			}
+			return func() error {
+				if err := lock.unlock(context.WithoutCancel(ctx)); err != nil {
+					return fmt.Errorf("failed to release the run lock %s: %w", name, err)
+				}
+				return nil
+			}, nil
+		}
+
+		if staleHolder(held, ttl) {
+			t.Logf("Removing the run lock %s, held by %s for longer than %s", name, describeHolder(held), ttl)
+			if err := lock.breakStale(ctx, held); err != nil {
+				return nil, fmt.Errorf("failed to remove the stale run lock %s: %w", name, err)
+			}
+			continue
+		}
+		if waited >= timeout {
+			return nil, fmt.Errorf("timed out after %s waiting for the run lock %s, held by %s; remove it if that run is no longer active", timeout, name, describeHolder(held))
+		}
+		if current := describeHolder(held); current != holder {
+			holder = current
+			t.Logf("Waiting up to %s for the run lock %s, held by %s", timeout-waited, name, holder)
+		}
+		if err := sleepContext(ctx, runLockPollInterval); err != nil {
+			return nil, fmt.Errorf("stopped waiting for the run lock %s: %w", name, err)
+		}
+		waited += runLockPollInterval
+	}
+}
@@ -0,0 +1,224 @@
+package validor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newBlobLockServer serves a single blob with the conditional requests the
+// run lock uses.
+func newBlobLockServer(t *testing.T) *httptest.Server {
+	var (
+		mu      sync.Mutex
+		blob    []byte
+		version int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		etag := fmt.Sprintf(`"v%d"`, version)
+		switch r.Method {
+		case http.MethodPut:
+			if r.Header.Get("If-None-Match") != "*" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if blob != nil {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			blob, _ = io.ReadAll(r.Body)
+			version++
+			w.Header().Set("ETag", fmt.Sprintf(`"v%d"`, version))
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			if blob == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Write(blob)
+		case http.MethodDelete:
+			if match := r.Header.Get("If-Match"); match != "" && match != etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			blob = nil
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAcquireRunLock(t *testing.T) {
+	server := newBlobLockServer(t)
+
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{name: "file", target: filepath.Join(t.TempDir(), "locks", "subscription.lock")},
+		{name: "blob", target: server.URL + "/locks/subscription.lock?sig=abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := stubSleep(t)
+			ctx := context.Background()
+
+			release, err := acquireRunLock(ctx, t, tt.target, time.Minute, 0)
+			if err != nil {
+				t.Fatalf("acquireRunLock() error = %v", err)
+			}
+
+			tb := &recordingTB{TB: t}
+			_, err = acquireRunLock(ctx, tb, tt.target, 40*time.Second, 0)
+			if err == nil || !strings.Contains(err.Error(), "timed out after 40s waiting for the run lock") || !strings.Contains(err.Error(), "(pid ") {
+				t.Fatalf("acquireRunLock() error = %v, want a timeout naming the holder", err)
+			}
+			if len(*delays) != 3 {
+				t.Errorf("waited %d times, want 3", len(*delays))
+			}
+			if len(tb.logs) != 1 || !strings.Contains(tb.logs[0], "Waiting up to 40s for the run lock") {
+				t.Errorf("logs = %q, want one waiting message", tb.logs)
+			}
+			if strings.Contains(err.Error()+strings.Join(tb.logs, "\n"), "sig=abc") {
+				t.Errorf("the SAS token should not be logged: %v %q", err, tb.logs)
+			}
+
+			if err := release(); err != nil {
+				t.Fatalf("release() error = %v", err)
+			}
+			release, err = acquireRunLock(ctx, t, tt.target, time.Minute, 0)
+			if err != nil {
+				t.Fatalf("acquireRunLock() after release error = %v", err)
+			}
+			if err := release(); err != nil {
+				t.Fatalf("release() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestAcquireRunLock_Stale(t *testing.T) {
+	server := newBlobLockServer(t)
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{name: "file", target: filepath.Join(t.TempDir(), "subscription.lock")},
+		{name: "blob", target: server.URL + "/locks/subscription.lock?sig=abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := stubSleep(t)
+			ctx := context.Background()
+			lock := newCrossRunLock(tt.target)
+			crashed := []byte(`{"host":"runner-1","pid":42,"since":"` + time.Now().Add(-7*time.Hour).UTC().Format(time.RFC3339) + `"}`)
+			if _, ok, err := lock.tryLock(ctx, crashed); err != nil || !ok {
+				t.Fatalf("tryLock() = %v, %v", ok, err)
+			}
+
+			tb := &recordingTB{TB: t}
+			release, err := acquireRunLock(ctx, tb, tt.target, time.Minute, 0)
+			if err != nil {
+				t.Fatalf("acquireRunLock() error = %v", err)
+			}
+			defer release()
+			if len(*delays) != 0 {
+				t.Errorf("waited %d times for a stale lock, want 0", len(*delays))
+			}
+			if len(tb.logs) != 1 || !strings.Contains(tb.logs[0], "held by runner-1 (pid 42)") || !strings.Contains(tb.logs[0], "longer than 6h0m0s") {
+				t.Errorf("logs = %q, want the stale lock to be removed", tb.logs)
+			}
+
+			// A lock younger than the TTL is waited for.
+			_, err = acquireRunLock(ctx, &recordingTB{TB: t}, tt.target, 15*time.Second, time.Hour)
+			if err == nil || !strings.Contains(err.Error(), "timed out") {
+				t.Errorf("acquireRunLock() error = %v, want a timeout", err)
+			}
+		})
+	}
+}
+
+func TestBlobRunLock_UnlockKeepsNextHolder(t *testing.T) {
+	server := newBlobLockServer(t)
+	ctx := context.Background()
+	target := server.URL + "/locks/subscription.lock"
+
+	crashed := newCrossRunLock(target)
+	stale := []byte(`{"host":"runner-1","pid":42,"since":"2020-01-01T00:00:00Z"}`)
+	if _, ok, err := crashed.tryLock(ctx, stale); err != nil || !ok {
+		t.Fatalf("tryLock() = %v, %v", ok, err)
+	}
+	next := newCrossRunLock(target)
+	if err := next.breakStale(ctx, stale); err != nil {
+		t.Fatalf("breakStale() error = %v", err)
+	}
+	if _, ok, err := next.tryLock(ctx, []byte(`{"host":"runner-2"}`)); err != nil || !ok {
+		t.Fatalf("tryLock() = %v, %v", ok, err)
+	}
+
+	// The run whose lock was broken finishes after all.
+	if err := crashed.unlock(ctx); err != nil {
+		t.Fatalf("unlock() error = %v", err)
+	}
+	if held, ok, err := newCrossRunLock(target).tryLock(ctx, []byte("{}")); err != nil || ok || !strings.Contains(string(held), "runner-2") {
+		t.Errorf("tryLock() = %q, %v, %v; want the next holder's lock to be kept", held, ok, err)
+	}
+	if err := next.unlock(ctx); err != nil {
+		t.Fatalf("unlock() error = %v", err)
+	}
+	if _, ok, err := newCrossRunLock(target).tryLock(ctx, []byte("{}")); err != nil || !ok {
+		t.Errorf("tryLock() = %v, %v; want the lock to be released", ok, err)
+	}
+}
+
+func TestRedactLockTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "/mnt/shared/subscription.lock", want: "/mnt/shared/subscription.lock"},
+		{target: "https://account.blob.core.windows.net/locks/sub.lock?sv=2022&sig=secret", want: "https://account.blob.core.windows.net/locks/sub.lock"},
+		{target: "https://bucket.s3.amazonaws.com/sub.lock?X-Amz-Signature=secret", want: "https://bucket.s3.amazonaws.com/sub.lock"},
+	}
+	for _, tt := range tests {
+		if got := redactLockTarget(tt.target); got != tt.want {
+			t.Errorf("redactLockTarget(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeHolder(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "local run", content: `{"host":"runner-1","pid":42,"since":"2026-10-16T08:00:00Z"}`, want: "runner-1 (pid 42) since 2026-10-16T08:00:00Z"},
+		{
+			name:    "CI job",
+			content: `{"host":"runner-1","pid":42,"job":"https://gitlab.com/acme/vnet/-/jobs/1","since":"2026-10-16T08:00:00Z"}`,
+			want:    "https://gitlab.com/acme/vnet/-/jobs/1 on runner-1 (pid 42) since 2026-10-16T08:00:00Z",
+		},
+		{name: "unreadable", content: "locked", want: "another run"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeHolder([]byte(tt.content)); got != tt.want {
+				t.Errorf("describeHolder() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
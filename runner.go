@@ -1,9 +1,994 @@
 package validor
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/rego"
 )
 
-func RunTests(t *testing.T, modules []*Module, parallel bool, config *Config) {
-	runModuleTests(t, modules, parallel, config, nil, "registry")
+// RunTests runs the modules with registry sources.
+//
+// Deprecated: use DefaultTestRunner.RunTests, which takes a context, setup
+// functions and exposes the results.
+func RunTests(t testing.TB, runners []ModuleRunner, parallel bool, config *Config) {
+	runModuleTests(t, runners, parallel, config, nil, "registry")
+}
+
+// DefaultTestRunner is the TestRunner behind the Test* entry points. Setup runs
+// once before any module, and Results collects every module as its subtest
+// finishes, so it is complete once the subtests have returned.
+type DefaultTestRunner struct {
+	Config     *Config
+	Setup      TestSetupFunc
+	SourceType string
+	Results    *TestResults
+}
+
+var _ TestRunner = (*DefaultTestRunner)(nil)
+
+func (r *DefaultTestRunner) config() *Config {
+	if r.Config != nil {
+		return r.Config
+	}
+	return GetConfig()
+}
+
+func (r *DefaultTestRunner) sourceType() string {
+	if r.SourceType == "" {
+		return "registry"
+	}
+	return r.SourceType
+}
+
+// RunLocalTests discovers the examples in examplesPath and runs them against
+// the local module source, restoring the registry sources afterwards. It uses
+// r.Config, or the shared config when that is nil, without modifying it.
+func (r *DefaultTestRunner) RunLocalTests(ctx context.Context, t testing.TB, examplesPath string) error {
+	local := *r.config()
+	local.ExamplesPath = examplesPath
+	local.Local = true
+	local.ParseExceptionList()
+
+	modules, err := findModules(&local)
+	if err != nil {
+		return fmt.Errorf("failed to discover modules: %w", err)
+	}
+	if local.DryRun {
+		previewLocalConversion(t, &local, modules)
+		return nil
+	}
+	if local.PlanDiff {
+		diffPlans(t, &local, modules)
+		return nil
+	}
+
+	runner := *r
+	runner.SourceType = "local"
+	runner.Setup = chainSetup(createLocalSetupFunc(&local), r.Setup)
+	runner.RunTests(ctx, t, Runners(modules), true, &local)
+	r.Results = runner.Results
+	return nil
+}
+
+func chainSetup(funcs ...TestSetupFunc) TestSetupFunc {
+	return func(ctx context.Context, t testing.TB, modules []*Module) error {
+		for _, setup := range funcs {
+			if setup == nil {
+				continue
+			}
+			if err := setup(ctx, t, modules); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// RunTests applies and then destroys every runner in its own subtest. Runners
+// backed by a Module also get the stages configured on config, such as scans,
+// drift detection and state assertions. A nil config falls back to r.Config.
+func (r *DefaultTestRunner) RunTests(ctx context.Context, t testing.TB, runners []ModuleRunner, parallel bool, config *Config) {
+	if config == nil {
+		config = r.config()
+	}
+	useColors(config)
+	started := time.Now()
+	results := NewTestResults()
+	r.Results = results
+	observer := observers(config.Observers)
+	if config.CIAdapter != "" {
+		adapter, err := NewCIAdapter(config.CIAdapter)
+		if err != nil {
+			t.Fatal(errorText(err.Error()))
+			return
+		}
+		observer = append(slices.Clip(observer), adapter)
+	}
+
+	binary, err := terraformBinary(ctx, config)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Failed to install terraform: %v", err)))
+		return
+	}
+	if binary != "" {
+		t.Logf("Using terraform installed at %s", binary)
+		for _, module := range runnerModules(runners) {
+			module.Options.TerraformBinary = binary
+		}
+	}
+	if config.Offline {
+		for _, module := range runnerModules(runners) {
+			useOffline(module.Options)
+		}
+	}
+	for _, module := range runnerModules(runners) {
+		module.useTerragrunt(config.TerragruntBinary, binary)
+	}
+
+	secrets, err := resolveSecrets(ctx, config.Secrets)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Failed to resolve secrets:\n%v", err)))
+		return
+	}
+	for _, runner := range runners {
+		if native, ok := runner.(*NativeTestRunner); ok {
+			useSecrets(native.Options, secrets)
+		} else if module := moduleOf(runner); module != nil {
+			useSecrets(module.Options, secrets)
+		}
+	}
+
+	for _, module := range runnerModules(runners) {
+		if err := module.useRemoteBackend(); err != nil {
+			t.Logf("Warning: failed to detect the backend of %s: %v", module.Name, err)
+		}
+	}
+
+	recordIdentity := func(identity Identity) {
+		t.Logf("Running as %s", identity)
+		results.AddIdentity(identity)
+	}
+	if err := RunPreflight(ctx, preflightChecks(config, runnerModules(runners), binary, recordIdentity)); err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Preflight checks failed:\n%v", err)))
+		return
+	}
+
+	if config.RunLock != "" {
+		release, err := acquireRunLock(ctx, t, config.RunLock, config.RunLockTimeout, config.RunLockTTL)
+		if err != nil {
+			t.Fatal(errorText(err.Error()))
+			return
+		}
+		t.Cleanup(func() {
+			if err := release(); err != nil {
+				t.Logf("Warning: %v", err)
+			}
+		})
+	}
+
+	if r.Setup != nil {
+		if err := r.Setup(ctx, t, runnerModules(runners)); err != nil {
+			t.Fatal(errorText(fmt.Sprintf("Setup failed: %v", err)))
+			return
+		}
+	}
+
+	runners, err = expandMatrix(runners, config.Matrix, config.ExceptionList)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Invalid matrix: %v", err)))
+		return
+	}
+	if config.UpgradeReleases > 0 {
+		repoInfo := config.moduleInfo()
+		var err error
+		runners, err = expandReleases(ctx, runners, config.registryClient(), config.UpgradeReleases, repoInfo)
+		if err != nil {
+			t.Fatal(errorText(fmt.Sprintf("Failed to list releases: %v", err)))
+			return
+		}
+	}
+	runners, shard, err := shardRunners(runners, config)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Failed to shard examples: %v", err)))
+		return
+	}
+	if shard != "" {
+		t.Log(shard)
+	}
+	if config.Shuffle {
+		var seed int64
+		runners, seed = shuffleRunners(runners, config.ShuffleSeed)
+		t.Logf("Shuffled examples with seed %d; run with -shuffle=%d to reproduce the order", seed, seed)
+	}
+	runners, err = orderByDependencies(orderByWeight(runners, config.Weights))
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Invalid example dependencies: %v", err)))
+		return
+	}
+	modules := runnerModules(runners)
+	dependencies := dependencyIndices(runners)
+	dependencyStates := make([]*dependencyState, len(runners))
+	for i := range runners {
+		dependencyStates[i] = &dependencyState{done: make(chan struct{})}
+	}
+
+	if config.BenchmarkIterations > 1 && (config.PhasedDestroy || config.SkipDestroy) {
+		t.Fatal(errorText("Benchmarks destroy every iteration and cannot be combined with phased destroy or skip destroy"))
+		return
+	}
+
+	if config.PhasedDestroy && slices.ContainsFunc(modules, func(m *Module) bool { return m.runLock != nil }) {
+		t.Fatal(errorText("Phased destroy cannot be combined with a matrix or -upgrade-from, as the variants of an example share its working directory"))
+		return
+	}
+
+	if err := validateStagePlugins(config.Stages, config.DisabledStages); err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Invalid pipeline stages: %v", err)))
+		return
+	}
+
+	if err := validateLockPlatforms(config.lockPlatforms()); err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Invalid lock file platforms: %v", err)))
+		return
+	}
+
+	if err := validateExtraArgs(config.ExtraArgs); err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Invalid extra arguments: %v", err)))
+		return
+	}
+
+	run := &moduleRun{config: config, sourceType: r.sourceType()}
+	run.scanner, err = scannerFromConfig(config)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Invalid scanner configuration: %v", err)))
+		return
+	}
+	if config.PolicyDir != "" {
+		if run.policies, err = loadPolicies(ctx, config.PolicyDir, config.PolicyQuery); err != nil {
+			t.Fatal(errorText(err.Error()))
+			return
+		}
+	}
+	if config.BudgetRules != "" {
+		if run.budgetRules, err = LoadBudgetRules(config.BudgetRules); err != nil {
+			t.Fatal(errorText(err.Error()))
+			return
+		}
+	}
+	if run.credentials, err = config.credentialPool(); err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Invalid credential pool: %v", err)))
+		return
+	}
+	if run.credentials != nil && config.PhasedDestroy && run.credentials.Size() < len(runners) {
+		t.Fatal(errorText("Phased destroy keeps every example's credential set until the destroy phase and needs at least one set per example"))
+		return
+	}
+	run.scanSeverity = config.ScanSeverity
+	if run.scanSeverity == "" {
+		run.scanSeverity = SeverityHigh
+	}
+	if run.scanner != nil && len(modules) > 0 && !slices.Contains(config.DisabledStages, StageScan) {
+		examplesPath := getExamplesPath(config)
+		findings, err := scanModuleRoot(ctx, t, run.scanner, filepath.Dir(examplesPath), examplesPath, run.scanSeverity)
+		results.SetRootFindings(findings)
+		if err != nil {
+			t.Fatal(errorText(err.Error()))
+			return
+		}
+	}
+	if run.masker, err = config.secretMasker(secrets); err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Invalid secret masking configuration: %v", err)))
+		return
+	}
+
+	if len(config.ProviderOverrides) > 0 || config.ProviderMirror != "" {
+		run.cliConfigPath, err = writeCLIConfig(t.TempDir(), config.ProviderOverrides, config.ProviderMirror)
+		if err != nil {
+			t.Fatal(errorText(fmt.Sprintf("Invalid provider installation: %v", err)))
+			return
+		}
+	}
+	for _, runner := range runners {
+		if native, ok := runner.(*NativeTestRunner); ok {
+			native.use(binary, run.cliConfigPath, config.Offline)
+		}
+	}
+
+	cacheDir, err := pluginCacheDir(config)
+	if err != nil {
+		t.Logf("Warning: %v", err)
+	}
+
+	mounts := []string{cacheDir}
+	if run.cliConfigPath != "" {
+		mounts = append(mounts, filepath.Dir(run.cliConfigPath))
+	}
+	for _, module := range modules {
+		mounts = append(mounts, filepath.Dir(filepath.Dir(module.Path)))
+	}
+	if wrappers := config.execWrappers(t.TempDir(), mounts...); wrappers != nil {
+		if config.ContainerImage != "" {
+			t.Logf("Running terraform in %s with %s", config.ContainerImage, config.containerRuntime())
+		}
+		for _, runner := range runners {
+			var err error
+			if native, ok := runner.(*NativeTestRunner); ok {
+				_, err = wrappers.wrap(native.Options)
+			} else if module := moduleOf(runner); module != nil {
+				err = module.useExecWrapper(wrappers)
+			}
+			if err != nil {
+				t.Fatal(errorText(err.Error()))
+				return
+			}
+		}
+	}
+
+	if cacheDir != "" {
+		for _, module := range modules {
+			module.UsePluginCache(cacheDir)
+		}
+		if config.PrewarmPluginCache {
+			prewarmPluginCache(t, modules, config)
+		}
+	}
+
+	if config.UpgradeTest || config.OutputContract {
+		run.moduleInfo = config.moduleInfo()
+		run.moduleInfo.Root = filepath.Dir(getExamplesPath(config))
+	}
+
+	names := make([]string, 0, len(runners))
+	for _, runner := range runners {
+		names = append(names, runner.Name())
+	}
+	observer.OnRunStart(ctx, RunStartEvent{Modules: names, Parallel: parallel, Started: started})
+	budget := newFailureBudget(config, len(runners))
+	quarantine, err := quarantined(config)
+	if err != nil {
+		t.Logf("Warning: %v", err)
+	}
+
+	var progress *ProgressRenderer
+	if config.Progress {
+		progress = NewProgressRenderer(os.Stderr)
+		progress.Start()
+		run.progress = progress
+	}
+
+	destroys := &destroyPhase{}
+	// finish fails the test of a runner's last attempt, records its outcome and
+	// completes it, or with PhasedDestroy leaves that to its destroy.
+	finish := func(t testing.TB, i int, record *Module, teardown teardownFunc) {
+		if !config.PhasedDestroy {
+			if teardown(t) {
+				t.Fail()
+			}
+			teardown = nil
+		} else if len(record.Errors) > 0 {
+			t.Fail()
+		}
+		record.endObservedStage()
+
+		dependencyStates[i].succeeded = len(record.Errors) == 0
+		if budget.record(!dependencyStates[i].succeeded) {
+			t.Log(errorText("Failure threshold exceeded, skipping the examples that have not started"))
+		}
+		complete := func() {
+			results.AddModule(record)
+			observer.OnModuleComplete(ctx, newModuleReport(record))
+		}
+		if teardown == nil {
+			complete()
+			return
+		}
+		destroys.add(i, record.Name, func(t testing.TB) {
+			t = run.masker.TB(t)
+			applyErrors := len(record.Errors)
+			teardown(t)
+			record.endObservedStage()
+			if len(record.Errors) > applyErrors {
+				t.Fail()
+			}
+			complete()
+		})
+	}
+	reruns := &rerunQueue{}
+	runModule := func(t testing.TB, i int) {
+		runner := runners[i]
+		dependencyState := dependencyStates[i]
+		if slices.Contains(config.ExceptionList, runner.Name()) {
+			t.Logf("Skipping example %s as it is in the exception list", runner.Name())
+			results.AddSkipped(runner.Name(), "in the exception list")
+			observer.OnModuleComplete(ctx, skippedModuleReport(runner.Name(), "in the exception list"))
+			close(dependencyState.done)
+			return
+		}
+
+		runSubtest(t, runner.Name(), parallel, func(t testing.TB) {
+			defer close(dependencyState.done)
+
+			module := moduleOf(runner)
+			skipReason := func() string {
+				if reason, ok := quarantine[runner.Name()]; ok {
+					return reason
+				}
+				if module != nil {
+					if reason := module.skipReason(); reason != "" {
+						return reason
+					}
+				}
+				for _, j := range dependencies[i] {
+					state := dependencyStates[j]
+					<-state.done
+					if !state.succeeded {
+						return fmt.Sprintf("dependency %s did not succeed", runners[j].Name())
+					}
+				}
+				if budget.exceeded() {
+					return "failure threshold exceeded"
+				}
+				return ""
+			}
+			if reason := skipReason(); reason != "" {
+				results.AddSkipped(runner.Name(), reason)
+				observer.OnModuleComplete(ctx, skippedModuleReport(runner.Name(), reason))
+				skipSubtest(t, "Skipping example %s: %s", runner.Name(), reason)
+				return
+			}
+
+			t = run.masker.TB(t)
+			record := module
+			if record == nil {
+				record = NewModule(runner.Name(), "")
+			}
+			if len(observer) > 0 {
+				record.observe(ctx, observer)
+			}
+			failed, teardown := run.run(ctx, t, runner, module, record)
+			if failed && config.RerunFailed > 0 {
+				// The failed attempt is torn down now and the example
+				// reruns once the first pass finished.
+				teardown(t)
+				record.endObservedStage()
+				t.Logf("Example %s failed, rerunning it after the first pass", record.Name)
+				reruns.add(i, record)
+				return
+			}
+			finish(t, i, record, teardown)
+		})
+	}
+	runModules := func(t testing.TB) {
+		waves := dependencyWaves(dependencies)
+		// Parallel subtests only run once the test that started them
+		// returned, so the first pass runs in a subtest of its own when
+		// failed examples rerun after it.
+		if !parallel || len(waves) == 1 && config.RerunFailed == 0 {
+			for i := range runners {
+				runModule(t, i)
+			}
+		} else {
+			// A parallel subtest waiting for its dependencies would hold one
+			// of the -parallel slots they need, so examples run in waves that
+			// each start once the examples of the waves before them
+			// completed.
+			for n, wave := range waves {
+				runSubtest(t, fmt.Sprintf("wave-%d", n+1), false, func(t testing.TB) {
+					for _, i := range wave {
+						runModule(t, i)
+					}
+				})
+			}
+		}
+
+		// Failed examples rerun one at a time, so they do not compete with
+		// each other or the first pass.
+		for _, rerun := range reruns.sorted() {
+			runSubtest(t, "rerun-"+runners[rerun.index].Name(), false, func(t testing.TB) {
+				t = run.masker.TB(t)
+				teardown := run.rerun(ctx, t, runners[rerun.index], moduleOf(runners[rerun.index]), rerun.record)
+				finish(t, rerun.index, rerun.record, teardown)
+			})
+		}
+	}
+
+	if config.PhasedDestroy {
+		runSubtest(t, "apply", false, runModules)
+		runSubtest(t, "destroy", false, destroys.run)
+	} else {
+		runModules(t)
+	}
+
+	t.Cleanup(func() {
+		t := run.masker.TB(t)
+		if progress != nil {
+			progress.Stop()
+		}
+		finished := time.Now()
+		if err := recordHistory(config, results, finished); err != nil {
+			t.Logf("Warning: %v", err)
+		}
+		if err := recordDestroyManifest(config, results, modules, started); err != nil {
+			t.Logf("Warning: %v", err)
+		} else if config.SkipDestroy {
+			t.Logf("Applied examples are recorded in %s; run TestDestroyAll to destroy them", config.destroyManifestPath())
+		}
+		modules, _ := results.GetResults()
+		report := NewRunReport(results, started, finished)
+		observer.OnRunComplete(ctx, report)
+		if err := writeReports(ctx, config, report); err != nil {
+			t.Logf("Warning: %v", err)
+		}
+		uploaded, err := uploadArtifacts(ctx, config, report, modules, run.masker, started)
+		if err != nil {
+			t.Logf("Warning: %v", err)
+		}
+		PrintModuleSummary(t, modules)
+		if uploaded != "" {
+			t.Logf("Reports and logs uploaded to %s", uploaded)
+		}
+		printRerunCommands(t, modules, run.sourceType == "local")
+		printUpgradePaths(t, modules)
+		printFlakyExamples(t, results.Flaky())
+		printBenchmarks(t, modules)
+		if err := emitMetrics(ctx, config, modules); err != nil {
+			t.Logf("Warning: %v", err)
+		}
+		if err := sendNotification(ctx, config, modules); err != nil {
+			t.Logf("Warning: %v", err)
+		}
+		if err := reportToGitHub(ctx, config, modules); err != nil {
+			t.Logf("Warning: %v", err)
+		}
+	})
+}
+
+type moduleRunner struct {
+	module *Module
+}
+
+// Runner adapts the module to the ModuleRunner interface.
+func (m *Module) Runner() ModuleRunner {
+	return moduleRunner{module: m}
+}
+
+func Runners(modules []*Module) []ModuleRunner {
+	runners := make([]ModuleRunner, 0, len(modules))
+	for _, module := range modules {
+		runners = append(runners, module.Runner())
+	}
+	return runners
+}
+
+func (r moduleRunner) Name() string {
+	return r.module.Name
+}
+
+func (r moduleRunner) Apply(ctx context.Context, t testing.TB) error {
+	return r.module.Apply(ctx, t)
+}
+
+func (r moduleRunner) Destroy(ctx context.Context, t testing.TB) error {
+	return r.module.Destroy(ctx, t)
+}
+
+func (r moduleRunner) Cleanup(ctx context.Context, t testing.TB) error {
+	return r.module.Cleanup(ctx, t)
+}
+
+// moduleOf returns the Module behind a runner, following Unwrap through
+// decorators, or nil for runners that are not backed by a Module.
+func moduleOf(runner ModuleRunner) *Module {
+	for runner != nil {
+		switch r := runner.(type) {
+		case moduleRunner:
+			return r.module
+		case interface{ Unwrap() ModuleRunner }:
+			runner = r.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func runnerModules(runners []ModuleRunner) []*Module {
+	var modules []*Module
+	for _, runner := range runners {
+		if module := moduleOf(runner); module != nil {
+			modules = append(modules, module)
+		}
+	}
+	return modules
+}
+
+type moduleRun struct {
+	config        *Config
+	sourceType    string
+	scanner       Scanner
+	scanSeverity  Severity
+	masker        *Masker
+	cliConfigPath string
+	moduleInfo    ModuleInfo
+	budgetRules   *BudgetRules
+	policies      *rego.PreparedEvalQuery
+	credentials   *CredentialPool
+	progress      *ProgressRenderer
+}
+
+// teardownFunc destroys what an attempt applied and reports whether the
+// attempt failed. It must be called exactly once.
+type teardownFunc func(t testing.TB) bool
+
+// applyModule runs the stages of a Module-backed runner up to destroy and
+// returns the teardown that runs the rest. Apply and Destroy go through the
+// runner so decorators see them; the other stages use the module.
+func (r *moduleRun) applyModule(ctx context.Context, t testing.TB, runner ModuleRunner, module *Module) teardownFunc {
+	config := r.config
+
+	var release []func()
+	releaseAll := func() {
+		for i := len(release) - 1; i >= 0; i-- {
+			release[i]()
+		}
+	}
+	returned := false
+	defer func() {
+		if !returned {
+			releaseAll()
+		}
+	}()
+
+	if module.runLock != nil {
+		module.runLock.Lock()
+		release = append(release, module.runLock.Unlock, module.restore)
+	}
+
+	if r.progress != nil {
+		module.onStage = r.progress.SetStage
+		release = append(release, func() { r.progress.Finish(module) })
+	}
+
+	if r.cliConfigPath != "" {
+		module.UseCLIConfig(r.cliConfigPath)
+	}
+
+	module.SetTargets(config.Targets[module.exampleName()], config.Replace[module.exampleName()])
+	module.useExtraArgs(config.ExtraArgs)
+	if expect, ok := config.ExpectedFailures[module.exampleName()]; ok {
+		module.expect(expect)
+	}
+
+	if r.policies != nil {
+		module.planChecks = append(module.planChecks, policyCheck(r.policies))
+	}
+
+	if check, ok := config.planBudget(module.exampleName()); ok {
+		module.planChecks = append(module.planChecks, check)
+	}
+
+	if r.budgetRules != nil {
+		module.planChecks = append(module.planChecks, budgetRulesCheck(r.budgetRules))
+	}
+
+	if config.CostEstimation || config.CostThreshold > 0 {
+		module.planChecks = append(module.planChecks, costCheck(config.CostThreshold))
+	}
+
+	if config.PlanSnapshots || config.UpdateSnapshots {
+		module.planChecks = append(module.planChecks, snapshotCheck(config.snapshotDir(), config.UpdateSnapshots))
+	}
+
+	if config.LogDir != "" {
+		if err := module.OpenLogFile(config.LogDir); err != nil {
+			t.Logf("Warning: %v", err)
+		}
+		release = append(release, func() { module.CloseLogFile() })
+	}
+	module.masker = r.masker
+	module.addSecretVariables(config.SecretVariables)
+	release = append(release, module.maskLogs())
+	module.stream = config.StreamOutput
+	module.cancelGrace = config.CancelGrace
+	module.hangTimeout = config.HangTimeout
+	module.hangRetry = config.HangRetry
+	module.alwaysInit = config.AlwaysInit
+	module.diagnostics = config.Diagnostics || config.FailOnWarnings
+	module.failOnWarnings = config.FailOnWarnings
+	module.cleanup = config.CleanupPatterns
+	module.keepLock = config.PreserveLockFile
+	module.useLockPlatforms(config.lockPlatforms(), config.FixLockFiles)
+	module.skipCleanup = config.SkipCleanup
+	module.useDestroyPolicy(config)
+	module.azurePurge = config.AzurePurge
+	module.revealOutputs = config.SensitiveOutputs
+	module.preDestroy = config.PreDestroyHooks[module.exampleName()]
+	module.preserveDir = config.PreserveOnFailure
+
+	if r.credentials != nil {
+		returnCredentials, err := module.useCredentials(ctx, t, r.credentials)
+		if err != nil {
+			module.failApply(t, &ModuleError{ModuleName: module.Name, Operation: "credentials", Err: err})
+			returned = true
+			return func(t testing.TB) bool {
+				releaseAll()
+				return true
+			}
+		}
+		release = append(release, returnCredentials)
+	}
+
+	if timeout := module.timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		release = append(release, cancel)
+	}
+
+	pipeline := buildPipeline(module, r.steps(runner, module), config.Stages, config.DisabledStages)
+	teardown := slices.IndexFunc(pipeline, func(step pipelineStep) bool { return isTeardown(step.name) })
+	if teardown < 0 {
+		teardown = len(pipeline)
+	}
+
+	var err error
+	failed := false
+	for _, step := range pipeline[:teardown] {
+		if err = step.run(ctx, t); err != nil {
+			break
+		}
+	}
+	if err == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = module.failApply(t, &ModuleError{ModuleName: module.Name, Operation: "timeout", Err: fmt.Errorf("exceeded timeout of %s", module.timeout())})
+	}
+	err = module.checkExpectedOutcome(t, err)
+	if err != nil {
+		if module.LogPath != "" {
+			t.Logf("Full terraform output for module %s: %s", module.Name, module.LogPath)
+		}
+		failed = true
+	} else {
+		t.Log(successText(fmt.Sprintf("✓ Module %s applied successfully with %s source", module.Name, r.sourceType)))
+	}
+
+	returned = true
+	return func(t testing.TB) bool {
+		defer releaseAll()
+
+		if failed && config.PauseOnFailure && teardown < len(pipeline) {
+			module.pauseOnFailure(config.PauseTimeout)
+		}
+
+		// Destroy and cleanup run regardless of the outcome; stages after them
+		// only when the module passed so far.
+		ctx := context.WithoutCancel(ctx)
+		for _, step := range pipeline[teardown:] {
+			if !isTeardown(step.name) && len(module.Errors) > 0 {
+				break
+			}
+			if err := step.run(ctx, t); err != nil && !isTeardown(step.name) {
+				failed = true
+			}
+		}
+		return failed
+	}
+}
+
+// info describes the module the example belongs to: its own in a monorepo,
+// otherwise the repository's.
+func (r *moduleRun) info(module *Module) ModuleInfo {
+	if module.info != nil {
+		return *module.info
+	}
+	return r.moduleInfo
+}
+
+// steps returns the built-in pipeline for a Module-backed runner; steps that
+// are not configured are disabled. The runner's Apply only applies, as init,
+// validate and plan are steps of their own.
+func (r *moduleRun) steps(runner ModuleRunner, module *Module) []pipelineStep {
+	config := r.config
+	scenario, importing := config.ImportScenarios[module.exampleName()]
+	assertions := config.StateAssertions[module.exampleName()]
+
+	module.staged = true
+	// Examples that upgrade from a release are pinned to it before the first
+	// of init and apply that runs.
+	pinned := module.UpgradeFrom == ""
+	pin := func(t testing.TB) error {
+		if pinned {
+			return nil
+		}
+		pinned = true
+		return module.pinRelease(t, r.info(module))
+	}
+
+	return []pipelineStep{
+		{name: StageScan, enabled: r.scanner != nil, run: func(ctx context.Context, t testing.TB) error {
+			return module.Scan(ctx, t, r.scanner, r.scanSeverity)
+		}},
+		{name: StageImport, enabled: importing, run: func(ctx context.Context, t testing.TB) error {
+			return module.Import(ctx, t, scenario)
+		}},
+		{name: StageInit, enabled: !importing, run: func(ctx context.Context, t testing.TB) error {
+			if err := pin(t); err != nil {
+				return err
+			}
+			return module.initStage(ctx, t)
+		}},
+		{name: StageValidate, enabled: !importing, run: module.validateStage},
+		{name: StagePlan, enabled: !importing, run: module.planStage},
+		{name: StageApply, enabled: !importing, run: func(ctx context.Context, t testing.TB) error {
+			if err := pin(t); err != nil {
+				return err
+			}
+			return runner.Apply(ctx, t)
+		}},
+		{name: StageDrift, enabled: config.DriftCheck, run: func(ctx context.Context, t testing.TB) error {
+			return module.DetectDrift(ctx, t, config.DriftWait)
+		}},
+		{name: StageVerify, enabled: len(assertions) > 0, run: func(ctx context.Context, t testing.TB) error {
+			return module.AssertState(ctx, t, assertions)
+		}},
+		{name: StageOutputs, enabled: config.OutputContract, run: func(ctx context.Context, t testing.TB) error {
+			info := r.info(module)
+			return module.CheckOutputs(ctx, t, info.Root, info, config.NullableOutputs)
+		}},
+		{name: StageUpgrade, enabled: config.UpgradeTest, run: func(ctx context.Context, t testing.TB) error {
+			return module.Upgrade(ctx, t, r.info(module), config.UpgradeAllowDestroy)
+		}},
+		{name: StageSoak, enabled: config.SoakDuration > 0, run: func(ctx context.Context, t testing.TB) error {
+			return module.Soak(ctx, t, config.SoakDuration, config.SoakInterval, assertions)
+		}},
+		{name: StageDestroy, enabled: !config.SkipDestroy, run: func(ctx context.Context, t testing.TB) error {
+			if err := runner.Destroy(ctx, t); err != nil && !module.ApplyFailed {
+				t.Logf("Cleanup failed for module %s: %v", module.Name, err)
+				return err
+			}
+			return nil
+		}},
+		{name: StageCleanup, enabled: config.SkipDestroy && config.CleanupWithoutDestroy && !config.SkipCleanup, run: func(ctx context.Context, t testing.TB) error {
+			if err := module.Cleanup(ctx, t); err != nil {
+				module.recordError(t, StageCleanup, &ModuleError{ModuleName: module.Name, Operation: "cleanup", Err: err})
+				return err
+			}
+			return nil
+		}},
+	}
+}
+
+// isTeardown reports whether a stage tears an example down, which happens
+// regardless of whether it failed. Cleanup is only a step of its own when
+// destroy is skipped; otherwise it is part of destroy.
+func isTeardown(stage Stage) bool {
+	return stage == StageDestroy || stage == StageCleanup
+}
+
+// applyOther applies a runner that is not backed by a Module, recording its
+// errors on record for the summary, and returns the teardown that destroys it.
+func (r *moduleRun) applyOther(ctx context.Context, t testing.TB, runner ModuleRunner, record *Module) teardownFunc {
+	done := record.startStage(StageApply)
+	err := runner.Apply(ctx, t)
+	done()
+	if err != nil {
+		record.failApply(t, &ModuleError{ModuleName: record.Name, Operation: "apply", Err: err})
+	} else {
+		t.Log(successText(fmt.Sprintf("✓ Module %s applied successfully with %s source", record.Name, r.sourceType)))
+	}
+
+	return func(t testing.TB) bool {
+		if r.config.SkipDestroy {
+			return len(record.Errors) > 0
+		}
+		done := record.startStage(StageDestroy)
+		err := runner.Destroy(ctx, t)
+		done()
+		if err != nil && !record.ApplyFailed {
+			record.recordError(t, StageDestroy, &ModuleError{ModuleName: record.Name, Operation: "destroy", Err: err})
+		}
+		return len(record.Errors) > 0
+	}
+}
+
+// run makes an attempt and reports whether it failed. With PhasedDestroy the
+// teardown of the attempt is left to the returned func; otherwise that has
+// already run. With BenchmarkIterations set a passing runner is applied and
+// destroyed again until it has run that many times, recording the stage
+// durations of each iteration.
+func (r *moduleRun) run(ctx context.Context, t testing.TB, runner ModuleRunner, module, record *Module) (bool, teardownFunc) {
+	attempt := func() (bool, teardownFunc) {
+		var teardown teardownFunc
+		if module == nil {
+			teardown = r.applyOther(ctx, t, runner, record)
+		} else {
+			teardown = r.applyModule(ctx, t, runner, module)
+		}
+		if r.config.PhasedDestroy {
+			return len(record.Errors) > 0, teardown
+		}
+		failed := teardown(t)
+		return failed, func(testing.TB) bool { return failed }
+	}
+
+	failed, teardown := attempt()
+	if iterations := r.config.BenchmarkIterations; iterations > 1 {
+		for iteration := 2; !failed && iteration <= iterations; iteration++ {
+			record.BenchmarkRuns = append(record.BenchmarkRuns, maps.Clone(record.Durations))
+			t.Logf("Benchmarking %s: iteration %d of %d", record.Name, iteration, iterations)
+			record.resetAttempt()
+			failed, teardown = attempt()
+		}
+		if !failed {
+			record.BenchmarkRuns = append(record.BenchmarkRuns, maps.Clone(record.Durations))
+		}
+	}
+	return failed, teardown
+}
+
+// rerun reruns a runner whose first attempt failed, after cleaning up its
+// working directory, until an attempt passes or RerunFailed reruns failed.
+// Like run, it leaves the teardown of the last attempt to the returned func.
+func (r *moduleRun) rerun(ctx context.Context, t testing.TB, runner ModuleRunner, module, record *Module) teardownFunc {
+	var teardown teardownFunc
+	for rerun := 1; rerun <= r.config.RerunFailed; rerun++ {
+		if teardown != nil {
+			teardown(t)
+		}
+		t.Logf("Rerunning failed example %s (%d of %d)", record.Name, rerun, r.config.RerunFailed)
+		if err := runner.Cleanup(ctx, t); err != nil {
+			t.Logf("Warning: cleanup before rerun failed for %s: %v", record.Name, err)
+		}
+		record.resetAttempt()
+		record.Retries = rerun
+		var failed bool
+		if failed, teardown = r.run(ctx, t, runner, module, record); !failed {
+			break
+		}
+	}
+	return func(t testing.TB) bool {
+		failed := teardown(t)
+		record.Flaky = !failed
+		return failed
+	}
+}
+
+// runSubtest runs fn as a named subtest of *testing.T and *testing.B. Other
+// testing.TB implementations have no subtests, so fn runs inline against tb
+// and parallel is ignored.
+func runSubtest(tb testing.TB, name string, parallel bool, fn func(tb testing.TB)) {
+	switch tb := tb.(type) {
+	case *testing.T:
+		tb.Run(name, func(t *testing.T) {
+			if parallel {
+				t.Parallel()
+			}
+			fn(t)
+		})
+	case *testing.B:
+		// b.Run calls the body again with a larger b.N when it returns
+		// quickly, but an example must only run once.
+		var once sync.Once
+		tb.Run(name, func(b *testing.B) { once.Do(func() { fn(b) }) })
+	default:
+		fn(tb)
+	}
+}
+
+// skipSubtest skips a subtest started by runSubtest. Inline subtests only log,
+// as skipping would skip the caller's test; the caller must return.
+func skipSubtest(tb testing.TB, format string, args ...any) {
+	switch tb.(type) {
+	case *testing.T, *testing.B:
+		tb.Skipf(format, args...)
+	default:
+		tb.Logf(format, args...)
+	}
 }
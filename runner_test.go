@@ -2,8 +2,12 @@ package validor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
 )
 
@@ -28,16 +32,16 @@ func TestRunModuleTests_SkipDestroy(t *testing.T) {
 	module := NewModule("mod1", t.TempDir())
 	var destroyCalled bool
 
-	module.applyHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		return nil
 	}
-	module.destroyHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		destroyCalled = true
 		return nil
 	}
 
 	config := &Config{SkipDestroy: true}
-	runModuleTests(t, []*Module{module}, false, config, nil, "local")
+	runModuleTests(t, Runners([]*Module{module}), false, config, nil, "local")
 
 	if destroyCalled {
 		t.Fatalf("destroy should not be called when SkipDestroy is true")
@@ -48,50 +52,92 @@ func TestRunModuleTests_InvokesDestroyWhenAllowed(t *testing.T) {
 	module := NewModule("mod1", t.TempDir())
 	var destroyCalled bool
 
-	module.applyHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		return nil
 	}
-	module.destroyHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		destroyCalled = true
 		return nil
 	}
 
 	config := &Config{SkipDestroy: false}
-	runModuleTests(t, []*Module{module}, false, config, nil, "local")
+	runModuleTests(t, Runners([]*Module{module}), false, config, nil, "local")
 
 	if !destroyCalled {
 		t.Fatalf("destroy should be called when SkipDestroy is false")
 	}
 }
 
+func TestRunModuleTests_SkipCleanup(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *Config
+		wantDestroy bool
+		wantCleanup bool
+	}{
+		{name: "destroy and cleanup", config: &Config{}, wantDestroy: true, wantCleanup: true},
+		{name: "skip cleanup", config: &Config{SkipCleanup: true}, wantDestroy: true},
+		{name: "skip destroy", config: &Config{SkipDestroy: true, DestroyManifest: filepath.Join(t.TempDir(), "manifest.json")}},
+		{
+			name:        "cleanup without destroy",
+			config:      &Config{SkipDestroy: true, CleanupWithoutDestroy: true, DestroyManifest: filepath.Join(t.TempDir(), "manifest.json")},
+			wantCleanup: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := NewModule("mod1", t.TempDir())
+			var destroyed, cleaned bool
+			module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+			module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+				destroyed = true
+				return nil
+			}
+			module.cleanupHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+				cleaned = true
+				return nil
+			}
+
+			t.Run("run", func(t *testing.T) {
+				runModuleTests(t, Runners([]*Module{module}), false, tt.config, nil, "local")
+			})
+
+			if destroyed != tt.wantDestroy || cleaned != tt.wantCleanup {
+				t.Errorf("destroyed = %v, cleaned = %v, want %v and %v", destroyed, cleaned, tt.wantDestroy, tt.wantCleanup)
+			}
+		})
+	}
+}
+
 func TestRunModuleTests_RespectsExceptionList(t *testing.T) {
 	seen := make(map[string]bool)
 
 	mod1 := NewModule("run-me", t.TempDir())
-	mod1.applyHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	mod1.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		seen[m.Name] = true
 		return nil
 	}
-	mod1.destroyHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	mod1.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		return nil
 	}
-	mod1.cleanupHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	mod1.cleanupHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		return nil
 	}
 	mod2 := NewModule("skip-me", t.TempDir())
-	mod2.applyHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	mod2.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		seen[m.Name] = true
 		return nil
 	}
-	mod2.destroyHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	mod2.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		return nil
 	}
-	mod2.cleanupHook = func(ctx context.Context, tb *testing.T, m *Module) error {
+	mod2.cleanupHook = func(ctx context.Context, tb testing.TB, m *Module) error {
 		return nil
 	}
 
 	config := &Config{ExceptionList: []string{"skip-me"}}
-	runModuleTests(t, []*Module{mod1, mod2}, false, config, nil, "local")
+	runModuleTests(t, Runners([]*Module{mod1, mod2}), false, config, nil, "local")
 
 	if !seen["run-me"] {
 		t.Fatalf("expected module run-me to execute")
@@ -121,3 +167,256 @@ func TestPrintModuleSummary_CapturesOutput(t *testing.T) {
 		t.Fatalf("expected failure count in summary, got %q", joined)
 	}
 }
+
+type recordingTB struct {
+	testing.TB
+	logs   []string
+	failed bool
+}
+
+func (r *recordingTB) Helper() {}
+func (r *recordingTB) Fail()   { r.failed = true }
+func (r *recordingTB) Log(args ...any) {
+	r.logs = append(r.logs, fmt.Sprint(args...))
+}
+func (r *recordingTB) Logf(format string, args ...any) {
+	r.logs = append(r.logs, fmt.Sprintf(format, args...))
+}
+func (r *recordingTB) Error(args ...any) {
+	r.Log(args...)
+	r.Fail()
+}
+
+func TestRunModuleTests_CustomTB(t *testing.T) {
+	var applied []string
+	var modules []*Module
+	for _, name := range []string{"first", "second"} {
+		module := NewModule(name, t.TempDir())
+		module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+			if _, ok := tb.(*recordingTB); !ok {
+				t.Errorf("expected the custom TB to be passed through, got %T", tb)
+			}
+			applied = append(applied, m.Name)
+			return nil
+		}
+		module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+		modules = append(modules, module)
+	}
+
+	recorder := &recordingTB{TB: t}
+	runModuleTests(recorder, Runners(modules), true, &Config{}, nil, "registry")
+
+	if strings.Join(applied, ",") != "first,second" {
+		t.Errorf("expected modules to run inline in order, got %v", applied)
+	}
+	if !strings.Contains(strings.Join(recorder.logs, "\n"), "Module first applied successfully") {
+		t.Errorf("expected logs on the custom TB, got %v", recorder.logs)
+	}
+}
+
+type fakeRunner struct {
+	name       string
+	applyErr   error
+	destroyErr error
+	applied    bool
+	destroyed  bool
+}
+
+func (f *fakeRunner) Name() string { return f.name }
+func (f *fakeRunner) Apply(ctx context.Context, t testing.TB) error {
+	f.applied = true
+	return f.applyErr
+}
+func (f *fakeRunner) Destroy(ctx context.Context, t testing.TB) error {
+	f.destroyed = true
+	return f.destroyErr
+}
+func (f *fakeRunner) Cleanup(ctx context.Context, t testing.TB) error { return nil }
+
+type countingRunner struct {
+	ModuleRunner
+	applies int
+}
+
+func (c *countingRunner) Apply(ctx context.Context, t testing.TB) error {
+	c.applies++
+	return c.ModuleRunner.Apply(ctx, t)
+}
+
+func (c *countingRunner) Unwrap() ModuleRunner { return c.ModuleRunner }
+
+func TestRunModuleTests_CustomRunner(t *testing.T) {
+	runner := &fakeRunner{name: "custom"}
+	recorder := &recordingTB{TB: t}
+	runModuleTests(recorder, []ModuleRunner{runner}, false, &Config{}, nil, "registry")
+
+	if !runner.applied || !runner.destroyed {
+		t.Errorf("expected apply and destroy, got applied=%v destroyed=%v", runner.applied, runner.destroyed)
+	}
+}
+
+func TestModuleRun_RunRecordsOtherRunnerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		runner     *fakeRunner
+		config     *Config
+		wantErrors int
+		wantFailed bool
+	}{
+		{name: "success", runner: &fakeRunner{name: "ok"}, config: &Config{}},
+		{name: "apply error hides destroy error", runner: &fakeRunner{name: "apply", applyErr: errors.New("boom"), destroyErr: errors.New("stuck")}, config: &Config{}, wantErrors: 1, wantFailed: true},
+		{name: "destroy error", runner: &fakeRunner{name: "destroy", destroyErr: errors.New("stuck")}, config: &Config{}, wantErrors: 1},
+		{name: "skip destroy", runner: &fakeRunner{name: "skip", destroyErr: errors.New("stuck")}, config: &Config{SkipDestroy: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &moduleRun{config: tt.config, sourceType: "registry"}
+			record := NewModule(tt.runner.name, "")
+			recorder := &recordingTB{TB: t}
+			failed, _ := run.run(context.Background(), recorder, tt.runner, nil, record)
+
+			if len(record.Errors) != tt.wantErrors {
+				t.Errorf("Errors = %v, want %d", record.Errors, tt.wantErrors)
+			}
+			if failed != (tt.wantErrors > 0) {
+				t.Errorf("failed = %v, want %v", failed, tt.wantErrors > 0)
+			}
+			if record.ApplyFailed != tt.wantFailed {
+				t.Errorf("ApplyFailed = %v, want %v", record.ApplyFailed, tt.wantFailed)
+			}
+			if tt.runner.destroyed == tt.config.SkipDestroy {
+				t.Errorf("destroyed = %v with SkipDestroy %v", tt.runner.destroyed, tt.config.SkipDestroy)
+			}
+		})
+	}
+}
+
+func TestRunModuleTests_Decorator(t *testing.T) {
+	module := NewModule("decorated", t.TempDir())
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+	module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+	module.stateHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+		return []byte(`{"values":{"root_module":{}}}`), nil
+	}
+	var asserted bool
+	config := NewConfig(WithExample(""), WithStateAssertions("decorated", func(t testing.TB, s *State) error {
+		asserted = true
+		return nil
+	}))
+
+	decorator := &countingRunner{ModuleRunner: module.Runner()}
+	runModuleTests(t, []ModuleRunner{decorator}, false, config, nil, "registry")
+
+	if decorator.applies != 1 {
+		t.Errorf("expected apply to go through the decorator once, got %d", decorator.applies)
+	}
+	if !asserted {
+		t.Error("expected module stages to run for the unwrapped module")
+	}
+}
+
+func TestModuleOf(t *testing.T) {
+	module := NewModule("default", "/tmp/default")
+
+	if got := moduleOf(module.Runner()); got != module {
+		t.Errorf("moduleOf(adapter) = %v", got)
+	}
+	if got := moduleOf(&countingRunner{ModuleRunner: module.Runner()}); got != module {
+		t.Errorf("moduleOf(decorator) = %v", got)
+	}
+	if got := moduleOf(&fakeRunner{name: "custom"}); got != nil {
+		t.Errorf("moduleOf(custom) = %v, want nil", got)
+	}
+	if got := moduleOf(&countingRunner{}); got != nil {
+		t.Errorf("moduleOf(empty decorator) = %v, want nil", got)
+	}
+}
+
+func TestDefaultTestRunner_RunTests(t *testing.T) {
+	var setupModules []string
+	runner := &DefaultTestRunner{
+		Config: NewConfig(WithExample("")),
+		Setup: func(ctx context.Context, t testing.TB, modules []*Module) error {
+			setupModules = extractModuleNames(modules)
+			return nil
+		},
+	}
+
+	module := NewModule("module", t.TempDir())
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+	module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+	failing := &fakeRunner{name: "failing", applyErr: errors.New("boom")}
+
+	recorder := &recordingTB{TB: t}
+	runner.RunTests(context.Background(), recorder, []ModuleRunner{module.Runner(), failing}, false, nil)
+
+	if !reflect.DeepEqual(setupModules, []string{"module"}) {
+		t.Errorf("setup received %v, want only the Module-backed runners", setupModules)
+	}
+	all, failed := runner.Results.GetResults()
+	if len(all) != 2 || len(failed) != 1 || failed[0].Name != "failing" {
+		t.Errorf("unexpected results: all=%v failed=%v", extractModuleNames(all), extractModuleNames(failed))
+	}
+	if !recorder.failed {
+		t.Error("expected the failing runner to fail the test")
+	}
+}
+
+func TestDefaultTestRunner_RunLocalTestsDiscoveryError(t *testing.T) {
+	config := NewConfig(WithExample(""))
+	runner := &DefaultTestRunner{Config: config}
+
+	err := runner.RunLocalTests(context.Background(), t, filepath.Join(t.TempDir(), "missing"))
+	if err == nil || !strings.Contains(err.Error(), "failed to discover modules") {
+		t.Errorf("RunLocalTests() error = %v, want discovery error", err)
+	}
+	if config.Local || config.ExamplesPath != "" {
+		t.Error("RunLocalTests should not modify the runner config")
+	}
+}
+
+func TestChainSetup(t *testing.T) {
+	var calls []string
+	record := func(name string, err error) TestSetupFunc {
+		return func(ctx context.Context, t testing.TB, modules []*Module) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+
+	err := chainSetup(record("first", nil), nil, record("second", errors.New("boom")), record("third", nil))(context.Background(), t, nil)
+	if err == nil {
+		t.Fatal("expected error from the second setup")
+	}
+	if !reflect.DeepEqual(calls, []string{"first", "second"}) {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestDefaultTestRunner_RunTestsBenchmark(t *testing.T) {
+	var applies, destroys atomic.Int32
+	module := NewModule("module", t.TempDir())
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+		applies.Add(1)
+		return nil
+	}
+	module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+		destroys.Add(1)
+		return nil
+	}
+
+	runner := &DefaultTestRunner{Config: NewConfig(WithExample(""))}
+	// Subbenchmarks whose body returns quickly are called again with a
+	// larger b.N; each example must still run once.
+	testing.Benchmark(func(b *testing.B) {
+		runner.RunTests(context.Background(), b, []ModuleRunner{module.Runner()}, false, nil)
+	})
+
+	if applies.Load() != 1 || destroys.Load() != 1 {
+		t.Errorf("applies, destroys = %d, %d, want 1, 1", applies.Load(), destroys.Load())
+	}
+	if all, _ := runner.Results.GetResults(); len(all) != 1 {
+		t.Errorf("results = %v, want one module", extractModuleNames(all))
+	}
+}
@@ -0,0 +1,318 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type Severity string
+
+const (
+	SeverityUnknown  Severity = "UNKNOWN"
+	SeverityLow      Severity = "LOW"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityHigh     Severity = "HIGH"
+	SeverityCritical Severity = "CRITICAL"
+)
+
+func (s Severity) rank() int {
+	switch Severity(strings.ToUpper(string(s))) {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	case SeverityCritical:
+		return 4
+	}
+	return 0
+}
+
+func (s Severity) AtLeast(threshold Severity) bool {
+	return s.rank() >= threshold.rank()
+}
+
+type Finding struct {
+	Scanner     string   `json:"scanner"`
+	RuleID      string   `json:"rule_id"`
+	Severity    Severity `json:"severity"`
+	Description string   `json:"description"`
+	Resource    string   `json:"resource,omitempty"`
+	Location    string   `json:"location,omitempty"`
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("[%s] %s %s: %s", f.Severity, f.Scanner, f.RuleID, f.Description)
+	if f.Resource != "" {
+		s += " (" + f.Resource + ")"
+	}
+	if f.Location != "" {
+		s += " at " + f.Location
+	}
+	return s
+}
+
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, dir string) ([]Finding, error)
+}
+
+func WithScanner(scanner Scanner) Option {
+	return func(c *Config) { c.Scanner = scanner }
+}
+
+func WithScanSeverity(threshold Severity) Option {
+	return func(c *Config) { c.ScanSeverity = threshold }
+}
+
+func NewScanner(name string) (Scanner, error) {
+	switch strings.ToLower(name) {
+	case "trivy":
+		return &TrivyScanner{}, nil
+	case "tfsec":
+		return &TfsecScanner{}, nil
+	case "checkov":
+		return &CheckovScanner{}, nil
+	}
+	return nil, fmt.Errorf("unknown scanner %q", name)
+}
+
+func scannerFromConfig(config *Config) (Scanner, error) {
+	if config.Scanner != nil {
+		return config.Scanner, nil
+	}
+	if config.ScannerName == "" {
+		return nil, nil
+	}
+	return NewScanner(config.ScannerName)
+}
+
+var runScannerCommand = func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+
+	// Scanners commonly exit non-zero when they report findings.
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(output) > 0 {
+		return output, nil
+	}
+	return output, err
+}
+
+type TrivyScanner struct{}
+
+func (s *TrivyScanner) Name() string { return "trivy" }
+
+func (s *TrivyScanner) Scan(ctx context.Context, dir string) ([]Finding, error) {
+	output, err := runScannerCommand(ctx, dir, "trivy", "config", "--format", "json", "--quiet", ".")
+	if err != nil {
+		return nil, fmt.Errorf("trivy failed: %w", err)
+	}
+
+	var report struct {
+		Results []struct {
+			Target            string `json:"Target"`
+			Misconfigurations []struct {
+				ID            string `json:"ID"`
+				Title         string `json:"Title"`
+				Severity      string `json:"Severity"`
+				CauseMetadata struct {
+					Resource  string `json:"Resource"`
+					StartLine int    `json:"StartLine"`
+				} `json:"CauseMetadata"`
+			} `json:"Misconfigurations"`
+		} `json:"Results"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse trivy output: %w", err)
+	}
+
+	var findings []Finding
+	for _, result := range report.Results {
+		for _, m := range result.Misconfigurations {
+			findings = append(findings, Finding{
+				Scanner:     s.Name(),
+				RuleID:      m.ID,
+				Severity:    Severity(m.Severity),
+				Description: m.Title,
+				Resource:    m.CauseMetadata.Resource,
+				Location:    fmt.Sprintf("%s:%d", result.Target, m.CauseMetadata.StartLine),
+			})
+		}
+	}
+	return findings, nil
+}
+
+type TfsecScanner struct{}
+
+func (s *TfsecScanner) Name() string { return "tfsec" }
+
+func (s *TfsecScanner) Scan(ctx context.Context, dir string) ([]Finding, error) {
+	output, err := runScannerCommand(ctx, dir, "tfsec", ".", "--format", "json", "--no-colour")
+	if err != nil {
+		return nil, fmt.Errorf("tfsec failed: %w", err)
+	}
+
+	var report struct {
+		Results []struct {
+			RuleID      string `json:"long_id"`
+			Severity    string `json:"severity"`
+			Description string `json:"description"`
+			Resource    string `json:"resource"`
+			Location    struct {
+				Filename  string `json:"filename"`
+				StartLine int    `json:"start_line"`
+			} `json:"location"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse tfsec output: %w", err)
+	}
+
+	var findings []Finding
+	for _, r := range report.Results {
+		findings = append(findings, Finding{
+			Scanner:     s.Name(),
+			RuleID:      r.RuleID,
+			Severity:    Severity(r.Severity),
+			Description: r.Description,
+			Resource:    r.Resource,
+			Location:    fmt.Sprintf("%s:%d", r.Location.Filename, r.Location.StartLine),
+		})
+	}
+	return findings, nil
+}
+
+type CheckovScanner struct{}
+
+func (s *CheckovScanner) Name() string { return "checkov" }
+
+type checkovReport struct {
+	Results struct {
+		FailedChecks []struct {
+			CheckID       string  `json:"check_id"`
+			CheckName     string  `json:"check_name"`
+			Severity      *string `json:"severity"`
+			Resource      string  `json:"resource"`
+			FilePath      string  `json:"file_path"`
+			FileLineRange []int   `json:"file_line_range"`
+		} `json:"failed_checks"`
+	} `json:"results"`
+}
+
+func (s *CheckovScanner) Scan(ctx context.Context, dir string) ([]Finding, error) {
+	output, err := runScannerCommand(ctx, dir, "checkov", "-d", ".", "-o", "json", "--quiet", "--framework", "terraform")
+	if err != nil {
+		return nil, fmt.Errorf("checkov failed: %w", err)
+	}
+
+	// checkov emits a single report or a list of reports depending on the frameworks involved.
+	var reports []checkovReport
+	if err := json.Unmarshal(output, &reports); err != nil {
+		var report checkovReport
+		if err := json.Unmarshal(output, &report); err != nil {
+			return nil, fmt.Errorf("failed to parse checkov output: %w", err)
+		}
+		reports = []checkovReport{report}
+	}
+
+	var findings []Finding
+	for _, report := range reports {
+		for _, c := range report.Results.FailedChecks {
+			severity := SeverityUnknown
+			if c.Severity != nil {
+				severity = Severity(*c.Severity)
+			}
+			location := c.FilePath
+			if len(c.FileLineRange) > 0 {
+				location = fmt.Sprintf("%s:%d", c.FilePath, c.FileLineRange[0])
+			}
+			findings = append(findings, Finding{
+				Scanner:     s.Name(),
+				RuleID:      c.CheckID,
+				Severity:    severity,
+				Description: c.CheckName,
+				Resource:    c.Resource,
+				Location:    location,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// moduleRootFindings is the key TestResults.Findings reports the findings in
+// the module root under.
+const moduleRootFindings = "module root"
+
+func (m *Module) Scan(ctx context.Context, t testing.TB, scanner Scanner, threshold Severity) error {
+	t.Helper()
+
+	done := m.startStage(StageScan)
+	defer done()
+
+	findings, err := scanner.Scan(ctx, m.Path)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "security scan", Err: err})
+	}
+	m.Findings = append(m.Findings, findings...)
+
+	if err := checkFindings(t, scanner, findings, threshold); err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "security scan", Err: err})
+	}
+	return nil
+}
+
+// scanModuleRoot scans the module the examples call once per run, as the
+// example scans only cover the example directories. Scanners recurse, so
+// findings in examplesPath are left to the example scans.
+func scanModuleRoot(ctx context.Context, t testing.TB, scanner Scanner, root, examplesPath string, threshold Severity) ([]Finding, error) {
+	t.Helper()
+
+	all, err := scanner.Scan(ctx, root)
+	if err != nil {
+		return nil, fmt.Errorf("security scan of %s failed: %w", root, err)
+	}
+	examples, err := filepath.Rel(root, examplesPath)
+	if err != nil {
+		return nil, fmt.Errorf("security scan of %s failed: %w", root, err)
+	}
+	examples = filepath.ToSlash(examples) + "/"
+	var findings []Finding
+	for _, finding := range all {
+		if !strings.HasPrefix(strings.TrimPrefix(filepath.ToSlash(finding.Location), "/"), examples) {
+			findings = append(findings, finding)
+		}
+	}
+	if err := checkFindings(t, scanner, findings, threshold); err != nil {
+		return findings, fmt.Errorf("security scan of %s failed: %w", root, err)
+	}
+	return findings, nil
+}
+
+// checkFindings logs findings and fails when any is at or above threshold.
+func checkFindings(t testing.TB, scanner Scanner, findings []Finding, threshold Severity) error {
+	t.Helper()
+
+	var blocking int
+	for _, finding := range findings {
+		if finding.Severity.AtLeast(threshold) {
+			blocking++
+			t.Log(errorText(finding.String()))
+		} else {
+			t.Logf("Warning: %s", finding)
+		}
+	}
+
+	if blocking > 0 {
+		return fmt.Errorf("%s reported %d finding(s) at or above %s severity", scanner.Name(), blocking, threshold)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package validor
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type mockScanner struct {
+	findings []Finding
+	err      error
+	dirs     []string
+}
+
+func (s *mockScanner) Name() string { return "mock" }
+
+func (s *mockScanner) Scan(ctx context.Context, dir string) ([]Finding, error) {
+	s.dirs = append(s.dirs, dir)
+	return s.findings, s.err
+}
+
+func TestSeverity_AtLeast(t *testing.T) {
+	tests := []struct {
+		severity  Severity
+		threshold Severity
+		want      bool
+	}{
+		{SeverityCritical, SeverityHigh, true},
+		{SeverityHigh, SeverityHigh, true},
+		{"high", SeverityHigh, true},
+		{SeverityMedium, SeverityHigh, false},
+		{SeverityUnknown, SeverityLow, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.severity.AtLeast(tt.threshold); got != tt.want {
+			t.Errorf("%s.AtLeast(%s) = %v, want %v", tt.severity, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestNewScanner(t *testing.T) {
+	for _, name := range []string{"trivy", "tfsec", "Checkov"} {
+		scanner, err := NewScanner(name)
+		if err != nil {
+			t.Errorf("NewScanner(%q) error = %v", name, err)
+			continue
+		}
+		if scanner.Name() != strings.ToLower(name) {
+			t.Errorf("NewScanner(%q).Name() = %v", name, scanner.Name())
+		}
+	}
+	if _, err := NewScanner("unknown"); err == nil {
+		t.Error("NewScanner() should fail for unknown scanners")
+	}
+}
+
+func TestScanners_ParseOutput(t *testing.T) {
+	origRun := runScannerCommand
+	defer func() { runScannerCommand = origRun }()
+
+	tests := []struct {
+		name    string
+		scanner Scanner
+		output  string
+		want    Finding
+	}{
+		{
+			name:    "trivy",
+			scanner: &TrivyScanner{},
+			output:  `{"Results":[{"Target":"main.tf","Misconfigurations":[{"ID":"AVD-AZU-0013","Title":"Vault network ACL","Severity":"HIGH","CauseMetadata":{"Resource":"azurerm_key_vault.kv","StartLine":3}}]}]}`,
+			want:    Finding{Scanner: "trivy", RuleID: "AVD-AZU-0013", Severity: SeverityHigh, Description: "Vault network ACL", Resource: "azurerm_key_vault.kv", Location: "main.tf:3"},
+		},
+		{
+			name:    "tfsec",
+			scanner: &TfsecScanner{},
+			output:  `{"results":[{"long_id":"azure-keyvault-specify-network-acl","severity":"CRITICAL","description":"Network ACL","resource":"azurerm_key_vault.kv","location":{"filename":"main.tf","start_line":5}}]}`,
+			want:    Finding{Scanner: "tfsec", RuleID: "azure-keyvault-specify-network-acl", Severity: SeverityCritical, Description: "Network ACL", Resource: "azurerm_key_vault.kv", Location: "main.tf:5"},
+		},
+		{
+			name:    "checkov list output",
+			scanner: &CheckovScanner{},
+			output:  `[{"check_type":"terraform","results":{"failed_checks":[{"check_id":"CKV_AZURE_42","check_name":"Key vault recoverable","severity":null,"resource":"azurerm_key_vault.kv","file_path":"/main.tf","file_line_range":[1,9]}]}}]`,
+			want:    Finding{Scanner: "checkov", RuleID: "CKV_AZURE_42", Severity: SeverityUnknown, Description: "Key vault recoverable", Resource: "azurerm_key_vault.kv", Location: "/main.tf:1"},
+		},
+		{
+			name:    "checkov object output",
+			scanner: &CheckovScanner{},
+			output:  `{"check_type":"terraform","results":{"failed_checks":[{"check_id":"CKV_AZURE_1","check_name":"SSH keys","severity":"LOW","resource":"azurerm_linux_virtual_machine.vm","file_path":"/vm.tf","file_line_range":[2,4]}]}}`,
+			want:    Finding{Scanner: "checkov", RuleID: "CKV_AZURE_1", Severity: SeverityLow, Description: "SSH keys", Resource: "azurerm_linux_virtual_machine.vm", Location: "/vm.tf:2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runScannerCommand = func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+				return []byte(tt.output), nil
+			}
+			findings, err := tt.scanner.Scan(context.Background(), t.TempDir())
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if len(findings) != 1 || findings[0] != tt.want {
+				t.Errorf("Scan() = %+v, want %+v", findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestModule_Scan(t *testing.T) {
+	t.Run("findings below threshold are warnings", func(t *testing.T) {
+		module := NewModule("example1", t.TempDir())
+		scanner := &mockScanner{findings: []Finding{{Scanner: "mock", RuleID: "R1", Severity: SeverityMedium}}}
+
+		if err := module.Scan(context.Background(), t, scanner, SeverityHigh); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		if len(module.Findings) != 1 || len(module.Errors) != 0 {
+			t.Errorf("unexpected module state: findings=%v errors=%v", module.Findings, module.Errors)
+		}
+	})
+
+	t.Run("findings at threshold fail the module", func(t *testing.T) {
+		module := NewModule("example1", t.TempDir())
+		scanner := &mockScanner{findings: []Finding{{Scanner: "mock", RuleID: "R1", Severity: SeverityCritical}}}
+
+		if err := module.Scan(context.Background(), t, scanner, SeverityHigh); err == nil {
+			t.Fatal("expected Scan() to fail")
+		}
+		if !module.ApplyFailed || len(module.Errors) != 1 || !strings.Contains(module.Errors[0], "security scan failed") {
+			t.Errorf("unexpected module state: failed=%v errors=%v", module.ApplyFailed, module.Errors)
+		}
+	})
+}
+
+func TestScanModuleRoot(t *testing.T) {
+	root := t.TempDir()
+	findings := []Finding{
+		{Scanner: "mock", RuleID: "R1", Severity: SeverityMedium, Location: "main.tf:3"},
+		{Scanner: "mock", RuleID: "R2", Severity: SeverityCritical, Location: "examples/default/main.tf:1"},
+		{Scanner: "mock", RuleID: "R3", Severity: SeverityCritical, Location: "/examples/default/main.tf:8"},
+	}
+
+	t.Run("example findings are left to the example scans", func(t *testing.T) {
+		got, err := scanModuleRoot(context.Background(), t, &mockScanner{findings: findings}, root, filepath.Join(root, "examples"), SeverityHigh)
+		if err != nil {
+			t.Fatalf("scanModuleRoot() error = %v", err)
+		}
+		if len(got) != 1 || got[0].RuleID != "R1" {
+			t.Errorf("scanModuleRoot() = %+v, want the finding in the module root only", got)
+		}
+	})
+
+	t.Run("findings at threshold fail the run", func(t *testing.T) {
+		scanner := &mockScanner{findings: findings}
+		got, err := scanModuleRoot(context.Background(), t, scanner, root, filepath.Join(root, "examples"), SeverityMedium)
+		if err == nil || !strings.Contains(err.Error(), root) {
+			t.Fatalf("scanModuleRoot() error = %v, want a failure naming the module root", err)
+		}
+		if len(got) != 1 || len(scanner.dirs) != 1 || scanner.dirs[0] != root {
+			t.Errorf("findings = %+v, scanned %v", got, scanner.dirs)
+		}
+	})
+}
+
+func TestDefaultTestRunner_ScansModuleRootOnce(t *testing.T) {
+	root := t.TempDir()
+	examples := filepath.Join(root, "examples")
+	scanner := &mockScanner{findings: []Finding{{Scanner: "mock", RuleID: "R1", Severity: SeverityLow, Location: "main.tf:1"}}}
+	var runners []ModuleRunner
+	for _, name := range []string{"example1", "example2"} {
+		module := NewModule(name, filepath.Join(examples, name))
+		module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+		module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+		runners = append(runners, module.Runner())
+	}
+
+	runner := &DefaultTestRunner{Config: NewConfig(WithExample(""), WithExamplesPath(examples), WithScanner(scanner))}
+	runner.RunTests(context.Background(), t, runners, false, nil)
+
+	if want := []string{root, filepath.Join(examples, "example1"), filepath.Join(examples, "example2")}; !slices.Equal(scanner.dirs, want) {
+		t.Errorf("scanned %v, want %v", scanner.dirs, want)
+	}
+	if findings := runner.Results.Findings(); len(findings[moduleRootFindings]) != 1 {
+		t.Errorf("Findings() = %v, want the module root's findings", findings)
+	}
+}
+
+func TestTestResults_Findings(t *testing.T) {
+	results := NewTestResults()
+
+	insecure := NewModule("insecure", "/path/insecure")
+	insecure.Findings = []Finding{{Scanner: "mock", RuleID: "R1", Severity: SeverityHigh}}
+	results.AddModule(insecure)
+	results.AddModule(NewModule("clean", "/path/clean"))
+
+	findings := results.Findings()
+	if len(findings) != 1 || len(findings["insecure"]) != 1 {
+		t.Errorf("Findings() = %v, want findings for insecure only", findings)
+	}
+}
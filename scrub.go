@@ -0,0 +1,201 @@
+package validor
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// artifact is a file about to be preserved or uploaded, named by its path
+// relative to the directory it is written to.
+type artifact struct {
+	name    string
+	content []byte
+}
+
+// scrubArtifacts removes sensitive values from artifacts before they leave
+// the run. Values terraform marks sensitive in state and plan JSON are
+// redacted, and masked wherever else they appear, such as in logs, together
+// with what masker masks. masker itself is not changed.
+func scrubArtifacts(artifacts []artifact, masker *Masker) {
+	scrub := masker.clone()
+	docs := make([]any, len(artifacts))
+	for i, a := range artifacts {
+		decoder := json.NewDecoder(bytes.NewReader(a.content))
+		decoder.UseNumber()
+		var doc map[string]any
+		if err := decoder.Decode(&doc); err != nil || doc == nil {
+			continue
+		}
+		redactDocument(doc, scrub.addValue)
+		docs[i] = doc
+	}
+
+	for i := range artifacts {
+		if docs[i] == nil {
+			artifacts[i].content = []byte(scrub.Mask(string(artifacts[i].content)))
+			continue
+		}
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(scrub.maskValue(docs[i])); err != nil {
+			artifacts[i].content = []byte(scrub.Mask(string(artifacts[i].content)))
+			continue
+		}
+		artifacts[i].content = buf.Bytes()
+	}
+}
+
+// redactDocument redacts the sensitive values of a state file, or of the
+// output of terraform show -json for a plan or state, passing each to found.
+func redactDocument(doc map[string]any, found func(any)) {
+	// Plans record changes with before_sensitive and after_sensitive markers.
+	for _, key := range []string{"resource_changes", "resource_drift"} {
+		for _, rc := range asSlice(doc[key]) {
+			if rc, ok := rc.(map[string]any); ok {
+				redactChange(rc["change"], found)
+			}
+		}
+	}
+	for _, change := range asMap(doc["output_changes"]) {
+		redactChange(change, found)
+	}
+	redactValues(doc["planned_values"], found)
+	redactValues(doc["values"], found)
+	if prior, ok := doc["prior_state"].(map[string]any); ok {
+		redactValues(prior["values"], found)
+	}
+	variables := asMap(asMap(asMap(doc["configuration"])["root_module"])["variables"])
+	for name, variable := range asMap(doc["variables"]) {
+		if asMap(variables[name])["sensitive"] == true {
+			variable := asMap(variable)
+			variable["value"] = redactSensitive(variable["value"], true, found)
+		}
+	}
+
+	// State files list the paths of sensitive attributes per instance.
+	redactOutputs(doc["outputs"], found)
+	for _, resource := range asSlice(doc["resources"]) {
+		for _, instance := range asSlice(asMap(resource)["instances"]) {
+			instance := asMap(instance)
+			for _, path := range asSlice(instance["sensitive_attributes"]) {
+				if path, ok := path.([]any); ok {
+					instance["attributes"] = redactPath(instance["attributes"], path, found)
+				}
+			}
+		}
+	}
+}
+
+func redactChange(change any, found func(any)) {
+	c := asMap(change)
+	if c == nil {
+		return
+	}
+	c["before"] = redactSensitive(c["before"], c["before_sensitive"], found)
+	c["after"] = redactSensitive(c["after"], c["after_sensitive"], found)
+}
+
+// redactValues redacts a values representation: the outputs and resources of
+// a module and its child modules.
+func redactValues(values any, found func(any)) {
+	v := asMap(values)
+	if v == nil {
+		return
+	}
+	redactOutputs(v["outputs"], found)
+	redactModule(v["root_module"], found)
+}
+
+func redactModule(module any, found func(any)) {
+	m := asMap(module)
+	if m == nil {
+		return
+	}
+	for _, resource := range asSlice(m["resources"]) {
+		if r := asMap(resource); r != nil {
+			r["values"] = redactSensitive(r["values"], r["sensitive_values"], found)
+		}
+	}
+	for _, child := range asSlice(m["child_modules"]) {
+		redactModule(child, found)
+	}
+}
+
+func redactOutputs(outputs any, found func(any)) {
+	for _, output := range asMap(outputs) {
+		if o := asMap(output); o != nil && o["sensitive"] == true {
+			o["value"] = redactSensitive(o["value"], true, found)
+		}
+	}
+}
+
+// redactSensitive replaces the parts of value that marks flags: true for the
+// whole value, or an object or list of markers shaped like it.
+func redactSensitive(value, marks any, found func(any)) any {
+	if value == nil {
+		return nil
+	}
+	switch m := marks.(type) {
+	case bool:
+		if !m {
+			return value
+		}
+		found(value)
+		return maskedSecret
+	case map[string]any:
+		if v, ok := value.(map[string]any); ok {
+			for key, nested := range m {
+				if _, ok := v[key]; ok {
+					v[key] = redactSensitive(v[key], nested, found)
+				}
+			}
+		}
+	case []any:
+		if v, ok := value.([]any); ok {
+			for i := range min(len(v), len(m)) {
+				v[i] = redactSensitive(v[i], m[i], found)
+			}
+		}
+	}
+	return value
+}
+
+// redactPath redacts the value at a path of sensitive_attributes in a state
+// file, a list of steps like {"type": "get_attr", "value": "password"}.
+func redactPath(value any, path []any, found func(any)) any {
+	if len(path) == 0 {
+		return redactSensitive(value, true, found)
+	}
+	step := asMap(path[0])
+	key := step["value"]
+	if step["type"] == "index" {
+		key = asMap(key)["value"]
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		if name, ok := key.(string); ok {
+			if _, ok := v[name]; ok {
+				v[name] = redactPath(v[name], path[1:], found)
+			}
+		}
+	case []any:
+		if n, ok := key.(json.Number); ok {
+			if i, err := n.Int64(); err == nil && i >= 0 && i < int64(len(v)) {
+				v[i] = redactPath(v[i], path[1:], found)
+			}
+		}
+	}
+	return value
+}
+
+func asMap(value any) map[string]any {
+	m, _ := value.(map[string]any)
+	return m
+}
+
+func asSlice(value any) []any {
+	s, _ := value.([]any)
+	return s
+}
@@ -0,0 +1,153 @@
+package validor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScrubArtifacts(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		patterns []string
+		values   []string
+		want     []string
+		wantNot  []string
+	}{
+		{
+			name: "plan resource changes",
+			content: `{"resource_changes":[{"address":"azurerm_key_vault_secret.this","change":{
+				"before":null,
+				"after":{"name":"admin","value":"hunter2-before","tags":["public","s3cr3t-tag"]},
+				"after_sensitive":{"value":true,"tags":[false,true]}}}]}`,
+			want:    []string{`"name": "admin"`, `"public"`, `"value": "***"`},
+			wantNot: []string{"hunter2-before", "s3cr3t-tag"},
+		},
+		{
+			name: "plan outputs, planned values and variables",
+			content: `{
+				"variables":{"password":{"value":"var-secret"},"location":{"value":"westeurope"}},
+				"output_changes":{"token":{"before":"old-token","after":"new-token","before_sensitive":true,"after_sensitive":true}},
+				"planned_values":{
+					"outputs":{"token":{"sensitive":true,"value":"new-token"}},
+					"root_module":{"child_modules":[{"resources":[{"values":{"key":"child-key"},"sensitive_values":{"key":true}}]}]}
+				},
+				"prior_state":{"values":{"root_module":{"resources":[{"values":{"key":"prior-key","id":"/subscriptions/1"},"sensitive_values":{"key":true}}]}}},
+				"configuration":{"root_module":{"variables":{"password":{"sensitive":true},"location":{}}}}
+			}`,
+			want:    []string{"westeurope", "/subscriptions/1"},
+			wantNot: []string{"var-secret", "old-token", "new-token", "child-key", "prior-key"},
+		},
+		{
+			name: "state sensitive attributes and outputs",
+			content: `{"version":4,
+				"outputs":{"connection":{"value":"Server=db;Password=out-secret","type":"string","sensitive":true},"name":{"value":"db01","type":"string"}},
+				"resources":[{"type":"azurerm_mssql_server","instances":[{
+					"attributes":{"administrator_login_password":"state-secret","keys":["first-key","second-key"],"ids":{"primary":"id-1"}},
+					"sensitive_attributes":[
+						[{"type":"get_attr","value":"administrator_login_password"}],
+						[{"type":"get_attr","value":"keys"},{"type":"index","value":{"value":1,"type":"number"}}]
+					]}]}]}`,
+			want:    []string{"db01", "first-key", "id-1"},
+			wantNot: []string{"out-secret", "state-secret", "second-key"},
+		},
+		{
+			name:     "logs with patterns and masked values",
+			content:  "apply failed with key AKIA1234567890ABCDEF and password run-secret\n",
+			patterns: []string{`AKIA[0-9A-Z]{16}`},
+			values:   []string{"run-secret"},
+			want:     []string{"apply failed with key ***"},
+			wantNot:  []string{"AKIA1234567890ABCDEF", "run-secret"},
+		},
+		{
+			name:    "numbers keep their precision",
+			content: `{"version":4,"serial":12345678901234567890,"resources":[]}`,
+			want:    []string{"12345678901234567890"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masker, err := NewMasker(tt.patterns...)
+			if err != nil {
+				t.Fatalf("NewMasker() error = %v", err)
+			}
+			masker.Add(tt.values...)
+
+			artifacts := []artifact{{name: "artifact", content: []byte(tt.content)}}
+			scrubArtifacts(artifacts, masker)
+			got := string(artifacts[0].content)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("scrubbed artifact should contain %q:\n%s", want, got)
+				}
+			}
+			for _, secret := range tt.wantNot {
+				if strings.Contains(got, secret) {
+					t.Errorf("scrubbed artifact should not contain %q:\n%s", secret, got)
+				}
+			}
+		})
+	}
+}
+
+func TestScrubArtifacts_MasksSensitiveValuesAcrossArtifacts(t *testing.T) {
+	masker, err := NewMasker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifacts := []artifact{
+		{name: "terraform.log", content: []byte("creating secret with value state-secret\n")},
+		{name: "terraform.tfstate", content: []byte(`{"resources":[{"instances":[{"attributes":{"value":"state-secret"},"sensitive_attributes":[[{"type":"get_attr","value":"value"}]]}]}]}`)},
+	}
+	scrubArtifacts(artifacts, masker)
+
+	for _, a := range artifacts {
+		if strings.Contains(string(a.content), "state-secret") {
+			t.Errorf("%s should not contain the sensitive value:\n%s", a.name, a.content)
+		}
+	}
+	if masker.Mask("state-secret") != "state-secret" {
+		t.Error("scrubbing should not add values to the run's masker")
+	}
+}
+
+func TestModule_PreserveOnFailureScrubsArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"terraform.tfstate":                             `{"version":4,"outputs":{"password":{"value":"state-password","sensitive":true}}}`,
+		"terraform.tfstate.d/staging/terraform.tfstate": `{"version":4,"outputs":{"password":{"value":"staging-password","sensitive":true}}}`,
+	})
+
+	module := NewModule("module/a", dir)
+	module.preserveDir = t.TempDir()
+	module.planJSON = []byte(`{"output_changes":{"password":{"after":"plan-password","after_sensitive":true}}}`)
+	if err := module.OpenLogFile(t.TempDir()); err != nil {
+		t.Fatalf("OpenLogFile() error = %v", err)
+	}
+	defer module.CloseLogFile()
+	if _, err := module.logFile.WriteString("output password = state-password\n"); err != nil {
+		t.Fatal(err)
+	}
+	module.failApply(t, &ModuleError{ModuleName: module.Name, Operation: "terraform apply", Err: errors.New("failed")})
+
+	if err := module.Cleanup(testContext(t), &recordingTB{TB: t}); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+
+	dest := filepath.Join(module.preserveDir, "module_a")
+	for _, file := range []string{"terraform.tfstate", "terraform.tfstate.d/staging/terraform.tfstate", "plan.json", "module_a.log"} {
+		content, err := os.ReadFile(filepath.Join(dest, file))
+		if err != nil {
+			t.Fatalf("%s should have been preserved: %v", file, err)
+		}
+		for _, secret := range []string{"state-password", "staging-password", "plan-password"} {
+			if strings.Contains(string(content), secret) {
+				t.Errorf("%s should not contain %q:\n%s", file, secret, content)
+			}
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// SecretProvider resolves a secret reference, the part after scheme:// in
+// vault://secret/data/app#password, to its value.
+type SecretProvider interface {
+	Resolve(ctx context.Context, reference string) (string, error)
+}
+
+type SecretProviderFunc func(ctx context.Context, reference string) (string, error)
+
+func (f SecretProviderFunc) Resolve(ctx context.Context, reference string) (string, error) {
+	return f(ctx, reference)
+}
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{
+		"vault":    SecretProviderFunc(resolveVaultSecret),
+		"keyvault": SecretProviderFunc(resolveKeyVaultSecret),
+	}
+)
+
+// RegisterSecretProvider makes a provider available for references starting
+// with scheme://, replacing any provider of that scheme.
+func RegisterSecretProvider(scheme string, provider SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[scheme] = provider
+}
+
+// WithSecret sets the environment variable name of every example to the
+// secret reference resolves to before the run, such as
+// vault://secret/data/app#password for HashiCorp Vault or
+// keyvault://my-vault/admin-password for Azure Key Vault. Name a variable
+// TF_VAR_<name> to pass the secret as a terraform variable. Secrets are
+// masked in logs and reports.
+func WithSecret(name, reference string) Option {
+	return func(c *Config) {
+		if c.Secrets == nil {
+			c.Secrets = make(map[string]string)
+		}
+		c.Secrets[name] = reference
+	}
+}
+
+func secretFlag(c *Config) func(string) error {
+	return func(value string) error {
+		name, reference, ok := strings.Cut(value, "=")
+		if !ok || name == "" || !strings.Contains(reference, "://") {
+			return fmt.Errorf("expected NAME=SCHEME://REFERENCE, got %q", value)
+		}
+		WithSecret(name, reference)(c)
+		return nil
+	}
+}
+
+// resolveSecrets resolves the references in secrets, keyed by the environment
+// variable they are for. Every failure is reported together.
+func resolveSecrets(ctx context.Context, secrets map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(secrets))
+	values := make(map[string]string)
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(secrets)) {
+		reference := secrets[name]
+		if value, ok := values[reference]; ok {
+			resolved[name] = value
+			continue
+		}
+		value, err := resolveSecret(ctx, reference)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		values[reference] = value
+		resolved[name] = value
+	}
+	return resolved, errors.Join(errs...)
+}
+
+func resolveSecret(ctx context.Context, reference string) (string, error) {
+	scheme, rest, ok := strings.Cut(reference, "://")
+	if !ok {
+		return "", fmt.Errorf("invalid secret reference %q, expected SCHEME://REFERENCE", reference)
+	}
+	secretProvidersMu.RLock()
+	provider, ok := secretProviders[scheme]
+	secretProvidersMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no secret provider for %s://", scheme)
+	}
+	value, err := provider.Resolve(ctx, rest)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", reference, err)
+	}
+	return value, nil
+}
+
+// useSecrets sets the resolved secrets as environment variables of options.
+func useSecrets(options *terraform.Options, secrets map[string]string) {
+	if len(secrets) == 0 {
+		return
+	}
+	if options.EnvVars == nil {
+		options.EnvVars = make(map[string]string)
+	}
+	maps.Copy(options.EnvVars, secrets)
+}
+
+var secretHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// getSecretJSON gets endpoint with headers and decodes the JSON response.
+func getSecretJSON(ctx context.Context, endpoint string, headers map[string]string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := secretHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// resolveVaultSecret reads a field of a HashiCorp Vault secret, given as
+// PATH#FIELD, from VAULT_ADDR with VAULT_TOKEN or the token vault login
+// stores. Paths of the KV version 2 engine include its data/ segment.
+func resolveVaultSecret(ctx context.Context, reference string) (string, error) {
+	path, field, ok := strings.Cut(reference, "#")
+	if !ok || path == "" || field == "" {
+		return "", errors.New("expected vault://PATH#FIELD")
+	}
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", errors.New("VAULT_ADDR is not set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			data, _ := os.ReadFile(filepath.Join(home, ".vault-token"))
+			token = strings.TrimSpace(string(data))
+		}
+	}
+	if token == "" {
+		return "", errors.New("VAULT_TOKEN is not set and there is no token from vault login")
+	}
+	headers := map[string]string{"X-Vault-Token": token}
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		headers["X-Vault-Namespace"] = namespace
+	}
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := getSecretJSON(ctx, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), headers, &secret); err != nil {
+		return "", err
+	}
+	data := secret.Data
+	// KV version 2 nests the secret in data.data, next to its metadata.
+	if nested, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("the secret has no field %s", field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+var (
+	keyVaultBaseURL = func(vault string) string {
+		if strings.Contains(vault, ".") {
+			return "https://" + vault
+		}
+		return "https://" + vault + ".vault.azure.net"
+	}
+	keyVaultAccessToken = func(ctx context.Context) (string, error) {
+		return azureAccessToken(ctx, "https://vault.azure.net/.default")
+	}
+)
+
+// resolveKeyVaultSecret reads an Azure Key Vault secret, given as
+// VAULT/NAME[/VERSION], with a token from azureCredential. VAULT is the name
+// of the vault or, in other clouds, its host name.
+func resolveKeyVaultSecret(ctx context.Context, reference string) (string, error) {
+	parts := strings.Split(reference, "/")
+	if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+		return "", errors.New("expected keyvault://VAULT/NAME[/VERSION]")
+	}
+	token, err := keyVaultAccessToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a Key Vault token: %w", err)
+	}
+
+	endpoint := keyVaultBaseURL(parts[0]) + "/secrets/" + url.PathEscape(parts[1])
+	if len(parts) == 3 {
+		endpoint += "/" + url.PathEscape(parts[2])
+	}
+	var secret struct {
+		Value string `json:"value"`
+	}
+	if err := getSecretJSON(ctx, endpoint+"?api-version=7.4", map[string]string{"Authorization": "Bearer " + token}, &secret); err != nil {
+		return "", err
+	}
+	return secret.Value, nil
+}
@@ -0,0 +1,250 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveVaultSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			w.Write([]byte(`{"data":{"data":{"password":"hunter22","ports":[80,443]},"metadata":{"version":3}}}`))
+		case "/v1/kv/app":
+			w.Write([]byte(`{"data":{"password":"legacy1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL+"/")
+	t.Setenv("VAULT_TOKEN", "s.token")
+	t.Setenv("VAULT_NAMESPACE", "team")
+
+	tests := []struct {
+		name      string
+		reference string
+		want      string
+		wantErr   string
+	}{
+		{name: "kv version 2", reference: "secret/data/app#password", want: "hunter22"},
+		{name: "kv version 1", reference: "kv/app#password", want: "legacy1"},
+		{name: "structured value", reference: "secret/data/app#ports", want: "[80,443]"},
+		{name: "missing field", reference: "secret/data/app#username", wantErr: "no field username"},
+		{name: "missing secret", reference: "secret/data/other#password", wantErr: "HTTP 404"},
+		{name: "no field", reference: "secret/data/app", wantErr: "expected vault://PATH#FIELD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveVaultSecret(context.Background(), tt.reference)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveVaultSecret() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveVaultSecret() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveVaultSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveKeyVaultSecret(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer kv-token" || r.URL.Query().Get("api-version") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		paths = append(paths, r.Host+r.URL.Path)
+		w.Write([]byte(`{"value":"p@ssw0rd","id":"https://kv-demo.vault.azure.net/secrets/admin/1"}`))
+	}))
+	defer server.Close()
+
+	origURL, origToken := keyVaultBaseURL, keyVaultAccessToken
+	t.Cleanup(func() { keyVaultBaseURL, keyVaultAccessToken = origURL, origToken })
+	keyVaultBaseURL = func(vault string) string { return server.URL }
+	keyVaultAccessToken = func(ctx context.Context) (string, error) { return "kv-token", nil }
+
+	for _, reference := range []string{"kv-demo/admin", "kv-demo/admin/0123abc"} {
+		got, err := resolveKeyVaultSecret(context.Background(), reference)
+		if err != nil || got != "p@ssw0rd" {
+			t.Errorf("resolveKeyVaultSecret(%q) = %q, %v", reference, got, err)
+		}
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	if want := []string{host + "/secrets/admin", host + "/secrets/admin/0123abc"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("requests = %v, want %v", paths, want)
+	}
+
+	if _, err := resolveKeyVaultSecret(context.Background(), "kv-demo"); err == nil {
+		t.Error("expected an error for a reference without a secret name")
+	}
+	keyVaultAccessToken = func(ctx context.Context) (string, error) { return "", errors.New("az login required") }
+	if _, err := resolveKeyVaultSecret(context.Background(), "kv-demo/admin"); err == nil || !strings.Contains(err.Error(), "az login required") {
+		t.Errorf("error = %v, want the credential error", err)
+	}
+	if got := origURL("kv-demo"); got != "https://kv-demo.vault.azure.net" {
+		t.Errorf("keyVaultBaseURL() = %q", got)
+	}
+	if got := origURL("kv-demo.vault.azure.cn"); got != "https://kv-demo.vault.azure.cn" {
+		t.Errorf("keyVaultBaseURL() in another cloud = %q", got)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	var calls []string
+	RegisterSecretProvider("test", SecretProviderFunc(func(ctx context.Context, reference string) (string, error) {
+		calls = append(calls, reference)
+		if reference == "missing" {
+			return "", errors.New("not found")
+		}
+		return "value-of-" + reference, nil
+	}))
+	t.Cleanup(func() {
+		secretProvidersMu.Lock()
+		delete(secretProviders, "test")
+		secretProvidersMu.Unlock()
+	})
+
+	got, err := resolveSecrets(context.Background(), map[string]string{
+		"ARM_CLIENT_SECRET":    "test://client",
+		"TF_VAR_client_secret": "test://client",
+		"TF_VAR_password":      "test://password",
+	})
+	if err != nil {
+		t.Fatalf("resolveSecrets() error = %v", err)
+	}
+	want := map[string]string{"ARM_CLIENT_SECRET": "value-of-client", "TF_VAR_client_secret": "value-of-client", "TF_VAR_password": "value-of-password"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveSecrets() = %v, want %v", got, want)
+	}
+	if len(calls) != 2 {
+		t.Errorf("resolved %v, want each reference once", calls)
+	}
+
+	_, err = resolveSecrets(context.Background(), map[string]string{"A": "test://missing", "B": "sops://secrets.yaml"})
+	if err == nil || !strings.Contains(err.Error(), "A: failed to resolve test://missing: not found") || !strings.Contains(err.Error(), "B: no secret provider for sops://") {
+		t.Errorf("resolveSecrets() error = %v, want both failures", err)
+	}
+}
+
+func TestSecretFlag(t *testing.T) {
+	config := &Config{}
+	flag := secretFlag(config)
+	if err := flag("TF_VAR_password=vault://secret/data/app#password"); err != nil {
+		t.Fatalf("secretFlag() error = %v", err)
+	}
+	if want := map[string]string{"TF_VAR_password": "vault://secret/data/app#password"}; !reflect.DeepEqual(config.Secrets, want) {
+		t.Errorf("Secrets = %v, want %v", config.Secrets, want)
+	}
+	for _, value := range []string{"TF_VAR_password", "=vault://a#b", "TF_VAR_password=hunter2"} {
+		if err := flag(value); err == nil {
+			t.Errorf("secretFlag(%q) should fail", value)
+		}
+	}
+}
+
+func TestRunModuleTests_Secrets(t *testing.T) {
+	RegisterSecretProvider("test", SecretProviderFunc(func(ctx context.Context, reference string) (string, error) {
+		return "s3cret-" + reference, nil
+	}))
+	t.Cleanup(func() {
+		secretProvidersMu.Lock()
+		delete(secretProviders, "test")
+		secretProvidersMu.Unlock()
+	})
+
+	var got string
+	module := NewModule("default", t.TempDir())
+	module.applyHook = func(ctx context.Context, tb testing.TB, m *Module) error {
+		got = m.Options.EnvVars["TF_VAR_password"]
+		tb.Logf("password is %s", got)
+		return nil
+	}
+	module.destroyHook = func(ctx context.Context, tb testing.TB, m *Module) error { return nil }
+
+	tb := &recordingTB{TB: t}
+	runModuleTests(tb, Runners([]*Module{module}), false, NewConfig(WithSecret("TF_VAR_password", "test://admin")), nil, "registry")
+
+	if got != "s3cret-admin" {
+		t.Errorf("TF_VAR_password = %q, want the resolved secret", got)
+	}
+	logs := strings.Join(tb.logs, "\n")
+	if strings.Contains(logs, "s3cret-admin") || !strings.Contains(logs, "password is ***") {
+		t.Errorf("the secret was not masked in the logs:\n%s", logs)
+	}
+}
+
+func TestDestroyAll_MasksSecrets(t *testing.T) {
+	RegisterSecretProvider("test", SecretProviderFunc(func(ctx context.Context, reference string) (string, error) {
+		return "s3cret-" + reference, nil
+	}))
+	t.Cleanup(func() {
+		secretProvidersMu.Lock()
+		delete(secretProviders, "test")
+		secretProvidersMu.Unlock()
+	})
+
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	manifest := &DestroyManifest{}
+	manifest.add(ManifestExample{Name: "default", Path: t.TempDir(), Workspace: "default", Created: time.Now()})
+	if err := manifest.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	origDestroy := destroyManifestExample
+	t.Cleanup(func() { destroyManifestExample = origDestroy })
+	destroyManifestExample = func(ctx context.Context, tb testing.TB, module *Module) error {
+		tb.Logf("password is %s", module.Options.EnvVars["TF_VAR_password"])
+		return nil
+	}
+
+	tb := &recordingTB{TB: t}
+	destroyAll(context.Background(), tb, NewConfig(WithDestroyManifest(path), WithSecret("TF_VAR_password", "test://admin")))
+
+	logs := strings.Join(tb.logs, "\n")
+	if strings.Contains(logs, "s3cret-admin") || !strings.Contains(logs, "password is ***") {
+		t.Errorf("the secret was not masked in the logs:\n%s", logs)
+	}
+}
+
+func TestCheckSnippets_MasksSecrets(t *testing.T) {
+	masker, err := (&Config{}).secretMasker(map[string]string{"TF_VAR_password": "s3cret-admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	module := NewModule("README.md:3", t.TempDir())
+	module.masker = masker
+	module.validateHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+		return []byte(`{"valid": true}`), nil
+	}
+	module.planHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+		tb.Logf("password is %s", "s3cret-admin")
+		return []byte(`{}`), nil
+	}
+
+	tb := &recordingTB{TB: t}
+	checkSnippets(context.Background(), tb, []*Module{module})
+
+	logs := strings.Join(tb.logs, "\n")
+	if strings.Contains(logs, "s3cret-admin") || !strings.Contains(logs, "password is ***") {
+		t.Errorf("the secret was not masked in the logs:\n%s", logs)
+	}
+}
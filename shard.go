@@ -0,0 +1,192 @@
+package validor
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithShard runs only the examples of shard index, from 1 to total, so a
+// suite can be split over parallel CI jobs. With WithHistory the shards are
+// balanced by the examples' recorded durations, otherwise by their count.
+// Examples that depend on each other end up in the same shard.
+func WithShard(index, total int) Option {
+	return func(c *Config) {
+		c.ShardIndex = index
+		c.ShardTotal = total
+	}
+}
+
+// shardFlag parses INDEX/TOTAL, e.g. 2/4.
+func shardFlag(c *Config) func(string) error {
+	return func(value string) error {
+		index, total, ok := strings.Cut(value, "/")
+		i, errIndex := strconv.Atoi(strings.TrimSpace(index))
+		n, errTotal := strconv.Atoi(strings.TrimSpace(total))
+		if !ok || errIndex != nil || errTotal != nil || n < 1 || i < 1 || i > n {
+			return fmt.Errorf("invalid value %q, expected INDEX/TOTAL with 1 <= INDEX <= TOTAL", value)
+		}
+		c.ShardIndex, c.ShardTotal = i, n
+		return nil
+	}
+}
+
+// ExpectedDurations returns the average duration of every example over its
+// last runs in the history.
+func ExpectedDurations(runs []HistoryRun) map[string]time.Duration {
+	recorded := make(map[string][]time.Duration)
+	for _, run := range runs {
+		for name, duration := range run.Durations {
+			recorded[name] = append(recorded[name], duration)
+		}
+	}
+
+	expected := make(map[string]time.Duration, len(recorded))
+	for name, durations := range recorded {
+		if len(durations) > historyWindow {
+			durations = durations[len(durations)-historyWindow:]
+		}
+		var total time.Duration
+		for _, duration := range durations {
+			total += duration
+		}
+		expected[name] = total / time.Duration(len(durations))
+	}
+	return expected
+}
+
+// Shard is one of the partitions made by PartitionByDuration.
+type Shard struct {
+	Examples []string
+	Expected time.Duration
+}
+
+// PartitionByDuration splits groups of examples over total shards so that
+// their expected durations are as even as possible, placing the longest group
+// first on the shard with the least work. A group stays on one shard. Examples
+// without a recorded duration count as the average of those with one, or as
+// equal when none has one, which balances the shards by count. The result
+// only depends on its input, so every shard of a CI job computes the same
+// partition.
+func PartitionByDuration(groups [][]string, durations map[string]time.Duration, total int) []Shard {
+	var known time.Duration
+	var counted int
+	for _, group := range groups {
+		for _, name := range group {
+			if duration, ok := durations[name]; ok {
+				known += duration
+				counted++
+			}
+		}
+	}
+	fallback := time.Minute
+	if counted > 0 {
+		fallback = known / time.Duration(counted)
+	}
+	expected := func(name string) time.Duration {
+		if duration, ok := durations[name]; ok {
+			return duration
+		}
+		return fallback
+	}
+
+	type weighted struct {
+		names    []string
+		duration time.Duration
+	}
+	items := make([]weighted, 0, len(groups))
+	for _, group := range groups {
+		item := weighted{names: group}
+		for _, name := range group {
+			item.duration += expected(name)
+		}
+		items = append(items, item)
+	}
+	slices.SortStableFunc(items, func(a, b weighted) int {
+		if c := cmp.Compare(b.duration, a.duration); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.names[0], b.names[0])
+	})
+
+	shards := make([]Shard, total)
+	for _, item := range items {
+		target := 0
+		for i := range shards {
+			if shards[i].Expected < shards[target].Expected ||
+				(shards[i].Expected == shards[target].Expected && len(shards[i].Examples) < len(shards[target].Examples)) {
+				target = i
+			}
+		}
+		shards[target].Examples = append(shards[target].Examples, item.names...)
+		shards[target].Expected += item.duration
+	}
+	return shards
+}
+
+// dependencyGroups returns the names of the runners grouped so that runners
+// that depend on each other, directly or not, share a group.
+func dependencyGroups(runners []ModuleRunner) [][]string {
+	parent := make([]int, len(runners))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i, dependencies := range dependencyIndices(runners) {
+		for _, j := range dependencies {
+			parent[find(i)] = find(j)
+		}
+	}
+
+	var groups [][]string
+	index := make(map[int]int)
+	for i, runner := range runners {
+		root := find(i)
+		if _, ok := index[root]; !ok {
+			index[root] = len(groups)
+			groups = append(groups, nil)
+		}
+		groups[index[root]] = append(groups[index[root]], runner.Name())
+	}
+	return groups
+}
+
+// shardRunners returns the runners of the configured shard, keeping their
+// order, and a description of the shard for the log.
+func shardRunners(runners []ModuleRunner, config *Config) ([]ModuleRunner, string, error) {
+	if config.ShardTotal <= 1 {
+		return runners, "", nil
+	}
+	if config.ShardIndex < 1 || config.ShardIndex > config.ShardTotal {
+		return nil, "", fmt.Errorf("shard %d is out of range 1 to %d", config.ShardIndex, config.ShardTotal)
+	}
+
+	var durations map[string]time.Duration
+	if config.HistoryFile != "" {
+		runs, err := LoadHistory(config.HistoryFile)
+		if err != nil {
+			return nil, "", err
+		}
+		durations = ExpectedDurations(runs)
+	}
+
+	shard := PartitionByDuration(dependencyGroups(runners), durations, config.ShardTotal)[config.ShardIndex-1]
+	selected := slices.DeleteFunc(slices.Clone(runners), func(runner ModuleRunner) bool {
+		return !slices.Contains(shard.Examples, runner.Name())
+	})
+
+	description := fmt.Sprintf("Running shard %d of %d: %d of %d examples", config.ShardIndex, config.ShardTotal, len(selected), len(runners))
+	if len(durations) > 0 {
+		description += fmt.Sprintf(", expected to take %s", shard.Expected.Round(time.Second))
+	}
+	return selected, description, nil
+}
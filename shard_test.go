@@ -0,0 +1,122 @@
+package validor
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPartitionByDuration(t *testing.T) {
+	tests := []struct {
+		name      string
+		groups    [][]string
+		durations map[string]time.Duration
+		want      []Shard
+	}{
+		{
+			name:      "balances by duration",
+			groups:    [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}},
+			durations: map[string]time.Duration{"a": 30 * time.Minute, "b": 10 * time.Minute, "c": 10 * time.Minute, "d": 5 * time.Minute, "e": 5 * time.Minute},
+			want: []Shard{
+				{Examples: []string{"a"}, Expected: 30 * time.Minute},
+				{Examples: []string{"b", "c", "d", "e"}, Expected: 30 * time.Minute},
+			},
+		},
+		{
+			name:   "balances by count without history",
+			groups: [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}},
+			want: []Shard{
+				{Examples: []string{"a", "c", "e"}, Expected: 3 * time.Minute},
+				{Examples: []string{"b", "d"}, Expected: 2 * time.Minute},
+			},
+		},
+		{
+			name:      "unknown examples count as the average",
+			groups:    [][]string{{"a"}, {"b"}, {"new"}},
+			durations: map[string]time.Duration{"a": 20 * time.Minute, "b": 10 * time.Minute},
+			want: []Shard{
+				{Examples: []string{"a"}, Expected: 20 * time.Minute},
+				{Examples: []string{"new", "b"}, Expected: 25 * time.Minute},
+			},
+		},
+		{
+			name:      "groups stay together",
+			groups:    [][]string{{"network", "app"}, {"storage"}},
+			durations: map[string]time.Duration{"network": time.Minute, "app": time.Minute, "storage": 5 * time.Minute},
+			want: []Shard{
+				{Examples: []string{"storage"}, Expected: 5 * time.Minute},
+				{Examples: []string{"network", "app"}, Expected: 2 * time.Minute},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PartitionByDuration(tt.groups, tt.durations, 2); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PartitionByDuration() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpectedDurations(t *testing.T) {
+	runs := []HistoryRun{
+		{Durations: map[string]time.Duration{"default": 10 * time.Minute}},
+		{Durations: map[string]time.Duration{"default": 20 * time.Minute, "complete": time.Hour}},
+		{Results: map[string]bool{"default": true}},
+	}
+	want := map[string]time.Duration{"default": 15 * time.Minute, "complete": time.Hour}
+	if got := ExpectedDurations(runs); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpectedDurations() = %v, want %v", got, want)
+	}
+}
+
+func TestShardRunners(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := appendHistory(history, HistoryRun{Durations: map[string]time.Duration{
+		"complete": time.Hour, "default": 10 * time.Minute, "network": 20 * time.Minute, "app": 20 * time.Minute,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewModule("app", "/tmp/app")
+	app.Metadata = &ExampleMetadata{DependsOn: []string{"network"}}
+	runners := Runners([]*Module{NewModule("complete", "/tmp/complete"), NewModule("default", "/tmp/default"), NewModule("network", "/tmp/network"), app})
+
+	var names [][]string
+	for index := 1; index <= 2; index++ {
+		selected, description, err := shardRunners(runners, NewConfig(WithHistory(history), WithShard(index, 2)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(description, "Running shard ") || !strings.Contains(description, "expected to take") {
+			t.Errorf("description = %q", description)
+		}
+		names = append(names, extractModuleNames(runnerModules(selected)))
+	}
+	want := [][]string{{"complete"}, {"default", "network", "app"}}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("shards = %v, want %v", names, want)
+	}
+
+	if selected, _, _ := shardRunners(runners, NewConfig()); len(selected) != len(runners) {
+		t.Error("without sharding every runner should run")
+	}
+	if _, _, err := shardRunners(runners, NewConfig(WithShard(3, 2))); err == nil {
+		t.Error("expected an error for a shard out of range")
+	}
+}
+
+func TestShardFlag(t *testing.T) {
+	config := NewConfig()
+	if err := shardFlag(config)("2/4"); err != nil || config.ShardIndex != 2 || config.ShardTotal != 4 {
+		t.Errorf("shardFlag(2/4) = %v, shard %d/%d", err, config.ShardIndex, config.ShardTotal)
+	}
+	for _, value := range []string{"0/4", "5/4", "2", "a/b", "1/0"} {
+		if err := shardFlag(config)(value); err == nil {
+			t.Errorf("shardFlag(%q) should fail", value)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package validor
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// WithShuffle runs the examples in a random order derived from seed, to bring
+// out hidden dependencies between them such as shared resource names or quota.
+// A seed of 0 picks one from the clock; the seed is logged either way.
+func WithShuffle(seed int64) Option {
+	return func(c *Config) {
+		c.Shuffle = true
+		c.ShuffleSeed = seed
+	}
+}
+
+// shuffleFlag parses -shuffle like go test does: off, on or a seed.
+func shuffleFlag(c *Config) func(string) error {
+	return func(value string) error {
+		switch value {
+		case "off":
+			c.Shuffle, c.ShuffleSeed = false, 0
+		case "on":
+			c.Shuffle, c.ShuffleSeed = true, 0
+		default:
+			seed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value %q, expected off, on or a seed", value)
+			}
+			c.Shuffle, c.ShuffleSeed = true, seed
+		}
+		return nil
+	}
+}
+
+var shuffleClock = time.Now
+
+// shuffleRunners returns runners in the order seed gives them, picking a seed
+// when it is 0, and the seed that was used.
+func shuffleRunners(runners []ModuleRunner, seed int64) ([]ModuleRunner, int64) {
+	if seed == 0 {
+		seed = shuffleClock().UnixNano()
+	}
+	shuffled := slices.Clone(runners)
+	random := rand.New(rand.NewPCG(uint64(seed), 0))
+	random.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	return shuffled, seed
+}
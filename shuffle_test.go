@@ -0,0 +1,65 @@
+package validor
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestShuffleRunners(t *testing.T) {
+	var modules []*Module
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		modules = append(modules, NewModule(name, "/tmp/"+name))
+	}
+	runners := Runners(modules)
+	original := extractModuleNames(modules)
+
+	first, seed := shuffleRunners(runners, 42)
+	second, _ := shuffleRunners(runners, 42)
+	if seed != 42 {
+		t.Errorf("seed = %d, want 42", seed)
+	}
+	if !reflect.DeepEqual(extractModuleNames(runnerModules(first)), extractModuleNames(runnerModules(second))) {
+		t.Error("the same seed should give the same order")
+	}
+	if got := extractModuleNames(runnerModules(first)); slices.Equal(got, original) {
+		t.Errorf("order %v was not shuffled", got)
+	}
+	if !reflect.DeepEqual(extractModuleNames(runnerModules(runners)), original) {
+		t.Error("shuffling should not reorder the input")
+	}
+
+	origClock := shuffleClock
+	t.Cleanup(func() { shuffleClock = origClock })
+	shuffleClock = func() time.Time { return time.Unix(0, 1234) }
+	if _, seed := shuffleRunners(runners, 0); seed != 1234 {
+		t.Errorf("seed from the clock = %d, want 1234", seed)
+	}
+}
+
+func TestShuffleFlag(t *testing.T) {
+	tests := []struct {
+		value    string
+		wantOn   bool
+		wantSeed int64
+		wantErr  bool
+	}{
+		{value: "on", wantOn: true},
+		{value: "off"},
+		{value: "7", wantOn: true, wantSeed: 7},
+		{value: "sometimes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			config := NewConfig(WithShuffle(99))
+			err := shuffleFlag(config)(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("shuffleFlag(%q) error = %v", tt.value, err)
+			}
+			if !tt.wantErr && (config.Shuffle != tt.wantOn || config.ShuffleSeed != tt.wantSeed) {
+				t.Errorf("Shuffle = %v, ShuffleSeed = %d", config.Shuffle, config.ShuffleSeed)
+			}
+		})
+	}
+}
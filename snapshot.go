@@ -0,0 +1,235 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// WithPlanSnapshots compares the normalized plan of every example to its golden
+// file before applying it, failing the example when they differ. This catches
+// unintended changes to what an example plans; TestPlanSnapshots does the same
+// without applying anything.
+func WithPlanSnapshots(enabled bool) Option {
+	return func(c *Config) { c.PlanSnapshots = enabled }
+}
+
+// WithSnapshotDir sets the directory the golden files are kept in, relative to
+// the test directory. It defaults to testdata/snapshots.
+func WithSnapshotDir(dir string) Option {
+	return func(c *Config) { c.SnapshotDir = dir }
+}
+
+// WithUpdateSnapshots writes the plans of the examples as their golden files
+// instead of comparing them.
+func WithUpdateSnapshots(update bool) Option {
+	return func(c *Config) { c.UpdateSnapshots = update }
+}
+
+const (
+	defaultSnapshotDir = "testdata/snapshots"
+	unknownValue       = "(known after apply)"
+)
+
+var (
+	uuidPattern      = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+)
+
+func (c *Config) snapshotDir() string {
+	if c.SnapshotDir != "" {
+		return c.SnapshotDir
+	}
+	return defaultSnapshotDir
+}
+
+type snapshotResource struct {
+	Address string   `json:"address"`
+	Actions []string `json:"actions"`
+	After   any      `json:"after,omitempty"`
+}
+
+// NormalizePlan reduces plan JSON to what a golden file compares: the resource
+// changes sorted by address, with values that are only known after apply
+// marked as such and UUIDs and timestamps replaced, so plans of the same
+// configuration are identical across runs and subscriptions.
+func NormalizePlan(planJSON []byte) ([]byte, error) {
+	resources, err := plannedResources(planJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := []snapshotResource{}
+	for _, address := range slices.Sorted(maps.Keys(resources)) {
+		rc := resources[address]
+		var after any
+		if rc.Change.After != nil {
+			after = normalizeValue(markUnknown(map[string]any(rc.Change.After), map[string]any(rc.Change.AfterUnknown)))
+		}
+		snapshot = append(snapshot, snapshotResource{Address: address, Actions: rc.Change.Actions, After: after})
+	}
+
+	out, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// markUnknown replaces the values after_unknown flags as unknown.
+func markUnknown(value, unknown any) any {
+	switch u := unknown.(type) {
+	case bool:
+		if u {
+			return unknownValue
+		}
+	case map[string]any:
+		v, _ := value.(map[string]any)
+		if v == nil {
+			v = make(map[string]any)
+		}
+		for key, nested := range u {
+			if marked := markUnknown(v[key], nested); marked != nil {
+				v[key] = marked
+			}
+		}
+		return v
+	case []any:
+		v, _ := value.([]any)
+		for i, nested := range u {
+			if i < len(v) {
+				v[i] = markUnknown(v[i], nested)
+			} else if nested == true {
+				v = append(v, unknownValue)
+			}
+		}
+		return v
+	}
+	return value
+}
+
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		v = uuidPattern.ReplaceAllString(v, "(uuid)")
+		return timestampPattern.ReplaceAllString(v, "(timestamp)")
+	case map[string]any:
+		for key, nested := range v {
+			v[key] = normalizeValue(nested)
+		}
+	case []any:
+		for i, nested := range v {
+			v[i] = normalizeValue(nested)
+		}
+	}
+	return value
+}
+
+func snapshotPath(dir, name string) string {
+	return filepath.Join(dir, strings.TrimSuffix(logFileName(name), ".log")+".json")
+}
+
+// CheckPlanSnapshot compares planJSON, normalized, to the golden file of the
+// example in dir, or writes it there when update is set.
+func (m *Module) CheckPlanSnapshot(t testing.TB, planJSON []byte, dir string, update bool) error {
+	snapshot, err := NormalizePlan(planJSON)
+	if err != nil {
+		return err
+	}
+	path := snapshotPath(dir, m.Name)
+
+	if update {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+		if err := os.WriteFile(path, snapshot, 0644); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		t.Logf("Updated plan snapshot of %s: %s", m.Name, path)
+		return nil
+	}
+
+	golden, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no plan snapshot at %s; run with -update-snapshots to create it", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if string(golden) == string(snapshot) {
+		return nil
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(golden)),
+		B:        difflib.SplitLines(string(snapshot)),
+		FromFile: path,
+		ToFile:   "plan",
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to diff snapshot: %w", err)
+	}
+	return fmt.Errorf("plan differs from snapshot %s (run with -update-snapshots if intended):\n%s", path, diff)
+}
+
+func snapshotCheck(dir string, update bool) planCheck {
+	return planCheck{
+		operation: "plan snapshot",
+		run: func(ctx context.Context, t testing.TB, m *Module, planJSON []byte) error {
+			return m.CheckPlanSnapshot(t, planJSON, dir, update)
+		},
+	}
+}
+
+// TestPlanSnapshots plans every example and compares the plans to their
+// golden files, or updates them with -update-snapshots. Nothing is applied.
+func TestPlanSnapshots(t testing.TB, opts ...Option) {
+	config := setupConfigWithOptions(opts...)
+	var modules []*Module
+	if config.Example != "" {
+		modules = createModulesFromNames(parseExampleList(config.Example), getExamplesPath(config))
+	} else {
+		modules = discoverModules(t, config)
+	}
+	checkPlanSnapshots(t, config, modules)
+}
+
+func checkPlanSnapshots(t testing.TB, config *Config, modules []*Module) {
+	ctx := context.Background()
+	for _, module := range modules {
+		if slices.Contains(config.ExceptionList, module.Name) {
+			continue
+		}
+		runSubtest(t, module.Name, false, func(t testing.TB) {
+			defer module.startStage(StagePlan)()
+			if module.planHook == nil {
+				if err := module.init(t); err != nil {
+					t.Error(errorText(fmt.Sprintf("Failed to init %s: %v", module.Name, err)))
+					return
+				}
+			}
+			planJSON, err := module.Plan(ctx, t)
+			if err != nil {
+				t.Error(errorText(fmt.Sprintf("Failed to plan %s: %v", module.Name, err)))
+				return
+			}
+			if err := module.CheckPlanSnapshot(t, planJSON, config.snapshotDir(), config.UpdateSnapshots); err != nil {
+				t.Error(errorText(err.Error()))
+				return
+			}
+			if !config.UpdateSnapshots {
+				t.Log(successText(fmt.Sprintf("✓ %s: the plan matches its snapshot", module.Name)))
+			}
+		})
+	}
+}
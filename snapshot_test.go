@@ -0,0 +1,110 @@
+package validor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePlan(t *testing.T) {
+	plan := `{"timestamp":"2026-01-02T10:00:00Z","resource_changes":[
+		{"address":"azurerm_storage_account.sa","change":{"actions":["create"],"after":{"name":"sa","tags":{"created":"2026-01-02T10:00:00.123+01:00"},"rules":[{"name":"a"}]},"after_unknown":{"id":true,"rules":[{"id":true}]}}},
+		{"address":"azurerm_role_assignment.ra","change":{"actions":["create"],"after":{"name":"0f8fad5b-d9cb-469f-a165-70867728950e"}}},
+		{"address":"azurerm_key_vault.kv","change":{"actions":["no-op"],"after":{"name":"kv"}}},
+		{"address":"data.azurerm_client_config.current","change":{"actions":["read"]}}
+	]}`
+
+	got, err := NormalizePlan([]byte(plan))
+	if err != nil {
+		t.Fatalf("NormalizePlan() error = %v", err)
+	}
+	want := `[
+  {
+    "address": "azurerm_role_assignment.ra",
+    "actions": [
+      "create"
+    ],
+    "after": {
+      "name": "(uuid)"
+    }
+  },
+  {
+    "address": "azurerm_storage_account.sa",
+    "actions": [
+      "create"
+    ],
+    "after": {
+      "id": "(known after apply)",
+      "name": "sa",
+      "rules": [
+        {
+          "id": "(known after apply)",
+          "name": "a"
+        }
+      ],
+      "tags": {
+        "created": "(timestamp)"
+      }
+    }
+  }
+]
+`
+	if string(got) != want {
+		t.Errorf("NormalizePlan() = %s, want %s", got, want)
+	}
+
+	if _, err := NormalizePlan([]byte("not json")); err == nil {
+		t.Error("NormalizePlan() should fail on invalid json")
+	}
+}
+
+func TestModule_CheckPlanSnapshot(t *testing.T) {
+	plan := func(name string) []byte {
+		return []byte(`{"resource_changes":[{"address":"azurerm_resource_group.rg","change":{"actions":["create"],"after":{"name":"` + name + `"}}}]}`)
+	}
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	module := NewModule("default", t.TempDir())
+
+	if err := module.CheckPlanSnapshot(t, plan("rg"), dir, false); err == nil || !strings.Contains(err.Error(), "-update-snapshots") {
+		t.Errorf("CheckPlanSnapshot() without a golden file error = %v, want a hint to update", err)
+	}
+	if err := module.CheckPlanSnapshot(t, plan("rg"), dir, true); err != nil {
+		t.Fatalf("CheckPlanSnapshot() update error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "default.json")); err != nil {
+		t.Fatalf("the golden file should be written: %v", err)
+	}
+	if err := module.CheckPlanSnapshot(t, plan("rg"), dir, false); err != nil {
+		t.Errorf("CheckPlanSnapshot() on a matching plan error = %v", err)
+	}
+
+	err := module.CheckPlanSnapshot(t, plan("rg-renamed"), dir, false)
+	if err == nil {
+		t.Fatal("CheckPlanSnapshot() should fail when the plan changed")
+	}
+	for _, line := range []string{`-      "name": "rg"`, `+      "name": "rg-renamed"`} {
+		if !strings.Contains(err.Error(), line) {
+			t.Errorf("CheckPlanSnapshot() error = %v, want the diff to contain %q", err, line)
+		}
+	}
+}
+
+func TestCheckPlanSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	module := NewModule("default", t.TempDir())
+	module.planHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+		return []byte(`{"resource_changes":[{"address":"azurerm_resource_group.rg","change":{"actions":["create"],"after":{"name":"rg"}}}]}`), nil
+	}
+	skipped := NewModule("skipped", t.TempDir())
+
+	checkPlanSnapshots(t, &Config{SnapshotDir: dir, UpdateSnapshots: true, ExceptionList: []string{"skipped"}}, []*Module{module, skipped})
+	if _, err := os.Stat(filepath.Join(dir, "default.json")); err != nil {
+		t.Errorf("the snapshot of default should be written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "skipped.json")); err == nil {
+		t.Error("excepted examples should not be snapshotted")
+	}
+	checkPlanSnapshots(t, &Config{SnapshotDir: dir}, []*Module{module})
+}
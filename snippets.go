@@ -0,0 +1,241 @@
+package validor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// snippetSkipMarker excludes the code block that follows it from
+// TestReadmeSnippets, for partial snippets that cannot be validated.
+const snippetSkipMarker = "<!-- validor:skip -->"
+
+var snippetLanguages = []string{"hcl", "terraform", "tf"}
+
+// Snippet is a fenced HCL code block in a README.
+type Snippet struct {
+	Path    string
+	Line    int
+	Content string
+}
+
+func (s Snippet) Name(root string) string {
+	name, err := filepath.Rel(root, s.Path)
+	if err != nil {
+		name = s.Path
+	}
+	return fmt.Sprintf("%s:%d", filepath.ToSlash(name), s.Line)
+}
+
+// ExtractSnippets returns the fenced code blocks tagged hcl, terraform or tf
+// in the README at readmePath, except those preceded by
+// <!-- validor:skip -->.
+func ExtractSnippets(readmePath string) ([]Snippet, error) {
+	file, err := os.Open(readmePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", readmePath, err)
+	}
+	defer file.Close()
+
+	var snippets []Snippet
+	// fence is set inside a code block, and current when that block is a
+	// snippet rather than one that is skipped.
+	var fence, previous string
+	var current *Snippet
+	var content []string
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				if current != nil {
+					current.Content = strings.Join(content, "\n") + "\n"
+					snippets = append(snippets, *current)
+				}
+				fence, previous, current, content = "", trimmed, nil, nil
+			} else if current != nil {
+				content = append(content, text)
+			}
+			continue
+		}
+
+		if marker := fenceMarker(trimmed); marker != "" {
+			fence = marker
+			info := strings.Fields(strings.TrimPrefix(trimmed, marker))
+			if len(info) > 0 && slices.Contains(snippetLanguages, strings.ToLower(info[0])) && previous != snippetSkipMarker {
+				current = &Snippet{Path: readmePath, Line: line + 1}
+			}
+		} else if trimmed != "" {
+			previous = trimmed
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", readmePath, err)
+	}
+	return snippets, nil
+}
+
+// fenceMarker returns the backticks or tildes that open a fenced code block
+// on line, or an empty string when it does not open one.
+func fenceMarker(line string) string {
+	for _, char := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, char))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// writeSnippets writes every snippet in the READMEs of the module at root and
+// its submodules to an example directory below dir, with the registry
+// sources of the module pointing at root.
+func writeSnippets(ctx context.Context, converter SourceConverter, root, dir string, moduleInfo ModuleInfo) ([]*Module, error) {
+	var modules []*Module
+	for _, moduleDir := range discoverDocumentedModules(root) {
+		snippets, err := ExtractSnippets(filepath.Join(moduleDir, "README.md"))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, snippet := range snippets {
+			exampleDir := filepath.Join(dir, fmt.Sprintf("snippet-%d", len(modules)+1))
+			if err := os.MkdirAll(exampleDir, 0755); err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(filepath.Join(exampleDir, "main.tf"), []byte(snippet.Content), 0644); err != nil {
+				return nil, err
+			}
+			if _, err := converter.ConvertToLocal(ctx, exampleDir, moduleInfo); err != nil {
+				return nil, fmt.Errorf("failed to convert %s to local source: %w", snippet.Name(root), err)
+			}
+			modules = append(modules, NewModule(snippet.Name(root), exampleDir))
+		}
+	}
+	return modules, nil
+}
+
+func checkSnippets(ctx context.Context, t testing.TB, modules []*Module) {
+	results := NewTestResults()
+	for _, module := range modules {
+		runSubtest(t, module.Name, false, func(t testing.TB) {
+			t = module.masker.TB(t)
+			defer module.maskLogs()()
+			defer results.AddModule(module)
+			fail := func(operation string, err error) {
+				wrappedErr := &ModuleError{ModuleName: module.Name, Operation: operation, Err: err}
+				module.Errors = append(module.Errors, wrappedErr.Error())
+				t.Error(errorText(wrappedErr.Error()))
+			}
+
+			if module.validateHook == nil {
+				if err := module.init(t); err != nil {
+					fail("terraform init", err)
+					return
+				}
+			}
+			out, err := module.validate(ctx, t)
+			if err == nil {
+				module.Diagnostics, err = ParseDiagnostics(out)
+			}
+			if err != nil {
+				fail("terraform validate", err)
+				return
+			}
+			var errs []string
+			for _, diagnostic := range module.Diagnostics {
+				if diagnostic.Severity == "error" {
+					errs = append(errs, diagnostic.String())
+				}
+			}
+			if len(errs) > 0 {
+				fail("terraform validate", fmt.Errorf("%d error(s): %s", len(errs), strings.Join(errs, "; ")))
+				return
+			}
+			if _, err := module.Plan(ctx, t); err != nil {
+				fail("terraform plan", err)
+				return
+			}
+			t.Log(successText(fmt.Sprintf("✓ %s: the snippet validates and plans", module.Name)))
+		})
+	}
+
+	modules, _ = results.GetResults()
+	PrintModuleSummary(t, modules)
+}
+
+// TestReadmeSnippets extracts the HCL code blocks from the README of the
+// module and of each submodule, points their registry sources at the local
+// module and then initializes, validates and plans each of them, so the
+// documentation does not drift from the code.
+func TestReadmeSnippets(t testing.TB, opts ...Option) {
+	ctx := context.Background()
+	config := setupConfigWithOptions(opts...)
+	root := filepath.Dir(getExamplesPath(config))
+
+	moduleInfo := config.moduleInfo()
+	if moduleInfo.Name == "" || moduleInfo.Provider == "" {
+		t.Fatal(errorText("could not determine module name and provider from repository; set them with WithModuleInfo or -repo-name-pattern"))
+		return
+	}
+	moduleInfo.Root = root
+
+	converter := NewSourceConverter(config.registryClient(), WithModuleMappings(config.ModuleMappings...))
+	modules, err := writeSnippets(ctx, converter, root, t.TempDir(), moduleInfo)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Failed to extract README snippets: %v", err)))
+		return
+	}
+	if len(modules) == 0 {
+		t.Skip("No HCL snippets found in README files")
+		return
+	}
+
+	binary, err := terraformBinary(ctx, config)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Failed to install terraform: %v", err)))
+		return
+	}
+	wrappers := config.execWrappers(t.TempDir(), root)
+	secrets, err := resolveSecrets(ctx, config.Secrets)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Failed to resolve secrets:\n%v", err)))
+		return
+	}
+	masker, err := config.secretMasker(secrets)
+	if err != nil {
+		t.Fatal(errorText(fmt.Sprintf("Invalid secret masking configuration: %v", err)))
+		return
+	}
+	for _, module := range modules {
+		if binary != "" {
+			module.Options.TerraformBinary = binary
+		}
+		if wrappers != nil {
+			if err := module.useExecWrapper(wrappers); err != nil {
+				t.Fatal(errorText(err.Error()))
+				return
+			}
+		}
+		useSecrets(module.Options, secrets)
+		module.masker = masker
+		module.addSecretVariables(config.SecretVariables)
+		module.useExtraArgs(config.ExtraArgs)
+		if config.Offline {
+			useOffline(module.Options)
+		}
+	}
+	checkSnippets(ctx, t, modules)
+}
@@ -0,0 +1,125 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractSnippets(t *testing.T) {
+	readme := "# vnet\n" +
+		"\n" +
+		"```hcl\n" +
+		"module \"vnet\" {\n" +
+		"  source = \"cloudnationhq/vnet/azure\"\n" +
+		"}\n" +
+		"```\n" +
+		"\n" +
+		"```bash\n" +
+		"```hcl inside a shell block is not a snippet\n" +
+		"```\n" +
+		"\n" +
+		"<!-- validor:skip -->\n" +
+		"```hcl\n" +
+		"vnet = { name = var.name }\n" +
+		"```\n" +
+		"\n" +
+		"~~~~ Terraform\n" +
+		"output \"id\" {\n" +
+		"  value = module.vnet.id\n" +
+		"}\n" +
+		"~~~~\n"
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"README.md": readme})
+
+	got, err := ExtractSnippets(filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Fatalf("ExtractSnippets() error = %v", err)
+	}
+	path := filepath.Join(dir, "README.md")
+	want := []Snippet{
+		{Path: path, Line: 4, Content: "module \"vnet\" {\n  source = \"cloudnationhq/vnet/azure\"\n}\n"},
+		{Path: path, Line: 19, Content: "output \"id\" {\n  value = module.vnet.id\n}\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractSnippets() = %+v, want %+v", got, want)
+	}
+	if name := got[0].Name(dir); name != "README.md:4" {
+		t.Errorf("Name() = %q, want README.md:4", name)
+	}
+}
+
+func TestWriteSnippets(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"README.md":                "```hcl\nmodule \"vnet\" {\n  source  = \"cloudnationhq/vnet/azure\"\n  version = \"~> 9.0\"\n}\n```\n",
+		"modules/subnet/README.md": "```hcl\nmodule \"subnet\" {\n  source = \"cloudnationhq/vnet/azure//modules/subnet\"\n}\n```\n",
+	})
+	dir := t.TempDir()
+
+	converter := NewSourceConverter(&mockRegistryClient{})
+	modules, err := writeSnippets(context.Background(), converter, root, dir, ModuleInfo{Namespace: "cloudnationhq", Name: "vnet", Provider: "azure", Root: root})
+	if err != nil {
+		t.Fatalf("writeSnippets() error = %v", err)
+	}
+
+	var names []string
+	for _, module := range modules {
+		names = append(names, module.Name)
+	}
+	if want := []string{"README.md:2", "modules/subnet/README.md:2"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("names = %v, want %v", names, want)
+	}
+
+	for i, wantSource := range []string{"source = \"../../", "modules/subnet\""} {
+		content, err := os.ReadFile(filepath.Join(modules[i].Path, "main.tf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(content), "cloudnationhq/vnet/azure") || !strings.Contains(string(content), wantSource) {
+			t.Errorf("snippet %d was not converted to a local source:\n%s", i, content)
+		}
+	}
+}
+
+func TestCheckSnippets(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate string
+		planErr  error
+		wantErr  string
+	}{
+		{name: "valid", validate: `{"valid": true, "diagnostics": []}`},
+		{
+			name:     "invalid",
+			validate: `{"valid": false, "diagnostics": [{"severity": "error", "summary": "Unsupported argument", "detail": "An argument named \"nme\" is not expected here."}]}`,
+			wantErr:  "terraform validate failed for module README.md:3: 1 error(s): Unsupported argument",
+		},
+		{name: "plan fails", validate: `{"valid": true}`, planErr: errors.New("missing credentials"), wantErr: "terraform plan failed for module README.md:3: missing credentials"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := NewModule("README.md:3", t.TempDir())
+			module.validateHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+				return []byte(tt.validate), nil
+			}
+			module.planHook = func(ctx context.Context, tb testing.TB, m *Module) ([]byte, error) {
+				return []byte(`{}`), tt.planErr
+			}
+			tb := &recordingTB{TB: t}
+
+			checkSnippets(context.Background(), tb, []*Module{module})
+			if tb.failed != (tt.wantErr != "") {
+				t.Errorf("failed = %v, want %v", tb.failed, tt.wantErr != "")
+			}
+			if tt.wantErr != "" && (len(module.Errors) != 1 || !strings.Contains(module.Errors[0], tt.wantErr)) {
+				t.Errorf("Errors = %v, want %q", module.Errors, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package validor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const defaultSoakInterval = 5 * time.Minute
+
+// WithSoakDuration keeps applied examples alive for d before destroying them,
+// checking them for drift and running their state assertions periodically.
+func WithSoakDuration(d time.Duration) Option {
+	return func(c *Config) { c.SoakDuration = d }
+}
+
+func WithSoakInterval(interval time.Duration) Option {
+	return func(c *Config) { c.SoakInterval = interval }
+}
+
+// Soak keeps the module applied for duration, checking it every interval and
+// at the end, and fails the module on the first check that fails. Time spent
+// in the checks comes on top of duration.
+func (m *Module) Soak(ctx context.Context, t testing.TB, duration, interval time.Duration, assertions []StateAssertion) error {
+	t.Helper()
+	defer m.startStage(StageSoak)()
+
+	if interval <= 0 {
+		interval = defaultSoakInterval
+	}
+	t.Logf("Soaking module %s for %s", m.Name, duration)
+
+	for elapsed, check := time.Duration(0), 1; elapsed < duration; check++ {
+		wait := min(interval, duration-elapsed)
+		if err := sleepContext(ctx, wait); err != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "soak", Err: err})
+		}
+		elapsed += wait
+		if err := m.soakCheck(ctx, t, assertions); err != nil {
+			err = fmt.Errorf("check %d after %s: %w", check, elapsed, err)
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "soak", Err: err})
+		}
+	}
+	return nil
+}
+
+func (m *Module) soakCheck(ctx context.Context, t testing.TB, assertions []StateAssertion) error {
+	planJSON, err := m.refreshPlan(ctx, t)
+	if err != nil {
+		return err
+	}
+	drifted, err := driftedResources(planJSON)
+	if err != nil {
+		return err
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("%d resource(s) drifted: %s", len(drifted), strings.Join(drifted, ", "))
+	}
+
+	if len(assertions) == 0 {
+		return nil
+	}
+	state, err := m.State(ctx, t)
+	if err != nil {
+		return err
+	}
+	for _, assertion := range assertions {
+		if err := assertion(t, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
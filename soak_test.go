@@ -0,0 +1,90 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModule_Soak(t *testing.T) {
+	noDrift := `{"resource_drift":[]}`
+	drifted := `{"resource_drift":[{"address":"azurerm_storage_account.sa","change":{"actions":["update"]}}]}`
+
+	tests := []struct {
+		name       string
+		plans      []string
+		assertion  StateAssertion
+		duration   time.Duration
+		interval   time.Duration
+		wantDelays []time.Duration
+		wantErr    string
+	}{
+		{
+			name:       "checks every interval and at the end",
+			plans:      []string{noDrift, noDrift, noDrift},
+			duration:   25 * time.Minute,
+			interval:   10 * time.Minute,
+			wantDelays: []time.Duration{10 * time.Minute, 10 * time.Minute, 5 * time.Minute},
+		},
+		{
+			name:       "default interval",
+			plans:      []string{noDrift, noDrift},
+			duration:   10 * time.Minute,
+			wantDelays: []time.Duration{5 * time.Minute, 5 * time.Minute},
+		},
+		{
+			name:       "stops at the first drift",
+			plans:      []string{noDrift, drifted, noDrift},
+			duration:   time.Hour,
+			interval:   20 * time.Minute,
+			wantDelays: []time.Duration{20 * time.Minute, 20 * time.Minute},
+			wantErr:    "check 2 after 40m0s: 1 resource(s) drifted: azurerm_storage_account.sa",
+		},
+		{
+			name:       "failing state assertion",
+			plans:      []string{noDrift},
+			assertion:  func(t testing.TB, s *State) error { return errors.New("endpoint gone") },
+			duration:   time.Minute,
+			wantDelays: []time.Duration{time.Minute},
+			wantErr:    "endpoint gone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := stubSleep(t)
+
+			module := newPlannedModule(t, "default")
+			refreshes := 0
+			module.refreshHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+				plan := tt.plans[refreshes]
+				refreshes++
+				return []byte(plan), nil
+			}
+			module.stateHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+				return []byte(testStateJSON), nil
+			}
+			var assertions []StateAssertion
+			if tt.assertion != nil {
+				assertions = append(assertions, tt.assertion)
+			}
+
+			err := module.Soak(context.Background(), t, tt.duration, tt.interval, assertions)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Soak() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr) || !module.ApplyFailed) {
+				t.Fatalf("Soak() error = %v, want failed module with %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(*delays, tt.wantDelays) {
+				t.Errorf("delays = %v, want %v", *delays, tt.wantDelays)
+			}
+			if _, ok := module.Durations[StageSoak]; !ok {
+				t.Error("soak stage duration should be recorded")
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+type StateAssertion func(t testing.TB, state *State) error
+
+func WithStateAssertions(example string, assertions ...StateAssertion) Option {
+	return func(c *Config) {
+		if c.StateAssertions == nil {
+			c.StateAssertions = make(map[string][]StateAssertion)
+		}
+		c.StateAssertions[example] = append(c.StateAssertions[example], assertions...)
+	}
+}
+
+type State struct {
+	Addresses []string
+	Resources map[string]map[string]any
+	JSON      []byte
+}
+
+type stateModule struct {
+	Resources []struct {
+		Address string         `json:"address"`
+		Values  map[string]any `json:"values"`
+	} `json:"resources"`
+	ChildModules []stateModule `json:"child_modules"`
+}
+
+func ParseState(stateJSON []byte) (*State, error) {
+	var show struct {
+		Values struct {
+			RootModule stateModule `json:"root_module"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(stateJSON, &show); err != nil {
+		return nil, fmt.Errorf("failed to parse state json: %w", err)
+	}
+
+	state := &State{Resources: make(map[string]map[string]any), JSON: stateJSON}
+	var walk func(module stateModule)
+	walk = func(module stateModule) {
+		for _, resource := range module.Resources {
+			state.Addresses = append(state.Addresses, resource.Address)
+			state.Resources[resource.Address] = resource.Values
+		}
+		for _, child := range module.ChildModules {
+			walk(child)
+		}
+	}
+	walk(show.Values.RootModule)
+	slices.Sort(state.Addresses)
+	return state, nil
+}
+
+// Has reports whether the state contains address, or any resource below it
+// when address is a module path such as module.network.
+func (s *State) Has(address string) bool {
+	for _, a := range s.Addresses {
+		if a == address || strings.HasPrefix(a, address+".") || strings.HasPrefix(a, address+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *State) Values(address string) map[string]any {
+	return s.Resources[address]
+}
+
+func (s *State) AssertExists(addresses ...string) error {
+	var missing []string
+	for _, address := range addresses {
+		if !s.Has(address) {
+			missing = append(missing, address)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("expected resources missing from state: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (s *State) AssertAbsent(addresses ...string) error {
+	var present []string
+	for _, address := range addresses {
+		if s.Has(address) {
+			present = append(present, address)
+		}
+	}
+	if len(present) > 0 {
+		return fmt.Errorf("unexpected resources in state: %s", strings.Join(present, ", "))
+	}
+	return nil
+}
+
+func (m *Module) StateList(t testing.TB) ([]string, error) {
+	t.Helper()
+	out, err := terraform.RunTerraformCommandAndGetStdoutE(t, m.Options, "state", "list")
+	if err != nil {
+		return nil, err
+	}
+	var addresses []string
+	for line := range strings.SplitSeq(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			addresses = append(addresses, line)
+		}
+	}
+	return addresses, nil
+}
+
+func (m *Module) State(ctx context.Context, t testing.TB) (*State, error) {
+	t.Helper()
+
+	var stateJSON []byte
+	if m.stateHook == nil && m.terragrunt == TerragruntStack {
+		return nil, fmt.Errorf("state is %w", errTerragruntStack)
+	}
+	if m.stateHook != nil {
+		out, err := m.stateHook(ctx, t, m)
+		if err != nil {
+			return nil, err
+		}
+		stateJSON = out
+	} else {
+		args := append([]string{"show", "-json", "-no-color"}, m.Options.ExtraArgs.Show...)
+		out, err := terraform.RunTerraformCommandAndGetStdoutE(t, m.Options, args...)
+		if err != nil {
+			return nil, err
+		}
+		stateJSON = []byte(out)
+	}
+	return ParseState(stateJSON)
+}
+
+func (m *Module) AssertState(ctx context.Context, t testing.TB, assertions []StateAssertion) error {
+	t.Helper()
+	defer m.startStage(StageVerify)()
+
+	state, err := m.State(ctx, t)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "state inspection", Err: err})
+	}
+	for _, assertion := range assertions {
+		if err := assertion(t, state); err != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "state assertion", Err: err})
+		}
+	}
+	return nil
+}
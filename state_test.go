@@ -0,0 +1,135 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testStateJSON = `{
+  "format_version": "1.0",
+  "values": {
+    "root_module": {
+      "resources": [
+        {"address": "azurerm_resource_group.rg", "values": {"name": "rg-test", "location": "westeurope"}}
+      ],
+      "child_modules": [
+        {
+          "address": "module.network",
+          "resources": [
+            {"address": "module.network.azurerm_virtual_network.vnet", "values": {"name": "vnet-test"}},
+            {"address": "module.network.azurerm_subnet.subnets[\"sn1\"]", "values": {"name": "sn1"}}
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func TestParseState(t *testing.T) {
+	state, err := ParseState([]byte(testStateJSON))
+	if err != nil {
+		t.Fatalf("ParseState() error = %v", err)
+	}
+
+	want := []string{
+		"azurerm_resource_group.rg",
+		"module.network.azurerm_subnet.subnets[\"sn1\"]",
+		"module.network.azurerm_virtual_network.vnet",
+	}
+	if !reflect.DeepEqual(state.Addresses, want) {
+		t.Errorf("Addresses = %v, want %v", state.Addresses, want)
+	}
+	if got := state.Values("azurerm_resource_group.rg")["location"]; got != "westeurope" {
+		t.Errorf("Values()[location] = %v, want westeurope", got)
+	}
+
+	if _, err := ParseState([]byte(`{`)); err == nil {
+		t.Error("expected error for invalid state json")
+	}
+}
+
+func TestState_Assertions(t *testing.T) {
+	state, err := ParseState([]byte(testStateJSON))
+	if err != nil {
+		t.Fatalf("ParseState() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr string
+	}{
+		{name: "exact address exists", err: state.AssertExists("azurerm_resource_group.rg")},
+		{name: "module prefix exists", err: state.AssertExists("module.network", "module.network.azurerm_subnet.subnets")},
+		{name: "missing resources", err: state.AssertExists("azurerm_resource_group.rg", "azurerm_key_vault.kv"), wantErr: "azurerm_key_vault.kv"},
+		{name: "absent resources", err: state.AssertAbsent("azurerm_key_vault.kv", "module.storage")},
+		{name: "partial address is not a match", err: state.AssertAbsent("azurerm_resource_group.r")},
+		{name: "unexpected resources", err: state.AssertAbsent("module.network"), wantErr: "module.network"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr == "" && tt.err != nil {
+				t.Fatalf("unexpected error: %v", tt.err)
+			}
+			if tt.wantErr != "" && (tt.err == nil || !strings.Contains(tt.err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want error containing %q", tt.err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestModule_AssertState(t *testing.T) {
+	newModule := func(t *testing.T) *Module {
+		module := newPlannedModule(t, "default")
+		module.stateHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+			return []byte(testStateJSON), nil
+		}
+		return module
+	}
+
+	t.Run("passing assertions", func(t *testing.T) {
+		module := newModule(t)
+		err := module.AssertState(context.Background(), t, []StateAssertion{
+			func(t testing.TB, s *State) error { return s.AssertExists("module.network") },
+			func(t testing.TB, s *State) error { return s.AssertAbsent("azurerm_key_vault.kv") },
+		})
+		if err != nil || module.ApplyFailed {
+			t.Fatalf("AssertState() error = %v", err)
+		}
+	})
+
+	t.Run("failing assertion", func(t *testing.T) {
+		module := newModule(t)
+		err := module.AssertState(context.Background(), t, []StateAssertion{
+			func(t testing.TB, s *State) error { return s.AssertExists("azurerm_key_vault.kv") },
+		})
+		if err == nil || !module.ApplyFailed || len(module.Errors) != 1 {
+			t.Fatalf("AssertState() should fail the module, got %v", err)
+		}
+		if !strings.Contains(module.Errors[0], "state assertion") {
+			t.Errorf("error should name the operation, got %q", module.Errors[0])
+		}
+	})
+
+	t.Run("state unavailable", func(t *testing.T) {
+		module := newModule(t)
+		module.stateHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+			return nil, errors.New("no state")
+		}
+		if err := module.AssertState(context.Background(), t, nil); err == nil {
+			t.Fatal("AssertState() should fail when state cannot be read")
+		}
+	})
+}
+
+func TestWithStateAssertions(t *testing.T) {
+	check := func(t testing.TB, s *State) error { return nil }
+	config := NewConfig(WithStateAssertions("default", check), WithStateAssertions("default", check, check))
+	if got := len(config.StateAssertions["default"]); got != 3 {
+		t.Errorf("StateAssertions[default] has %d assertions, want 3", got)
+	}
+}
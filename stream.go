@@ -0,0 +1,119 @@
+package validor
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamTailLines is how many lines of streamed output an error includes.
+const streamTailLines = 40
+
+// WithStreamOutput runs terraform apply and destroy through a streaming path
+// that writes their output to the module's log file as it is produced, instead
+// of buffering all of it in memory. Only the last lines are kept and
+// included in errors. Without WithLogDir the output is discarded apart from
+// those lines.
+func WithStreamOutput(enabled bool) Option {
+	return func(c *Config) { c.StreamOutput = enabled }
+}
+
+// streamWriter passes output through to dst as it arrives, keeping its last
+// lines and calling onLine for every complete line. With mask set, output is
+// passed through a line at a time so secrets are masked whole.
+type streamWriter struct {
+	mu        sync.Mutex
+	dst       io.Writer
+	onLine    func(line string)
+	mask      func(string) string
+	lines     []string
+	partial   []byte
+	lastWrite time.Time
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lastWrite = time.Now()
+	if w.mask == nil {
+		if _, err := w.dst.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.partial[:i])
+		w.partial = w.partial[i+1:]
+		if w.mask != nil {
+			line = w.mask(line)
+			if _, err := io.WriteString(w.dst, line+"\n"); err != nil {
+				return 0, err
+			}
+		}
+		if w.onLine != nil {
+			w.onLine(line)
+		}
+		w.lines = append(w.lines, line)
+		if len(w.lines) > streamTailLines {
+			w.lines = w.lines[len(w.lines)-streamTailLines:]
+		}
+	}
+	return len(p), nil
+}
+
+// tail returns the last lines written, including an unterminated one.
+func (w *streamWriter) tail() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lines := w.lines
+	if len(w.partial) > 0 {
+		lines = append(lines[:len(lines):len(lines)], w.masked(string(w.partial)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (w *streamWriter) masked(s string) string {
+	if w.mask == nil {
+		return s
+	}
+	return w.mask(s)
+}
+
+// flush writes an unterminated last line that masking held back.
+func (w *streamWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.mask != nil && len(w.partial) > 0 {
+		io.WriteString(w.dst, w.mask(string(w.partial)))
+	}
+}
+
+// idle returns how long ago output was last written, or since the writer was
+// first asked when nothing was written yet.
+func (w *streamWriter) idle() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.lastWrite.IsZero() {
+		w.lastWrite = time.Now()
+	}
+	return time.Since(w.lastWrite)
+}
+
+// retryableOutput reports whether output matches one of the retryable error
+// patterns terratest is configured with.
+func retryableOutput(retryable map[string]string, output string) bool {
+	for pattern := range retryable {
+		if matched, err := regexp.MatchString(pattern, output); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
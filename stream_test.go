@@ -0,0 +1,129 @@
+package validor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamWriter(t *testing.T) {
+	var dst strings.Builder
+	var seen []string
+	w := &streamWriter{dst: &dst, onLine: func(line string) { seen = append(seen, line) }}
+
+	for i := 1; i <= streamTailLines+5; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	fmt.Fprint(w, "partial")
+
+	if !strings.HasPrefix(dst.String(), "line 1\n") || !strings.HasSuffix(dst.String(), "partial") {
+		t.Errorf("output should pass through unchanged, got %q", dst.String())
+	}
+	if len(seen) != streamTailLines+5 {
+		t.Errorf("onLine called %d times, want %d", len(seen), streamTailLines+5)
+	}
+	tail := strings.Split(w.tail(), "\n")
+	if len(tail) != streamTailLines+1 || tail[0] != "line 6" || tail[len(tail)-1] != "partial" {
+		t.Errorf("tail = %q", tail)
+	}
+}
+
+// fakeTerraform writes a script standing in for the terraform binary. Tests
+// that use it are skipped on Windows, which cannot run shell scripts.
+func fakeTerraform(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as terraform")
+	}
+	path := filepath.Join(t.TempDir(), "terraform")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Failed to write fake terraform: %v", err)
+	}
+	return path
+}
+
+func TestModule_StreamTerraform(t *testing.T) {
+	stubSleep(t)
+
+	tests := []struct {
+		name      string
+		script    string
+		retries   int
+		wantErr   string
+		wantLog   string
+		wantCalls int
+	}{
+		{
+			name:      "streams output to the log file",
+			script:    "echo \"$@\"\necho \"Apply complete! $TF_STREAM_TEST\"\n",
+			wantLog:   "apply -input=false -auto-approve -refresh=false -no-color -lock=false\nApply complete! set\n",
+			wantCalls: 1,
+		},
+		{
+			name:      "includes the tail of the output in errors",
+			script:    "echo 'Error: quota exceeded' >&2\nexit 1\n",
+			wantErr:   "last lines of output:\nError: quota exceeded",
+			wantCalls: 1,
+		},
+		{
+			name:      "retries retryable errors",
+			script:    "echo 'Error: connection reset by peer'\nexit 1\n",
+			retries:   2,
+			wantErr:   "connection reset by peer",
+			wantCalls: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := filepath.Join(t.TempDir(), "calls")
+			binary := fakeTerraform(t, "echo x >> "+calls+"\n"+tt.script)
+
+			module := NewModule("default", t.TempDir())
+			module.Options.TerraformBinary = binary
+			module.stream = true
+			module.Options.EnvVars = map[string]string{"TF_STREAM_TEST": "set"}
+			module.Options.ExtraArgs.Apply = []string{"-refresh=false"}
+			module.Options.MaxRetries = tt.retries
+			module.Options.TimeBetweenRetries = time.Second
+			module.Options.RetryableTerraformErrors = map[string]string{"connection reset": "network"}
+			if err := module.OpenLogFile(t.TempDir()); err != nil {
+				t.Fatalf("OpenLogFile() error = %v", err)
+			}
+			path := module.logFile.Name()
+			defer module.CloseLogFile()
+
+			_, err := module.runTerraform(context.Background(), t, append([]string{"apply", "-input=false", "-auto-approve"}, module.Options.ExtraArgs.Apply...)...)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runTerraform() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("runTerraform() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if content, _ := os.ReadFile(calls); strings.Count(string(content), "x") != tt.wantCalls {
+				t.Errorf("terraform ran %d times, want %d", strings.Count(string(content), "x"), tt.wantCalls)
+			}
+			if tt.wantLog != "" {
+				if content, _ := os.ReadFile(path); !strings.Contains(string(content), tt.wantLog) {
+					t.Errorf("log file = %q, want it to contain %q", content, tt.wantLog)
+				}
+			}
+		})
+	}
+}
+
+func TestModule_ApplyStreamsOutput(t *testing.T) {
+	module := NewModule("default", t.TempDir())
+	module.Options.TerraformBinary = fakeTerraform(t, "if [ \"$1\" = apply ]; then echo 'Error: boom'; exit 1; fi\n")
+	module.stream = true
+
+	err := module.Apply(context.Background(), &recordingTB{TB: t})
+	if err == nil || !strings.Contains(err.Error(), "Error: boom") || !module.ApplyFailed {
+		t.Errorf("Apply() error = %v, want the streamed output tail", err)
+	}
+}
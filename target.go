@@ -0,0 +1,50 @@
+package validor
+
+import (
+	"fmt"
+	"strings"
+)
+
+func WithTargets(example string, addresses ...string) Option {
+	return func(c *Config) {
+		if c.Targets == nil {
+			c.Targets = make(map[string][]string)
+		}
+		c.Targets[example] = append(c.Targets[example], addresses...)
+	}
+}
+
+func WithReplace(example string, addresses ...string) Option {
+	return func(c *Config) {
+		if c.Replace == nil {
+			c.Replace = make(map[string][]string)
+		}
+		c.Replace[example] = append(c.Replace[example], addresses...)
+	}
+}
+
+// exampleAddressFlag parses repeated EXAMPLE=ADDRESS flag values into m.
+func exampleAddressFlag(m *map[string][]string) func(string) error {
+	return func(value string) error {
+		example, address, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(example) == "" || strings.TrimSpace(address) == "" {
+			return fmt.Errorf("invalid value %q, expected EXAMPLE=ADDRESS", value)
+		}
+		if *m == nil {
+			*m = make(map[string][]string)
+		}
+		example = strings.TrimSpace(example)
+		(*m)[example] = append((*m)[example], strings.TrimSpace(address))
+		return nil
+	}
+}
+
+// SetTargets limits plan, apply and destroy to the given addresses and forces
+// the replace addresses to be recreated on plan and apply.
+func (m *Module) SetTargets(targets, replace []string) {
+	m.Options.Targets = append(m.Options.Targets, targets...)
+	for _, address := range replace {
+		m.Options.ExtraArgs.Plan = append(m.Options.ExtraArgs.Plan, "-replace="+address)
+		m.Options.ExtraArgs.Apply = append(m.Options.ExtraArgs.Apply, "-replace="+address)
+	}
+}
@@ -0,0 +1,57 @@
+package validor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExampleAddressFlag(t *testing.T) {
+	var targets map[string][]string
+	parse := exampleAddressFlag(&targets)
+
+	for _, value := range []string{"default=module.network", " default = azurerm_subnet.s[\"a=b\"]", "complete=azurerm_resource_group.rg"} {
+		if err := parse(value); err != nil {
+			t.Fatalf("parse(%q) error = %v", value, err)
+		}
+	}
+	want := map[string][]string{
+		"default":  {"module.network", `azurerm_subnet.s["a=b"]`},
+		"complete": {"azurerm_resource_group.rg"},
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("targets = %v, want %v", targets, want)
+	}
+
+	for _, value := range []string{"module.network", "=module.network", "default="} {
+		if err := parse(value); err == nil {
+			t.Errorf("parse(%q) should fail", value)
+		}
+	}
+}
+
+func TestModule_SetTargets(t *testing.T) {
+	config := NewConfig(
+		WithTargets("default", "module.network"),
+		WithTargets("default", "azurerm_resource_group.rg"),
+		WithReplace("default", "azurerm_key_vault.kv"),
+	)
+
+	module := NewModule("default", "/tmp/default")
+	module.SetTargets(config.Targets[module.Name], config.Replace[module.Name])
+
+	if want := []string{"module.network", "azurerm_resource_group.rg"}; !reflect.DeepEqual(module.Options.Targets, want) {
+		t.Errorf("Targets = %v, want %v", module.Options.Targets, want)
+	}
+	if want := []string{"-replace=azurerm_key_vault.kv"}; !reflect.DeepEqual(module.Options.ExtraArgs.Apply, want) || !reflect.DeepEqual(module.Options.ExtraArgs.Plan, want) {
+		t.Errorf("ExtraArgs = %+v, want %v on plan and apply", module.Options.ExtraArgs, want)
+	}
+	if len(module.Options.ExtraArgs.Destroy) != 0 {
+		t.Errorf("replace should not be passed to destroy, got %v", module.Options.ExtraArgs.Destroy)
+	}
+
+	other := NewModule("complete", "/tmp/complete")
+	other.SetTargets(config.Targets[other.Name], config.Replace[other.Name])
+	if len(other.Options.Targets) != 0 || len(other.Options.ExtraArgs.Apply) != 0 {
+		t.Errorf("examples without targets should be unchanged, got %+v", other.Options)
+	}
+}
@@ -0,0 +1,284 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// WithTerragruntBinary sets the terragrunt binary that examples containing
+// terragrunt.hcl are run with. It defaults to terragrunt on the PATH.
+func WithTerragruntBinary(path string) Option {
+	return func(c *Config) { c.TerragruntBinary = path }
+}
+
+const (
+	terragruntConfig      = "terragrunt.hcl"
+	terragruntStackConfig = "terragrunt.stack.hcl"
+	terragruntCache       = ".terragrunt-cache"
+)
+
+// TerragruntLayout describes how an example uses terragrunt.
+type TerragruntLayout string
+
+const (
+	// TerragruntNone is an example of plain terraform files.
+	TerragruntNone TerragruntLayout = ""
+	// TerragruntUnit is an example with a terragrunt.hcl of its own, run with
+	// terragrunt apply.
+	TerragruntUnit TerragruntLayout = "unit"
+	// TerragruntStack is an example of several units in subdirectories, run
+	// with terragrunt run-all apply.
+	TerragruntStack TerragruntLayout = "stack"
+)
+
+// DetectTerragrunt returns how the example in dir uses terragrunt. Units in
+// subdirectories make it a stack, even when dir has a terragrunt.hcl of its
+// own that they include.
+func DetectTerragrunt(dir string) TerragruntLayout {
+	if fileExists(filepath.Join(dir, terragruntStackConfig)) {
+		return TerragruntStack
+	}
+	found := errors.New("found")
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == terragruntConfig && filepath.Dir(path) != dir {
+			return found
+		}
+		return nil
+	})
+	switch {
+	case err == found:
+		return TerragruntStack
+	case fileExists(filepath.Join(dir, terragruntConfig)):
+		return TerragruntUnit
+	}
+	return TerragruntNone
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// useTerragrunt runs the module with the terragrunt binary when its example
+// uses terragrunt. A terraform binary validor installed is passed on for
+// terragrunt to run.
+func (m *Module) useTerragrunt(binary, terraformBinary string) {
+	if m.terragrunt == TerragruntNone {
+		return
+	}
+	if binary == "" {
+		binary = terraform.TerragruntDefaultPath
+	}
+	m.Options.TerraformBinary = binary
+	if m.Options.EnvVars == nil {
+		m.Options.EnvVars = make(map[string]string)
+	}
+	// terratest only passes the flag to a binary named terragrunt.
+	m.Options.EnvVars["TERRAGRUNT_NON_INTERACTIVE"] = "true"
+	m.Options.EnvVars["TG_NON_INTERACTIVE"] = "true"
+	if terraformBinary != "" {
+		m.Options.EnvVars["TERRAGRUNT_TFPATH"] = terraformBinary
+		m.Options.EnvVars["TG_TF_PATH"] = terraformBinary
+	}
+}
+
+// command returns the arguments of a terraform command, run across all units
+// for a terragrunt stack.
+func (m *Module) command(args ...string) []string {
+	if m.terragrunt == TerragruntStack {
+		return append([]string{"run-all"}, args...)
+	}
+	return args
+}
+
+var errTerragruntStack = errors.New("not supported for terragrunt stacks, which have a plan and state per unit")
+
+// initTerragruntStack initializes every unit of a stack.
+func (m *Module) initTerragruntStack(t testing.TB) error {
+	args := append(m.command("init", "-input=false"), m.Options.ExtraArgs.Init...)
+	_, err := terraform.RunTerraformCommandE(t, m.Options, args...)
+	return err
+}
+
+// cleanupTerragruntCaches removes the caches terragrunt keeps in the example
+// and the directories of its units.
+func (m *Module) cleanupTerragruntCaches(ctx context.Context) error {
+	var caches []string
+	err := filepath.WalkDir(m.Options.TerraformDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == terragruntCache {
+			caches = append(caches, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to find terragrunt caches: %w", err)
+	}
+	for _, cache := range caches {
+		if err := removeAll(ctx, cache); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", cache, err)
+		}
+	}
+	return nil
+}
+
+// parseTerragruntSource splits a terragrunt registry source, such as
+// tfr:///namespace/name/provider//modules/sub?version=1.2.0, into the module
+// address with its subdirectory and the version. Other sources are not
+// registry sources.
+func parseTerragruntSource(source string) (address, version string, ok bool) {
+	rest, isRegistry := strings.CutPrefix(source, "tfr://")
+	if !isRegistry {
+		return "", "", false
+	}
+	rest, query, _ := strings.Cut(rest, "?")
+	if values, err := url.ParseQuery(query); err == nil {
+		version = values.Get("version")
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	if host != "" {
+		path = host + "/" + path
+	}
+	return registryAddress(path), version, true
+}
+
+type terragruntSource struct {
+	body      *hclwrite.Body
+	source    string
+	submodule string
+}
+
+// terragruntSources returns the terraform blocks in a terragrunt.hcl whose
+// source points at moduleSource or one of its submodules.
+func terragruntSources(body *hclwrite.Body, moduleSource string) []terragruntSource {
+	var sources []terragruntSource
+	for _, block := range body.Blocks() {
+		if block.Type() != "terraform" {
+			continue
+		}
+		attr := block.Body().GetAttribute("source")
+		if attr == nil {
+			continue
+		}
+		source, ok := attributeStringValue(attr)
+		if !ok {
+			continue
+		}
+		address, _, ok := parseTerragruntSource(source)
+		if !ok {
+			continue
+		}
+		if address == moduleSource {
+			sources = append(sources, terragruntSource{body: block.Body(), source: source})
+		} else if sub, found := strings.CutPrefix(address, moduleSource+"//"); found {
+			sources = append(sources, terragruntSource{body: block.Body(), source: source, submodule: sub})
+		}
+	}
+	return sources
+}
+
+// updateTerragruntSources points the registry sources of moduleSource in a
+// terragrunt.hcl at localSource.
+func updateTerragruntSources(body *hclwrite.Body, moduleSource, localSource string) bool {
+	sources := terragruntSources(body, moduleSource)
+	for _, source := range sources {
+		local := localSource
+		if source.submodule != "" {
+			local += strings.TrimPrefix(source.submodule, "/")
+		}
+		source.body.SetAttributeValue("source", cty.StringVal(local))
+	}
+	return len(sources) > 0
+}
+
+// setTerragruntVersion sets the version of the registry sources of
+// moduleSource in a terragrunt.hcl.
+func setTerragruntVersion(body *hclwrite.Body, moduleSource, version string) bool {
+	changed := false
+	for _, source := range terragruntSources(body, moduleSource) {
+		base, query, _ := strings.Cut(source.source, "?")
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			continue
+		}
+		values.Set("version", version)
+		source.body.SetAttributeValue("source", cty.StringVal(base+"?"+values.Encode()))
+		changed = true
+	}
+	return changed
+}
+
+// terragruntVersion returns the version of the first registry source of
+// moduleSource in a terragrunt.hcl.
+func terragruntVersion(body *hclwrite.Body, moduleSource string) string {
+	for _, source := range terragruntSources(body, moduleSource) {
+		if _, version, _ := parseTerragruntSource(source.source); version != "" {
+			return version
+		}
+	}
+	return ""
+}
+
+func isTerragruntFile(path string) bool {
+	return filepath.Base(path) == terragruntConfig
+}
+
+// exactVersion returns the version of a constraint, since terragrunt registry
+// sources only take exact versions.
+func exactVersion(constraint string) string {
+	first, _, _ := strings.Cut(constraint, ",")
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(first), "~><=!v "))
+}
+
+// revertTerragruntFile writes the original content of a converted
+// terragrunt.hcl back. Registry sources there take an exact version, so they
+// are set to the pinned version, or else the latest release.
+func (c *DefaultSourceConverter) revertTerragruntFile(ctx context.Context, restore FileRestore, versions *versionCache) error {
+	content := []byte(restore.OriginalContent)
+	moduleSource := fmt.Sprintf("%s/%s/%s", restore.Namespace, restore.ModuleName, restore.Provider)
+
+	version := c.modulePins[restore.ModuleName]
+	if version == "" {
+		version = c.pinnedVersion
+	}
+	var err error
+	if version == "" {
+		version, err = versions.get(moduleSource+"@", func() (string, error) {
+			return c.registryClient.GetLatestVersion(ctx, restore.Namespace, restore.ModuleName, restore.Provider)
+		})
+	}
+	if err == nil {
+		if parsed, diags := hclwrite.ParseConfig(content, restore.Path, hcl.InitialPos); !diags.HasErrors() &&
+			terragruntVersion(parsed.Body(), moduleSource) != "" && setTerragruntVersion(parsed.Body(), moduleSource, exactVersion(version)) {
+			content = parsed.Bytes()
+		}
+	}
+
+	if err := os.WriteFile(restore.Path, content, 0644); err != nil {
+		return fmt.Errorf("failed to restore file %s: %w", restore.Path, err)
+	}
+	return nil
+}
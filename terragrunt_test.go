@@ -0,0 +1,197 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestDetectTerragrunt(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  TerragruntLayout
+	}{
+		{name: "terraform", files: map[string]string{"main.tf": ""}, want: TerragruntNone},
+		{name: "unit", files: map[string]string{"terragrunt.hcl": ""}, want: TerragruntUnit},
+		{name: "cache is ignored", files: map[string]string{"terragrunt.hcl": "", ".terragrunt-cache/x/terragrunt.hcl": ""}, want: TerragruntUnit},
+		{name: "stack of units", files: map[string]string{"root.hcl": "", "network/terragrunt.hcl": "", "app/terragrunt.hcl": ""}, want: TerragruntStack},
+		{name: "stack including the root", files: map[string]string{"terragrunt.hcl": "", "network/terragrunt.hcl": ""}, want: TerragruntStack},
+		{name: "stack file", files: map[string]string{"terragrunt.stack.hcl": ""}, want: TerragruntStack},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			if got := DetectTerragrunt(dir); got != tt.want {
+				t.Errorf("DetectTerragrunt() = %q, want %q", got, tt.want)
+			}
+			if got := NewModule("default", dir).terragrunt; got != tt.want {
+				t.Errorf("NewModule() detected %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := DetectTerragrunt(filepath.Join(t.TempDir(), "missing")); got != TerragruntNone {
+		t.Errorf("DetectTerragrunt() of a missing directory = %q", got)
+	}
+}
+
+func TestParseTerragruntSource(t *testing.T) {
+	tests := []struct {
+		source      string
+		wantAddress string
+		wantVersion string
+		wantOK      bool
+	}{
+		{source: "tfr:///cloudnationhq/mymodule/azure?version=1.2.0", wantAddress: "cloudnationhq/mymodule/azure", wantVersion: "1.2.0", wantOK: true},
+		{source: "tfr://registry.terraform.io/cloudnationhq/mymodule/azure//modules/network?version=1.2.0", wantAddress: "cloudnationhq/mymodule/azure//modules/network", wantVersion: "1.2.0", wantOK: true},
+		{source: "tfr:///cloudnationhq/mymodule/azure", wantAddress: "cloudnationhq/mymodule/azure", wantOK: true},
+		{source: "git::https://github.com/cloudnationhq/terraform-azure-mymodule.git?ref=v1.2.0"},
+		{source: "../../"},
+	}
+
+	for _, tt := range tests {
+		address, version, ok := parseTerragruntSource(tt.source)
+		if address != tt.wantAddress || version != tt.wantVersion || ok != tt.wantOK {
+			t.Errorf("parseTerragruntSource(%q) = %q, %q, %v, want %q, %q, %v", tt.source, address, version, ok, tt.wantAddress, tt.wantVersion, tt.wantOK)
+		}
+	}
+}
+
+func TestModule_TerragruntStack(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"network/terragrunt.hcl":                 "",
+		"network/.terragrunt-cache/abc/main.tf":  "",
+		"app/terragrunt.hcl":                     "",
+		"app/.terragrunt-cache/def/.terraform/x": "",
+	})
+	calls := filepath.Join(t.TempDir(), "calls")
+	binary := fakeTerraform(t, `echo "$@" >> `+calls+"\n")
+
+	module := NewModule("stack", dir)
+	module.useTerragrunt(binary, "/opt/terraform")
+	if module.Options.TerraformBinary != binary || module.Options.EnvVars["TG_TF_PATH"] != "/opt/terraform" || module.Options.EnvVars["TG_NON_INTERACTIVE"] != "true" {
+		t.Errorf("useTerragrunt() set binary %q and env %v", module.Options.TerraformBinary, module.Options.EnvVars)
+	}
+
+	ctx := context.Background()
+	if err := module.terraformApply(ctx, t); err != nil {
+		t.Fatalf("terraformApply() error = %v", err)
+	}
+	if err := module.terraformDestroy(ctx, t); err != nil {
+		t.Fatalf("terraformDestroy() error = %v", err)
+	}
+	content, _ := os.ReadFile(calls)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "run-all apply -input=false -auto-approve") || !strings.HasPrefix(lines[1], "run-all destroy -auto-approve") {
+		t.Errorf("terragrunt ran with %q", lines)
+	}
+
+	if _, err := module.Plan(ctx, t); !errors.Is(err, errTerragruntStack) {
+		t.Errorf("Plan() error = %v, want it to be unsupported", err)
+	}
+	if err := module.verifyDestroyed(ctx, t); err != nil {
+		t.Errorf("verifyDestroyed() error = %v, want it skipped", err)
+	}
+
+	if err := module.Cleanup(ctx, t); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	for _, unit := range []string{"network", "app"} {
+		if _, err := os.Stat(filepath.Join(dir, unit, ".terragrunt-cache")); !os.IsNotExist(err) {
+			t.Errorf("the terragrunt cache of %s should be removed, got %v", unit, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, unit, "terragrunt.hcl")); err != nil {
+			t.Errorf("the config of %s should be kept: %v", unit, err)
+		}
+	}
+}
+
+func TestModule_UseTerragruntIgnoresTerraform(t *testing.T) {
+	module := NewModule("default", t.TempDir())
+	module.useTerragrunt("terragrunt", "/opt/terraform")
+	if module.Options.TerraformBinary != "terraform" || module.Options.EnvVars != nil {
+		t.Errorf("useTerragrunt() changed a terraform example: %q, %v", module.Options.TerraformBinary, module.Options.EnvVars)
+	}
+	if got := module.command("apply"); len(got) != 1 || got[0] != "apply" {
+		t.Errorf("command() = %q", got)
+	}
+}
+
+func TestDefaultSourceConverter_Terragrunt(t *testing.T) {
+	root := t.TempDir()
+	original := `terraform {
+  source = "tfr:///cloudnationhq/mymodule/azure?version=1.0.0"
+}
+
+inputs = {
+  name = "test"
+}
+`
+	submodule := `terraform {
+  source = "tfr://registry.terraform.io/cloudnationhq/mymodule/azure//modules/network?version=1.0.0"
+}
+`
+	other := `terraform {
+  source = "tfr:///cloudnationhq/other/azure?version=2.0.0"
+}
+`
+	writeFiles(t, root, map[string]string{
+		"examples/stack/app/terragrunt.hcl":     original,
+		"examples/stack/network/terragrunt.hcl": submodule,
+		"examples/stack/other/terragrunt.hcl":   other,
+	})
+	moduleInfo := ModuleInfo{Name: "mymodule", Provider: "azure", Namespace: "cloudnationhq", Root: root}
+	converter := NewSourceConverter(&mockRegistryClient{latestVersion: "1.4.0"})
+	ctx := testContext(t)
+
+	restores, err := converter.ConvertToLocal(ctx, filepath.Join(root, "examples", "stack"), moduleInfo)
+	if err != nil {
+		t.Fatalf("ConvertToLocal() error = %v", err)
+	}
+	if len(restores) != 2 {
+		t.Fatalf("ConvertToLocal() converted %d files, want 2", len(restores))
+	}
+	read := func(unit string) string {
+		content, _ := os.ReadFile(filepath.Join(root, "examples", "stack", unit, "terragrunt.hcl"))
+		return string(content)
+	}
+	if got := read("app"); !strings.Contains(got, `source = "../../../"`) || !strings.Contains(got, `name = "test"`) {
+		t.Errorf("app should point at the local module, got:\n%s", got)
+	}
+	if got := read("network"); !strings.Contains(got, `source = "../../../modules/network"`) {
+		t.Errorf("network should point at the local submodule, got:\n%s", got)
+	}
+	if got := read("other"); got != other {
+		t.Errorf("other modules should be left alone, got:\n%s", got)
+	}
+
+	if err := converter.RevertToRegistry(ctx, restores); err != nil {
+		t.Fatalf("RevertToRegistry() error = %v", err)
+	}
+	if got, want := read("app"), strings.Replace(original, "1.0.0", "1.4.0", 1); got != want {
+		t.Errorf("app after revert:\n%s\nwant:\n%s", got, want)
+	}
+	if got := read("network"); !strings.Contains(got, "azure//modules/network?version=1.4.0") {
+		t.Errorf("network after revert:\n%s", got)
+	}
+}
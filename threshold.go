@@ -0,0 +1,88 @@
+package validor
+
+import (
+	"slices"
+	"sync"
+)
+
+// WithMaxFailures skips the modules that have not started yet once more than
+// n modules failed. Zero means no limit.
+func WithMaxFailures(n int) Option {
+	return func(c *Config) { c.MaxFailures = n }
+}
+
+// WithMaxFailurePercent skips the modules that have not started yet once more
+// than percent of the run's modules failed. Zero means no limit.
+func WithMaxFailurePercent(percent float64) Option {
+	return func(c *Config) { c.MaxFailurePercent = percent }
+}
+
+// WithRerunFailed reruns a failed example up to attempts times once every
+// example made its first attempt, one example at a time. The example only
+// fails when every attempt failed and is reported as flaky when a rerun passed.
+func WithRerunFailed(attempts int) Option {
+	return func(c *Config) { c.RerunFailed = attempts }
+}
+
+type failureBudget struct {
+	mu       sync.Mutex
+	max      int
+	percent  float64
+	total    int
+	failures int
+}
+
+func newFailureBudget(config *Config, total int) *failureBudget {
+	return &failureBudget{max: config.MaxFailures, percent: config.MaxFailurePercent, total: total}
+}
+
+// record counts a finished module and reports whether it pushed the run over
+// the threshold.
+func (b *failureBudget) record(failed bool) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		return false
+	}
+	wasExceeded := b.exceededLocked()
+	b.failures++
+	return !wasExceeded && b.exceededLocked()
+}
+
+func (b *failureBudget) exceeded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exceededLocked()
+}
+
+func (b *failureBudget) exceededLocked() bool {
+	if b.max > 0 && b.failures > b.max {
+		return true
+	}
+	return b.percent > 0 && b.total > 0 && float64(b.failures)*100/float64(b.total) > b.percent
+}
+
+type queuedRerun struct {
+	index  int
+	record *Module
+}
+
+// rerunQueue collects the examples whose first attempt failed, to rerun them
+// after the first pass.
+type rerunQueue struct {
+	mu     sync.Mutex
+	queued []queuedRerun
+}
+
+func (q *rerunQueue) add(index int, record *Module) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queued = append(q.queued, queuedRerun{index: index, record: record})
+}
+
+// sorted returns the queued examples in the order of the run.
+func (q *rerunQueue) sorted() []queuedRerun {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return slices.SortedFunc(slices.Values(q.queued), func(a, b queuedRerun) int { return a.index - b.index })
+}
@@ -0,0 +1,151 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFailureBudget(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *Config
+		total        int
+		failures     int
+		wantExceeded bool
+	}{
+		{name: "no limit", config: &Config{}, total: 4, failures: 4},
+		{name: "at max failures", config: &Config{MaxFailures: 2}, total: 4, failures: 2},
+		{name: "over max failures", config: &Config{MaxFailures: 2}, total: 4, failures: 3, wantExceeded: true},
+		{name: "at percentage", config: &Config{MaxFailurePercent: 50}, total: 4, failures: 2},
+		{name: "over percentage", config: &Config{MaxFailurePercent: 50}, total: 4, failures: 3, wantExceeded: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := newFailureBudget(tt.config, tt.total)
+			budget.record(false)
+			crossed := 0
+			for range tt.failures {
+				if budget.record(true) {
+					crossed++
+				}
+			}
+			if budget.exceeded() != tt.wantExceeded {
+				t.Errorf("exceeded() = %v, want %v", budget.exceeded(), tt.wantExceeded)
+			}
+			if want := map[bool]int{true: 1}[tt.wantExceeded]; crossed != want {
+				t.Errorf("threshold crossed %d times, want %d", crossed, want)
+			}
+		})
+	}
+}
+
+func TestRunModuleTests_MaxFailures(t *testing.T) {
+	runners := []ModuleRunner{
+		&fakeRunner{name: "first", applyErr: errors.New("boom")},
+		&fakeRunner{name: "second", applyErr: errors.New("boom")},
+		&fakeRunner{name: "third"},
+	}
+
+	runner := &DefaultTestRunner{Config: NewConfig(WithExample(""), WithMaxFailures(1))}
+	runner.RunTests(context.Background(), &recordingTB{TB: t}, runners, false, nil)
+
+	if !runners[1].(*fakeRunner).applied {
+		t.Error("second module should run while the threshold is not exceeded")
+	}
+	if runners[2].(*fakeRunner).applied {
+		t.Error("third module should be skipped once the threshold is exceeded")
+	}
+	if reason := runner.Results.Skipped()["third"]; reason != "failure threshold exceeded" {
+		t.Errorf("skip reason = %q", reason)
+	}
+}
+
+type flakyRunner struct {
+	fakeRunner
+	failures int
+	applies  int
+	cleanups int
+	events   *[]string
+}
+
+func (f *flakyRunner) Apply(ctx context.Context, t testing.TB) error {
+	f.applies++
+	if f.events != nil {
+		*f.events = append(*f.events, "apply "+f.name)
+	}
+	if f.applies <= f.failures {
+		return errors.New("transient")
+	}
+	return nil
+}
+
+func (f *flakyRunner) Cleanup(ctx context.Context, t testing.TB) error {
+	f.cleanups++
+	return nil
+}
+
+func TestRunModuleTests_RerunFailed(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		reruns      int
+		wantApplies int
+		wantFailed  bool
+		wantFlaky   bool
+	}{
+		{name: "passes first time", failures: 0, reruns: 2, wantApplies: 1},
+		{name: "passes on rerun", failures: 1, reruns: 2, wantApplies: 2, wantFlaky: true},
+		{name: "fails every attempt", failures: 5, reruns: 2, wantApplies: 3, wantFailed: true},
+		{name: "reruns disabled", failures: 1, reruns: 0, wantApplies: 1, wantFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &flakyRunner{fakeRunner: fakeRunner{name: "flaky"}, failures: tt.failures}
+			recorder := &recordingTB{TB: t}
+			runner := &DefaultTestRunner{Config: NewConfig(WithExample(""), WithRerunFailed(tt.reruns))}
+			report := runner.RunTestsWithReport(context.Background(), recorder, []ModuleRunner{flaky}, false, nil)
+
+			if flaky.applies != tt.wantApplies {
+				t.Errorf("applies = %d, want %d", flaky.applies, tt.wantApplies)
+			}
+			if flaky.cleanups != tt.wantApplies-1 {
+				t.Errorf("cleanups = %d, want one before every rerun", flaky.cleanups)
+			}
+			if recorder.failed != tt.wantFailed {
+				t.Errorf("failed = %v, want %v", recorder.failed, tt.wantFailed)
+			}
+			module := report.Modules[0]
+			if module.Passed == tt.wantFailed || module.Flaky != tt.wantFlaky {
+				t.Errorf("report = %+v, want failed %v flaky %v", module, tt.wantFailed, tt.wantFlaky)
+			}
+		})
+	}
+}
+
+func TestRunModuleTests_RerunFailedAfterFirstPass(t *testing.T) {
+	var events []string
+	first := &flakyRunner{fakeRunner: fakeRunner{name: "first"}, failures: 1, events: &events}
+	second := &flakyRunner{fakeRunner: fakeRunner{name: "second"}, failures: 2, events: &events}
+	third := &flakyRunner{fakeRunner: fakeRunner{name: "third"}, events: &events}
+
+	recorder := &recordingTB{TB: t}
+	runner := &DefaultTestRunner{Config: NewConfig(WithExample(""), WithRerunFailed(2))}
+	report := runner.RunTestsWithReport(context.Background(), recorder, []ModuleRunner{second, first, third}, false, nil)
+
+	want := []string{"apply second", "apply first", "apply third", "apply second", "apply second", "apply first"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+	if recorder.failed {
+		t.Errorf("run should pass when every rerun passed, logs: %v", recorder.logs)
+	}
+	for _, module := range report.Modules {
+		if wantFlaky := module.Name != "third"; !module.Passed || module.Flaky != wantFlaky {
+			t.Errorf("report of %s = %+v, want passed, flaky %v", module.Name, module, wantFlaky)
+		}
+	}
+}
@@ -3,20 +3,30 @@ package validor
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"testing"
 )
 
 type ModuleProcessor interface {
-	Apply(ctx context.Context, t *testing.T) error
-	Destroy(ctx context.Context, t *testing.T) error
-	CleanupFiles(t *testing.T) error
+	Apply(ctx context.Context, t testing.TB) error
+	Destroy(ctx context.Context, t testing.TB) error
+	CleanupFiles(t testing.TB) error
 }
 
 type TestResults struct {
 	mu            sync.RWMutex
 	modules       []*Module
 	failedModules []*Module
+	skipped       []skippedModule
+	flaky         []FlakyExample
+	identities    []Identity
+	rootFindings  []Finding
+}
+
+type skippedModule struct {
+	name   string
+	reason string
 }
 
 func NewTestResults() *TestResults {
@@ -35,16 +45,84 @@ func (tr *TestResults) AddModule(module *Module) {
 	}
 }
 
+func (tr *TestResults) AddSkipped(name, reason string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.skipped = append(tr.skipped, skippedModule{name: name, reason: reason})
+}
+
+// Skipped returns the reason every skipped module was not run, by name.
+func (tr *TestResults) Skipped() map[string]string {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	skipped := make(map[string]string, len(tr.skipped))
+	for _, s := range tr.skipped {
+		skipped[s.name] = s.reason
+	}
+	return skipped
+}
+
+func (tr *TestResults) SetFlaky(flaky []FlakyExample) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.flaky = flaky
+}
+
+// Flaky returns the flaky examples in the run history, most flaky first.
+func (tr *TestResults) Flaky() []FlakyExample {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	return tr.flaky
+}
+
+// AddIdentity records a cloud identity the run's credentials resolved to.
+func (tr *TestResults) AddIdentity(identity Identity) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.identities = append(tr.identities, identity)
+}
+
+func (tr *TestResults) Identities() []Identity {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	return slices.Clone(tr.identities)
+}
+
 func (tr *TestResults) GetResults() ([]*Module, []*Module) {
 	tr.mu.RLock()
 	defer tr.mu.RUnlock()
 	return tr.modules, tr.failedModules
 }
 
+// SetRootFindings records what the scanner found in the module root, which
+// Findings reports under moduleRootFindings.
+func (tr *TestResults) SetRootFindings(findings []Finding) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.rootFindings = findings
+}
+
+func (tr *TestResults) Findings() map[string][]Finding {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	findings := make(map[string][]Finding)
+	if len(tr.rootFindings) > 0 {
+		findings[moduleRootFindings] = tr.rootFindings
+	}
+	for _, module := range tr.modules {
+		if len(module.Findings) > 0 {
+			findings[module.Name] = module.Findings
+		}
+	}
+	return findings
+}
+
 type ModuleInfo struct {
-	Name      string
-	Provider  string
-	Namespace string
+	Name         string
+	Provider     string
+	Namespace    string
+	Root         string
+	ExamplesPath string
 }
 
 type FileRestore struct {
@@ -59,12 +137,34 @@ type TerraformRegistryResponse struct {
 	Versions []struct {
 		Version string `json:"version"`
 	} `json:"versions"`
+	Modules []struct {
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	} `json:"modules"`
+}
+
+func (r *TerraformRegistryResponse) AllVersions() []string {
+	var versions []string
+	for _, v := range r.Versions {
+		versions = append(versions, v.Version)
+	}
+	for _, module := range r.Modules {
+		for _, v := range module.Versions {
+			versions = append(versions, v.Version)
+		}
+	}
+	return versions
 }
 
 type ModuleError struct {
 	ModuleName string
 	Operation  string
-	Err        error
+	// Stage is the stage the module failed in and Code why, both set when the
+	// error is recorded for the module.
+	Stage Stage
+	Code  ErrorCode
+	Err   error
 }
 
 func (e *ModuleError) Error() string {
@@ -74,3 +174,23 @@ func (e *ModuleError) Error() string {
 func (e *ModuleError) Unwrap() error {
 	return e.Err
 }
+
+type Stage string
+
+const (
+	StageScan     Stage = "scan"
+	StageImport   Stage = "import"
+	StageInit     Stage = "init"
+	StageValidate Stage = "validate"
+	StagePlan     Stage = "plan"
+	StageApply    Stage = "apply"
+	StageDrift    Stage = "drift"
+	StageVerify   Stage = "verify"
+	StageOutputs  Stage = "outputs"
+	StageUpgrade  Stage = "upgrade"
+	StageSoak     Stage = "soak"
+	StageDestroy  Stage = "destroy"
+	StageCleanup  Stage = "cleanup"
+)
+
+var stageOrder = []Stage{StageScan, StageImport, StageInit, StageValidate, StagePlan, StageApply, StageDrift, StageVerify, StageOutputs, StageUpgrade, StageSoak, StageDestroy, StageCleanup}
@@ -0,0 +1,238 @@
+package validor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func WithUpgradeTest(enabled bool) Option {
+	return func(c *Config) { c.UpgradeTest = enabled }
+}
+
+func WithUpgradeAllowDestroy(allow bool) Option {
+	return func(c *Config) { c.UpgradeAllowDestroy = allow }
+}
+
+// WithUpgradeFromReleases runs the upgrade test of every example from each of
+// the last n releases of the module, instead of only from the version its
+// source allows, and reports which releases upgrade cleanly.
+func WithUpgradeFromReleases(n int) Option {
+	return func(c *Config) {
+		c.UpgradeTest = n > 0 || c.UpgradeTest
+		c.UpgradeReleases = n
+	}
+}
+
+// upgradeReleasesFlag parses -upgrade-from N.
+func upgradeReleasesFlag(c *Config) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid value %q, expected a number of releases", value)
+		}
+		WithUpgradeFromReleases(n)(c)
+		return nil
+	}
+}
+
+// expandReleases replaces every Module-backed runner with one variant per
+// release among the last n of its module, named <example>@<version>. The
+// variants of an example share its working directory and run one at a time.
+func expandReleases(ctx context.Context, runners []ModuleRunner, client RegistryClient, n int, repoInfo ModuleInfo) ([]ModuleRunner, error) {
+	releases := make(map[string][]string)
+	expanded := make([]ModuleRunner, 0, len(runners))
+	for _, runner := range runners {
+		adapter, ok := runner.(moduleRunner)
+		if !ok {
+			expanded = append(expanded, runner)
+			continue
+		}
+
+		module := adapter.module
+		info := repoInfo
+		if module.info != nil {
+			info = *module.info
+		}
+		source := fmt.Sprintf("%s/%s/%s", info.Namespace, info.Name, info.Provider)
+		versions, ok := releases[source]
+		if !ok {
+			all, err := client.ListVersions(ctx, info.Namespace, info.Name, info.Provider)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list releases of %s: %w", source, err)
+			}
+			versions = all[max(0, len(all)-n):]
+			releases[source] = versions
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no releases of %s found", source)
+		}
+
+		lock := module.variantLock()
+		for _, release := range versions {
+			variant := module.variant(module.Name+"@"+release, lock)
+			variant.UpgradeFrom = release
+			expanded = append(expanded, variant.Runner())
+		}
+	}
+	return expanded, nil
+}
+
+// pinRelease points the example's registry sources of the module at the
+// release it upgrades from. The original files are restored when the test
+// finishes.
+func (m *Module) pinRelease(t testing.TB, moduleInfo ModuleInfo) error {
+	moduleSource := fmt.Sprintf("%s/%s/%s", moduleInfo.Namespace, moduleInfo.Name, moduleInfo.Provider)
+	files, err := findTerraformFiles(m.Path)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "pin release", Err: err})
+	}
+
+	pinned := false
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "pin release", Err: err})
+		}
+		parsed, diags := hclwrite.ParseConfig(content, file, hcl.InitialPos)
+		if diags.HasErrors() {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "pin release", Err: diags})
+		}
+		blocks := registryModuleBlocks(parsed.Body(), moduleSource)
+		for _, block := range blocks {
+			block.Body().SetAttributeValue("version", cty.StringVal(m.UpgradeFrom))
+		}
+		if !setTerragruntVersion(parsed.Body(), moduleSource, m.UpgradeFrom) && len(blocks) == 0 {
+			continue
+		}
+		if err := os.WriteFile(file, parsed.Bytes(), 0644); err != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "pin release", Err: err})
+		}
+		m.restoreLater(t, func() {
+			if err := os.WriteFile(file, content, 0644); err != nil {
+				t.Logf("Warning: failed to restore %s: %v", file, err)
+			}
+		})
+		pinned = true
+	}
+	if !pinned {
+		err := fmt.Errorf("no registry sources for %s found", moduleSource)
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "pin release", Err: err})
+	}
+	t.Logf("Pinned %s to release %s of %s", m.Name, m.UpgradeFrom, moduleSource)
+	return nil
+}
+
+// printUpgradePaths lists, per example, the releases that upgraded cleanly to
+// the local source.
+func printUpgradePaths(tb testLogger, modules []*Module) {
+	var upgraded []*Module
+	for _, module := range modules {
+		if module.UpgradeFrom != "" {
+			upgraded = append(upgraded, module)
+		}
+	}
+	if len(upgraded) == 0 {
+		return
+	}
+	tb.Log("Upgrade paths to the local source:")
+	for _, module := range upgraded {
+		if len(module.Errors) == 0 {
+			tb.Logf("  %s from %s: ✓ clean", module.exampleName(), module.UpgradeFrom)
+		} else {
+			tb.Logf("  %s from %s: ✗ %s", module.exampleName(), module.UpgradeFrom, module.Errors[0])
+		}
+	}
+	tb.Log("")
+}
+
+// plannedDestroys returns the addresses of resources the plan deletes,
+// including replacements.
+func plannedDestroys(planJSON []byte) ([]string, error) {
+	changes, err := planResourceChanges(planJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	for _, rc := range changes {
+		if slices.Contains(rc.Change.Actions, "delete") {
+			addresses = append(addresses, rc.Address)
+		}
+	}
+	return addresses, nil
+}
+
+// Upgrade switches an applied example from its registry source to the local
+// working tree and re-applies it. The original files are restored when the
+// test finishes, after destroy has run against the local source.
+func (m *Module) Upgrade(ctx context.Context, t testing.TB, moduleInfo ModuleInfo, allowDestroy bool) error {
+	t.Helper()
+	defer m.startStage(StageUpgrade)()
+
+	t.Logf("Upgrading Terraform module %s to local source", m.Name)
+
+	filesToRestore, err := NewSourceConverter(nil).ConvertToLocal(ctx, m.Path, moduleInfo)
+	m.restoreLater(t, func() {
+		for _, restore := range filesToRestore {
+			if err := os.WriteFile(restore.Path, []byte(restore.OriginalContent), 0644); err != nil {
+				t.Logf("Warning: failed to restore %s: %v", restore.Path, err)
+			}
+		}
+	})
+	if err == nil && len(filesToRestore) == 0 {
+		err = fmt.Errorf("no registry sources for %s/%s/%s found", moduleInfo.Namespace, moduleInfo.Name, moduleInfo.Provider)
+	}
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "upgrade", Err: err})
+	}
+
+	m.planJSON = nil
+	if m.applyHook == nil {
+		if err := m.init(t); err != nil {
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "upgrade init", Err: err})
+		}
+	}
+
+	planJSON, err := m.Plan(ctx, t)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "upgrade plan", Err: err})
+	}
+	destroys, err := plannedDestroys(planJSON)
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "upgrade plan", Err: err})
+	}
+	if len(destroys) > 0 {
+		if !allowDestroy {
+			err := fmt.Errorf("upgrading to the local source would destroy %d resource(s): %s", len(destroys), strings.Join(destroys, ", "))
+			return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "upgrade plan", Err: err})
+		}
+		t.Logf("Warning: upgrade destroys %d resource(s): %s", len(destroys), strings.Join(destroys, ", "))
+	}
+
+	if m.applyHook != nil {
+		err = m.applyHook(ctx, t, m)
+	} else {
+		err = m.terraformApply(ctx, t)
+	}
+	if err != nil {
+		return m.failApply(t, &ModuleError{ModuleName: m.Name, Operation: "upgrade apply", Err: err})
+	}
+	return nil
+}
+
+// TestUpgradePath applies every example against its published registry
+// version, then re-applies it with the local source.
+func TestUpgradePath(t testing.TB, opts ...Option) {
+	config := setupConfigWithOptions(append(opts, WithUpgradeTest(true))...)
+	modules := discoverModules(t, config)
+	runModuleTests(t, Runners(modules), true, config, nil, "registry")
+}
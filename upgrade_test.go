@@ -0,0 +1,257 @@
+package validor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPlannedDestroys(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "no changes",
+			plan: `{"resource_changes":[{"address":"azurerm_resource_group.rg","change":{"actions":["no-op"]}}]}`,
+		},
+		{
+			name: "update in place",
+			plan: `{"resource_changes":[{"address":"azurerm_resource_group.rg","change":{"actions":["update"]}}]}`,
+		},
+		{
+			name: "delete and replace",
+			plan: `{"resource_changes":[
+				{"address":"azurerm_subnet.a","change":{"actions":["delete"]}},
+				{"address":"azurerm_subnet.b","change":{"actions":["create"]}},
+				{"address":"azurerm_subnet.c","change":{"actions":["create","delete"]}}
+			]}`,
+			want: []string{"azurerm_subnet.a", "azurerm_subnet.c"},
+		},
+		{name: "invalid json", plan: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plannedDestroys([]byte(tt.plan))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("plannedDestroys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("plannedDestroys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModule_Upgrade(t *testing.T) {
+	original := "module \"test\" {\n  source  = \"cloudnationhq/mymodule/azure\"\n  version = \"~> 1.0\"\n}\n"
+	moduleInfo := ModuleInfo{Name: "mymodule", Provider: "azure", Namespace: "cloudnationhq"}
+
+	tests := []struct {
+		name         string
+		plan         string
+		allowDestroy bool
+		wantErr      string
+		wantApplies  int
+	}{
+		{
+			name:        "clean upgrade",
+			plan:        `{"resource_changes":[{"address":"azurerm_resource_group.rg","change":{"actions":["update"]}}]}`,
+			wantApplies: 1,
+		},
+		{
+			name:    "destroy blocks upgrade",
+			plan:    `{"resource_changes":[{"address":"azurerm_subnet.a","change":{"actions":["delete","create"]}}]}`,
+			wantErr: "azurerm_subnet.a",
+		},
+		{
+			name:         "destroy allowed",
+			plan:         `{"resource_changes":[{"address":"azurerm_subnet.a","change":{"actions":["delete","create"]}}]}`,
+			allowDestroy: true,
+			wantApplies:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		dir := filepath.Join(t.TempDir(), "examples", "default")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create example dir: %v", err)
+		}
+		tfFile := filepath.Join(dir, "main.tf")
+		if err := os.WriteFile(tfFile, []byte(original), 0644); err != nil {
+			t.Fatalf("Failed to create terraform file: %v", err)
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			applies := 0
+			module := NewModule("default", dir)
+			module.planJSON = []byte(`{"stale":true}`)
+			module.planHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+				content, _ := os.ReadFile(tfFile)
+				if !strings.Contains(string(content), `source = "../../"`) {
+					t.Errorf("plan should run against the local source, got: %s", content)
+				}
+				return []byte(tt.plan), nil
+			}
+			module.applyHook = func(ctx context.Context, t testing.TB, m *Module) error {
+				applies++
+				return nil
+			}
+
+			err := module.Upgrade(context.Background(), t, moduleInfo, tt.allowDestroy)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Upgrade() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Upgrade() error = %v, want error containing %q", err, tt.wantErr)
+			}
+			if applies != tt.wantApplies {
+				t.Errorf("applies = %d, want %d", applies, tt.wantApplies)
+			}
+			if _, ok := module.Durations[StageUpgrade]; !ok {
+				t.Error("upgrade stage duration should be recorded")
+			}
+		})
+
+		content, err := os.ReadFile(tfFile)
+		if err != nil {
+			t.Fatalf("Failed to read terraform file: %v", err)
+		}
+		if string(content) != original {
+			t.Errorf("%s: original sources should be restored after the test, got: %s", tt.name, content)
+		}
+	}
+}
+
+func TestModule_Upgrade_NoRegistrySource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte("module \"test\" {\n  source = \"../../\"\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create terraform file: %v", err)
+	}
+	module := newPlannedModule(t, "default")
+	module.Path = dir
+
+	err := module.Upgrade(context.Background(), t, ModuleInfo{Name: "mymodule", Provider: "azure", Namespace: "ns"}, false)
+	if err == nil || !module.ApplyFailed {
+		t.Fatalf("Upgrade() should fail when the example has no registry source, got %v", err)
+	}
+}
+
+func TestExpandReleases(t *testing.T) {
+	repoInfo := ModuleInfo{Name: "mymodule", Provider: "azure", Namespace: "cloudnationhq"}
+	client := &mockRegistryClient{versions: []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"}}
+	custom := &fakeRunner{name: "custom"}
+	runners := []ModuleRunner{NewModule("default", "/tmp/default").Runner(), custom}
+
+	expanded, err := expandReleases(context.Background(), runners, client, 2, repoInfo)
+	if err != nil {
+		t.Fatalf("expandReleases() error = %v", err)
+	}
+	if got, want := extractModuleNames(runnerModules(expanded)), []string{"default@1.2.0", "default@2.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("variants = %v, want %v", got, want)
+	}
+	if expanded[len(expanded)-1] != ModuleRunner(custom) {
+		t.Error("runners not backed by a Module should be kept as they are")
+	}
+	variants := runnerModules(expanded)
+	if variants[0].UpgradeFrom != "1.2.0" || variants[0].exampleName() != "default" || variants[0].runLock == nil || variants[0].runLock != variants[1].runLock {
+		t.Errorf("variant = %+v, want one pinned to 1.2.0 sharing the example's lock", variants[0])
+	}
+
+	if expanded, _ := expandReleases(context.Background(), runners[:1], client, 10, repoInfo); len(expanded) != 4 {
+		t.Errorf("expected every release when fewer than n are published, got %d", len(expanded))
+	}
+	if _, err := expandReleases(context.Background(), runners, &mockRegistryClient{}, 2, repoInfo); err == nil {
+		t.Error("expected an error when the module has no releases")
+	}
+	if _, err := expandReleases(context.Background(), runners, &mockRegistryClient{err: os.ErrNotExist}, 2, repoInfo); err == nil {
+		t.Error("expected an error when the releases cannot be listed")
+	}
+}
+
+func TestModule_PinRelease(t *testing.T) {
+	original := "module \"test\" {\n  source  = \"cloudnationhq/mymodule/azure\"\n  version = \"~> 1.0\"\n}\n"
+	moduleInfo := ModuleInfo{Name: "mymodule", Provider: "azure", Namespace: "cloudnationhq"}
+	dir := filepath.Join(t.TempDir(), "examples", "default")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create example dir: %v", err)
+	}
+	tfFile := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(tfFile, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create terraform file: %v", err)
+	}
+
+	module := NewModule("default", dir).variant("default@1.1.0", &sync.Mutex{})
+	module.UpgradeFrom = "1.1.0"
+	module.planHook = func(ctx context.Context, t testing.TB, m *Module) ([]byte, error) {
+		return []byte(`{"resource_changes":[]}`), nil
+	}
+	module.applyHook = func(ctx context.Context, t testing.TB, m *Module) error { return nil }
+
+	if err := module.pinRelease(t, moduleInfo); err != nil {
+		t.Fatalf("pinRelease() error = %v", err)
+	}
+	content, _ := os.ReadFile(tfFile)
+	if !strings.Contains(string(content), `version = "1.1.0"`) {
+		t.Errorf("the example should be pinned to the release, got: %s", content)
+	}
+
+	if err := module.Upgrade(context.Background(), t, moduleInfo, false); err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	module.restore()
+	if content, _ := os.ReadFile(tfFile); string(content) != original {
+		t.Errorf("the original sources should be restored, got: %s", content)
+	}
+
+	other := NewModule("other", dir)
+	other.UpgradeFrom = "1.1.0"
+	if err := other.pinRelease(t, ModuleInfo{Name: "othermodule", Provider: "azure", Namespace: "cloudnationhq"}); err == nil || !other.ApplyFailed {
+		t.Errorf("pinRelease() should fail without a registry source, got %v", err)
+	}
+}
+
+func TestPrintUpgradePaths(t *testing.T) {
+	clean := NewModule("default@1.1.0", "/tmp/default")
+	clean.example, clean.UpgradeFrom = "default", "1.1.0"
+	broken := NewModule("default@1.0.0", "/tmp/default")
+	broken.example, broken.UpgradeFrom = "default", "1.0.0"
+	broken.Errors = []string{"upgrade plan: would destroy 1 resource(s)"}
+
+	tb := &recordingTB{}
+	printUpgradePaths(tb, []*Module{broken, clean, NewModule("complete", "/tmp/complete")})
+	output := strings.Join(tb.logs, "\n")
+	for _, want := range []string{"default from 1.0.0: ✗ upgrade plan", "default from 1.1.0: ✓ clean"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "complete") {
+		t.Errorf("examples without an upgrade path should not be listed:\n%s", output)
+	}
+
+	tb = &recordingTB{}
+	printUpgradePaths(tb, []*Module{NewModule("complete", "/tmp/complete")})
+	if len(tb.logs) != 0 {
+		t.Errorf("expected no output without upgrade paths, got %v", tb.logs)
+	}
+}
+
+func TestUpgradeReleasesFlag(t *testing.T) {
+	config := NewConfig()
+	if err := upgradeReleasesFlag(config)("3"); err != nil || config.UpgradeReleases != 3 || !config.UpgradeTest {
+		t.Errorf("upgradeReleasesFlag(3) = %v, releases %d, upgrade %v", err, config.UpgradeReleases, config.UpgradeTest)
+	}
+	for _, value := range []string{"-1", "many"} {
+		if err := upgradeReleasesFlag(config)(value); err == nil {
+			t.Errorf("upgradeReleasesFlag(%q) should fail", value)
+		}
+	}
+}
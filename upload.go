@@ -0,0 +1,155 @@
+package validor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithArtifactUpload uploads the run report, the files the reporters wrote and
+// the per-module logs to target under a prefix named after the run, so
+// results outlive ephemeral CI workspaces. Target is s3://BUCKET/PREFIX,
+// gs://BUCKET/PREFIX or the https URL of an Azure storage container, with an
+// optional path and SAS token.
+func WithArtifactUpload(target string) Option {
+	return func(c *Config) { c.ArtifactUpload = target }
+}
+
+// ArtifactUploader uploads the files in dir to target under key and returns
+// the URL they can be browsed at.
+type ArtifactUploader func(ctx context.Context, dir string, target *url.URL, key string) (string, error)
+
+var (
+	artifactUploadersMu sync.RWMutex
+	artifactUploaders   = map[string]ArtifactUploader{
+		"s3":    uploadToS3,
+		"gs":    uploadToGCS,
+		"https": uploadToAzureBlob,
+	}
+)
+
+// RegisterArtifactUploader makes an uploader available for targets starting
+// with scheme://, replacing any uploader of that scheme.
+func RegisterArtifactUploader(scheme string, uploader ArtifactUploader) {
+	artifactUploadersMu.Lock()
+	defer artifactUploadersMu.Unlock()
+	artifactUploaders[scheme] = uploader
+}
+
+// uploadArtifacts uploads the artifacts of a run to config.ArtifactUpload
+// under its run ID and returns the URL they were uploaded to. They are
+// scrubbed of sensitive values first.
+func uploadArtifacts(ctx context.Context, config *Config, report *RunReport, modules []*Module, masker *Masker, started time.Time) (string, error) {
+	if config.ArtifactUpload == "" {
+		return "", nil
+	}
+	target, err := url.Parse(config.ArtifactUpload)
+	if err != nil || target.Host == "" {
+		return "", fmt.Errorf("invalid artifact upload target %q", config.ArtifactUpload)
+	}
+	artifactUploadersMu.RLock()
+	uploader, ok := artifactUploaders[target.Scheme]
+	schemes := slices.Sorted(maps.Keys(artifactUploaders))
+	artifactUploadersMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no artifact uploader for %s:// (want %s)", target.Scheme, strings.Join(schemes, ", "))
+	}
+
+	encoded, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the run report: %w", err)
+	}
+	artifacts := []artifact{{name: "report.json", content: encoded}}
+	for _, spec := range config.Reports {
+		name, reportPath, _ := strings.Cut(spec, "=")
+		if reportPath == "" {
+			reportPath = defaultReportPaths[name]
+		}
+		if reportPath == "" {
+			continue
+		}
+		if content, err := os.ReadFile(reportPath); err == nil {
+			artifacts = append(artifacts, artifact{name: "reports/" + filepath.Base(reportPath), content: content})
+		}
+	}
+	// Log files are closed by now, so they are read from their path.
+	for _, module := range modules {
+		if module.LogPath == "" {
+			continue
+		}
+		if content, err := os.ReadFile(module.LogPath); err == nil {
+			artifacts = append(artifacts, artifact{name: "logs/" + filepath.Base(module.LogPath), content: content})
+		}
+	}
+	scrubArtifacts(artifacts, masker)
+
+	dir, err := os.MkdirTemp("", "validor-artifacts-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	for _, a := range artifacts {
+		file := filepath.Join(dir, filepath.FromSlash(a.name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(file, a.content, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	key := path.Join(strings.Trim(target.Path, "/"), runID(started))
+	location, err := uploader(ctx, dir, target, key)
+	if err != nil {
+		// The query is left out as it can hold a SAS token.
+		return "", fmt.Errorf("failed to upload artifacts to %s://%s/%s: %w", target.Scheme, target.Host, key, err)
+	}
+	return location, nil
+}
+
+func uploadToS3(ctx context.Context, dir string, target *url.URL, key string) (string, error) {
+	if _, err := runHookCommand(ctx, "aws", "s3", "cp", dir, "s3://"+target.Host+"/"+key, "--recursive", "--only-show-errors"); err != nil {
+		return "", err
+	}
+	return "https://s3.console.aws.amazon.com/s3/buckets/" + target.Host + "?prefix=" + url.QueryEscape(key+"/"), nil
+}
+
+func uploadToGCS(ctx context.Context, dir string, target *url.URL, key string) (string, error) {
+	if _, err := runHookCommand(ctx, "gcloud", "storage", "rsync", dir, "gs://"+target.Host+"/"+key, "--recursive"); err != nil {
+		return "", err
+	}
+	return "https://console.cloud.google.com/storage/browser/" + target.Host + "/" + key, nil
+}
+
+// uploadToAzureBlob uploads to a container given as
+// https://ACCOUNT.blob.core.windows.net/CONTAINER[/PREFIX], with the SAS token
+// in its query or else the az CLI's login.
+func uploadToAzureBlob(ctx context.Context, dir string, target *url.URL, key string) (string, error) {
+	container, prefix, _ := strings.Cut(key, "/")
+	if prefix == "" {
+		return "", errors.New("the URL of an Azure storage container is missing the container")
+	}
+	args := []string{"storage", "blob", "upload-batch",
+		"--blob-endpoint", "https://" + target.Host,
+		"--destination", container, "--destination-path", prefix,
+		"--source", dir, "--overwrite", "--only-show-errors"}
+	if target.RawQuery != "" {
+		args = append(args, "--sas-token", target.RawQuery)
+	} else {
+		args = append(args, "--auth-mode", "login")
+	}
+	if _, err := runHookCommand(ctx, "az", args...); err != nil {
+		return "", err
+	}
+	return "https://" + target.Host + "/" + key + "/", nil
+}
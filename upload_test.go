@@ -0,0 +1,166 @@
+package validor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadArtifacts(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	t.Setenv("GITHUB_RUN_ID", "")
+
+	tests := []struct {
+		name     string
+		target   string
+		wantURL  string
+		wantArgs []string
+		wantErr  string
+	}{
+		{name: "no target"},
+		{
+			name:     "s3",
+			target:   "s3://results/validor",
+			wantURL:  "https://s3.console.aws.amazon.com/s3/buckets/results?prefix=validor%2F20260102T030405Z%2F",
+			wantArgs: []string{"aws", "s3", "cp", "DIR", "s3://results/validor/20260102T030405Z", "--recursive", "--only-show-errors"},
+		},
+		{
+			name:     "gcs without prefix",
+			target:   "gs://results",
+			wantURL:  "https://console.cloud.google.com/storage/browser/results/20260102T030405Z",
+			wantArgs: []string{"gcloud", "storage", "rsync", "DIR", "gs://results/20260102T030405Z", "--recursive"},
+		},
+		{
+			name:    "azure with login",
+			target:  "https://account.blob.core.windows.net/results/nightly",
+			wantURL: "https://account.blob.core.windows.net/results/nightly/20260102T030405Z/",
+			wantArgs: []string{"az", "storage", "blob", "upload-batch", "--blob-endpoint", "https://account.blob.core.windows.net",
+				"--destination", "results", "--destination-path", "nightly/20260102T030405Z", "--source", "DIR", "--overwrite", "--only-show-errors", "--auth-mode", "login"},
+		},
+		{
+			name:    "azure with SAS token",
+			target:  "https://account.blob.core.windows.net/results?sv=2022&sig=abc",
+			wantURL: "https://account.blob.core.windows.net/results/20260102T030405Z/",
+			wantArgs: []string{"az", "storage", "blob", "upload-batch", "--blob-endpoint", "https://account.blob.core.windows.net",
+				"--destination", "results", "--destination-path", "20260102T030405Z", "--source", "DIR", "--overwrite", "--only-show-errors", "--sas-token", "sv=2022&sig=abc"},
+		},
+		{name: "azure without container", target: "https://account.blob.core.windows.net", wantErr: "missing the container"},
+		{name: "unknown scheme", target: "ftp://results/validor", wantErr: "no artifact uploader for ftp://"},
+		{name: "no bucket", target: "results", wantErr: "invalid artifact upload target"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []string
+			var uploaded map[string]string
+			orig := runHookCommand
+			t.Cleanup(func() { runHookCommand = orig })
+			runHookCommand = func(ctx context.Context, name string, arguments ...string) ([]byte, error) {
+				args = append([]string{name}, arguments...)
+				for i, arg := range args {
+					if strings.HasPrefix(arg, os.TempDir()) && strings.Contains(arg, "validor-artifacts-") {
+						uploaded = readTree(t, arg)
+						args[i] = "DIR"
+					}
+				}
+				return nil, nil
+			}
+
+			dir := t.TempDir()
+			junitPath := filepath.Join(dir, "junit.xml")
+			writeFiles(t, dir, map[string]string{"junit.xml": "<testsuite/>"})
+			module := NewModule("module/a", t.TempDir())
+			if err := module.OpenLogFile(dir); err != nil {
+				t.Fatalf("OpenLogFile() error = %v", err)
+			}
+			if _, err := module.logFile.WriteString("password: hunter2-secret\n"); err != nil {
+				t.Fatal(err)
+			}
+			// The runner closes log files when a module is torn down, before
+			// the artifacts are uploaded.
+			if err := module.CloseLogFile(); err != nil {
+				t.Fatal(err)
+			}
+			masker, _ := NewMasker()
+			masker.Add("hunter2-secret")
+
+			config := &Config{ArtifactUpload: tt.target, Reports: []string{"junit=" + junitPath, "azure-devops"}}
+			report := NewRunReport(NewTestResults(), started, started.Add(time.Minute))
+			got, err := uploadArtifacts(context.Background(), config, report, []*Module{module}, masker, started)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("uploadArtifacts() error = %v, want %q", err, tt.wantErr)
+				}
+				if strings.Contains(err.Error(), "sig=") {
+					t.Errorf("the error should not contain the SAS token: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("uploadArtifacts() error = %v", err)
+			}
+			if got != tt.wantURL {
+				t.Errorf("uploadArtifacts() = %q, want %q", got, tt.wantURL)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("upload command = %q, want %q", args, tt.wantArgs)
+			}
+			if tt.target == "" {
+				return
+			}
+			for _, name := range []string{"report.json", "reports/junit.xml", "logs/module_a.log"} {
+				if _, ok := uploaded[name]; !ok {
+					t.Errorf("%s should have been uploaded, got %v", name, uploaded)
+				}
+			}
+			if strings.Contains(uploaded["logs/module_a.log"], "hunter2-secret") {
+				t.Error("uploaded logs should be scrubbed")
+			}
+		})
+	}
+}
+
+func TestUploadArtifacts_Failure(t *testing.T) {
+	orig := runHookCommand
+	t.Cleanup(func() { runHookCommand = orig })
+	runHookCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, errors.New("AuthorizationFailure")
+	}
+
+	config := &Config{ArtifactUpload: "https://account.blob.core.windows.net/results?sig=secret"}
+	report := NewRunReport(NewTestResults(), time.Now(), time.Now())
+	_, err := uploadArtifacts(context.Background(), config, report, nil, nil, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "AuthorizationFailure") {
+		t.Fatalf("uploadArtifacts() error = %v, want the upload failure", err)
+	}
+	if strings.Contains(err.Error(), "sig=secret") {
+		t.Errorf("the error should not contain the SAS token: %v", err)
+	}
+}
+
+// readTree returns the files under dir by their slash-separated relative path.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
@@ -1,10 +1,29 @@
 package validor
 
 import (
-	"github.com/fatih/color"
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
 )
 
-var redError = color.New(color.FgHiRed, color.Bold).SprintFunc()
+// Windows reports these while another process, such as a virus scanner or a
+// provider plugin that is still exiting, holds a file open.
+const (
+	windowsAccessDenied     syscall.Errno = 5
+	windowsSharingViolation syscall.Errno = 32
+	windowsLockViolation    syscall.Errno = 33
+)
+
+const removeAttempts = 5
+
+var (
+	goos             = runtime.GOOS
+	removePath       = os.RemoveAll
+	removeRetryDelay = 200 * time.Millisecond
+)
 
 func BoolToStr(cond bool, yes, no string) string {
 	if cond {
@@ -12,3 +31,36 @@ func BoolToStr(cond bool, yes, no string) string {
 	}
 	return no
 }
+
+var sleepContext = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// removeAll removes path like os.RemoveAll, retrying with a growing delay
+// while Windows reports the file as in use.
+func removeAll(ctx context.Context, path string) error {
+	for attempt := 1; ; attempt++ {
+		err := removePath(path)
+		if err == nil || attempt == removeAttempts || !isSharingViolation(err) {
+			return err
+		}
+		if err := sleepContext(ctx, time.Duration(attempt)*removeRetryDelay); err != nil {
+			return err
+		}
+	}
+}
+
+func isSharingViolation(err error) bool {
+	var errno syscall.Errno
+	if goos != "windows" || !errors.As(err, &errno) {
+		return false
+	}
+	return errno == windowsAccessDenied || errno == windowsSharingViolation || errno == windowsLockViolation
+}
@@ -1,6 +1,14 @@
 package validor
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
 
 func TestBoolToStr(t *testing.T) {
 	tests := []struct {
@@ -49,3 +57,71 @@ func TestBoolToStr(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveAll(t *testing.T) {
+	sharingViolation := &fs.PathError{Op: "remove", Path: "terraform-provider.exe", Err: windowsSharingViolation}
+
+	tests := []struct {
+		name         string
+		goos         string
+		errs         []error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "removed", goos: "windows", errs: []error{nil}, wantAttempts: 1},
+		{name: "retried while in use", goos: "windows", errs: []error{sharingViolation, sharingViolation, nil}, wantAttempts: 3},
+		{name: "gives up", goos: "windows", errs: []error{sharingViolation, sharingViolation, sharingViolation, sharingViolation, sharingViolation}, wantAttempts: removeAttempts, wantErr: true},
+		{name: "other errors are not retried", goos: "windows", errs: []error{errors.New("boom")}, wantAttempts: 1, wantErr: true},
+		{name: "no retries outside windows", goos: "linux", errs: []error{&fs.PathError{Err: syscall.Errno(32)}}, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := stubSleep(t)
+			origGOOS, origRemove := goos, removePath
+			t.Cleanup(func() { goos, removePath = origGOOS, origRemove })
+			goos = tt.goos
+
+			attempts := 0
+			removePath = func(path string) error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			}
+
+			err := removeAll(context.Background(), "dir")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("removeAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if len(*delays) > 0 && (*delays)[0] != removeRetryDelay {
+				t.Errorf("first delay = %s, want %s", (*delays)[0], removeRetryDelay)
+			}
+		})
+	}
+}
+
+func TestModule_Cleanup_LiteralDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "example[1]")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{".terraform", "terraform.tfstate", ".terraform.lock.hcl", "main.tf"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := NewModule("example", dir).Cleanup(context.Background(), t); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "main.tf" {
+		t.Errorf("remaining files = %v, want only main.tf", entries)
+	}
+}
@@ -2,28 +2,164 @@
 package validor
 
 import (
+	"cmp"
 	"context"
 	"flag"
 	"fmt"
+	"maps"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
-var globalConfig *Config
+var (
+	globalConfig   *Config
+	globalConfigMu sync.Mutex
+)
 
 type Config struct {
-	SkipDestroy   bool
-	Exception     string
-	Example       string
-	Local         bool
-	ExceptionList []string
-	Namespace     string
-	ExamplesPath  string
+	SkipDestroy           bool
+	Exception             string
+	Example               string
+	Local                 bool
+	ExceptionList         []string
+	Namespace             string
+	ModuleInfo            ModuleInfo
+	RepoNamePattern       *regexp.Regexp
+	ExamplesPath          string
+	LogDir                string
+	StreamOutput          bool
+	CancelGrace           time.Duration
+	HangTimeout           time.Duration
+	HangRetry             bool
+	CleanupPatterns       []string
+	PreserveLockFile      bool
+	CheckLockPlatforms    bool
+	LockPlatforms         []string
+	FixLockFiles          bool
+	SkipCleanup           bool
+	CleanupWithoutDestroy bool
+	DestroyRetries        int
+	DestroyBackoff        time.Duration
+	DestroyTimeout        time.Duration
+	AzurePurge            bool
+	PreserveOnFailure     string
+	AlwaysInit            bool
+	Diagnostics           bool
+	FailOnWarnings        bool
+	NativeTests           bool
+	Progress              bool
+	Observers             []Observer
+	Stages                []StagePlugin
+	DisabledStages        []Stage
+	MetricsFile           string
+	PushgatewayURL        string
+	MetricsJob            string
+
+	NotificationURL    string
+	NotificationFormat NotificationFormat
+	GitHubComment      bool
+	CostEstimation     bool
+	CostThreshold      float64
+	PlanSnapshots      bool
+	SnapshotDir        string
+	UpdateSnapshots    bool
+	Scanner            Scanner
+	ScannerName        string
+	ScanSeverity       Severity
+	PolicyDir          string
+	PolicyQuery        string
+	CoverageThreshold  float64
+	Modules            []ModuleInfo
+	Monorepo           bool
+	DryRun             bool
+	PlanDiff           bool
+	PinnedVersion      string
+	ConstraintStyle    ConstraintStyle
+	ModuleMappings     []ModuleMapping
+	ModulePins         map[string]string
+	ProviderOverrides  map[string]string
+	ProviderMirror     string
+	Offline            bool
+	DisablePluginCache bool
+	PluginCacheDir     string
+	PrewarmPluginCache bool
+
+	UpgradeTest         bool
+	UpgradeAllowDestroy bool
+	UpgradeReleases     int
+	StateAssertions     map[string][]StateAssertion
+	ImportScenarios     map[string]ImportScenario
+	PreDestroyHooks     map[string][]PreDestroyHook
+	Targets             map[string][]string
+	Replace             map[string][]string
+	ExtraArgs           map[string][]string
+	TerragruntBinary    string
+	DriftCheck          bool
+	DriftWait           time.Duration
+	Matrix              map[string][]string
+	Include             []string
+	Exclude             []string
+	MaxFailures         int
+	MaxFailurePercent   float64
+	RerunFailed         int
+	BenchmarkIterations int
+	Preflight           bool
+	PreflightChecks     []PreflightCheck
+	TerraformVersion    string
+	RequiredEnv         []string
+	Credentials         []string
+	InstallTerraform    bool
+	TerraformInstallDir string
+	TerraformSigningKey string
+	HistoryFile         string
+	QuarantineRate      float64
+	PauseOnFailure      bool
+	PauseTimeout        time.Duration
+	PhasedDestroy       bool
+	SoakDuration        time.Duration
+	SoakInterval        time.Duration
+	DestroyManifest     string
+	Reports             []string
+	CIAdapter           string
+	OutputContract      bool
+	NullableOutputs     []string
+	SensitiveOutputs    bool
+	MaskSecrets         bool
+	SecretPatterns      []string
+	SecretVariables     []string
+	ExpectedFailures    map[string]ExpectedOutcome
+	MaxPlannedResources map[string]int
+	ForbiddenActions    map[string][]string
+	BudgetRules         string
+	RunLock             string
+	RunLockTimeout      time.Duration
+	RunLockTTL          time.Duration
+	CredentialSets      []CredentialSet
+	CredentialPool      string
+	ExecWrapper         []string
+	ContainerImage      string
+	ContainerRuntime    string
+	Secrets             map[string]string
+	ArtifactUpload      string
+	Weights             map[string]int
+	Shuffle             bool
+	ShuffleSeed         int64
+	ShardIndex          int
+	ShardTotal          int
+	RegistryOptions     []RegistryOption
+	Color               *bool
+	ErrorColor          ColorFunc
+	SuccessColor        ColorFunc
 }
 
 type Option func(*Config)
@@ -32,6 +168,32 @@ func WithSkipDestroy(skip bool) Option {
 	return func(c *Config) { c.SkipDestroy = skip }
 }
 
+// WithSkipCleanup keeps the state, lock file and terraform directory of an
+// example after it is destroyed, for instance to inspect its outputs.
+func WithSkipCleanup(skip bool) Option {
+	return func(c *Config) { c.SkipCleanup = skip }
+}
+
+// WithCleanupWithoutDestroy cleans up examples whose destroy is skipped. Their
+// infrastructure is left running, so this is only safe when their state is
+// kept in a remote backend.
+func WithCleanupWithoutDestroy(enabled bool) Option {
+	return func(c *Config) { c.CleanupWithoutDestroy = enabled }
+}
+
+// WithCleanupPatterns removes files matching these globs from an example after
+// destroy, in addition to the terraform directory, state and lock file, for
+// artifacts like crash.log, plan files or override files.
+func WithCleanupPatterns(patterns []string) Option {
+	return func(c *Config) { c.CleanupPatterns = patterns }
+}
+
+// WithPreserveLockFile keeps .terraform.lock.hcl when cleaning up an example,
+// for repositories that commit it.
+func WithPreserveLockFile(preserve bool) Option {
+	return func(c *Config) { c.PreserveLockFile = preserve }
+}
+
 func WithException(exception string) Option {
 	return func(c *Config) {
 		c.Exception = exception
@@ -47,29 +209,232 @@ func WithLocal(local bool) Option {
 	return func(c *Config) { c.Local = local }
 }
 
+// WithModuleInfo sets the name and provider of the module under test instead
+// of inferring them from the repository name. Fields left empty are still
+// inferred.
+func WithModuleInfo(info ModuleInfo) Option {
+	return func(c *Config) { c.ModuleInfo = info }
+}
+
+// WithRepoNamePattern infers the module name and provider from repository
+// names matching pattern, for repositories not named
+// terraform-<provider>-<name>. The pattern uses the named groups name and
+// provider, or otherwise its first group as the provider and its second as
+// the name.
+func WithRepoNamePattern(pattern *regexp.Regexp) Option {
+	return func(c *Config) { c.RepoNamePattern = pattern }
+}
+
 func WithExamplesPath(path string) Option {
 	return func(c *Config) { c.ExamplesPath = path }
 }
 
+func WithPinnedVersion(version string) Option {
+	return func(c *Config) { c.PinnedVersion = version }
+}
+
+func WithModulePinnedVersion(module, version string) Option {
+	return func(c *Config) {
+		if c.ModulePins == nil {
+			c.ModulePins = make(map[string]string)
+		}
+		c.ModulePins[module] = version
+	}
+}
+
+// WithModuleMapping converts or pins another module the examples call besides
+// the module under test when they run with the local source.
+func WithModuleMapping(mapping ModuleMapping) Option {
+	return func(c *Config) { c.ModuleMappings = append(c.ModuleMappings, mapping) }
+}
+
+// WithVersionConstraintStyle sets how the version constraints of examples are
+// written back after local tests; see ConstraintStyle.
+func WithVersionConstraintStyle(style ConstraintStyle) Option {
+	return func(c *Config) { c.ConstraintStyle = style }
+}
+
+// WithRegistryOptions configures the registry client that looks up the
+// versions of registry sources, e.g. with WithRegistryBaseURL or
+// WithCACertFile.
+func WithRegistryOptions(opts ...RegistryOption) Option {
+	return func(c *Config) { c.RegistryOptions = append(c.RegistryOptions, opts...) }
+}
+
+func WithDryRun(dryRun bool) Option {
+	return func(c *Config) { c.DryRun = dryRun }
+}
+
+// WithPlanDiff makes local tests plan every example with its registry and its
+// local source and report the differences instead of applying it.
+func WithPlanDiff(enabled bool) Option {
+	return func(c *Config) { c.PlanDiff = enabled }
+}
+
 func NewConfig(opts ...Option) *Config {
-	config := &Config{}
+	config := &Config{
+		Namespace:          "cloudnationhq",
+		MetricsJob:         "validor",
+		NotificationFormat: NotificationJSON,
+		ScanSeverity:       SeverityHigh,
+		PolicyQuery:        defaultPolicyQuery,
+	}
 	for _, opt := range opts {
 		opt(config)
 	}
 	return config
 }
 
-func init() {
-	globalConfig = &Config{}
-	flag.BoolVar(&globalConfig.SkipDestroy, "skip-destroy", false, "Skip running terraform destroy after apply")
-	flag.StringVar(&globalConfig.Exception, "exception", "", "Comma-separated list of examples to exclude")
-	flag.StringVar(&globalConfig.Example, "example", "", "Specific example(s) to test (comma-separated)")
-	flag.BoolVar(&globalConfig.Local, "local", false, "Use local source for testing")
-	flag.StringVar(&globalConfig.Namespace, "namespace", "cloudnationhq", "Terraform registry namespace")
-	flag.StringVar(&globalConfig.ExamplesPath, "examples-path", "", "Path to examples directory (defaults to '../examples')")
+// RegisterFlags binds the command-line flags to the shared config returned by
+// GetConfig. Call it from an init function or TestMain before flags are
+// parsed; registering on the same FlagSet twice is a no-op.
+func RegisterFlags(fs *flag.FlagSet) {
+	if fs.Lookup("example") != nil {
+		return
+	}
+	c := GetConfig()
+	fs.BoolVar(&c.SkipDestroy, "skip-destroy", c.SkipDestroy, "Skip running terraform destroy after apply")
+	fs.StringVar(&c.Exception, "exception", c.Exception, "Comma-separated list of examples to exclude")
+	fs.StringVar(&c.Example, "example", c.Example, "Specific example(s) to test (comma-separated)")
+	fs.BoolVar(&c.Local, "local", c.Local, "Use local source for testing")
+	fs.StringVar(&c.Namespace, "namespace", c.Namespace, "Terraform registry namespace")
+	fs.Func("repo-name-pattern", "Regular expression to infer the module name and provider from the repository name, using the named groups name and provider", repoNamePatternFlag(c))
+	fs.StringVar(&c.ExamplesPath, "examples-path", c.ExamplesPath, "Path to examples directory (defaults to '../examples')")
+	fs.BoolVar(&c.Progress, "progress", c.Progress, "Show live per-module progress while tests run")
+	fs.StringVar(&c.LogDir, "log-dir", c.LogDir, "Directory to write per-module terraform logs to")
+	fs.BoolVar(&c.AlwaysInit, "always-init", c.AlwaysInit, "Run terraform init before every stage and retry, even when nothing it depends on changed")
+	fs.BoolVar(&c.Diagnostics, "diagnostics", c.Diagnostics, "Validate each example after init and list deprecation warnings in the summary")
+	fs.BoolVar(&c.FailOnWarnings, "fail-on-warnings", c.FailOnWarnings, "Fail examples for which terraform validate reports warnings (implies -diagnostics)")
+	fs.BoolVar(&c.NativeTests, "native-tests", c.NativeTests, "Also run terraform test for modules that have tests/*.tftest.hcl files")
+	fs.DurationVar(&c.CancelGrace, "cancel-grace", c.CancelGrace, "How long a cancelled terraform apply or destroy gets to stop before it is killed (default 30s)")
+	fs.DurationVar(&c.HangTimeout, "hang-timeout", c.HangTimeout, "Warn and snapshot processes when terraform apply or destroy produces no output for this long (0 to disable)")
+	fs.BoolVar(&c.HangRetry, "hang-retry", c.HangRetry, "Stop and retry a terraform apply or destroy that hung per -hang-timeout")
+	fs.BoolVar(&c.SkipCleanup, "skip-cleanup", c.SkipCleanup, "Keep the state, lock file and terraform directory of examples after destroy")
+	fs.BoolVar(&c.CleanupWithoutDestroy, "cleanup-without-destroy", c.CleanupWithoutDestroy, "Clean up examples whose destroy is skipped (only safe with remote state)")
+	fs.IntVar(&c.DestroyRetries, "destroy-retries", c.DestroyRetries, "Retry a failed destroy this many times, without refreshing")
+	fs.DurationVar(&c.DestroyBackoff, "destroy-backoff", c.DestroyBackoff, "How long to wait before the first destroy retry, doubling for each next one")
+	fs.DurationVar(&c.DestroyTimeout, "destroy-timeout", c.DestroyTimeout, "Limit how long destroying an example may take, including retries (0 for no limit)")
+	fs.BoolVar(&c.AzurePurge, "azure-purge", c.AzurePurge, "Purge soft-deleted Key Vaults, API Management instances and Cognitive Services accounts after destroy")
+	fs.Func("cleanup-patterns", "Additional globs of files to remove from an example after destroy (comma-separated)", listFlag(&c.CleanupPatterns))
+	fs.BoolVar(&c.PreserveLockFile, "preserve-lock-file", c.PreserveLockFile, "Keep .terraform.lock.hcl when cleaning up an example")
+	fs.BoolVar(&c.CheckLockPlatforms, "check-lock-platforms", c.CheckLockPlatforms, "Fail examples whose committed lock file lacks provider hashes for -lock-platforms")
+	fs.Func("lock-platforms", "Platforms lock files must cover (comma-separated, default linux_amd64,darwin_arm64,windows_amd64)", listFlag(&c.LockPlatforms))
+	fs.BoolVar(&c.FixLockFiles, "fix-lock-files", c.FixLockFiles, "Add missing platform hashes to committed lock files instead of failing (implies -check-lock-platforms)")
+	fs.StringVar(&c.PreserveOnFailure, "preserve-on-failure", c.PreserveOnFailure, "Copy the state, plan JSON and log of failed examples to this directory before cleanup")
+	fs.BoolVar(&c.StreamOutput, "stream-output", c.StreamOutput, "Stream terraform apply and destroy output to the log files instead of buffering it in memory")
+	fs.StringVar(&c.MetricsFile, "metrics-file", c.MetricsFile, "Write run metrics in OpenMetrics format to this file")
+	fs.StringVar(&c.PushgatewayURL, "pushgateway-url", c.PushgatewayURL, "Push run metrics to this Prometheus Pushgateway")
+	fs.StringVar(&c.MetricsJob, "metrics-job", c.MetricsJob, "Job name used when pushing metrics")
+	fs.StringVar(&c.NotificationURL, "notify-url", c.NotificationURL, "Webhook URL to post a run summary to")
+	fs.StringVar((*string)(&c.NotificationFormat), "notify-format", string(c.NotificationFormat), "Notification payload format (json, slack, teams)")
+	fs.BoolVar(&c.CostEstimation, "cost-estimation", c.CostEstimation, "Estimate monthly cost of each example with infracost before apply")
+	fs.Float64Var(&c.CostThreshold, "cost-threshold", c.CostThreshold, "Fail modules whose estimated monthly cost exceeds this amount")
+	fs.BoolVar(&c.PlanSnapshots, "plan-snapshots", c.PlanSnapshots, "Compare each example's normalized plan to its golden file before apply")
+	fs.StringVar(&c.SnapshotDir, "snapshot-dir", c.SnapshotDir, "Directory of the plan golden files (default testdata/snapshots)")
+	fs.BoolVar(&c.UpdateSnapshots, "update-snapshots", c.UpdateSnapshots, "Regenerate the plan golden files instead of comparing them")
+	fs.StringVar(&c.ScannerName, "scanner", c.ScannerName, "Static security scanner to run on each example (trivy, tfsec, checkov)")
+	fs.StringVar((*string)(&c.ScanSeverity), "scan-severity", string(c.ScanSeverity), "Minimum finding severity that fails a module")
+	fs.StringVar(&c.PolicyDir, "policy-dir", c.PolicyDir, "Directory of Rego policies evaluated against each example's plan")
+	fs.StringVar(&c.PolicyQuery, "policy-query", c.PolicyQuery, "Rego query that returns policy violations")
+	fs.Float64Var(&c.CoverageThreshold, "coverage-threshold", c.CoverageThreshold, "Minimum percentage of module variables that examples must set")
+	fs.BoolVar(&c.GitHubComment, "github-comment", c.GitHubComment, "Post or update a pull request comment with the module results")
+	fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, "Show the local source rewrites as a diff instead of running local tests")
+	fs.BoolVar(&c.PlanDiff, "plan-diff", c.PlanDiff, "Diff the plans of the registry and the local source instead of running local tests")
+	fs.StringVar(&c.PinnedVersion, "pin-version", c.PinnedVersion, "Exact version written back to examples after local tests (VERSION or module=VERSION,...)")
+	fs.Func("module-map", "Convert or pin another module in local tests: NAMESPACE/NAME/PROVIDER=PATH or =VERSION (repeatable)", moduleMappingFlag(c))
+	fs.Func("constraint-style", "Version constraint written back to examples after local tests: preserve, pessimistic, minimum or exact", constraintStyleFlag(c))
+	fs.Func("provider-override", "Use a locally built provider binary (SOURCE=DIR, comma-separated or repeated)", func(value string) error {
+		if c.ProviderOverrides == nil {
+			c.ProviderOverrides = make(map[string]string)
+		}
+		return parseProviderOverrides(value, c.ProviderOverrides)
+	})
+	fs.StringVar(&c.ProviderMirror, "provider-mirror", c.ProviderMirror, "Install providers from this directory, written by terraform providers mirror, instead of their registries")
+	fs.BoolVar(&c.Offline, "offline", c.Offline, "Run without internet access: skip registry lookups, update checks and terraform downloads")
+	fs.BoolVar(&c.DisablePluginCache, "disable-plugin-cache", c.DisablePluginCache, "Do not share a provider plugin cache between modules")
+	fs.StringVar(&c.PluginCacheDir, "plugin-cache-dir", c.PluginCacheDir, "Provider plugin cache directory (defaults to the user cache directory)")
+	fs.BoolVar(&c.PrewarmPluginCache, "plugin-cache-prewarm", c.PrewarmPluginCache, "Install all providers into the plugin cache before modules run")
+	fs.BoolVar(&c.UpgradeTest, "upgrade", c.UpgradeTest, "Apply each example from the registry first, then re-apply it with the local source")
+	fs.BoolVar(&c.UpgradeAllowDestroy, "upgrade-allow-destroy", c.UpgradeAllowDestroy, "Allow the upgrade plan to destroy or replace resources")
+	fs.Func("upgrade-from", "Run the upgrade test from each of the last N releases of the module", upgradeReleasesFlag(c))
+	fs.Func("target", "Limit an example to a resource address (EXAMPLE=ADDRESS, repeatable)", exampleAddressFlag(&c.Targets))
+	fs.Func("replace", "Force an example's resource to be recreated (EXAMPLE=ADDRESS, repeatable)", exampleAddressFlag(&c.Replace))
+	fs.StringVar(&c.TerragruntBinary, "terragrunt-binary", c.TerragruntBinary, "Terragrunt binary to run examples containing terragrunt.hcl with (default terragrunt)")
+	fs.Func("extra-args", "Pass arguments to a terraform command: COMMAND=ARGS, such as apply=-parallelism=5 (repeatable)", extraArgsFlag(&c.ExtraArgs))
+	fs.BoolVar(&c.DriftCheck, "drift-check", c.DriftCheck, "Fail modules whose resources drift in a refresh-only plan after apply")
+	fs.DurationVar(&c.DriftWait, "drift-wait", c.DriftWait, "Time to wait after apply before checking for drift")
+	fs.Func("matrix", "Run every example once per value combination (KEY=VALUE1,VALUE2, repeatable)", matrixFlag(&c.Matrix))
+	fs.Func("include", "Only test examples whose name matches one of these globs (comma-separated)", patternListFlag(&c.Include))
+	fs.Func("exclude", "Skip examples whose name matches one of these globs (comma-separated)", patternListFlag(&c.Exclude))
+	fs.IntVar(&c.MaxFailures, "max-failures", c.MaxFailures, "Skip the remaining examples once more than this many failed (0 for no limit)")
+	fs.Float64Var(&c.MaxFailurePercent, "max-failure-percent", c.MaxFailurePercent, "Skip the remaining examples once more than this percentage failed (0 for no limit)")
+	fs.IntVar(&c.RerunFailed, "rerun-failed", c.RerunFailed, "Rerun a failed example up to this many times and only fail it when every attempt fails")
+	fs.IntVar(&c.BenchmarkIterations, "benchmark", c.BenchmarkIterations, "Apply and destroy each example this many times and report min, mean and p95 stage durations")
+	fs.BoolVar(&c.Preflight, "preflight", c.Preflight, "Check that terraform is installed and every example exists before running any")
+	fs.StringVar(&c.TerraformVersion, "terraform-version", c.TerraformVersion, "Version constraint the terraform binary must satisfy")
+	fs.Func("require-env", "Environment variables that must be set before running (comma-separated)", listFlag(&c.RequiredEnv))
+	fs.Func("check-credentials", "Verify cloud credentials before running (azure, aws, gcp; comma-separated)", listFlag(&c.Credentials))
+	fs.BoolVar(&c.InstallTerraform, "install-terraform", c.InstallTerraform, "Download terraform when no binary on PATH satisfies -terraform-version")
+	fs.StringVar(&c.TerraformInstallDir, "terraform-install-dir", c.TerraformInstallDir, "Directory downloaded terraform versions are kept in (defaults to the user cache directory)")
+	fs.StringVar(&c.TerraformSigningKey, "terraform-signing-key", c.TerraformSigningKey, "Armored public key to verify downloaded terraform checksums with instead of HashiCorp's release key")
+	fs.StringVar(&c.HistoryFile, "history-file", c.HistoryFile, "Append each run's outcomes to this JSON lines file and report flaky examples")
+	fs.Float64Var(&c.QuarantineRate, "quarantine-rate", c.QuarantineRate, "Skip examples whose outcome changed in at least this share (0-1) of their recent runs")
+	fs.BoolVar(&c.PauseOnFailure, "pause-on-failure", c.PauseOnFailure, "Wait for enter before destroying a failed example when run from a terminal")
+	fs.DurationVar(&c.PauseTimeout, "pause-timeout", c.PauseTimeout, "How long to wait before destroying a paused example (defaults to 30m)")
+	fs.BoolVar(&c.PhasedDestroy, "phased-destroy", c.PhasedDestroy, "Apply every example before destroying any of them, in reverse order")
+	fs.DurationVar(&c.SoakDuration, "soak-duration", c.SoakDuration, "Keep each applied example alive this long, checking it periodically, before destroying it")
+	fs.DurationVar(&c.SoakInterval, "soak-interval", c.SoakInterval, "Time between drift checks and state assertions while soaking (defaults to 5m)")
+	fs.StringVar(&c.DestroyManifest, "destroy-manifest", c.DestroyManifest, "File that -skip-destroy records applied examples in for TestDestroyAll (defaults to validor-destroy-manifest.json)")
+	fs.Func("report", "Write the results with these reporters (junit, gitlab, azure-devops; comma-separated, name=path to set the file)", listFlag(&c.Reports))
+	fs.StringVar(&c.CIAdapter, "ci-adapter", c.CIAdapter, "Report module progress to a CI system's UI (teamcity, buildkite)")
+	fs.BoolVar(&c.OutputContract, "output-contract", c.OutputContract, "Check that examples only reference declared outputs and that every declared output is set after apply")
+	fs.Func("nullable-outputs", "Outputs that may be null with -output-contract (comma-separated)", listFlag(&c.NullableOutputs))
+	fs.BoolVar(&c.SensitiveOutputs, "sensitive-outputs", c.SensitiveOutputs, "Keep the values of sensitive outputs in the captured outputs instead of redacting them")
+	fs.BoolVar(&c.MaskSecrets, "mask-secrets", c.MaskSecrets, "Mask sensitive outputs and sensitive variables in logs, log files and reports")
+	fs.Func("secret-pattern", "Mask text matching this regular expression in logs and reports (repeatable)", func(value string) error {
+		c.SecretPatterns = append(c.SecretPatterns, value)
+		return nil
+	})
+	fs.Func("secret", "Set an environment variable of every example to a secret, e.g. TF_VAR_password=vault://secret/data/app#password (NAME=REFERENCE, repeatable)", secretFlag(c))
+	fs.Func("secret-variables", "Mask the values of these variables in logs and reports (comma-separated)", listFlag(&c.SecretVariables))
+	fs.Func("expect-failure", "Example that must fail with an error matching a pattern (EXAMPLE=PATTERN, repeatable)", expectFailureFlag(c))
+	fs.Func("max-planned-resources", "Fail an example whose plan changes more resources than this (EXAMPLE=N, repeatable)", exampleIntFlag(&c.MaxPlannedResources))
+	fs.Func("forbid-actions", "Fail an example whose plan contains this action: create, update, delete or replace (EXAMPLE=ACTION, repeatable)", exampleAddressFlag(&c.ForbiddenActions))
+	fs.StringVar(&c.BudgetRules, "budget-rules", c.BudgetRules, "YAML file of per resource type limits, such as a maximum count or banned SKUs, that plans are checked against before apply")
+	fs.StringVar(&c.RunLock, "run-lock", c.RunLock, "File or blob URL (allowing PUT, GET and DELETE) that runs sharing a subscription or account lock, so they run one at a time")
+	fs.DurationVar(&c.RunLockTimeout, "run-lock-timeout", c.RunLockTimeout, "How long to wait for the run lock before failing (defaults to 1h)")
+	fs.DurationVar(&c.RunLockTTL, "run-lock-ttl", c.RunLockTTL, "Age after which a run lock is considered left behind by a crashed run and removed (defaults to 6h)")
+	fs.Func("exec-wrapper", "Command to run every terraform invocation through, such as 'aws-vault exec profile --'", func(value string) error {
+		c.ExecWrapper = strings.Fields(value)
+		return nil
+	})
+	fs.StringVar(&c.ContainerImage, "container-image", c.ContainerImage, "Run terraform in a container of this image, e.g. hashicorp/terraform:1.9, instead of a binary on the host")
+	fs.StringVar(&c.ContainerRuntime, "container-runtime", c.ContainerRuntime, "Command that runs containers for -container-image: docker or podman (defaults to docker)")
+	fs.StringVar(&c.CredentialPool, "credential-pool", c.CredentialPool, "YAML file or directory of env files with credential sets that examples check out one at a time, to spread them over subscriptions or accounts")
+	fs.StringVar(&c.ArtifactUpload, "upload-artifacts", c.ArtifactUpload, "Upload the reports and module logs under the run ID to s3://BUCKET/PREFIX, gs://BUCKET/PREFIX or an Azure storage container URL")
+	fs.Func("weight", "Run examples with a lower weight first, e.g. cheap ones before expensive ones (EXAMPLE=N, repeatable)", exampleIntFlag(&c.Weights))
+	fs.Func("shuffle", "Run examples in a random order: off, on or a seed to reproduce an earlier order", shuffleFlag(c))
+	fs.Func("shard", "Run one shard of the examples, balanced by the durations in -history-file when set (INDEX/TOTAL, e.g. 2/4)", shardFlag(c))
+	fs.Func("registry-url", "Base URL of the modules API to look up versions in, for private registries and mirrors", func(url string) error {
+		c.RegistryOptions = append(c.RegistryOptions, WithRegistryBaseURL(url))
+		return nil
+	})
+	fs.Func("registry-ca-file", "PEM file with CA certificates to trust for the registry, in addition to the system's", func(path string) error {
+		c.RegistryOptions = append(c.RegistryOptions, WithCACertFile(path))
+		return nil
+	})
+	fs.BoolFunc("color", "Force colored output on or off (defaults to on unless NO_COLOR is set or output is not a terminal)", colorFlag(&c.Color))
+	fs.BoolVar(&c.Monorepo, "monorepo", c.Monorepo, "Discover every module in the repository that has an examples directory")
 }
 
+// GetConfig returns the shared config used by the Test* entry points, creating
+// it with defaults on first use.
 func GetConfig() *Config {
+	globalConfigMu.Lock()
+	defer globalConfigMu.Unlock()
+	if globalConfig == nil {
+		globalConfig = NewConfig()
+	}
 	return globalConfig
 }
 
@@ -83,41 +448,75 @@ func (c *Config) ParseExceptionList() {
 	}
 }
 
-func TestApplyNoError(t *testing.T, opts ...Option) {
+// versionPins splits PinnedVersion into a default pin and module=version
+// entries, merged with any pins set through WithModulePinnedVersion.
+func (c *Config) versionPins() (string, map[string]string) {
+	var defaultPin string
+	pins := make(map[string]string)
+	for entry := range strings.SplitSeq(c.PinnedVersion, ",") {
+		entry = strings.TrimSpace(entry)
+		if module, version, ok := strings.Cut(entry, "="); ok {
+			pins[strings.TrimSpace(module)] = strings.TrimSpace(version)
+		} else if entry != "" {
+			defaultPin = entry
+		}
+	}
+	maps.Copy(pins, c.ModulePins)
+	return defaultPin, pins
+}
+
+func TestApplyNoError(t testing.TB, opts ...Option) {
 	config := setupConfigWithOptions(opts...)
 	if config.Example == "" {
-		t.Fatal(redError("-example flag is not set"))
+		t.Fatal(errorText("-example flag is not set"))
 	}
 	modules := createModulesFromNames(parseExampleList(config.Example), getExamplesPath(config))
+	loadModulesMetadata(t, modules, getExamplesPath(config))
 	sourceType := map[bool]string{true: "local", false: "registry"}[config.Local]
 	var setup TestSetupFunc
 	if config.Local {
+		if config.DryRun {
+			previewLocalConversion(t, config, modules)
+			return
+		}
+		if config.PlanDiff {
+			diffPlans(t, config, modules)
+			return
+		}
 		setup = createLocalSetupFunc(config)
 	}
-	runModuleTests(t, modules, true, config, setup, sourceType)
+	runModuleTests(t, Runners(modules), true, config, setup, sourceType)
 }
 
-func TestApplyAllParallel(t *testing.T, opts ...Option) {
+func TestApplyAllParallel(t testing.TB, opts ...Option) {
 	config := setupConfigWithOptions(opts...)
 	modules := discoverModules(t, config)
-	RunTests(t, modules, true, config)
+	runModuleTests(t, Runners(modules), true, config, nil, "registry")
 }
 
-func TestApplyAllSequential(t *testing.T, opts ...Option) {
+func TestApplyAllSequential(t testing.TB, opts ...Option) {
 	config := setupConfigWithOptions(opts...)
 	modules := discoverModules(t, config)
-	RunTests(t, modules, false, config)
+	runModuleTests(t, Runners(modules), false, config, nil, "registry")
 }
 
-func TestApplyAllLocal(t *testing.T, opts ...Option) {
+func TestApplyAllLocal(t testing.TB, opts ...Option) {
 	config := setupConfigWithOptions(opts...)
 	modules := discoverModules(t, config)
-	runModuleTests(t, modules, true, config, createLocalSetupFunc(config), "local")
+	if config.DryRun {
+		previewLocalConversion(t, config, modules)
+		return
+	}
+	if config.PlanDiff {
+		diffPlans(t, config, modules)
+		return
+	}
+	runModuleTests(t, Runners(modules), true, config, createLocalSetupFunc(config), "local")
 }
 
 type TestOption func(*TestConfig)
 
-type TestSetupFunc func(ctx context.Context, t *testing.T, modules []*Module) error
+type TestSetupFunc func(ctx context.Context, t testing.TB, modules []*Module) error
 
 type TestConfig struct {
 	Config       *Config
@@ -147,7 +546,7 @@ func WithTestExamplesPath(path string) TestOption {
 	return func(tc *TestConfig) { tc.ExamplesPath = path }
 }
 
-func RunTestsWithOptions(t *testing.T, opts ...TestOption) {
+func RunTestsWithOptions(t testing.TB, opts ...TestOption) {
 	tc := &TestConfig{
 		Parallel: true, // default to parallel
 	}
@@ -171,52 +570,20 @@ func RunTestsWithOptions(t *testing.T, opts ...TestOption) {
 	if tc.UseLocal {
 		setup = createLocalSetupFunc(tc.Config)
 	}
-	runModuleTestsFn(t, modules, tc.Parallel, tc.Config, setup, sourceType)
+	runModuleTestsFn(t, Runners(modules), tc.Parallel, tc.Config, setup, sourceType)
 }
 
-
-func runModuleTests(t *testing.T, modules []*Module, parallel bool, config *Config, setup TestSetupFunc, sourceType string) {
-	ctx := context.Background()
-	results := NewTestResults()
-
-	if setup != nil {
-		if err := setup(ctx, t, modules); err != nil {
-			t.Fatal(redError(fmt.Sprintf("Setup failed: %v", err)))
+func runModuleTests(t testing.TB, runners []ModuleRunner, parallel bool, config *Config, setup TestSetupFunc, sourceType string) {
+	if config.NativeTests {
+		native, err := nativeTestRunners(config)
+		if err != nil {
+			t.Fatal(errorText(fmt.Sprintf("Failed to discover terraform tests: %v", err)))
 			return
 		}
+		runners = append(runners, native...)
 	}
-
-	for _, module := range modules {
-		if slices.Contains(config.ExceptionList, module.Name) {
-			t.Logf("Skipping example %s as it is in the exception list", module.Name)
-			continue
-		}
-
-		t.Run(module.Name, func(t *testing.T) {
-			if parallel {
-				t.Parallel()
-			}
-
-			if err := module.Apply(ctx, t); err != nil {
-				t.Fail()
-			} else {
-				t.Logf("✓ Module %s applied successfully with %s source", module.Name, sourceType)
-			}
-
-			if !config.SkipDestroy {
-				if err := module.Destroy(ctx, t); err != nil && !module.ApplyFailed {
-					t.Logf("Cleanup failed for module %s: %v", module.Name, err)
-				}
-			}
-
-			results.AddModule(module)
-		})
-	}
-
-	t.Cleanup(func() {
-		modules, _ := results.GetResults()
-		PrintModuleSummary(t, modules)
-	})
+	runner := &DefaultTestRunner{Setup: setup, SourceType: sourceType}
+	runner.RunTests(context.Background(), t, runners, parallel, config)
 }
 
 func setupConfigWithOptions(opts ...Option) *Config {
@@ -225,6 +592,7 @@ func setupConfigWithOptions(opts ...Option) *Config {
 		opt(config)
 	}
 	config.ParseExceptionList()
+	useColors(config)
 	return config
 }
 
@@ -235,18 +603,28 @@ func getExamplesPath(config *Config) string {
 	return filepath.Join("..", "examples")
 }
 
-func discoverModules(t *testing.T, config *Config) []*Module {
-	examplesPath := getExamplesPath(config)
-	manager := NewModuleManager(examplesPath)
-	manager.SetConfig(config)
-	modules, err := manager.DiscoverModules()
+func discoverModules(t testing.TB, config *Config) []*Module {
+	modules, err := findModules(config)
 	if err != nil {
-		errText := fmt.Sprintf("Failed to discover modules: %v", err)
-		t.Fatal(redError(errText))
+		t.Fatal(errorText(fmt.Sprintf("Failed to discover modules: %v", err)))
 	}
 	return modules
 }
 
+func findModules(config *Config) ([]*Module, error) {
+	targets, err := moduleTargets(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) > 0 {
+		return discoverTargetModules(targets, config)
+	}
+
+	manager := NewModuleManager(getExamplesPath(config))
+	manager.SetConfig(config)
+	return manager.DiscoverModules()
+}
+
 func extractModuleNames(modules []*Module) []string {
 	var moduleNames []string
 	for _, module := range modules {
@@ -264,37 +642,66 @@ func createModulesFromNames(moduleNames []string, basePath string) []*Module {
 	return modules
 }
 
-func convertModulesToLocal(ctx context.Context, t *testing.T, converter SourceConverter, moduleNames []string, exceptionList []string, moduleInfo ModuleInfo, examplesPath string) []FileRestore {
-	var allFilesToRestore []FileRestore
-
-	for _, moduleName := range moduleNames {
+func convertModulesToLocal(ctx context.Context, t testing.TB, converter SourceConverter, moduleNames []string, exceptionList []string, moduleInfo ModuleInfo, examplesPath string) []FileRestore {
+	restores := make([][]FileRestore, len(moduleNames))
+	errs := make([]error, len(moduleNames))
+	var group errgroup.Group
+	group.SetLimit(defaultConversionWorkers)
+	for i, moduleName := range moduleNames {
 		if slices.Contains(exceptionList, moduleName) {
 			continue
 		}
+		group.Go(func() error {
+			restores[i], errs[i] = converter.ConvertToLocal(ctx, filepath.Join(examplesPath, moduleName), moduleInfo)
+			return nil
+		})
+	}
+	group.Wait()
 
-		modulePath := filepath.Join(examplesPath, moduleName)
-		filesToRestore, err := converter.ConvertToLocal(ctx, modulePath, moduleInfo)
-		if err != nil {
-			t.Logf("Warning: Failed to convert module %s to local source: %v", moduleName, err)
-			continue
+	var allFilesToRestore []FileRestore
+	for i, moduleName := range moduleNames {
+		if errs[i] != nil {
+			t.Logf("Warning: Failed to convert module %s to local source: %v", moduleName, errs[i])
 		}
-		allFilesToRestore = append(allFilesToRestore, filesToRestore...)
+		allFilesToRestore = append(allFilesToRestore, restores[i]...)
 	}
-
 	return allFilesToRestore
 }
 
 func createLocalSetupFunc(config *Config) TestSetupFunc {
-	return func(ctx context.Context, t *testing.T, modules []*Module) error {
-		moduleInfo := extractModuleInfoFromRepo()
-		if moduleInfo.Name == "" || moduleInfo.Provider == "" {
-			return fmt.Errorf("could not determine module name and provider from repository")
+	return func(ctx context.Context, t testing.TB, modules []*Module) error {
+		var repoModules, targetModules []*Module
+		for _, module := range modules {
+			if module.info != nil {
+				targetModules = append(targetModules, module)
+			} else {
+				repoModules = append(repoModules, module)
+			}
 		}
-		moduleInfo.Namespace = config.Namespace
 
-		converter := NewSourceConverter(NewRegistryClient())
-		moduleNames := extractModuleNames(modules)
-		allFilesToRestore := convertModulesToLocal(ctx, t, converter, moduleNames, config.ExceptionList, moduleInfo, getExamplesPath(config))
+		moduleInfo := config.moduleInfo()
+		if len(repoModules) > 0 && (moduleInfo.Name == "" || moduleInfo.Provider == "") {
+			return fmt.Errorf("could not determine module name and provider from repository; set them with WithModuleInfo or -repo-name-pattern")
+		}
+		moduleInfo.Root = filepath.Dir(getExamplesPath(config))
+
+		defaultPin, modulePins := config.versionPins()
+		converter := NewSourceConverter(config.registryClient(), WithVersionPins(defaultPin, modulePins), WithConstraintStyle(config.ConstraintStyle), WithModuleMappings(config.ModuleMappings...))
+		var allFilesToRestore []FileRestore
+		if len(repoModules) > 0 {
+			moduleNames := extractModuleNames(repoModules)
+			allFilesToRestore = convertModulesToLocal(ctx, t, converter, moduleNames, config.ExceptionList, moduleInfo, getExamplesPath(config))
+		}
+		for _, module := range targetModules {
+			if slices.Contains(config.ExceptionList, module.Name) {
+				continue
+			}
+			filesToRestore, err := converter.ConvertToLocal(ctx, module.Path, *module.info)
+			if err != nil {
+				t.Logf("Warning: Failed to convert module %s to local source: %v", module.Name, err)
+			}
+			allFilesToRestore = append(allFilesToRestore, filesToRestore...)
+		}
 
 		t.Cleanup(func() {
 			if err := converter.RevertToRegistry(context.Background(), allFilesToRestore); err != nil {
@@ -305,6 +712,52 @@ func createLocalSetupFunc(config *Config) TestSetupFunc {
 	}
 }
 
+// TestConvertDryRun prints the diff that local testing would apply to every
+// example without modifying any files.
+func TestConvertDryRun(t testing.TB, opts ...Option) {
+	config := setupConfigWithOptions(opts...)
+	var modules []*Module
+	if config.Example != "" {
+		modules = createModulesFromNames(parseExampleList(config.Example), getExamplesPath(config))
+	} else {
+		modules = discoverModules(t, config)
+	}
+	previewLocalConversion(t, config, modules)
+}
+
+func previewLocalConversion(t testing.TB, config *Config, modules []*Module) {
+	ctx := context.Background()
+
+	repoInfo := config.moduleInfo()
+	repoInfo.Root = filepath.Dir(getExamplesPath(config))
+
+	for _, module := range modules {
+		if slices.Contains(config.ExceptionList, module.Name) {
+			continue
+		}
+
+		moduleInfo := repoInfo
+		if module.info != nil {
+			moduleInfo = *module.info
+		}
+		if moduleInfo.Name == "" || moduleInfo.Provider == "" {
+			t.Fatal(errorText("could not determine module name and provider from repository; set them with WithModuleInfo or -repo-name-pattern"))
+		}
+
+		var diff strings.Builder
+		converter := NewSourceConverter(nil, WithDryRunOutput(&diff), WithModuleMappings(config.ModuleMappings...))
+		if _, err := converter.ConvertToLocal(ctx, module.Path, moduleInfo); err != nil {
+			t.Error(errorText(fmt.Sprintf("Failed to preview conversion of %s: %v", module.Name, err)))
+			continue
+		}
+		if diff.Len() == 0 {
+			t.Logf("%s: no registry sources to convert", module.Name)
+			continue
+		}
+		t.Logf("%s:\n%s", module.Name, diff.String())
+	}
+}
+
 func parseExampleList(example string) []string {
 	var examples []string
 	for ex := range strings.SplitSeq(example, ",") {
@@ -315,7 +768,42 @@ func parseExampleList(example string) []string {
 	return examples
 }
 
-func extractModuleInfoFromRepo() ModuleInfo {
+var defaultRepoNamePattern = regexp.MustCompile(`^terraform-([^-]+)-(.+)$`)
+
+func repoNamePatternFlag(c *Config) func(string) error {
+	return func(value string) error {
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return err
+		}
+		c.RepoNamePattern = pattern
+		return nil
+	}
+}
+
+// moduleInfo returns the module under test as configured with
+// WithModuleInfo, inferring what is not set from the registry sources in the
+// examples and otherwise from the repository name.
+func (c *Config) moduleInfo() ModuleInfo {
+	info := c.ModuleInfo
+	if info.Name == "" || info.Provider == "" {
+		examplesPath := getExamplesPath(c)
+		inferred, err := InferModuleInfo(filepath.Dir(examplesPath), examplesPath)
+		if err != nil {
+			inferred = extractModuleInfoFromRepo(c.RepoNamePattern)
+		}
+		info.Namespace = cmp.Or(info.Namespace, inferred.Namespace)
+		info.Name = cmp.Or(info.Name, inferred.Name)
+		info.Provider = cmp.Or(info.Provider, inferred.Provider)
+	}
+	info.Namespace = cmp.Or(info.Namespace, c.Namespace)
+	return info
+}
+
+func extractModuleInfoFromRepo(pattern *regexp.Regexp) ModuleInfo {
+	if pattern == nil {
+		pattern = defaultRepoNamePattern
+	}
 	wd, err := os.Getwd()
 	if err != nil {
 		return ModuleInfo{}
@@ -326,24 +814,29 @@ func extractModuleInfoFromRepo() ModuleInfo {
 	}
 
 	if repoName := getRepoNameFromGit(wd); repoName != "" {
-		re := regexp.MustCompile(`^terraform-([^-]+)-(.+)$`)
-		if matches := re.FindStringSubmatch(repoName); len(matches) > 2 {
-			return ModuleInfo{
-				Name:     matches[2],
-				Provider: matches[1],
-			}
+		if info, ok := parseRepoName(pattern, repoName); ok {
+			return info
 		}
 	}
+	info, _ := parseRepoName(pattern, filepath.Base(wd))
+	return info
+}
 
-	repoName := filepath.Base(wd)
-	re := regexp.MustCompile(`^terraform-([^-]+)-(.+)$`)
-	if matches := re.FindStringSubmatch(repoName); len(matches) > 2 {
-		return ModuleInfo{
-			Name:     matches[2],
-			Provider: matches[1],
+func parseRepoName(pattern *regexp.Regexp, repoName string) (ModuleInfo, bool) {
+	matches := pattern.FindStringSubmatch(repoName)
+	if matches == nil {
+		return ModuleInfo{}, false
+	}
+	var info ModuleInfo
+	if i := pattern.SubexpIndex("name"); i > 0 {
+		info.Name = matches[i]
+		if i := pattern.SubexpIndex("provider"); i > 0 {
+			info.Provider = matches[i]
 		}
+	} else if len(matches) > 2 {
+		info.Provider, info.Name = matches[1], matches[2]
 	}
-	return ModuleInfo{}
+	return info, info.Name != ""
 }
 
 func getRepoNameFromGit(dir string) string {
@@ -352,13 +845,35 @@ func getRepoNameFromGit(dir string) string {
 		return ""
 	}
 
-	url := strings.TrimSpace(string(output))
-	parts := strings.Split(url, "/")
-	if len(parts) > 0 {
-		repoName := parts[len(parts)-1]
-		return strings.TrimSuffix(repoName, ".git")
+	return repoNameFromURL(string(output))
+}
+
+// repoNameFromURL returns the repository name of a git remote URL, in the
+// forms GitHub, GitLab, Bitbucket and Azure DevOps hand out: https and ssh
+// URLs with or without .git, scp-like ssh addresses such as
+// git@github.com:org/repo.git, and Azure DevOps addresses such as
+// https://dev.azure.com/org/project/_git/repo and
+// git@ssh.dev.azure.com:v3/org/project/repo.
+func repoNameFromURL(remote string) string {
+	remote = strings.TrimSpace(remote)
+	path := remote
+	if strings.Contains(remote, "://") {
+		parsed, err := url.Parse(remote)
+		if err != nil {
+			return ""
+		}
+		path = parsed.Path
+	} else if host, after, ok := strings.Cut(remote, ":"); ok && !strings.ContainsAny(host, "/\\") {
+		path = after
+	}
+
+	path = strings.ReplaceAll(path, "\\", "/")
+	path = strings.TrimSuffix(strings.TrimRight(path, "/"), ".git")
+	name := path[strings.LastIndex(path, "/")+1:]
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
 	}
-	return ""
+	return name
 }
 
 var gitRemoteURL = func(dir string) ([]byte, error) {